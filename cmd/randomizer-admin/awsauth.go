@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/featherbread/randomizer/internal/admin"
+	"github.com/featherbread/randomizer/internal/awsconfig"
+)
+
+// awsToken returns a bearer token proving the caller's AWS identity to an
+// admin API that accepts AWS principals, from the AWS credentials in the
+// environment. The token is a presigned STS GetCallerIdentity request, which
+// the server forwards to STS.
+func awsToken(ctx context.Context, audience string) (string, error) {
+	cfg, err := awsconfig.New(ctx)
+	if err != nil {
+		return "", fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	presigner := sts.NewPresignClient(sts.NewFromConfig(cfg))
+	req, err := presigner.PresignGetCallerIdentity(ctx, &sts.GetCallerIdentityInput{},
+		sts.WithPresignClientFromClientOptions(
+			sts.WithAPIOptions(smithyhttp.SetHeaderValue(admin.AWSAudienceHeader, audience)),
+		))
+	if err != nil {
+		return "", fmt.Errorf("presigning STS request: %w", err)
+	}
+	return admin.AWSTokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(req.URL)), nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/featherbread/randomizer/internal/admin"
+)
+
+var listWorkspacesCmd = &cobra.Command{
+	Use:   "list-workspaces",
+	Short: "List the partitions (e.g. Slack channels) with saved data",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		callAdmin(http.MethodGet, "partitions", nil)
+	},
+}
+
+var dumpGroupCmd = &cobra.Command{
+	Use:   "dump-group PARTITION GROUP",
+	Short: "Print the stored options of a single group",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		callAdmin(http.MethodGet,
+			"partitions/"+url.PathEscape(args[0])+"/groups/"+url.PathEscape(args[1]), nil)
+	},
+}
+
+var selectGroupsCmd = &cobra.Command{
+	Use:   "select-groups PARTITION GROUP...",
+	Short: "Make a separate selection from each of several groups",
+	Long: `Make a separate selection from each of several groups, as if each were
+requested in the partition on its own (e.g. to pick a reviewer for each of
+several repositories). Failures are reported for each group individually.`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		callAdmin(http.MethodPost,
+			"partitions/"+url.PathEscape(args[0])+"/selections",
+			map[string]any{"groups": args[1:]})
+	},
+}
+
+var showResultCmd = &cobra.Command{
+	Use:   "show-result PARTITION ID",
+	Short: "Print the details of a past selection by its result ID",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		callAdmin(http.MethodGet,
+			"partitions/"+url.PathEscape(args[0])+"/results/"+url.PathEscape(args[1]), nil)
+	},
+}
+
+var showReportCmd = &cobra.Command{
+	Use:   "show-report NAME",
+	Short: "Print a report on the deployment's in-memory state",
+	Long: `Print a report on the deployment's in-memory state.
+
+The "verification" report shows how many requests from each workspace were
+verified with a signing secret or a legacy token, to track a migration to
+signing secrets.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		callAdmin(http.MethodGet, "reports/"+url.PathEscape(args[0]), nil)
+	},
+}
+
+var deleteWorkspaceDataCmd = &cobra.Command{
+	Use:   "delete-workspace-data PARTITION",
+	Short: "Delete every group saved in a partition",
+	Long: `Delete every group saved in a partition.
+
+This permanently removes data, and requires --yes to confirm.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if !deleteConfirmed {
+			fmt.Fprintln(os.Stderr, "refusing to delete data without --yes")
+			os.Exit(2)
+		}
+		callAdmin(http.MethodDelete, "partitions/"+url.PathEscape(args[0]), nil)
+	},
+}
+
+var deleteConfirmed bool
+
+var runAsCmd = &cobra.Command{
+	Use:   "run-as CHANNEL USER -- TEXT...",
+	Short: "Run a slash command as a user, to reproduce their problem",
+	Long: `Run a slash command as if a user had run it in a channel, to reproduce a
+problem they reported without access to their workspace. The response is
+printed rather than posted, and opens with a notice that an operator ran it.
+
+The deployment logs the command along with your identity, and records it in
+the workspace's audit trail if that's enabled. Commands that change groups
+really change them, so add --dry-run to the text to only preview changes.`,
+	Example: `  randomizer-admin run-as C12345678 U12345678 --team T12345678 -- /save lunch pizza --dry-run`,
+	Args:    cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		callAdmin(http.MethodPost, "run-as", admin.RunAsRequest{
+			Team:    runAsTeam,
+			Channel: args[0],
+			User:    args[1],
+			Command: runAsCommand,
+			Text:    strings.Join(args[2:], " "),
+		})
+	},
+}
+
+var runAsTeam, runAsCommand string
+
+var flushCacheCmd = &cobra.Command{
+	Use:   "flush-cache",
+	Short: "Discard cached state in the running deployment",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		callAdmin(http.MethodPost, "cache/flush", nil)
+		fmt.Println("caches flushed")
+	},
+}
+
+var setFlagCmd = &cobra.Command{
+	Use:   "set-flag NAME true|false",
+	Short: "Enable or disable a feature flag in the running deployment",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		enabled, err := strconv.ParseBool(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid flag value %q\n", args[1])
+			os.Exit(2)
+		}
+		callAdmin(http.MethodPut, "flags/"+url.PathEscape(args[0]),
+			map[string]bool{"enabled": enabled})
+	},
+}
+
+func init() {
+	runAsCmd.Flags().StringVar(&runAsTeam, "team", "", "team ID of the user's workspace")
+	runAsCmd.Flags().StringVar(&runAsCommand, "command", "/randomize", "name of the slash command")
+
+	deleteWorkspaceDataCmd.Flags().BoolVar(
+		&deleteConfirmed,
+		"yes", false,
+		"confirm permanent deletion of the partition's data",
+	)
+
+	rootCmd.AddCommand(
+		listWorkspacesCmd,
+		dumpGroupCmd,
+		selectGroupsCmd,
+		showResultCmd,
+		runAsCmd,
+		deleteWorkspaceDataCmd,
+		flushCacheCmd,
+		setFlagCmd,
+		showReportCmd,
+	)
+}
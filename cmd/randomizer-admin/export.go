@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var exportDataCmd = &cobra.Command{
+	Use:   "export-data FILE",
+	Short: "Stream every partition's groups and records to a JSON Lines file",
+	Long: `Stream every partition's groups and records to a JSON Lines file, with
+one group or record per line.
+
+If FILE already holds part of an export, for example because an earlier run was
+interrupted, export-data resumes after its last complete line. Use "-" to write
+a complete export to stdout instead.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runExportData,
+}
+
+// exportClient has no overall timeout, since a large export may take longer
+// than any fixed limit.
+var exportClient = &http.Client{}
+
+func init() {
+	rootCmd.AddCommand(exportDataCmd)
+}
+
+func runExportData(cmd *cobra.Command, args []string) {
+	var (
+		out    io.Writer = os.Stdout
+		cursor string
+	)
+	if args[0] != "-" {
+		file, err := os.OpenFile(args[0], os.O_RDWR|os.O_CREATE, 0o600)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not open export file: %v\n", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		if cursor, err = resumeExport(file); err != nil {
+			fmt.Fprintf(os.Stderr, "could not resume export: %v\n", err)
+			os.Exit(1)
+		}
+		if cursor != "" {
+			fmt.Fprintln(os.Stderr, "resuming partial export")
+		}
+		out = file
+	}
+
+	var query url.Values
+	if cursor != "" {
+		query = url.Values{"after": {cursor}}
+	}
+	resp := sendAdmin(exportClient, http.MethodGet, "export", query, nil)
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		fmt.Fprintf(os.Stderr, "request failed with %s: %s\n", resp.Status, bytes.TrimSpace(body))
+		os.Exit(1)
+	}
+
+	lines, err := copyExport(out, resp.Body)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export interrupted after %d lines: %v\n", lines, err)
+		if args[0] != "-" {
+			fmt.Fprintln(os.Stderr, "run the same command again to resume")
+		}
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "exported %d lines\n", lines)
+}
+
+// resumeExport truncates a partial export file after its last complete line,
+// positions the file for writing after it, and returns that line's cursor.
+func resumeExport(file *os.File) (cursor string, err error) {
+	var (
+		reader   = bufio.NewReader(file)
+		complete int64
+	)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break // Any partial line at the end is discarded.
+		}
+		if err != nil {
+			return "", err
+		}
+		var parsed struct{ Cursor string }
+		if json.Unmarshal(line, &parsed) != nil || parsed.Cursor == "" {
+			return "", fmt.Errorf("unexpected line at offset %d", complete)
+		}
+		cursor = parsed.Cursor
+		complete += int64(len(line))
+	}
+
+	if err := file.Truncate(complete); err != nil {
+		return "", err
+	}
+	_, err = file.Seek(complete, io.SeekStart)
+	return cursor, err
+}
+
+// copyExport copies complete lines of an export stream to out, and returns
+// the number of lines copied. It stops with an error at an error line from the
+// server, or at a partial line if the stream ends early.
+func copyExport(out io.Writer, stream io.Reader) (lines int, err error) {
+	reader := bufio.NewReader(stream)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				return lines, io.ErrUnexpectedEOF
+			}
+			return lines, nil
+		}
+		if err != nil {
+			return lines, err
+		}
+
+		var parsed struct{ Error string }
+		if json.Unmarshal(line, &parsed) == nil && parsed.Error != "" {
+			return lines, errors.New(parsed.Error)
+		}
+		if _, err := out.Write(line); err != nil {
+			return lines, err
+		}
+		lines++
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResumeExport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.jsonl")
+	partial := `{"partition":"C1","group":"a","options":["x"],"cursor":"first"}` + "\n" +
+		`{"partition":"C1","group":"b","options":["y"],"cursor":"second"}` + "\n" +
+		`{"partition":"C1","group":"c","opt`
+	if err := os.WriteFile(path, []byte(partial), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	cursor, err := resumeExport(file)
+	if err != nil {
+		t.Fatalf("resuming export: %v", err)
+	}
+	if cursor != "second" {
+		t.Errorf("got cursor %q, want %q", cursor, "second")
+	}
+
+	file.WriteString("next\n")
+	file.Seek(0, io.SeekStart)
+	contents, _ := io.ReadAll(file)
+	if !strings.HasSuffix(string(contents), `"cursor":"second"}`+"\nnext\n") {
+		t.Errorf("partial line not replaced: %q", contents)
+	}
+}
+
+func TestCopyExport(t *testing.T) {
+	var out bytes.Buffer
+	lines, err := copyExport(&out, strings.NewReader("{\"cursor\":\"a\"}\n{\"error\":\"store failed\"}\n"))
+	if lines != 1 || err == nil || !strings.Contains(err.Error(), "store failed") {
+		t.Errorf("got %d lines and error %v, want 1 line and the server's error", lines, err)
+	}
+
+	out.Reset()
+	lines, err = copyExport(&out, strings.NewReader("{\"cursor\":\"a\"}\n{\"curs"))
+	if lines != 1 || err == nil || out.String() != "{\"cursor\":\"a\"}\n" {
+		t.Errorf("got %d lines and error %v for a truncated stream", lines, err)
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var loadtestCmd = &cobra.Command{
+	Use:   "loadtest",
+	Short: "Send realistic slash command traffic to a randomizer deployment",
+	Long: `Send realistic slash command traffic to a randomizer deployment.
+
+The load test sends a mix of selections, group lookups, and group saves to the
+target's slash command endpoint at a fixed rate, then reports latency
+percentiles and error rates. Requests carry the provided verification token,
+and are also signed if a signing secret is provided.
+
+All requests use the channel ID given by --channel, so that any groups saved
+during the test are isolated from real channels.`,
+	Args: cobra.NoArgs,
+	Run:  runLoadtest,
+}
+
+var (
+	loadtestTarget        string
+	loadtestRPS           int
+	loadtestDuration      time.Duration
+	loadtestToken         string
+	loadtestSigningSecret string
+	loadtestChannel       string
+)
+
+func init() {
+	loadtestCmd.Flags().StringVar(&loadtestTarget, "target", "", "URL of the slash command endpoint (required)")
+	loadtestCmd.MarkFlagRequired("target")
+	loadtestCmd.Flags().IntVar(&loadtestRPS, "rps", 10, "requests to send per second")
+	loadtestCmd.Flags().DurationVar(&loadtestDuration, "duration", 30*time.Second, "how long to send requests")
+	loadtestCmd.Flags().StringVar(&loadtestToken, "slack-token", os.Getenv("SLACK_TOKEN"), "slash command verification token")
+	loadtestCmd.Flags().StringVar(&loadtestSigningSecret, "signing-secret", os.Getenv("SLACK_SIGNING_SECRET"), "Slack signing secret")
+	loadtestCmd.Flags().StringVar(&loadtestChannel, "channel", "CLOADTEST", "channel ID to send in requests")
+
+	rootCmd.AddCommand(loadtestCmd)
+}
+
+// loadtestCommands is the mix of commands sent during a load test, roughly
+// weighted toward what real users run most often.
+var loadtestCommands = []string{
+	"alice bob carol dave",
+	"alice bob carol dave",
+	"loadtest",
+	"loadtest",
+	"+loadtest where not ooo",
+	"/list",
+	"/show loadtest",
+	"/save loadtest alice bob carol#ooo dave",
+}
+
+// slackDeadline is the time limit that Slack imposes on slash command
+// responses.
+const slackDeadline = 3 * time.Second
+
+type loadtestSample struct {
+	latency time.Duration
+	command string
+	err     error
+}
+
+func runLoadtest(cmd *cobra.Command, args []string) {
+	if loadtestRPS < 1 {
+		fmt.Fprintln(os.Stderr, "--rps must be at least 1")
+		os.Exit(2)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	// Make sure that the group used in the test mix exists up front.
+	first := sendLoadtestRequest(client, loadtestCommands[len(loadtestCommands)-1])
+	if first.err != nil {
+		fmt.Fprintf(os.Stderr, "initial request failed: %v\n", first.err)
+		os.Exit(1)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		samples []loadtestSample
+	)
+	ticker := time.NewTicker(time.Second / time.Duration(loadtestRPS))
+	defer ticker.Stop()
+	deadline := time.After(loadtestDuration)
+
+	fmt.Printf("sending %d requests/s to %s for %v\n", loadtestRPS, loadtestTarget, loadtestDuration)
+send:
+	for {
+		select {
+		case <-deadline:
+			break send
+		case <-ticker.C:
+			command := loadtestCommands[rand.IntN(len(loadtestCommands))]
+			wg.Go(func() {
+				sample := sendLoadtestRequest(client, command)
+				mu.Lock()
+				samples = append(samples, sample)
+				mu.Unlock()
+			})
+		}
+	}
+	wg.Wait()
+
+	reportLoadtest(samples)
+}
+
+func sendLoadtestRequest(client *http.Client, command string) loadtestSample {
+	params := url.Values{
+		"token":        {loadtestToken},
+		"team_id":      {"TLOADTEST"},
+		"channel_id":   {loadtestChannel},
+		"user_id":      {"ULOADTEST"},
+		"command":      {"/randomize"},
+		"text":         {command},
+		"trigger_id":   {strconv.FormatUint(rand.Uint64(), 10)},
+		"response_url": {"https://hooks.slack.com/commands/loadtest"},
+	}
+	body := params.Encode()
+
+	req, err := http.NewRequest(http.MethodPost, loadtestTarget, strings.NewReader(body))
+	if err != nil {
+		return loadtestSample{command: command, err: err}
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if loadtestSigningSecret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(loadtestSigningSecret))
+		fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+		req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+		req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return loadtestSample{latency: time.Since(start), command: command, err: err}
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	latency := time.Since(start)
+
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("status %s", resp.Status)
+	}
+	return loadtestSample{latency: latency, command: command, err: err}
+}
+
+func reportLoadtest(samples []loadtestSample) {
+	if len(samples) == 0 {
+		fmt.Println("no requests were sent")
+		return
+	}
+
+	latencies := make([]time.Duration, len(samples))
+	errors := make(map[string]int)
+	var failed, slow int
+	for i, s := range samples {
+		latencies[i] = s.latency
+		if s.latency > slackDeadline {
+			slow++
+		}
+		if s.err != nil {
+			failed++
+			errors[s.err.Error()]++
+		}
+	}
+	slices.Sort(latencies)
+
+	percentile := func(p float64) time.Duration {
+		return latencies[min(len(latencies)-1, int(float64(len(latencies))*p))]
+	}
+
+	fmt.Printf("requests: %d\n", len(samples))
+	fmt.Printf("errors:   %d (%.2f%%)\n", failed, 100*float64(failed)/float64(len(samples)))
+	fmt.Printf("slow:     %d exceeded Slack's %v response deadline\n", slow, slackDeadline)
+	fmt.Printf("latency:  p50=%v p90=%v p99=%v max=%v\n",
+		percentile(0.5), percentile(0.9), percentile(0.99), latencies[len(latencies)-1])
+	for msg, count := range errors {
+		fmt.Printf("  %d× %s\n", count, msg)
+	}
+}
@@ -0,0 +1,162 @@
+// The randomizer-admin command manages a running randomizer deployment
+// through its admin API.
+//
+// The admin API must be enabled on the target deployment (for example, by
+// setting ADMIN_TOKEN for randomizer-server). The URL and token for the API
+// may be given with flags, or with the RANDOMIZER_ADMIN_URL and
+// RANDOMIZER_ADMIN_TOKEN environment variables. The token may be a static
+// token or a JWT from an identity provider, depending on the deployment. For
+// deployments that accept AWS principals, --aws-auth (or
+// RANDOMIZER_ADMIN_AWS_AUTH=1) authenticates with the AWS credentials in the
+// environment instead.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/featherbread/randomizer/internal/admin"
+)
+
+var rootCmd = &cobra.Command{
+	Use:   "randomizer-admin",
+	Short: "Manage a running randomizer deployment through its admin API",
+}
+
+var (
+	adminURL    string
+	adminToken  string
+	awsAuth     bool
+	awsAudience string
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVarP(
+		&adminURL,
+		"url", "u", os.Getenv("RANDOMIZER_ADMIN_URL"),
+		"base URL of the randomizer deployment",
+	)
+
+	rootCmd.PersistentFlags().StringVarP(
+		&adminToken,
+		"token", "k", os.Getenv("RANDOMIZER_ADMIN_TOKEN"),
+		"admin API token for the deployment",
+	)
+
+	rootCmd.PersistentFlags().BoolVar(
+		&awsAuth,
+		"aws-auth", os.Getenv("RANDOMIZER_ADMIN_AWS_AUTH") == "1",
+		"authenticate with AWS credentials instead of a token",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&awsAudience,
+		"aws-audience", admin.DefaultAWSAudience,
+		"audience that the deployment expects in AWS credentials",
+	)
+}
+
+func main() {
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// callAdmin makes a request to the admin API, and copies any response body to
+// stdout. It exits the program if the request fails.
+func callAdmin(method, path string, body any) {
+	if out := requestAdmin(method, path, nil, body); len(out) > 0 {
+		fmt.Println(string(out))
+	}
+}
+
+// requestAdmin makes a request to the admin API, and returns the response body
+// with any JSON indented. It exits the program if the request fails.
+func requestAdmin(method, path string, query url.Values, body any) []byte {
+	resp := sendAdmin(httpClient, method, path, query, body)
+	defer resp.Body.Close()
+
+	var out bytes.Buffer
+	if _, err := io.Copy(&out, resp.Body); err != nil {
+		fmt.Fprintf(os.Stderr, "could not read response: %v\n", err)
+		os.Exit(1)
+	}
+
+	var pretty bytes.Buffer
+	if json.Indent(&pretty, out.Bytes(), "", "  ") == nil {
+		out = pretty
+	}
+	if resp.StatusCode >= 300 {
+		fmt.Fprintf(os.Stderr, "request failed with %s: %s\n", resp.Status, strings.TrimSpace(out.String()))
+		os.Exit(1)
+	}
+	return bytes.TrimSpace(out.Bytes())
+}
+
+// sendAdmin makes a request to the admin API with client, and returns the
+// response for the caller to read and close. It exits the program if the
+// request can't be sent.
+func sendAdmin(client *http.Client, method, path string, query url.Values, body any) *http.Response {
+	if adminURL == "" || (adminToken == "" && !awsAuth) {
+		fmt.Fprintln(os.Stderr, "both --url and either --token or --aws-auth are required")
+		os.Exit(2)
+	}
+
+	token := adminToken
+	if awsAuth {
+		var err error
+		if token, err = awsToken(context.Background(), awsAudience); err != nil {
+			fmt.Fprintf(os.Stderr, "could not authenticate with AWS: %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	target, err := url.JoinPath(adminURL, "admin/v1", path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid URL: %v\n", err)
+		os.Exit(2)
+	}
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not encode request: %v\n", err)
+			os.Exit(2)
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, target, reqBody)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not create request: %v\n", err)
+		os.Exit(2)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "request failed: %v\n", err)
+		os.Exit(1)
+	}
+	return resp
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	bolt "go.etcd.io/bbolt"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot FILE",
+	Short: "Copy a running deployment's bbolt database to a file",
+	Long: `Copy a running deployment's bbolt database to a file, without stopping it.
+
+The copy is consistent as of the moment it starts, and is itself a bbolt
+database. To move a deployment to a new machine, point DB_PATH there at the
+copy. snapshot checks the copy before replacing FILE, so an interrupted
+download never leaves a broken database in its place.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runSnapshot,
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+}
+
+func runSnapshot(cmd *cobra.Command, args []string) {
+	partial := args[0] + ".partial"
+	if err := downloadSnapshot(partial); err != nil {
+		os.Remove(partial)
+		fmt.Fprintf(os.Stderr, "could not download snapshot: %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkSnapshot(partial); err != nil {
+		os.Remove(partial)
+		fmt.Fprintf(os.Stderr, "downloaded snapshot is incomplete: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.Rename(partial, args[0]); err != nil {
+		fmt.Fprintf(os.Stderr, "could not save snapshot: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "saved snapshot to %s\n", args[0])
+}
+
+func downloadSnapshot(path string) error {
+	// exportClient has no overall timeout, which a large database may need.
+	resp := sendAdmin(exportClient, http.MethodGet, "snapshot", nil, nil)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, body)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, resp.Body)
+	return errors.Join(err, file.Close())
+}
+
+// checkSnapshot opens a downloaded snapshot and verifies every page in it,
+// since a stream cut off partway through can leave intact metadata pointing at
+// missing pages.
+func checkSnapshot(path string) error {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{ReadOnly: true, Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return db.View(func(tx *bolt.Tx) error {
+		// Reading pages past the end of a truncated file would crash rather
+		// than fail, so check its size first.
+		if info.Size() < tx.Size() {
+			return fmt.Errorf("got %d of %d bytes", info.Size(), tx.Size())
+		}
+		var errs []error
+		for err := range tx.Check() {
+			errs = append(errs, err)
+		}
+		return errors.Join(errs...)
+	})
+}
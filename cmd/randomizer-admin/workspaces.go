@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var listDeletionsCmd = &cobra.Command{
+	Use:   "list-deletions",
+	Short: "List workspaces whose data is scheduled for deletion after uninstalling",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		callAdmin(http.MethodGet, "deletions", nil)
+	},
+}
+
+var exportWorkspaceCmd = &cobra.Command{
+	Use:   "export-workspace TEAM",
+	Short: "Print every group, setting, and history record of a Slack workspace",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		callAdmin(http.MethodGet, "workspaces/"+url.PathEscape(args[0])+"/export", nil)
+	},
+}
+
+var showSettingsCmd = &cobra.Command{
+	Use:   "show-settings TEAM",
+	Short: "Print the selection settings of a Slack workspace at each level",
+	Long: `Print the selection settings configured for a Slack workspace at each
+level, from the deployment's defaults down to a single user, and the settings
+that apply with the level that each one comes from. Without --channel or
+--user, only the deployment's and workspace's levels are included.`,
+	Example: `  randomizer-admin show-settings T12345678 --channel C12345678 --user U12345678`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		query := url.Values{}
+		if settingsChannel != "" {
+			query.Set("channel", settingsChannel)
+		}
+		if settingsUser != "" {
+			query.Set("user", settingsUser)
+		}
+		out := requestAdmin(http.MethodGet, "workspaces/"+url.PathEscape(args[0])+"/settings", query, nil)
+		fmt.Println(string(out))
+	},
+}
+
+var settingsChannel, settingsUser string
+
+var deleteWorkspaceCmd = &cobra.Command{
+	Use:   "delete-workspace TEAM",
+	Short: "Delete the data in every channel of a Slack workspace",
+	Long: `Delete the data in every channel of a Slack workspace, and cancel any
+scheduled deletion of it.
+
+This permanently removes data, and requires --yes to confirm.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if !deleteConfirmed {
+			fmt.Fprintln(os.Stderr, "refusing to delete data without --yes")
+			os.Exit(2)
+		}
+		callAdmin(http.MethodDelete, "workspaces/"+url.PathEscape(args[0]), nil)
+	},
+}
+
+var purgeUninstalledCmd = &cobra.Command{
+	Use:   "purge-uninstalled",
+	Short: "Export and delete the data of workspaces whose grace period has ended",
+	Long: `Export and delete the data of every workspace that uninstalled the app
+and has reached the end of its grace period.
+
+Each workspace's data is written to <TEAM>.json in --export-dir before it's
+deleted, so run this from a trusted machine and handle the exports according
+to your data retention policy. Scheduling this command (e.g. daily) completes
+the cleanup that an uninstall begins.
+
+This permanently removes data, and requires --yes to confirm.`,
+	Args: cobra.NoArgs,
+	Run:  runPurgeUninstalled,
+}
+
+var exportDir string
+
+func init() {
+	showSettingsCmd.Flags().StringVar(&settingsChannel, "channel", "", "channel ID to include the settings of")
+	showSettingsCmd.Flags().StringVar(&settingsUser, "user", "", "user ID to include the settings of")
+
+	deleteWorkspaceCmd.Flags().BoolVar(
+		&deleteConfirmed,
+		"yes", false,
+		"confirm permanent deletion of the workspace's data",
+	)
+	purgeUninstalledCmd.Flags().BoolVar(
+		&deleteConfirmed,
+		"yes", false,
+		"confirm permanent deletion of each workspace's data",
+	)
+	purgeUninstalledCmd.Flags().StringVar(
+		&exportDir,
+		"export-dir", "",
+		"directory to write each workspace's data to before deleting it (required)",
+	)
+	purgeUninstalledCmd.MarkFlagRequired("export-dir")
+
+	rootCmd.AddCommand(
+		listDeletionsCmd,
+		exportWorkspaceCmd,
+		showSettingsCmd,
+		deleteWorkspaceCmd,
+		purgeUninstalledCmd,
+	)
+}
+
+func runPurgeUninstalled(cmd *cobra.Command, args []string) {
+	if !deleteConfirmed {
+		fmt.Fprintln(os.Stderr, "refusing to delete data without --yes")
+		os.Exit(2)
+	}
+
+	var pending struct {
+		Deletions []struct {
+			Team string    `json:"team"`
+			Due  time.Time `json:"due"`
+		} `json:"deletions"`
+	}
+	if err := json.Unmarshal(requestAdmin(http.MethodGet, "deletions", nil, nil), &pending); err != nil {
+		fmt.Fprintf(os.Stderr, "could not read deletions: %v\n", err)
+		os.Exit(1)
+	}
+
+	var purged int
+	for _, d := range pending.Deletions {
+		if d.Due.After(time.Now()) {
+			continue
+		}
+
+		export := requestAdmin(http.MethodGet, "workspaces/"+url.PathEscape(d.Team)+"/export", nil, nil)
+		path := filepath.Join(exportDir, d.Team+".json")
+		if err := os.WriteFile(path, append(export, '\n'), 0o600); err != nil {
+			fmt.Fprintf(os.Stderr, "could not write export for %s: %v\n", d.Team, err)
+			os.Exit(1)
+		}
+
+		requestAdmin(http.MethodDelete, "workspaces/"+url.PathEscape(d.Team), nil, nil)
+		fmt.Printf("exported %s to %s and deleted its data\n", d.Team, path)
+		purged++
+	}
+	fmt.Printf("purged %d of %d pending workspaces\n", purged, len(pending.Deletions))
+}
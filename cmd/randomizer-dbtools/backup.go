@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Write every partition's groups and records to a JSON Lines file",
+	Long: `Write every partition's groups and records to a JSON Lines file, with one
+group or record per line, in the same format as "randomizer-admin export-data".
+
+The store and its encryption are configured from the environment, the same way
+as the randomizer itself. A bbolt database can only be opened by one process at
+a time, so stop the server first, or use "randomizer-admin snapshot" to copy
+the database of a running server.`,
+	Args: cobra.NoArgs,
+	Run:  runBackup,
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Save every group and record from a JSON Lines file",
+	Long: `Save every group and record from a JSON Lines file written by "backup" or by
+"randomizer-admin export-data", replacing any that already exist.
+
+By default, restore refuses to write to a store that already has data, so that
+a backup can't be mixed into the wrong deployment by accident. Groups that
+aren't in the file are never deleted.`,
+	Args: cobra.NoArgs,
+	Run:  runRestore,
+}
+
+var (
+	backupOut        string
+	restoreIn        string
+	restoreOverwrite bool
+	restoreFileDry   bool
+)
+
+func init() {
+	backupCmd.Flags().StringVarP(&backupOut, "out", "o", "-", `file to write the backup to, or "-" for stdout`)
+
+	restoreCmd.Flags().StringVarP(&restoreIn, "in", "i", "", `file to restore from, or "-" for stdin (required)`)
+	restoreCmd.MarkFlagRequired("in")
+	restoreCmd.Flags().BoolVar(&restoreOverwrite, "overwrite", false, "restore into a store that already has data")
+	restoreCmd.Flags().BoolVar(&restoreFileDry, "dry-run", false, "print what would be restored without writing it")
+
+	rootCmd.AddCommand(backupCmd, restoreCmd)
+}
+
+// backupLine is a single group or record in a backup. Exports from the admin
+// API add a cursor, which restore ignores.
+type backupLine struct {
+	Partition string   `json:"partition"`
+	Group     string   `json:"group"`
+	Options   []string `json:"options"`
+	Error     string   `json:"error,omitempty"`
+}
+
+type partitionLister interface {
+	Partitions(ctx context.Context) ([]string, error)
+}
+
+func runBackup(cmd *cobra.Command, args []string) {
+	ctx := context.Background()
+	storeFactory, err := baseFactoryFromEnv(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	lister, ok := storeFactory("backup").(partitionLister)
+	if !ok {
+		fmt.Fprintln(os.Stderr, "store backend can't list partitions")
+		os.Exit(2)
+	}
+
+	var out io.Writer = os.Stdout
+	if backupOut != "-" {
+		file, err := os.OpenFile(backupOut, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not create backup file: %v\n", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		out = file
+	}
+	buf := bufio.NewWriter(out)
+	enc := json.NewEncoder(buf)
+
+	partitions, err := lister.Partitions(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not list partitions: %v\n", err)
+		os.Exit(1)
+	}
+	slices.Sort(partitions)
+
+	var lines int
+	for _, partition := range partitions {
+		store := storeFactory(partition)
+		groups, err := store.List(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not list groups in %q: %v\n", partition, err)
+			os.Exit(1)
+		}
+		slices.Sort(groups)
+		for _, group := range groups {
+			options, err := store.Get(ctx, group)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "could not get %q in %q: %v\n", group, partition, err)
+				os.Exit(1)
+			}
+			if err := enc.Encode(backupLine{Partition: partition, Group: group, Options: options}); err != nil {
+				fmt.Fprintf(os.Stderr, "could not write backup: %v\n", err)
+				os.Exit(1)
+			}
+			lines++
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "could not write backup: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "backed up %d groups and records from %d partitions\n", lines, len(partitions))
+}
+
+func runRestore(cmd *cobra.Command, args []string) {
+	ctx := context.Background()
+	storeFactory, err := baseFactoryFromEnv(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if !restoreOverwrite && !restoreFileDry {
+		lister, ok := storeFactory("restore").(partitionLister)
+		if !ok {
+			fmt.Fprintln(os.Stderr, "store backend can't list partitions to check that it's empty; use --overwrite to restore anyway")
+			os.Exit(2)
+		}
+		if existing, err := lister.Partitions(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "could not list partitions: %v\n", err)
+			os.Exit(1)
+		} else if len(existing) > 0 {
+			fmt.Fprintf(os.Stderr, "store already has data in %d partitions; use --overwrite to restore anyway\n", len(existing))
+			os.Exit(2)
+		}
+	}
+
+	var in io.Reader = os.Stdin
+	if restoreIn != "-" {
+		file, err := os.Open(restoreIn)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not open backup file: %v\n", err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		in = file
+	}
+
+	n, err := restoreLines(in, func(line backupLine) error {
+		if restoreFileDry {
+			fmt.Printf("would restore %q in %q (%d items)\n", line.Group, line.Partition, len(line.Options))
+			return nil
+		}
+		return storeFactory(line.Partition).Put(ctx, line.Group, line.Options)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "restore failed after %d groups and records: %v\n", n, err)
+		os.Exit(1)
+	}
+	if restoreFileDry {
+		fmt.Printf("dry run: would restore %d groups and records\n", n)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "restored %d groups and records\n", n)
+}
+
+// restoreLines calls save for each line of a backup, and returns the number
+// of lines it saved.
+func restoreLines(r io.Reader, save func(backupLine) error) (n int, err error) {
+	dec := json.NewDecoder(r)
+	for {
+		var line backupLine
+		err := dec.Decode(&line)
+		if errors.Is(err, io.EOF) {
+			return n, nil
+		}
+		if err != nil {
+			return n, fmt.Errorf("reading line %d: %w", n+1, err)
+		}
+		if line.Error != "" {
+			// An export that failed partway through ends with its error.
+			return n, fmt.Errorf("backup is incomplete: %s", line.Error)
+		}
+		if line.Partition == "" || line.Group == "" {
+			return n, fmt.Errorf("line %d is missing a partition or group", n+1)
+		}
+		if err := save(line); err != nil {
+			return n, fmt.Errorf("saving %q in %q: %w", line.Group, line.Partition, err)
+		}
+		n++
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/store/bench"
+	"github.com/featherbread/randomizer/internal/store/encrypted"
+	"github.com/featherbread/randomizer/internal/store/eventlog"
+	"github.com/featherbread/randomizer/internal/store/registry"
+	"github.com/featherbread/randomizer/internal/store/schema"
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Measure the performance of store backends",
+}
+
+var benchStoreCmd = &cobra.Command{
+	Use:   "store BACKEND BACKEND",
+	Short: "Compare the latency and cost of two store backends",
+	Long: `Compare the latency and cost of two store backends.
+
+Each backend is configured from its own environment variables, the same way as
+the randomizer itself, so the environment needs settings for both. Encryption,
+if configured, applies to both. The workload saves, reads, lists, edits, and
+deletes groups in a fresh partition whose name starts with "` + bench.PartitionPrefix + `",
+and cleans up after itself.
+
+The bytes column counts the option text read and written, which drives the
+cost of most hosted backends.`,
+	Args: cobra.ExactArgs(2),
+	Run:  runBenchStore,
+}
+
+var benchWorkload bench.Workload
+
+func init() {
+	benchStoreCmd.Flags().IntVar(&benchWorkload.Groups, "groups", bench.DefaultGroups, "number of groups to work on")
+	benchStoreCmd.Flags().IntVar(&benchWorkload.Options, "options", bench.DefaultOptions, "number of options in each group")
+	benchStoreCmd.Flags().IntVar(&benchWorkload.Rounds, "rounds", bench.DefaultRounds, "number of times to run through the groups")
+	benchStoreCmd.Flags().IntVar(&benchWorkload.Concurrency, "concurrency", bench.DefaultConcurrency, "number of groups to work on at once")
+
+	benchCmd.AddCommand(benchStoreCmd)
+	rootCmd.AddCommand(benchCmd)
+}
+
+func runBenchStore(cmd *cobra.Command, args []string) {
+	ctx := context.Background()
+	if args[0] == args[1] {
+		fmt.Fprintln(os.Stderr, "name two different backends to compare")
+		os.Exit(2)
+	}
+
+	reports := make([]bench.Report, len(args))
+	for i, name := range args {
+		factory, err := backendFactoryFromEnv(ctx, name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "benchmarking %s...\n", name)
+		reports[i], err = bench.Run(ctx, factory, benchWorkload)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not benchmark %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		if err := reports[i].FirstError; err != nil {
+			fmt.Fprintf(os.Stderr, "%s returned errors, starting with: %v\n", name, err)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "op\tbackend\tcalls\terrors\tbytes\tmean\tp50\tp95\tp99\tmax\t\n")
+	for _, op := range reports[0].Ops {
+		for i, report := range reports {
+			s := report.Op(op.Op)
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t\n",
+				s.Op, args[i], s.Calls, s.Errors, s.Bytes,
+				round(s.Mean), round(s.P50), round(s.P95), round(s.P99), round(s.Max))
+		}
+	}
+	w.Flush()
+
+	fmt.Println()
+	for i, report := range reports {
+		fmt.Printf("%s: %d calls in %s (%.0f calls/s)\n",
+			args[i], report.Calls(), round(report.Elapsed), float64(report.Calls())/report.Elapsed.Seconds())
+	}
+	a, b := reports[0].Op(bench.OpGet).P95, reports[1].Op(bench.OpGet).P95
+	if a > 0 && b > 0 {
+		fmt.Printf("%s's p95 read latency is %.2fx %s's\n", args[1], float64(b)/float64(a), args[0])
+	}
+}
+
+// backendFactoryFromEnv returns a factory for the named store backend,
+// wrapped the same way as in the randomizer, even if the environment also
+// configures other backends.
+func backendFactoryFromEnv(ctx context.Context, name string) (func(string) randomizer.Store, error) {
+	entry, ok := registry.Registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown store backend %q (available: %v)", name, slices.Sorted(maps.Keys(registry.Registry)))
+	}
+	factory, err := entry.FactoryFromEnv(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not create %s store: %w", name, err)
+	}
+	factory, err = encrypted.WrapFromEnv(factory)
+	if err != nil {
+		return nil, fmt.Errorf("could not configure store encryption: %w", err)
+	}
+	return eventlog.Wrap(schema.Wrap(factory), nil), nil
+}
+
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	default:
+		return d.Round(time.Microsecond)
+	}
+}
@@ -24,7 +24,10 @@ schema, e.g. by using "randomizer-dbtools dynamodb create".
 	Run: runDynamoDBImportBolt,
 }
 
-var boltDBFile string
+var (
+	boltDBFile   string
+	importDryRun bool
+)
 
 func init() {
 	dynamoImportBoltCmd.Flags().StringVarP(
@@ -34,6 +37,12 @@ func init() {
 	)
 	dynamoImportBoltCmd.MarkFlagRequired("file")
 
+	dynamoImportBoltCmd.Flags().BoolVar(
+		&importDryRun,
+		"dry-run", false,
+		"print the groups that would be imported without writing them",
+	)
+
 	dynamoDBCmd.AddCommand(dynamoImportBoltCmd)
 }
 
@@ -44,8 +53,6 @@ func runDynamoDBImportBolt(cmd *cobra.Command, args []string) {
 		os.Exit(2)
 	}
 
-	dynamoDB := getDynamoDB()
-
 	writeRequests := make([]types.WriteRequest, 0)
 	err = boltDB.View(func(tx *bolt.Tx) error {
 		return tx.ForEach(func(partition []byte, b *bolt.Bucket) error {
@@ -62,12 +69,22 @@ func runDynamoDBImportBolt(cmd *cobra.Command, args []string) {
 					return fmt.Errorf("decoding items for %q in %q: %w", groupStr, partitionStr, err)
 				}
 
+				if importDryRun {
+					fmt.Printf("would import %q in %q (%d items)\n", groupStr, partitionStr, len(items))
+				}
+
+				// Lists preserve the order of the options, unlike string sets.
+				list := make([]types.AttributeValue, len(items))
+				for i, item := range items {
+					list[i] = &types.AttributeValueMemberS{Value: item}
+				}
+
 				writeRequests = append(writeRequests, types.WriteRequest{
 					PutRequest: &types.PutRequest{
 						Item: map[string]types.AttributeValue{
 							"Partition": &types.AttributeValueMemberS{Value: partitionStr},
 							"Group":     &types.AttributeValueMemberS{Value: groupStr},
-							"Items":     &types.AttributeValueMemberSS{Value: items},
+							"Items":     &types.AttributeValueMemberL{Value: list},
 						},
 					},
 				})
@@ -81,6 +98,12 @@ func runDynamoDBImportBolt(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	if importDryRun {
+		fmt.Printf("dry run: would write %d groups to %q\n", len(writeRequests), dynamoDBTable)
+		return
+	}
+
+	dynamoDB := getDynamoDB()
 	_, err = dynamoDB.BatchWriteItem(context.Background(), &dynamodb.BatchWriteItemInput{
 		RequestItems: map[string][]types.WriteRequest{
 			dynamoDBTable: writeRequests,
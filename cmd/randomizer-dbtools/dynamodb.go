@@ -5,10 +5,11 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/spf13/cobra"
+
+	dynamodbstore "github.com/featherbread/randomizer/internal/store/dynamodb"
 )
 
 var dynamoDBCmd = &cobra.Command{
@@ -34,8 +35,8 @@ func init() {
 
 	dynamoDBCmd.PersistentFlags().StringVarP(
 		&dynamoDBEndpoint,
-		"endpoint", "e", "",
-		"endpoint URL for DynamoDB API requests",
+		"endpoint", "e", dynamodbstore.EndpointFromEnv(),
+		"endpoint URL for DynamoDB API requests (default from $DYNAMODB_ENDPOINT_URL)",
 	)
 
 	rootCmd.AddCommand(dynamoDBCmd)
@@ -47,9 +48,5 @@ func getDynamoDB() *dynamodb.Client {
 		fmt.Fprintf(os.Stderr, "could not load AWS config: %v\n", err)
 		os.Exit(2)
 	}
-	return dynamodb.NewFromConfig(cfg, func(opts *dynamodb.Options) {
-		if dynamoDBEndpoint != "" {
-			opts.BaseEndpoint = aws.String(dynamoDBEndpoint)
-		}
-	})
+	return dynamodbstore.NewClient(cfg, dynamoDBEndpoint)
 }
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/featherbread/randomizer/internal/store/eventlog"
+	"github.com/featherbread/randomizer/internal/store/schema"
+)
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Inspect and replay the logged changes to groups",
+	Long: `Inspect and replay the logged changes to groups.
+
+With EVENT_LOG=1, the randomizer logs the most recent changes to each group, so
+that a group can be rebuilt as it was at any point in its recent past. The store and its
+encryption are configured from the environment, the same way as the randomizer
+itself.`,
+}
+
+var eventsListCmd = &cobra.Command{
+	Use:   "list PARTITION GROUP",
+	Short: "Print the logged changes to a group",
+	Args:  cobra.ExactArgs(2),
+	Run:   runEventsList,
+}
+
+var eventsRestoreCmd = &cobra.Command{
+	Use:   "restore PARTITION GROUP",
+	Short: "Restore a group as it was at a past time",
+	Long: `Restore a group as it was at a past time.
+
+The restoration is logged like any other change, so it can be undone by
+restoring the group again.`,
+	Args: cobra.ExactArgs(2),
+	Run:  runEventsRestore,
+}
+
+var (
+	restoreAt     string
+	restoreDryRun bool
+)
+
+func init() {
+	eventsRestoreCmd.Flags().StringVar(
+		&restoreAt,
+		"at", "",
+		"time to restore the group to, in RFC 3339 format (required)",
+	)
+	eventsRestoreCmd.MarkFlagRequired("at")
+
+	eventsRestoreCmd.Flags().BoolVar(
+		&restoreDryRun,
+		"dry-run", false,
+		"print the restored options without saving them",
+	)
+
+	eventsCmd.AddCommand(eventsListCmd)
+	eventsCmd.AddCommand(eventsRestoreCmd)
+	rootCmd.AddCommand(eventsCmd)
+}
+
+func eventStore(ctx context.Context, partition string) eventlog.Store {
+	storeFactory, err := baseFactoryFromEnv(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	return eventlog.Wrap(schema.WrapFromEnv(storeFactory), nil)(partition).(eventlog.Store)
+}
+
+func runEventsList(cmd *cobra.Command, args []string) {
+	ctx := context.Background()
+	events, err := eventStore(ctx, args[0]).Events(ctx, args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not read events: %v\n", err)
+		os.Exit(1)
+	}
+	for _, e := range events {
+		switch {
+		case e.Deleted:
+			fmt.Printf("%s\tdeleted\n", e.At.Format(time.RFC3339Nano))
+		case e.Unlogged:
+			fmt.Printf("%s\tsaved with too many options to log\n", e.At.Format(time.RFC3339Nano))
+		default:
+			fmt.Printf("%s\tsaved\t%s\n", e.At.Format(time.RFC3339Nano), strings.Join(e.Options, ", "))
+		}
+	}
+}
+
+func runEventsRestore(cmd *cobra.Command, args []string) {
+	at, err := time.Parse(time.RFC3339Nano, restoreAt)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid time: %v\n", err)
+		os.Exit(2)
+	}
+
+	ctx := context.Background()
+	store := eventStore(ctx, args[0])
+	var options []string
+	if restoreDryRun {
+		options, err = store.StateAt(ctx, args[1], at)
+	} else {
+		options, err = store.Restore(ctx, args[1], at)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not restore %q: %v\n", args[1], err)
+		os.Exit(1)
+	}
+
+	verb := "restored"
+	if restoreDryRun {
+		verb = "would restore"
+	}
+	if len(options) == 0 {
+		fmt.Printf("%s %q as deleted\n", verb, args[1])
+		return
+	}
+	fmt.Printf("%s %q with options: %s\n", verb, args[1], strings.Join(options, ", "))
+}
@@ -3,10 +3,15 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/store"
+	"github.com/featherbread/randomizer/internal/store/encrypted"
 )
 
 var rootCmd = &cobra.Command{
@@ -19,3 +24,18 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// baseFactoryFromEnv returns the randomizer's store factory as configured by
+// the environment, with encryption if configured, for tools that wrap it
+// further.
+func baseFactoryFromEnv(ctx context.Context) (func(string) randomizer.Store, error) {
+	storeFactory, err := store.FactoryFromEnv(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not create store: %w", err)
+	}
+	storeFactory, err = encrypted.WrapFromEnv(storeFactory)
+	if err != nil {
+		return nil, fmt.Errorf("could not configure store encryption: %w", err)
+	}
+	return storeFactory, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/featherbread/randomizer/internal/store/schema"
+)
+
+var migrateSchemaCmd = &cobra.Command{
+	Use:   "migrate-schema [partition...]",
+	Short: "Save every stored item in the current schema version",
+	Long: `Save every stored item in the current schema version.
+
+The randomizer upgrades items saved in older schema versions as it reads them,
+so running this command is never required. It's useful for upgrading every
+item ahead of a release that drops support for an older version.
+
+The store and its encryption are configured from the environment, the same way
+as the randomizer itself. Without any partition arguments, every partition is
+migrated, if the store can list them.
+
+With --down, it instead saves every item without its schema marker, in the
+format of the first version, so that a build from before schema versions
+existed can read it. Turn SCHEMA_VERSIONS off before migrating down, so that
+the randomizer doesn't add the markers back.
+
+Items written while the migration runs may lose that write, so it's best to
+migrate while the randomizer is quiet.`,
+	Run: runMigrateSchema,
+}
+
+var (
+	migrateDryRun bool
+	migrateDown   bool
+)
+
+func init() {
+	migrateSchemaCmd.Flags().BoolVar(
+		&migrateDryRun,
+		"dry-run", false,
+		"print the items that would be migrated without writing them",
+	)
+	migrateSchemaCmd.Flags().BoolVar(
+		&migrateDown,
+		"down", false,
+		"remove schema markers for a build from before schema versions existed",
+	)
+
+	rootCmd.AddCommand(migrateSchemaCmd)
+}
+
+func runMigrateSchema(cmd *cobra.Command, args []string) {
+	ctx := context.Background()
+
+	storeFactory, err := baseFactoryFromEnv(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	storeFactory = schema.Wrap(storeFactory)
+
+	partitions := args
+	if len(partitions) == 0 {
+		lister := storeFactory("").(schema.Store)
+		partitions, err = lister.Partitions(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not list partitions (try naming them instead): %v\n", err)
+			os.Exit(2)
+		}
+	}
+
+	var total int
+	for _, partition := range partitions {
+		s := storeFactory(partition).(schema.Store)
+		var names []string
+		switch {
+		case migrateDown && migrateDryRun:
+			names, err = s.Marked(ctx)
+		case migrateDown:
+			names, err = s.Downgrade(ctx)
+		case migrateDryRun:
+			names, err = s.Outdated(ctx)
+		default:
+			names, err = s.Migrate(ctx)
+		}
+		for _, name := range names {
+			if migrateDryRun {
+				fmt.Printf("would migrate %q in %q\n", name, partition)
+			} else {
+				fmt.Printf("migrated %q in %q\n", name, partition)
+			}
+		}
+		total += len(names)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not migrate %q: %v\n", partition, err)
+			os.Exit(1)
+		}
+	}
+	verb := "migrated"
+	if migrateDryRun {
+		verb = "would migrate"
+	}
+	version := schema.CurrentVersion
+	if migrateDown {
+		version = 1
+	}
+	fmt.Printf("%s %d items in %d partitions to schema version %d\n", verb, total, len(partitions), version)
+}
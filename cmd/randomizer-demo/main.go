@@ -12,15 +12,23 @@
 // the randomizer. Note that this allows the demo CLI to exhibit behaviors not
 // normally possible with the slash command, such as randomizing or storing
 // options containing whitespace.
+//
+// With "--every <duration>", the demo CLI keeps running and repeats the same
+// selection on that schedule, as a personal decision aid. "--notify desktop"
+// shows each result as a desktop notification rather than printing it.
 package main
 
 import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
 
 	"github.com/featherbread/randomizer/internal/randomizer"
 	"github.com/featherbread/randomizer/internal/store"
+	"github.com/featherbread/randomizer/internal/store/encrypted"
+	"github.com/featherbread/randomizer/internal/store/eventlog"
+	"github.com/featherbread/randomizer/internal/store/schema"
 )
 
 func main() {
@@ -30,13 +38,56 @@ func main() {
 		os.Exit(2)
 	}
 
-	app := randomizer.NewApp(os.Args[0], storeFactory("Groups"))
-	result, err := app.Main(context.Background(), os.Args[1:])
+	storeFactory, err = encrypted.WrapFromEnv(storeFactory)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to configure store encryption: %v\n", err)
+		os.Exit(2)
+	}
+	storeFactory = eventlog.WrapFromEnv(schema.WrapFromEnv(storeFactory), nil)
+
+	watchCfg, args, err := extractWatchFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	backend, _ := store.BackendFromEnv() // Checked with the store's configuration
+	app := randomizer.NewApp(os.Args[0], storeFactory("Groups"),
+		randomizer.WithDeployment(randomizer.Deployment{Store: backend}))
+	if watchCfg.Every == 0 {
+		message, err := pick(app, args)
+		if err != nil {
+			os.Exit(1)
+		}
+		fmt.Println(message)
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := watch(ctx, watchCfg, func() (string, error) { return pick(app, args) }); err != nil {
+		os.Exit(1)
+	}
+}
+
+// pick runs the randomizer once, reporting any error to stderr.
+func pick(app randomizer.App, args []string) (string, error) {
+	result, err := app.Main(context.Background(), args)
 	if err != nil {
 		err := err.(randomizer.Error)
 		fmt.Fprintln(os.Stderr, err.HelpText())
 		fmt.Fprintf(os.Stderr, "(%v)\n", err)
-		os.Exit(1)
+		return "", err
+	}
+
+	message := result.Message()
+	if event := result.Event(); event != nil {
+		const eventPath = "randomizer-event.ics"
+		if err := os.WriteFile(eventPath, []byte(event.ICS()), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write calendar event: %v\n", err)
+			return "", err
+		}
+		message += fmt.Sprintf("\n(Saved calendar event to %s)", eventPath)
 	}
-	fmt.Println(result.Message())
+	return message, nil
 }
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// watchConfig holds the options for watch mode, which repeats a selection on a
+// schedule for as long as the demo CLI keeps running.
+//
+// These options belong to the demo CLI itself, so they're removed from the
+// arguments before the rest are passed to the randomizer.
+type watchConfig struct {
+	Every  time.Duration
+	Notify string
+}
+
+// extractWatchFlags removes "--every" and "--notify" from args, accepting both
+// "--flag=value" and "--flag value" forms. Arguments after a "--" terminator
+// are left alone.
+func extractWatchFlags(args []string) (watchConfig, []string, error) {
+	cfg := watchConfig{Notify: "stdout"}
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if !strings.HasPrefix(arg, "--") || (name != "every" && name != "notify") {
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return watchConfig{}, nil, fmt.Errorf("--%s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+
+		switch name {
+		case "every":
+			every, err := time.ParseDuration(value)
+			if err != nil || every <= 0 {
+				return watchConfig{}, nil, fmt.Errorf("--every must be a positive duration like 24h, not %q", value)
+			}
+			cfg.Every = every
+		case "notify":
+			if value != "stdout" && value != "desktop" {
+				return watchConfig{}, nil, fmt.Errorf(`--notify must be "stdout" or "desktop", not %q`, value)
+			}
+			cfg.Notify = value
+		}
+	}
+
+	if cfg.Every == 0 && cfg.Notify != "stdout" {
+		return watchConfig{}, nil, errors.New("--notify requires --every")
+	}
+	return cfg, rest, nil
+}
+
+// watch runs pick immediately and then on every tick until ctx ends.
+func watch(ctx context.Context, cfg watchConfig, pick func() (string, error)) error {
+	ticker := time.NewTicker(cfg.Every)
+	defer ticker.Stop()
+
+	for {
+		message, err := pick()
+		if err != nil {
+			return err
+		}
+		notify(cfg.Notify, message)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// notify shows the result of a scheduled selection, falling back to stdout if
+// a desktop notification can't be shown.
+func notify(method, message string) {
+	stamp := time.Now().Format(time.DateTime)
+	if method == "desktop" {
+		err := desktopNotification("Randomizer", plainText(message))
+		if err == nil {
+			fmt.Printf("[%s] (Sent desktop notification)\n", stamp)
+			return
+		}
+		fmt.Printf("[%s] (Desktop notification failed: %v)\n", stamp, err)
+	}
+	fmt.Printf("[%s] %s\n", stamp, message)
+}
+
+func desktopNotification(title, body string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title %q", body, title)
+		cmd = exec.Command("osascript", "-e", script)
+	case "windows":
+		script := fmt.Sprintf(
+			`Add-Type -AssemblyName System.Windows.Forms; `+
+				`$n = New-Object System.Windows.Forms.NotifyIcon; `+
+				`$n.Icon = [System.Drawing.SystemIcons]::Information; $n.Visible = $true; `+
+				`$n.ShowBalloonTip(10000, '%s', '%s', 'Info'); Start-Sleep -Seconds 10`,
+			strings.ReplaceAll(title, "'", "''"), strings.ReplaceAll(body, "'", "''"))
+		cmd = exec.Command("powershell", "-NoProfile", "-Command", script)
+		return cmd.Start()
+	default:
+		cmd = exec.Command("notify-send", title, body)
+	}
+	return cmd.Run()
+}
+
+// plainText strips the mrkdwn emphasis from a result for display outside of
+// Slack.
+func plainText(message string) string {
+	return strings.NewReplacer("*", "", "_", "").Replace(message)
+}
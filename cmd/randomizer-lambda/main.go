@@ -6,6 +6,13 @@
 // [Lambda function URL], or through an AWS Lambda proxy integration in an
 // Amazon API Gateway HTTP API.
 //
+// The handler also accepts the scheduled events that Amazon EventBridge sends
+// on a schedule rule, which sweep the schedules that channels register with
+// /schedule when SLACK_SCHEDULES is "1". A rule that invokes the function every
+// minute posts each pick within a minute of its scheduled time. A rule with the
+// constant input {"keep-warm": true} keeps the function warm, with pings to
+// KEEP_WARM_URL if KEEP_WARM_CONCURRENCY asks for more than one environment.
+//
 // See the randomizer repository README for more information on configuring and
 // deploying the randomizer on AWS Lambda.
 //
@@ -15,10 +22,16 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"log/slog"
+	"net/http"
 	"os"
+	"sync"
+	"time"
+	_ "time/tzdata" // For the time zones of schedules, which deployment images may lack
 
 	"github.com/aws-observability/aws-otel-go/exporters/xrayudp"
+	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
 	lambdadetector "go.opentelemetry.io/contrib/detectors/aws/lambda"
@@ -31,15 +44,41 @@ import (
 	"go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 
+	"github.com/featherbread/randomizer/internal/alert"
+	"github.com/featherbread/randomizer/internal/awsconfig"
+	"github.com/featherbread/randomizer/internal/compress"
+	"github.com/featherbread/randomizer/internal/health"
+	"github.com/featherbread/randomizer/internal/moderation"
+	"github.com/featherbread/randomizer/internal/randomizer"
 	"github.com/featherbread/randomizer/internal/slack"
 	"github.com/featherbread/randomizer/internal/store/dynamodb"
+	"github.com/featherbread/randomizer/internal/store/encrypted"
+	"github.com/featherbread/randomizer/internal/store/eventlog"
+	"github.com/featherbread/randomizer/internal/store/schema"
+	"github.com/featherbread/randomizer/internal/tracing"
+	"github.com/featherbread/randomizer/internal/warm"
+	"github.com/featherbread/randomizer/internal/webhook"
+	"github.com/featherbread/randomizer/internal/workspace"
 )
 
 func main() {
 	ctx := context.Background()
 	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
 
-	tokenProvider, err := slack.TokenProviderFromEnv()
+	telemetry := initTraceResource(ctx, logger)
+	logs, err := tracing.LogsFromEnv(ctx, telemetry)
+	if err != nil {
+		logger.Error("Failed to configure log export", "err", err)
+		os.Exit(2)
+	}
+	logger = slog.New(logs.Handler(logger.Handler()))
+
+	// Do the work that every request needs during initialization, which
+	// SnapStart-style checkpoints capture, rather than on the first request.
+	// Work that only some invocations need may wait; see lazyInit.
+	awsconfig.Prime()
+
+	tokenProvider, signingSecret, err := slack.VerifiersFromEnv()
 	if err != nil {
 		logger.Error("Failed to configure Slack token", "err", err)
 		os.Exit(2)
@@ -51,12 +90,98 @@ func main() {
 		os.Exit(2)
 	}
 
-	var otellambdaOptions []otellambda.Option
+	storeFactory, err = encrypted.WrapFromEnv(storeFactory)
+	if err != nil {
+		logger.Error("Failed to configure store encryption", "err", err)
+		os.Exit(2)
+	}
+	storeFactory = eventlog.WrapFromEnv(schema.WrapFromEnv(storeFactory), logger)
+
+	botTokens, err := slack.BotTokenProviderFromEnv()
+	if err != nil {
+		logger.Error("Failed to configure Slack bot token", "err", err)
+		os.Exit(2)
+	}
+
+	installer, err := slack.InstallerFromEnv(slack.TokenStoreFromEnv(storeFactory))
+	if err != nil {
+		logger.Error("Failed to configure Slack installs", "err", err)
+		os.Exit(2)
+	}
+	var (
+		installations  slack.TokenStore
+		sharedBotToken = botTokens != nil
+	)
+	if installer != nil {
+		installer.Logger = logger
+		installations = installer.Tokens
+		botTokens = slack.InstalledBotTokens(installations, botTokens)
+	}
+
+	accessLog, err := slack.AccessLogFromEnv(logger)
+	if err != nil {
+		logger.Error("Failed to configure access logging", "err", err)
+		os.Exit(2)
+	}
+
+	alerts, err := alert.TrackerFromEnv(logger)
+	if err != nil {
+		logger.Error("Failed to configure alerting", "err", err)
+		os.Exit(2)
+	}
+
+	auditLog, err := slack.AuditFromEnv(ctx)
+	if err != nil {
+		logger.Error("Failed to configure audit trail", "err", err)
+		os.Exit(2)
+	}
+
+	budget, err := slack.BudgetFromEnv()
+	if err != nil {
+		logger.Error("Failed to configure response budget", "err", err)
+		os.Exit(2)
+	}
+
+	defaults, err := slack.DefaultsFromEnv()
+	if err != nil {
+		logger.Error("Failed to configure default operations", "err", err)
+		os.Exit(2)
+	}
+
+	contentCheck, err := moderation.CheckFromEnv()
+	if err != nil {
+		logger.Error("Failed to configure content checks", "err", err)
+		os.Exit(2)
+	}
+
+	randomness, err := randomizer.RandomnessFromName(os.Getenv("RANDOMNESS"))
+	if err != nil {
+		logger.Error("Failed to configure randomness", "err", err)
+		os.Exit(2)
+	}
+
+	pinger, err := warm.PingerFromEnv()
+	if err != nil {
+		logger.Error("Failed to configure keep-warm pings", "err", err)
+		os.Exit(2)
+	}
+
+	workspaces, err := workspace.RegistryFromEnv(storeFactory)
+	if err != nil {
+		logger.Error("Failed to configure workspace tracking", "err", err)
+		os.Exit(2)
+	}
+
+	var (
+		otellambdaOptions []otellambda.Option
+		flushers          tracing.Flushers
+	)
 	if xrayTracerProviderEnabled {
-		tp := initXRayTracerProvider(ctx, logger)
+		tp := initXRayTracerProvider(ctx, telemetry, budget, logger)
 		otel.SetTracerProvider(tp)
 		otel.SetTextMapPropagator(xraypropagator.Propagator{})
 		otellambdaOptions = xrayconfig.WithRecommendedOptions(tp)
+		flushers = append(flushers, tp)
 		defer func() {
 			err := tp.Shutdown(ctx)
 			if err != nil {
@@ -64,35 +189,246 @@ func main() {
 			}
 		}()
 	}
+	if logs != nil {
+		// Lambda may freeze the environment between invocations, so exported
+		// logs are flushed after each one along with any spans.
+		flushers = append(flushers, logs)
+		otellambdaOptions = append(otellambdaOptions, otellambda.WithFlusher(flushers))
+		defer func() {
+			err := logs.Shutdown(ctx)
+			if err != nil {
+				logger.Warn("Failed to shut down log export", "err", err)
+			}
+		}()
+	}
 
-	app := slack.App{
-		TokenProvider: tokenProvider,
-		StoreFactory:  storeFactory,
-		Logger:        logger,
+	opts := []slack.AppOption{
+		slack.WithRetryCache(slack.NewRetryCache(slack.DefaultRetryCacheTTL)),
+		slack.WithOnboarding(slack.NewOnboarding()),
+		slack.WithSigningSecret(signingSecret),
+		slack.WithRequireSignature(slack.TeamSetFromEnv("SLACK_REQUIRE_SIGNATURE_TEAMS")),
+		slack.WithAccessLog(accessLog),
+		slack.WithAudit(auditLog),
+		slack.WithAlerts(alerts),
+		slack.WithInstallations(installations),
+		slack.WithBudget(budget),
+		slack.WithWorkspaces(workspaces),
+		slack.WithPlainText(slack.TeamSetFromEnv("PLAIN_TEXT_TEAMS")),
+		slack.WithEmailExport(slack.TeamSetFromEnv("EMAIL_EXPORT_TEAMS")),
+		slack.WithDefaults(defaults),
+		slack.WithContentCheck(contentCheck),
+		slack.WithWebhookSender(webhook.SenderFromEnv()),
+		slack.WithRandomness(randomness),
+		slack.WithDeployment(randomizer.Deployment{Store: "dynamodb"}),
+		slack.WithPickAgain(os.Getenv("SLACK_PICK_AGAIN") == "1"),
+		slack.WithFederation(os.Getenv("SLACK_FEDERATION") == "1"),
+		slack.WithBotUserID(os.Getenv("SLACK_BOT_USER_ID")),
+		slack.WithLogger(logger),
 	}
-	httpHandler := otelhttp.NewHandler(app, "/")
-	adapterHandler := httpadapter.NewV2(httpHandler).ProxyWithContext
-	parentHandler := otellambda.InstrumentHandler(adapterHandler, otellambdaOptions...)
+	botClient := slack.WebClient{Tokens: botTokens}
+	if botTokens != nil {
+		opts = append(opts, slack.WithUserNames(&slack.UserNames{Client: botClient}))
+		if os.Getenv("SLACK_APP_HOME") == "1" {
+			opts = append(opts, slack.WithHome(&slack.Home{
+				Client:  botClient,
+				Command: os.Getenv("SLACK_COMMAND_NAME"),
+				Digests: os.Getenv("SLACK_DIGESTS") == "1",
+			}))
+		}
+		if os.Getenv("SLACK_UPLOAD_LARGE_RESULTS") == "1" {
+			opts = append(opts, slack.WithUploader(&slack.Uploader{Client: botClient}))
+		}
+		if os.Getenv("SLACK_SCHEDULES") == "1" {
+			opts = append(opts, slack.WithScheduler(&slack.Scheduler{Client: botClient}))
+		}
+	}
+	app := slack.NewApp(tokenProvider, storeFactory, opts...)
+	var checkScopes func(context.Context)
+	if sharedBotToken {
+		checkScopes = func(ctx context.Context) {
+			checkCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+			if err := app.CheckScopes(checkCtx, botClient); err != nil {
+				logger.Warn("Failed to check Slack bot token scopes", "err", err)
+			}
+		}
+		if !lazyInit {
+			// Checking scopes during initialization puts any warnings at the
+			// top of each new environment's logs, and adds nothing to request
+			// latency when no request waits for initialization.
+			checkScopes(ctx)
+			checkScopes = nil
+		}
+	}
+
+	// Lambda freezes the environment between requests, so rather than probing
+	// in the background, requests take turns probing once per interval.
+	prober := &health.Prober{Store: storeFactory(health.Partition), Timeout: lambdaProbeTimeout, Alerts: alerts, Logger: logger}
+	mux := http.NewServeMux()
+	mux.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prober.CheckIfDue(r.Context())
+		app.ServeHTTP(w, r)
+	}))
+	mux.Handle("GET /readyz", prober.ReadyHandler())
+	mux.Handle("GET "+warm.Path, warm.Handler())
+	if installer != nil {
+		mux.Handle("GET /slack/", installer.Handler())
+	}
+
+	handler := eventHandler{
+		Proxy:      proxyHandler(mux),
+		ColdStarts: &warm.ColdStarts{Logger: logger},
+	}
+	if checkScopes != nil {
+		var once sync.Once
+		handler.Deferred = func(ctx context.Context) { once.Do(func() { checkScopes(ctx) }) }
+	}
+	if botTokens != nil && os.Getenv("SLACK_SCHEDULES") == "1" {
+		handler.Sweep = func(ctx context.Context) error {
+			posted, err := app.SweepSchedules(ctx)
+			logger.Info("Swept schedules", "posted", posted)
+			return err
+		}
+	}
+	if pinger != nil && pinger.Concurrency > 1 {
+		// The invocation itself occupies one environment while its pings warm
+		// the others.
+		pinger.Concurrency--
+		handler.KeepWarm = pinger.Ping
+	}
+	parentHandler := otellambda.InstrumentHandler(reseedAfterRestore(handler.Handle), otellambdaOptions...)
 	lambda.Start(parentHandler)
 }
 
+// lambdaProbeTimeout bounds the store health checks that requests make, which
+// delay their responses.
+const lambdaProbeTimeout = time.Second
+
+// proxyHandler adapts an HTTP handler for the Slack API to serve API Gateway
+// payload format version 2.0 events.
+func proxyHandler(app http.Handler) func(context.Context, events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	httpHandler := otelhttp.NewHandler(compress.Handler(app, 0), "/")
+	return reseedAfterRestore(httpadapter.NewV2(httpHandler).ProxyWithContext)
+}
+
+// eventHandler serves the HTTP request events that Proxy handles, along with
+// the EventBridge events that sweep schedules and keep the function warm.
+type eventHandler struct {
+	Proxy func(context.Context, events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error)
+	// Sweep, if non-nil, handles the "Scheduled Event" events of EventBridge
+	// schedule rules.
+	Sweep func(context.Context) error
+	// KeepWarm, if non-nil, handles events with a true "keep-warm" field, like
+	// the constant input of a keep-warm schedule rule.
+	KeepWarm func(context.Context) error
+	// ColdStarts, if non-nil, logs what triggered the environment's first
+	// invocation.
+	ColdStarts *warm.ColdStarts
+	// Deferred, if non-nil, runs before each keep-warm or schedule event, to
+	// finish initialization that was put off until an invocation that no user
+	// waits on. It must return immediately after its first run.
+	Deferred func(context.Context)
+}
+
+func (h eventHandler) Handle(ctx context.Context, raw json.RawMessage) (any, error) {
+	var kind struct {
+		DetailType string `json:"detail-type"`
+		KeepWarm   bool   `json:"keep-warm"`
+		RawPath    string `json:"rawPath"`
+	}
+	json.Unmarshal(raw, &kind) // Anything else is left to the proxy.
+
+	if (kind.KeepWarm || kind.DetailType == "Scheduled Event") && h.Deferred != nil {
+		h.Deferred(ctx)
+	}
+
+	switch {
+	case kind.KeepWarm:
+		h.ColdStarts.Observe(warm.TriggerWarmUp)
+		if h.KeepWarm == nil {
+			return nil, nil // This invocation alone warmed the environment.
+		}
+		return nil, h.KeepWarm(ctx)
+	case kind.DetailType == "Scheduled Event":
+		h.ColdStarts.Observe(warm.TriggerSchedule)
+		if h.Sweep == nil {
+			return nil, nil
+		}
+		return nil, h.Sweep(ctx)
+	}
+
+	trigger := warm.TriggerRequest
+	if kind.RawPath == warm.Path {
+		trigger = warm.TriggerWarmUp
+	}
+	h.ColdStarts.Observe(trigger)
+	var event events.APIGatewayV2HTTPRequest
+	if err := json.Unmarshal(raw, &event); err != nil {
+		return nil, err
+	}
+	return h.Proxy(ctx, event)
+}
+
+// reseedAfterRestore wraps handler to reseed the randomizer on its first
+// invocation, if this environment may have been restored from a checkpoint.
+// Environments restored from the same checkpoint would otherwise share the
+// state of the random number generator, and make identical selections.
+func reseedAfterRestore[Req, Resp any](handler func(context.Context, Req) (Resp, error)) func(context.Context, Req) (Resp, error) {
+	if os.Getenv("AWS_LAMBDA_INITIALIZATION_TYPE") != "snap-start" {
+		return handler
+	}
+
+	return func(ctx context.Context, event Req) (Resp, error) {
+		reseedOnce.Do(randomizer.Reseed)
+		return handler(ctx, event)
+	}
+}
+
+// reseedOnce is shared by every handler that reseedAfterRestore wraps, so that
+// the first invocation of any kind reseeds the randomizer.
+var reseedOnce sync.Once
+
 // xrayTracerProviderEnabled indicates whether we should manually configure
 // OpenTelemetry to export spans to Lambda's X-Ray UDP collector. This could be
 // disabled if we don't want X-Ray tracing at all, or if we're configuring the
 // Auto SDK via eBPF (e.g. ADOT Lambda layers).
 var xrayTracerProviderEnabled = os.Getenv("AWS_XRAY_TRACER_PROVIDER_ENABLED") == "1"
 
-func initXRayTracerProvider(ctx context.Context, logger *slog.Logger) *trace.TracerProvider {
-	tp := trace.NewTracerProvider(
-		trace.WithResource(initTraceResource(ctx, logger)))
+// lazyInit indicates whether initialization is on the path of the first
+// request, as it is for on-demand environments (but not for provisioned
+// concurrency or SnapStart). If so, work that serving requests doesn't need is
+// put off until its first use, or until an invocation that no user waits on.
+var lazyInit = os.Getenv("AWS_LAMBDA_INITIALIZATION_TYPE") == "on-demand"
 
-	exporter, err := xrayudp.NewSpanExporter(ctx)
-	if err != nil {
-		logger.Warn("Failed to initialize X-Ray span exporter", "err", err)
-		return tp
+func initXRayTracerProvider(ctx context.Context, res *resource.Resource, budget *slack.Budget, logger *slog.Logger) *trace.TracerProvider {
+	tp := trace.NewTracerProvider(trace.WithResource(res))
+
+	newExporter := func(ctx context.Context) (trace.SpanExporter, error) {
+		exporter, err := xrayudp.NewSpanExporter(ctx)
+		if err != nil {
+			logger.Warn("Failed to initialize X-Ray span exporter", "err", err)
+			return nil, err
+		}
+		return exporter, nil
+	}
+	var exporter trace.SpanExporter
+	if lazyInit {
+		exporter = tracing.LazySpanExporter(newExporter)
+	} else {
+		var err error
+		if exporter, err = newExporter(ctx); err != nil {
+			return tp
+		}
 	}
 
-	tp.RegisterSpanProcessor(trace.NewSimpleSpanProcessor(exporter))
+	// Exporting spans synchronously keeps them from being lost when Lambda
+	// freezes the environment, but mustn't push a slow request past Slack's
+	// deadline.
+	processor := tracing.NewAdaptiveProcessor(exporter)
+	if budget != nil {
+		processor.DropAfter = budget.Limit()
+	}
+	tp.RegisterSpanProcessor(processor)
 	return tp
 }
 
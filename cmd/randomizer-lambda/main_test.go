@@ -1,12 +1,23 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+	"github.com/featherbread/randomizer/internal/slack"
+	"github.com/featherbread/randomizer/internal/slacktest"
 )
 
 func TestBannedPackages(t *testing.T) {
@@ -44,3 +55,81 @@ func TestBannedPackages(t *testing.T) {
 		t.Errorf("%s imports go.etcd.io/bbolt, even though it's for AWS", name)
 	}
 }
+
+func TestProxyHandler(t *testing.T) {
+	store := &rndtest.SyncStore{Store: rndtest.Store{}}
+	app := slack.NewApp(
+		slack.StaticToken("token"),
+		func(string) randomizer.Store { return store },
+		slack.WithSigningSecret(slack.StaticToken("secret")),
+	)
+	handler := proxyHandler(app)
+
+	params := slacktest.SlashCommand("/save lunch pizza tacos")
+	body := params.Encode()
+	timestamp, signature := slacktest.Sign("secret", []byte(body), time.Now())
+	event := events.APIGatewayV2HTTPRequest{
+		Version: "2.0",
+		RawPath: "/",
+		Body:    body,
+		Headers: map[string]string{
+			"content-type":              "application/x-www-form-urlencoded",
+			"x-slack-request-timestamp": timestamp,
+			"x-slack-signature":         signature,
+		},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: "POST", Path: "/"},
+		},
+	}
+
+	resp, err := handler(context.Background(), event)
+	if err != nil {
+		t.Fatalf("handler failed: %v", err)
+	}
+	if resp.StatusCode != 200 || !strings.Contains(resp.Body, `"in_channel"`) {
+		t.Errorf("unexpected response: %d %s", resp.StatusCode, resp.Body)
+	}
+	if got := store.Store["lunch"]; !slices.Equal(got, []string{"pizza", "tacos"}) {
+		t.Errorf("got saved group %v, want [pizza tacos]", got)
+	}
+}
+
+func TestEventHandler(t *testing.T) {
+	var swept, warmed, deferred int
+	handler := eventHandler{
+		Proxy: func(_ context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+			return events.APIGatewayV2HTTPResponse{StatusCode: 200, Body: event.RawPath}, nil
+		},
+		Sweep: func(context.Context) error {
+			swept++
+			return nil
+		},
+		KeepWarm: func(context.Context) error {
+			warmed++
+			return nil
+		},
+		Deferred: func(context.Context) { deferred++ },
+	}
+
+	if _, err := handler.Handle(context.Background(), json.RawMessage(`{"version": "2.0", "rawPath": "/"}`)); err != nil || deferred != 0 {
+		t.Errorf("HTTP event: ran deferred work %d times, %v; want 0 on a user's request", deferred, err)
+	}
+
+	scheduled := `{"version": "0", "source": "aws.events", "detail-type": "Scheduled Event", "detail": {}}`
+	if _, err := handler.Handle(context.Background(), json.RawMessage(scheduled)); err != nil || swept != 1 {
+		t.Errorf("scheduled event: got %d sweeps, %v; want 1", swept, err)
+	}
+
+	if _, err := handler.Handle(context.Background(), json.RawMessage(`{"keep-warm": true}`)); err != nil || warmed != 1 {
+		t.Errorf("keep-warm event: got %d warm-ups, %v; want 1", warmed, err)
+	}
+
+	if deferred != 2 {
+		t.Errorf("ran deferred work %d times, want once for each event that no user waits on", deferred)
+	}
+
+	resp, err := handler.Handle(context.Background(), json.RawMessage(`{"version": "2.0", "rawPath": "/readyz"}`))
+	if err != nil || resp.(events.APIGatewayV2HTTPResponse).Body != "/readyz" || swept != 1 || warmed != 1 {
+		t.Errorf("HTTP event: got %+v, %v after %d sweeps and %d warm-ups", resp, err, swept, warmed)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"net/http"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/slack"
+	"github.com/featherbread/randomizer/internal/store/memory"
+)
+
+var flagDemo = flag.Bool("demo", false,
+	"run an insecure demo with sample groups in memory (see /demo)")
+
+// demoToken is the verification token and admin API token in demo mode.
+const demoToken = "demo"
+
+// demoGroups are the sample groups available in every channel in demo mode.
+var demoGroups = map[string][]string{
+	"lunch":  {"sushi#cheap#cuisine=japanese", "ramen#cheap#cuisine=japanese", "tacos#cheap", "steak"},
+	"team":   {"alice#backend", "bob#frontend", "carol#backend#ooo", "dave#design"},
+	"chores": {"dishes", "laundry#capacity=2", "trash"},
+}
+
+// demoConfig returns the settings used in place of the environment in demo
+// mode.
+func demoConfig() (slack.TokenProvider, func(string) randomizer.Store) {
+	db := memory.NewDatabase(demoGroups)
+	return slack.StaticToken(demoToken), db.Factory()
+}
+
+// demoHandler logs each simulated Slack payload, and fills in the demo
+// verification token for payloads that don't have one, so that any HTTP
+// client can make requests without configuration.
+func demoHandler(next http.Handler, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.ParseForm() == nil {
+			if r.PostForm.Get("token") == "" {
+				r.PostForm.Set("token", demoToken)
+			}
+			logger.Info("Demo slash command payload", "payload", r.PostForm)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func serveDemoConsole(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(demoConsoleHTML))
+}
+
+const demoConsoleHTML = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Randomizer Demo</title></head>
+<body>
+<h1>Randomizer Demo</h1>
+<p>Type anything you'd type after <code>/randomize</code> in Slack, like
+<code>+lunch #cheap</code>, <code>/show team</code>, or <code>help</code>.
+The sample groups are <code>lunch</code>, <code>team</code>, and <code>chores</code>.</p>
+<form method="post" action="/">
+<input type="hidden" name="token" value="demo">
+<input type="hidden" name="team_id" value="TDEMO">
+<input type="hidden" name="channel_id" value="CDEMO">
+<input type="hidden" name="user_id" value="UDEMO">
+<input type="hidden" name="command" value="/randomize">
+<input type="text" name="text" size="60" autofocus>
+<button type="submit">Run</button>
+</form>
+<p>The admin API is available under <code>/admin/v1/</code> with the token <code>demo</code>.</p>
+</body>
+</html>
+`
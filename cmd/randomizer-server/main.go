@@ -1,5 +1,5 @@
 // The randomizer-server command is an HTTP server that serves the Slack slash
-// command API for the randomizer.
+// command API for the randomizer, and optionally a Discord application command.
 //
 // See the randomizer repository README for more information on configuring and
 // deploying the server.
@@ -8,13 +8,40 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"time"
+	_ "time/tzdata" // For the time zones of schedules, which deployment images may lack
 
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/featherbread/randomizer/internal/activity"
+	"github.com/featherbread/randomizer/internal/admin"
+	"github.com/featherbread/randomizer/internal/alert"
+	"github.com/featherbread/randomizer/internal/api"
+	"github.com/featherbread/randomizer/internal/compress"
+	"github.com/featherbread/randomizer/internal/discord"
+	"github.com/featherbread/randomizer/internal/flags"
+	"github.com/featherbread/randomizer/internal/health"
+	"github.com/featherbread/randomizer/internal/ingress"
+	"github.com/featherbread/randomizer/internal/moderation"
+	"github.com/featherbread/randomizer/internal/msteams"
+	"github.com/featherbread/randomizer/internal/randomizer"
 	"github.com/featherbread/randomizer/internal/slack"
 	"github.com/featherbread/randomizer/internal/store"
+	"github.com/featherbread/randomizer/internal/store/encrypted"
+	"github.com/featherbread/randomizer/internal/store/eventlog"
+	"github.com/featherbread/randomizer/internal/store/schema"
+	"github.com/featherbread/randomizer/internal/tracing"
+	"github.com/featherbread/randomizer/internal/warm"
+	"github.com/featherbread/randomizer/internal/webhook"
+	"github.com/featherbread/randomizer/internal/workspace"
 )
 
 var exitSignals = []os.Signal{os.Interrupt}
@@ -22,48 +49,339 @@ var exitSignals = []os.Signal{os.Interrupt}
 var (
 	flagAddr    = flag.String("addr", ":7636", "address to bind the server to")
 	flagLogJSON = flag.Bool("log-json", false, "log JSON to stderr instead of text")
+	flagAPI     = flag.Bool("api", false, "serve the REST API under /v1/, authorized by API_TOKEN")
 )
 
 func main() {
 	flag.Parse()
+	if flag.NArg() > 0 {
+		if err := runServiceCommand(flag.Args()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		return
+	}
+
+	exit := make(chan os.Signal, 1)
+	signal.Notify(exit, exitSignals...)
 
 	logger := slog.Default()
 	if *flagLogJSON {
 		logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
 	}
 
-	tokenProvider, err := slack.TokenProviderFromEnv()
+	serviceStopped := func() {}
+	if isWindowsService() {
+		if handler, err := serviceLogHandler(); err != nil {
+			logger.Warn("Failed to open the Windows Event Log", "err", err)
+		} else {
+			logger = slog.New(handler)
+		}
+		serviceStopped = runService(exit, logger)
+	}
+
+	telemetry := tracing.Resource(context.Background(), "randomizer-server")
+	logs, err := tracing.LogsFromEnv(context.Background(), telemetry)
 	if err != nil {
-		logger.Error("Failed to configure Slack token", "err", err)
+		logger.Error("Failed to configure log export", "err", err)
 		os.Exit(2)
 	}
+	logger = slog.New(logs.Handler(logger.Handler()))
+	traces, err := tracing.TracesFromEnv(context.Background(), telemetry)
+	if err != nil {
+		logger.Error("Failed to configure trace export", "err", err)
+		os.Exit(2)
+	}
+	if traces != nil {
+		otel.SetTracerProvider(traces)
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	}
 
-	storeFactory, err := store.FactoryFromEnv(context.Background())
+	tokenProvider, signingSecret, storeFactory := configFromEnv(logger)
+	adminAuth, err := admin.AuthFromEnv()
 	if err != nil {
-		logger.Error("Failed to create store", "err", err)
+		logger.Error("Failed to configure admin API authentication", "err", err)
 		os.Exit(2)
 	}
+	if *flagDemo {
+		logger.Warn("Starting in demo mode; requests will not be authenticated")
+		tokenProvider, storeFactory = demoConfig()
+		signingSecret = nil
+		adminAuth = admin.StaticToken(demoToken)
+	}
+
+	discordKey, err := discord.PublicKeyFromEnv()
+	if err != nil {
+		logger.Error("Failed to configure Discord verification", "err", err)
+		os.Exit(2)
+	}
+
+	teamsToken, err := msteams.SecurityTokenFromEnv()
+	if err != nil {
+		logger.Error("Failed to configure Microsoft Teams verification", "err", err)
+		os.Exit(2)
+	}
+
+	botTokens, err := slack.BotTokenProviderFromEnv()
+	if err != nil {
+		logger.Error("Failed to configure Slack bot token", "err", err)
+		os.Exit(2)
+	}
+
+	installer, err := slack.InstallerFromEnv(slack.TokenStoreFromEnv(storeFactory))
+	if err != nil {
+		logger.Error("Failed to configure Slack installs", "err", err)
+		os.Exit(2)
+	}
+	var (
+		installations  slack.TokenStore
+		sharedBotToken = botTokens != nil
+	)
+	if installer != nil {
+		installer.Logger = logger
+		installations = installer.Tokens
+		botTokens = slack.InstalledBotTokens(installations, botTokens)
+	}
+
+	var (
+		featureFlags = new(flags.Set)
+		retryCache   = slack.NewRetryCache(slack.DefaultRetryCacheTTL)
+		verification = new(slack.VerificationTracker)
+		botClient    = slack.WebClient{Tokens: botTokens}
+		suspense     *slack.Suspense
+		outbox       *slack.Outbox
+		scheduler    *slack.Scheduler
+		userNames    *slack.UserNames
+		home         *slack.Home
+		uploader     *slack.Uploader
+		feed         *activity.Feed
+	)
+	if adminAuth != nil {
+		feed = new(activity.Feed)
+	}
+	if botTokens != nil {
+		userNames = &slack.UserNames{Client: botClient}
+		if os.Getenv("SLACK_SUSPENSE") == "1" {
+			outbox = &slack.Outbox{StoreFactory: storeFactory, Client: botClient, Logger: logger}
+			suspense = &slack.Suspense{Client: botClient, Outbox: outbox}
+		}
+		if os.Getenv("SLACK_SCHEDULES") == "1" {
+			scheduler = &slack.Scheduler{Client: botClient, Outbox: outbox}
+		}
+		if os.Getenv("SLACK_APP_HOME") == "1" {
+			home = &slack.Home{
+				Client:  botClient,
+				Command: os.Getenv("SLACK_COMMAND_NAME"),
+				Digests: os.Getenv("SLACK_DIGESTS") == "1",
+			}
+		}
+		if os.Getenv("SLACK_UPLOAD_LARGE_RESULTS") == "1" {
+			uploader = &slack.Uploader{Client: botClient}
+		}
+	}
+
+	accessLog, err := slack.AccessLogFromEnv(logger)
+	if err != nil {
+		logger.Error("Failed to configure access logging", "err", err)
+		os.Exit(2)
+	}
+
+	alerts, err := alert.TrackerFromEnv(logger)
+	if err != nil {
+		logger.Error("Failed to configure alerting", "err", err)
+		os.Exit(2)
+	}
+
+	auditLog, err := slack.AuditFromEnv(context.Background())
+	if err != nil {
+		logger.Error("Failed to configure audit trail", "err", err)
+		os.Exit(2)
+	}
+
+	budget, err := slack.BudgetFromEnv()
+	if err != nil {
+		logger.Error("Failed to configure response budget", "err", err)
+		os.Exit(2)
+	}
+
+	defaults, err := slack.DefaultsFromEnv()
+	if err != nil {
+		logger.Error("Failed to configure default operations", "err", err)
+		os.Exit(2)
+	}
+
+	contentCheck, err := moderation.CheckFromEnv()
+	if err != nil {
+		logger.Error("Failed to configure content checks", "err", err)
+		os.Exit(2)
+	}
+
+	randomness, err := randomizer.RandomnessFromName(os.Getenv("RANDOMNESS"))
+	if err != nil {
+		logger.Error("Failed to configure randomness", "err", err)
+		os.Exit(2)
+	}
+
+	pinger, err := warm.PingerFromEnv()
+	if err != nil {
+		logger.Error("Failed to configure keep-warm pings", "err", err)
+		os.Exit(2)
+	}
+	if pinger != nil {
+		pinger.Logger = logger
+	}
+
+	workspaces, err := workspace.RegistryFromEnv(storeFactory)
+	if err != nil {
+		logger.Error("Failed to configure workspace tracking", "err", err)
+		os.Exit(2)
+	}
+
+	backend, _ := store.BackendFromEnv() // Checked with the store's configuration
+	deployment := randomizer.Deployment{Store: backend, Features: featureFlags.EnabledNames}
+
+	slackApp := slack.NewApp(tokenProvider, storeFactory,
+		slack.WithSuspense(suspense),
+		slack.WithScheduler(scheduler),
+		slack.WithRetryCache(retryCache),
+		slack.WithOnboarding(slack.NewOnboarding()),
+		slack.WithSigningSecret(signingSecret),
+		slack.WithRequireSignature(slack.TeamSetFromEnv("SLACK_REQUIRE_SIGNATURE_TEAMS")),
+		slack.WithVerificationTracker(verification),
+		slack.WithAccessLog(accessLog),
+		slack.WithAudit(auditLog),
+		slack.WithAlerts(alerts),
+		slack.WithInstallations(installations),
+		slack.WithBudget(budget),
+		slack.WithWorkspaces(workspaces),
+		slack.WithUserNames(userNames),
+		slack.WithHome(home),
+		slack.WithUploader(uploader),
+		slack.WithActivity(feed),
+		slack.WithPlainText(slack.TeamSetFromEnv("PLAIN_TEXT_TEAMS")),
+		slack.WithEmailExport(slack.TeamSetFromEnv("EMAIL_EXPORT_TEAMS")),
+		slack.WithDefaults(defaults),
+		slack.WithContentCheck(contentCheck),
+		slack.WithWebhookSender(webhook.SenderFromEnv()),
+		slack.WithRandomness(randomness),
+		slack.WithDeployment(deployment),
+		slack.WithPickAgain(os.Getenv("SLACK_PICK_AGAIN") == "1"),
+		slack.WithFederation(os.Getenv("SLACK_FEDERATION") == "1"),
+		slack.WithBotUserID(os.Getenv("SLACK_BOT_USER_ID")),
+		slack.WithLogger(logger),
+	)
+	if sharedBotToken {
+		checkScopes(slackApp, botClient, logger)
+	}
+
+	var slackHandler http.Handler = slackApp
+	if *flagDemo {
+		slackHandler = demoHandler(slackHandler, logger)
+	}
+
+	prober := &health.Prober{Store: storeFactory(health.Partition), Alerts: alerts, Logger: logger}
 
 	mux := http.NewServeMux()
-	mux.Handle("/", slack.App{
-		TokenProvider: tokenProvider,
-		StoreFactory:  storeFactory,
-		Logger:        logger,
-	})
+	mux.Handle("/", slackHandler)
+	if installer != nil {
+		mux.Handle("GET /slack/", installer.Handler())
+	}
+	if *flagDemo {
+		mux.HandleFunc("GET /demo", serveDemoConsole)
+	}
+	if discordKey != nil {
+		mux.Handle("/discord", discord.App{PublicKey: discordKey, StoreFactory: storeFactory, Defaults: defaults, ContentCheck: contentCheck, Deployment: deployment, Logger: logger})
+	}
+	if teamsToken != nil {
+		mux.Handle("/msteams", msteams.App{SecurityToken: teamsToken, StoreFactory: storeFactory, Defaults: defaults, ContentCheck: contentCheck, Deployment: deployment, Logger: logger})
+	}
+	if adminAuth != nil {
+		mux.Handle("/admin/", admin.API{
+			Auth:         adminAuth,
+			StoreFactory: storeFactory,
+			Flags:        featureFlags,
+			Workspaces:   workspaces,
+			Flushers:     []func(){retryCache.Flush},
+			RunAs:        slackApp.RunAs,
+			Settings:     defaults.Settings,
+			Reports: map[string]func() any{
+				"verification": func() any { return verification.Report() },
+				"activity":     func() any { return map[string]int{"subscribers": feed.Subscribers()} },
+				"store-health": func() any { return prober.Status() },
+				"keep-warm":    func() any { return pinger.Status() },
+			},
+			Logger: logger,
+		}.Handler())
+		mux.Handle("GET /events", feed.Handler(adminAuth, logger))
+	}
+	if *flagAPI {
+		token := os.Getenv("API_TOKEN")
+		if token == "" {
+			logger.Error("The REST API requires API_TOKEN")
+			os.Exit(2)
+		}
+		restAPI := api.API{
+			Token:        token,
+			Partition:    os.Getenv("API_PARTITION"),
+			Partitions:   api.PartitionsFromEnv("API_PARTITIONS"),
+			StoreFactory: storeFactory,
+			ContentCheck: contentCheck,
+			Logger:       logger,
+		}.Handler()
+		mux.Handle("/v1/", restAPI)
+		mux.Handle("GET "+api.SpecPath, restAPI)
+	}
 	mux.Handle("GET /healthz",
 		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusNoContent)
 		}))
+	mux.Handle("GET /readyz", prober.ReadyHandler())
+	mux.Handle("GET "+warm.Path, warm.Handler())
+	mux.Handle("GET /metrics", prober.MetricsHandler())
+
+	filter, err := ingress.FromEnv()
+	if err != nil {
+		logger.Error("Failed to configure request filtering", "err", err)
+		os.Exit(2)
+	}
+	if filter != nil {
+		filter.ExemptPaths = []string{"/healthz", "/readyz", warm.Path}
+		filter.Logger = logger
+	}
+
+	sweepCtx, stopSweeps := context.WithCancel(context.Background())
+	defer stopSweeps()
+	go prober.Run(sweepCtx)
+	if outbox != nil {
+		go outbox.Run(sweepCtx, 0)
+	}
+	if scheduler != nil {
+		go slackApp.RunSchedules(sweepCtx, 0)
+	}
+	if pinger != nil {
+		go pinger.Run(sweepCtx)
+	}
+
+	ln, err := listen(*flagAddr)
+	if err != nil {
+		logger.Error("Failed to listen", "err", err)
+		os.Exit(1)
+	}
 
-	srv := &http.Server{Addr: *flagAddr, Handler: mux}
+	var handler http.Handler = compress.Handler(filter.Handler(mux), 0)
+	if traces != nil {
+		handler = otelhttp.NewHandler(handler, "randomizer-server")
+	}
+	srv := &http.Server{Handler: handler}
 	srvErr := make(chan error, 1)
 	go func() {
-		logger.Info("Starting randomizer server", "addr", *flagAddr)
-		srvErr <- srv.ListenAndServe()
+		logger.Info("Starting randomizer server", "addr", ln.Addr().String())
+		srvErr <- srv.Serve(ln)
 	}()
-
-	exit := make(chan os.Signal, 1)
-	signal.Notify(exit, exitSignals...)
+	if err := sdNotify("READY=1"); err != nil {
+		logger.Warn("Failed to notify systemd", "err", err)
+	}
+	startWatchdog(sweepCtx, logger)
 
 	select {
 	case err := <-srvErr:
@@ -73,10 +391,76 @@ func main() {
 	case <-exit:
 	}
 
+	sdNotify("STOPPING=1")
 	signal.Stop(exit)
 	logger.Info("Shutting down; interrupt again to force exit")
 	err = srv.Shutdown(context.Background())
 	if err != nil {
 		logger.Error("Failed to shut down gracefully", "err", err)
 	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := budget.Wait(ctx); err != nil {
+		logger.Error("Failed to finish deferred writes", "err", err)
+	}
+	if traces != nil {
+		if err := traces.Shutdown(ctx); err != nil {
+			logger.Warn("Failed to shut down tracer provider", "err", err)
+		}
+	}
+	if err := logs.Shutdown(ctx); err != nil {
+		logger.Warn("Failed to shut down log export", "err", err)
+	}
+	serviceStopped()
+}
+
+// listen returns the socket that systemd opened for the server, if it was
+// socket activated, or else listens for TCP connections on addr.
+func listen(addr string) (net.Listener, error) {
+	if ln, err := systemdListener(); ln != nil || err != nil {
+		return ln, err
+	}
+	return net.Listen("tcp", addr)
+}
+
+// configFromEnv sets up Slack request verification and the store as
+// configured by the environment, or exits the program if the configuration is
+// invalid. In demo mode, it returns nothing and leaves the environment
+// unchecked.
+func configFromEnv(logger *slog.Logger) (tokenProvider, signingSecret slack.TokenProvider, _ func(string) randomizer.Store) {
+	if *flagDemo {
+		return nil, nil, nil
+	}
+
+	tokenProvider, signingSecret, err := slack.VerifiersFromEnv()
+	if err != nil {
+		logger.Error("Failed to configure Slack verification", "err", err)
+		os.Exit(2)
+	}
+
+	storeFactory, err := store.FactoryFromEnv(context.Background())
+	if err != nil {
+		logger.Error("Failed to create store", "err", err)
+		os.Exit(2)
+	}
+
+	storeFactory, err = encrypted.WrapFromEnv(storeFactory)
+	if err != nil {
+		logger.Error("Failed to configure store encryption", "err", err)
+		os.Exit(2)
+	}
+	storeFactory = eventlog.WrapFromEnv(schema.WrapFromEnv(storeFactory), logger)
+
+	return tokenProvider, signingSecret, storeFactory
+}
+
+// checkScopes warns about missing bot token scopes at startup, without holding
+// up the server for long if Slack is slow to respond.
+func checkScopes(app slack.App, client slack.WebClient, logger *slog.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := app.CheckScopes(ctx, client); err != nil {
+		logger.Warn("Failed to check Slack bot token scopes", "err", err)
+	}
 }
@@ -0,0 +1,19 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+func runServiceCommand(args []string) error {
+	return fmt.Errorf("the %q command manages Windows services; see the randomizer repository for running the server under systemd", args[0])
+}
+
+func isWindowsService() bool { return false }
+
+func runService(chan<- os.Signal, *slog.Logger) func() { return func() {} }
+
+func serviceLogHandler() (slog.Handler, error) { return nil, nil }
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// The Windows service runs the same executable, with the flags given to the
+// install command. Its environment comes from the "Environment" value in the
+// service's registry key.
+const (
+	serviceName        = "randomizer"
+	serviceDisplayName = "Randomizer"
+	serviceDescription = "Serves the randomizer's Slack slash command and Discord application command."
+)
+
+// serviceStopTimeout bounds the time that the stop command waits, and that the
+// service tells Windows it needs to shut down.
+const serviceStopTimeout = 30 * time.Second
+
+// runServiceCommand manages the Windows service for the server, with the
+// arguments that follow the flags on the command line.
+func runServiceCommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: randomizer-server [flags] install|uninstall|start|stop")
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connecting to the service manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	switch args[0] {
+	case "install":
+		return installService(m, os.Args[1:len(os.Args)-len(args)])
+	case "uninstall":
+		return uninstallService(m)
+	}
+
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		return fmt.Errorf("opening the %s service (is it installed?): %w", serviceName, err)
+	}
+	defer s.Close()
+
+	switch args[0] {
+	case "start":
+		return s.Start()
+	case "stop":
+		status, err := s.Control(svc.Stop)
+		if err != nil {
+			return fmt.Errorf("stopping the %s service: %w", serviceName, err)
+		}
+		deadline := time.Now().Add(serviceStopTimeout)
+		for status.State != svc.Stopped {
+			if time.Now().After(deadline) {
+				return fmt.Errorf("the %s service didn't stop within %v", serviceName, serviceStopTimeout)
+			}
+			time.Sleep(500 * time.Millisecond)
+			if status, err = s.Query(); err != nil {
+				return fmt.Errorf("checking the %s service: %w", serviceName, err)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func installService(m *mgr.Mgr, flags []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if s, err := m.OpenService(serviceName); err == nil {
+		s.Close()
+		return fmt.Errorf("the %s service is already installed", serviceName)
+	}
+
+	s, err := m.CreateService(serviceName, exe, mgr.Config{
+		DisplayName: serviceDisplayName,
+		Description: serviceDescription,
+		StartType:   mgr.StartAutomatic,
+	}, flags...)
+	if err != nil {
+		return fmt.Errorf("creating the %s service: %w", serviceName, err)
+	}
+	defer s.Close()
+
+	// Restart the server if it crashes, backing off after repeated failures
+	// within a day.
+	err = s.SetRecoveryActions([]mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: 5 * time.Second},
+		{Type: mgr.ServiceRestart, Delay: 30 * time.Second},
+		{Type: mgr.ServiceRestart, Delay: 5 * time.Minute},
+	}, uint32((24 * time.Hour).Seconds()))
+	if err == nil {
+		err = eventlog.InstallAsEventCreate(serviceName, eventlog.Error|eventlog.Warning|eventlog.Info)
+	}
+	if err != nil {
+		s.Delete()
+		return fmt.Errorf("configuring the %s service: %w", serviceName, err)
+	}
+	return nil
+}
+
+func uninstallService(m *mgr.Mgr) error {
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		return fmt.Errorf("opening the %s service (is it installed?): %w", serviceName, err)
+	}
+	defer s.Close()
+	if err := s.Delete(); err != nil {
+		return fmt.Errorf("removing the %s service: %w", serviceName, err)
+	}
+	// The event source only matters to a service that logs with it.
+	eventlog.Remove(serviceName)
+	return nil
+}
+
+// isWindowsService indicates whether the Windows service manager started the
+// server.
+func isWindowsService() bool {
+	ok, err := svc.IsWindowsService()
+	return err == nil && ok
+}
+
+// runService reports the server's status to the Windows service manager, and
+// forwards requests to stop the service to exit. The returned function reports
+// that the service stopped, once the server has shut down.
+func runService(exit chan<- os.Signal, logger *slog.Logger) (stopped func()) {
+	var (
+		done = make(chan struct{})
+		ran  = make(chan struct{})
+	)
+	go func() {
+		defer close(ran)
+		if err := svc.Run(serviceName, serviceHandler{exit, done}); err != nil {
+			logger.Error("Failed to run as a Windows service", "err", err)
+		}
+	}()
+	return func() {
+		close(done)
+		<-ran
+	}
+}
+
+type serviceHandler struct {
+	exit chan<- os.Signal
+	done <-chan struct{}
+}
+
+func (h serviceHandler) Execute(_ []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case <-h.done:
+			return false, 0
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending, WaitHint: uint32(serviceStopTimeout.Milliseconds())}
+				select {
+				case h.exit <- os.Interrupt:
+				default:
+				}
+				<-h.done
+				return false, 0
+			}
+		}
+	}
+}
+
+// serviceLogHandler returns a log handler that writes to the Windows Event
+// Log, since a service's standard error goes nowhere.
+func serviceLogHandler() (slog.Handler, error) {
+	l, err := eventlog.Open(serviceName)
+	if err != nil {
+		return nil, err
+	}
+	return slog.NewTextHandler(eventLogWriter{l}, nil), nil
+}
+
+// eventLogWriter writes each log record that a text handler formats as an
+// event, at the record's level.
+type eventLogWriter struct {
+	log *eventlog.Log
+}
+
+// eventID is the ID of every event that the service logs. Event IDs would
+// need a message file to mean anything.
+const eventID = 1
+
+func (w eventLogWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSuffix(string(p), "\n")
+	var err error
+	switch {
+	case strings.Contains(msg, " level=ERROR "):
+		err = w.log.Error(eventID, msg)
+	case strings.Contains(msg, " level=WARN "):
+		err = w.log.Warning(eventID, msg)
+	default:
+		err = w.log.Info(eventID, msg)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("writing to the event log: %w", err)
+	}
+	return len(p), nil
+}
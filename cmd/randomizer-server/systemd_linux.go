@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// systemd integration follows the protocols of sd_listen_fds(3) and
+// sd_notify(3), so that the server can run as a Type=notify unit with a
+// watchdog, and accept connections on a socket that systemd opens for it.
+
+// systemdListenFD is the first file descriptor that systemd passes through
+// socket activation.
+const systemdListenFD = 3
+
+// systemdListener returns the socket that systemd passed to the server, or nil
+// if the server wasn't socket activated.
+func systemdListener() (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, nil
+	}
+	if n > 1 {
+		return nil, fmt.Errorf("systemd passed %d sockets, but the server accepts only one", n)
+	}
+
+	// Child processes shouldn't think the socket is theirs.
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	f := os.NewFile(systemdListenFD, "systemd socket")
+	defer f.Close() // FileListener duplicates the descriptor.
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("using systemd socket: %w", err)
+	}
+	return ln, nil
+}
+
+// sdNotify sends a state change, like "READY=1", to systemd if it asked for
+// notifications. Otherwise, it does nothing.
+func sdNotify(state string) error {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return nil
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// startWatchdog keeps systemd's watchdog from restarting the server, if the
+// unit enables one, by notifying systemd at half of the watchdog's interval
+// until ctx is canceled.
+func startWatchdog(ctx context.Context, logger *slog.Logger) {
+	usec, err := strconv.Atoi(os.Getenv("WATCHDOG_USEC"))
+	if err != nil || usec <= 0 {
+		return
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return
+	}
+
+	interval := time.Duration(usec) * time.Microsecond / 2
+	logger.Info("Notifying systemd watchdog", "interval", interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := sdNotify("WATCHDOG=1"); err != nil {
+					logger.Warn("Failed to notify systemd watchdog", "err", err)
+				}
+			}
+		}
+	}()
+}
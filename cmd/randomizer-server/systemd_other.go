@@ -0,0 +1,15 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net"
+)
+
+func systemdListener() (net.Listener, error) { return nil, nil }
+
+func sdNotify(string) error { return nil }
+
+func startWatchdog(context.Context, *slog.Logger) {}
@@ -0,0 +1,21 @@
+#!/bin/sh
+# Runs randomizer-server against DynamoDB Local, creating the table on first
+# use. The same steps work in CI to exercise the full stack without AWS.
+set -e
+
+host_port="${HOST_PORT:-8000}"
+export DYNAMODB_ENDPOINT_URL="${DYNAMODB_ENDPOINT_URL:-http://localhost:$host_port}"
+export DYNAMODB_TABLE="${DYNAMODB_TABLE:-RandomizerGroups}"
+export SLACK_TOKEN="${SLACK_TOKEN:-dev}"
+
+if ! "${DOCKER:-docker}" container inspect "${CONTAINER_NAME:-dynamodb-randomizer}" >/dev/null 2>&1; then
+  HOST_PORT="$host_port" ./dynamodb-docker.sh
+fi
+
+echo "Waiting for DynamoDB Local at $DYNAMODB_ENDPOINT_URL..."
+until curl -s -o /dev/null "$DYNAMODB_ENDPOINT_URL"; do sleep 1; done
+
+# Creating a table that already exists fails harmlessly.
+go run ./cmd/randomizer-dbtools dynamodb create --table "$DYNAMODB_TABLE" || true
+
+exec go run ./cmd/randomizer-server "$@"
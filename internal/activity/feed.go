@@ -0,0 +1,259 @@
+// Package activity streams the randomizer's selections and changes to groups
+// as they happen, for dashboards and live displays that would otherwise poll.
+package activity
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/admin"
+	"github.com/featherbread/randomizer/internal/clock"
+)
+
+// Default settings for a Feed.
+const (
+	DefaultHistory   = 100
+	DefaultHeartbeat = 30 * time.Second
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before the
+// Feed disconnects it. A disconnected client reconnects with the ID of the
+// last event it saw, and catches up from the Feed's history.
+const subscriberBuffer = 64
+
+// Kinds of events.
+const (
+	// KindSelection is the kind of event for a random selection or assignment.
+	KindSelection = "selection"
+	// KindMutation is the kind of event for a change to saved groups or
+	// settings.
+	KindMutation = "mutation"
+)
+
+// Event describes a single randomizer operation.
+type Event struct {
+	// ID is assigned by the Feed, and increases with each event.
+	ID      uint64    `json:"id"`
+	Kind    string    `json:"kind"`
+	Time    time.Time `json:"time"`
+	Team    string    `json:"team_id,omitempty"`
+	Channel string    `json:"channel_id"`
+	User    string    `json:"user_id,omitempty"`
+	// Command is the slash command that the user ran, and Text is the text
+	// that they ran it with.
+	Command string `json:"command"`
+	Text    string `json:"text"`
+	// Result is the type of the randomizer's result, and Message is the
+	// response that the user saw.
+	Result  string `json:"result"`
+	Message string `json:"message"`
+	// Choices are the options of a selection in their chosen order, and
+	// ResultID is the ID that recalls the selection later.
+	Choices  []string `json:"choices,omitempty"`
+	ResultID string   `json:"result_id,omitempty"`
+}
+
+// Feed fans out randomizer events to Server-Sent Events clients.
+//
+// A Feed lives in memory, so each server process has its own, and clients see
+// only the events handled by the process that they're connected to. It keeps
+// a short history so that clients can catch up after reconnecting with the
+// standard Last-Event-ID header.
+//
+// Its zero value is ready to use, and a nil *Feed drops every event.
+type Feed struct {
+	// History overrides DefaultHistory.
+	History int
+	// Heartbeat overrides DefaultHeartbeat. Heartbeats keep idle connections
+	// open through proxies that time them out.
+	Heartbeat time.Duration
+	// Clock, if non-nil, replaces the system clock for timestamping events.
+	Clock clock.Clock
+
+	mu          sync.Mutex
+	lastID      uint64
+	history     []Event
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	filter  filter
+	events  chan Event
+	dropped bool
+}
+
+// filter limits a subscription to the events of a workspace or channel.
+type filter struct {
+	team, channel string
+}
+
+func (f filter) matches(e Event) bool {
+	return (f.team == "" || f.team == e.Team) && (f.channel == "" || f.channel == e.Channel)
+}
+
+// Publish assigns an ID and timestamp to an event, and sends it to every
+// matching subscriber. It never blocks on slow subscribers.
+func (f *Feed) Publish(e Event) {
+	if f == nil {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.lastID++
+	e.ID = f.lastID
+	e.Time = clock.Or(f.Clock).Now()
+
+	limit := f.History
+	if limit == 0 {
+		limit = DefaultHistory
+	}
+	f.history = append(f.history, e)
+	if len(f.history) > limit {
+		f.history = f.history[len(f.history)-limit:]
+	}
+
+	for sub := range f.subscribers {
+		if !sub.filter.matches(e) {
+			continue
+		}
+		select {
+		case sub.events <- e:
+		default:
+			f.drop(sub)
+		}
+	}
+}
+
+// subscribe registers a new subscriber, and returns the events in the history
+// after lastID that it should see first.
+func (f *Feed) subscribe(flt filter, lastID uint64) (*subscriber, []Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sub := &subscriber{filter: flt, events: make(chan Event, subscriberBuffer)}
+	if f.subscribers == nil {
+		f.subscribers = make(map[*subscriber]struct{})
+	}
+	f.subscribers[sub] = struct{}{}
+
+	var backlog []Event
+	for _, e := range f.history {
+		if e.ID > lastID && flt.matches(e) {
+			backlog = append(backlog, e)
+		}
+	}
+	return sub, backlog
+}
+
+func (f *Feed) unsubscribe(sub *subscriber) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !sub.dropped {
+		f.drop(sub)
+	}
+}
+
+// drop removes a subscriber and closes its channel. f.mu must be held.
+func (f *Feed) drop(sub *subscriber) {
+	delete(f.subscribers, sub)
+	sub.dropped = true
+	close(sub.events)
+}
+
+// Subscribers returns the number of connected clients.
+func (f *Feed) Subscribers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subscribers)
+}
+
+// Handler returns an HTTP handler that streams the Feed's events as
+// Server-Sent Events, to clients authorized by auth.
+//
+// The optional "team_id" and "channel_id" query parameters limit the stream
+// to the events of a single workspace or channel. Each event's SSE type is its
+// Kind, and its data is the Event as JSON.
+func (f *Feed) Handler(auth admin.Authenticator, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := auth.Authenticate(r)
+		if err != nil {
+			http.Error(w, "invalid or missing credentials", http.StatusUnauthorized)
+			return
+		}
+
+		var lastID uint64
+		if header := r.Header.Get("Last-Event-ID"); header != "" {
+			if lastID, err = strconv.ParseUint(header, 10, 64); err != nil {
+				http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+				return
+			}
+		}
+
+		rc := http.NewResponseController(w)
+		flt := filter{team: r.URL.Query().Get("team_id"), channel: r.URL.Query().Get("channel_id")}
+		sub, backlog := f.subscribe(flt, lastID)
+		defer f.unsubscribe(sub)
+		if logger != nil {
+			logger.Info("Streaming activity", "principal", principal, "team_id", flt.team, "channel_id", flt.channel)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		for _, e := range backlog {
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		heartbeat := f.Heartbeat
+		if heartbeat == 0 {
+			heartbeat = DefaultHeartbeat
+		}
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case e, ok := <-sub.events:
+				if !ok {
+					return // Too slow; the client will reconnect and catch up.
+				}
+				err = writeEvent(w, e)
+			case <-ticker.C:
+				_, err = fmt.Fprint(w, ": heartbeat\n\n")
+			}
+			if err == nil {
+				err = rc.Flush()
+			}
+			if err != nil {
+				if logger != nil && r.Context().Err() == nil {
+					logger.Warn("Stopped streaming activity", "principal", principal, "err", err)
+				}
+				return
+			}
+		}
+	})
+}
+
+func writeEvent(w http.ResponseWriter, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	// JSON never contains a raw newline, so the data fits on one line.
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Kind, data)
+	return err
+}
@@ -0,0 +1,135 @@
+package activity
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/admin"
+	"github.com/featherbread/randomizer/internal/clock/clocktest"
+)
+
+func TestFeedStream(t *testing.T) {
+	feed := &Feed{Heartbeat: time.Hour}
+	srv := httptest.NewServer(feed.Handler(admin.StaticToken("right"), nil))
+	t.Cleanup(srv.Close)
+
+	connect := func(query, lastID string) *bufio.Reader {
+		t.Helper()
+		req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL+"/events"+query, nil)
+		req.Header.Set("Authorization", "Bearer right")
+		if lastID != "" {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("got status %d, want %d", resp.StatusCode, http.StatusOK)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("got Content-Type %q", ct)
+		}
+		return bufio.NewReader(resp.Body)
+	}
+
+	all := connect("", "")
+	oneChannel := connect("?channel_id=C2", "")
+
+	feed.Publish(Event{Kind: KindSelection, Channel: "C1", Message: "first"})
+	feed.Publish(Event{Kind: KindMutation, Channel: "C2", Message: "second"})
+
+	for _, want := range []string{"first", "second"} {
+		if e := readEvent(t, all); e.Message != want {
+			t.Errorf("got event %+v, want %q", e, want)
+		}
+	}
+	if e := readEvent(t, oneChannel); e.Message != "second" || e.ID != 2 || e.Kind != KindMutation {
+		t.Errorf("got filtered event %+v, want the second", e)
+	}
+
+	// A reconnecting client catches up from its last event.
+	resumed := connect("", "1")
+	if e := readEvent(t, resumed); e.Message != "second" {
+		t.Errorf("got resumed event %+v, want the second", e)
+	}
+}
+
+func TestFeedRejectsUnauthorized(t *testing.T) {
+	feed := new(Feed)
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	feed.Handler(admin.StaticToken("right"), nil).ServeHTTP(resp, req)
+	if resp.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d, want %d", resp.Code, http.StatusUnauthorized)
+	}
+	if n := feed.Subscribers(); n != 0 {
+		t.Errorf("unauthorized request subscribed to the feed")
+	}
+}
+
+func TestFeedHistory(t *testing.T) {
+	clk := clocktest.New(time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC))
+	feed := &Feed{History: 2, Clock: clk}
+	for _, msg := range []string{"one", "two", "three"} {
+		feed.Publish(Event{Kind: KindSelection, Message: msg})
+	}
+
+	// A canceled request writes the backlog and returns.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/events", nil)
+	req.Header.Set("Authorization", "Bearer right")
+	feed.Handler(admin.StaticToken("right"), nil).ServeHTTP(resp, req)
+
+	body := bufio.NewReader(strings.NewReader(resp.Body.String()))
+	for _, want := range []string{"two", "three"} {
+		e := readEvent(t, body)
+		if e.Message != want || !e.Time.Equal(clk.Now()) {
+			t.Errorf("got event %+v, want %q at %v", e, want, clk.Now())
+		}
+	}
+	if n := feed.Subscribers(); n != 0 {
+		t.Errorf("closed request left %d subscribers", n)
+	}
+}
+
+// readEvent reads the next event from an SSE stream, skipping comments.
+func readEvent(t *testing.T, r *bufio.Reader) Event {
+	t.Helper()
+	var (
+		event Event
+		kind  string
+		found bool
+	)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && found:
+			if kind != event.Kind {
+				t.Errorf("SSE event type %q doesn't match kind %q", kind, event.Kind)
+			}
+			return event
+		case strings.HasPrefix(line, "event: "):
+			kind = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			found = true
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+				t.Fatalf("invalid event data: %v", err)
+			}
+		}
+	}
+}
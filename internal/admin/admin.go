@@ -0,0 +1,549 @@
+// Package admin provides an HTTP API for operators to inspect and manage a
+// running randomizer deployment without direct access to its database.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/featherbread/randomizer/internal/flags"
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/store/wrap"
+	"github.com/featherbread/randomizer/internal/workspace"
+)
+
+// PartitionLister is implemented by stores that can enumerate every partition
+// (e.g. Slack channel) in their backing database, not only their own.
+type PartitionLister interface {
+	Partitions(ctx context.Context) ([]string, error)
+}
+
+// Snapshotter is implemented by stores that can copy their entire backing
+// database while it's in use, like a bbolt store.
+type Snapshotter interface {
+	Snapshot(ctx context.Context, w io.Writer) (int64, error)
+}
+
+// listerPartition is the partition used to obtain a store for listing
+// partitions, since store factories require a non-empty partition.
+const listerPartition = "admin"
+
+// API serves the admin API under the /admin/v1/ path.
+//
+// Every request must be authorized by the configured Authenticator, or by the
+// configured static token if there is none.
+type API struct {
+	// Auth authorizes admin requests. If nil, requests must include an
+	// "Authorization: Bearer <token>" header with Token.
+	Auth Authenticator
+	// Token is the bearer token that authorizes admin requests when Auth is
+	// nil. If both are empty, all requests are rejected.
+	Token string
+	// StoreFactory provides a Store for a given partition.
+	StoreFactory func(partition string) randomizer.Store
+	// Flags, if non-nil, is the set of feature flags managed by this API.
+	Flags *flags.Set
+	// Flushers are called to discard cached state when an operator requests it.
+	Flushers []func()
+	// Workspaces, if non-nil, finds the channels of each Slack workspace, to
+	// export and delete a workspace's data after it uninstalls the app.
+	Workspaces *workspace.Registry
+	// RunAs, if non-nil, runs a command as a user, so that operators can
+	// reproduce a user's problem without access to their workspace. It must
+	// pass operator to randomizer.WithOperator.
+	RunAs func(ctx context.Context, operator string, req RunAsRequest) (randomizer.Result, error)
+	// Settings are the org-wide selection settings, which the settings
+	// endpoint reports beneath each workspace's, channel's, and user's own.
+	Settings randomizer.Settings
+	// Reports provide named snapshots of in-memory state for operators, like
+	// how each workspace's requests are verified.
+	Reports map[string]func() any
+	// Logger, if non-nil, logs errors and administrative actions.
+	Logger *slog.Logger
+}
+
+// Handler returns an HTTP handler for the admin API.
+func (a API) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/v1/partitions", a.listPartitions)
+	mux.HandleFunc("GET /admin/v1/partitions/{partition}/groups/{group}", a.dumpGroup)
+	mux.HandleFunc("DELETE /admin/v1/partitions/{partition}", a.deletePartition)
+	mux.HandleFunc("POST /admin/v1/partitions/{partition}/selections", a.selectGroups)
+	mux.HandleFunc("GET /admin/v1/partitions/{partition}/results/{id}", a.getResult)
+	mux.HandleFunc("GET /admin/v1/export", a.exportData)
+	mux.HandleFunc("GET /admin/v1/snapshot", a.snapshot)
+	mux.HandleFunc("GET /admin/v1/deletions", a.listDeletions)
+	mux.HandleFunc("GET /admin/v1/workspaces/{team}/export", a.exportWorkspace)
+	mux.HandleFunc("DELETE /admin/v1/workspaces/{team}", a.deleteWorkspace)
+	mux.HandleFunc("GET /admin/v1/workspaces/{team}/settings", a.showSettings)
+	mux.HandleFunc("POST /admin/v1/run-as", a.runAs)
+	mux.HandleFunc("POST /admin/v1/cache/flush", a.flushCache)
+	mux.HandleFunc("GET /admin/v1/flags", a.listFlags)
+	mux.HandleFunc("GET /admin/v1/reports/{name}", a.showReport)
+	mux.HandleFunc("PUT /admin/v1/flags/{name}", a.setFlag)
+	return a.authorize(mux)
+}
+
+func (a API) authorize(next http.Handler) http.Handler {
+	auth := a.Auth
+	if auth == nil {
+		auth = StaticToken(a.Token)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := auth.Authenticate(r)
+		if err != nil {
+			if a.Logger != nil && !errors.Is(err, errNoCredentials) {
+				a.Logger.Warn("Rejected admin request", "err", err)
+			}
+			a.writeError(w, http.StatusUnauthorized, errNoCredentials)
+			return
+		}
+		ctx := context.WithValue(r.Context(), principalKey{}, principal)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (a API) listPartitions(w http.ResponseWriter, r *http.Request) {
+	lister, ok := a.StoreFactory(listerPartition).(PartitionLister)
+	if !ok {
+		a.writeError(w, http.StatusNotImplemented, errors.New("store backend can't list partitions"))
+		return
+	}
+
+	partitions, err := lister.Partitions(r.Context())
+	if err != nil {
+		a.writeError(w, http.StatusInternalServerError, fmt.Errorf("listing partitions: %w", err))
+		return
+	}
+	slices.Sort(partitions)
+	a.writeJSON(w, http.StatusOK, map[string]any{"partitions": partitions})
+}
+
+func (a API) dumpGroup(w http.ResponseWriter, r *http.Request) {
+	var (
+		partition = r.PathValue("partition")
+		group     = r.PathValue("group")
+	)
+
+	options, err := a.StoreFactory(partition).Get(r.Context(), group)
+	if err != nil {
+		a.writeError(w, http.StatusInternalServerError, fmt.Errorf("getting group: %w", err))
+		return
+	}
+	if len(options) == 0 {
+		a.writeError(w, http.StatusNotFound, fmt.Errorf("group %q not found in %q", group, partition))
+		return
+	}
+	a.writeJSON(w, http.StatusOK, map[string]any{
+		"partition": partition,
+		"group":     group,
+		"options":   options,
+	})
+}
+
+func (a API) deletePartition(w http.ResponseWriter, r *http.Request) {
+	partition := r.PathValue("partition")
+	deleted, err := a.clearPartition(r.Context(), partition)
+	if err != nil {
+		a.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	a.logInfo(r.Context(), "Deleted partition data", "partition", partition, "groups", len(deleted))
+	a.writeJSON(w, http.StatusOK, map[string]any{"partition": partition, "deleted": deleted})
+}
+
+// clearPartition deletes every group and record in a partition, along with
+// its data key if the store is encrypted, and returns the deleted names.
+func (a API) clearPartition(ctx context.Context, partition string) ([]string, error) {
+	store := a.StoreFactory(partition)
+	groups, err := store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing groups: %w", err)
+	}
+
+	deleted := make([]string, 0, len(groups))
+	for _, group := range groups {
+		if _, err := store.Delete(ctx, group); err != nil {
+			return deleted, fmt.Errorf("deleting %q: %w", group, err)
+		}
+		deleted = append(deleted, group)
+	}
+
+	if err := wrap.Shred(ctx, store); err != nil {
+		return deleted, fmt.Errorf("shredding data key: %w", err)
+	}
+	return deleted, nil
+}
+
+// snapshot streams a consistent copy of the store's entire database, for
+// backends like bbolt whose database file can't be copied while the server
+// has it open.
+func (a API) snapshot(w http.ResponseWriter, r *http.Request) {
+	snapshotter, ok := a.StoreFactory(listerPartition).(Snapshotter)
+	if !ok {
+		a.writeError(w, http.StatusNotImplemented, errors.New("store backend can't take snapshots"))
+		return
+	}
+
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="randomizer.db"`)
+	n, err := snapshotter.Snapshot(ctx, w)
+	if err != nil {
+		// Once any of the snapshot is written, the status can't change, so
+		// clients must check that the copy is complete.
+		if n == 0 {
+			w.Header().Del("Content-Disposition")
+			a.writeError(w, http.StatusInternalServerError, fmt.Errorf("taking snapshot: %w", err))
+		} else if a.Logger != nil {
+			a.Logger.Error("Snapshot failed", "err", err, "bytes", n)
+		}
+		return
+	}
+	a.logInfo(ctx, "Took store snapshot", "bytes", n)
+}
+
+func (a API) listDeletions(w http.ResponseWriter, r *http.Request) {
+	if a.Workspaces == nil {
+		a.writeError(w, http.StatusNotImplemented, errors.New("workspace tracking is not configured"))
+		return
+	}
+	deletions, err := a.Workspaces.Pending(r.Context())
+	if err != nil {
+		a.writeError(w, http.StatusInternalServerError, fmt.Errorf("listing deletions: %w", err))
+		return
+	}
+	a.writeJSON(w, http.StatusOK, map[string]any{"deletions": deletions})
+}
+
+// exportWorkspace returns every group and record in each of a workspace's
+// channels, including history and settings, decrypted if necessary. The
+// workspace's own partition, with settings like its theme, is included too.
+func (a API) exportWorkspace(w http.ResponseWriter, r *http.Request) {
+	if a.Workspaces == nil {
+		a.writeError(w, http.StatusNotImplemented, errors.New("workspace tracking is not configured"))
+		return
+	}
+
+	var (
+		ctx  = r.Context()
+		team = r.PathValue("team")
+	)
+	channels, err := a.Workspaces.Channels(ctx, team)
+	if err != nil {
+		a.writeError(w, http.StatusInternalServerError, fmt.Errorf("listing channels: %w", err))
+		return
+	}
+
+	partitions := make(map[string]map[string][]string, len(channels)+1)
+	for _, partition := range append(channels, workspace.Partition(team)) {
+		store := a.StoreFactory(partition)
+		names, err := store.List(ctx)
+		if err != nil {
+			a.writeError(w, http.StatusInternalServerError, fmt.Errorf("listing groups in %q: %w", partition, err))
+			return
+		}
+		data := make(map[string][]string, len(names))
+		for _, name := range names {
+			if data[name], err = store.Get(ctx, name); err != nil {
+				a.writeError(w, http.StatusInternalServerError, fmt.Errorf("getting %q in %q: %w", name, partition, err))
+				return
+			}
+		}
+		partitions[partition] = data
+	}
+	a.writeJSON(w, http.StatusOK, map[string]any{"team": team, "partitions": partitions})
+}
+
+// deleteWorkspace deletes the data in each of a workspace's channels and its
+// workspace-wide settings, and removes the workspace from the registry along
+// with any scheduled deletion.
+func (a API) deleteWorkspace(w http.ResponseWriter, r *http.Request) {
+	if a.Workspaces == nil {
+		a.writeError(w, http.StatusNotImplemented, errors.New("workspace tracking is not configured"))
+		return
+	}
+
+	var (
+		ctx  = r.Context()
+		team = r.PathValue("team")
+	)
+	channels, err := a.Workspaces.Channels(ctx, team)
+	if err != nil {
+		a.writeError(w, http.StatusInternalServerError, fmt.Errorf("listing channels: %w", err))
+		return
+	}
+	for _, partition := range append(channels, workspace.Partition(team)) {
+		if _, err := a.clearPartition(ctx, partition); err != nil {
+			a.writeError(w, http.StatusInternalServerError, fmt.Errorf("deleting %q: %w", partition, err))
+			return
+		}
+	}
+	if err := a.Workspaces.Forget(ctx, team); err != nil {
+		a.writeError(w, http.StatusInternalServerError, fmt.Errorf("forgetting workspace: %w", err))
+		return
+	}
+
+	a.logInfo(ctx, "Deleted workspace data", "team", team, "channels", len(channels))
+	a.writeJSON(w, http.StatusOK, map[string]any{"team": team, "deleted": channels})
+}
+
+// showSettings reports the selection settings configured at each level for a
+// Slack workspace, along with the settings that apply and the level each one
+// comes from. The "channel" and "user" query parameters narrow the report to a
+// channel and a user within the workspace.
+func (a API) showSettings(w http.ResponseWriter, r *http.Request) {
+	var (
+		team    = r.PathValue("team")
+		channel = r.URL.Query().Get("channel")
+		user    = r.URL.Query().Get("user")
+	)
+	var channelStore randomizer.Store
+	if channel != "" {
+		channelStore = a.StoreFactory(channel)
+	}
+	settings, err := randomizer.LoadSettings(r.Context(), a.Settings, a.StoreFactory(workspace.Partition(team)), channelStore, user)
+	if err != nil {
+		a.writeError(w, http.StatusInternalServerError, fmt.Errorf("loading settings: %w", err))
+		return
+	}
+
+	type effectiveSetting struct {
+		Value string `json:"value"`
+		Level string `json:"level"`
+	}
+	var (
+		levels    = make(map[string]map[string]string)
+		effective = make(map[string]effectiveSetting)
+	)
+	for _, level := range []randomizer.SettingsLevel{randomizer.LevelOrg, randomizer.LevelWorkspace, randomizer.LevelChannel, randomizer.LevelUser} {
+		levels[level.String()] = settings.At(level).Values()
+	}
+	for key, value := range settings.Effective().Values() {
+		level, _ := settings.Source(key)
+		effective[key] = effectiveSetting{Value: value, Level: level.String()}
+	}
+	a.writeJSON(w, http.StatusOK, map[string]any{
+		"team":      team,
+		"channel":   channel,
+		"user":      user,
+		"levels":    levels,
+		"effective": effective,
+	})
+}
+
+// maxBatchGroups limits the number of groups in a single batch selection.
+const maxBatchGroups = 100
+
+// batchSelection reports the outcome of selecting from one group in a batch.
+type batchSelection struct {
+	Group   string   `json:"group"`
+	ID      string   `json:"id,omitempty"`
+	Choices []string `json:"choices,omitempty"`
+	Message string   `json:"message,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// selectGroups makes a selection from each of several groups in a partition,
+// like picking a reviewer for each of several repositories. Failures are
+// reported per group, so the response is successful as long as the request
+// itself is valid.
+func (a API) selectGroups(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Groups []string `json:"groups"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Groups) == 0 {
+		a.writeError(w, http.StatusBadRequest, errors.New(`body must be {"groups": ["name", ...]}`))
+		return
+	}
+	if len(body.Groups) > maxBatchGroups {
+		a.writeError(w, http.StatusBadRequest, fmt.Errorf("at most %d groups may be selected at once", maxBatchGroups))
+		return
+	}
+
+	var opts []randomizer.AppOption
+	if a.Logger != nil {
+		opts = append(opts, randomizer.WithLogger(a.Logger))
+	}
+	app := randomizer.NewApp("randomizer", a.StoreFactory(r.PathValue("partition")), opts...)
+
+	var failed int
+	selections := make([]batchSelection, 0, len(body.Groups))
+	for _, s := range app.SelectGroups(r.Context(), body.Groups) {
+		selection := batchSelection{Group: s.Group}
+		if s.Err != nil {
+			failed++
+			selection.Error = s.Err.Error()
+		} else {
+			selection.ID = s.Result.ID()
+			selection.Choices = s.Result.Choices()
+			selection.Message = s.Result.Message()
+		}
+		selections = append(selections, selection)
+	}
+	a.writeJSON(w, http.StatusOK, map[string]any{
+		"partition":  r.PathValue("partition"),
+		"selections": selections,
+		"failed":     failed,
+	})
+}
+
+// getResult returns the details of a past selection in a partition by its ID,
+// for audits and disputes.
+func (a API) getResult(w http.ResponseWriter, r *http.Request) {
+	var (
+		partition = r.PathValue("partition")
+		id        = r.PathValue("id")
+	)
+
+	app := randomizer.NewApp("randomizer", a.StoreFactory(partition))
+	result, found, err := app.LookupResult(r.Context(), id)
+	if err != nil {
+		a.writeError(w, http.StatusInternalServerError, fmt.Errorf("looking up result: %w", err))
+		return
+	}
+	if !found {
+		a.writeError(w, http.StatusNotFound, fmt.Errorf("result %q not found in %q", id, partition))
+		return
+	}
+	a.writeJSON(w, http.StatusOK, map[string]any{
+		"partition": partition,
+		"id":        result.ID,
+		"at":        result.At,
+		"group":     result.Group,
+		"choices":   result.Choices,
+	})
+}
+
+// RunAsRequest describes a command for an operator to run as a user.
+type RunAsRequest struct {
+	Team    string `json:"team_id"`
+	Channel string `json:"channel_id"`
+	User    string `json:"user_id"`
+	// Command is the slash command, like "/randomize", and Text is the text
+	// to run it with, like "/save lunch pizza tacos".
+	Command string `json:"command"`
+	Text    string `json:"text"`
+}
+
+// runAs runs a command as a user, logging the operator who ran it. Like
+// selections in a batch, the randomizer's own errors are reported in the
+// response body as the user would see them, so the response is successful as
+// long as the request itself is valid.
+func (a API) runAs(w http.ResponseWriter, r *http.Request) {
+	if a.RunAs == nil {
+		a.writeError(w, http.StatusNotImplemented, errors.New("running commands as users is not configured"))
+		return
+	}
+
+	var req RunAsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Channel == "" || req.User == "" || req.Command == "" {
+		a.writeError(w, http.StatusBadRequest, errors.New(`body must include "channel_id", "user_id", and "command"`))
+		return
+	}
+
+	ctx := r.Context()
+	operator := principalFrom(ctx)
+	a.logInfo(ctx, "Running command as user",
+		"team", req.Team, "channel", req.Channel, "user", req.User, "command", req.Command, "text", req.Text)
+	result, err := a.RunAs(ctx, operator, req)
+
+	body := map[string]any{
+		"impersonated": true,
+		"operator":     operator,
+		"team_id":      req.Team,
+		"channel_id":   req.Channel,
+		"user_id":      req.User,
+	}
+	var rerr randomizer.Error
+	switch {
+	case errors.As(err, &rerr):
+		body["error"] = rerr.Error()
+		body["message"] = rerr.HelpText()
+	case err != nil:
+		a.writeError(w, http.StatusInternalServerError, fmt.Errorf("running command: %w", err))
+		return
+	default:
+		body["type"] = result.Type().String()
+		body["message"] = result.Message()
+		body["changed"] = result.Changed()
+		if choices := result.Choices(); len(choices) > 0 {
+			body["choices"] = choices
+		}
+		if id := result.ID(); id != "" {
+			body["id"] = id
+		}
+	}
+	a.writeJSON(w, http.StatusOK, body)
+}
+
+func (a API) flushCache(w http.ResponseWriter, r *http.Request) {
+	for _, flush := range a.Flushers {
+		flush()
+	}
+	a.logInfo(r.Context(), "Flushed caches", "count", len(a.Flushers))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a API) showReport(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	report, ok := a.Reports[name]
+	if !ok {
+		a.writeError(w, http.StatusNotFound, fmt.Errorf("no report named %q", name))
+		return
+	}
+	a.writeJSON(w, http.StatusOK, map[string]any{"report": name, "data": report()})
+}
+
+func (a API) listFlags(w http.ResponseWriter, _ *http.Request) {
+	a.writeJSON(w, http.StatusOK, map[string]any{"flags": a.Flags.All()})
+}
+
+func (a API) setFlag(w http.ResponseWriter, r *http.Request) {
+	if a.Flags == nil {
+		a.writeError(w, http.StatusNotImplemented, errors.New("feature flags are not configured"))
+		return
+	}
+
+	var body struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Enabled == nil {
+		a.writeError(w, http.StatusBadRequest, errors.New(`body must be {"enabled": true|false}`))
+		return
+	}
+
+	name := r.PathValue("name")
+	a.Flags.Set(name, *body.Enabled)
+	a.logInfo(r.Context(), "Set feature flag", "flag", name, "enabled", *body.Enabled)
+	a.writeJSON(w, http.StatusOK, map[string]any{"flag": name, "enabled": *body.Enabled})
+}
+
+func (a API) writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil && a.Logger != nil {
+		a.Logger.Error("Failed to write admin response", "err", err)
+	}
+}
+
+func (a API) writeError(w http.ResponseWriter, status int, err error) {
+	if a.Logger != nil && status >= 500 {
+		a.Logger.Error("Admin request failed", "err", err)
+	}
+	a.writeJSON(w, status, map[string]any{"error": err.Error()})
+}
+
+// logInfo logs an administrative action along with the principal that
+// requested it.
+func (a API) logInfo(ctx context.Context, msg string, args ...any) {
+	if a.Logger != nil {
+		a.Logger.Info(msg, append(args, "principal", principalFrom(ctx))...)
+	}
+}
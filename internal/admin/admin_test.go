@@ -0,0 +1,373 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/featherbread/randomizer/internal/flags"
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+	"github.com/featherbread/randomizer/internal/store/encrypted"
+	"github.com/featherbread/randomizer/internal/workspace"
+)
+
+func TestUnauthorized(t *testing.T) {
+	api := API{
+		Token:        "right",
+		StoreFactory: func(_ string) randomizer.Store { return rndtest.Store{} },
+	}
+
+	for _, header := range []string{"", "Bearer wrong", "right"} {
+		req := httptest.NewRequest(http.MethodGet, "/admin/v1/flags", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp := httptest.NewRecorder()
+		api.Handler().ServeHTTP(resp, req)
+
+		if resp.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: got status %v, want %v", header, resp.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestDumpAndDelete(t *testing.T) {
+	store := rndtest.Store{"lunch": {"ramen", "sushi"}, "snacks": {"chips", "pretzels"}}
+	api := API{
+		Token:        "right",
+		StoreFactory: func(_ string) randomizer.Store { return store },
+	}
+
+	resp := serveAuthorized(api, http.MethodGet, "/admin/v1/partitions/C123/groups/lunch", "")
+	var dump struct{ Options []string }
+	if err := json.NewDecoder(resp.Body).Decode(&dump); err != nil {
+		t.Fatalf("decoding dump: %v", err)
+	}
+	if !slices.Equal(dump.Options, []string{"ramen", "sushi"}) {
+		t.Errorf("got options %v, want %v", dump.Options, []string{"ramen", "sushi"})
+	}
+
+	resp = serveAuthorized(api, http.MethodDelete, "/admin/v1/partitions/C123", "")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("delete got status %v, want %v", resp.Code, http.StatusOK)
+	}
+	if len(store) > 0 {
+		t.Errorf("store still has groups after delete: %v", store)
+	}
+}
+
+func TestSetFlag(t *testing.T) {
+	api := API{Token: "right", Flags: new(flags.Set)}
+
+	resp := serveAuthorized(api, http.MethodPut, "/admin/v1/flags/suspense", `{"enabled": true}`)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("got status %v, want %v", resp.Code, http.StatusOK)
+	}
+	if !api.Flags.Enabled("suspense") {
+		t.Error("flag was not enabled")
+	}
+}
+
+func TestSelectGroups(t *testing.T) {
+	store := &rndtest.SyncStore{Store: rndtest.Store{
+		"repo-a": {"alice", "bob"},
+		"repo-b": {"carol"},
+	}}
+	api := API{
+		Token:        "right",
+		StoreFactory: func(_ string) randomizer.Store { return store },
+	}
+
+	resp := serveAuthorized(api, http.MethodPost, "/admin/v1/partitions/C123/selections",
+		`{"groups": ["repo-a", "repo-b", "repo-c"]}`)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("got status %v, want %v", resp.Code, http.StatusOK)
+	}
+
+	var body struct {
+		Selections []struct {
+			Group   string
+			Choices []string
+			Error   string
+		}
+		Failed int
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding selections: %v", err)
+	}
+	if len(body.Selections) != 3 || body.Failed != 1 {
+		t.Fatalf("got %d selections with %d failed, want 3 with 1 failed", len(body.Selections), body.Failed)
+	}
+	if s := body.Selections[1]; s.Group != "repo-b" || !slices.Equal(s.Choices, []string{"carol"}) {
+		t.Errorf("got selection %+v for repo-b", s)
+	}
+	if s := body.Selections[2]; s.Group != "repo-c" || s.Error == "" {
+		t.Errorf("got selection %+v for missing repo-c, want an error", s)
+	}
+
+	resp = serveAuthorized(api, http.MethodPost, "/admin/v1/partitions/C123/selections", `{"groups": []}`)
+	if resp.Code != http.StatusBadRequest {
+		t.Errorf("empty batch got status %v, want %v", resp.Code, http.StatusBadRequest)
+	}
+}
+
+func TestGetResult(t *testing.T) {
+	store := rndtest.Store{"/results": {"2026-10-16T12:00:00.000000000Z k3x9qp lunch pizza tacos"}}
+	api := API{
+		Token:        "right",
+		StoreFactory: func(_ string) randomizer.Store { return store },
+	}
+
+	resp := serveAuthorized(api, http.MethodGet, "/admin/v1/partitions/C123/results/k3x9qp", "")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("got status %v, want %v", resp.Code, http.StatusOK)
+	}
+	var body struct {
+		Group   string
+		Choices []string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding result: %v", err)
+	}
+	if body.Group != "lunch" || !slices.Equal(body.Choices, []string{"pizza", "tacos"}) {
+		t.Errorf("got result %+v", body)
+	}
+
+	resp = serveAuthorized(api, http.MethodGet, "/admin/v1/partitions/C123/results/zzzzzz", "")
+	if resp.Code != http.StatusNotFound {
+		t.Errorf("missing result got status %v, want %v", resp.Code, http.StatusNotFound)
+	}
+}
+
+func TestWorkspaceExportAndDelete(t *testing.T) {
+	partitions := map[string]rndtest.Store{
+		"C1":    {"lunch": {"ramen", "sushi"}, "/history/lunch": {"2026-10-16T12:00:00.000000000Z|ramen"}},
+		"C2":    {"snacks": {"chips", "pretzels"}},
+		"other": {"lunch": {"tacos", "pizza"}},
+	}
+	factory := func(partition string) randomizer.Store {
+		if partitions[partition] == nil {
+			partitions[partition] = make(rndtest.Store)
+		}
+		return partitions[partition]
+	}
+	registry := &workspace.Registry{StoreFactory: factory}
+	registry.Track(t.Context(), "T1", "C1")
+	registry.Track(t.Context(), "T1", "C2")
+	registry.ScheduleDeletion(t.Context(), "T1")
+	factory(workspace.Partition("T1")).Put(t.Context(), "/theme", []string{"emoji|:tada:"})
+	api := API{Token: "right", StoreFactory: factory, Workspaces: registry}
+
+	resp := serveAuthorized(api, http.MethodGet, "/admin/v1/deletions", "")
+	if !strings.Contains(resp.Body.String(), `"team":"T1"`) {
+		t.Errorf("deletions missing T1: %s", resp.Body)
+	}
+
+	resp = serveAuthorized(api, http.MethodGet, "/admin/v1/workspaces/T1/export", "")
+	var export struct {
+		Partitions map[string]map[string][]string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&export); err != nil {
+		t.Fatalf("decoding export: %v", err)
+	}
+	if len(export.Partitions) != 3 || len(export.Partitions["C1"]["/history/lunch"]) != 1 ||
+		len(export.Partitions[workspace.Partition("T1")]["/theme"]) != 1 {
+		t.Errorf("unexpected export %v", export.Partitions)
+	}
+
+	resp = serveAuthorized(api, http.MethodDelete, "/admin/v1/workspaces/T1", "")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("delete got status %v, want %v", resp.Code, http.StatusOK)
+	}
+	if len(partitions["C1"]) > 0 || len(partitions["C2"]) > 0 {
+		t.Errorf("workspace channels still have data: %v %v", partitions["C1"], partitions["C2"])
+	}
+	if settings := partitions[workspace.Partition("T1")]; len(settings) > 0 {
+		t.Errorf("workspace settings still have data: %v", settings)
+	}
+	if len(partitions["other"]) != 1 {
+		t.Error("deleting a workspace deleted another partition's data")
+	}
+	if pending, _ := registry.Pending(t.Context()); len(pending) != 0 {
+		t.Errorf("deletion still pending after delete: %v", pending)
+	}
+}
+
+func TestWorkspaceShred(t *testing.T) {
+	partitions := map[string]rndtest.Store{}
+	keyring, err := encrypted.NewKeyring([]byte(strings.Repeat("k", encrypted.MinSecretLength)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	factory := encrypted.Wrap(func(partition string) randomizer.Store {
+		if partitions[partition] == nil {
+			partitions[partition] = make(rndtest.Store)
+		}
+		return partitions[partition]
+	}, keyring)
+	registry := &workspace.Registry{StoreFactory: factory}
+	registry.Track(t.Context(), "T1", "C1")
+	for _, partition := range []string{"C1", workspace.Partition("T1"), "other"} {
+		if err := factory(partition).Put(t.Context(), "lunch", []string{"ramen"}); err != nil {
+			t.Fatalf("saving in %q: %v", partition, err)
+		}
+	}
+	backup := maps.Clone(partitions["C1"])
+	api := API{Token: "right", StoreFactory: factory, Workspaces: registry}
+
+	resp := serveAuthorized(api, http.MethodDelete, "/admin/v1/workspaces/T1", "")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("delete got status %v, want %v", resp.Code, http.StatusOK)
+	}
+
+	// Restoring the workspace's data from a backup doesn't bring back its key.
+	partitions["C1"]["lunch"] = backup["lunch"]
+	if options, err := factory("C1").Get(t.Context(), "lunch"); err == nil {
+		t.Errorf("read %v from a shredded channel", options)
+	}
+	if options, err := factory("other").Get(t.Context(), "lunch"); err != nil || !slices.Equal(options, []string{"ramen"}) {
+		t.Errorf("got %v (err %v) from another workspace's channel, want [ramen]", options, err)
+	}
+}
+
+func TestShowSettings(t *testing.T) {
+	partitions := map[string]rndtest.Store{
+		workspace.Partition("T1"): {"/config": {"pick|3"}, "/config/user/U1": {"visibility|private"}},
+		"C1":                      {"/config": {"pick|2", "group|lunch"}},
+	}
+	factory := func(partition string) randomizer.Store {
+		if partitions[partition] == nil {
+			partitions[partition] = make(rndtest.Store)
+		}
+		return partitions[partition]
+	}
+	var org randomizer.Settings
+	org.Set("phrasing", "Winner: {result}")
+	api := API{Token: "right", StoreFactory: factory, Settings: org}
+
+	resp := serveAuthorized(api, http.MethodGet, "/admin/v1/workspaces/T1/settings?channel=C1&user=U1", "")
+	var report struct {
+		Levels    map[string]map[string]string
+		Effective map[string]struct{ Value, Level string }
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("decoding settings: %v", err)
+	}
+	if got := report.Levels["workspace"]["pick"]; got != "3" {
+		t.Errorf("got workspace pick %q, want 3", got)
+	}
+	want := map[string]struct{ Value, Level string }{
+		"group":      {"lunch", "channel"},
+		"pick":       {"2", "channel"},
+		"phrasing":   {"Winner: {result}", "org"},
+		"visibility": {"private", "user"},
+	}
+	if !maps.Equal(report.Effective, want) {
+		t.Errorf("got effective settings %v, want %v", report.Effective, want)
+	}
+
+	resp = serveAuthorized(api, http.MethodGet, "/admin/v1/workspaces/T1/settings", "")
+	report.Effective = nil
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("decoding settings: %v", err)
+	}
+	if got := report.Effective["pick"]; got.Value != "3" || got.Level != "workspace" {
+		t.Errorf("got pick %v without a channel, want the workspace's", got)
+	}
+}
+
+func serveAuthorized(api API, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer right")
+	resp := httptest.NewRecorder()
+	api.Handler().ServeHTTP(resp, req)
+	return resp
+}
+
+func TestRunAs(t *testing.T) {
+	store := rndtest.Store{"lunch": {"pizza"}}
+	var gotOperator string
+	api := API{
+		Token: "right",
+		RunAs: func(ctx context.Context, operator string, req RunAsRequest) (randomizer.Result, error) {
+			gotOperator = operator
+			app := randomizer.NewApp(req.Command, store, randomizer.WithUser(req.User), randomizer.WithOperator(operator))
+			return app.Main(ctx, strings.Fields(req.Text))
+		},
+	}
+
+	resp := serveAuthorized(api, http.MethodPost, "/admin/v1/run-as", `{"channel_id": "C1", "command": "/randomize"}`)
+	if resp.Code != http.StatusBadRequest {
+		t.Errorf("got status %v without a user, want %v", resp.Code, http.StatusBadRequest)
+	}
+
+	var body struct {
+		Impersonated bool
+		Operator     string
+		Type         string
+		Message      string
+		Choices      []string
+		Error        string
+	}
+	resp = serveAuthorized(api, http.MethodPost, "/admin/v1/run-as", `{"channel_id": "C1", "user_id": "U1", "command": "/randomize", "text": "lunch"}`)
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if !body.Impersonated || body.Operator != "static token" || gotOperator != "static token" {
+		t.Errorf("response not flagged as run by the operator: %+v", body)
+	}
+	if body.Type != "Selection" || !slices.Equal(body.Choices, []string{"pizza"}) || !strings.Contains(body.Message, "Operator static token ran this as <@U1>") {
+		t.Errorf("got result %+v", body)
+	}
+
+	body.Message = ""
+	resp = serveAuthorized(api, http.MethodPost, "/admin/v1/run-as", `{"channel_id": "C1", "user_id": "U1", "command": "/randomize", "text": "/delete dinner"}`)
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if resp.Code != http.StatusOK || body.Error == "" || !strings.Contains(body.Message, "Whoops") {
+		t.Errorf("got status %v and %+v for a failed command", resp.Code, body)
+	}
+
+	resp = serveAuthorized(API{Token: "right"}, http.MethodPost, "/admin/v1/run-as", `{"channel_id": "C1", "user_id": "U1", "command": "/randomize"}`)
+	if resp.Code != http.StatusNotImplemented {
+		t.Errorf("got status %v without RunAs, want %v", resp.Code, http.StatusNotImplemented)
+	}
+}
+
+type snapshotStore struct {
+	rndtest.Store
+	data string
+}
+
+func (s snapshotStore) Snapshot(_ context.Context, w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, s.data)
+	return int64(n), err
+}
+
+func TestSnapshot(t *testing.T) {
+	api := API{
+		Token:        "right",
+		StoreFactory: func(_ string) randomizer.Store { return snapshotStore{data: "bbolt bytes"} },
+	}
+	resp := serveAuthorized(api, http.MethodGet, "/admin/v1/snapshot", "")
+	if resp.Code != http.StatusOK || resp.Body.String() != "bbolt bytes" {
+		t.Errorf("got status %v and body %q", resp.Code, resp.Body.String())
+	}
+	if disposition := resp.Header().Get("Content-Disposition"); !strings.Contains(disposition, "randomizer.db") {
+		t.Errorf("got Content-Disposition %q", disposition)
+	}
+
+	api.StoreFactory = func(_ string) randomizer.Store { return rndtest.Store{} }
+	resp = serveAuthorized(api, http.MethodGet, "/admin/v1/snapshot", "")
+	if resp.Code != http.StatusNotImplemented {
+		t.Errorf("got status %v without snapshot support, want %v", resp.Code, http.StatusNotImplemented)
+	}
+}
@@ -0,0 +1,545 @@
+package admin
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/clock"
+)
+
+// Authenticator verifies the identity behind an admin API request.
+type Authenticator interface {
+	// Authenticate returns a description of the caller for audit logs (e.g. a
+	// role ARN or JWT subject), or an error if the request isn't authorized.
+	Authenticate(r *http.Request) (principal string, err error)
+}
+
+var errNoCredentials = errors.New("invalid or missing admin credentials")
+
+// bearerToken returns the token in a request's "Authorization: Bearer"
+// header, if any.
+func bearerToken(r *http.Request) (string, bool) {
+	return strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// StaticToken authorizes requests whose bearer token matches a fixed secret.
+// An empty StaticToken rejects all requests.
+type StaticToken string
+
+func (t StaticToken) Authenticate(r *http.Request) (string, error) {
+	got, ok := bearerToken(r)
+	if !ok || t == "" || subtle.ConstantTimeCompare([]byte(got), []byte(t)) != 1 {
+		return "", errNoCredentials
+	}
+	return "static token", nil
+}
+
+// AnyOf authorizes requests that any of its Authenticators authorize, trying
+// each in order.
+type AnyOf []Authenticator
+
+func (a AnyOf) Authenticate(r *http.Request) (string, error) {
+	for _, auth := range a {
+		if principal, err := auth.Authenticate(r); err == nil {
+			return principal, nil
+		}
+	}
+	return "", errNoCredentials
+}
+
+// AuthFromEnv returns an Authenticator for the methods configured in the
+// environment, or nil if none are.
+//
+// ADMIN_TOKEN sets a static bearer token. ADMIN_OIDC_ISSUER and
+// ADMIN_OIDC_AUDIENCE accept JWTs from an OpenID Connect provider.
+// ADMIN_AWS_PRINCIPALS accepts AWS SigV4 credentials for the listed IAM
+// principals. Any combination may be set.
+func AuthFromEnv() (Authenticator, error) {
+	var auths AnyOf
+	if token, ok := os.LookupEnv("ADMIN_TOKEN"); ok {
+		auths = append(auths, StaticToken(token))
+	}
+	if issuer := os.Getenv("ADMIN_OIDC_ISSUER"); issuer != "" {
+		audience := os.Getenv("ADMIN_OIDC_AUDIENCE")
+		if audience == "" {
+			return nil, errors.New("ADMIN_OIDC_AUDIENCE is required with ADMIN_OIDC_ISSUER")
+		}
+		auths = append(auths, &OIDC{Issuer: issuer, Audience: audience})
+	}
+	if principals := os.Getenv("ADMIN_AWS_PRINCIPALS"); principals != "" {
+		auths = append(auths, &AWSIdentity{Principals: strings.Split(principals, ",")})
+	}
+
+	switch len(auths) {
+	case 0:
+		return nil, nil
+	case 1:
+		return auths[0], nil
+	default:
+		return auths, nil
+	}
+}
+
+// AWSTokenPrefix starts a bearer token carrying a presigned AWS STS
+// GetCallerIdentity request, encoded with unpadded URL-safe base64.
+const AWSTokenPrefix = "aws-sts."
+
+// AWSAudienceHeader must be included in the signature of a presigned STS
+// request, with a value matching the AWSIdentity's audience, so that a token
+// made for one service can't be replayed to another.
+const AWSAudienceHeader = "X-Randomizer-Audience"
+
+// DefaultAWSAudience is the default audience of an AWSIdentity.
+const DefaultAWSAudience = "randomizer-admin"
+
+// maxAWSTokenAge bounds how long a presigned STS request is accepted after it
+// was signed.
+const maxAWSTokenAge = 15 * time.Minute
+
+// AWSIdentity authorizes requests from AWS IAM principals, which prove their
+// identity with a SigV4-signed STS GetCallerIdentity request that the server
+// forwards to STS. This is the same technique that Kubernetes clusters on EKS
+// use to accept IAM credentials, and works without sharing any secret with
+// the server.
+type AWSIdentity struct {
+	// Principals lists the IAM ARNs that are authorized, as path.Match patterns
+	// (e.g. "arn:aws:sts::123456789012:assumed-role/Operators/*").
+	Principals []string
+	// Audience sets the value of AWSAudienceHeader that tokens must sign. If
+	// empty, it defaults to DefaultAWSAudience.
+	Audience string
+	// HTTPClient overrides http.DefaultClient.
+	HTTPClient *http.Client
+	// Validate overrides the check that the presigned request targets AWS STS,
+	// for tests.
+	Validate func(*url.URL) error
+	// Clock, if non-nil, replaces the system clock for checking the age of
+	// tokens.
+	Clock clock.Clock
+}
+
+func (a *AWSIdentity) Authenticate(r *http.Request) (string, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return "", errNoCredentials
+	}
+	encoded, ok := strings.CutPrefix(token, AWSTokenPrefix)
+	if !ok {
+		return "", errNoCredentials
+	}
+	rawURL, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("decoding AWS token: %w", err)
+	}
+	target, err := url.Parse(string(rawURL))
+	if err != nil {
+		return "", fmt.Errorf("parsing AWS token: %w", err)
+	}
+
+	validate := a.Validate
+	if validate == nil {
+		validate = func(target *url.URL) error {
+			return validateSTSRequest(target, clock.Or(a.Clock).Now())
+		}
+	}
+	if err := validate(target); err != nil {
+		return "", err
+	}
+
+	audience := a.Audience
+	if audience == "" {
+		audience = DefaultAWSAudience
+	}
+	if !signsHeader(target.Query().Get("X-Amz-SignedHeaders"), AWSAudienceHeader) {
+		return "", errors.New("AWS token does not sign the audience header")
+	}
+
+	arn, err := a.callerIdentity(r.Context(), target, audience)
+	if err != nil {
+		return "", err
+	}
+	for _, pattern := range a.Principals {
+		if ok, _ := path.Match(strings.TrimSpace(pattern), arn); ok {
+			return arn, nil
+		}
+	}
+	return "", fmt.Errorf("AWS principal %s is not authorized", arn)
+}
+
+// validateSTSRequest checks that a presigned request is a recent
+// GetCallerIdentity call to an AWS STS endpoint, so that the server never
+// forwards a token anywhere else.
+func validateSTSRequest(target *url.URL, now time.Time) error {
+	// Regional endpoints have exactly one label between "sts." and the
+	// domain, which keeps out other hosts under amazonaws.com that customers
+	// control, like S3 website buckets.
+	host := target.Hostname()
+	region, hasPrefix := strings.CutPrefix(host, "sts.")
+	region, hasSuffix := strings.CutSuffix(region, ".amazonaws.com")
+	regional := hasPrefix && hasSuffix && region != "" && !strings.Contains(region, ".")
+	if target.Scheme != "https" || (host != "sts.amazonaws.com" && !regional) {
+		return fmt.Errorf("AWS token targets unexpected host %q", host)
+	}
+
+	query := target.Query()
+	if query.Get("Action") != "GetCallerIdentity" {
+		return errors.New("AWS token is not a GetCallerIdentity request")
+	}
+	signed, err := time.Parse("20060102T150405Z", query.Get("X-Amz-Date"))
+	if err != nil {
+		return errors.New("AWS token has no valid signing date")
+	}
+	if age := now.Sub(signed); age > maxAWSTokenAge || age < -maxAWSTokenAge {
+		return errors.New("AWS token has expired")
+	}
+	return nil
+}
+
+func signsHeader(signedHeaders, header string) bool {
+	for h := range strings.SplitSeq(signedHeaders, ";") {
+		if strings.EqualFold(h, header) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *AWSIdentity) callerIdentity(ctx context.Context, target *url.URL, audience string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set(AWSAudienceHeader, audience)
+	req.Header.Set("Accept", "application/xml")
+
+	client := a.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("calling STS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("STS rejected AWS token with %s", resp.Status)
+	}
+
+	var body struct {
+		Result struct {
+			Arn string `xml:"Arn"`
+		} `xml:"GetCallerIdentityResult"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&body); err != nil || body.Result.Arn == "" {
+		return "", errors.New("STS returned no caller identity")
+	}
+	return body.Result.Arn, nil
+}
+
+// jwksTTL bounds how long an OIDC authenticator trusts a provider's signing
+// keys before fetching them again. Unknown key IDs trigger a fetch sooner, no
+// more than once per jwksMinRefresh.
+const (
+	jwksTTL        = time.Hour
+	jwksMinRefresh = time.Minute
+	jwtLeeway      = time.Minute
+)
+
+// OIDC authorizes requests with bearer tokens that are JWTs signed by an
+// OpenID Connect provider, like an identity provider's machine-to-machine
+// tokens or a CI system's workload identity tokens. It supports the RS256,
+// RS384, RS512, ES256, and ES384 algorithms.
+type OIDC struct {
+	// Issuer is the provider's issuer URL, which tokens must match in their
+	// "iss" claim, and which serves the provider's discovery document.
+	Issuer string
+	// Audience must appear in each token's "aud" claim.
+	Audience string
+	// HTTPClient overrides http.DefaultClient.
+	HTTPClient *http.Client
+	// Clock, if non-nil, replaces the system clock for checking token expiry
+	// and refreshing the provider's keys.
+	Clock clock.Clock
+
+	mu       sync.Mutex
+	keys     map[string]crypto.PublicKey
+	fetched  time.Time
+	fetching *jwksFetch
+}
+
+// jwksFetch is a fetch of the provider's keys, which requests that need the
+// keys while it's in flight wait for rather than starting their own.
+type jwksFetch struct {
+	done chan struct{}
+	keys map[string]crypto.PublicKey
+	err  error
+}
+
+func (o *OIDC) Authenticate(r *http.Request) (string, error) {
+	token, ok := bearerToken(r)
+	if !ok || strings.Count(token, ".") != 2 {
+		return "", errNoCredentials
+	}
+
+	parts := strings.Split(token, ".")
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return "", fmt.Errorf("decoding JWT header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("decoding JWT signature: %w", err)
+	}
+
+	key, err := o.key(r.Context(), header.Kid)
+	if err != nil {
+		return "", err
+	}
+	if err := verifyJWT(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return "", err
+	}
+
+	var claims struct {
+		Issuer    string          `json:"iss"`
+		Subject   string          `json:"sub"`
+		Audience  json.RawMessage `json:"aud"`
+		Expiry    *int64          `json:"exp"`
+		NotBefore *int64          `json:"nbf"`
+	}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return "", fmt.Errorf("decoding JWT claims: %w", err)
+	}
+
+	now := clock.Or(o.Clock).Now()
+	switch {
+	case claims.Issuer != o.Issuer:
+		return "", fmt.Errorf("JWT has unexpected issuer %q", claims.Issuer)
+	case !hasAudience(claims.Audience, o.Audience):
+		return "", errors.New("JWT has unexpected audience")
+	case claims.Expiry == nil || now.After(time.Unix(*claims.Expiry, 0).Add(jwtLeeway)):
+		return "", errors.New("JWT has expired")
+	case claims.NotBefore != nil && now.Add(jwtLeeway).Before(time.Unix(*claims.NotBefore, 0)):
+		return "", errors.New("JWT is not valid yet")
+	}
+	return claims.Subject, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func hasAudience(raw json.RawMessage, want string) bool {
+	var single string
+	if json.Unmarshal(raw, &single) == nil {
+		return single == want
+	}
+	var many []string
+	if json.Unmarshal(raw, &many) == nil {
+		for _, aud := range many {
+			if aud == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jwtCurves maps each ECDSA algorithm to the curve that its keys must use.
+var jwtCurves = map[string]elliptic.Curve{
+	"ES256": elliptic.P256(),
+	"ES384": elliptic.P384(),
+}
+
+func verifyJWT(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported JWT algorithm %q", alg)
+	}
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			break
+		}
+		if rsa.VerifyPKCS1v15(key, hash, digest, signature) != nil {
+			return errors.New("invalid JWT signature")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		// Each algorithm fixes its curve, and with it the signature's length.
+		if key.Curve != jwtCurves[alg] || len(signature) != 2*((key.Curve.Params().BitSize+7)/8) {
+			break
+		}
+		half := len(signature) / 2
+		r, s := new(big.Int).SetBytes(signature[:half]), new(big.Int).SetBytes(signature[half:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return errors.New("invalid JWT signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("JWT algorithm %q does not match its key", alg)
+}
+
+// key returns the provider's signing key with the provided ID, fetching the
+// provider's keys if they're stale or don't include it. Only one fetch runs
+// at a time, without holding the lock, so requests with fresh keys never wait
+// on the provider.
+func (o *OIDC) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	o.mu.Lock()
+	age := clock.Or(o.Clock).Now().Sub(o.fetched)
+	key, ok := o.keys[kid]
+	if ok && age < jwksTTL {
+		o.mu.Unlock()
+		return key, nil
+	}
+	if !ok && o.keys != nil && age < jwksMinRefresh {
+		o.mu.Unlock()
+		return nil, fmt.Errorf("unknown JWT key %q", kid)
+	}
+	fetch, leader := o.fetching, o.fetching == nil
+	if leader {
+		fetch = &jwksFetch{done: make(chan struct{})}
+		o.fetching = fetch
+	}
+	o.mu.Unlock()
+
+	if leader {
+		fetch.keys, fetch.err = o.fetchKeys(ctx)
+		o.mu.Lock()
+		if fetch.err == nil {
+			o.keys, o.fetched = fetch.keys, clock.Or(o.Clock).Now()
+		}
+		o.fetching = nil
+		o.mu.Unlock()
+		close(fetch.done)
+	}
+
+	select {
+	case <-fetch.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if fetch.err != nil {
+		return nil, fmt.Errorf("fetching OIDC keys: %w", fetch.err)
+	}
+	if key, ok = fetch.keys[kid]; !ok {
+		return nil, fmt.Errorf("unknown JWT key %q", kid)
+	}
+	return key, nil
+}
+
+func (o *OIDC) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var discovery struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := o.getJSON(ctx, strings.TrimSuffix(o.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := o.getJSON(ctx, discovery.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		switch k.Kty {
+		case "RSA":
+			n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+			e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch k.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			default:
+				continue
+			}
+			x, err1 := base64.RawURLEncoding.DecodeString(k.X)
+			y, err2 := base64.RawURLEncoding.DecodeString(k.Y)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	return keys, nil
+}
+
+func (o *OIDC) getJSON(ctx context.Context, target string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	client := o.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", target, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// principalKey holds the authenticated principal in a request's context.
+type principalKey struct{}
+
+func principalFrom(ctx context.Context) string {
+	p, _ := ctx.Value(principalKey{}).(string)
+	return p
+}
@@ -0,0 +1,289 @@
+package admin
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/clock/clocktest"
+)
+
+func TestOIDC(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var issuer string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"jwks_uri": issuer + "/keys"})
+		case "/keys":
+			json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+				"kid": "k1",
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	issuer = srv.URL
+
+	sign := func(claims map[string]any) string {
+		header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "k1"})
+		payload, _ := json.Marshal(claims)
+		signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+		digest := sha256.Sum256([]byte(signed))
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+	}
+	clock := clocktest.New(time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC))
+	exp := clock.Now().Add(time.Hour).Unix()
+
+	auth := &OIDC{Issuer: issuer, Audience: "randomizer", Clock: clock}
+	testCases := []struct {
+		description string
+		token       string
+		wantOK      bool
+	}{
+		{"valid token", sign(map[string]any{"iss": issuer, "aud": "randomizer", "sub": "ops", "exp": exp}), true},
+		{"audience list", sign(map[string]any{"iss": issuer, "aud": []string{"other", "randomizer"}, "sub": "ops", "exp": exp}), true},
+		{"wrong audience", sign(map[string]any{"iss": issuer, "aud": "other", "exp": exp}), false},
+		{"wrong issuer", sign(map[string]any{"iss": "https://evil.example", "aud": "randomizer", "exp": exp}), false},
+		{"expired", sign(map[string]any{"iss": issuer, "aud": "randomizer", "exp": clock.Now().Add(-time.Hour).Unix()}), false},
+		{"no expiry", sign(map[string]any{"iss": issuer, "aud": "randomizer"}), false},
+		{"tampered", sign(map[string]any{"iss": issuer, "aud": "randomizer", "exp": exp}) + "x", false},
+		{"not a JWT", "right", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/v1/flags", nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			principal, err := auth.Authenticate(req)
+			if ok := err == nil; ok != tc.wantOK {
+				t.Fatalf("got error %v, want success %v", err, tc.wantOK)
+			}
+			if tc.wantOK && principal != "ops" {
+				t.Errorf("got principal %q, want %q", principal, "ops")
+			}
+		})
+	}
+
+	clock.Advance(2 * time.Hour)
+	req := httptest.NewRequest(http.MethodGet, "/admin/v1/flags", nil)
+	req.Header.Set("Authorization", "Bearer "+testCases[0].token)
+	if _, err := auth.Authenticate(req); err == nil {
+		t.Error("token still valid after it expired")
+	}
+}
+
+func TestOIDCCurves(t *testing.T) {
+	p256, err1 := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, err2 := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err := errors.Join(err1, err2); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		issuer  string
+		fetches atomic.Int32
+		gate    = make(chan struct{})
+	)
+	close(gate)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"jwks_uri": issuer + "/keys"})
+		case "/keys":
+			fetches.Add(1)
+			<-gate
+			var keys []map[string]string
+			for kid, key := range map[string]*ecdsa.PrivateKey{"p256": p256, "p384": p384} {
+				size := (key.Curve.Params().BitSize + 7) / 8
+				keys = append(keys, map[string]string{
+					"kid": kid,
+					"kty": "EC",
+					"crv": key.Curve.Params().Name,
+					"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size))),
+					"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size))),
+				})
+			}
+			json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	issuer = srv.URL
+
+	clock := clocktest.New(time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC))
+	claims, _ := json.Marshal(map[string]any{"iss": issuer, "aud": "randomizer", "sub": "ops", "exp": clock.Now().Add(time.Hour).Unix()})
+	sign := func(alg, kid string, key *ecdsa.PrivateKey, hash crypto.Hash) string {
+		header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid})
+		signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+		h := hash.New()
+		h.Write([]byte(signed))
+		r, s, err := ecdsa.Sign(rand.Reader, key, h.Sum(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		sig := append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...)
+		return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+	}
+	authenticate := func(auth *OIDC, ctx context.Context, token string) error {
+		req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/admin/v1/flags", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		_, err := auth.Authenticate(req)
+		return err
+	}
+
+	auth := &OIDC{Issuer: issuer, Audience: "randomizer", Clock: clock}
+	testCases := []struct {
+		description string
+		token       string
+		wantOK      bool
+	}{
+		{"ES256 with P-256", sign("ES256", "p256", p256, crypto.SHA256), true},
+		{"ES384 with P-384", sign("ES384", "p384", p384, crypto.SHA384), true},
+		{"ES256 with P-384", sign("ES256", "p384", p384, crypto.SHA256), false},
+		{"ES384 with P-256", sign("ES384", "p256", p256, crypto.SHA384), false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			if err := authenticate(auth, t.Context(), tc.token); (err == nil) != tc.wantOK {
+				t.Errorf("got error %v, want success %v", err, tc.wantOK)
+			}
+		})
+	}
+
+	// While the keys are being fetched, other requests wait for that fetch
+	// without blocking on it, and can give up.
+	gate = make(chan struct{})
+	fetches.Store(0)
+	auth = &OIDC{Issuer: issuer, Audience: "randomizer", Clock: clock}
+	done := make(chan error, 2)
+	for range 2 {
+		go func() { done <- authenticate(auth, context.Background(), testCases[0].token) }()
+	}
+	for fetches.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := authenticate(auth, ctx, testCases[0].token); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v for a canceled request during a fetch, want context.Canceled", err)
+	}
+	close(gate)
+	for range 2 {
+		if err := <-done; err != nil {
+			t.Errorf("request during a fetch failed: %v", err)
+		}
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("fetched keys %d times for concurrent requests, want 1", n)
+	}
+}
+
+func TestAWSIdentity(t *testing.T) {
+	sts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(AWSAudienceHeader) != DefaultAWSAudience {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`<GetCallerIdentityResponse><GetCallerIdentityResult>` +
+			`<Arn>arn:aws:sts::123456789012:assumed-role/Operators/alice</Arn>` +
+			`</GetCallerIdentityResult></GetCallerIdentityResponse>`))
+	}))
+	defer sts.Close()
+
+	token := func(signedHeaders string) string {
+		query := url.Values{
+			"Action":              {"GetCallerIdentity"},
+			"X-Amz-SignedHeaders": {signedHeaders},
+		}
+		return AWSTokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(sts.URL+"/?"+query.Encode()))
+	}
+	newAuth := func(principals ...string) *AWSIdentity {
+		return &AWSIdentity{Principals: principals, Validate: func(*url.URL) error { return nil }}
+	}
+
+	testCases := []struct {
+		description string
+		auth        *AWSIdentity
+		token       string
+		wantOK      bool
+	}{
+		{"authorized role", newAuth("arn:aws:sts::123456789012:assumed-role/Operators/*"), token("host;x-randomizer-audience"), true},
+		{"unauthorized role", newAuth("arn:aws:sts::123456789012:assumed-role/Admins/*"), token("host;x-randomizer-audience"), false},
+		{"unsigned audience", newAuth("*"), token("host"), false},
+		{"static token", newAuth("*"), "right", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/v1/flags", nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			_, err := tc.auth.Authenticate(req)
+			if ok := err == nil; ok != tc.wantOK {
+				t.Errorf("got error %v, want success %v", err, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidateSTSRequest(t *testing.T) {
+	at := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	now := at.Format("20060102T150405Z")
+	testCases := []struct {
+		url    string
+		wantOK bool
+	}{
+		{"https://sts.amazonaws.com/?Action=GetCallerIdentity&X-Amz-Date=" + now, true},
+		{"https://sts.us-west-2.amazonaws.com/?Action=GetCallerIdentity&X-Amz-Date=" + now, true},
+		{"https://sts.amazonaws.com.evil.example/?Action=GetCallerIdentity&X-Amz-Date=" + now, false},
+		{"https://evil.amazonaws.com/?Action=GetCallerIdentity&X-Amz-Date=" + now, false},
+		{"https://sts.s3-website.us-east-1.amazonaws.com/?Action=GetCallerIdentity&X-Amz-Date=" + now, false},
+		{"http://sts.amazonaws.com/?Action=GetCallerIdentity&X-Amz-Date=" + now, false},
+		{"https://sts.amazonaws.com/?Action=AssumeRole&X-Amz-Date=" + now, false},
+		{"https://sts.amazonaws.com/?Action=GetCallerIdentity&X-Amz-Date=20261017T114000Z", false},
+	}
+	for _, tc := range testCases {
+		target, _ := url.Parse(tc.url)
+		if err := validateSTSRequest(target, at); (err == nil) != tc.wantOK {
+			t.Errorf("%s: got error %v, want success %v", tc.url, err, tc.wantOK)
+		}
+	}
+}
+
+func TestAnyOf(t *testing.T) {
+	api := API{Auth: AnyOf{StaticToken("first"), StaticToken("right")}}
+	resp := serveAuthorized(api, http.MethodGet, "/admin/v1/flags", "")
+	if resp.Code != http.StatusOK {
+		t.Errorf("got status %v, want %v", resp.Code, http.StatusOK)
+	}
+	if !strings.Contains(resp.Body.String(), "flags") {
+		t.Errorf("unexpected body %q", resp.Body)
+	}
+}
@@ -0,0 +1,142 @@
+package admin
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// exportFlushEvery sets how many lines an export writes between flushes, so
+// that clients see steady progress without a flush for every line.
+const exportFlushEvery = 100
+
+// exportLine is a single group or record in a streaming export.
+type exportLine struct {
+	Partition string   `json:"partition"`
+	Group     string   `json:"group"`
+	Options   []string `json:"options"`
+	// Cursor resumes an interrupted export after this line.
+	Cursor string `json:"cursor"`
+}
+
+// exportCursor encodes the position of a line in an export, which is ordered
+// by partition and then by group.
+func exportCursor(partition, group string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(partition + "\x00" + group))
+}
+
+func parseExportCursor(cursor string) (partition, group string, err error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", "", err
+	}
+	partition, group, ok := strings.Cut(string(raw), "\x00")
+	if !ok {
+		return "", "", errors.New("malformed cursor")
+	}
+	return partition, group, nil
+}
+
+// exportData streams every group and record in every partition as JSON Lines,
+// reading one group at a time so that memory use doesn't grow with the size of
+// the dataset. The "after" query parameter takes the cursor of the last line
+// that a client received, to resume an interrupted export.
+//
+// Once streaming starts, the status can no longer change, so a failure
+// partway through ends the stream with an {"error": ...} line instead.
+func (a API) exportData(w http.ResponseWriter, r *http.Request) {
+	lister, ok := a.StoreFactory(listerPartition).(PartitionLister)
+	if !ok {
+		a.writeError(w, http.StatusNotImplemented, errors.New("store backend can't list partitions"))
+		return
+	}
+
+	var afterPartition, afterGroup string
+	if after := r.URL.Query().Get("after"); after != "" {
+		var err error
+		if afterPartition, afterGroup, err = parseExportCursor(after); err != nil {
+			a.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid cursor: %w", err))
+			return
+		}
+	}
+
+	ctx := r.Context()
+	partitions, err := lister.Partitions(ctx)
+	if err != nil {
+		a.writeError(w, http.StatusInternalServerError, fmt.Errorf("listing partitions: %w", err))
+		return
+	}
+	slices.Sort(partitions)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+
+	var written int
+	err = func() error {
+		for _, partition := range partitions {
+			if partition < afterPartition {
+				continue
+			}
+			var after string
+			if partition == afterPartition {
+				after = afterGroup
+			}
+			err := a.exportPartition(ctx, partition, after, func(group string, options []string) error {
+				line := exportLine{partition, group, options, exportCursor(partition, group)}
+				if err := enc.Encode(line); err != nil {
+					return err
+				}
+				if written++; written%exportFlushEvery == 0 && flusher != nil {
+					flusher.Flush()
+				}
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("exporting %q: %w", partition, err)
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		if a.Logger != nil {
+			a.Logger.Error("Export failed", "err", err, "lines", written)
+		}
+		enc.Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	a.logInfo(ctx, "Exported data", "lines", written)
+}
+
+// exportPartition calls emit for each group and record in a partition whose
+// name sorts after the provided one, in order by name.
+func (a API) exportPartition(ctx context.Context, partition, after string, emit func(group string, options []string) error) error {
+	store := a.StoreFactory(partition)
+	groups, err := store.List(ctx)
+	if err != nil {
+		return err
+	}
+	slices.Sort(groups)
+	for _, group := range groups {
+		if after != "" && group <= after {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		options, err := store.Get(ctx, group)
+		if err != nil {
+			return err
+		}
+		if err := emit(group, options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
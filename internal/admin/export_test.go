@@ -0,0 +1,80 @@
+package admin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+)
+
+// listableStore serves one partition of a set of partitions, and can list
+// them all.
+type listableStore struct {
+	rndtest.Store
+	all map[string]rndtest.Store
+}
+
+func (s listableStore) Partitions(_ context.Context) ([]string, error) {
+	return slices.Collect(maps.Keys(s.all)), nil
+}
+
+func TestExportData(t *testing.T) {
+	partitions := map[string]rndtest.Store{
+		"C2": {"snacks": {"chips", "pretzels"}},
+		"C1": {"lunch": {"ramen", "sushi"}, "/history/lunch": {"2026-10-16T12:00:00.000000000Z|ramen"}},
+	}
+	api := API{
+		Token: "right",
+		StoreFactory: func(partition string) randomizer.Store {
+			return listableStore{Store: partitions[partition], all: partitions}
+		},
+	}
+
+	export := func(after string) []exportLine {
+		path := "/admin/v1/export"
+		if after != "" {
+			path += "?after=" + after
+		}
+		resp := serveAuthorized(api, http.MethodGet, path, "")
+		if resp.Code != http.StatusOK {
+			t.Fatalf("got status %v, want %v", resp.Code, http.StatusOK)
+		}
+		var lines []exportLine
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			var line exportLine
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				t.Fatalf("decoding line %q: %v", scanner.Text(), err)
+			}
+			lines = append(lines, line)
+		}
+		return lines
+	}
+
+	lines := export("")
+	var got []string
+	for _, line := range lines {
+		got = append(got, line.Partition+"/"+line.Group)
+	}
+	want := []string{"C1//history/lunch", "C1/lunch", "C2/snacks"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got lines %v, want %v", got, want)
+	}
+
+	resumed := export(lines[0].Cursor)
+	if len(resumed) != 2 || resumed[0].Group != "lunch" || !slices.Equal(resumed[1].Options, []string{"chips", "pretzels"}) {
+		t.Errorf("resumed export got %+v", resumed)
+	}
+
+	resp := serveAuthorized(api, http.MethodGet, "/admin/v1/export?after=!!!", "")
+	if resp.Code != http.StatusBadRequest || !strings.Contains(resp.Body.String(), "cursor") {
+		t.Errorf("invalid cursor got status %v", resp.Code)
+	}
+}
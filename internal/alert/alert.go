@@ -0,0 +1,242 @@
+// Package alert notifies operators when the randomizer's error rates spike.
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/clock"
+)
+
+// Kind identifies a class of error that the randomizer tracks for alerting.
+type Kind string
+
+const (
+	// Verification counts requests rejected for an invalid Slack token.
+	Verification Kind = "verification"
+	// Store counts requests that failed because saved groups were
+	// unavailable.
+	Store Kind = "store"
+	// Timeout counts requests that ran out of time before responding.
+	Timeout Kind = "timeout"
+	// Notice marks one-off notifications that aren't counted against a
+	// threshold, like a workspace uninstalling the app.
+	Notice Kind = "notice"
+)
+
+const (
+	// DefaultWindow is the default period over which a Tracker counts errors.
+	DefaultWindow = 5 * time.Minute
+	// DefaultThreshold is the default number of errors of a single kind within
+	// the window that triggers an alert.
+	DefaultThreshold = 10
+	// notifyTimeout bounds the time that a request spends sending an alert.
+	notifyTimeout = 5 * time.Second
+)
+
+// Alert describes an error rate that exceeded its threshold, or a [Notice].
+type Alert struct {
+	Kind      Kind
+	Count     int
+	Threshold int
+	Window    time.Duration
+	At        time.Time
+	// Message describes a Notice in place of the error counts.
+	Message string
+}
+
+// Summary returns a one-line description of the alert for operators.
+func (a Alert) Summary() string {
+	if a.Kind == Notice {
+		return "randomizer: " + a.Message
+	}
+	return fmt.Sprintf(
+		"randomizer: %d %s errors in the last %v (threshold %d)",
+		a.Count, a.Kind, a.Window, a.Threshold,
+	)
+}
+
+// Notifier delivers alerts to operators.
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// Tracker counts errors by kind over a sliding window, and notifies operators
+// when any kind exceeds its threshold. After an alert, a Tracker stays quiet
+// about the same kind for the rest of the window, so that a sustained outage
+// produces one alert per window rather than one per error.
+//
+// A nil *Tracker ignores all errors.
+type Tracker struct {
+	// Window sets the period over which errors are counted. If zero, it
+	// defaults to DefaultWindow.
+	Window time.Duration
+	// Thresholds sets the number of errors of each kind within the window that
+	// triggers an alert. Kinds without a threshold use DefaultThreshold.
+	Thresholds map[Kind]int
+	// Notifiers receive every alert.
+	Notifiers []Notifier
+	// Logger, if non-nil, logs every alert along with any failure to deliver
+	// it.
+	Logger *slog.Logger
+	// Clock, if non-nil, replaces the system clock for counting errors within
+	// the window and spacing out repeated alerts.
+	Clock clock.Clock
+
+	mu        sync.Mutex
+	errors    map[Kind][]time.Time
+	lastAlert map[Kind]time.Time
+}
+
+// TrackerFromEnv returns a Tracker based on available environment variables,
+// or nil if no notifiers are configured.
+//
+// ALERT_SLACK_WEBHOOK_URL sends alerts to a Slack incoming webhook, such as one
+// for an ops channel. ALERT_SNS_TOPIC_ARN publishes alerts to an AWS SNS topic.
+// ALERT_PAGERDUTY_ROUTING_KEY triggers PagerDuty events through the Events API
+// v2. Any combination may be set.
+//
+// ALERT_WINDOW optionally sets the window as a Go duration, and
+// ALERT_THRESHOLDS optionally sets thresholds as comma-separated "kind=count"
+// pairs, like "verification=50,store=5,timeout=5".
+func TrackerFromEnv(logger *slog.Logger) (*Tracker, error) {
+	var notifiers []Notifier
+	if url := os.Getenv("ALERT_SLACK_WEBHOOK_URL"); url != "" {
+		notifiers = append(notifiers, SlackWebhook{URL: url})
+	}
+	if arn := os.Getenv("ALERT_SNS_TOPIC_ARN"); arn != "" {
+		notifiers = append(notifiers, SNS{TopicARN: arn})
+	}
+	if key := os.Getenv("ALERT_PAGERDUTY_ROUTING_KEY"); key != "" {
+		notifiers = append(notifiers, PagerDuty{RoutingKey: key})
+	}
+	if len(notifiers) == 0 {
+		return nil, nil
+	}
+
+	tracker := &Tracker{Notifiers: notifiers, Logger: logger}
+	if env, ok := os.LookupEnv("ALERT_WINDOW"); ok {
+		window, err := time.ParseDuration(env)
+		if err != nil || window <= 0 {
+			return nil, fmt.Errorf("ALERT_WINDOW is not a valid positive duration: %q", env)
+		}
+		tracker.Window = window
+	}
+	if env, ok := os.LookupEnv("ALERT_THRESHOLDS"); ok {
+		thresholds, err := parseThresholds(env)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ALERT_THRESHOLDS: %w", err)
+		}
+		tracker.Thresholds = thresholds
+	}
+	return tracker, nil
+}
+
+func parseThresholds(spec string) (map[Kind]int, error) {
+	thresholds := make(map[Kind]int)
+	for pair := range strings.SplitSeq(spec, ",") {
+		kind, count, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		switch Kind(kind) {
+		case Verification, Store, Timeout:
+		default:
+			return nil, fmt.Errorf("unknown error kind %q", kind)
+		}
+		n, err := strconv.Atoi(count)
+		if !ok || err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid threshold %q for %s", count, kind)
+		}
+		thresholds[Kind(kind)] = n
+	}
+	return thresholds, nil
+}
+
+// Record counts an error of the provided kind, and sends an alert if that
+// kind's count within the window has reached its threshold. Alerts are sent
+// before Record returns, since an environment like AWS Lambda may freeze any
+// work left in the background.
+func (t *Tracker) Record(ctx context.Context, kind Kind) {
+	if t == nil {
+		return
+	}
+
+	alert, ok := t.count(kind, clock.Or(t.Clock).Now())
+	if !ok {
+		return
+	}
+
+	if t.Logger != nil {
+		t.Logger.Warn("Error rate exceeded alert threshold",
+			"kind", alert.Kind, "count", alert.Count, "window", alert.Window)
+	}
+	t.send(ctx, alert)
+}
+
+// Notify sends a [Notice] with the provided message to every notifier, without
+// counting it against any threshold. Like Record, it returns after sending.
+func (t *Tracker) Notify(ctx context.Context, message string) {
+	if t == nil {
+		return
+	}
+	if t.Logger != nil {
+		t.Logger.Info("Notifying operators", "message", message)
+	}
+	t.send(ctx, Alert{Kind: Notice, At: clock.Or(t.Clock).Now(), Message: message})
+}
+
+func (t *Tracker) send(ctx context.Context, alert Alert) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+	defer cancel()
+	var errs []error
+	for _, n := range t.Notifiers {
+		errs = append(errs, n.Notify(ctx, alert))
+	}
+	if err := errors.Join(errs...); err != nil && t.Logger != nil {
+		t.Logger.Error("Failed to send alert", "kind", alert.Kind, "err", err)
+	}
+}
+
+// count records an error at now, and returns an alert if one is due.
+func (t *Tracker) count(kind Kind, now time.Time) (Alert, bool) {
+	window := t.Window
+	if window == 0 {
+		window = DefaultWindow
+	}
+	threshold := t.Thresholds[kind]
+	if threshold == 0 {
+		threshold = DefaultThreshold
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.errors == nil {
+		t.errors = make(map[Kind][]time.Time)
+		t.lastAlert = make(map[Kind]time.Time)
+	}
+
+	cutoff := now.Add(-window)
+	recent := t.errors[kind]
+	for len(recent) > 0 && !recent[0].After(cutoff) {
+		recent = recent[1:]
+	}
+	recent = append(recent, now)
+	t.errors[kind] = recent
+
+	if len(recent) < threshold || t.lastAlert[kind].After(cutoff) {
+		return Alert{}, false
+	}
+	t.lastAlert[kind] = now
+	return Alert{
+		Kind:      kind,
+		Count:     len(recent),
+		Threshold: threshold,
+		Window:    window,
+		At:        now,
+	}, true
+}
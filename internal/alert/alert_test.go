@@ -0,0 +1,88 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTrackerThreshold(t *testing.T) {
+	tracker := &Tracker{Window: time.Minute, Thresholds: map[Kind]int{Store: 3}}
+	start := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	var alerts []Alert
+	for i, offset := range []time.Duration{
+		0,
+		10 * time.Second,
+		20 * time.Second,  // Third error in the window: alert
+		30 * time.Second,  // Still within the window of the last alert
+		90 * time.Second,  // The window now starts after the first 3 errors
+		100 * time.Second, // Only 2 errors in the window
+		110 * time.Second, // Third error in the new window: alert
+	} {
+		if alert, ok := tracker.count(Store, start.Add(offset)); ok {
+			alerts = append(alerts, alert)
+			t.Logf("alert after error %d: %s", i, alert.Summary())
+		}
+	}
+
+	if len(alerts) != 2 {
+		t.Fatalf("got %d alerts, want 2", len(alerts))
+	}
+	if alerts[0].Count != 3 || alerts[1].Count != 3 {
+		t.Errorf("got alert counts %d and %d, want 3 and 3", alerts[0].Count, alerts[1].Count)
+	}
+}
+
+func TestNotifiers(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies = make(map[string]map[string]any)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		bodies[r.URL.Path] = body
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	tracker := &Tracker{
+		Thresholds: map[Kind]int{Timeout: 1},
+		Notifiers: []Notifier{
+			SlackWebhook{URL: srv.URL + "/slack"},
+			PagerDuty{RoutingKey: "key", URL: srv.URL + "/pagerduty"},
+		},
+	}
+	tracker.Record(context.Background(), Timeout)
+
+	if text, _ := bodies["/slack"]["text"].(string); !strings.Contains(text, "1 timeout errors") {
+		t.Errorf("unexpected Slack webhook text %q", text)
+	}
+	if key, _ := bodies["/pagerduty"]["dedup_key"].(string); key != "randomizer-timeout" {
+		t.Errorf("unexpected PagerDuty dedup key %q", key)
+	}
+	tracker.Notify(context.Background(), "workspace T123 uninstalled the app")
+	if text, _ := bodies["/slack"]["text"].(string); !strings.Contains(text, "workspace T123 uninstalled") {
+		t.Errorf("unexpected Slack webhook text for notice %q", text)
+	}
+	if _, ok := bodies["/pagerduty"]["dedup_key"]; ok {
+		t.Error("PagerDuty notice has a dedup key")
+	}
+}
+
+func TestParseThresholds(t *testing.T) {
+	got, err := parseThresholds("verification=50, store=5")
+	if err != nil || got[Verification] != 50 || got[Store] != 5 {
+		t.Errorf("parseThresholds() = %v, %v", got, err)
+	}
+	if _, err := parseThresholds("bogus=1"); err == nil {
+		t.Error("parseThresholds() accepted an unknown kind")
+	}
+}
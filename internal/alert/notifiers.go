@@ -0,0 +1,133 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+
+	"github.com/featherbread/randomizer/internal/awsconfig"
+)
+
+// SlackWebhook posts alerts to a Slack incoming webhook.
+type SlackWebhook struct {
+	URL string
+	// HTTPClient overrides http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+func (s SlackWebhook) Notify(ctx context.Context, alert Alert) error {
+	emoji := ":rotating_light: "
+	if alert.Kind == Notice {
+		emoji = ":information_source: "
+	}
+	return postJSON(ctx, s.HTTPClient, s.URL, map[string]string{
+		"text": emoji + alert.Summary(),
+	})
+}
+
+// DefaultPagerDutyURL is the endpoint for the PagerDuty Events API v2.
+const DefaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDuty triggers events through the PagerDuty Events API v2. Alerts of the
+// same kind share a deduplication key, so PagerDuty groups repeated alerts
+// into a single incident.
+type PagerDuty struct {
+	RoutingKey string
+	// URL overrides DefaultPagerDutyURL.
+	URL string
+	// HTTPClient overrides http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+func (p PagerDuty) Notify(ctx context.Context, alert Alert) error {
+	url := p.URL
+	if url == "" {
+		url = DefaultPagerDutyURL
+	}
+	if alert.Kind == Notice {
+		// Without a dedup_key, PagerDuty keeps each notice separate.
+		return postJSON(ctx, p.HTTPClient, url, map[string]any{
+			"routing_key":  p.RoutingKey,
+			"event_action": "trigger",
+			"payload": map[string]any{
+				"summary":   alert.Summary(),
+				"source":    "randomizer",
+				"severity":  "info",
+				"timestamp": alert.At.UTC().Format(time.RFC3339),
+			},
+		})
+	}
+	return postJSON(ctx, p.HTTPClient, url, map[string]any{
+		"routing_key":  p.RoutingKey,
+		"event_action": "trigger",
+		"dedup_key":    "randomizer-" + string(alert.Kind),
+		"payload": map[string]any{
+			"summary":   alert.Summary(),
+			"source":    "randomizer",
+			"severity":  "error",
+			"timestamp": alert.At.UTC().Format(time.RFC3339),
+			"custom_details": map[string]any{
+				"kind":      alert.Kind,
+				"count":     alert.Count,
+				"threshold": alert.Threshold,
+				"window":    alert.Window.String(),
+			},
+		},
+	})
+}
+
+// SNS publishes alerts to an AWS SNS topic.
+type SNS struct {
+	TopicARN string
+}
+
+func (s SNS) Notify(ctx context.Context, alert Alert) error {
+	cfg, err := awsconfig.New(ctx)
+	if err != nil {
+		return err
+	}
+	subject := "randomizer " + string(alert.Kind) + " errors"
+	if alert.Kind == Notice {
+		subject = "randomizer notice"
+	}
+	_, err = sns.NewFromConfig(cfg).Publish(ctx, &sns.PublishInput{
+		TopicArn: aws.String(s.TopicARN),
+		Subject:  aws.String(subject),
+		Message:  aws.String(alert.Summary()),
+	})
+	if err != nil {
+		return fmt.Errorf("publishing to SNS: %w", err)
+	}
+	return nil
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("alert to %s failed with status %d", req.URL.Host, resp.StatusCode)
+	}
+	return nil
+}
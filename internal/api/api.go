@@ -0,0 +1,273 @@
+// Package api exposes the randomizer over a plain JSON HTTP API, for clients
+// other than chat apps, like scripts, dashboards, and home automation.
+package api
+
+import (
+	"cmp"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+// DefaultPartition is the store partition that requests use if neither the
+// API nor the request names one.
+const DefaultPartition = "api"
+
+// API serves the REST API under the /v1/ path.
+//
+// Every request must include an "Authorization: Bearer <token>" header with
+// Token. Requests work with the groups in the API's partition by default, or
+// in another partition that Token grants access to, named by a "partition"
+// query parameter, like the ID of a Slack channel whose groups a script should
+// share.
+type API struct {
+	// Token is the bearer token that authorizes requests. If empty, all
+	// requests are rejected.
+	Token string
+	// Partition is the default store partition for requests. If empty, it
+	// defaults to DefaultPartition.
+	Partition string
+	// Partitions lists the other store partitions that Token grants access
+	// to. Requests for any partition not listed here are forbidden.
+	Partitions []string
+	// StoreFactory provides a Store for a given partition.
+	StoreFactory func(partition string) randomizer.Store
+	// ContentCheck, if non-nil, reviews group names and options before they're
+	// saved.
+	ContentCheck randomizer.ContentCheck
+	// Logger, if non-nil, logs errors.
+	Logger *slog.Logger
+}
+
+// The API's routes, types, and request validation are generated from its
+// OpenAPI specification in openapi.json, which documents it for clients.
+//
+//go:generate go run ./gen
+
+//go:embed openapi.json
+var spec []byte
+
+// SpecPath is where the API serves its OpenAPI specification, which clients
+// may fetch without a token.
+const SpecPath = "/openapi.json"
+
+// Handler returns an HTTP handler for the API, and its specification at
+// SpecPath.
+func (a API) Handler() http.Handler {
+	ops := http.NewServeMux()
+	routes(ops, a)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+SpecPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(spec)
+	})
+	mux.Handle("/", a.authorize(ops))
+	return mux
+}
+
+var errUnauthorized = errors.New("invalid or missing API token")
+
+// PartitionsFromEnv returns the partitions listed in a comma-separated
+// environment variable, or nil if it is unset.
+func PartitionsFromEnv(name string) []string {
+	var partitions []string
+	for partition := range strings.SplitSeq(os.Getenv(name), ",") {
+		if partition = strings.TrimSpace(partition); partition != "" {
+			partitions = append(partitions, partition)
+		}
+	}
+	return partitions
+}
+
+func (a API) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || a.Token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(a.Token)) != 1 {
+			a.writeError(w, http.StatusUnauthorized, errUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a API) pick(w http.ResponseWriter, r *http.Request, partition string, body pickRequest) {
+	if (body.Group == "") == (len(body.Options) == 0) {
+		a.writeError(w, http.StatusBadRequest, errors.New(`body must be {"group": "name"} or {"options": ["a", "b", ...]}, with an optional "count"`))
+		return
+	}
+
+	args := body.Options
+	if body.Group != "" {
+		if !validName(body.Group) {
+			a.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid group name %q", body.Group))
+			return
+		}
+		args = []string{"+" + body.Group}
+	}
+	for _, option := range body.Options {
+		// The randomizer would read these as flags or operations.
+		if strings.HasPrefix(option, "-") || strings.HasPrefix(option, "/") || strings.ContainsFunc(option, unicode.IsSpace) {
+			a.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid option %q", option))
+			return
+		}
+	}
+	if body.Count > 0 {
+		args = append(args, "--pick", strconv.Itoa(body.Count))
+	}
+
+	result, ok := a.run(w, r, partition, args)
+	if !ok {
+		return
+	}
+	// A selection without a count puts every option in order, but has only one
+	// winner.
+	winners := result.Choices()
+	if !result.Picked() && len(winners) > 0 {
+		winners = winners[:1]
+	}
+	a.writeJSON(w, http.StatusOK, pickResponse{
+		Winners:  winners,
+		ResultID: result.ID(),
+		Message:  result.Message(),
+	})
+}
+
+func (a API) getGroup(w http.ResponseWriter, r *http.Request, name, partition string) {
+	if !validName(name) {
+		a.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid group name %q", name))
+		return
+	}
+	store, ok := a.store(w, partition)
+	if !ok {
+		return
+	}
+	options, err := store.Get(r.Context(), name)
+	if err != nil {
+		a.writeError(w, http.StatusServiceUnavailable, fmt.Errorf("getting group: %w", err))
+		return
+	}
+	if len(options) == 0 {
+		a.writeError(w, http.StatusNotFound, fmt.Errorf("group %q not found", name))
+		return
+	}
+	a.writeJSON(w, http.StatusOK, group{Group: name, Options: options})
+}
+
+func (a API) putGroup(w http.ResponseWriter, r *http.Request, name, partition string, body groupOptions) {
+	if !validName(name) {
+		a.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid group name %q", name))
+		return
+	}
+	for _, option := range body.Options {
+		if strings.HasPrefix(option, "/") || strings.ContainsFunc(option, unicode.IsSpace) {
+			a.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid option %q", option))
+			return
+		}
+	}
+
+	// Saving through the randomizer applies the same rules as a chat app, like
+	// the minimum number of options, and records the change's provenance. The
+	// "--" keeps options that look like flags from being read as flags.
+	args := append([]string{"/save", name, "--"}, body.Options...)
+	result, ok := a.run(w, r, partition, args)
+	if !ok {
+		return
+	}
+	a.writeJSON(w, http.StatusOK, groupMessage{Group: name, Message: result.Message()})
+}
+
+func (a API) deleteGroup(w http.ResponseWriter, r *http.Request, name, partition string) {
+	if !validName(name) {
+		a.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid group name %q", name))
+		return
+	}
+	result, ok := a.run(w, r, partition, []string{"/delete", name})
+	if !ok {
+		return
+	}
+	a.writeJSON(w, http.StatusOK, groupMessage{Group: name, Message: result.Message()})
+}
+
+// run runs the randomizer with args in a partition, and writes an error
+// response if it fails.
+func (a API) run(w http.ResponseWriter, r *http.Request, partition string, args []string) (randomizer.Result, bool) {
+	var opts []randomizer.AppOption
+	if a.ContentCheck != nil {
+		opts = append(opts, randomizer.WithContentCheck(a.ContentCheck))
+	}
+	if a.Logger != nil {
+		opts = append(opts, randomizer.WithLogger(a.Logger))
+	}
+	store, ok := a.store(w, partition)
+	if !ok {
+		return randomizer.Result{}, false
+	}
+	app := randomizer.NewApp("randomizer", store, opts...)
+	result, err := app.Main(r.Context(), args)
+	if err != nil {
+		status := http.StatusBadRequest
+		var rerr randomizer.Error
+		if errors.As(err, &rerr) && rerr.StoreUnavailable() {
+			status = http.StatusServiceUnavailable
+		}
+		a.writeError(w, status, err)
+		return randomizer.Result{}, false
+	}
+	return result, true
+}
+
+// store returns the store for a request's partition, or for the API's default
+// partition if the request names none. It writes an error response if Token
+// doesn't grant access to the partition.
+func (a API) store(w http.ResponseWriter, partition string) (randomizer.Store, bool) {
+	defaultPartition := cmp.Or(a.Partition, DefaultPartition)
+	if partition == "" {
+		partition = defaultPartition
+	}
+	if partition != defaultPartition && !slices.Contains(a.Partitions, partition) {
+		a.writeError(w, http.StatusForbidden, fmt.Errorf("the API token doesn't grant access to partition %q", partition))
+		return nil, false
+	}
+	return a.StoreFactory(partition), true
+}
+
+// validName reports whether a group name could have been saved through the
+// randomizer, which keeps names starting with "/" for its own records.
+func validName(name string) bool {
+	return name != "" && name != "help" &&
+		!strings.HasPrefix(name, "/") && !strings.HasPrefix(name, "+") && !strings.ContainsFunc(name, unicode.IsSpace)
+}
+
+func (a API) writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil && a.Logger != nil {
+		a.Logger.Error("Failed to write API response", "err", err)
+	}
+}
+
+func (a API) writeError(w http.ResponseWriter, status int, err error) {
+	if a.Logger != nil && status >= 500 {
+		a.Logger.Error("API request failed", "err", err)
+	}
+	var rerr randomizer.Error
+	if errors.As(err, &rerr) {
+		// Help text is written for people, which suits API clients better than
+		// the developer-oriented cause.
+		a.writeJSON(w, status, errorBody{Error: rerr.HelpText()})
+		return
+	}
+	a.writeJSON(w, status, errorBody{Error: err.Error()})
+}
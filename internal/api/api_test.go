@@ -0,0 +1,178 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+)
+
+func TestUnauthorized(t *testing.T) {
+	api := API{Token: "right", StoreFactory: func(_ string) randomizer.Store { return rndtest.Store{} }}
+	for _, header := range []string{"", "Bearer wrong", "right"} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/groups/lunch", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp := httptest.NewRecorder()
+		api.Handler().ServeHTTP(resp, req)
+		if resp.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: got status %v, want %v", header, resp.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestGroups(t *testing.T) {
+	partitions := map[string]rndtest.Store{DefaultPartition: {}, "C123": {"snacks": {"chips", "pretzels"}}}
+	api := API{
+		Token:        "right",
+		Partitions:   []string{"C123"},
+		StoreFactory: func(partition string) randomizer.Store { return partitions[partition] },
+	}
+
+	if resp := serveAuthorized(api, http.MethodPut, "/v1/groups/lunch", `{"options": ["ramen", "sushi"]}`); resp.Code != http.StatusOK {
+		t.Fatalf("put got status %v: %s", resp.Code, resp.Body)
+	}
+	if got := partitions[DefaultPartition]["lunch"]; !slices.Equal(got, []string{"ramen", "sushi"}) {
+		t.Errorf("saved options %v, want [ramen sushi]", got)
+	}
+	if resp := serveAuthorized(api, http.MethodPut, "/v1/groups/lunch", `{"options": ["ramen"]}`); resp.Code != http.StatusBadRequest ||
+		!strings.Contains(resp.Body.String(), "Whoops") {
+		t.Errorf("put with one option got %v: %s", resp.Code, resp.Body)
+	}
+	if resp := serveAuthorized(api, http.MethodPut, "/v1/groups/lunch", `{"options": []}`); resp.Code != http.StatusBadRequest ||
+		!strings.Contains(resp.Body.String(), `\"options\" can't be empty`) {
+		t.Errorf("put without options got %v: %s", resp.Code, resp.Body)
+	}
+
+	resp := serveAuthorized(api, http.MethodGet, "/v1/groups/snacks?partition=C123", "")
+	var group struct{ Options []string }
+	json.NewDecoder(resp.Body).Decode(&group)
+	if resp.Code != http.StatusOK || !slices.Equal(group.Options, []string{"chips", "pretzels"}) {
+		t.Errorf("get from another partition got %v with options %v", resp.Code, group.Options)
+	}
+	if resp := serveAuthorized(api, http.MethodGet, "/v1/groups/%2Fresults", ""); resp.Code != http.StatusBadRequest {
+		t.Errorf("get of a record got status %v, want %v", resp.Code, http.StatusBadRequest)
+	}
+
+	if resp := serveAuthorized(api, http.MethodDelete, "/v1/groups/lunch", ""); resp.Code != http.StatusOK {
+		t.Fatalf("delete got status %v: %s", resp.Code, resp.Body)
+	}
+	if resp := serveAuthorized(api, http.MethodGet, "/v1/groups/lunch", ""); resp.Code != http.StatusNotFound {
+		t.Errorf("get after delete got status %v, want %v", resp.Code, http.StatusNotFound)
+	}
+}
+
+func TestPutGroupWithFlags(t *testing.T) {
+	for _, options := range [][]string{
+		{"pizza", "tacos", "--dry-run"},
+		{"pizza", "-n", "2", "tacos"},
+		{"a", "--", "b"},
+	} {
+		store := rndtest.Store{}
+		api := API{Token: "right", StoreFactory: func(_ string) randomizer.Store { return store }}
+		body, _ := json.Marshal(groupOptions{Options: options})
+		if resp := serveAuthorized(api, http.MethodPut, "/v1/groups/lunch", string(body)); resp.Code != http.StatusOK {
+			t.Errorf("put of %q got status %v: %s", options, resp.Code, resp.Body)
+		}
+		if got := store["lunch"]; !slices.Equal(got, options) {
+			t.Errorf("put of %q saved %q", options, got)
+		}
+	}
+}
+
+func TestForbiddenPartition(t *testing.T) {
+	partitions := map[string]rndtest.Store{
+		DefaultPartition: {},
+		"C123":           {"snacks": {"chips", "pretzels"}},
+		"C999":           {"secrets": {"one", "two"}},
+	}
+	api := API{
+		Token:        "right",
+		Partitions:   []string{"C123"},
+		StoreFactory: func(partition string) randomizer.Store { return partitions[partition] },
+	}
+
+	for _, tc := range []struct{ method, path, body string }{
+		{http.MethodGet, "/v1/groups/secrets?partition=C999", ""},
+		{http.MethodPut, "/v1/groups/secrets?partition=C999", `{"options": ["mine", "now"]}`},
+		{http.MethodDelete, "/v1/groups/secrets?partition=C999", ""},
+		{http.MethodPost, "/v1/pick?partition=C999", `{"group": "secrets"}`},
+	} {
+		if resp := serveAuthorized(api, tc.method, tc.path, tc.body); resp.Code != http.StatusForbidden {
+			t.Errorf("%s %s: got status %v, want %v", tc.method, tc.path, resp.Code, http.StatusForbidden)
+		}
+	}
+	if got := partitions["C999"]; len(got) != 1 || !slices.Equal(got["secrets"], []string{"one", "two"}) {
+		t.Errorf("forbidden partition changed to %v", got)
+	}
+}
+
+func TestPick(t *testing.T) {
+	store := rndtest.Store{"lunch": {"ramen", "sushi", "tacos"}}
+	api := API{Token: "right", StoreFactory: func(_ string) randomizer.Store { return store }}
+
+	testCases := []struct {
+		body    string
+		status  int
+		winners int
+	}{
+		{`{"group": "lunch"}`, http.StatusOK, 1},
+		{`{"group": "lunch", "count": 2}`, http.StatusOK, 2},
+		{`{"options": ["heads", "tails"]}`, http.StatusOK, 1},
+		{`{"group": "dinner"}`, http.StatusBadRequest, 0},
+		{`{"options": ["--help"]}`, http.StatusBadRequest, 0},
+		{`{"group": "lunch", "options": ["heads"]}`, http.StatusBadRequest, 0},
+		{`{}`, http.StatusBadRequest, 0},
+	}
+	for _, tc := range testCases {
+		resp := serveAuthorized(api, http.MethodPost, "/v1/pick", tc.body)
+		var got pickResponse
+		json.NewDecoder(resp.Body).Decode(&got)
+		if resp.Code != tc.status || len(got.Winners) != tc.winners {
+			t.Errorf("%s: got status %v with winners %v, want %v with %d", tc.body, resp.Code, got.Winners, tc.status, tc.winners)
+		}
+	}
+	if history := store["/history/lunch"]; len(history) != 3 {
+		t.Errorf("got history %v, want each winner of both picks from the group", history)
+	}
+}
+
+func TestSpec(t *testing.T) {
+	api := API{Token: "right", StoreFactory: func(_ string) randomizer.Store { return rndtest.Store{} }}
+	resp := httptest.NewRecorder()
+	api.Handler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, SpecPath, nil))
+
+	var spec struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&spec); resp.Code != http.StatusOK || err != nil || spec.OpenAPI == "" {
+		t.Fatalf("got status %v with spec error %v", resp.Code, err)
+	}
+
+	// Every documented operation should be served by the API, rather than
+	// fall through to the mux's plain text errors.
+	for path, ops := range spec.Paths {
+		for method := range ops {
+			path := strings.ReplaceAll(path, "{name}", "lunch")
+			resp := serveAuthorized(api, strings.ToUpper(method), path, "")
+			if ct := resp.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("%s %s: got status %v with %s", method, path, resp.Code, ct)
+			}
+		}
+	}
+}
+
+func serveAuthorized(api API, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer right")
+	resp := httptest.NewRecorder()
+	api.Handler().ServeHTTP(resp, req)
+	return resp
+}
@@ -0,0 +1,362 @@
+// Gen generates the routes, types, and request validation of the REST API
+// from its OpenAPI specification, so that the two can't drift apart. It runs
+// from the api package's directory with "go generate".
+//
+// Gen only understands the parts of OpenAPI that the API uses: operations with
+// path and query parameters of type string, JSON request and response bodies
+// that refer to component schemas, and schemas of objects whose properties are
+// strings, integers, booleans, or arrays of strings. Of the validation
+// keywords, it checks "required", "minimum", and "minItems".
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	specFile   = "openapi.json"
+	outputFile = "openapi.gen.go"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("gen: ")
+
+	spec, err := os.ReadFile(specFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	out, err := generate(spec)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(outputFile, out, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+type document struct {
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components struct {
+		Parameters map[string]parameter `json:"parameters"`
+		Schemas    map[string]schema    `json:"schemas"`
+	} `json:"components"`
+}
+
+type operation struct {
+	OperationID string      `json:"operationId"`
+	Summary     string      `json:"summary"`
+	Parameters  []parameter `json:"parameters"`
+	RequestBody *struct {
+		Content map[string]struct {
+			Schema schema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+}
+
+type parameter struct {
+	Ref      string `json:"$ref"`
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required"`
+	Schema   schema `json:"schema"`
+}
+
+type schema struct {
+	Ref         string     `json:"$ref"`
+	Type        string     `json:"type"`
+	Description string     `json:"description"`
+	Properties  properties `json:"properties"`
+	Required    []string   `json:"required"`
+	Items       *schema    `json:"items"`
+	Minimum     *int       `json:"minimum"`
+	MinItems    *int       `json:"minItems"`
+}
+
+// properties keeps the properties of a schema in the order that the
+// specification lists them, which is the order that their fields are encoded
+// in responses.
+type properties []property
+
+type property struct {
+	name   string
+	schema schema
+}
+
+func (p *properties) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var s schema
+		if err := dec.Decode(&s); err != nil {
+			return err
+		}
+		*p = append(*p, property{name: tok.(string), schema: s})
+	}
+	return nil
+}
+
+// methodOrder orders the operations of each path.
+var methodOrder = []string{"get", "put", "post", "delete", "patch"}
+
+func generate(spec []byte) ([]byte, error) {
+	var doc document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", specFile, err)
+	}
+
+	var (
+		iface  bytes.Buffer
+		routes bytes.Buffer
+		bodies = make(map[string]bool)
+	)
+	for _, path := range slices.Sorted(maps.Keys(doc.Paths)) {
+		ops := doc.Paths[path]
+		for _, method := range methodOrder {
+			op, ok := ops[method]
+			if !ok {
+				continue
+			}
+			if err := writeOperation(&iface, &routes, &doc, path, method, op, bodies); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			}
+		}
+	}
+
+	var types bytes.Buffer
+	for _, name := range slices.Sorted(maps.Keys(doc.Components.Schemas)) {
+		if err := writeSchema(&types, name, doc.Components.Schemas[name], bodies[name]); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by \"go run ./gen\" from %s; DO NOT EDIT.\n\n", specFile)
+	out.WriteString("package api\n\n")
+	out.WriteString("import (\n\"encoding/json\"\n\"errors\"\n\"fmt\"\n\"net/http\"\n)\n\n")
+	out.WriteString("// server has a method for each operation in the specification, which\n")
+	out.WriteString("// receives the operation's parameters and validated body.\n")
+	out.WriteString("type server interface {\n")
+	out.Write(iface.Bytes())
+	out.WriteString("\nwriteError(w http.ResponseWriter, status int, err error)\n}\n\n")
+	out.WriteString("// routes registers each operation in the specification with mux, and\n")
+	out.WriteString("// rejects requests with invalid parameters or bodies before they reach s.\n")
+	out.WriteString("func routes(mux *http.ServeMux, s server) {\n")
+	out.Write(routes.Bytes())
+	out.WriteString("}\n\n")
+	out.WriteString(decodeBodyFunc)
+	out.Write(types.Bytes())
+	return format.Source(out.Bytes())
+}
+
+const decodeBodyFunc = `// decodeBody decodes and validates a JSON request body.
+func decodeBody[T interface{ validate() error }](r *http.Request) (T, error) {
+	var body T
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return body, fmt.Errorf("invalid request body: %w", err)
+	}
+	return body, body.validate()
+}
+
+`
+
+func writeOperation(iface, routes *bytes.Buffer, doc *document, path, method string, op operation, bodies map[string]bool) error {
+	if op.OperationID == "" {
+		return errors.New("missing operationId")
+	}
+
+	var (
+		params []string // Go parameters of the server method
+		args   []string // Go expressions for them, in the route
+		checks []string // Go statements validating them, in the route
+	)
+	for _, p := range op.Parameters {
+		if p.Ref != "" {
+			name, ok := strings.CutPrefix(p.Ref, "#/components/parameters/")
+			if !ok || doc.Components.Parameters[name].Name == "" {
+				return fmt.Errorf("unknown parameter %s", p.Ref)
+			}
+			p = doc.Components.Parameters[name]
+		}
+		if p.Schema.Type != "string" || !isIdentifier(p.Name) {
+			return fmt.Errorf("parameter %q must be a string with a Go-friendly name", p.Name)
+		}
+		params = append(params, p.Name+" string")
+		switch p.In {
+		case "path":
+			args = append(args, fmt.Sprintf("r.PathValue(%q)", p.Name))
+		case "query":
+			args = append(args, fmt.Sprintf("r.URL.Query().Get(%q)", p.Name))
+			if p.Required {
+				checks = append(checks, fmt.Sprintf(
+					"if !r.URL.Query().Has(%[1]q) {\ns.writeError(w, http.StatusBadRequest, errors.New(`the %[1]q parameter is required`))\nreturn\n}\n",
+					p.Name,
+				))
+			}
+		default:
+			return fmt.Errorf("parameter %q is in %s, not the path or query", p.Name, p.In)
+		}
+	}
+	if op.RequestBody != nil {
+		content, ok := op.RequestBody.Content["application/json"]
+		name, isRef := strings.CutPrefix(content.Schema.Ref, "#/components/schemas/")
+		if !ok || !isRef {
+			return errors.New("request body must be JSON that refers to a component schema")
+		}
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			return fmt.Errorf("unknown schema %s", name)
+		}
+		bodies[name] = true
+		params = append(params, "body "+typeName(name))
+		args = append(args, "body")
+		checks = append(checks, fmt.Sprintf(
+			"body, err := decodeBody[%s](r)\nif err != nil {\ns.writeError(w, http.StatusBadRequest, err)\nreturn\n}\n",
+			typeName(name),
+		))
+	}
+
+	if op.Summary != "" {
+		fmt.Fprintf(iface, "// %s handles %s %s: %s.\n", op.OperationID, strings.ToUpper(method), path, op.Summary)
+	}
+	fmt.Fprintf(iface, "%s(%s)\n", op.OperationID, strings.Join(append([]string{"w http.ResponseWriter, r *http.Request"}, params...), ", "))
+
+	fmt.Fprintf(routes, "mux.HandleFunc(%q, func(w http.ResponseWriter, r *http.Request) {\n", strings.ToUpper(method)+" "+path)
+	for _, check := range checks {
+		routes.WriteString(check)
+	}
+	fmt.Fprintf(routes, "s.%s(%s)\n", op.OperationID, strings.Join(append([]string{"w, r"}, args...), ", "))
+	routes.WriteString("})\n")
+	return nil
+}
+
+func writeSchema(w *bytes.Buffer, name string, s schema, isBody bool) error {
+	if s.Type != "object" {
+		return errors.New("only objects are supported")
+	}
+
+	fmt.Fprintf(w, "// %s is the %s schema.", typeName(name), name)
+	if s.Description != "" {
+		fmt.Fprintf(w, " %s", s.Description)
+	}
+	fmt.Fprintf(w, "\ntype %s struct {\n", typeName(name))
+
+	var checks bytes.Buffer
+	for _, p := range s.Properties {
+		typ, zero, err := goType(p.schema)
+		if err != nil {
+			return fmt.Errorf("property %q: %w", p.name, err)
+		}
+		field := fieldName(p.name)
+		required := slices.Contains(s.Required, p.name)
+
+		if p.schema.Description != "" {
+			fmt.Fprintf(w, "// %s\n", p.schema.Description)
+		}
+		tag := p.name
+		if !required {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(w, "%s %s `json:%q`\n", field, typ, tag)
+
+		if !isBody {
+			continue
+		}
+		if required {
+			fmt.Fprintf(&checks, "if v.%s == %s {\nreturn errors.New(`%q is required`)\n}\n", field, zero, p.name)
+		}
+		if p.schema.Minimum != nil {
+			fmt.Fprintf(&checks, "if v.%s < %d {\nreturn errors.New(`%q must be at least %d`)\n}\n", field, *p.schema.Minimum, p.name, *p.schema.Minimum)
+		}
+		if p.schema.MinItems != nil {
+			cond := fmt.Sprintf("len(v.%s) < %d", field, *p.schema.MinItems)
+			if !required {
+				cond = fmt.Sprintf("v.%s != nil && %s", field, cond)
+			}
+			message := fmt.Sprintf("%q must have at least %d items", p.name, *p.schema.MinItems)
+			if *p.schema.MinItems == 1 {
+				message = fmt.Sprintf("%q can't be empty", p.name)
+			}
+			fmt.Fprintf(&checks, "if %s {\nreturn errors.New(`%s`)\n}\n", cond, message)
+		}
+	}
+	for _, req := range s.Required {
+		if !slices.ContainsFunc(s.Properties, func(p property) bool { return p.name == req }) {
+			return fmt.Errorf("required property %q isn't defined", req)
+		}
+	}
+	w.WriteString("}\n\n")
+
+	if isBody {
+		fmt.Fprintf(w, "func (v %s) validate() error {\n", typeName(name))
+		w.Write(checks.Bytes())
+		w.WriteString("return nil\n}\n\n")
+	}
+	return nil
+}
+
+// goType returns the Go type of a property, and the expression for its zero
+// value that "required" checks against.
+func goType(s schema) (typ, zero string, err error) {
+	switch s.Type {
+	case "string":
+		return "string", `""`, nil
+	case "integer":
+		return "int", "0", nil
+	case "boolean":
+		return "bool", "false", nil
+	case "array":
+		if s.Items == nil || s.Items.Type != "string" {
+			return "", "", errors.New("only arrays of strings are supported")
+		}
+		// An empty array is as good as a missing one.
+		return "[]string", "nil", nil
+	default:
+		return "", "", fmt.Errorf("unsupported type %q", s.Type)
+	}
+}
+
+// typeName returns the unexported Go name of a schema, like pickRequest for
+// PickRequest. The Error schema becomes errorBody, so as not to shadow the
+// error type.
+func typeName(schema string) string {
+	if schema == "Error" {
+		return "errorBody"
+	}
+	return strings.ToLower(schema[:1]) + schema[1:]
+}
+
+// fieldName returns the Go name of a property, like ResultID for result_id.
+func fieldName(property string) string {
+	var b strings.Builder
+	for _, word := range strings.Split(property, "_") {
+		switch strings.ToLower(word) {
+		case "id", "url":
+			b.WriteString(strings.ToUpper(word))
+		default:
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
+
+func isIdentifier(s string) bool {
+	return s != "" && !unicode.IsDigit(rune(s[0])) && !strings.ContainsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+}
@@ -0,0 +1,137 @@
+// Package audit sends the randomizer's audit trail of changes to groups and
+// settings to sinks outside of its store, where it can be kept for as long as
+// a team needs and reviewed alongside other audit logs.
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/featherbread/randomizer/internal/awsconfig"
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+// SinkFromEnv returns the sink named by AUDIT_SINK, or nil if it is unset:
+//
+//   - "stdout": Write each entry to standard output as a line of JSON.
+//   - "file:<path>": Append each entry to a file as a line of JSON.
+//   - "dynamodb:<table>": Put each entry in a DynamoDB table. See [DynamoDB].
+func SinkFromEnv(ctx context.Context) (randomizer.AuditSink, error) {
+	env := os.Getenv("AUDIT_SINK")
+	kind, arg, _ := strings.Cut(env, ":")
+	switch {
+	case env == "":
+		return nil, nil
+	case env == "stdout":
+		return NewWriter(os.Stdout).Record, nil
+	case kind == "file" && arg != "":
+		w, err := OpenFile(arg)
+		if err != nil {
+			return nil, err
+		}
+		return w.Record, nil
+	case kind == "dynamodb" && arg != "":
+		cfg, err := awsconfig.New(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return DynamoDB{Client: dynamodb.NewFromConfig(cfg), Table: arg}.Record, nil
+	}
+	return nil, fmt.Errorf(`invalid AUDIT_SINK %q (want "stdout", "file:<path>", or "dynamodb:<table>")`, env)
+}
+
+// Writer writes each entry as a line of JSON, for log collectors and files.
+type Writer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriter returns a Writer that writes to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// OpenFile returns a Writer that appends to the file at path, creating it if
+// necessary. The file stays open for the life of the process.
+func OpenFile(path string) (*Writer, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit file: %w", err)
+	}
+	return NewWriter(f), nil
+}
+
+// Record implements randomizer.AuditSink.
+func (w *Writer) Record(_ context.Context, entry randomizer.AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err = w.w.Write(append(line, '\n'))
+	return err
+}
+
+// PutItemAPI is the part of the DynamoDB client that [DynamoDB] needs.
+type PutItemAPI interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+}
+
+// DynamoDB puts each entry in a DynamoDB table, whose partition key is the
+// string "Team" and whose sort key is the string "Entry". Each entry's Team is
+// its workspace's team ID (or "-" if there is none), and its Entry is its
+// timestamp in RFC 3339 format followed by a random suffix, so that a query on
+// a workspace returns its changes in order. The rest of the entry's fields are
+// separate attributes.
+type DynamoDB struct {
+	Client PutItemAPI
+	Table  string
+}
+
+// Record implements randomizer.AuditSink.
+func (d DynamoDB) Record(ctx context.Context, entry randomizer.AuditEntry) error {
+	team := entry.Team
+	if team == "" {
+		team = "-"
+	}
+	item := map[string]types.AttributeValue{
+		"Team":      &types.AttributeValueMemberS{Value: team},
+		"Entry":     &types.AttributeValueMemberS{Value: entry.Time.UTC().Format(time.RFC3339Nano) + "/" + randomSuffix()},
+		"Operation": &types.AttributeValueMemberS{Value: entry.Operation},
+		"Result":    &types.AttributeValueMemberS{Value: entry.Result},
+	}
+	for name, value := range map[string]string{"Channel": entry.Channel, "User": entry.User, "Operator": entry.Operator, "Target": entry.Target} {
+		if value != "" {
+			item[name] = &types.AttributeValueMemberS{Value: value}
+		}
+	}
+	if len(entry.Args) > 0 {
+		args := make([]types.AttributeValue, len(entry.Args))
+		for i, arg := range entry.Args {
+			args[i] = &types.AttributeValueMemberS{Value: arg}
+		}
+		item["Args"] = &types.AttributeValueMemberL{Value: args}
+	}
+
+	_, err := d.Client.PutItem(ctx, &dynamodb.PutItemInput{TableName: &d.Table, Item: item})
+	return err
+}
+
+// randomSuffix keeps simultaneous entries from the same workspace distinct.
+func randomSuffix() string {
+	var b [4]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
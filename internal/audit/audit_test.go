@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+var testEntry = randomizer.AuditEntry{
+	Time:      time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC),
+	Team:      "T1",
+	Channel:   "C1",
+	User:      "U1",
+	Operation: "save",
+	Target:    "lunch",
+	Args:      []string{"pizza", "tacos"},
+	Result:    "SavedGroup",
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	for range 2 {
+		w, err := OpenFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Record(t.Context(), testEntry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2 appended entries:\n%s", len(lines), data)
+	}
+	var got randomizer.AuditEntry
+	if err := json.Unmarshal([]byte(lines[1]), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.User != "U1" || got.Target != "lunch" || len(got.Args) != 2 || !got.Time.Equal(testEntry.Time) {
+		t.Errorf("got entry %+v", got)
+	}
+}
+
+type fakeDynamoDB struct {
+	inputs []*dynamodb.PutItemInput
+}
+
+func (f *fakeDynamoDB) PutItem(_ context.Context, in *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.inputs = append(f.inputs, in)
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func TestDynamoDB(t *testing.T) {
+	var client fakeDynamoDB
+	sink := DynamoDB{Client: &client, Table: "RandomizerAudit"}
+	if err := sink.Record(t.Context(), testEntry); err != nil {
+		t.Fatal(err)
+	}
+	noTeam := testEntry
+	noTeam.Team, noTeam.Args = "", nil
+	if err := sink.Record(t.Context(), noTeam); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(client.inputs) != 2 || *client.inputs[0].TableName != "RandomizerAudit" {
+		t.Fatalf("got inputs %+v", client.inputs)
+	}
+	str := func(item map[string]types.AttributeValue, name string) string {
+		v, _ := item[name].(*types.AttributeValueMemberS)
+		if v == nil {
+			return ""
+		}
+		return v.Value
+	}
+	first, second := client.inputs[0].Item, client.inputs[1].Item
+	if str(first, "Team") != "T1" || str(second, "Team") != "-" {
+		t.Errorf("got teams %q and %q", str(first, "Team"), str(second, "Team"))
+	}
+	if e1, e2 := str(first, "Entry"), str(second, "Entry"); !strings.HasPrefix(e1, "2026-10-17T12:00:00Z/") || e1 == e2 {
+		t.Errorf("got entry keys %q and %q, want distinct timestamped keys", e1, e2)
+	}
+	if str(first, "User") != "U1" || str(first, "Target") != "lunch" || str(first, "Result") != "SavedGroup" {
+		t.Errorf("got item %+v", first)
+	}
+	if args, ok := first["Args"].(*types.AttributeValueMemberL); !ok || len(args.Value) != 2 {
+		t.Errorf("got args %+v", first["Args"])
+	}
+	if _, ok := second["Args"]; ok {
+		t.Errorf("item has args without any: %+v", second)
+	}
+}
+
+func TestSinkFromEnv(t *testing.T) {
+	t.Setenv("AUDIT_SINK", "")
+	if sink, err := SinkFromEnv(t.Context()); sink != nil || err != nil {
+		t.Errorf("SinkFromEnv() = %v, %v with no AUDIT_SINK", sink, err)
+	}
+	for _, value := range []string{"stderr", "file:", "dynamodb"} {
+		t.Setenv("AUDIT_SINK", value)
+		if _, err := SinkFromEnv(t.Context()); err == nil {
+			t.Errorf("no error for AUDIT_SINK=%q", value)
+		}
+	}
+	t.Setenv("AUDIT_SINK", "file:"+filepath.Join(t.TempDir(), "audit.jsonl"))
+	if sink, err := SinkFromEnv(t.Context()); sink == nil || err != nil {
+		t.Errorf("SinkFromEnv() = %v, %v for a file", sink, err)
+	}
+}
@@ -67,6 +67,17 @@ func New(ctx context.Context) (aws.Config, error) {
 	return cfg, nil
 }
 
+// Prime eagerly initializes state that New would otherwise build lazily on
+// first use, such as the parsed embedded TLS roots. This moves the work into
+// the initialization phase of a process, where it can be captured by a
+// checkpoint (as with AWS Lambda SnapStart) instead of repeated after every
+// restore. State built by Prime remains valid after a restore.
+func Prime() {
+	if os.Getenv("AWS_CLIENT_EMBEDDED_TLS_ROOTS") == "1" {
+		getEmbeddedCertTransport()
+	}
+}
+
 // getEmbeddedCertTransport returns an HTTP transport that trusts only the root
 // CAs operated by Amazon Trust Services, which all AWS service endpoints chain
 // from.
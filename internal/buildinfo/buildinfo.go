@@ -0,0 +1,82 @@
+// Package buildinfo describes the build of the running randomizer binary.
+//
+// Release builds set the version, and optionally the commit and build date,
+// with linker flags:
+//
+//	go build -ldflags='-X github.com/featherbread/randomizer/internal/buildinfo.Version=v1.2.3' ./cmd/randomizer-server
+//
+// Anything left unset falls back to what the Go toolchain embeds in every
+// binary, including the version control revision when building from a Git
+// checkout.
+package buildinfo
+
+import (
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Set with -ldflags="-X ...". Date is in RFC 3339 format.
+var (
+	Version string
+	Commit  string
+	Date    string
+)
+
+// Info describes a build.
+type Info struct {
+	// Version is the release version, or "dev" for a build without one.
+	Version string
+	// Commit is the version control revision, if known.
+	Commit string
+	// Date is the time of the build or commit, or zero if unknown.
+	Date time.Time
+	// Modified reports whether the working tree had uncommitted changes.
+	Modified bool
+	// GoVersion is the version of the Go toolchain behind the build.
+	GoVersion string
+}
+
+// ShortCommit returns the first 12 characters of the commit.
+func (i Info) ShortCommit() string {
+	if len(i.Commit) > 12 {
+		return i.Commit[:12]
+	}
+	return i.Commit
+}
+
+// Get returns the build info of the running binary.
+var Get = sync.OnceValue(read)
+
+func read() Info {
+	info := Info{Version: Version, Commit: Commit}
+	if Date != "" {
+		info.Date, _ = time.Parse(time.RFC3339, Date)
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		info.GoVersion = build.GoVersion
+		if info.Version == "" && build.Main.Version != "" && build.Main.Version != "(devel)" {
+			info.Version = build.Main.Version
+		}
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.Date.IsZero() {
+					info.Date, _ = time.Parse(time.RFC3339, setting.Value)
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	return info
+}
@@ -0,0 +1,25 @@
+package buildinfo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLinkerFlags(t *testing.T) {
+	defer func(version, commit, date string) { Version, Commit, Date = version, commit, date }(Version, Commit, Date)
+	Version, Commit, Date = "v1.2.3", "0123456789abcdef", "2026-10-17T12:00:00Z"
+
+	info := read()
+	if info.Version != "v1.2.3" || info.ShortCommit() != "0123456789ab" || !info.Date.Equal(time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("got %+v, want the values from linker flags", info)
+	}
+	if info.GoVersion == "" {
+		t.Error("missing Go version")
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	if info := read(); info.Version == "" {
+		t.Errorf("got %+v, want a default version", info)
+	}
+}
@@ -0,0 +1,198 @@
+// Package cache provides a single cached value with singleflight loading,
+// stale-while-revalidate refreshes, and jittered expiration, suitable for
+// secrets and settings that are expensive to load and change rarely.
+package cache
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/featherbread/randomizer/internal/clock"
+)
+
+// Loader retrieves a fresh copy of a cached value.
+type Loader[T any] func(ctx context.Context) (T, error)
+
+// Options configures the expiration behavior of a [Value].
+type Options struct {
+	// TTL is the nominal time for which a loaded value is considered fresh.
+	TTL time.Duration
+	// Jitter shortens each TTL by a random fraction of up to this amount (e.g.
+	// 0.1 for 10%), so that many instances loaded at the same time don't all
+	// expire at the same time.
+	Jitter float64
+	// Stale is the additional time after expiration for which a value may be
+	// returned while a refresh proceeds in the background. Calls made after
+	// this window wait for a fresh value.
+	Stale time.Duration
+	// Clock, if non-nil, replaces the system clock for expiration.
+	Clock clock.Clock
+}
+
+// Stats counts the outcomes of calls to [Value.Get].
+type Stats struct {
+	Hits      uint64 // Fresh values returned immediately.
+	StaleHits uint64 // Stale values returned while refreshing in the background.
+	Misses    uint64 // Calls that waited for a value to load.
+	Errors    uint64 // Failed loads, in the foreground or background.
+}
+
+// Value is a lazily loaded cached value. A given Value loads at most once at
+// a time no matter how many callers are waiting on it.
+type Value[T any] struct {
+	load  Loader[T]
+	opts  Options
+	clock clock.Clock
+
+	// loadLock is held for the duration of each load. It is a channel rather
+	// than a mutex so that waiting callers can give up when their contexts end.
+	loadLock chan struct{}
+
+	mu         sync.Mutex
+	value      T
+	loaded     bool
+	expiry     time.Time
+	refreshing bool
+
+	hits, staleHits, misses, errors atomic.Uint64
+}
+
+// New creates a Value that loads using load and expires according to opts.
+func New[T any](load Loader[T], opts Options) *Value[T] {
+	return &Value[T]{
+		load:     load,
+		opts:     opts,
+		clock:    clock.Or(opts.Clock),
+		loadLock: make(chan struct{}, 1),
+	}
+}
+
+// forever is the TTL of a [Lazy] value.
+const forever = time.Duration(math.MaxInt64)
+
+// Lazy creates a Value that loads on first use and never expires, for state
+// that's expensive to build and better left off of a process's startup path.
+// As with any Value, concurrent first uses share a single load, and a failed
+// load is retried by the next call to Get.
+func Lazy[T any](load Loader[T]) *Value[T] {
+	return New(load, Options{TTL: forever})
+}
+
+// Get returns the cached value, loading it first if it is missing or too stale
+// to use.
+func (v *Value[T]) Get(ctx context.Context) (T, error) {
+	span := trace.SpanFromContext(ctx)
+
+	v.mu.Lock()
+	now := v.clock.Now()
+	switch {
+	case v.loaded && now.Before(v.expiry):
+		value := v.value
+		v.mu.Unlock()
+		v.hits.Add(1)
+		span.SetAttributes(attribute.String("randomizer.cache.result", "hit"))
+		return value, nil
+
+	case v.loaded && now.Before(v.expiry.Add(v.opts.Stale)):
+		value := v.value
+		if !v.refreshing {
+			v.refreshing = true
+			go v.refresh(context.WithoutCancel(ctx))
+		}
+		v.mu.Unlock()
+		v.staleHits.Add(1)
+		span.SetAttributes(attribute.String("randomizer.cache.result", "stale"))
+		return value, nil
+	}
+	v.mu.Unlock()
+
+	v.misses.Add(1)
+	span.SetAttributes(attribute.String("randomizer.cache.result", "miss"))
+	return v.loadFresh(ctx)
+}
+
+// loadFresh waits for any in-flight load to finish, and performs a new load
+// only if that one didn't produce a fresh value.
+func (v *Value[T]) loadFresh(ctx context.Context) (T, error) {
+	select {
+	case v.loadLock <- struct{}{}:
+		defer func() { <-v.loadLock }()
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+
+	v.mu.Lock()
+	if v.loaded && v.clock.Now().Before(v.expiry) {
+		value := v.value
+		v.mu.Unlock()
+		return value, nil
+	}
+	v.mu.Unlock()
+
+	return v.store(v.load(ctx))
+}
+
+func (v *Value[T]) refresh(ctx context.Context) {
+	defer func() {
+		v.mu.Lock()
+		v.refreshing = false
+		v.mu.Unlock()
+	}()
+	v.loadFresh(ctx)
+}
+
+func (v *Value[T]) store(value T, err error) (T, error) {
+	if err != nil {
+		v.errors.Add(1)
+		return value, err
+	}
+
+	ttl := v.opts.TTL
+	if v.opts.Jitter > 0 {
+		ttl -= time.Duration(rand.Float64() * v.opts.Jitter * float64(ttl))
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.value = value
+	v.loaded = true
+	v.expiry = v.clock.Now().Add(ttl)
+	return value, nil
+}
+
+// Peek returns the cached value without loading it, and whether a value was
+// loaded at all. It may return an expired value.
+func (v *Value[T]) Peek() (T, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.value, v.loaded
+}
+
+// Flush discards the cached value, so that the next call to Get loads a fresh
+// one.
+func (v *Value[T]) Flush() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	var zero T
+	v.value = zero
+	v.loaded = false
+	v.expiry = time.Time{}
+}
+
+// Stats returns the counts of cache outcomes since the Value was created.
+func (v *Value[T]) Stats() Stats {
+	return Stats{
+		Hits:      v.hits.Load(),
+		StaleHits: v.staleHits.Load(),
+		Misses:    v.misses.Load(),
+		Errors:    v.errors.Load(),
+	}
+}
@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/clock/clocktest"
+)
+
+func TestSingleflight(t *testing.T) {
+	var loads atomic.Int32
+	release := make(chan struct{})
+	v := New(func(context.Context) (int, error) {
+		loads.Add(1)
+		<-release
+		return 42, nil
+	}, Options{TTL: time.Minute})
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			if got, err := v.Get(context.Background()); err != nil || got != 42 {
+				t.Errorf("Get() = %v, %v; want 42, nil", got, err)
+			}
+		})
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("loaded %d times, want 1", n)
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	var (
+		clock = clocktest.New(time.Unix(0, 0))
+		count atomic.Int32
+		done  = make(chan struct{}, 1)
+	)
+	v := New(func(context.Context) (int32, error) {
+		defer func() {
+			select {
+			case done <- struct{}{}:
+			default:
+			}
+		}()
+		return count.Add(1), nil
+	}, Options{TTL: time.Minute, Stale: time.Minute, Clock: clock})
+
+	ctx := context.Background()
+	if got, _ := v.Get(ctx); got != 1 {
+		t.Fatalf("initial Get() = %d, want 1", got)
+	}
+	<-done
+
+	clock.Advance(90 * time.Second)
+	if got, _ := v.Get(ctx); got != 1 {
+		t.Errorf("stale Get() = %d, want the stale value 1", got)
+	}
+	<-done
+	if got, _ := v.Get(ctx); got != 2 {
+		t.Errorf("Get() after refresh = %d, want 2", got)
+	}
+
+	clock.Advance(3 * time.Minute)
+	if got, _ := v.Get(ctx); got != 3 {
+		t.Errorf("expired Get() = %d, want 3", got)
+	}
+
+	want := Stats{Hits: 1, StaleHits: 1, Misses: 2}
+	if got := v.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestErrorsAreNotCached(t *testing.T) {
+	fail := true
+	v := New(func(context.Context) (string, error) {
+		if fail {
+			return "", errors.New("unavailable")
+		}
+		return "ok", nil
+	}, Options{TTL: time.Minute})
+
+	if _, err := v.Get(context.Background()); err == nil {
+		t.Fatal("expected an error from the first load")
+	}
+	fail = false
+	if got, err := v.Get(context.Background()); err != nil || got != "ok" {
+		t.Errorf("Get() = %q, %v; want \"ok\", nil", got, err)
+	}
+	if n := v.Stats().Errors; n != 1 {
+		t.Errorf("Errors = %d, want 1", n)
+	}
+}
+
+func TestLazy(t *testing.T) {
+	var (
+		clock = clocktest.New(time.Unix(0, 0))
+		loads atomic.Int32
+		fail  = true
+	)
+	v := Lazy(func(context.Context) (int32, error) {
+		if fail {
+			return 0, errors.New("not yet")
+		}
+		return loads.Add(1), nil
+	})
+	v.clock = clock
+
+	if _, ok := v.Peek(); ok {
+		t.Error("Peek() reports a value before the first load")
+	}
+	ctx := context.Background()
+	if _, err := v.Get(ctx); err == nil {
+		t.Fatal("Get() succeeded when the load failed")
+	}
+	fail = false
+	if got, err := v.Get(ctx); got != 1 || err != nil {
+		t.Fatalf("Get() = %v, %v after a failure; want 1, nil", got, err)
+	}
+
+	clock.Advance(100 * 365 * 24 * time.Hour)
+	if got, _ := v.Get(ctx); got != 1 {
+		t.Errorf("Get() = %d after a century, want the first value", got)
+	}
+	if got, ok := v.Peek(); got != 1 || !ok {
+		t.Errorf("Peek() = %v, %v; want 1, true", got, ok)
+	}
+}
@@ -0,0 +1,97 @@
+// Package calendar renders randomizer selections as calendar events, either as
+// iCalendar (.ics) files or as links that pre-fill a new event in Google
+// Calendar.
+package calendar
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Event is a single calendar event, such as "Alice presents at Friday's demo."
+type Event struct {
+	Title       string
+	Description string
+	Start       time.Time
+	Duration    time.Duration
+	// AllDay indicates that only the date of Start is significant.
+	AllDay bool
+}
+
+const (
+	icsTimeFormat = "20060102T150405Z"
+	icsDateFormat = "20060102"
+)
+
+// ICS renders the event as an iCalendar file that most calendar applications
+// can import.
+func (e Event) ICS() string {
+	var b strings.Builder
+	line := func(format string, args ...any) {
+		b.WriteString(fmt.Sprintf(format, args...))
+		b.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//featherbread//randomizer//EN")
+	line("BEGIN:VEVENT")
+	line("UID:%s@randomizer", newUID())
+	line("DTSTAMP:%s", time.Now().UTC().Format(icsTimeFormat))
+	if e.AllDay {
+		line("DTSTART;VALUE=DATE:%s", e.Start.Format(icsDateFormat))
+		line("DTEND;VALUE=DATE:%s", e.Start.AddDate(0, 0, 1).Format(icsDateFormat))
+	} else {
+		line("DTSTART:%s", e.Start.UTC().Format(icsTimeFormat))
+		line("DTEND:%s", e.end().UTC().Format(icsTimeFormat))
+	}
+	line("SUMMARY:%s", escapeText(e.Title))
+	if e.Description != "" {
+		line("DESCRIPTION:%s", escapeText(e.Description))
+	}
+	line("END:VEVENT")
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+// GoogleCalendarURL returns a link that opens a pre-filled new event in
+// Google Calendar, which requires no credentials on the randomizer's part.
+func (e Event) GoogleCalendarURL() string {
+	var dates string
+	if e.AllDay {
+		dates = e.Start.Format(icsDateFormat) + "/" + e.Start.AddDate(0, 0, 1).Format(icsDateFormat)
+	} else {
+		dates = e.Start.UTC().Format(icsTimeFormat) + "/" + e.end().UTC().Format(icsTimeFormat)
+	}
+
+	query := url.Values{
+		"action":  {"TEMPLATE"},
+		"text":    {e.Title},
+		"dates":   {dates},
+		"details": {e.Description},
+	}
+	return "https://calendar.google.com/calendar/render?" + query.Encode()
+}
+
+func (e Event) end() time.Time {
+	if e.Duration <= 0 {
+		return e.Start.Add(30 * time.Minute)
+	}
+	return e.Start.Add(e.Duration)
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func newUID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
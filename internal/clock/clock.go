@@ -0,0 +1,29 @@
+// Package clock abstracts the source of the current time, so that behavior
+// that depends on it (like cache expiration, selection history, and scheduled
+// deletions) can be tested deterministically.
+package clock
+
+import "time"
+
+// Clock tells the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the Clock that reads the system's time.
+var System Clock = Func(time.Now)
+
+// Func adapts an ordinary function to a Clock.
+type Func func() time.Time
+
+// Now returns f().
+func (f Func) Now() time.Time { return f() }
+
+// Or returns c, or System if c is nil, for types whose zero value should use
+// the system clock.
+func Or(c Clock) Clock {
+	if c == nil {
+		return System
+	}
+	return c
+}
@@ -0,0 +1,144 @@
+// Package compress provides HTTP middleware that gzips large responses.
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// DefaultMinSize is the smallest response body that Handler compresses by
+// default. Smaller bodies tend to shrink too little to be worth the effort,
+// and most slash command responses fall well below it.
+const DefaultMinSize = 1024
+
+// Handler gzips the responses from h when the client accepts gzip encoding and
+// the body reaches minSize bytes (or DefaultMinSize, if minSize is 0).
+//
+// Handler buffers responses until it decides whether to compress them. If h
+// flushes a response before then, Handler stops buffering and sends the rest
+// of the response uncompressed, so streaming responses keep working.
+func Handler(h http.Handler, minSize int) http.Handler {
+	if minSize == 0 {
+		minSize = DefaultMinSize
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, minSize: minSize, status: http.StatusOK}
+		defer cw.close()
+		h.ServeHTTP(cw, r)
+	})
+}
+
+func acceptsGzip(header string) bool {
+	for coding := range strings.SplitSeq(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(coding), ";")
+		if strings.EqualFold(strings.TrimSpace(name), "gzip") {
+			return strings.ReplaceAll(params, " ", "") != "q=0"
+		}
+	}
+	return false
+}
+
+type compressWriter struct {
+	http.ResponseWriter
+	minSize int
+
+	status      int
+	wroteHeader bool
+	buf         bytes.Buffer
+	gz          *gzip.Writer
+	passthrough bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if !cw.wroteHeader {
+		cw.status = status
+		cw.wroteHeader = true
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	cw.wroteHeader = true
+	switch {
+	case cw.passthrough:
+		return cw.ResponseWriter.Write(p)
+	case cw.gz != nil:
+		return cw.gz.Write(p)
+	}
+
+	cw.buf.Write(p)
+	if cw.buf.Len() >= cw.minSize {
+		if err := cw.start(cw.compressible()); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends any buffered response immediately, giving up on compression if
+// it hasn't started yet.
+func (cw *compressWriter) Flush() {
+	if cw.gz == nil && !cw.passthrough {
+		cw.start(false)
+	}
+	if cw.gz != nil {
+		cw.gz.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *compressWriter) compressible() bool {
+	header := cw.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	switch contentType := header.Get("Content-Type"); {
+	case strings.HasPrefix(contentType, "text/event-stream"):
+		return false
+	case strings.HasPrefix(contentType, "image/"), strings.HasPrefix(contentType, "video/"):
+		return false
+	}
+	return cw.status != http.StatusNoContent && cw.status != http.StatusNotModified
+}
+
+// start writes the response header and buffered body, and directs the rest of
+// the body through gzip if compress is true.
+func (cw *compressWriter) start(compress bool) error {
+	if compress {
+		cw.Header().Set("Content-Encoding", "gzip")
+		cw.Header().Del("Content-Length")
+		cw.ResponseWriter.WriteHeader(cw.status)
+		cw.gz = gzip.NewWriter(cw.ResponseWriter)
+		_, err := cw.gz.Write(cw.buf.Bytes())
+		cw.buf.Reset()
+		return err
+	}
+
+	cw.passthrough = true
+	cw.ResponseWriter.WriteHeader(cw.status)
+	_, err := cw.ResponseWriter.Write(cw.buf.Bytes())
+	cw.buf.Reset()
+	return err
+}
+
+func (cw *compressWriter) close() {
+	switch {
+	case cw.gz != nil:
+		cw.gz.Close()
+	case !cw.passthrough:
+		if cw.wroteHeader || cw.buf.Len() > 0 {
+			cw.start(false)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package compress
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	large := strings.Repeat("randomize-", 500)
+	handler := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, r.URL.Query().Get("body"))
+	}), 0)
+
+	for _, tc := range []struct {
+		description    string
+		body           string
+		acceptEncoding string
+		wantGzip       bool
+	}{
+		{"large response", large, "gzip, deflate, br", true},
+		{"small response", "ok", "gzip", false},
+		{"client without gzip", large, "br", false},
+		{"client refusing gzip", large, "gzip;q=0", false},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/?body="+tc.body, nil)
+			req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+
+			body := io.Reader(resp.Body)
+			if gotGzip := resp.Header().Get("Content-Encoding") == "gzip"; gotGzip != tc.wantGzip {
+				t.Fatalf("got gzip %v, want %v", gotGzip, tc.wantGzip)
+			}
+			if tc.wantGzip {
+				gz, err := gzip.NewReader(resp.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = gz
+			}
+			got, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.body {
+				t.Errorf("got body of length %d, want %d", len(got), len(tc.body))
+			}
+		})
+	}
+}
+
+func TestHandlerFlush(t *testing.T) {
+	handler := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "first")
+		http.NewResponseController(w).Flush()
+		io.WriteString(w, strings.Repeat("x", 2*DefaultMinSize))
+	}), 0)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	if resp.Header().Get("Content-Encoding") != "" {
+		t.Error("compressed a response after it was flushed")
+	}
+	if !resp.Flushed || !strings.HasPrefix(resp.Body.String(), "first") {
+		t.Error("response was not flushed through")
+	}
+}
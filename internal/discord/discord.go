@@ -0,0 +1,404 @@
+// Package discord supports invoking the randomizer as a Discord application
+// command.
+package discord
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+
+	"github.com/featherbread/randomizer/internal/clock"
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+var tracer = otel.Tracer("github.com/featherbread/randomizer/internal/discord")
+
+// Default settings for an App.
+const (
+	DefaultAPIURL     = "https://discord.com/api/v10/"
+	DefaultDeferAfter = 2 * time.Second
+)
+
+// maxRequestBytes bounds the size of an interaction from Discord.
+const maxRequestBytes = 1 << 20
+
+// maxTimestampAge bounds how old a signed interaction may be, to limit replays.
+const maxTimestampAge = 5 * time.Minute
+
+// followUpTimeout bounds the time spent on a deferred command. Discord accepts
+// follow-ups with an interaction's token for 15 minutes.
+const followUpTimeout = 14 * time.Minute
+
+// argsOption is the name of the string option that carries the randomizer's
+// arguments, as registered with the application command.
+const argsOption = "args"
+
+// Interaction and response types from the Discord API.
+const (
+	interactionPing               = 1
+	interactionApplicationCommand = 2
+
+	responsePong                   = 1
+	responseChannelMessage         = 4
+	responseDeferredChannelMessage = 5
+)
+
+// Message limits and formatting from the Discord API.
+const (
+	messageFlagEphemeral      = 1 << 6
+	maxContentLength          = 2000
+	maxEmbedDescriptionLength = 4096
+	embedColor                = 0x5865F2
+)
+
+// Partitions for Discord channels and guilds are prefixed so that they can't
+// collide with Slack's.
+const (
+	partitionPrefix = "discord-"
+	guildPrefix     = "discord-guild-"
+)
+
+// App serves the randomizer through Discord's interactions endpoint, using
+// the same stores as the Slack App.
+//
+// Each Discord channel is its own partition, named with a "discord-" prefix
+// so that it can't collide with a Slack channel. Settings shared by a whole
+// workspace in Slack, like themes, are shared by a Discord server (guild).
+//
+// Discord expects a response to each interaction within 3 seconds. When a
+// command takes longer than DeferAfter, the App acknowledges it right away,
+// and edits in the result once the command finishes. Since that requires the
+// process to keep running after it responds, App doesn't suit platforms that
+// freeze between requests, like AWS Lambda.
+type App struct {
+	// PublicKey verifies the signature that Discord adds to each interaction.
+	// It can be obtained from the application's settings in the Discord
+	// Developer Portal.
+	PublicKey ed25519.PublicKey
+	// StoreFactory provides a Store for each partition.
+	StoreFactory func(partition string) randomizer.Store
+	// Defaults configures what the randomizer does with commands that don't
+	// name an operation it knows.
+	Defaults randomizer.Defaults
+	// ContentCheck, if non-nil, reviews group names, options, and tags before
+	// they're saved.
+	ContentCheck randomizer.ContentCheck
+	// Deployment describes the deployment for the /version operation.
+	Deployment randomizer.Deployment
+	// DeferAfter overrides DefaultDeferAfter.
+	DeferAfter time.Duration
+	// BaseURL overrides DefaultAPIURL, e.g. for testing.
+	BaseURL string
+	// HTTPClient overrides http.DefaultClient for follow-up messages.
+	HTTPClient *http.Client
+	// Clock, if non-nil, replaces the system clock for checking interaction
+	// timestamps and for the randomizer's history and schedules.
+	Clock clock.Clock
+	// Logger, if non-nil, logs errors encountered during request handling.
+	Logger *slog.Logger
+}
+
+// PublicKeyFromEnv returns the application public key in the hex-encoded
+// DISCORD_PUBLIC_KEY environment variable, or nil if it is unset.
+func PublicKeyFromEnv() (ed25519.PublicKey, error) {
+	encoded, ok := os.LookupEnv("DISCORD_PUBLIC_KEY")
+	if !ok {
+		return nil, nil
+	}
+	key, err := hex.DecodeString(encoded)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("DISCORD_PUBLIC_KEY must be a hex-encoded Ed25519 public key")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// Partition returns the partition that holds a Discord channel's groups.
+func Partition(channelID string) string {
+	return partitionPrefix + channelID
+}
+
+// interaction is the subset of a Discord interaction that the App uses.
+type interaction struct {
+	Type          int    `json:"type"`
+	ApplicationID string `json:"application_id"`
+	Token         string `json:"token"`
+	GuildID       string `json:"guild_id"`
+	ChannelID     string `json:"channel_id"`
+	Member        *struct {
+		User user `json:"user"`
+	} `json:"member"`
+	User *user `json:"user"`
+	Data struct {
+		Name    string `json:"name"`
+		Options []struct {
+			Name  string          `json:"name"`
+			Value json.RawMessage `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+}
+
+type user struct {
+	ID string `json:"id"`
+}
+
+// userID returns the ID of the user who invoked the command, who appears as a
+// guild member in servers and as a user in DMs.
+func (i interaction) userID() string {
+	switch {
+	case i.Member != nil:
+		return i.Member.User.ID
+	case i.User != nil:
+		return i.User.ID
+	default:
+		return ""
+	}
+}
+
+// args returns the randomizer's arguments from the command's options.
+func (i interaction) args() []string {
+	for _, opt := range i.Data.Options {
+		var value string
+		if opt.Name == argsOption && json.Unmarshal(opt.Value, &value) == nil {
+			return strings.Fields(value)
+		}
+	}
+	return nil
+}
+
+type response struct {
+	Type int          `json:"type"`
+	Data *messageData `json:"data,omitempty"`
+}
+
+type messageData struct {
+	Content         string          `json:"content,omitempty"`
+	Embeds          []embed         `json:"embeds,omitempty"`
+	Flags           int             `json:"flags,omitempty"`
+	AllowedMentions allowedMentions `json:"allowed_mentions"`
+}
+
+type embed struct {
+	Description string `json:"description"`
+	Color       int    `json:"color,omitempty"`
+}
+
+// allowedMentions controls who a message notifies.
+type allowedMentions struct {
+	Parse []string `json:"parse"`
+}
+
+// noMentions keeps a message from notifying anyone, since picking someone
+// isn't the same as paging them.
+var noMentions = allowedMentions{Parse: []string{}}
+
+// ServeHTTP serves POST requests from Discord's interactions endpoint.
+func (a App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Add("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	if err != nil {
+		a.logErr(err, "Failed to read request body")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !a.validSignature(r.Header, body) {
+		// Discord checks that endpoints reject bad signatures before accepting
+		// them, so this is a normal part of setup.
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var in interaction
+	if err := json.Unmarshal(body, &in); err != nil {
+		a.logErr(err, "Failed to decode interaction")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch in.Type {
+	case interactionPing:
+		a.writeResponse(w, response{Type: responsePong})
+	case interactionApplicationCommand:
+		a.serveCommand(r.Context(), w, in)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func (a App) validSignature(header http.Header, body []byte) bool {
+	timestamp := header.Get("X-Signature-Timestamp")
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := clock.Or(a.Clock).Now().Sub(time.Unix(ts, 0)); age > maxTimestampAge || age < -maxTimestampAge {
+		return false
+	}
+
+	signature, err := hex.DecodeString(header.Get("X-Signature-Ed25519"))
+	if err != nil || len(signature) != ed25519.SignatureSize || len(a.PublicKey) != ed25519.PublicKeySize {
+		return false
+	}
+	return ed25519.Verify(a.PublicKey, append([]byte(timestamp), body...), signature)
+}
+
+// serveCommand responds with the result of a command if it finishes within
+// DeferAfter, or else defers the response and edits the result in later.
+func (a App) serveCommand(ctx context.Context, w http.ResponseWriter, in interaction) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), followUpTimeout)
+	done := make(chan *messageData, 1)
+	go func() { done <- a.runCommand(ctx, in) }()
+
+	deferAfter := a.DeferAfter
+	if deferAfter == 0 {
+		deferAfter = DefaultDeferAfter
+	}
+	timer := time.NewTimer(deferAfter)
+	defer timer.Stop()
+
+	select {
+	case data := <-done:
+		cancel()
+		a.writeResponse(w, response{Type: responseChannelMessage, Data: data})
+	case <-timer.C:
+		// Whether a deferred response is ephemeral can't change later, so slow
+		// results are always posted to the channel.
+		a.writeResponse(w, response{Type: responseDeferredChannelMessage})
+		go func() {
+			defer cancel()
+			data := <-done
+			data.Flags = 0
+			if err := a.editOriginal(ctx, in, data); err != nil {
+				a.logErr(err, "Failed to send deferred response")
+			}
+		}()
+	}
+}
+
+// runCommand runs the randomizer for a command, and returns the message that
+// should answer it.
+func (a App) runCommand(ctx context.Context, in interaction) *messageData {
+	ctx, span := tracer.Start(ctx, "discord.App.runCommand")
+	defer span.End()
+
+	opts := []randomizer.AppOption{
+		randomizer.WithRenderer(markdown),
+		randomizer.WithUser(in.userID()),
+		randomizer.WithDefaults(a.Defaults),
+		randomizer.WithDeployment(a.Deployment),
+	}
+	if in.GuildID != "" {
+		opts = append(opts, randomizer.WithWorkspaceStore(a.StoreFactory(guildPrefix+in.GuildID)))
+	}
+	if a.ContentCheck != nil {
+		opts = append(opts, randomizer.WithContentCheck(a.ContentCheck))
+	}
+	if a.Clock != nil {
+		opts = append(opts, randomizer.WithClock(a.Clock))
+	}
+	if a.Logger != nil {
+		opts = append(opts, randomizer.WithLogger(a.Logger))
+	}
+
+	app := randomizer.NewApp("/"+in.Data.Name, a.StoreFactory(Partition(in.ChannelID)), opts...)
+	result, err := app.Main(ctx, in.args())
+	if err != nil {
+		a.logErr(err, "Failed to run randomizer")
+		return &messageData{
+			Content:         truncate(err.(randomizer.Error).HelpText(), maxContentLength),
+			Flags:           messageFlagEphemeral,
+			AllowedMentions: noMentions,
+		}
+	}
+
+	switch {
+	case result.DryRun(), result.Private():
+	case result.Type() == randomizer.Selection, result.Type() == randomizer.Assignment, result.Type() == randomizer.RevealedPick, result.Type() == randomizer.ProposedPick, result.Type() == randomizer.RatifiedPick, result.Type() == randomizer.Teams, result.Type() == randomizer.Sampled, result.Type() == randomizer.RolledDice, result.Type() == randomizer.PickedNumbers, result.Changed():
+		return &messageData{
+			Embeds: []embed{{
+				Description: truncate(result.Message(), maxEmbedDescriptionLength),
+				Color:       embedColor,
+			}},
+			AllowedMentions: noMentions,
+		}
+	}
+	return &messageData{
+		Content:         truncate(result.Message(), maxContentLength),
+		Flags:           messageFlagEphemeral,
+		AllowedMentions: noMentions,
+	}
+}
+
+// editOriginal replaces a deferred response with its result, through the
+// interaction's webhook, which needs no bot token.
+func (a App) editOriginal(ctx context.Context, in interaction, data *messageData) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	baseURL := a.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultAPIURL
+	}
+	target := fmt.Sprintf("%swebhooks/%s/%s/messages/@original", baseURL, in.ApplicationID, in.Token)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := a.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("discord returned %s: %s", resp.Status, detail)
+	}
+	return nil
+}
+
+func (a App) writeResponse(w http.ResponseWriter, resp response) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		a.logErr(err, "Failed to write response")
+	}
+}
+
+func (a App) logErr(err error, msg string) {
+	if a.Logger != nil {
+		a.Logger.Error(msg, "err", err)
+	}
+}
+
+// truncate shortens s to at most n characters, to fit Discord's limits.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,141 @@
+package discord
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/clock/clocktest"
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+)
+
+var testNow = time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+func TestInteractions(t *testing.T) {
+	public, private, _ := ed25519.GenerateKey(nil)
+	store := &rndtest.SyncStore{Store: make(rndtest.Store)}
+	app := App{
+		PublicKey:    public,
+		StoreFactory: func(_ string) randomizer.Store { return store },
+		Clock:        clocktest.New(testNow),
+	}
+
+	if resp := post(app, private, `{"type": 1}`); resp.Code != http.StatusOK || !strings.Contains(resp.Body.String(), `"type":1`) {
+		t.Errorf("PING got %d %s, want PONG", resp.Code, resp.Body)
+	}
+
+	_, otherKey, _ := ed25519.GenerateKey(nil)
+	if resp := post(app, otherKey, `{"type": 1}`); resp.Code != http.StatusUnauthorized {
+		t.Errorf("wrong status for a bad signature: got %d, want %d", resp.Code, http.StatusUnauthorized)
+	}
+
+	for _, tc := range []struct {
+		text      string
+		ephemeral bool
+		want      string
+	}{
+		{text: "/save lunch pizza tacos", want: `The "lunch" group was saved`},
+		{text: "/list", ephemeral: true, want: "lunch"},
+		{text: "lunch", want: "I randomized and got: **"},
+		{text: "/show", ephemeral: true, want: "Whoops"},
+	} {
+		resp := post(app, private, command(tc.text))
+		var got response
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatalf("%q: %v", tc.text, err)
+		}
+		if got.Type != responseChannelMessage || got.Data == nil {
+			t.Fatalf("%q: got response %+v", tc.text, got)
+		}
+		if ephemeral := got.Data.Flags&messageFlagEphemeral != 0; ephemeral != tc.ephemeral {
+			t.Errorf("%q: got ephemeral %v, want %v", tc.text, ephemeral, tc.ephemeral)
+		}
+		text := got.Data.Content
+		if len(got.Data.Embeds) > 0 {
+			text = got.Data.Embeds[0].Description
+		}
+		if !strings.Contains(text, tc.want) {
+			t.Errorf("%q: got message %q, want %q", tc.text, text, tc.want)
+		}
+	}
+
+	if groups, _ := store.List(context.Background()); len(groups) == 0 {
+		t.Error("saved group is missing from the store")
+	}
+}
+
+func TestDeferredResponse(t *testing.T) {
+	edits := make(chan string, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != "/webhooks/A1/tok/messages/@original" {
+			t.Errorf("unexpected follow-up %s %s", r.Method, r.URL.Path)
+		}
+		body, _ := io.ReadAll(r.Body)
+		edits <- string(body)
+	}))
+	defer webhook.Close()
+
+	public, private, _ := ed25519.GenerateKey(nil)
+	gate := make(chan struct{})
+	app := App{
+		PublicKey: public,
+		StoreFactory: func(_ string) randomizer.Store {
+			return slowStore{&rndtest.SyncStore{Store: make(rndtest.Store)}, gate}
+		},
+		DeferAfter: time.Millisecond,
+		BaseURL:    webhook.URL + "/",
+		Clock:      clocktest.New(testNow),
+	}
+
+	resp := post(app, private, command("/save lunch pizza tacos"))
+	if !strings.Contains(resp.Body.String(), `"type":5`) {
+		t.Fatalf("got response %s, want a deferred message", resp.Body)
+	}
+
+	close(gate)
+	select {
+	case edit := <-edits:
+		if !strings.Contains(edit, "lunch") || !strings.Contains(edit, `"embeds"`) {
+			t.Errorf("got edit %s, want the saved group", edit)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("deferred result was never sent")
+	}
+}
+
+// slowStore blocks writes until its gate is closed.
+type slowStore struct {
+	*rndtest.SyncStore
+	gate chan struct{}
+}
+
+func (s slowStore) Put(ctx context.Context, name string, options []string) error {
+	<-s.gate
+	return s.SyncStore.Put(ctx, name, options)
+}
+
+func command(text string) string {
+	args, _ := json.Marshal(text)
+	return `{"type": 2, "application_id": "A1", "token": "tok", "guild_id": "G1", "channel_id": "C1",
+		"member": {"user": {"id": "U1"}},
+		"data": {"name": "randomize", "options": [{"name": "args", "type": 3, "value": ` + string(args) + `}]}}`
+}
+
+func post(app App, key ed25519.PrivateKey, body string) *httptest.ResponseRecorder {
+	timestamp := strconv.FormatInt(testNow.Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, "/discord", strings.NewReader(body))
+	req.Header.Set("X-Signature-Timestamp", timestamp)
+	req.Header.Set("X-Signature-Ed25519", hex.EncodeToString(ed25519.Sign(key, []byte(timestamp+body))))
+	resp := httptest.NewRecorder()
+	app.ServeHTTP(resp, req)
+	return resp
+}
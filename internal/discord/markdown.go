@@ -0,0 +1,27 @@
+package discord
+
+import (
+	"regexp"
+
+	"github.com/featherbread/randomizer/internal/mrkdwn"
+)
+
+// slackUserPattern matches Slack's user mentions, like "<@U123>" or
+// "<@U123|alice>", which Discord can't resolve. Discord's own mentions use
+// numeric IDs, and render as they are.
+var slackUserPattern = regexp.MustCompile(`<@([UW][A-Z0-9]+)(?:\|([^>]*))?>`)
+
+// markdown converts a message from Slack's mrkdwn to Discord's Markdown.
+// Slack mentions become the user's name where the mention includes one, or
+// their ID otherwise.
+func markdown(text string) string {
+	return mrkdwn.Markdown(text, func(part string) string {
+		return slackUserPattern.ReplaceAllStringFunc(part, func(mention string) string {
+			match := slackUserPattern.FindStringSubmatch(mention)
+			if match[2] != "" {
+				return "@" + match[2]
+			}
+			return match[1]
+		})
+	})
+}
@@ -0,0 +1,15 @@
+package discord
+
+import "testing"
+
+func TestMarkdown(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"I randomized and got: *<@U123>*, *<@W456|bob>*.", "I randomized and got: **U123**, **@bob**."},
+		{"Ask <@123456789012345678> instead.", "Ask <@123456789012345678> instead."},
+		{"Table:\n```\n| <@U123> |\n```", "Table:\n```\n| <@U123> |\n```"},
+	} {
+		if got := markdown(tc.in); got != tc.want {
+			t.Errorf("markdown(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Package flags provides feature flags that operators can toggle at runtime
+// without redeploying the randomizer.
+package flags
+
+import (
+	"maps"
+	"slices"
+	"sync"
+)
+
+// Set is a concurrency-safe collection of named boolean flags. The zero value
+// is an empty Set ready for use, in which every flag is disabled.
+type Set struct {
+	mu     sync.RWMutex
+	values map[string]bool
+}
+
+// Enabled indicates whether the named flag is enabled. A nil Set reports
+// every flag as disabled.
+func (s *Set) Enabled(name string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.values[name]
+}
+
+// Set enables or disables the named flag.
+func (s *Set) Set(name string, enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values == nil {
+		s.values = make(map[string]bool)
+	}
+	s.values[name] = enabled
+}
+
+// All returns a snapshot of every flag that has been explicitly set.
+func (s *Set) All() map[string]bool {
+	if s == nil {
+		return map[string]bool{}
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.values == nil {
+		return map[string]bool{}
+	}
+	return maps.Clone(s.values)
+}
+
+// EnabledNames returns the names of the enabled flags in sorted order.
+func (s *Set) EnabledNames() []string {
+	var names []string
+	for name, enabled := range s.All() {
+		if enabled {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,227 @@
+// Package health probes the randomizer's store in the background, so that
+// operators see it degrade before users do.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/alert"
+	"github.com/featherbread/randomizer/internal/clock"
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+// Default settings for a Prober.
+const (
+	DefaultInterval         = 30 * time.Second
+	DefaultTimeout          = 5 * time.Second
+	DefaultFailureThreshold = 3
+)
+
+// Partition is the store partition that a Prober reads from. It never holds
+// any groups, so a probe costs a single small read.
+const Partition = "randomizer-health"
+
+// probeRecord is the group that a probe reads. Its name is a record name, so
+// users could never have saved it.
+const probeRecord = "/health"
+
+// Status describes the store's health as of the most recent probe.
+type Status struct {
+	Healthy             bool          `json:"healthy"`
+	CheckedAt           time.Time     `json:"checked_at,omitzero"`
+	LastSuccess         time.Time     `json:"last_success,omitzero"`
+	Latency             time.Duration `json:"latency_ns"`
+	ConsecutiveFailures int           `json:"consecutive_failures"`
+	Checks              int64         `json:"checks"`
+	Failures            int64         `json:"failures"`
+	Error               string        `json:"error,omitempty"`
+}
+
+// Prober periodically reads from a store to check that it's available. The
+// store counts as degraded after FailureThreshold consecutive failures, and
+// recovers after a single success.
+//
+// A nil *Prober reports a healthy store.
+type Prober struct {
+	// Store is the store to probe, normally from the partition named by
+	// [Partition].
+	Store randomizer.Store
+	// Interval sets the time between probes. If zero, it defaults to
+	// DefaultInterval.
+	Interval time.Duration
+	// Timeout bounds each probe. If zero, it defaults to DefaultTimeout.
+	Timeout time.Duration
+	// FailureThreshold sets the number of consecutive failures that degrade
+	// the store. If zero, it defaults to DefaultFailureThreshold.
+	FailureThreshold int
+	// Alerts, if non-nil, notifies operators when the store degrades and
+	// recovers.
+	Alerts *alert.Tracker
+	// Clock, if non-nil, replaces the system clock.
+	Clock clock.Clock
+	// Logger, if non-nil, logs changes in the store's health.
+	Logger *slog.Logger
+
+	mu       sync.Mutex
+	status   Status
+	degraded bool
+}
+
+// Run probes the store on every interval until ctx is canceled.
+func (p *Prober) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval())
+	defer ticker.Stop()
+	for {
+		p.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckIfDue probes the store if a full interval has passed since the last
+// probe, for platforms like AWS Lambda that can't probe in the background.
+func (p *Prober) CheckIfDue(ctx context.Context) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	due := p.status.CheckedAt.IsZero() || p.now().Sub(p.status.CheckedAt) >= p.interval()
+	if due {
+		// Concurrent requests shouldn't all probe at once.
+		p.status.CheckedAt = p.now()
+	}
+	p.mu.Unlock()
+	if due {
+		p.Check(ctx)
+	}
+}
+
+// Check probes the store once, and returns its updated status.
+func (p *Prober) Check(ctx context.Context) Status {
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := p.now()
+	_, err := p.Store.Get(probeCtx, probeRecord)
+	end := p.now()
+
+	p.mu.Lock()
+	s := &p.status
+	s.CheckedAt = end
+	s.Latency = end.Sub(start)
+	s.Checks++
+	if err == nil {
+		s.LastSuccess = end
+		s.ConsecutiveFailures = 0
+		s.Error = ""
+	} else {
+		s.ConsecutiveFailures++
+		s.Failures++
+		s.Error = err.Error()
+	}
+	threshold := p.FailureThreshold
+	if threshold == 0 {
+		threshold = DefaultFailureThreshold
+	}
+	s.Healthy = s.ConsecutiveFailures < threshold
+	changed := p.degraded == s.Healthy
+	p.degraded = !s.Healthy
+	status := *s
+	p.mu.Unlock()
+
+	if changed {
+		p.reportChange(ctx, status)
+	}
+	return status
+}
+
+func (p *Prober) reportChange(ctx context.Context, status Status) {
+	logger := p.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	if status.Healthy {
+		logger.Info("Store recovered", "latency", status.Latency)
+		p.Alerts.Notify(ctx, "the store recovered")
+		return
+	}
+	logger.Error("Store is degraded", "failures", status.ConsecutiveFailures, "err", status.Error)
+	p.Alerts.Notify(ctx, fmt.Sprintf("the store is degraded after %d failed health checks: %s", status.ConsecutiveFailures, status.Error))
+}
+
+// Status returns the store's health as of the most recent probe. Before the
+// first probe, the store counts as healthy.
+func (p *Prober) Status() Status {
+	if p == nil {
+		return Status{Healthy: true}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := p.status
+	if status.Checks == 0 {
+		status.Healthy = true
+	}
+	return status
+}
+
+// ReadyHandler serves the store's status as JSON, with a 503 Service
+// Unavailable status while the store is degraded, for load balancers and
+// orchestrators that stop routing traffic to unready instances.
+func (p *Prober) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := p.Status()
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if !status.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(status)
+	})
+}
+
+// MetricsHandler serves the store's status in the Prometheus text exposition
+// format.
+func (p *Prober) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := p.Status()
+		healthy := 0
+		if status.Healthy {
+			healthy = 1
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprintf(w, "# HELP randomizer_store_healthy Whether the store passed its recent health checks.\n")
+		fmt.Fprintf(w, "# TYPE randomizer_store_healthy gauge\nrandomizer_store_healthy %d\n", healthy)
+		fmt.Fprintf(w, "# HELP randomizer_store_probe_latency_seconds The latency of the most recent health check.\n")
+		fmt.Fprintf(w, "# TYPE randomizer_store_probe_latency_seconds gauge\nrandomizer_store_probe_latency_seconds %g\n", status.Latency.Seconds())
+		fmt.Fprintf(w, "# HELP randomizer_store_probe_consecutive_failures The number of health checks that failed since the last success.\n")
+		fmt.Fprintf(w, "# TYPE randomizer_store_probe_consecutive_failures gauge\nrandomizer_store_probe_consecutive_failures %d\n", status.ConsecutiveFailures)
+		fmt.Fprintf(w, "# HELP randomizer_store_probes_total The number of health checks run.\n")
+		fmt.Fprintf(w, "# TYPE randomizer_store_probes_total counter\nrandomizer_store_probes_total %d\n", status.Checks)
+		fmt.Fprintf(w, "# HELP randomizer_store_probe_failures_total The number of health checks that failed.\n")
+		fmt.Fprintf(w, "# TYPE randomizer_store_probe_failures_total counter\nrandomizer_store_probe_failures_total %d\n", status.Failures)
+	})
+}
+
+func (p *Prober) interval() time.Duration {
+	if p.Interval == 0 {
+		return DefaultInterval
+	}
+	return p.Interval
+}
+
+func (p *Prober) now() time.Time {
+	return clock.Or(p.Clock).Now()
+}
@@ -0,0 +1,116 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/alert"
+	"github.com/featherbread/randomizer/internal/clock/clocktest"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+)
+
+func TestProber(t *testing.T) {
+	var (
+		store    = &flakyStore{Store: make(rndtest.Store)}
+		notifier = &recordingNotifier{}
+		p        = &Prober{
+			Store:  store,
+			Alerts: &alert.Tracker{Notifiers: []alert.Notifier{notifier}},
+			Clock:  clocktest.New(time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)),
+		}
+		ctx = context.Background()
+	)
+
+	if !p.Status().Healthy {
+		t.Fatal("store is unhealthy before the first probe")
+	}
+	if status := p.Check(ctx); !status.Healthy || status.Checks != 1 {
+		t.Fatalf("got status %+v after a successful probe", status)
+	}
+
+	store.failing.Store(true)
+	for i := range DefaultFailureThreshold {
+		status := p.Check(ctx)
+		if want := i+1 < DefaultFailureThreshold; status.Healthy != want {
+			t.Fatalf("after %d failures, got healthy %v, want %v", i+1, status.Healthy, want)
+		}
+	}
+	assertReady(t, p, http.StatusServiceUnavailable, "probe failed")
+
+	store.failing.Store(false)
+	p.Check(ctx)
+	assertReady(t, p, http.StatusOK, `"healthy":true`)
+
+	if len(notifier.alerts) != 2 ||
+		!strings.Contains(notifier.alerts[0].Message, "degraded") ||
+		!strings.Contains(notifier.alerts[1].Message, "recovered") {
+		t.Errorf("got alerts %+v, want one for degrading and one for recovering", notifier.alerts)
+	}
+
+	resp := httptest.NewRecorder()
+	p.MetricsHandler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{"randomizer_store_healthy 1\n", "randomizer_store_probes_total 5\n", "randomizer_store_probe_failures_total 3\n"} {
+		if !strings.Contains(resp.Body.String(), want) {
+			t.Errorf("metrics missing %q\n%s", want, resp.Body)
+		}
+	}
+}
+
+func TestCheckIfDue(t *testing.T) {
+	clock := clocktest.New(time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC))
+	p := &Prober{Store: make(rndtest.Store), Clock: clock}
+	ctx := context.Background()
+
+	p.CheckIfDue(ctx)
+	p.CheckIfDue(ctx)
+	if checks := p.Status().Checks; checks != 1 {
+		t.Errorf("got %d probes within an interval, want 1", checks)
+	}
+	clock.Advance(DefaultInterval)
+	p.CheckIfDue(ctx)
+	if checks := p.Status().Checks; checks != 2 {
+		t.Errorf("got %d probes after an interval, want 2", checks)
+	}
+
+	var nilProber *Prober
+	nilProber.CheckIfDue(ctx)
+	if !nilProber.Status().Healthy {
+		t.Error("nil prober reports an unhealthy store")
+	}
+}
+
+func assertReady(t *testing.T, p *Prober, code int, body string) {
+	t.Helper()
+	resp := httptest.NewRecorder()
+	p.ReadyHandler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if resp.Code != code || !strings.Contains(resp.Body.String(), body) {
+		t.Errorf("got /readyz %d %s, want %d with %q", resp.Code, resp.Body, code, body)
+	}
+}
+
+type flakyStore struct {
+	rndtest.Store
+	failing atomic.Bool
+}
+
+func (s *flakyStore) Get(ctx context.Context, name string) ([]string, error) {
+	if s.failing.Load() {
+		return nil, errors.New("probe failed")
+	}
+	return s.Store.Get(ctx, name)
+}
+
+type recordingNotifier struct {
+	alerts []alert.Alert
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, a alert.Alert) error {
+	n.alerts = append(n.alerts, a)
+	return nil
+}
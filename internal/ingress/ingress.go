@@ -0,0 +1,231 @@
+// Package ingress filters HTTP requests before they reach the randomizer, to
+// harden self-hosted deployments that can't sit behind a managed web
+// application firewall.
+package ingress
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxBodyBytes is the largest request body that a Filter accepts by
+// default. Slack and Discord requests are far smaller.
+const DefaultMaxBodyBytes = 1 << 20
+
+// DefaultMethods are the HTTP methods that a Filter accepts by default: those
+// that any of the randomizer's endpoints serve.
+var DefaultMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodDelete,
+}
+
+// Filter rejects requests from outside a set of allowed networks, with bodies
+// that are too large, or with unexpected methods, and logs each rejection.
+type Filter struct {
+	// Allow lists the networks that requests may come from, such as Slack's
+	// published IP ranges. If empty, requests may come from anywhere.
+	Allow []netip.Prefix
+	// TrustedProxies lists the networks of reverse proxies in front of the
+	// server, whose X-Forwarded-For headers identify the true source of their
+	// requests. Connections over Unix sockets, which only local processes can
+	// make, always count as coming from a trusted proxy.
+	TrustedProxies []netip.Prefix
+	// ExemptPaths lists paths that requests from anywhere may reach, like
+	// health checks from a load balancer.
+	ExemptPaths []string
+	// MaxBodyBytes bounds the size of request bodies. If zero, it defaults to
+	// DefaultMaxBodyBytes. If negative, bodies may be any size.
+	MaxBodyBytes int64
+	// Methods lists the HTTP methods that requests may use. If empty, it
+	// defaults to DefaultMethods.
+	Methods []string
+	// Logger, if non-nil, logs rejected requests.
+	Logger *slog.Logger
+}
+
+// FromEnv returns a Filter configured by the following environment variables,
+// or nil if none of them are set:
+//
+//   - INGRESS_ALLOW_CIDRS: comma-separated networks for Allow
+//   - INGRESS_TRUSTED_PROXIES: comma-separated networks for TrustedProxies
+//   - INGRESS_MAX_BODY_BYTES: the value of MaxBodyBytes
+//   - INGRESS_METHODS: comma-separated methods for Methods
+//
+// Networks may be written in CIDR notation or as single IP addresses.
+func FromEnv() (*Filter, error) {
+	var (
+		allow   = os.Getenv("INGRESS_ALLOW_CIDRS")
+		proxies = os.Getenv("INGRESS_TRUSTED_PROXIES")
+		maxBody = os.Getenv("INGRESS_MAX_BODY_BYTES")
+		methods = os.Getenv("INGRESS_METHODS")
+	)
+	if allow == "" && proxies == "" && maxBody == "" && methods == "" {
+		return nil, nil
+	}
+
+	var (
+		f   Filter
+		err error
+	)
+	if f.Allow, err = ParsePrefixes(allow); err != nil {
+		return nil, fmt.Errorf("invalid INGRESS_ALLOW_CIDRS: %w", err)
+	}
+	if f.TrustedProxies, err = ParsePrefixes(proxies); err != nil {
+		return nil, fmt.Errorf("invalid INGRESS_TRUSTED_PROXIES: %w", err)
+	}
+	if maxBody != "" {
+		if f.MaxBodyBytes, err = strconv.ParseInt(maxBody, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid INGRESS_MAX_BODY_BYTES: %w", err)
+		}
+	}
+	for method := range strings.SplitSeq(methods, ",") {
+		if method = strings.ToUpper(strings.TrimSpace(method)); method != "" {
+			f.Methods = append(f.Methods, method)
+		}
+	}
+	return &f, nil
+}
+
+// ParsePrefixes parses a comma-separated list of networks, written in CIDR
+// notation or as single IP addresses.
+func ParsePrefixes(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for field := range strings.SplitSeq(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(field); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// Handler returns a handler that passes the requests that f accepts to h. A
+// nil Filter accepts every request.
+func (f *Filter) Handler(h http.Handler) http.Handler {
+	if f == nil {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !slices.Contains(f.methods(), r.Method) {
+			f.reject(w, r, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		if len(f.Allow) > 0 && !slices.Contains(f.ExemptPaths, r.URL.Path) {
+			source, ok := f.source(r)
+			if !ok {
+				f.reject(w, r, http.StatusForbidden, "unknown source address")
+				return
+			}
+			if !containsAddr(f.Allow, source) {
+				f.reject(w, r, http.StatusForbidden, "source not allowed")
+				return
+			}
+		}
+
+		maxBody := f.MaxBodyBytes
+		if maxBody == 0 {
+			maxBody = DefaultMaxBodyBytes
+		}
+		if maxBody > 0 {
+			if r.ContentLength > maxBody {
+				f.reject(w, r, http.StatusRequestEntityTooLarge, "body too large")
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// source returns the address of the client that a request came from, taking
+// X-Forwarded-For headers from trusted proxies into account.
+func (f *Filter) source(r *http.Request) (netip.Addr, bool) {
+	addr, ok := remoteAddr(r.RemoteAddr)
+	trusted := !ok || containsAddr(f.TrustedProxies, addr)
+	if !trusted {
+		return addr, true
+	}
+
+	// Each proxy appends the address it received the request from, so the
+	// rightmost address that isn't a trusted proxy is the client's. Anything
+	// further left may have been forged by the client.
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for _, hop := range slices.Backward(hops) {
+		hopAddr, err := netip.ParseAddr(strings.TrimSpace(hop))
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		addr, ok = hopAddr.Unmap(), true
+		if !containsAddr(f.TrustedProxies, addr) {
+			break
+		}
+	}
+	return addr, ok
+}
+
+// remoteAddr parses the address of a connection as set in
+// [http.Request.RemoteAddr], which is not an IP address for Unix sockets.
+func remoteAddr(s string) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(s)
+	if err != nil {
+		host = s
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func (f *Filter) methods() []string {
+	if len(f.Methods) == 0 {
+		return DefaultMethods
+	}
+	return f.Methods
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	return slices.ContainsFunc(prefixes, func(p netip.Prefix) bool { return p.Contains(addr) })
+}
+
+func (f *Filter) reject(w http.ResponseWriter, r *http.Request, status int, reason string) {
+	if f.Logger != nil {
+		f.Logger.Warn("Rejected request",
+			"reason", reason,
+			"status", status,
+			"method", r.Method,
+			"path", r.URL.Path,
+			"remote_addr", r.RemoteAddr,
+			"forwarded_for", r.Header.Values("X-Forwarded-For"),
+			"content_length", r.ContentLength,
+		)
+	}
+	if status == http.StatusMethodNotAllowed {
+		w.Header().Set("Allow", strings.Join(f.methods(), ", "))
+	}
+	http.Error(w, http.StatusText(status), status)
+}
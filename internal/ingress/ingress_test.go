@@ -0,0 +1,126 @@
+package ingress
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	var logs bytes.Buffer
+	f := &Filter{
+		Allow:          mustParsePrefixes(t, "203.0.113.0/24, 2001:db8::1"),
+		TrustedProxies: mustParsePrefixes(t, "10.0.0.0/8"),
+		ExemptPaths:    []string{"/healthz"},
+		MaxBodyBytes:   16,
+		Logger:         slog.New(slog.NewJSONHandler(&logs, nil)),
+	}
+	h := f.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		}
+	}))
+
+	testCases := []struct {
+		description  string
+		method, path string
+		remoteAddr   string
+		forwardedFor string
+		body         string
+		chunked      bool
+		want         int
+	}{
+		{description: "allowed source", remoteAddr: "203.0.113.7:1234", want: http.StatusOK},
+		{description: "allowed IPv6 source", remoteAddr: "[2001:db8::1]:1234", want: http.StatusOK},
+		{description: "other source", remoteAddr: "198.51.100.7:1234", want: http.StatusForbidden},
+		{description: "exempt path", path: "/healthz", remoteAddr: "198.51.100.7:1234", want: http.StatusOK},
+		{description: "forwarded by trusted proxy", remoteAddr: "10.1.2.3:1234", forwardedFor: "203.0.113.7", want: http.StatusOK},
+		{description: "forwarded through several proxies", remoteAddr: "10.1.2.3:1234", forwardedFor: "198.51.100.7, 203.0.113.7, 10.4.5.6", want: http.StatusOK},
+		{description: "forged forwarding", remoteAddr: "10.1.2.3:1234", forwardedFor: "203.0.113.7, 198.51.100.7", want: http.StatusForbidden},
+		{description: "forwarded by untrusted proxy", remoteAddr: "198.51.100.7:1234", forwardedFor: "203.0.113.7", want: http.StatusForbidden},
+		{description: "Unix socket with forwarding", remoteAddr: "@", forwardedFor: "203.0.113.7", want: http.StatusOK},
+		{description: "Unix socket without forwarding", remoteAddr: "@", want: http.StatusForbidden},
+		{description: "method not allowed", method: http.MethodOptions, remoteAddr: "203.0.113.7:1234", want: http.StatusMethodNotAllowed},
+		{description: "large body", body: strings.Repeat("x", 17), remoteAddr: "203.0.113.7:1234", want: http.StatusRequestEntityTooLarge},
+		{description: "large chunked body", body: strings.Repeat("x", 17), chunked: true, remoteAddr: "203.0.113.7:1234", want: http.StatusRequestEntityTooLarge},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			method, path := tc.method, tc.path
+			if method == "" {
+				method = http.MethodPost
+			}
+			if path == "" {
+				path = "/"
+			}
+			r := httptest.NewRequest(method, path, strings.NewReader(tc.body))
+			r.RemoteAddr = tc.remoteAddr
+			if tc.forwardedFor != "" {
+				r.Header.Set("X-Forwarded-For", tc.forwardedFor)
+			}
+			if tc.chunked {
+				r.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tc.want {
+				t.Errorf("got status %d, want %d", w.Code, tc.want)
+			}
+		})
+	}
+
+	if !strings.Contains(logs.String(), `"reason":"source not allowed"`) {
+		t.Errorf("rejections were not logged with a reason:\n%s", logs.String())
+	}
+}
+
+func TestNilFilter(t *testing.T) {
+	var f *Filter
+	h := f.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("nil filter rejected a request with status %d", w.Code)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	for _, key := range []string{"INGRESS_ALLOW_CIDRS", "INGRESS_TRUSTED_PROXIES", "INGRESS_MAX_BODY_BYTES", "INGRESS_METHODS"} {
+		t.Setenv(key, "")
+	}
+	if f, err := FromEnv(); f != nil || err != nil {
+		t.Errorf("got filter %+v (err %v) without configuration", f, err)
+	}
+
+	t.Setenv("INGRESS_ALLOW_CIDRS", "203.0.113.0/24")
+	t.Setenv("INGRESS_METHODS", "get, post")
+	f, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Allow) != 1 || f.Allow[0] != netip.MustParsePrefix("203.0.113.0/24") {
+		t.Errorf("got allowed networks %v", f.Allow)
+	}
+	if strings.Join(f.Methods, ",") != "GET,POST" {
+		t.Errorf("got methods %v", f.Methods)
+	}
+
+	t.Setenv("INGRESS_ALLOW_CIDRS", "not-a-network")
+	if _, err := FromEnv(); err == nil {
+		t.Error("accepted an invalid network")
+	}
+}
+
+func mustParsePrefixes(t *testing.T, s string) []netip.Prefix {
+	t.Helper()
+	prefixes, err := ParsePrefixes(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prefixes
+}
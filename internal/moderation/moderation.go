@@ -0,0 +1,182 @@
+// Package moderation provides content checks that operators can configure to
+// keep groups within a workplace's content policy.
+package moderation
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+// DefaultTimeout bounds each request to a moderation service by default.
+const DefaultTimeout = 3 * time.Second
+
+// Denylist rejects text containing any of a set of words or patterns.
+type Denylist struct {
+	patterns []*regexp.Regexp
+}
+
+// ParseDenylist reads a denylist with one entry per line. Each entry is a word
+// or phrase, matched without regard to case as long as it isn't part of a
+// longer word, or a regular expression surrounded by slashes, like
+// "/b[a4]d/". Blank lines and lines starting with "#" are ignored.
+func ParseDenylist(r io.Reader) (*Denylist, error) {
+	var (
+		d       Denylist
+		scanner = bufio.NewScanner(r)
+		line    int
+	)
+	for scanner.Scan() {
+		line++
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		expr := `(?i)\b` + regexp.QuoteMeta(entry) + `\b`
+		if len(entry) > 2 && strings.HasPrefix(entry, "/") && strings.HasSuffix(entry, "/") {
+			expr = entry[1 : len(entry)-1]
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		d.patterns = append(d.patterns, re)
+	}
+	return &d, scanner.Err()
+}
+
+// Check implements randomizer.ContentCheck.
+func (d *Denylist) Check(_ context.Context, texts []string) error {
+	for _, text := range texts {
+		for _, re := range d.patterns {
+			if re.MatchString(text) {
+				return randomizer.ContentRejection{Reason: "it includes a word or phrase that isn't allowed"}
+			}
+		}
+	}
+	return nil
+}
+
+// Webhook asks an external moderation service to review text. It POSTs a JSON
+// object like {"texts": ["lunch", "pizza"]}, and expects a 200 OK response with
+// a JSON object like {"allowed": false, "reason": "..."}. Any other response
+// counts as a failure to review the text.
+type Webhook struct {
+	URL string
+	// Token, if set, is sent as a bearer token with each request.
+	Token string
+	// Timeout bounds each request. If zero, it defaults to DefaultTimeout.
+	Timeout time.Duration
+	// HTTPClient, if non-nil, replaces http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Check implements randomizer.ContentCheck.
+func (w Webhook) Check(ctx context.Context, texts []string) error {
+	timeout := w.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	body, err := json.Marshal(map[string][]string{"texts": texts})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+w.Token)
+	}
+
+	client := w.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling moderation service: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("moderation service responded with %s", resp.Status)
+	}
+
+	var verdict struct {
+		Allowed *bool  `json:"allowed"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&verdict); err != nil {
+		return fmt.Errorf("decoding moderation verdict: %w", err)
+	}
+	if verdict.Allowed == nil {
+		return errors.New("moderation verdict is missing the allowed field")
+	}
+	if !*verdict.Allowed {
+		return randomizer.ContentRejection{Reason: verdict.Reason}
+	}
+	return nil
+}
+
+// All combines content checks, rejecting text that any of them reject.
+func All(checks ...randomizer.ContentCheck) randomizer.ContentCheck {
+	return func(ctx context.Context, texts []string) error {
+		for _, check := range checks {
+			if err := check(ctx, texts); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// CheckFromEnv returns the content check configured by the following
+// environment variables, or nil if neither is set:
+//
+//   - CONTENT_DENYLIST_FILE: the path to a denylist (see [ParseDenylist])
+//   - CONTENT_MODERATION_URL: the URL of a moderation service (see [Webhook]),
+//     with an optional bearer token in CONTENT_MODERATION_TOKEN
+//
+// If both are set, text must pass both checks.
+func CheckFromEnv() (randomizer.ContentCheck, error) {
+	var checks []randomizer.ContentCheck
+	if path := os.Getenv("CONTENT_DENYLIST_FILE"); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("opening content denylist: %w", err)
+		}
+		defer f.Close()
+		denylist, err := ParseDenylist(f)
+		if err != nil {
+			return nil, fmt.Errorf("reading content denylist: %w", err)
+		}
+		checks = append(checks, denylist.Check)
+	}
+	if url := os.Getenv("CONTENT_MODERATION_URL"); url != "" {
+		checks = append(checks, Webhook{URL: url, Token: os.Getenv("CONTENT_MODERATION_TOKEN")}.Check)
+	}
+
+	switch len(checks) {
+	case 0:
+		return nil, nil
+	case 1:
+		return checks[0], nil
+	default:
+		return All(checks...), nil
+	}
+}
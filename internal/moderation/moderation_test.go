@@ -0,0 +1,78 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+func TestDenylist(t *testing.T) {
+	denylist, err := ParseDenylist(strings.NewReader("# Comments are ignored\n\ndarn\nheck no\n/b[a4]d+/\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		texts   []string
+		allowed bool
+	}{
+		{texts: []string{"pizza", "tacos"}, allowed: true},
+		{texts: []string{"pizza", "DARN it"}, allowed: false},
+		{texts: []string{"darned socks"}, allowed: true},
+		{texts: []string{"oh heck no"}, allowed: false},
+		{texts: []string{"b4ddd"}, allowed: false},
+	}
+	for _, tc := range testCases {
+		err := denylist.Check(context.Background(), tc.texts)
+		var rejection randomizer.ContentRejection
+		if rejected := errors.As(err, &rejection); rejected == tc.allowed {
+			t.Errorf("%q: got error %v, want allowed %v", tc.texts, err, tc.allowed)
+		}
+	}
+
+	if _, err := ParseDenylist(strings.NewReader("/(/")); err == nil {
+		t.Error("parsed an invalid pattern")
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var body struct{ Texts []string }
+		json.NewDecoder(r.Body).Decode(&body)
+		switch body.Texts[0] {
+		case "broken":
+			w.Write([]byte(`{}`))
+		case "rejected":
+			w.Write([]byte(`{"allowed": false, "reason": "not nice"}`))
+		default:
+			w.Write([]byte(`{"allowed": true}`))
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	hook := Webhook{URL: srv.URL, Token: "secret"}
+	if err := hook.Check(ctx, []string{"pizza"}); err != nil {
+		t.Errorf("allowed text got error %v", err)
+	}
+	var rejection randomizer.ContentRejection
+	if err := hook.Check(ctx, []string{"rejected"}); !errors.As(err, &rejection) || rejection.Reason != "not nice" {
+		t.Errorf("rejected text got error %v", err)
+	}
+	if err := hook.Check(ctx, []string{"broken"}); err == nil || errors.As(err, &rejection) {
+		t.Errorf("invalid verdict got error %v", err)
+	}
+	if err := (Webhook{URL: srv.URL}).Check(ctx, []string{"pizza"}); err == nil {
+		t.Error("unauthorized request succeeded")
+	}
+}
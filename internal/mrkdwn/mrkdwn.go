@@ -0,0 +1,76 @@
+// Package mrkdwn converts the randomizer's messages, which use Slack's mrkdwn
+// formatting, for readers and chat platforms that don't understand it.
+package mrkdwn
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// boldPattern matches the *bold* text that randomizer results use to
+	// highlight choices.
+	boldPattern = regexp.MustCompile(`\*([^*\n]+)\*`)
+	// linkPattern matches Slack's <url|text> links.
+	linkPattern = regexp.MustCompile(`<(https?://[^|>]+)\|([^>]+)>`)
+	// datePattern matches Slack's date formatting sequences, capturing their
+	// plain-text fallback.
+	datePattern = regexp.MustCompile(`<!date\^[^|>]*\|([^>]*)>`)
+	// emojiPattern matches emoji shortcodes like ":hourglass:". Shortcodes
+	// never start with a digit, which keeps times like "10:30:00" intact.
+	emojiPattern = regexp.MustCompile(` ?:[a-z_][a-z0-9_+'-]*: ?`)
+)
+
+// PlainText strips formatting from a message, so that it reads naturally both
+// on screen and through a screen reader, which would otherwise announce
+// formatting characters and emoji names literally. Mentions are kept, since
+// Slack renders them the same way in plain text.
+func PlainText(text string) string {
+	return convert(text, func(part string) string {
+		part = boldPattern.ReplaceAllString(part, "$1")
+		part = datePattern.ReplaceAllString(part, "$1")
+		return stripEmoji(part)
+	})
+}
+
+// Markdown converts a message to the Markdown of other chat platforms, where
+// *text* is italic rather than bold, and emoji shortcodes in messages from
+// apps appear literally. If rewrite isn't nil, it then makes any changes that
+// a particular platform needs to each part of the message.
+func Markdown(text string, rewrite func(part string) string) string {
+	return convert(text, func(part string) string {
+		part = boldPattern.ReplaceAllString(part, "**$1**")
+		part = linkPattern.ReplaceAllString(part, "[$2]($1)")
+		part = datePattern.ReplaceAllString(part, "$1")
+		part = stripEmoji(part)
+		if rewrite != nil {
+			part = rewrite(part)
+		}
+		return part
+	})
+}
+
+// convert applies fn to the parts of text outside of code blocks. Code blocks
+// (like exported tables) are kept exactly as they are.
+func convert(text string, fn func(string) string) string {
+	var b strings.Builder
+	for i, part := range strings.Split(text, "```") {
+		if i%2 == 1 {
+			b.WriteString("```" + part + "```")
+			continue
+		}
+		b.WriteString(fn(part))
+	}
+	return b.String()
+}
+
+// stripEmoji removes emoji shortcodes, along with one of the spaces around
+// them.
+func stripEmoji(text string) string {
+	return emojiPattern.ReplaceAllStringFunc(text, func(emoji string) string {
+		if strings.HasPrefix(emoji, " ") && strings.HasSuffix(emoji, " ") {
+			return " "
+		}
+		return ""
+	})
+}
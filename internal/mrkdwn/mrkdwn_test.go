@@ -0,0 +1,39 @@
+package mrkdwn
+
+import "testing"
+
+func TestPlainText(t *testing.T) {
+	testCases := []struct {
+		in, want string
+	}{
+		{"I randomized and got: *one*, *two*.", "I randomized and got: one, two."},
+		{"Done!\n:hourglass: *two* hasn't been picked.", "Done!\ntwo hasn't been picked."},
+		{"Lunch with <@U123> at 10:30:00", "Lunch with <@U123> at 10:30:00"},
+		{"Starts <!date^1792162800^{date_short_pretty} at {time}|Oct 17, 2026 at 9:00 AM>.", "Starts Oct 17, 2026 at 9:00 AM."},
+		{"Export:\n```\n| *a* |\n```", "Export:\n```\n| *a* |\n```"},
+	}
+	for _, tc := range testCases {
+		if got := PlainText(tc.in); got != tc.want {
+			t.Errorf("PlainText(%q)\ngot:  %q\nwant: %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"I randomized and got: *one*, *two*.", "I randomized and got: **one**, **two**."},
+		{":tada: Done! Meet at 10:30:00.", "Done! Meet at 10:30:00."},
+		{"See <https://example.com/help|the docs>.", "See [the docs](https://example.com/help)."},
+		{"Due <!date^1700000000^{date_short}|Nov 14, 2023>.", "Due Nov 14, 2023."},
+		{"Table:\n```\n| *a* | :x: |\n```", "Table:\n```\n| *a* | :x: |\n```"},
+	} {
+		if got := Markdown(tc.in, nil); got != tc.want {
+			t.Errorf("Markdown(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+
+	shout := func(part string) string { return part + "!" }
+	if got, want := Markdown("*a*```*b*```", shout), "**a**!```*b*```!"; got != want {
+		t.Errorf("Markdown() with a rewrite = %q, want %q", got, want)
+	}
+}
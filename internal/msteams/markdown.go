@@ -0,0 +1,21 @@
+package msteams
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/featherbread/randomizer/internal/mrkdwn"
+)
+
+// userPattern matches Slack's user mentions, which Teams can't resolve.
+var userPattern = regexp.MustCompile(`<@([^>]+)>`)
+
+// markdown converts a message from Slack's mrkdwn to Teams' Markdown. Teams
+// joins lines that are separated by a single line break, so each line becomes
+// its own paragraph.
+func markdown(text string) string {
+	return mrkdwn.Markdown(text, func(part string) string {
+		part = userPattern.ReplaceAllString(part, "$1")
+		return strings.ReplaceAll(part, "\n", "\n\n")
+	})
+}
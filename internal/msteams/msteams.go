@@ -0,0 +1,322 @@
+// Package msteams supports invoking the randomizer through a Microsoft Teams
+// outgoing webhook.
+package msteams
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"html"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+
+	"github.com/featherbread/randomizer/internal/clock"
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+var tracer = otel.Tracer("github.com/featherbread/randomizer/internal/msteams")
+
+// DefaultTimeout bounds the time spent on a command. Teams gives outgoing
+// webhooks 5 seconds to respond, and has no way to follow up later.
+const DefaultTimeout = 4 * time.Second
+
+// maxRequestBytes bounds the size of an activity from Teams.
+const maxRequestBytes = 1 << 20
+
+// Message card formatting, from the Office 365 connector card format that
+// outgoing webhooks can respond with.
+const (
+	cardContentType = "application/vnd.microsoft.teams.card.o365connector"
+	cardColor       = "5B5FC7"
+	errorColor      = "C4314B"
+	maxTextLength   = 20000
+)
+
+// Partitions for Teams channels and teams are prefixed so that they can't
+// collide with Slack's or Discord's.
+const (
+	partitionPrefix = "msteams-"
+	teamPrefix      = "msteams-team-"
+)
+
+// App serves the randomizer through a Teams outgoing webhook, using the same
+// stores as the Slack App.
+//
+// Users invoke the webhook by mentioning it, like "@Randomizer /save lunch
+// pizza tacos", and the response is posted to the channel as a reply. Each
+// Teams channel is its own partition, named with a "msteams-" prefix so that it
+// can't collide with a Slack channel, and settings shared by a whole workspace
+// in Slack, like themes, are shared by a team.
+type App struct {
+	// SecurityToken verifies the HMAC signature that Teams adds to each
+	// request. It is the base64-decoded security token shown when the outgoing
+	// webhook is created.
+	SecurityToken []byte
+	// StoreFactory provides a Store for each partition.
+	StoreFactory func(partition string) randomizer.Store
+	// Defaults configures what the randomizer does with commands that don't
+	// name an operation it knows.
+	Defaults randomizer.Defaults
+	// ContentCheck, if non-nil, reviews group names, options, and tags before
+	// they're saved.
+	ContentCheck randomizer.ContentCheck
+	// Deployment describes the deployment for the /version operation.
+	Deployment randomizer.Deployment
+	// Timeout overrides DefaultTimeout.
+	Timeout time.Duration
+	// Clock, if non-nil, replaces the system clock for the randomizer's
+	// history and schedules.
+	Clock clock.Clock
+	// Logger, if non-nil, logs errors encountered during request handling.
+	Logger *slog.Logger
+}
+
+// SecurityTokenFromEnv returns the outgoing webhook's security token from the
+// base64-encoded TEAMS_SECURITY_TOKEN environment variable, or nil if it is
+// unset.
+func SecurityTokenFromEnv() ([]byte, error) {
+	encoded, ok := os.LookupEnv("TEAMS_SECURITY_TOKEN")
+	if !ok {
+		return nil, nil
+	}
+	token, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(token) == 0 {
+		return nil, errors.New("TEAMS_SECURITY_TOKEN must be the base64-encoded security token of an outgoing webhook")
+	}
+	return token, nil
+}
+
+// Partition returns the partition that holds a Teams channel's groups.
+func Partition(channelID string) string {
+	return partitionPrefix + channelID
+}
+
+// activity is the subset of a Bot Framework message activity that the App
+// uses.
+type activity struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+	From struct {
+		ID          string `json:"id"`
+		AADObjectID string `json:"aadObjectId"`
+	} `json:"from"`
+	Conversation struct {
+		ID string `json:"id"`
+	} `json:"conversation"`
+	ChannelData struct {
+		Team struct {
+			ID string `json:"id"`
+		} `json:"team"`
+		Channel struct {
+			ID string `json:"id"`
+		} `json:"channel"`
+	} `json:"channelData"`
+}
+
+// channelID returns the ID of the channel the webhook was invoked in. Replies
+// in a thread carry the ID of their root message in the conversation ID, which
+// shouldn't give each thread its own groups.
+func (a activity) channelID() string {
+	if id := a.ChannelData.Channel.ID; id != "" {
+		return id
+	}
+	id, _, _ := strings.Cut(a.Conversation.ID, ";")
+	return id
+}
+
+// userID returns the ID of the user who invoked the webhook, preferring their
+// Microsoft Entra ID, which is stable across teams.
+func (a activity) userID() string {
+	if a.From.AADObjectID != "" {
+		return a.From.AADObjectID
+	}
+	return a.From.ID
+}
+
+var (
+	mentionPattern = regexp.MustCompile(`(?s)<at>(.*?)</at>`)
+	tagPattern     = regexp.MustCompile(`<[^>]*>`)
+)
+
+// command returns the name that invoked the webhook and the randomizer's
+// arguments. Teams sends the message as HTML, starting with a mention of the
+// webhook.
+func (a activity) command() (name string, args []string) {
+	text := a.Text
+	if m := mentionPattern.FindStringSubmatchIndex(text); m != nil {
+		name = "@" + html.UnescapeString(text[m[2]:m[3]])
+		text = text[:m[0]] + text[m[1]:]
+	}
+	text = tagPattern.ReplaceAllString(text, " ")
+	text = html.UnescapeString(text)
+	if name == "" {
+		name = "@Randomizer"
+	}
+	return name, strings.Fields(text)
+}
+
+type message struct {
+	Type        string       `json:"type"`
+	Text        string       `json:"text,omitempty"`
+	Attachments []attachment `json:"attachments,omitempty"`
+}
+
+type attachment struct {
+	ContentType string      `json:"contentType"`
+	Content     messageCard `json:"content"`
+}
+
+type messageCard struct {
+	Type       string `json:"@type"`
+	Context    string `json:"@context"`
+	Summary    string `json:"summary"`
+	ThemeColor string `json:"themeColor"`
+	Text       string `json:"text"`
+}
+
+// ServeHTTP serves POST requests from a Teams outgoing webhook.
+func (a App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Add("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	if err != nil {
+		a.logErr(err, "Failed to read request body")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !a.validSignature(r.Header, body) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var in activity
+	if err := json.Unmarshal(body, &in); err != nil {
+		a.logErr(err, "Failed to decode activity")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if in.Type != "message" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	timeout := a.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(a.runCommand(ctx, in)); err != nil {
+		a.logErr(err, "Failed to write response")
+	}
+}
+
+// validSignature checks the "HMAC <signature>" Authorization header, which
+// holds the base64-encoded HMAC-SHA256 of the body keyed by the security
+// token.
+func (a App) validSignature(header http.Header, body []byte) bool {
+	encoded, ok := strings.CutPrefix(header.Get("Authorization"), "HMAC ")
+	if !ok || len(a.SecurityToken) == 0 {
+		return false
+	}
+	signature, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, a.SecurityToken)
+	mac.Write(body)
+	return hmac.Equal(signature, mac.Sum(nil))
+}
+
+// runCommand runs the randomizer for an activity, and returns the message
+// that should answer it.
+func (a App) runCommand(ctx context.Context, in activity) message {
+	ctx, span := tracer.Start(ctx, "msteams.App.runCommand")
+	defer span.End()
+
+	opts := []randomizer.AppOption{
+		randomizer.WithRenderer(markdown),
+		randomizer.WithUser(in.userID()),
+		randomizer.WithDefaults(a.Defaults),
+		randomizer.WithDeployment(a.Deployment),
+	}
+	if team := in.ChannelData.Team.ID; team != "" {
+		opts = append(opts, randomizer.WithWorkspaceStore(a.StoreFactory(teamPrefix+team)))
+	}
+	if a.ContentCheck != nil {
+		opts = append(opts, randomizer.WithContentCheck(a.ContentCheck))
+	}
+	if a.Clock != nil {
+		opts = append(opts, randomizer.WithClock(a.Clock))
+	}
+	if a.Logger != nil {
+		opts = append(opts, randomizer.WithLogger(a.Logger))
+	}
+
+	name, args := in.command()
+	app := randomizer.NewApp(name, a.StoreFactory(Partition(in.channelID())), opts...)
+	result, err := app.Main(ctx, args)
+	if err != nil {
+		a.logErr(err, "Failed to run randomizer")
+		return card(err.(randomizer.Error).HelpText(), errorColor)
+	}
+
+	switch {
+	case result.DryRun():
+	case result.Type() == randomizer.Selection, result.Type() == randomizer.Assignment, result.Type() == randomizer.RevealedPick, result.Type() == randomizer.ProposedPick, result.Type() == randomizer.RatifiedPick, result.Type() == randomizer.Teams, result.Type() == randomizer.Sampled, result.Type() == randomizer.RolledDice, result.Type() == randomizer.PickedNumbers, result.Changed():
+		return card(result.Message(), cardColor)
+	}
+	// Outgoing webhooks can't respond privately, so other results are plain
+	// messages to set them apart from selections and changes.
+	return message{Type: "message", Text: truncate(result.Message(), maxTextLength)}
+}
+
+// card returns a message that holds text in a message card.
+func card(text, color string) message {
+	text = truncate(text, maxTextLength)
+	summary, _, _ := strings.Cut(text, "\n")
+	return message{
+		Type: "message",
+		Attachments: []attachment{{
+			ContentType: cardContentType,
+			Content: messageCard{
+				Type:       "MessageCard",
+				Context:    "https://schema.org/extensions",
+				Summary:    truncate(summary, 80),
+				ThemeColor: color,
+				Text:       text,
+			},
+		}},
+	}
+}
+
+func (a App) logErr(err error, msg string) {
+	if a.Logger != nil {
+		a.Logger.Error(msg, "err", err)
+	}
+}
+
+// truncate shortens s to at most n characters, to fit Teams' limits.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,149 @@
+package msteams
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/clock/clocktest"
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+)
+
+var testNow = time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+func TestOutgoingWebhook(t *testing.T) {
+	token := []byte("security token")
+	var partitions []string
+	store := &rndtest.SyncStore{Store: make(rndtest.Store)}
+	app := App{
+		SecurityToken: token,
+		StoreFactory: func(partition string) randomizer.Store {
+			partitions = append(partitions, partition)
+			return store
+		},
+		Clock: clocktest.New(testNow),
+	}
+
+	if resp := post(app, []byte("wrong token"), activityJSON("/list")); resp.Code != http.StatusUnauthorized {
+		t.Errorf("wrong status for a bad signature: got %d, want %d", resp.Code, http.StatusUnauthorized)
+	}
+	if resp := post(app, nil, activityJSON("/list")); resp.Code != http.StatusUnauthorized {
+		t.Errorf("wrong status for a missing signature: got %d, want %d", resp.Code, http.StatusUnauthorized)
+	}
+
+	for _, tc := range []struct {
+		text  string
+		card  bool
+		color string
+		want  string
+	}{
+		{text: "/save lunch pizza tacos", card: true, color: cardColor, want: `The "lunch" group was saved`},
+		{text: "/list", want: "lunch"},
+		{text: "lunch", card: true, color: cardColor, want: "I randomized and got: **"},
+		{text: "/show", card: true, color: errorColor, want: "Whoops"},
+		{text: "/help", want: "@Randomizer /save"},
+	} {
+		resp := post(app, token, activityJSON(tc.text))
+		var got message
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatalf("%q: %v", tc.text, err)
+		}
+		if got.Type != "message" {
+			t.Fatalf("%q: got response %+v", tc.text, got)
+		}
+		if card := len(got.Attachments) > 0; card != tc.card {
+			t.Errorf("%q: got card %v, want %v", tc.text, card, tc.card)
+		}
+		text := got.Text
+		if len(got.Attachments) > 0 {
+			card := got.Attachments[0]
+			if card.ContentType != cardContentType || card.Content.Type != "MessageCard" || card.Content.ThemeColor != tc.color {
+				t.Errorf("%q: got card %+v", tc.text, card)
+			}
+			text = card.Content.Text
+		}
+		if !strings.Contains(text, tc.want) {
+			t.Errorf("%q: got message %q, want %q", tc.text, text, tc.want)
+		}
+	}
+
+	if groups, _ := store.List(context.Background()); len(groups) == 0 {
+		t.Error("saved group is missing from the store")
+	}
+	for _, partition := range partitions {
+		if partition != "msteams-19:general@thread.tacv2" && partition != "msteams-team-19:team@thread.tacv2" {
+			t.Errorf("used unexpected partition %q", partition)
+		}
+	}
+}
+
+func TestCommand(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		name string
+		args []string
+	}{
+		{
+			text: "<at>Randomizer</at> /save lunch pizza tacos\n",
+			name: "@Randomizer",
+			args: []string{"/save", "lunch", "pizza", "tacos"},
+		},
+		{
+			text: "<div><at>Pick &amp; Choose</at>&nbsp;Tom&nbsp;Jerry<br></div>",
+			name: "@Pick & Choose",
+			args: []string{"Tom", "Jerry"},
+		},
+		{
+			text: "fish &lt;3 chips",
+			name: "@Randomizer",
+			args: []string{"fish", "<3", "chips"},
+		},
+	} {
+		name, args := activity{Text: tc.text}.command()
+		if name != tc.name || strings.Join(args, "|") != strings.Join(tc.args, "|") {
+			t.Errorf("%q: got %q %q, want %q %q", tc.text, name, args, tc.name, tc.args)
+		}
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	got := markdown(":game_die: I randomized and got: *tacos*.\nAsk <@U123> or see <https://example.com|the docs> at 10:30:00.\n```*kept*\nas is```")
+	want := "I randomized and got: **tacos**.\n\nAsk U123 or see [the docs](https://example.com) at 10:30:00.\n\n```*kept*\nas is```"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func activityJSON(text string) string {
+	body, _ := json.Marshal(map[string]any{
+		"type":         "message",
+		"text":         "<at>Randomizer</at> " + text,
+		"from":         map[string]any{"id": "29:user", "aadObjectId": "00000000-0000-0000-0000-000000000001"},
+		"conversation": map[string]any{"id": "19:general@thread.tacv2;messageid=1760702400000"},
+		"channelData": map[string]any{
+			"team":    map[string]any{"id": "19:team@thread.tacv2"},
+			"channel": map[string]any{"id": "19:general@thread.tacv2"},
+		},
+	})
+	return string(body)
+}
+
+func post(app App, token []byte, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/msteams", strings.NewReader(body))
+	if token != nil {
+		mac := hmac.New(sha256.New, token)
+		mac.Write([]byte(body))
+		req.Header.Set("Authorization", "HMAC "+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	}
+	resp := httptest.NewRecorder()
+	app.ServeHTTP(resp, req)
+	return resp
+}
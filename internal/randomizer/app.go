@@ -2,10 +2,14 @@ package randomizer
 
 import (
 	"context"
-	"math/rand/v2"
+	"log/slog"
+	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/featherbread/randomizer/internal/clock"
 )
 
 var tracer = otel.Tracer("github.com/featherbread/randomizer/internal/randomizer")
@@ -16,8 +20,9 @@ type Store interface {
 	// saved, it returns an empty list with a nil error.
 	List(ctx context.Context) (groups []string, err error)
 
-	// Get returns the list of options in the named group. If the group does not
-	// exist, it returns an empty list with a nil error.
+	// Get returns the list of options in the named group, in the order they were
+	// saved. If the group does not exist, it returns an empty list with a nil
+	// error.
 	Get(ctx context.Context, group string) (options []string, err error)
 
 	// Put saves the provided options as a named group, overwriting any previous
@@ -30,24 +35,105 @@ type Store interface {
 }
 
 // App represents a randomizer instance that can accept commands.
+//
+// An App is never modified after [NewApp] returns it, so a single App may
+// serve concurrent requests as long as its Store and options support that.
 type App struct {
-	name    string
-	store   Store
-	shuffle func([]string) // Overridden in tests for predictable behavior
+	name           string
+	store          Store
+	workspace      Store
+	randomness     Randomness
+	order          func([]string)
+	now            func() time.Time
+	logger         *slog.Logger
+	render         func(string) string
+	onboarding     bool
+	noHistory      bool
+	dryRun         bool
+	resolveName    NameResolver
+	resolveEmail   EmailResolver
+	user           string
+	defaults       Defaults
+	checkContent   ContentCheck
+	deployment     Deployment
+	sendWebhook    WebhookSender
+	schedules      Store
+	scheduleTarget string
+	federation     Store
+	team           string
+	audit          *Audit
+	operator       string
+
+	startDeferred func(func())
 }
 
-func NewApp(name string, store Store) App {
-	return App{
-		name:    name,
-		store:   store,
-		shuffle: shuffle,
-	}
+// AppOption configures optional behavior for an App.
+type AppOption func(*App)
+
+// WithOnboarding enables a one-time welcome message on the first request that
+// an App handles for a given store.
+func WithOnboarding() AppOption {
+	return func(a *App) { a.onboarding = true }
+}
+
+// WithLogger sets a logger for problems that don't fail the request, like
+// being unable to record a selection in a group's history.
+func WithLogger(logger *slog.Logger) AppOption {
+	return func(a *App) { a.logger = logger }
+}
+
+// WithClock replaces the source of the current time, for example to test
+// behavior that depends on the age of a group's history.
+func WithClock(c clock.Clock) AppOption {
+	return func(a *App) { a.now = c.Now }
+}
+
+// WithRandomizer replaces the function that puts options in a random order,
+// for example to make selections predictable in tests, while other random
+// choices still draw from the App's [Randomness]. It takes precedence over a
+// channel's /seed setting.
+func WithRandomizer(order func([]string)) AppOption {
+	return func(a *App) { a.order = order }
+}
+
+// WithHistory controls whether the App records the winners of selections from
+// groups, which /stats and /export report on, along with the details of each
+// selection that /result looks up by ID. History is enabled by default.
+func WithHistory(enabled bool) AppOption {
+	return func(a *App) { a.noHistory = !enabled }
+}
+
+// WithRenderer transforms the message of every result and the help text of
+// every error before the App returns them, for frontends that can't display
+// Slack's formatting as is.
+func WithRenderer(render func(string) string) AppOption {
+	return func(a *App) { a.render = render }
+}
+
+// WithWorkspaceStore sets a store for settings shared by every channel in a
+// workspace, like the theme that /theme configures.
+func WithWorkspaceStore(store Store) AppOption {
+	return func(a *App) { a.workspace = store }
+}
+
+// WithDryRun makes every request a dry run, as if it had the --dry-run flag,
+// for frontends that only preview what a command would do.
+func WithDryRun() AppOption {
+	return func(a *App) { a.dryRun = true }
 }
 
-func shuffle(options []string) {
-	rand.Shuffle(len(options), func(i, j int) {
-		options[i], options[j] = options[j], options[i]
-	})
+func NewApp(name string, store Store, opts ...AppOption) App {
+	app := App{
+		name:       name,
+		store:      store,
+		randomness: rng,
+		now:        time.Now,
+		logger:     slog.New(slog.DiscardHandler),
+	}
+	for _, opt := range opts {
+		opt(&app)
+	}
+	return app
 }
 
 // Main is the entrypoint to the randomizer.
@@ -58,24 +144,86 @@ func (a App) Main(ctx context.Context, args []string) (Result, error) {
 	ctx, span := tracer.Start(ctx, "randomizer.Main")
 	defer span.End()
 
+	result, err := a.main(ctx, args)
+	if err == nil && a.operator != "" {
+		result.message = a.operatorBanner() + result.message
+	}
+	if a.render != nil {
+		result.message = a.render(result.message)
+		if rerr, ok := err.(Error); ok {
+			rerr.helpText = a.render(rerr.HelpText())
+			err = rerr
+		}
+	}
+	return result, err
+}
+
+func (a App) main(ctx context.Context, args []string) (Result, error) {
+	span := trace.SpanFromContext(ctx)
+
+	a, prefetchGroups := a.withPrefetch(ctx)
 	request, err := a.newRequest(ctx, args)
 	if err != nil {
 		span.RecordError(err)
 		return Result{}, err
 	}
+	if request.Command.exclusions {
+		prefetchGroups(selectedGroups(request.Args))
+	}
 
-	span.SetAttributes(attribute.String("randomizer.operation", request.Operation.String()))
-	handler := appHandlers[request.Operation]
-	return handler(a, request)
-}
-
-type appHandler func(App, request) (Result, error)
+	span.SetAttributes(
+		attribute.String("randomizer.operation", request.Command.name),
+		attribute.String("randomizer.permission", request.Command.permission.String()),
+		attribute.Bool("randomizer.dry_run", request.DryRun()))
+	handler := request.Command.handler
+	a, randomness := a.withChannelRandomness(ctx)
+	if !request.DryRun() {
+		result, err := handler(a, request)
+		if err == nil {
+			randomness.finish(&result)
+		}
+		if err == nil && a.onboarding {
+			welcome, onboardErr := a.onboard(ctx)
+			if onboardErr != nil {
+				span.RecordError(onboardErr)
+				welcome = degradedBanner
+			}
+			result.onboarded = onboardErr == nil
+			result.welcomed = onboardErr == nil && welcome != ""
+			result.message = welcome + result.message
+		}
+		if err == nil {
+			a.deliverWebhook(ctx, result)
+		}
+		result.changed = err == nil && request.Command.permission != permRead
+		if result.changed || (err == nil && a.operator != "") {
+			a.recordAudit(ctx, request, result)
+		}
+		return result, err
+	}
 
-var appHandlers = map[operation]appHandler{
-	showHelp:      App.showHelp,
-	makeSelection: App.makeSelection,
-	listGroups:    App.listGroups,
-	showGroup:     App.showGroup,
-	saveGroup:     App.saveGroup,
-	deleteGroup:   App.deleteGroup,
+	var changes []storeChange
+	a.store = dryRunStore{Store: a.store, changes: &changes}
+	if a.workspace != nil {
+		a.workspace = dryRunStore{Store: a.workspace, changes: &changes}
+	}
+	if a.schedules != nil {
+		a.schedules = dryRunStore{Store: a.schedules, changes: &changes}
+	}
+	if a.federation != nil {
+		a.federation = dryRunStore{Store: a.federation, changes: &changes}
+	}
+	if request.Command.permission == permRead {
+		// Read-only commands have nothing to preview, so a dry run shows their
+		// usual result, without the bookkeeping that would record it.
+		result, err := handler(a, request)
+		result.dryRun = true
+		return result, err
+	}
+	if _, err := handler(a, request); err != nil {
+		return Result{}, err
+	}
+	result := previewChanges(changes)
+	result.dryRun = true
+	return result, nil
 }
@@ -2,11 +2,21 @@ package randomizer
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"math/rand/v2"
 	"reflect"
+	"regexp"
 	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/featherbread/randomizer/internal/clock/clocktest"
 	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
 )
 
@@ -71,6 +81,317 @@ var testCases = []struct {
 		check:       isError(`had trouble getting the "test" group`),
 	},
 
+	{
+		description: "randomizing an explicit group reference",
+		store:       rndtest.Store{"test": {"three", "two", "one"}},
+		args:        []string{"+test"},
+		check:       isResult(Selection, "*one*", "*three*", "*two*"),
+	},
+
+	{
+		description: "randomizing a group with tagged options",
+		store:       rndtest.Store{"test": {"one #odd", "two #even"}},
+		args:        []string{"test"},
+		check:       isResult(Selection, "*one*, *two*."),
+	},
+
+	{
+		description: "randomizing with a calendar event",
+		args:        []string{"one", "two", "--event=2026-10-23T15:00"},
+		check:       isResult(Selection, "*one*, *two*", "calendar.google.com", "<!date^1792767600^{date_short_pretty} at {time}|Fri, Oct 23 at 15:00 UTC>"),
+	},
+
+	{
+		description: "randomizing with an all-day calendar event",
+		args:        []string{"one", "two", "--event", "2026-10-23", "--event-duration=1h"},
+		check:       isResult(Selection, "Add *one* to your calendar> for <!date^1792756800^{date_short_pretty}|Fri, Oct 23>"),
+	},
+
+	{
+		description: "randomizing with an invalid calendar event time",
+		args:        []string{"one", "two", "--event=friday"},
+		check:       isError("need the event time"),
+	},
+
+	// Weighting options
+
+	{
+		description: "randomizing weighted inline options",
+		args:        []string{"b*3", "a"},
+		check:       isResult(Selection, "got: *b*, *a*."),
+	},
+
+	{
+		description: "randomizing a group with weighted options",
+		store:       rndtest.Store{"test": {"b #weight=2", "a"}},
+		args:        []string{"test"},
+		check:       isResult(Selection, "got: *b*, *a*."),
+	},
+
+	{
+		description: "saving a group with weighted options",
+		store:       rndtest.Store{},
+		args:        []string{"/save", "test", "pizza*3", "sushi"},
+		check:       isResult(SavedGroup, "• pizza (weight=3)", "• sushi"),
+		expectedStore: rndtest.Store{
+			"test":             {"pizza #weight=3", "sushi"},
+			"/provenance/test": {"2026-10-17T12:00:00Z|U123|/save|pizza", "2026-10-17T12:00:00Z|U123|/save|sushi"},
+		},
+	},
+
+	{
+		description: "randomizing with an invalid weight",
+		args:        []string{"pizza*0", "sushi"},
+		check:       isError("whole numbers from 1 to 100"),
+	},
+
+	// Merging groups
+
+	{
+		description: "merging groups with weights",
+		store: rndtest.Store{
+			"seniors": {"alice", "bob #backend"},
+			"juniors": {"carol #weight=2", "Bob"},
+		},
+		args:  []string{"/merge", "everyone", "seniors", "+juniors", "--weights", "2,1"},
+		check: isResult(MergedGroups, "from *seniors*, *juniors*", "• alice (weight=2)", "• bob (backend, weight=3)", "• carol (weight=2)"),
+		expectedStore: rndtest.Store{
+			"seniors":  {"alice", "bob #backend"},
+			"juniors":  {"carol #weight=2", "Bob"},
+			"everyone": {"alice #weight=2", "bob #backend #weight=3", "carol #weight=2"},
+			"/provenance/everyone": {
+				"2026-10-17T12:00:00Z|U123|/merge|alice",
+				"2026-10-17T12:00:00Z|U123|/merge|bob",
+				"2026-10-17T12:00:00Z|U123|/merge|carol",
+			},
+		},
+	},
+
+	{
+		description: "merging groups keeping the highest weight",
+		store:       rndtest.Store{"a": {"x #weight=3", "y"}, "b": {"x", "z"}},
+		args:        []string{"/merge", "c", "a", "b", "--dedupe=max", "--weights=1,2"},
+		check:       isResult(MergedGroups, "• x (weight=3)", "• y\n", "• z (weight=2)"),
+	},
+
+	{
+		description: "merging groups keeping the first of each option",
+		store:       rndtest.Store{"a": {"x", "y"}, "b": {"x*5", "y #weight=5"}},
+		args:        []string{"/merge", "c", "a", "b", "--dedupe", "first"},
+		check:       isResult(MergedGroups, "• x\n• y"),
+	},
+
+	{
+		description: "merging groups with the wrong number of weights",
+		store:       rndtest.Store{"a": {"x", "y"}, "b": {"z"}},
+		args:        []string{"/merge", "c", "a", "b", "--weights", "2"},
+		check:       isError("one weight for each group to merge, like --weights 1,1"),
+	},
+
+	{
+		description: "merging groups with an invalid dedupe rule",
+		store:       rndtest.Store{"a": {"x", "y"}},
+		args:        []string{"/merge", "c", "a", "--dedupe", "min"},
+		check:       isError(`"sum", "max", or "first"`),
+	},
+
+	{
+		description: "merging a group that doesn't exist",
+		store:       rndtest.Store{"a": {"x", "y"}},
+		args:        []string{"/merge", "c", "a", "b"},
+		check:       isError(`couldn't find the "b" group`),
+	},
+
+	{
+		description: "merging without any groups",
+		args:        []string{"/merge", "c"},
+		check:       isError("need the groups to merge"),
+	},
+
+	// Picking several winners
+
+	{
+		description: "picking winners from inline options",
+		args:        []string{"/pick", "2", "three", "two", "one"},
+		check:       isResult(Selection, "got: *one*, *three*."),
+	},
+
+	{
+		description: "picking winners from a group with a short flag",
+		store:       rndtest.Store{"test": {"three", "two", "one"}},
+		args:        []string{"-n", "2", "test"},
+		check:       isResult(Selection, "got: *one*, *three*."),
+	},
+
+	{
+		description: "picking more winners than there are options",
+		args:        []string{"one", "two", "three", "--pick=4"},
+		check:       isError("can't pick 4 winners from only 3 options"),
+	},
+
+	{
+		description: "picking an invalid number of winners",
+		args:        []string{"/pick", "some", "one", "two"},
+		check:       isError("number of winners to pick"),
+	},
+
+	{
+		description: "exporting a group's selection history",
+		store: rndtest.Store{
+			"test":          {"one", "two"},
+			"/history/test": {"2026-10-09T15:00:00.000000000Z|two", "2026-10-02T15:00:00.000000000Z|o|ne"},
+		},
+		args:  []string{"/export", "test"},
+		check: isResult(ExportedHistory, "| Date (UTC) | Selected |", "| 2026-10-02 15:00 | o\\|ne |", "| 2026-10-09 15:00 | two |"),
+	},
+
+	{
+		description: "showing a group's stats",
+		store: rndtest.Store{
+			"test":          {"one", "two", "three"},
+			"/history/test": {"2026-10-02T15:00:00.000000000Z|two", "2026-10-09T15:00:00.000000000Z|two", "2026-10-16T15:00:00.000000000Z|one"},
+			"/feedback/test": {
+				"2026-10-02T15:00:00.000000000Z|U1|+",
+				"2026-10-02T15:00:00.000000000Z|U2|-",
+				"2026-10-09T15:00:00.000000000Z|U1|+",
+			},
+		},
+		args:  []string{"/stats", "test"},
+		check: isResult(ShowedStats, "two: picked 2 times, 2 :+1: / 1 :-1:", "one: picked 1 time", "three: picked 0 times"),
+	},
+
+	{
+		description: "exporting a group without history",
+		store:       rndtest.Store{"test": {"one", "two"}},
+		args:        []string{"/export", "test"},
+		check:       isError("haven't made any selections"),
+	},
+
+	{
+		description: "saving a group of mentions",
+		store:       rndtest.Store{},
+		args:        []string{"/save", "team", "<@U111|alice>", "<@U222|bob>#oncall"},
+		check:       isResult(SavedGroup),
+		expectedStore: rndtest.Store{
+			"team":             {"<@U111>", "<@U222> #oncall"},
+			"/provenance/team": {"2026-10-17T12:00:00Z|U123|/save|<@U111>", "2026-10-17T12:00:00Z|U123|/save|<@U222>"},
+		},
+	},
+
+	{
+		description: "exporting history with mentions",
+		store: rndtest.Store{
+			"team":          {"<@U111>", "<@U222>"},
+			"/history/team": {"2026-10-16T15:00:00.000000000Z|<@U111>"},
+		},
+		args:  []string{"/export", "team"},
+		check: isResult(ExportedHistory, "| @U111 |"),
+	},
+
+	{
+		description: "exporting emails without an email resolver",
+		store: rndtest.Store{
+			"team":          {"<@U111>", "<@U222>"},
+			"/history/team": {"2026-10-16T15:00:00.000000000Z|<@U111>"},
+		},
+		args:  []string{"/export", "--emails", "team"},
+		check: isError("isn't turned on"),
+	},
+
+	// Filtering groups
+
+	{
+		description: "filtering a group by tag",
+		store:       rndtest.Store{"team": {"alice #backend", "bob #frontend", "carol #backend #ooo"}},
+		args:        []string{"+team", "where", "tag=backend", "and", "not", "ooo"},
+		check:       isResult(Selection, "got: *alice*."),
+	},
+
+	{
+		description: "filtering a group by attribute",
+		store:       rndtest.Store{"lunch": {"sushi #cuisine=japanese", "ramen #cuisine=Japanese", "tacos #cuisine=mexican"}},
+		args:        []string{"lunch", "WHERE", "cuisine=japanese"},
+		check:       isResult(Selection, "got: *ramen*, *sushi*."),
+	},
+
+	{
+		description: "filtering a group with grouped expressions",
+		store:       rndtest.Store{"team": {"alice #backend", "bob #frontend #ooo", "carol #design"}},
+		args:        []string{"+team", "where", "(backend", "or", "frontend)", "and", "not", "ooo"},
+		check:       isResult(Selection, "got: *alice*."),
+	},
+
+	{
+		description: "filtering a group with a negated attribute",
+		store:       rndtest.Store{"team": {"alice #role=lead", "bob #role=member", "carol"}},
+		args:        []string{"+team", "where", "role!=lead"},
+		check:       isResult(Selection, "got: *bob*, *carol*."),
+	},
+
+	{
+		description: "filtering a group with no matching options",
+		store:       rndtest.Store{"team": {"alice #backend", "bob #frontend"}},
+		args:        []string{"+team", "where", "design"},
+		check:       isError("none of the options in that group match"),
+	},
+
+	{
+		description: "filtering a group with an invalid expression",
+		store:       rndtest.Store{"team": {"alice #backend", "bob #frontend"}},
+		args:        []string{"+team", "where", "(backend", "and"},
+		check:       isError("couldn't understand that filter"),
+	},
+
+	{
+		description: "selecting from a group by tag",
+		store:       rndtest.Store{"lunch": {"sushi #cheap #japanese", "steak #pricey", "tacos #cheap"}},
+		args:        []string{"+lunch", "#cheap"},
+		check:       isResult(Selection, "got: *sushi*, *tacos*."),
+	},
+
+	{
+		description: "selecting from a group by multiple tags and a filter",
+		store:       rndtest.Store{"lunch": {"sushi #cheap #japanese", "ramen #cheap #japanese #far", "tacos #cheap"}},
+		args:        []string{"+lunch", "#cheap", "#japanese", "where", "not", "far"},
+		check:       isResult(Selection, "got: *sushi*."),
+	},
+
+	{
+		description: "randomizing inline options that look like tags",
+		args:        []string{"#general", "#random"},
+		check:       isResult(Selection, "*#general*", "*#random*"),
+	},
+
+	{
+		description: "randomizing a group saved before tags existed",
+		store:       rndtest.Store{"keys": {"F#minor", "C#"}},
+		args:        []string{"keys"},
+		check:       isResult(Selection, "*C#*", "*F#minor*"),
+	},
+
+	{
+		description: "randomizing inline options with an escaped #",
+		args:        []string{`F\#minor`, "C#"},
+		check:       isResult(Selection, "*C#*", "*F#minor*"),
+	},
+
+	{
+		description: "saving a group with an escaped #",
+		store:       rndtest.Store{},
+		args:        []string{"/save", "keys", `F\#minor`, "C#", `D\#min#sad`},
+		check:       isResult(SavedGroup, "• F#minor", "• C#", "• D#min (sad)"),
+		expectedStore: rndtest.Store{
+			"keys":             {"F#minor", "C#", "D#min #sad"},
+			"/provenance/keys": {"2026-10-17T12:00:00Z|U123|/save|C#", "2026-10-17T12:00:00Z|U123|/save|D#min", "2026-10-17T12:00:00Z|U123|/save|F#minor"},
+		},
+	},
+
+	{
+		description: "randomizing inline options including the word where",
+		args:        []string{"here", "there", "where"},
+		check:       isResult(Selection, "*here*", "*there*", "*where*"),
+	},
+
 	// Group CRUD operations
 
 	{
@@ -80,6 +401,28 @@ var testCases = []struct {
 		check:       isResult(ListedGroups, "• first", "• second"),
 	},
 
+	{
+		description: "listing groups hides internal records",
+		store:       rndtest.Store{"first": {"one"}, onboardingRecord: {"done"}},
+		args:        []string{"/list"},
+		check:       isResult(ListedGroups, "available in this channel:\n• first"),
+	},
+
+	{
+		description: "showing an internal record",
+		store:       rndtest.Store{onboardingRecord: {"done"}},
+		args:        []string{"/show", onboardingRecord},
+		check:       isError("can't find that group"),
+	},
+
+	{
+		description:   "deleting an internal record",
+		store:         rndtest.Store{onboardingRecord: {"done"}},
+		args:          []string{"/delete", onboardingRecord},
+		check:         isError("can't find that group"),
+		expectedStore: rndtest.Store{onboardingRecord: {"done"}},
+	},
+
 	{
 		description: "listing groups when there are none",
 		store:       rndtest.Store{},
@@ -95,154 +438,1926 @@ var testCases = []struct {
 	},
 
 	{
-		description: "showing a group",
+		description: "showing a group in its saved order",
 		store:       rndtest.Store{"test": {"one", "two", "three"}},
 		args:        []string{"/show", "test"},
-		check:       isResult(ShowedGroup, "• one", "• three", "• two"),
+		check:       isResult(ShowedGroup, "• one", "• two", "• three"),
 	},
 
 	{
-		description: "showing a group that does not exist",
-		store:       rndtest.Store{},
-		args:        []string{"/show", "test"},
-		check:       isError("can't find that group"),
+		description: "moving an option in a group",
+		store:       rndtest.Store{"test": {"one", "two #even", "three"}},
+		args:        []string{"/reorder", "test", "three", "1"},
+		check:       isResult(ReorderedGroup, "1. three", "2. one", "3. two (even)"),
+		expectedStore: rndtest.Store{
+			"test": {"three", "one", "two #even"},
+		},
 	},
 
 	{
-		description: "unable to show a group",
-		store:       nil,
-		args:        []string{"/show", "test"},
-		check:       isError("had trouble getting that group"),
+		description: "reordering a whole group",
+		store:       rndtest.Store{"test": {"one", "two"}},
+		args:        []string{"/reorder", "test", "two", "one"},
+		check:       isResult(ReorderedGroup, "1. two", "2. one"),
+		expectedStore: rndtest.Store{
+			"test": {"two", "one"},
+		},
 	},
 
 	{
-		description: "no group provided to show",
-		store:       rndtest.Store{},
-		args:        []string{"/show"},
-		check:       isError("requires an argument"),
+		description: "previewing a reorder",
+		store:       rndtest.Store{"test": {"one", "two"}},
+		args:        []string{"/reorder", "test", "two", "1", "--dry-run"},
+		check:       isResult(PreviewedChanges, `Reorder the "test" group to two, one`),
+		expectedStore: rndtest.Store{
+			"test": {"one", "two"},
+		},
 	},
 
 	{
-		description:   "saving a group",
-		store:         rndtest.Store{},
-		args:          []string{"/save", "test", "one", "two"},
-		check:         isResult(SavedGroup, `The "test" group was saved`, "• one", "• two"),
+		description: "moving an option out of range",
+		store:       rndtest.Store{"test": {"one", "two", "three"}},
+		args:        []string{"/reorder", "test", "one", "4"},
+		check:       isError("must be a number from 1 to 3"),
+	},
+
+	{
+		description: "reordering a group with a repeated option",
+		store:       rndtest.Store{"test": {"one", "two", "three"}},
+		args:        []string{"/reorder", "test", "one", "one", "two"},
+		check:       isError(`and "one" doesn't fit`),
+	},
+
+	{
+		description: "setting an expiry policy",
+		store:       rndtest.Store{"test": {"one", "two"}},
+		args:        []string{"/expire", "test", "6mo"},
+		check:       isResult(UpdatedExpiry, "haven't been picked or confirmed in 6mo"),
+	},
+
+	{
+		description: "setting an expiry policy with an invalid age",
+		store:       rndtest.Store{"test": {"one", "two"}},
+		args:        []string{"/expire", "test", "soon"},
+		check:       isError(`not "soon"`),
+	},
+
+	{
+		description: "flagging stale options in a selection",
+		store: rndtest.Store{
+			"test":         {"one", "two"},
+			"/expiry/test": {"policy|90d|flag", "confirmed|one|2099-01-01T00:00:00Z", "confirmed|two|2020-01-01T00:00:00Z"},
+		},
+		args:  []string{"test"},
+		check: isResult(Selection, "*one*", "*two*", "*two* hasn't been picked or confirmed in 90d", "/confirm-options test two"),
+	},
+
+	{
+		description: "leaving stale options out of a selection",
+		store: rndtest.Store{
+			"test":         {"one", "two", "three"},
+			"/expiry/test": {"policy|90d|disable", "confirmed|one|2099-01-01T00:00:00Z"},
+		},
+		args:  []string{"test"},
+		check: isResult(Selection, "*one*", "I left out *two*, *three*, which haven't"),
+	},
+
+	{
+		description: "treating recent picks as activity",
+		store: rndtest.Store{
+			"test":          {"one", "two"},
+			"/expiry/test":  {"policy|90d|disable"},
+			"/history/test": {"2099-01-01T00:00:00.000000000Z|two"},
+		},
+		args:  []string{"test"},
+		check: isResult(Selection, "*two*", "I left out *one*, which hasn't"),
+	},
+
+	{
+		description: "selecting from a group where every option has expired",
+		store: rndtest.Store{
+			"test":         {"one", "two"},
+			"/expiry/test": {"policy|90d|disable"},
+		},
+		args:  []string{"test"},
+		check: isError("every option in the \"test\" group has expired"),
+	},
+
+	{
+		description: "confirming an option that isn't in the group",
+		store:       rndtest.Store{"test": {"one", "two"}},
+		args:        []string{"/confirm-options", "test", "three"},
+		check:       isError(`doesn't have an option named "three"`),
+	},
+
+	{
+		description: "turning off an expiry policy",
+		store: rndtest.Store{
+			"test":         {"one", "two"},
+			"/expiry/test": {"policy|90d|disable"},
+		},
+		args:          []string{"/expire", "test", "off"},
+		check:         isResult(UpdatedExpiry, "will no longer expire"),
 		expectedStore: rndtest.Store{"test": {"one", "two"}},
 	},
 
 	{
-		description: "unable to save a group",
-		store:       nil,
-		args:        []string{"/save", "test", "one", "two"},
-		check:       isError("trouble saving that group"),
+		description: "looking up a past result",
+		store: rndtest.Store{
+			"/results": {
+				"2026-10-16T12:00:00.000000000Z k3x9qp lunch pizza tacos",
+				"2026-10-16T13:00:00.000000000Z m7ab2c - heads tails",
+			},
+		},
+		args:  []string{"/result", "k3x9qp"},
+		check: isResult(ShowedResult, `Result k3x9qp was made from the "lunch" group`, "*pizza*, *tacos*"),
 	},
 
 	{
-		description: "saving a group with a flag name",
-		store:       rndtest.Store{},
-		args:        []string{"/save", "/delete", "one", "two"},
-		check:       isError("has a special meaning"),
+		description: "looking up a past result with options given directly",
+		store: rndtest.Store{
+			"/results": {"2026-10-16T13:00:00.000000000Z m7ab2c - heads tails"},
+		},
+		args:  []string{"/result", "#M7AB2C"},
+		check: isResult(ShowedResult, "from options given directly", "*heads*, *tails*"),
 	},
 
 	{
-		description: "saving a group with a potential flag name",
+		description: "looking up a result that does not exist",
 		store:       rndtest.Store{},
-		args:        []string{"/save", "/futureflag", "one", "two"},
-		check:       isError("has a special meaning"),
+		args:        []string{"/result", "zzzzzz"},
+		check:       isError(`can't find a result with the ID "zzzzzz"`),
 	},
 
 	{
-		description: `saving a group named "help"`,
+		description: "looking up a result without an ID",
 		store:       rndtest.Store{},
-		args:        []string{"/save", "help", "one", "two"},
-		check:       isError("has a special meaning"),
+		args:        []string{"/result"},
+		check:       isError("Whoops"),
 	},
 
 	{
-		description: "no group name provided to save",
-		store:       rndtest.Store{},
-		args:        []string{"/save"},
-		check:       isError("requires an argument"),
+		description: "rotating through a group",
+		store: rndtest.Store{
+			"standup":           {"alice", "bob", "carol"},
+			"/rotation/standup": {"round|alice", "last|alice"},
+		},
+		args:  []string{"/rotate", "standup"},
+		check: isResult(Selection, "I randomized and got: *bob*.", "1 of 3 left in this round."),
 	},
 
 	{
-		description: "no options provided to save",
+		description: "rotating into a new round",
+		store: rndtest.Store{
+			"standup":           {"alice", "bob", "carol"},
+			"/rotation/standup": {"round|alice", "round|bob", "round|carol", "last|alice"},
+		},
+		args:  []string{"/rotate", "standup", "--dry-run"},
+		check: isResult(Selection, "*bob*", "started a new round. 2 of 3 left."),
+	},
+
+	{
+		description: "rotating through more winners than are left in the round",
+		store: rndtest.Store{
+			"standup":           {"alice", "bob", "carol"},
+			"/rotation/standup": {"round|alice", "round|bob", "last|bob"},
+		},
+		args:  []string{"/rotate", "standup", "-n", "2"},
+		check: isResult(Selection, "*carol*, *alice*", "started a new round. 1 of 3 left."),
+	},
+
+	{
+		description: "showing a group's recent picks",
+		store: rndtest.Store{
+			"/history/lunch": {
+				"2026-10-14T12:00:00.000000000Z|pizza k3x9qp",
+				"2026-10-15T12:00:00.000000000Z|tacos",
+				"2026-10-16T12:00:00.000000000Z|sushi m7ab2c",
+			},
+		},
+		args: []string{"/history", "lunch", "--last", "2"},
+		check: isResult(ShowedHistory,
+			`the last 2 picks from the "lunch" group`,
+			"*sushi* (m7ab2c)\n• <!date^1792065600", "*tacos*"),
+	},
+
+	{
+		description: "showing recent picks in the channel",
+		store: rndtest.Store{
+			"/results": {
+				"2026-10-16T12:00:00.000000000Z k3x9qp lunch pizza tacos",
+				"2026-10-16T13:00:00.000000000Z m7ab2c - heads tails",
+			},
+		},
+		args:  []string{"/history"},
+		check: isResult(ShowedHistory, "the last 2 selections in this channel", `*heads*, *tails* (m7ab2c)`, `*pizza*, *tacos* from "lunch" (k3x9qp)`),
+	},
+
+	{
+		description: "showing the history of a group without any",
 		store:       rndtest.Store{},
-		args:        []string{"/save", "test"},
-		check:       isError("need at least two options"),
+		args:        []string{"/history", "lunch"},
+		check:       isError(`haven't made any selections from the "lunch" group`),
 	},
 
 	{
-		description: "only one option provided to save",
+		description: "showing history with an invalid length",
 		store:       rndtest.Store{},
-		args:        []string{"/save", "test", "one"},
-		check:       isError("need at least two options"),
+		args:        []string{"/history", "--last", "zero"},
+		check:       isError("I need a number of picks"),
 	},
 
 	{
-		description:   "deleting a group",
-		store:         rndtest.Store{"test": {"one", "two"}},
-		args:          []string{"/delete", "test"},
-		check:         isResult(DeletedGroup, `The "test" group was deleted`),
-		expectedStore: rndtest.Store{},
+		description: "proposing a pick",
+		store:       rndtest.Store{"lunch": {"pizza", "sushi", "tacos"}},
+		args:        []string{"/propose", "lunch", "--approvals", "3"},
+		check:       isResult(ProposedPick, "I propose: *pizza*. It needs 3 approvals to stand.", "/approve "),
 	},
 
 	{
-		description: "deleting a group that does not exist",
+		description: "proposing a pick with an invalid approval count",
+		store:       rndtest.Store{"lunch": {"pizza", "sushi", "tacos"}},
+		args:        []string{"/propose", "lunch", "--approvals", "0"},
+		check:       isError("I need a number of approvals"),
+	},
+
+	{
+		description: "approving a proposal that does not exist",
 		store:       rndtest.Store{},
-		args:        []string{"/delete", "test"},
-		check:       isError("can't find that group"),
+		args:        []string{"/approve", "zzzzzz"},
+		check:       isError(`can't find an open proposal with the ID "zzzzzz"`),
 	},
 
 	{
-		description: "unable to delete a group",
-		store:       nil,
-		args:        []string{"/delete", "test"},
-		check:       isError("trouble deleting that group"),
+		description: "revealing a sealed pick that does not exist",
+		store:       rndtest.Store{},
+		args:        []string{"/reveal", "zzzzzz"},
+		check:       isError(`can't find a sealed pick with the ID "zzzzzz"`),
 	},
 
 	{
-		description: "no group provided to delete",
+		description: "theming without a workspace store",
 		store:       rndtest.Store{},
-		args:        []string{"/delete"},
-		check:       isError("requires an argument"),
+		args:        []string{"/theme", "emoji", ":tada:"},
+		check:       isError("themes aren't available here"),
 	},
 
-	// Requesting help
+	{
+		description: "capping how often options are picked",
+		store:       rndtest.Store{"test": {"one", "two"}},
+		args:        []string{"/cap", "test", "2", "1w"},
+		check:       isResult(UpdatedCap, "at most 2 times per 1w"),
+		expectedStore: rndtest.Store{
+			"test":      {"one", "two"},
+			"/cap/test": {"2|1w"},
+		},
+	},
 
 	{
-		description: "help as a flag",
-		args:        []string{"/help"},
-		check:       isResult(ShowedHelp),
+		description: "capping picks with an invalid period",
+		store:       rndtest.Store{"test": {"one", "two"}},
+		args:        []string{"/cap", "test", "2", "weekly"},
+		check:       isError(`period like "7d"`),
 	},
 
 	{
-		description: "help as a standalone argument",
-		args:        []string{"help"},
-		check:       isResult(ShowedHelp),
+		description: "drawing again when the winner is at its cap",
+		store: rndtest.Store{
+			"test":      {"one", "two", "three"},
+			"/cap/test": {"1|1w"},
+			"/history/test": {
+				"2026-10-16T12:00:00.000000000Z|one",
+				"2026-09-01T12:00:00.000000000Z|two",
+			},
+		},
+		args:  []string{"test"},
+		check: isResult(Selection, "I randomized and got: *three*, *two*.", "I drew again, since *one* has already been picked 1 time per 1w"),
 	},
 
 	{
-		description: "help as an option to be randomized",
-		args:        []string{"help", "me"},
-		check:       isResult(Selection, "*help*", "*me*"),
+		description: "selecting when every option is at its cap",
+		store: rndtest.Store{
+			"test":          {"one"},
+			"/cap/test":     {"1|1w"},
+			"/history/test": {"2026-10-16T12:00:00.000000000Z|one"},
+		},
+		args:  []string{"test"},
+		check: isError("/cap test off"),
 	},
-}
 
-func TestMain(t *testing.T) {
-	for _, tc := range testCases {
-		t.Run(tc.description, func(t *testing.T) {
-			store := tc.store.Clone()
-			app := NewApp("randomizer", store)
-			app.shuffle = slices.Sort
+	{
+		description:   "removing a cap",
+		store:         rndtest.Store{"test": {"one"}, "/cap/test": {"1|1w"}},
+		args:          []string{"/cap", "test", "off"},
+		check:         isResult(UpdatedCap, "any number of times"),
+		expectedStore: rndtest.Store{"test": {"one"}},
+	},
+
+	{
+		description: "giving a group an icon",
+		store:       rndtest.Store{"lunch": {"pizza", "tacos"}, "/icons": {"snacks|:popcorn:"}},
+		args:        []string{"/icon", "lunch", ":pizza:"},
+		check:       isResult(UpdatedIcon, `The "lunch" group's icon is now :pizza:`),
+		expectedStore: rndtest.Store{
+			"lunch":  {"pizza", "tacos"},
+			"/icons": {"lunch|:pizza:", "snacks|:popcorn:"},
+		},
+	},
+
+	{
+		description: "giving a group an invalid icon",
+		store:       rndtest.Store{"lunch": {"pizza", "tacos"}},
+		args:        []string{"/icon", "lunch", "pizza"},
+		check:       isError("single emoji"),
+	},
+
+	{
+		description: "showing group icons",
+		store:       rndtest.Store{"lunch": {"pizza", "tacos"}, "snacks": {"chips", "popcorn"}, "/icons": {"lunch|🍕"}},
+		args:        []string{"/list"},
+		check:       isResult(ListedGroups, "• 🍕 lunch\n• snacks"),
+	},
+
+	{
+		description: "selecting from a group with an icon",
+		store:       rndtest.Store{"lunch": {"pizza", "tacos"}, "/icons": {"lunch|:pizza:"}},
+		args:        []string{"lunch"},
+		check:       isResult(Selection, ":pizza: I randomized and got: *pizza*, *tacos*."),
+	},
+
+	{
+		description:   "removing a group's icon",
+		store:         rndtest.Store{"lunch": {"pizza", "tacos"}, "/icons": {"lunch|:pizza:"}},
+		args:          []string{"/icon", "lunch", "off"},
+		check:         isResult(UpdatedIcon, "no longer has an icon"),
+		expectedStore: rndtest.Store{"lunch": {"pizza", "tacos"}},
+	},
+
+	{
+		description: "adding a variant to a group",
+		store:       rndtest.Store{"lunch": {"pizza"}, "friday-lunch": {"tacos"}},
+		args:        []string{"/variant", "lunch", "fri", "friday-lunch"},
+		check:       isResult(UpdatedVariants, `On fri, selections from the "lunch" group will use the "friday-lunch" group`),
+		expectedStore: rndtest.Store{
+			"lunch":           {"pizza"},
+			"friday-lunch":    {"tacos"},
+			"/variants/lunch": {"fri||friday-lunch"},
+		},
+	},
+
+	{
+		description: "adding a variant with invalid days",
+		store:       rndtest.Store{"lunch": {"pizza"}, "friday-lunch": {"tacos"}},
+		args:        []string{"/variant", "lunch", "fryday", "friday-lunch"},
+		check:       isError(`I need days like "fri"`),
+	},
+
+	{
+		description: "adding a variant that does not exist",
+		store:       rndtest.Store{"lunch": {"pizza"}},
+		args:        []string{"/variant", "lunch", "fri", "friday-lunch"},
+		check:       isError(`can't find the "friday-lunch" group`),
+	},
+
+	{
+		description: "listing a group's variants",
+		store: rndtest.Store{
+			"lunch":           {"pizza"},
+			"/variants/lunch": {"daily||any-lunch", "fri|11:00-14:00|friday-lunch"},
+		},
+		args:  []string{"/variant", "lunch"},
+		check: isResult(ShowedVariants, "• friday-lunch on fri from 11:00-14:00", "• any-lunch on daily"),
+	},
+
+	{
+		description: "randomizing a group with an active variant",
+		store: rndtest.Store{
+			"lunch":           {"pizza"},
+			"any-lunch":       {"tacos", "ramen"},
+			"/variants/lunch": {"daily||any-lunch"},
+		},
+		args:  []string{"lunch"},
+		check: isResult(Selection, "*ramen*, *tacos*", `I used the "any-lunch" variant of the "lunch" group`),
+	},
+
+	{
+		description: "overriding a group's variants",
+		store: rndtest.Store{
+			"lunch":           {"pizza"},
+			"any-lunch":       {"tacos", "ramen"},
+			"/variants/lunch": {"daily||any-lunch"},
+		},
+		args:  []string{"lunch", "--variant=none"},
+		check: isResult(Selection, "*pizza*."),
+	},
+
+	{
+		description: "requesting a variant that does not exist",
+		store:       rndtest.Store{"lunch": {"pizza"}},
+		args:        []string{"lunch", "--variant", "brunch"},
+		check:       isError(`doesn't have a variant named "brunch"`),
+	},
+
+	{
+		description: "showing a group with tagged options",
+		store:       rndtest.Store{"test": {"two #even", "one #odd #first"}},
+		args:        []string{"/show", "test"},
+		check:       isResult(ShowedGroup, "• two (even)", "• one (odd, first)"),
+	},
+
+	{
+		description: "showing a group that does not exist",
+		store:       rndtest.Store{},
+		args:        []string{"/show", "test"},
+		check:       isError("can't find that group"),
+	},
+
+	{
+		description: "unable to show a group",
+		store:       nil,
+		args:        []string{"/show", "test"},
+		check:       isError("had trouble getting that group"),
+	},
+
+	{
+		description: "no group provided to show",
+		store:       rndtest.Store{},
+		args:        []string{"/show"},
+		check:       isError("requires an argument"),
+	},
+
+	{
+		description: "saving a group",
+		store:       rndtest.Store{},
+		args:        []string{"/save", "test", "one", "two"},
+		check:       isResult(SavedGroup, `The "test" group was saved`, "• one", "• two"),
+		expectedStore: rndtest.Store{
+			"test":             {"one", "two"},
+			"/provenance/test": {"2026-10-17T12:00:00Z|U123|/save|one", "2026-10-17T12:00:00Z|U123|/save|two"},
+		},
+	},
+
+	{
+		description: "saving a group again keeps the provenance of existing options",
+		store: rndtest.Store{
+			"test":             {"one", "two"},
+			"/provenance/test": {"2026-01-01T00:00:00Z|U999|/save|one", "2026-01-01T00:00:00Z|U999|/save|two"},
+		},
+		args:  []string{"/save", "test", "one", "three"},
+		check: isResult(SavedGroup),
+		expectedStore: rndtest.Store{
+			"test":             {"one", "three"},
+			"/provenance/test": {"2026-01-01T00:00:00Z|U999|/save|one", "2026-10-17T12:00:00Z|U123|/save|three"},
+		},
+	},
+
+	{
+		description: "showing a group's provenance",
+		store: rndtest.Store{
+			"test":             {"one #odd", "two"},
+			"/provenance/test": {"2026-01-01T00:00:00Z|U999|/save|one", "2026-01-01T00:00:00Z|-|/save|two"},
+		},
+		args: []string{"/show", "test", "--verbose"},
+		check: isResult(ShowedGroup,
+			"• one (odd) — added by <@U999> with /save on <!date^",
+			"• two — added with /save on <!date^"),
+	},
+
+	{
+		description: "showing a group without provenance",
+		store:       rndtest.Store{"test": {"one", "two"}},
+		args:        []string{"/show", "test", "--verbose"},
+		check:       isResult(ShowedGroup, "• one — origin unknown", "• two — origin unknown"),
+	},
+
+	{
+		description: "unable to save a group",
+		store:       nil,
+		args:        []string{"/save", "test", "one", "two"},
+		check:       isError("trouble saving that group"),
+	},
+
+	{
+		description: "saving a group with a flag name",
+		store:       rndtest.Store{},
+		args:        []string{"/save", "/delete", "one", "two"},
+		check:       isError("has a special meaning"),
+	},
+
+	{
+		description: "saving a group with a potential flag name",
+		store:       rndtest.Store{},
+		args:        []string{"/save", "/futureflag", "one", "two"},
+		check:       isError("has a special meaning"),
+	},
+
+	{
+		description: "saving a group with a group reference name",
+		store:       rndtest.Store{},
+		args:        []string{"/save", "+test", "one", "two"},
+		check:       isError("has a special meaning"),
+	},
+
+	{
+		description: `saving a group named "help"`,
+		store:       rndtest.Store{},
+		args:        []string{"/save", "help", "one", "two"},
+		check:       isError("has a special meaning"),
+	},
+
+	{
+		description: "no group name provided to save",
+		store:       rndtest.Store{},
+		args:        []string{"/save"},
+		check:       isError("requires an argument"),
+	},
+
+	{
+		description: "no options provided to save",
+		store:       rndtest.Store{},
+		args:        []string{"/save", "test"},
+		check:       isError("need at least two options"),
+	},
+
+	{
+		description: "only one option provided to save",
+		store:       rndtest.Store{},
+		args:        []string{"/save", "test", "one"},
+		check:       isError("need at least two options"),
+	},
+
+	{
+		description:   "deleting a group",
+		store:         rndtest.Store{"test": {"one", "two"}},
+		args:          []string{"/delete", "test"},
+		check:         isResult(DeletedGroup, `The "test" group was deleted`),
+		expectedStore: rndtest.Store{},
+	},
+
+	{
+		description: "deleting a group that does not exist",
+		store:       rndtest.Store{},
+		args:        []string{"/delete", "test"},
+		check:       isError("can't find that group"),
+	},
+
+	{
+		description: "unable to delete a group",
+		store:       nil,
+		args:        []string{"/delete", "test"},
+		check:       isError("trouble deleting that group"),
+	},
+
+	{
+		description: "no group provided to delete",
+		store:       rndtest.Store{},
+		args:        []string{"/delete"},
+		check:       isError("requires an argument"),
+	},
+
+	// Tagging options
+
+	{
+		description:   "tagging an option",
+		store:         rndtest.Store{"lunch": {"ramen", "sushi #cheap"}},
+		args:          []string{"/tag", "lunch", "sushi", "cuisine=japanese", "#cheap"},
+		check:         isResult(TaggedOption, "• cheap", "• cuisine=japanese"),
+		expectedStore: rndtest.Store{"lunch": {"ramen", "sushi #cheap #cuisine=japanese"}},
+	},
+
+	{
+		description:   "replacing an attribute on an option",
+		store:         rndtest.Store{"lunch": {"ramen", "sushi #cuisine=japan"}},
+		args:          []string{"/tag", "lunch", "sushi", "cuisine=japanese"},
+		check:         isResult(TaggedOption, "• cuisine=japanese"),
+		expectedStore: rndtest.Store{"lunch": {"ramen", "sushi #cuisine=japanese"}},
+	},
+
+	{
+		description:   "untagging an option",
+		store:         rndtest.Store{"lunch": {"ramen", "sushi #cheap #cuisine=japanese"}},
+		args:          []string{"/untag", "lunch", "sushi", "cheap", "cuisine"},
+		check:         isResult(TaggedOption, "has no tags"),
+		expectedStore: rndtest.Store{"lunch": {"ramen", "sushi"}},
+	},
+
+	{
+		description: "tagging an option that does not exist",
+		store:       rndtest.Store{"lunch": {"ramen", "sushi"}},
+		args:        []string{"/tag", "lunch", "tacos", "cheap"},
+		check:       isError(`doesn't have an option named "tacos"`),
+	},
+
+	{
+		description: "tagging an option in a group that does not exist",
+		store:       rndtest.Store{},
+		args:        []string{"/tag", "lunch", "sushi", "cheap"},
+		check:       isError("can't find that group"),
+	},
+
+	{
+		description: "tagging an option without any tags",
+		store:       rndtest.Store{"lunch": {"ramen", "sushi"}},
+		args:        []string{"/tag", "lunch", "sushi"},
+		check:       isError("need the name of an option and at least one tag"),
+	},
+
+	{
+		description: "unable to tag an option",
+		store:       nil,
+		args:        []string{"/tag", "lunch", "sushi", "cheap"},
+		check:       isError("trouble getting that group"),
+	},
+
+	// Assigning tasks
+
+	{
+		description: "sampling from a uniform distribution",
+		store:       rndtest.Store{},
+		args:        []string{"/sample", "uniform", "min=5", "max=5", "decimals=1"},
+		check:       isResult(Sampled, "I sampled from uniform(min=5, max=5) and got: *5.0*."),
+	},
+
+	{
+		description: "sampling several numbers from a normal distribution",
+		store:       rndtest.Store{},
+		args:        []string{"/sample", "normal", "mean=10", "sd=0", "-n", "3"},
+		check:       isResult(Sampled, "normal(mean=10, stddev=0) and got: *10.00*, *10.00*, *10.00*.", "Mean: 10.00, min: 10.00, max: 10.00."),
+	},
+
+	{
+		description: "sampling from a Poisson distribution",
+		store:       rndtest.Store{},
+		args:        []string{"/sample", "poisson", "lambda=0"},
+		check:       isResult(Sampled, "poisson(mean=0) and got: *0*."),
+	},
+
+	{
+		description: "sampling from an unknown distribution",
+		store:       rndtest.Store{},
+		args:        []string{"/sample", "cauchy"},
+		check:       isError("I can sample from exponential, normal, poisson, uniform."),
+	},
+
+	{
+		description: "sampling with an unknown parameter",
+		store:       rndtest.Store{},
+		args:        []string{"/sample", "exponential", "rate=2"},
+		check:       isError(`I don't understand "rate=2"! The exponential distribution takes mean=…`),
+	},
+
+	{
+		description: "sampling with invalid parameters",
+		store:       rndtest.Store{},
+		args:        []string{"/sample", "uniform", "min=2", "max=1"},
+		check:       isError("max can't be less than min"),
+	},
+
+	{
+		description: "sampling too many numbers",
+		store:       rndtest.Store{},
+		args:        []string{"/sample", "normal", "-n", "1000"},
+		check:       isError("from 1 to 100 numbers"),
+	},
+
+	{
+		description: "showing the version",
+		store:       rndtest.Store{},
+		args:        []string{"/version"},
+		check:       isResult(ShowedVersion, "Randomizer ", "• Store: unknown"),
+	},
+
+	{
+		description: "rolling dice with a modifier",
+		store:       rndtest.Store{},
+		args:        []string{"/roll", "2D1", "+", "3"},
+		check:       isResult(RolledDice, "I rolled 2d1+3 and got: *5*.", "• *5* = 2d1 (1, 1) + 3"),
+	},
+
+	{
+		description: "rolling dice several times",
+		store:       rndtest.Store{},
+		args:        []string{"/roll", "d1-1", "-n", "2"},
+		check:       isResult(RolledDice, "I rolled 1d1-1 and got: *0*, *0*."),
+	},
+
+	{
+		description: "rolling invalid dice",
+		store:       rndtest.Store{},
+		args:        []string{"/roll", "2d6x"},
+		check:       isError(`I can't roll "2d6x"`),
+	},
+
+	{
+		description: "rolling too many dice",
+		store:       rndtest.Store{},
+		args:        []string{"/roll", "60d6+60d6"},
+		check:       isError("at most 100 dice"),
+	},
+
+	{
+		description: "picking numbers from a range",
+		store:       rndtest.Store{},
+		args:        []string{"/range", "-5", "-5", "-n", "2"},
+		check:       isResult(PickedNumbers, "I picked from -5 to -5 and got: *-5*, *-5*."),
+	},
+
+	{
+		description: "picking from a range without bounds",
+		store:       rndtest.Store{},
+		args:        []string{"/range", "100"},
+		check:       isError("give me the smallest and largest numbers"),
+	},
+
+	{
+		description: "picking from a range with invalid bounds",
+		store:       rndtest.Store{},
+		args:        []string{"/range", "1", "ten"},
+		check:       isError(`"ten" needs to be a whole number`),
+	},
+
+	{
+		description: "excluding options from a group",
+		store:       rndtest.Store{"standup": {"alice", "bob", "carol", "dave"}},
+		args:        []string{"+standup", "-alice", "-Dave"},
+		check:       isResult(Selection, "I randomized and got: *bob*, *carol*."),
+	},
+
+	{
+		description: "excluding options that aren't in the group",
+		store:       rndtest.Store{"standup": {"alice", "bob", "carol"}},
+		args:        []string{"/pick", "2", "+standup", "--exclude", "bob", "-erin"},
+		check:       isResult(Selection, "*alice*, *carol*.", "I didn't find *erin* to leave out."),
+	},
+
+	{
+		description: "excluding every option",
+		store:       rndtest.Store{},
+		args:        []string{"heads", "tails", "-heads", "-tails"},
+		check:       isError("leaves nothing to pick from"),
+	},
+
+	{
+		description: "combining groups and inline options",
+		store:       rndtest.Store{"on-call": {"alice", "bob"}, "backend": {"bob", "dave"}},
+		args:        []string{"+on-call", "+backend", "charlie"},
+		check:       isResult(Selection, "I randomized and got: *alice*, *bob*, *charlie*, *dave*."),
+	},
+
+	{
+		description: "combining a missing group with inline options",
+		store:       rndtest.Store{"on-call": {"alice", "bob"}},
+		args:        []string{"+on-call", "+backend", "charlie"},
+		check:       isError(`I couldn't find the "backend" group`),
+	},
+
+	{
+		description: "selecting negative numbers and escaped dashes",
+		store:       rndtest.Store{},
+		args:        []string{"-1", "-2", "--", "-three"},
+		check:       isResult(Selection, "*-1*, *-2*, *-three*"),
+	},
+
+	{
+		description: "excluding options from teams",
+		store:       rndtest.Store{"people": {"bob", "alice", "dave", "carol"}},
+		args:        []string{"/teams", "2", "+people", "-dave"},
+		check:       isResult(Teams, "• Team 1: *alice*, *bob*", "• Team 2: *carol*"),
+	},
+
+	{
+		description: "saving options that look like exclusions",
+		store:       rndtest.Store{},
+		args:        []string{"/save", "moods", "happy", "-_-", "meh"},
+		check:       isResult(SavedGroup, "• happy", "• -_-", "• meh"),
+		expectedStore: rndtest.Store{
+			"moods":             {"happy", "-_-", "meh"},
+			"/provenance/moods": {"2026-10-17T12:00:00Z|U123|/save|-_-", "2026-10-17T12:00:00Z|U123|/save|happy", "2026-10-17T12:00:00Z|U123|/save|meh"},
+		},
+	},
+
+	{
+		description: "splitting options into teams",
+		store:       rndtest.Store{},
+		args:        []string{"/teams", "2", "eve", "carol", "alice", "dave", "bob"},
+		check:       isResult(Teams, "• Team 1: *alice*, *bob*, *carol*", "• Team 2: *dave*, *eve*"),
+	},
+
+	{
+		description: "splitting a group into teams",
+		store:       rndtest.Store{"people": {"bob", "alice", "dave", "carol"}},
+		args:        []string{"/teams", "2", "+people"},
+		check:       isResult(Teams, "• Team 1: *alice*, *bob*", "• Team 2: *carol*, *dave*"),
+	},
+
+	{
+		description: "splitting too few options into teams",
+		store:       rndtest.Store{},
+		args:        []string{"/teams", "3", "alice", "bob"},
+		check:       isError("can't split 2 options into 3 teams"),
+	},
+
+	{
+		description: "splitting options into an invalid number of teams",
+		store:       rndtest.Store{},
+		args:        []string{"/teams", "zero", "alice", "bob"},
+		check:       isError("I need a number of teams"),
+	},
+
+	{
+		description: "splitting nothing into teams",
+		store:       rndtest.Store{},
+		args:        []string{"/teams", "2"},
+		check:       isError("I need a group or some options"),
+	},
+
+	{
+		description: "assigning people to tasks",
+		store:       rndtest.Store{"chores": {"dishes", "laundry #capacity=2"}},
+		args:        []string{"/assign", "chores", "carol", "alice", "bob"},
+		check:       isResult(Assignment, "• dishes: *alice*", "• laundry: *bob*, *carol*"),
+	},
+
+	{
+		description: "assigning people from a group to tasks",
+		store:       rndtest.Store{"chores": {"dishes", "laundry"}, "people": {"bob", "alice"}},
+		args:        []string{"/assign", "+chores", "+people"},
+		check:       isResult(Assignment, "• dishes: *alice*", "• laundry: *bob*"),
+	},
+
+	{
+		description: "assigning too few people to tasks",
+		store:       rndtest.Store{"chores": {"dishes", "laundry #capacity=2"}},
+		args:        []string{"/assign", "chores", "alice", "bob"},
+		check:       isError("need 3 people, but I have 2 people to assign"),
+	},
+
+	{
+		description: "assigning too many people to tasks",
+		store:       rndtest.Store{"chores": {"dishes"}},
+		args:        []string{"/assign", "chores", "alice", "bob"},
+		check:       isError("need 1 person, but I have 2 people to assign"),
+	},
+
+	{
+		description: "assigning people to tasks with an invalid capacity",
+		store:       rndtest.Store{"chores": {"dishes #capacity=lots"}},
+		args:        []string{"/assign", "chores", "alice", "bob"},
+		check:       isError(`capacity for "dishes" needs to be a positive whole number`),
+	},
+
+	{
+		description: "assigning nobody to tasks",
+		store:       rndtest.Store{"chores": {"dishes"}},
+		args:        []string{"/assign", "chores"},
+		check:       isError("need a group of tasks followed by the people"),
+	},
+
+	{
+		description: "assigning people to tasks that do not exist",
+		store:       rndtest.Store{},
+		args:        []string{"/assign", "chores", "alice", "bob"},
+		check:       isError(`couldn't find the "chores" group`),
+	},
+
+	// Previewing changes
+
+	{
+		description:   "previewing a new group",
+		store:         rndtest.Store{},
+		args:          []string{"/save", "test", "one", "two", "--dry-run"},
+		check:         isResult(PreviewedChanges, `Create the "test" group with one, two`),
+		expectedStore: rndtest.Store{},
+	},
+
+	{
+		description:   "previewing changes to a group",
+		store:         rndtest.Store{"test": {"one", "two"}},
+		args:          []string{"--dry-run", "/save", "test", "two", "three"},
+		check:         isResult(PreviewedChanges, `Update the "test" group: add three; remove one`),
+		expectedStore: rndtest.Store{"test": {"one", "two"}},
+	},
+
+	{
+		description:   "previewing a deletion",
+		store:         rndtest.Store{"test": {"one", "two"}},
+		args:          []string{"/delete", "--dry-run", "test"},
+		check:         isResult(PreviewedChanges, `Delete the "test" group (one, two)`),
+		expectedStore: rndtest.Store{"test": {"one", "two"}},
+	},
+
+	{
+		description:   "previewing a selection",
+		store:         rndtest.Store{"test": {"one", "two"}},
+		args:          []string{"test", "--dry-run"},
+		check:         isResult(Selection, "I randomized and got"),
+		expectedStore: rndtest.Store{"test": {"one", "two"}},
+	},
+
+	{
+		description: "previewing the deletion of a group that does not exist",
+		store:       rndtest.Store{},
+		args:        []string{"/delete", "test", "--dry-run"},
+		check:       isError("can't find that group"),
+	},
+
+	{
+		description:   "previewing a tag change",
+		store:         rndtest.Store{"test": {"one", "two"}},
+		args:          []string{"/tag", "test", "one", "odd", "--dry-run"},
+		check:         isResult(PreviewedChanges, "add one (odd); remove one"),
+		expectedStore: rndtest.Store{"test": {"one", "two"}},
+	},
+
+	// Parsing flags
+
+	{
+		description: "spelling a verb as a long flag",
+		store:       rndtest.Store{"first": {"one"}},
+		args:        []string{"--list"},
+		check:       isResult(ListedGroups, "• first"),
+	},
+
+	{
+		description:   "giving a boolean flag an explicit value",
+		store:         rndtest.Store{},
+		args:          []string{"/save", "test", "one", "two", "--dry-run=true"},
+		check:         isResult(PreviewedChanges),
+		expectedStore: rndtest.Store{},
+	},
+
+	{
+		description: "repeating a boolean flag",
+		store:       rndtest.Store{},
+		args:        []string{"--dry-run", "/save", "test", "one", "two", "--dry-run=false"},
+		check:       isResult(SavedGroup),
+		expectedStore: rndtest.Store{
+			"test":             {"one", "two"},
+			"/provenance/test": {"2026-10-17T12:00:00Z|U123|/save|one", "2026-10-17T12:00:00Z|U123|/save|two"},
+		},
+	},
+
+	{
+		description: "giving a boolean flag an invalid value",
+		args:        []string{"/save", "test", "one", "two", "--dry-run=maybe"},
+		check:       isError("needs to be true or false"),
+	},
+
+	{
+		description: "using an unknown flag",
+		args:        []string{"one", "two", "--bogus"},
+		check:       isError(`don't know the "--bogus" flag`),
+	},
+
+	{
+		description:   "using a flag that the operation doesn't use",
+		store:         rndtest.Store{},
+		args:          []string{"/save", "x", "a", "b", "--pick", "3"},
+		check:         isError(`the "--pick" flag doesn't do anything there`),
+		expectedStore: rndtest.Store{},
+	},
+
+	{
+		description: "saving options that look like flags after a terminator",
+		store:       rndtest.Store{},
+		args:        []string{"/save", "flags", "--", "--fast", "--pick"},
+		check:       isResult(SavedGroup),
+		expectedStore: rndtest.Store{
+			"flags":             {"--fast", "--pick"},
+			"/provenance/flags": {"2026-10-17T12:00:00Z|U123|/save|--fast", "2026-10-17T12:00:00Z|U123|/save|--pick"},
+		},
+	},
+
+	{
+		description: "ending flags with a terminator",
+		args:        []string{"--", "--one", "--dry-run"},
+		check:       isResult(Selection, "*--dry-run*, *--one*"),
+	},
+
+	// Requesting help
+
+	{
+		description: "help as a flag",
+		args:        []string{"/help"},
+		check:       isResult(ShowedHelp),
+	},
+
+	{
+		description: "help as a standalone argument",
+		args:        []string{"help"},
+		check:       isResult(ShowedHelp),
+	},
+
+	{
+		description: "help as an option to be randomized",
+		args:        []string{"help", "me"},
+		check:       isResult(Selection, "*help*", "*me*"),
+	},
+}
+
+// testNow is the current time for the cases in testCases.
+var testNow = time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+func TestMain(t *testing.T) {
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			store := tc.store.Clone()
+			app := NewApp("randomizer", store,
+				WithRandomizer(slices.Sort),
+				WithClock(clocktest.New(testNow)),
+				WithUser("U123"))
 
 			res, err := app.Main(context.Background(), tc.args)
 			tc.check(t, res, err)
 
-			if tc.expectedStore != nil && !reflect.DeepEqual(store, tc.expectedStore) {
-				t.Errorf("unexpected store state\ngot:  %v\nwant: %v", tc.store, tc.expectedStore)
+			if tc.expectedStore != nil && !reflect.DeepEqual(store, tc.expectedStore) {
+				t.Errorf("unexpected store state\ngot:  %v\nwant: %v", tc.store, tc.expectedStore)
+			}
+		})
+	}
+}
+
+func TestOnboarding(t *testing.T) {
+	store := rndtest.Store{}
+	app := NewApp("randomizer", store, WithOnboarding(), WithRandomizer(slices.Sort))
+
+	first, err := app.Main(context.Background(), []string{"one", "two"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !strings.Contains(first.Message(), "Welcome!") || !strings.Contains(first.Message(), "/config group lunch") {
+		t.Errorf("first result missing welcome message\n%v", first.Message())
+	}
+	if !first.Welcomed() || !first.Onboarded() {
+		t.Errorf("first result Welcomed() = %v, Onboarded() = %v, want true", first.Welcomed(), first.Onboarded())
+	}
+
+	second, err := app.Main(context.Background(), []string{"one", "two"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if strings.Contains(second.Message(), "Welcome!") {
+		t.Errorf("second result repeated welcome message\n%v", second.Message())
+	}
+	if second.Welcomed() || !second.Onboarded() {
+		t.Errorf("second result Welcomed() = %v, Onboarded() = %v, want only Onboarded", second.Welcomed(), second.Onboarded())
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	store := rndtest.Store{"lunch": {"pizza", "tacos"}}
+	for _, tc := range []struct {
+		defaults Defaults
+		args     []string
+		check    validator
+	}{
+		{args: []string{}, check: isResult(ShowedHelp, "randomizes the order")},
+		{args: []string{"/nope", "one"}, check: isResult(Selection, "*/nope*, *one*")},
+		{defaults: Defaults{Bare: DefaultGroup("lunch")}, args: []string{}, check: isResult(Selection, "*pizza*, *tacos*")},
+		{defaults: Defaults{Bare: DefaultError}, args: []string{}, check: isError("tell me what to randomize")},
+		{defaults: Defaults{Bare: DefaultError}, args: []string{"help"}, check: isResult(ShowedHelp)},
+		{defaults: Defaults{UnknownFlag: DefaultHelp}, args: []string{"/nope"}, check: isResult(ShowedHelp)},
+		{defaults: Defaults{UnknownFlag: DefaultError}, args: []string{"/nope"}, check: isError(`don't know the "/nope" operation`)},
+		{defaults: Defaults{UnknownFlag: DefaultError}, args: []string{"/show", "lunch"}, check: isResult(ShowedGroup)},
+		{defaults: Defaults{UnknownFlag: DefaultGroup("lunch")}, args: []string{"/nope"}, check: isResult(Selection, "*pizza*")},
+	} {
+		app := NewApp("randomizer", maps.Clone(store), WithRandomizer(slices.Sort), WithDefaults(tc.defaults))
+		res, err := app.Main(context.Background(), tc.args)
+		tc.check(t, res, err)
+	}
+}
+
+func TestParseDefaultOperation(t *testing.T) {
+	for _, s := range []string{"", "help", "randomize", "error", "group:lunch"} {
+		if op, err := ParseDefaultOperation(s); err != nil || op.String() != s {
+			t.Errorf("ParseDefaultOperation(%q) = %v, %v", s, op, err)
+		}
+	}
+	for _, s := range []string{"pick", "group:", "group:/results"} {
+		if _, err := ParseDefaultOperation(s); err == nil {
+			t.Errorf("ParseDefaultOperation(%q) succeeded, want error", s)
+		}
+	}
+}
+
+func TestUnavailableStore(t *testing.T) {
+	app := NewApp("randomizer", rndtest.Store(nil), WithOnboarding(), WithRandomizer(slices.Sort))
+
+	res, err := app.Main(context.Background(), []string{"one", "two"})
+	isResult(Selection, "Saved groups are temporarily unavailable", "*one*")(t, res, err)
+
+	_, err = app.Main(context.Background(), []string{"test"})
+	if rerr, ok := err.(Error); !ok || !rerr.StoreUnavailable() {
+		t.Errorf("got error %#v, want one for an unavailable store", err)
+	}
+	isError("you can still pick from options you list directly")(t, res, err)
+}
+
+func TestRecordFeedback(t *testing.T) {
+	ctx := context.Background()
+	store := rndtest.Store{
+		"test":          {"one", "two"},
+		"/history/test": {"2026-10-16T15:00:00.000000000Z|one"},
+	}
+	app := NewApp("randomizer", store)
+	announced := time.Date(2026, 10, 16, 15, 0, 2, 0, time.UTC)
+
+	for _, f := range []Feedback{
+		{At: announced, User: "U1", Positive: true},
+		{At: announced, User: "U2", Positive: true},
+		{At: announced, User: "U2", Positive: true, Withdrawn: true},
+		{At: announced, User: "U3"},
+		{At: announced.Add(time.Hour), User: "U4", Positive: true},
+	} {
+		if err := app.RecordFeedback(ctx, f); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+
+	got := slices.Sorted(slices.Values(store["/feedback/test"]))
+	want := []string{"2026-10-16T15:00:00.000000000Z|U1|+", "2026-10-16T15:00:00.000000000Z|U3|-"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got feedback %v, want %v", got, want)
+	}
+}
+
+func TestSelectionHistory(t *testing.T) {
+	store := rndtest.Store{"test": {"one", "two"}}
+	app := NewApp("randomizer", store, WithRandomizer(slices.Sort))
+
+	for range 2 {
+		if _, err := app.Main(context.Background(), []string{"+test"}); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	if _, err := app.Main(context.Background(), []string{"one", "two"}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got := len(store[historyRecord("test")]); got != 2 {
+		t.Errorf("got %d history entries, want 2", got)
+	}
+	if _, err := app.Main(context.Background(), []string{"/pick", "2", "test"}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if got := len(store[historyRecord("test")]); got != 4 {
+		t.Errorf("got %d history entries after picking 2 winners, want 4", got)
+	}
+
+	export, err := app.Main(context.Background(), []string{"/export", "test"})
+	isResult(ExportedHistory, "| one |", "| one |")(t, export, err)
+
+	if _, err := app.Main(context.Background(), []string{"/delete", "test"}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, ok := store[historyRecord("test")]; ok {
+		t.Error("history still present after deleting group")
+	}
+}
+
+func TestExportEmails(t *testing.T) {
+	var lookups []string
+	resolve := func(_ context.Context, id string) (string, error) {
+		lookups = append(lookups, id)
+		if id == "U111" {
+			return "alice@example.com", nil
+		}
+		return "", nil
+	}
+	store := rndtest.Store{
+		"team": {"<@U111>", "<@U222>", "carol"},
+		"/history/team": {
+			"2026-10-02T15:00:00.000000000Z|<@U111>",
+			"2026-10-09T15:00:00.000000000Z|<@U222>",
+			"2026-10-16T15:00:00.000000000Z|<@U111>",
+			"2026-10-17T15:00:00.000000000Z|carol",
+		},
+	}
+	app := NewApp("/randomize", store, WithEmailResolver(resolve))
+
+	result, err := app.Main(context.Background(), []string{"/export", "--emails", "team"})
+	isResult(ExportedHistory,
+		"| Date (UTC) | Selected | Email | Result ID |",
+		"| 2026-10-02 15:00 | @U111 | alice@example.com |  |",
+		"| 2026-10-09 15:00 | @U222 |  |  |",
+		"| 2026-10-17 15:00 | carol |  |  |",
+		"couldn't find email addresses for 1 of the 2 people",
+	)(t, result, err)
+	if !slices.Equal(lookups, []string{"U111", "U222"}) {
+		t.Errorf("looked up emails for %v, want each user once", lookups)
+	}
+
+	result, err = app.Main(context.Background(), []string{"/export", "team"})
+	if err != nil || strings.Contains(result.Message(), "Email") {
+		t.Errorf("export without --emails included emails: %q, %v", result.Message(), err)
+	}
+}
+
+func TestResultIDs(t *testing.T) {
+	store := rndtest.Store{"test": {"one", "two"}}
+	app := NewApp("randomizer", store, WithRandomizer(slices.Sort))
+
+	result, err := app.Main(context.Background(), []string{"test"})
+	isResult(Selection, "*one*, *two*", "Result ID: "+result.ID())(t, result, err)
+	if len(result.ID()) != resultIDLength {
+		t.Fatalf("got result ID %q", result.ID())
+	}
+	if history := parseHistory(store[historyRecord("test")]); len(history) != 1 || history[0].ResultID != result.ID() {
+		t.Errorf("history does not reference result ID: %v", history)
+	}
+
+	lookup, err := app.Main(context.Background(), []string{"/result", result.ID()})
+	isResult(ShowedResult, `from the "test" group`, "*one*, *two*")(t, lookup, err)
+
+	if _, err := app.Main(context.Background(), []string{"--dry-run", "test"}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(store[resultsRecord]) != 1 {
+		t.Error("dry run recorded a result")
+	}
+}
+
+func TestSealedPick(t *testing.T) {
+	store := rndtest.Store{"raffle": {"one", "two", "three"}}
+	app := NewApp("randomizer", store, WithRandomizer(slices.Sort), WithUser("U1"))
+
+	sealed, err := app.Main(context.Background(), []string{"/sealed-pick", "raffle"})
+	isResult(SealedPick, "sealed a pick of 1 winner", "/reveal "+sealed.ID())(t, sealed, err)
+	if strings.Contains(sealed.Message(), "one") || len(store[historyRecord("raffle")]) > 0 {
+		t.Fatalf("sealed pick disclosed its winner: %q", sealed.Message())
+	}
+
+	other := NewApp("randomizer", store, WithUser("U2"))
+	_, err = other.Main(context.Background(), []string{"/reveal", sealed.ID()})
+	isError("only <@U1> can reveal")(t, Result{}, err)
+
+	for range 2 {
+		revealed, err := app.Main(context.Background(), []string{"/reveal", sealed.ID()})
+		isResult(RevealedPick, "*one*")(t, revealed, err)
+		pick, _ := parseSealedPick(store[sealedRecord][0])
+		if !strings.Contains(sealed.Message(), pick.hash()) || !strings.Contains(revealed.Message(), pick.commitment()) {
+			t.Errorf("reveal %q does not match commitment in %q", revealed.Message(), sealed.Message())
+		}
+	}
+	if history := parseHistory(store[historyRecord("raffle")]); len(history) != 1 || history[0].ResultID != sealed.ID() {
+		t.Errorf("got history %v, want one entry for the revealed pick", history)
+	}
+
+	lookup, err := app.Main(context.Background(), []string{"/result", sealed.ID()})
+	isResult(ShowedResult, `from the "raffle" group`, "*one*")(t, lookup, err)
+}
+
+func TestProposal(t *testing.T) {
+	store := rndtest.Store{"lunch": {"pizza", "sushi", "tacos"}}
+	users := func(user string) App { return NewApp("randomizer", store, WithRandomizer(slices.Sort), WithUser(user)) }
+	ctx := context.Background()
+
+	proposed, err := users("U1").Main(ctx, []string{"/propose", "lunch"})
+	isResult(ProposedPick, "*pizza*")(t, proposed, err)
+
+	rerolled, err := users("U2").Main(ctx, []string{"/reject", proposed.ID()})
+	isResult(ProposedPick, "*sushi*", "needs 2 approvals")(t, rerolled, err)
+	_, err = users("U2").Main(ctx, []string{"/approve", proposed.ID()})
+	isError("can't find an open proposal")(t, Result{}, err)
+
+	for range 2 {
+		counted, err := users("U1").Main(ctx, []string{"/approve", rerolled.ID()})
+		isResult(CountedApproval, "1 of 2 approvals")(t, counted, err)
+	}
+	if len(store[historyRecord("lunch")]) > 0 {
+		t.Fatal("recorded history before the proposal stood")
+	}
+
+	ratified, err := users("U3").Main(ctx, []string{"/approve", rerolled.ID()})
+	isResult(RatifiedPick, "it stands: *sushi*", "Approved by <@U1>, <@U3>")(t, ratified, err)
+	if history := parseHistory(store[historyRecord("lunch")]); len(history) != 1 || history[0].ResultID != rerolled.ID() {
+		t.Errorf("got history %v, want one entry for the ratified pick", history)
+	}
+	if proposals := store[proposalsRecord]; len(proposals) != 0 {
+		t.Errorf("ratified proposal is still open: %v", proposals)
+	}
+
+	_, err = NewApp("randomizer", store).Main(ctx, []string{"/approve", rerolled.ID()})
+	isError("people I can tell apart")(t, Result{}, err)
+}
+
+func TestRotation(t *testing.T) {
+	store := rndtest.Store{"standup": {"alice", "bob", "carol", "dave"}}
+	app := NewApp("randomizer", store)
+
+	var picks []string
+	for range 12 {
+		result, err := app.Main(context.Background(), []string{"/rotate", "standup"})
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		picks = append(picks, result.Choices()...)
+	}
+	for round := range slices.Chunk(picks, 4) {
+		if sorted := slices.Sorted(slices.Values(round)); !slices.Equal(sorted, store["standup"]) {
+			t.Errorf("round %v didn't pick everyone once", round)
+		}
+	}
+	for i := 1; i < len(picks); i++ {
+		if picks[i] == picks[i-1] {
+			t.Errorf("picked %s twice in a row: %v", picks[i], picks)
+		}
+	}
+}
+
+func TestDeferredWrites(t *testing.T) {
+	store := rndtest.Store{"test": {"one", "two"}}
+	var deferred []func()
+	app := NewApp("randomizer", store,
+		WithOnboarding(),
+		WithDeferredWrites(func(write func()) { deferred = append(deferred, write) }))
+
+	if _, err := app.Main(context.Background(), []string{"test"}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(deferred) != 0 || len(store[historyRecord("test")]) != 1 {
+		t.Fatalf("writes deferred without a deadline")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deferThreshold/2)
+	defer cancel()
+	result, err := app.Main(ctx, []string{"test"})
+	isResult(Selection, "Result ID: "+result.ID())(t, result, err)
+	if len(deferred) != 2 || len(store[historyRecord("test")]) != 1 {
+		t.Fatalf("got %d deferred writes with history %v, want 2 deferred writes", len(deferred), store[historyRecord("test")])
+	}
+
+	cancel()
+	for _, write := range deferred {
+		write()
+	}
+	if len(store[historyRecord("test")]) != 2 || len(store[resultsRecord]) != 2 {
+		t.Error("deferred writes did not complete after the request's context ended")
+	}
+}
+
+// readCountingStore counts the reads of each item, from requests that may read
+// them concurrently.
+type readCountingStore struct {
+	rndtest.Store
+	mu    *sync.Mutex
+	reads map[string]int
+}
+
+func (s readCountingStore) Get(ctx context.Context, name string) ([]string, error) {
+	s.mu.Lock()
+	s.reads[name]++
+	s.mu.Unlock()
+	return s.Store.Get(ctx, name)
+}
+
+func TestPrefetch(t *testing.T) {
+	store := readCountingStore{
+		Store: rndtest.Store{"lunch": {"pizza", "tacos"}, configRecord: {"group|lunch"}},
+		mu:    new(sync.Mutex),
+		reads: make(map[string]int),
+	}
+	workspace := readCountingStore{Store: rndtest.Store{}, mu: new(sync.Mutex), reads: make(map[string]int)}
+	app := NewApp("randomizer", store, WithOnboarding(), WithUser("UALICE"), WithWorkspaceStore(workspace))
+
+	for range 2 {
+		clear(store.reads)
+		clear(workspace.reads)
+		result, err := app.Main(context.Background(), nil)
+		isResult(Selection)(t, result, err)
+		for _, reads := range []map[string]int{store.reads, workspace.reads} {
+			for name, n := range reads {
+				if n > 1 {
+					t.Errorf("read %q %d times in one request", name, n)
+				}
 			}
-		})
+		}
+	}
+	if store.reads[onboardingRecord] != 1 || store.reads[capRecord("lunch")] != 1 {
+		t.Errorf("didn't read ahead the records of a selection: %v", store.reads)
+	}
+}
+
+func TestTheme(t *testing.T) {
+	store := rndtest.Store{}
+	workspace := rndtest.Store{}
+	app := NewApp("randomizer", store, WithWorkspaceStore(workspace))
+	run := func(check validator, args ...string) {
+		t.Helper()
+		result, err := app.Main(context.Background(), args)
+		check(t, result, err)
+	}
+
+	run(isResult(ShowedTheme, "default theme"), "/theme")
+	run(isResult(UpdatedTheme, "emoji is now :tada:"), "/theme", "emoji", ":tada:")
+	run(isResult(UpdatedTheme, "name is now Lunch Bot"), "--theme", "name", "Lunch", "Bot")
+	run(isResult(UpdatedTheme, "color is now #36a64f"), "/theme", "COLOR", "#36a64f")
+	run(isError("hex code"), "/theme", "color", "green")
+	run(isError("emoji, color, name, or icon"), "/theme", "font", "serif")
+	run(isResult(PreviewedChanges, "wouldn't change any groups"), "/theme", "icon", ":robot_face:", "--dry-run")
+
+	theme, err := LoadTheme(context.Background(), workspace)
+	want := Theme{Emoji: ":tada:", Color: "#36a64f", Username: "Lunch Bot"}
+	if err != nil || theme != want {
+		t.Fatalf("LoadTheme() = %+v, %v; want %+v", theme, err, want)
+	}
+	if len(store) != 0 {
+		t.Errorf("theme saved in the channel store: %v", store)
+	}
+
+	run(isResult(ShowedTheme, "• emoji: :tada:", "• name: Lunch Bot"), "/theme")
+	run(isResult(UpdatedTheme, "emoji is back to the default"), "/theme", "emoji", "off")
+	run(isResult(UpdatedTheme, "back to the default theme"), "/theme", "reset")
+	if _, ok := workspace[themeRecord]; ok {
+		t.Errorf("theme not removed after reset: %v", workspace)
+	}
+}
+
+func TestChannelConfig(t *testing.T) {
+	store := rndtest.Store{"lunch": {"three", "two", "one"}, "solo": {"one"}}
+	app := NewApp("randomizer", store, WithRandomizer(slices.Sort))
+	run := func(check validator, args ...string) Result {
+		t.Helper()
+		result, err := app.Main(context.Background(), args)
+		check(t, result, err)
+		return result
+	}
+
+	run(isResult(ShowedConfig, "default configuration"), "/config")
+	run(isResult(UpdatedConfig, "group is now lunch"), "/config", "group", "lunch")
+	run(isResult(Selection, "*one*, *three*, *two*"))
+	run(isResult(Selection, "*one*, *two*"), "-three")
+	run(isResult(UpdatedConfig, "pick is now 2"), "/config", "pick", "2")
+	run(isResult(Selection, "got: *one*, *three*."))
+	run(isResult(Selection, "got: *a*, *b*."), "a", "b", "c")
+	run(isResult(Selection, "got: *one*."), "+solo")
+	run(isResult(Selection, "*one*, *three*, *two*"), "+lunch", "--pick", "3")
+	run(isResult(UpdatedConfig, "phrasing is now Today's winners are {result}!"), "/config", "phrasing", "Today's", "winners", "are", "{result}!")
+	run(isResult(Selection, "Today's winners are *one*, *three*!"), "+lunch")
+	run(isError("needs to include {result}"), "/config", "phrasing", "Hello!")
+	run(isError("number of winners"), "/config", "pick", "zero")
+	run(isError("group, pick, phrasing, template, or visibility"), "/config", "color", "blue")
+	run(isError("need a value"), "/config", "visibility")
+
+	if run(isResult(Selection), "+lunch").Private() {
+		t.Errorf("selection private by default")
+	}
+	run(isResult(UpdatedConfig, "visibility is now private"), "/config", "visibility", "PRIVATE")
+	if !run(isResult(Selection), "+lunch").Private() {
+		t.Errorf("selection not private with private visibility")
+	}
+
+	run(isResult(ShowedConfig, "• group: lunch", "• pick: 2", "• phrasing: ", "• visibility: private"), "/config")
+	run(isResult(UpdatedConfig, "pick is back to the default"), "/config", "pick", "off")
+	run(isResult(Selection, "Today's winners are *one*, *three*, *two*!"), "+lunch")
+	run(isResult(UpdatedConfig, "back to the default configuration"), "/config", "reset")
+	if _, ok := store[configRecord]; ok {
+		t.Errorf("configuration not removed after reset: %v", store)
+	}
+	run(isResult(ShowedHelp))
+}
+
+func TestSettingsLayers(t *testing.T) {
+	var org Settings
+	if err := org.Set("pick", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := org.Set("color", "blue"); err == nil {
+		t.Error("Set() accepted an unknown setting")
+	}
+
+	store := rndtest.Store{"lunch": {"three", "two", "one"}}
+	workspace := rndtest.Store{}
+	newApp := func(user string) App {
+		return NewApp("randomizer", store,
+			WithRandomizer(slices.Sort),
+			WithWorkspaceStore(workspace),
+			WithUser(user),
+			WithDefaults(Defaults{Settings: org}))
+	}
+	alice, bob := newApp("U1"), newApp("U2")
+	run := func(app App, check validator, args ...string) {
+		t.Helper()
+		result, err := app.Main(context.Background(), args)
+		check(t, result, err)
+	}
+
+	run(alice, isResult(ShowedConfig, "• pick: 1 (set by your administrators)"), "/config")
+	run(alice, isResult(Selection, "got: *one*."), "+lunch")
+	run(alice, isResult(UpdatedConfig, "This workspace's pick is now 3"), "/config", "pick", "3", "--for", "workspace")
+	run(alice, isResult(UpdatedConfig, "This workspace's group is now lunch"), "/config", "group", "lunch", "--for", "workspace")
+	run(alice, isResult(UpdatedConfig, "This channel's pick is now 2"), "/config", "pick", "2")
+	run(alice, isResult(UpdatedConfig, "Your phrasing is now Me: {result}"), "/config", "phrasing", "Me:", "{result}", "--for", "me")
+	run(alice, isError("the channel, the workspace, or"), "/config", "pick", "4", "--for", "org")
+
+	run(alice, isResult(Selection, "Me: *one*, *three*"))
+	run(bob, isResult(Selection, "I randomized and got: *one*, *three*."))
+	run(alice, isResult(ShowedConfig,
+		"• group: lunch (set for this workspace)",
+		"• pick: 2 (set for this channel)",
+		"• phrasing: Me: {result} (set for you)",
+	), "/config")
+	run(bob, isResult(ShowedConfig, "Nothing is set for you"), "/config", "--for", "me")
+	run(alice, isResult(ShowedConfig, "Settings set for this workspace:", "• group: lunch", "• pick: 3"), "/config", "--for", "workspace")
+
+	run(alice, isResult(UpdatedConfig, "This channel is back to the default configuration"), "/config", "reset")
+	run(bob, isResult(Selection, "got: *one*, *three*, *two*."))
+	run(alice, isResult(UpdatedConfig, "You're back to the default configuration"), "/config", "reset", "--for", "me")
+
+	settings, err := LoadSettings(context.Background(), org, workspace, store, "U1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level, ok := settings.Source("pick"); !ok || level != LevelWorkspace {
+		t.Errorf("Source(pick) = %v, %v; want %v", level, ok, LevelWorkspace)
+	}
+	if got, want := settings.Effective(), (Settings{Group: "lunch", Pick: 3}); got != want {
+		t.Errorf("Effective() = %+v; want %+v", got, want)
+	}
+
+	channelOnly := NewApp("randomizer", store)
+	if _, err := channelOnly.Main(context.Background(), []string{"/config", "pick", "2", "--for", "workspace"}); err == nil {
+		t.Error("configured the workspace without a workspace store")
+	}
+}
+
+func TestResultTemplate(t *testing.T) {
+	store := rndtest.Store{"lunch": {"three", "two", "one"}}
+	app := NewApp("randomizer", store, WithRandomizer(slices.Sort), WithUser("U1"))
+	run := func(check validator, args ...string) Result {
+		t.Helper()
+		result, err := app.Main(context.Background(), args)
+		check(t, result, err)
+		return result
+	}
+
+	run(isError("only use fields of a result"), "/config", "template", "{{.Winner}}")
+	run(isError("only use fields of a result"), "/config", "template", "{{printf .Choice}}")
+	run(isError("only use fields of a result"), "/config", "template", "{{.Choice")
+
+	result := run(isResult(Selection, "I randomized and got: *one*, *three*, *two*."), "lunch")
+	if result.Template() != "" {
+		t.Errorf("got template %q for a channel without one", result.Template())
+	}
+
+	run(isResult(UpdatedConfig, "This channel's template is now"),
+		"/config", "template", "{{.Choice}}", "was", "chosen", "from", `{{.Group}}{{if .Picked}}`, `(of {{join .Choices ", "}}){{end}}!`)
+	result = run(isResult(Selection, "I randomized and got: *one*, *three*."), "/pick", "2", "lunch")
+	if got, want := result.Fields(), (ResultFields{
+		Choice:  "one",
+		Choices: []string{"one", "three"},
+		Group:   "lunch",
+		User:    "U1",
+		ID:      result.ID(),
+		Picked:  true,
+	}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Fields() = %+v; want %+v", got, want)
+	}
+
+	text, err := RenderResultTemplate(result.Template(), result.Fields())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("one was chosen from lunch (of one, three)!\n(Result ID: %s)", result.ID())
+	if got := result.Rephrased(text); got != want {
+		t.Errorf("Rephrased() = %q; want %q", got, want)
+	}
+}
+
+func TestExpiryOverTime(t *testing.T) {
+	clock := clocktest.New(testNow)
+	store := rndtest.Store{"test": {"one", "two"}}
+	app := NewApp("randomizer", store, WithRandomizer(slices.Sort), WithClock(clock))
+	run := func(check validator, args ...string) {
+		t.Helper()
+		result, err := app.Main(context.Background(), args)
+		check(t, result, err)
+	}
+
+	run(isResult(UpdatedExpiry, "90d"), "/expire", "test", "90d", "disable")
+	run(isResult(ConfirmedOptions), "/confirm-options", "test", "one", "two")
+
+	clock.Advance(60 * 24 * time.Hour)
+	run(isResult(Selection, "*one*, *two*"), "test")
+
+	// Picking "one" above keeps it active after the confirmation of "two" expires.
+	clock.Advance(60 * 24 * time.Hour)
+	run(isResult(Selection, "*one*", "I left out *two*"), "test")
+
+	clock.Advance(100 * 24 * time.Hour)
+	run(isError("every option in the \"test\" group has expired"), "test")
+}
+
+func TestAppOptions(t *testing.T) {
+	store := rndtest.Store{"test": {"one", "two"}}
+
+	app := NewApp("randomizer", store,
+		WithRandomizer(slices.Sort),
+		WithClock(clocktest.New(time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC))),
+		WithRenderer(strings.ToUpper))
+	result, err := app.Main(context.Background(), []string{"test"})
+	isResult(Selection, "*ONE*, *TWO*")(t, result, err)
+	if got := store[historyRecord("test")]; len(got) != 1 || !strings.HasPrefix(got[0], "2026-10-17T12:00:00.000000000Z|one ") {
+		t.Errorf("history not recorded with clock time: %v", got)
+	}
+
+	_, err = app.Main(context.Background(), []string{"/show", "missing"})
+	isError("WHOOPS")(t, Result{}, err)
+
+	store = rndtest.Store{"test": {"one", "two"}}
+	app = NewApp("randomizer", store, WithDryRun())
+	result, err = app.Main(context.Background(), []string{"/save", "test", "one", "three", "--dry-run=false"})
+	isResult(PreviewedChanges, "add three")(t, result, err)
+	if got := store["test"]; !slices.Equal(got, []string{"one", "two"}) {
+		t.Errorf("dry run app changed the group to %v", got)
+	}
+
+	app = NewApp("randomizer", store, WithHistory(false))
+	if _, err := app.Main(context.Background(), []string{"test"}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, ok := store[historyRecord("test")]; ok {
+		t.Error("history recorded with history disabled")
+	}
+	if _, ok := store[resultsRecord]; ok {
+		t.Error("result recorded with history disabled")
+	}
+}
+
+func TestContentCheck(t *testing.T) {
+	var unavailable bool
+	check := func(_ context.Context, texts []string) error {
+		if unavailable {
+			return errors.New("moderation service unavailable")
+		}
+		if slices.Contains(texts, "darn") {
+			return ContentRejection{Reason: "mind your language"}
+		}
+		return nil
+	}
+	store := rndtest.Store{"test": {"one", "two"}}
+	app := NewApp("randomizer", store, WithContentCheck(check))
+	ctx := context.Background()
+
+	_, err := app.Main(ctx, []string{"/save", "test", "one", "darn"})
+	isError("content policy (mind your language)")(t, Result{}, err)
+	_, err = app.Main(ctx, []string{"/save", "darn", "one", "two"})
+	isError("content policy")(t, Result{}, err)
+	_, err = app.Main(ctx, []string{"/tag", "test", "one", "#darn"})
+	isError("content policy")(t, Result{}, err)
+	if got := store["test"]; !slices.Equal(got, []string{"one", "two"}) {
+		t.Errorf("rejected content changed the group to %v", got)
+	}
+
+	result, err := app.Main(ctx, []string{"/save", "test", "one", "three"})
+	isResult(SavedGroup, "three")(t, result, err)
+
+	unavailable = true
+	_, err = app.Main(ctx, []string{"/save", "test", "one", "four"})
+	isError("couldn't check that")(t, Result{}, err)
+}
+
+func TestListPages(t *testing.T) {
+	store := rndtest.Store{onboardingRecord: {"done"}}
+	for i := range listPageSize + 5 {
+		store[fmt.Sprintf("group%03d", i)] = []string{"one"}
+	}
+	app := NewApp("/randomize", store)
+	ctx := context.Background()
+
+	result, err := app.Main(ctx, []string{"/list"})
+	isResult(ListedGroups, "• group000", "• group049", "/randomize /list --after group049")(t, result, err)
+	if strings.Contains(result.Message(), "group050") || result.More() != "/list --after group049" {
+		t.Fatalf("first page %q has the wrong groups, or more %q", result.Message(), result.More())
+	}
+
+	result, err = app.Main(ctx, append([]string{"/list"}, strings.Fields(result.More())[1:]...))
+	isResult(ListedGroups, `after "group049":`+"\n• group050", "• group054")(t, result, err)
+	if result.More() != "" || strings.Contains(result.Message(), "• group049") {
+		t.Errorf("last page %q has the wrong groups, or more %q", result.Message(), result.More())
+	}
+
+	result, err = app.Main(ctx, []string{"/list", "--after", "group054"})
+	isResult(ListedGroups, "no more groups")(t, result, err)
+}
+
+func TestVersionDeployment(t *testing.T) {
+	var features []string
+	app := NewApp("/randomize", make(rndtest.Store), WithDeployment(Deployment{
+		Store:    "dynamodb",
+		Features: func() []string { return features },
+	}))
+
+	result, err := app.Main(context.Background(), []string{"/version"})
+	isResult(ShowedVersion, "• Store: dynamodb", "• Features: none")(t, result, err)
+
+	features = []string{"beta-ui", "fast-path"}
+	result, err = app.Main(context.Background(), []string{"/version"})
+	isResult(ShowedVersion, "• Features: beta-ui, fast-path")(t, result, err)
+}
+
+func TestWebhook(t *testing.T) {
+	type delivery struct {
+		url     string
+		payload string
+	}
+	var (
+		store      = rndtest.Store{"lunch": {"pizza", "tacos"}}
+		deliveries []delivery
+		deferred   []func()
+	)
+	app := NewApp("/randomize", store,
+		WithRandomizer(slices.Sort),
+		WithClock(clocktest.New(testNow)),
+		WithUser("U1"),
+		WithWebhookSender(func(_ context.Context, url string, payload []byte) error {
+			deliveries = append(deliveries, delivery{url, string(payload)})
+			return nil
+		}),
+		WithDeferredWrites(func(write func()) { deferred = append(deferred, write) }),
+	)
+	run := func(check validator, args ...string) {
+		t.Helper()
+		result, err := app.Main(context.Background(), args)
+		check(t, result, err)
+	}
+
+	run(isResult(ShowedWebhook, "don't go to a webhook"), "/webhook")
+	run(isError("HTTPS URL"), "/webhook", "http://example.com/hook")
+	run(isError("can't use that template"), "/webhook", "https://example.com/hook", "{{.Loser}}")
+	run(isError("can't use that template"), "/webhook", "https://example.com/hook", "{{json")
+	run(isResult(UpdatedWebhook, "every selection in this channel to https://example.com/hook"),
+		"/webhook", "<https://example.com/hook>", `{"group":`, "{{json", ".Group}},", `"winner":`, "{{json", ".Winner}},", `"all":`, `"{{join`, ".Choices", `"&amp;"}}"}`)
+	run(isResult(ShowedWebhook, "https://example.com/hook", "{{json .Winner}}"), "/webhook")
+	if len(deliveries) != 0 {
+		t.Fatalf("configuring the webhook sent %v", deliveries)
+	}
+
+	run(isResult(Selection, "*pizza*"), "lunch")
+	run(isResult(ShowedGroup, "pizza"), "/show", "lunch")
+	run(isResult(Selection, "*pizza*"), "lunch", "--dry-run")
+	if len(deliveries) != 0 || len(deferred) != 1 {
+		t.Fatalf("got deliveries %v and %d deferred writes, want one deferred delivery", deliveries, len(deferred))
+	}
+	deferred[0]()
+	want := delivery{"https://example.com/hook", `{"group": "lunch", "winner": "pizza", "all": "pizza&tacos"}`}
+	if len(deliveries) != 1 || deliveries[0] != want {
+		t.Errorf("got deliveries %+v, want %+v", deliveries, want)
+	}
+
+	run(isResult(UpdatedWebhook, "every selection"), "/webhook", "https://example.com/hook")
+	run(isResult(RolledDice, ""), "/roll", "1d1")
+	deferred[1]()
+	var got WebhookResult
+	if err := json.Unmarshal([]byte(deliveries[1].payload), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != "RolledDice" || got.User != "U1" || got.Winner != "1" || !got.At.Equal(testNow) {
+		t.Errorf("got default payload %+v", got)
+	}
+
+	run(isResult(UpdatedWebhook, "won't go to a webhook"), "/webhook", "off")
+	run(isResult(Selection, ""), "lunch")
+	if len(deliveries) != 2 || len(deferred) != 2 {
+		t.Errorf("got %d deliveries after removing the webhook, want 2", len(deliveries))
+	}
+
+	run(isError("not supported"), "/webhook", "https://example.com/hook", "{{with", ".Group}}{{.}}{{end}}")
+	run(isError("no field .Loser"), "/webhook", "https://example.com/hook", "{{if", ".Group}}{{.Loser}}{{end}}")
+
+	unavailable := NewApp("/randomize", store)
+	result, err := unavailable.Main(context.Background(), []string{"/webhook"})
+	isError("aren't available")(t, result, err)
+}
+
+func TestCommandRegistry(t *testing.T) {
+	help := helpMessageTemplate()
+	for _, c := range commands {
+		if c.handler == nil {
+			t.Errorf("command %q has no handler", c.name)
+		}
+		if c.implicit {
+			continue
+		}
+		if c.name != "help" && !strings.Contains(help, "/"+c.name) {
+			t.Errorf("help message doesn't mention command %q", c.name)
+		}
+		if got, ok := lookupCommand("/" + c.name); !ok || got != c {
+			t.Errorf("lookupCommand(%q) = %v, want %v", "/"+c.name, got, c)
+		}
+	}
+	if implicitCommand == nil || !implicitCommand.implicit {
+		t.Error("no implicit command registered")
+	}
+}
+
+func TestWeightedOrder(t *testing.T) {
+	app := NewApp("randomizer", rndtest.Store{})
+	options := parseOptions([]string{"heavy #weight=3", "light"})
+
+	const trials = 4000
+	var heavyFirst int
+	for range trials {
+		if app.weightedOrder(options)[0] == "heavy" {
+			heavyFirst++
+		}
+	}
+	if ratio := float64(heavyFirst) / trials; ratio < 0.7 || ratio > 0.8 {
+		t.Errorf("heavy option picked first %.2f of the time, want about 0.75", ratio)
+	}
+}
+
+func TestDistributions(t *testing.T) {
+	testCases := []struct {
+		name   string
+		params map[string]float64
+		mean   float64
+	}{
+		{"uniform", map[string]float64{"min": 2, "max": 4}, 3},
+		{"normal", map[string]float64{"mean": 10, "stddev": 2}, 10},
+		{"exponential", map[string]float64{"mean": 5}, 5},
+		{"poisson", map[string]float64{"mean": 3}, 3},
+		{"poisson", map[string]float64{"mean": 500}, 500},
+	}
+	r := rand.New(rand.NewPCG(1, 2))
+	for _, tc := range testCases {
+		const trials = 4000
+		var sum float64
+		for range trials {
+			sum += distributions[tc.name].sample(r, tc.params)
+		}
+		if mean := sum / trials; math.Abs(mean-tc.mean) > 0.05*tc.mean {
+			t.Errorf("%s%v samples have mean %.2f, want about %g", tc.name, tc.params, mean, tc.mean)
+		}
+	}
+}
+
+func TestDice(t *testing.T) {
+	terms, err := parseDice([]string{"2d6-d4+3"})
+	if err != nil || formatDice(terms) != "2d6-1d4+3" {
+		t.Fatalf("parsed %v (err %v), want 2d6-1d4+3", terms, err)
+	}
+	seen := make(map[int]bool)
+	for range 2000 {
+		total := rollDice(rng, terms).total
+		if total < 2-4+3 || total > 12-1+3 {
+			t.Fatalf("rolled %d, outside 2d6-1d4+3", total)
+		}
+		seen[total] = true
+	}
+	if len(seen) != 14 {
+		t.Errorf("rolled %d distinct totals, want all 14", len(seen))
+	}
+
+	result, err := NewApp("/randomize", make(rndtest.Store)).Main(t.Context(),
+		[]string{"/range", "-9223372036854775808", "9223372036854775807"})
+	if err != nil || result.Type() != PickedNumbers {
+		t.Errorf("got %v (err %v) for the widest range", result, err)
+	}
+}
+
+func TestVariantRules(t *testing.T) {
+	testCases := []struct {
+		days, window string
+		at           time.Time
+		want         bool
+	}{
+		{"fri", "", time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC), true},
+		{"fri", "", time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC), false},
+		{"weekends", "", time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC), true},
+		{"fri-mon", "", time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC), true},
+		{"mon,wed", "", time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC), false},
+		{"weekdays", "11:00-14:00", time.Date(2026, 10, 16, 11, 0, 0, 0, time.UTC), true},
+		{"weekdays", "11:00-14:00", time.Date(2026, 10, 16, 14, 0, 0, 0, time.UTC), false},
+		{"fri", "22:00-02:00", time.Date(2026, 10, 17, 1, 0, 0, 0, time.UTC), true},
+		{"fri", "22:00-02:00", time.Date(2026, 10, 16, 1, 0, 0, 0, time.UTC), false},
+	}
+	for _, tc := range testCases {
+		rule, err := parseVariantRule(tc.days, tc.window, "variant")
+		if err != nil {
+			t.Fatalf("parseVariantRule(%q, %q) = %v", tc.days, tc.window, err)
+		}
+		if got := rule.matches(tc.at); got != tc.want {
+			t.Errorf("%s %s matches %v = %v, want %v", tc.days, tc.window, tc.at, got, tc.want)
+		}
+	}
+
+	store := rndtest.Store{
+		"lunch":           {"pizza"},
+		"friday-lunch":    {"tacos"},
+		"fast-lunch":      {"salad"},
+		"/variants/lunch": {"fri||friday-lunch", "daily|11:00-12:00|fast-lunch"},
+	}
+	for _, tc := range []struct {
+		at   time.Time
+		want string
+	}{
+		{time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC), "*tacos*"},
+		{time.Date(2026, 10, 16, 11, 30, 0, 0, time.UTC), "*salad*"},
+		{time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC), "*pizza*"},
+	} {
+		app := NewApp("randomizer", store, WithClock(clocktest.New(tc.at)))
+		result, err := app.Main(context.Background(), []string{"lunch"})
+		isResult(Selection, tc.want)(t, result, err)
 	}
 }
 
@@ -281,3 +2396,323 @@ func isError(contains string) validator {
 		}
 	}
 }
+
+func TestPayloadTemplate(t *testing.T) {
+	result := WebhookResult{
+		ID:      "k3x9qp",
+		Type:    "Selection",
+		Group:   "lunch",
+		Choices: []string{"pizza", `"tacos"`},
+		Winner:  "pizza",
+		At:      testNow,
+	}
+	for _, tc := range []struct {
+		template string
+		want     string
+	}{
+		{template: "{{.Winner}} won {{.Group}}", want: "pizza won lunch"},
+		{template: `{"winner": {{json .Winner}}, "all": {{.Choices | json}}}`, want: `{"winner": "pizza", "all": ["pizza","\"tacos\""]}`},
+		{template: `{{join .Choices ", "}} at {{.At}}`, want: `pizza, "tacos" at 2026-10-17T12:00:00Z`},
+		{template: `{{range .Choices}}- {{.}} ({{$.ID}}){{"\n"}}{{end}}`, want: "- pizza (k3x9qp)\n- \"tacos\" (k3x9qp)\n"},
+		{template: "{{if .User}}by {{.User}}{{else}}anonymous{{end}}, {{len .Choices}} options", want: "anonymous, 2 options"},
+		{template: "{{/* ignored */}}{{.}}", want: fmt.Sprint(result)},
+	} {
+		tmpl, err := parsePayloadTemplate(tc.template)
+		if err != nil {
+			t.Errorf("%q: %v", tc.template, err)
+			continue
+		}
+		got, err := tmpl.execute(result)
+		if err != nil || string(got) != tc.want {
+			t.Errorf("%q: got %q, %v; want %q", tc.template, got, err, tc.want)
+		}
+	}
+
+	for _, template := range []string{
+		"{{.Winner.Name}}",
+		"{{$x := .Winner}}{{$x}}",
+		`{{define "x"}}{{end}}`,
+		"{{printf .Winner}}",
+		"{{.Winner | .Group}}",
+		"{{range .Winner}}{{end}}",
+	} {
+		tmpl, err := parsePayloadTemplate(template)
+		if err == nil {
+			_, err = tmpl.execute(result)
+		}
+		if err == nil {
+			t.Errorf("%q: no error for an unsupported template", template)
+		}
+	}
+}
+
+func TestSeed(t *testing.T) {
+	store := rndtest.Store{"lunch": {"pizza", "tacos", "sushi", "salad", "soup"}}
+	app := NewApp("/randomize", store)
+	pick := func() string {
+		t.Helper()
+		result, err := app.Main(context.Background(), []string{"/pick", "2", "lunch"})
+		isResult(Selection, "seeded with 42")(t, result, err)
+		return strings.Join(result.Choices(), ",")
+	}
+
+	run := func(check validator, args ...string) {
+		t.Helper()
+		result, err := app.Main(context.Background(), args)
+		check(t, result, err)
+	}
+	run(isResult(ShowedSeed, "aren't seeded"), "/seed")
+	run(isError("whole number"), "/seed", "forty-two")
+	run(isResult(UpdatedSeed, "seeded with 42"), "/seed", "42")
+
+	first := []string{pick(), pick(), pick()}
+	run(isResult(ShowedSeed, "seeded with 42, and 3 have been made"), "/seed")
+	if first[0] == first[1] && first[1] == first[2] {
+		t.Errorf("seeded picks didn't advance: %v", first)
+	}
+	run(isResult(UpdatedSeed, "seeded with 42"), "/seed", "42")
+	if again := []string{pick(), pick(), pick()}; !slices.Equal(again, first) {
+		t.Errorf("got %v after resetting the seed, want %v", again, first)
+	}
+
+	run(isResult(UpdatedSeed, "random again"), "/seed", "off")
+	result, err := app.Main(context.Background(), []string{"lunch"})
+	if err != nil || strings.Contains(result.Message(), "seeded") {
+		t.Errorf("got %q, %v after removing the seed", result.Message(), err)
+	}
+}
+
+func TestRandomness(t *testing.T) {
+	store := rndtest.Store{"lunch": {"pizza", "tacos", "sushi", "salad", "soup"}}
+	roll := func(randomness Randomness) string {
+		app := NewApp("/randomize", store, WithRandomness(randomness))
+		result, err := app.Main(context.Background(), []string{"/roll", "4d20"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Message()
+	}
+	if roll(SeededRandomness(7)) != roll(SeededRandomness(7)) {
+		t.Error("rolls with the same seed differ")
+	}
+	if msg := roll(CryptoRandomness()); !strings.Contains(msg, "4d20") {
+		t.Errorf("got crypto roll %q", msg)
+	}
+
+	for name, want := range map[string]Randomness{"": rng, "math": rng, "crypto": cryptoRand} {
+		if got, err := RandomnessFromName(name); err != nil || got != want {
+			t.Errorf("RandomnessFromName(%q) = %v, %v", name, got, err)
+		}
+	}
+	if _, err := RandomnessFromName("seed:42"); err != nil {
+		t.Errorf("RandomnessFromName(seed:42): %v", err)
+	}
+	for _, name := range []string{"seed:", "seed:-1", "dice"} {
+		if _, err := RandomnessFromName(name); err == nil {
+			t.Errorf("RandomnessFromName(%q) succeeded", name)
+		}
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	var (
+		store     = rndtest.Store{"standup": {"alice", "bob", "carol"}}
+		schedules = rndtest.Store{}
+		clk       = clocktest.New(time.Date(2026, 10, 16, 14, 0, 0, 0, time.UTC)) // A Friday
+		app       = NewApp("/randomize", store, WithSchedules(schedules, "T1/C1"), WithClock(clk))
+		other     = NewApp("/randomize", store, WithSchedules(schedules, "T1/C2"), WithClock(clk))
+	)
+	run := func(app App, check validator, args ...string) {
+		t.Helper()
+		result, err := app.Main(context.Background(), args)
+		check(t, result, err)
+	}
+
+	run(NewApp("/randomize", store), isError("aren't available"), "/schedule")
+	run(app, isResult(ShowedSchedules, "doesn't have any schedules"), "/schedule")
+	run(app, isError("Try days like"), "/schedule", "fortnightly", "9am", "standup")
+	run(app, isError("Try days like"), "/schedule", "daily", "25:00", "standup")
+	run(app, isError("group"), "/schedule", "daily", "9am", "+missing")
+	run(app, isError("only schedule picks"), "/schedule", "daily", "9am", "/save", "lunch", "pizza", "tacos")
+	run(app, isResult(PreviewedChanges, "Pick from `standup` every day at 9:00 AM (UTC), starting", "Sat, Oct 17 at 09:00 UTC"),
+		"/schedule", "daily", "9am", "standup", "--dry-run")
+	if len(schedules) != 0 {
+		t.Fatalf("invalid schedules were saved: %v", schedules)
+	}
+
+	run(app, isResult(UpdatedSchedules, "every weekday at 9:00 AM (America/New_York)", "Mon, Oct 19 at 13:00 UTC"),
+		"/schedule", "weekdays", "9am", "America/New_York", "+standup")
+	run(app, isResult(UpdatedSchedules, "every Monday at 5:30 PM (UTC)"), "/schedule", "Mondays", "17:30", "standup")
+	run(other, isResult(UpdatedSchedules, "every day at 12:00 PM (UTC)", "Sat, Oct 17 at 12:00 UTC"), "/schedule", "daily", "12pm", "/roll", "d6")
+	run(app, isResult(ShowedSchedules, "`+standup` every weekday", "`standup` every Monday"), "/schedule")
+
+	due, err := DueSchedules(context.Background(), schedules, clk.Now())
+	if err != nil || len(due) != 0 {
+		t.Fatalf("DueSchedules() = %v, %v before any are due", due, err)
+	}
+	clk.Advance(25 * time.Hour)
+	due, err = DueSchedules(context.Background(), schedules, clk.Now())
+	if err != nil || len(due) != 1 || due[0].Target != "T1/C2" || !slices.Equal(due[0].Args, []string{"/roll", "d6"}) {
+		t.Fatalf("DueSchedules() = %+v, %v, want the daily roll", due, err)
+	}
+	if claimed, err := due[0].Claim(context.Background(), schedules, clk.Now()); !claimed || err != nil {
+		t.Fatalf("Claim() = %v, %v", claimed, err)
+	}
+	if claimed, err := due[0].Claim(context.Background(), schedules, clk.Now()); claimed || err != nil {
+		t.Errorf("second Claim() = %v, %v, want false", claimed, err)
+	}
+	if due, _ := DueSchedules(context.Background(), schedules, clk.Now()); len(due) != 0 {
+		t.Errorf("claimed schedule is still due: %+v", due)
+	}
+
+	run(app, isError("doesn't have a schedule"), "/schedule", "remove", due[0].ID)
+	run(other, isResult(PreviewedChanges, "Remove the schedule `"+due[0].ID+"`"), "/schedule", "remove", due[0].ID, "--dry-run")
+	run(other, isResult(UpdatedSchedules, "removed"), "/schedule", "remove", due[0].ID)
+	run(other, isResult(ShowedSchedules, "doesn't have any schedules"), "/schedule")
+}
+
+func TestCadence(t *testing.T) {
+	for _, tc := range []struct {
+		days, at, zone string
+		after, want    time.Time
+	}{
+		{
+			days: "daily", at: "9am",
+			after: time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC),
+			want:  time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			days: "weekdays", at: "12am",
+			after: time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC),
+			want:  time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			days: "tue,thursdays", at: "9:15pm",
+			after: time.Date(2026, 10, 20, 22, 0, 0, 0, time.UTC),
+			want:  time.Date(2026, 10, 22, 21, 15, 0, 0, time.UTC),
+		},
+		{
+			// Across the end of daylight saving time in New York.
+			days: "sunday", at: "9am", zone: "America/New_York",
+			after: time.Date(2026, 10, 30, 0, 0, 0, 0, time.UTC),
+			want:  time.Date(2026, 11, 1, 14, 0, 0, 0, time.UTC),
+		},
+	} {
+		c, err := parseCadence(tc.days, tc.at, tc.zone)
+		if err != nil {
+			t.Errorf("parseCadence(%q, %q, %q) failed: %v", tc.days, tc.at, tc.zone, err)
+			continue
+		}
+		if got := c.next(tc.after); !got.Equal(tc.want) {
+			t.Errorf("%q at %q: next after %v = %v, want %v", tc.days, tc.at, tc.after, got.UTC(), tc.want)
+		}
+	}
+}
+
+func TestFederation(t *testing.T) {
+	var (
+		federation = rndtest.Store{}
+		workspaceA = rndtest.Store{}
+		workspaceB = rndtest.Store{}
+		appA       = NewApp("/randomize", rndtest.Store{}, WithRandomizer(slices.Sort),
+			WithWorkspaceStore(workspaceA), WithFederation(federation, "T0A"))
+		appB = NewApp("/randomize", rndtest.Store{}, WithRandomizer(slices.Sort),
+			WithWorkspaceStore(workspaceB), WithFederation(federation, "T0B"))
+		appC = NewApp("/randomize", rndtest.Store{},
+			WithWorkspaceStore(rndtest.Store{}), WithFederation(federation, "T0C"))
+	)
+	run := func(app App, check validator, args ...string) Result {
+		t.Helper()
+		result, err := app.Main(context.Background(), args)
+		check(t, result, err)
+		return result
+	}
+
+	run(NewApp("/randomize", rndtest.Store{}), isError("isn't available"), "/federate")
+	run(appA, isResult(ShowedFederation, "doesn't share any groups"), "/federate")
+	run(appA, isError("ID of another workspace"), "/federate", "reviewers", "invite", "T0A")
+	run(appA, isError("ID of another workspace"), "/federate", "reviewers", "invite", "acme")
+
+	invite := run(appA, isResult(UpdatedFederation, "workspace T0B", "/federate accept"), "/federate", "reviewers", "invite", "T0B")
+	id := regexp.MustCompile(`accept (\S+)"`).FindStringSubmatch(invite.Message())[1]
+	run(appA, isError("this workspace already shares a group"), "/federate", "reviewers", "invite", "T0C")
+	run(appA, isError("isn't shared with another workspace"), "+reviewers")
+	run(appA, isResult(ShowedFederation, "waiting for workspace T0B"), "/federate", "reviewers")
+
+	run(appC, isError("couldn't find an invitation"), "/federate", "accept", id)
+	run(appB, isError("couldn't find an invitation"), "/federate", "accept", "bogus")
+	run(appB, isResult(PreviewedChanges), "/federate", "accept", id, "--dry-run")
+	run(appB, isResult(UpdatedFederation, "shared with workspace T0A"), "/federate", "accept", id, "partner-reviewers")
+	run(appB, isError("couldn't find an invitation"), "/federate", "accept", id)
+
+	run(appA, isError("nobody has joined"), "+reviewers")
+	run(appA, isResult(UpdatedFederation, "2 members"), "/federate", "reviewers", "add", "alice", "bob")
+	run(appB, isResult(UpdatedFederation, "1 member"), "/federate", "partner-reviewers", "add", "carol")
+	run(appB, isResult(UpdatedFederation, "1 member"), "/federate", "partner-reviewers", "remove", "alice")
+	run(appA, isResult(Selection, "*alice*", "*bob*", "*carol*"), "+reviewers", "/3")
+	run(appB, isResult(Selection, "*alice*", "*bob*", "*carol*"), "+partner-reviewers", "/3")
+	run(appB, isResult(ShowedFederation, "between workspaces T0A and T0B", "from workspace T0A", "alice", "bob", "from workspace T0B", "carol"),
+		"/federate", "partner-reviewers")
+
+	run(appB, isResult(UpdatedFederation, "neither workspace"), "/federate", "partner-reviewers", "leave")
+	run(appB, isResult(ShowedFederation, "doesn't share any groups"), "/federate")
+	run(appA, isError("isn't shared with another workspace"), "+reviewers")
+	run(appA, isResult(ShowedFederation, "other workspace left"), "/federate", "reviewers")
+	run(appA, isResult(UpdatedFederation, "neither workspace"), "/federate", "reviewers", "leave")
+	if len(federation) != 0 {
+		t.Errorf("federated groups remain after both workspaces left: %v", federation)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	var (
+		workspace = rndtest.Store{}
+		clk       = clocktest.New(time.Date(2026, 10, 16, 14, 0, 0, 0, time.UTC))
+		sunk      []AuditEntry
+		sink      = func(_ context.Context, e AuditEntry) error { sunk = append(sunk, e); return nil }
+	)
+	newApp := func(channel, user string) App {
+		return NewApp("/randomize", rndtest.Store{}, WithClock(clk), WithUser(user), WithWorkspaceStore(workspace),
+			WithAudit(Audit{Team: "T1", Channel: channel, Sink: sink, Admins: []string{"UADMIN"}}))
+	}
+	run := func(app App, check validator, args ...string) {
+		t.Helper()
+		result, err := app.Main(context.Background(), args)
+		check(t, result, err)
+	}
+
+	alice, bob, admin := newApp("C1", "UALICE"), newApp("C2", "UBOB"), newApp("C1", "UADMIN")
+	run(NewApp("/randomize", rndtest.Store{}), isError("isn't available"), "/audit")
+	run(alice, isError("only admins"), "/audit")
+	run(admin, isResult(ShowedAudit, "haven't recorded any changes in this workspace"), "/audit")
+
+	run(alice, isResult(SavedGroup), "/save", "lunch", "pizza", "tacos")
+	run(alice, isResult(Selection), "lunch")
+	run(alice, isResult(PreviewedChanges), "/delete", "lunch", "--dry-run")
+	clk.Advance(time.Minute)
+	run(bob, isResult(SavedGroup), "/save", "snacks", "chips", "pretzels")
+	clk.Advance(time.Minute)
+	run(alice, isResult(DeletedGroup), "/delete", "lunch")
+
+	run(admin, isResult(ShowedAudit, "last 3 changes in this workspace",
+		"<@UALICE> ran `/randomize /delete lunch` in <#C1>",
+		"<@UBOB> ran `/randomize /save snacks chips pretzels` in <#C2>",
+		"<@UALICE> ran `/randomize /save lunch pizza tacos` in <#C1>"),
+		"/audit")
+	run(admin, isResult(ShowedAudit, "last 2 changes to \"lunch\"", "/delete lunch", "/save lunch"), "/audit", "lunch")
+	run(admin, isResult(ShowedAudit, "last 1 change", "/delete lunch"), "/audit", "--last", "1")
+	run(admin, isError("number of changes"), "/audit", "--last", "zero")
+
+	operator := NewApp("/randomize", rndtest.Store{}, WithClock(clk), WithUser("UBOB"), WithWorkspaceStore(workspace),
+		WithAudit(Audit{Team: "T1", Channel: "C2", Admins: []string{"UADMIN"}}), WithOperator("static token"))
+	run(operator, isResult(ListedGroups, "Operator static token ran this as <@UBOB>"), "/list")
+	run(admin, isResult(ShowedAudit, "Operator static_token (as <@UBOB>) ran `/randomize /list` in <#C2>"), "/audit", "--last", "1")
+
+	want := AuditEntry{
+		Time: clk.Now(), Team: "T1", Channel: "C1", User: "UALICE",
+		Operation: "delete", Target: "lunch", Result: "DeletedGroup",
+	}
+	if len(sunk) != 3 || !reflect.DeepEqual(sunk[2], want) {
+		t.Errorf("sink got %+v, want 3 entries ending with %+v", sunk, want)
+	}
+}
@@ -0,0 +1,112 @@
+package randomizer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "assign",
+		permission: permRead,
+		handler:    App.assignTasks,
+		section:    helpRules,
+		help: []string{
+			"*Assign people to tasks:* {{.Name}} /save chores dishes laundry#capacity=2",
+			"&gt; {{.Name}} /assign chores alice bob carol",
+		},
+	})
+}
+
+// capacityAttr is the option attribute that sets how many people a task
+// needs in an assignment, e.g. "triage#capacity=2". Tasks without it need
+// one person.
+const capacityAttr = "capacity"
+
+func (a App) assignTasks(request request) (Result, error) {
+	var (
+		ctx       = request.Context
+		taskGroup = groupReference(request.Operand)
+	)
+
+	if len(request.Args) == 0 {
+		return Result{}, Error{
+			cause: errors.New("no people to assign"),
+			helpText: fmt.Sprintf(
+				`Whoops, I need a group of tasks followed by the people to assign them to! (Type "%s help" to see an example.)`,
+				a.name,
+			),
+		}
+	}
+
+	tasks, err := a.expandGroup(ctx, taskGroup)
+	if err != nil {
+		return Result{}, err
+	}
+	people, err := a.expandArgs(ctx, request.Args)
+	if err != nil {
+		return Result{}, err
+	}
+
+	capacities := make([]int, len(tasks))
+	demand := 0
+	for i, task := range tasks {
+		capacities[i], err = taskCapacity(task)
+		if err != nil {
+			return Result{}, err
+		}
+		demand += capacities[i]
+	}
+
+	if supply := len(people); supply != demand {
+		return Result{}, Error{
+			cause: fmt.Errorf("%d people for %d slots", supply, demand),
+			helpText: fmt.Sprintf(
+				"Whoops, the tasks in the %q group need %s, but I have %s to assign! (Adjust the tasks' capacity tags or the list of people so they match.)",
+				taskGroup, pluralize(demand, "person", "people"), pluralize(supply, "person", "people"),
+			),
+		}
+	}
+
+	names := optionNames(people)
+	a.shuffle(names)
+
+	lines := make([]string, len(tasks))
+	for i, task := range tasks {
+		assigned := names[:capacities[i]]
+		names = names[capacities[i]:]
+		lines[i] = fmt.Sprintf("%s: %s", task.Name, inlinelist(assigned))
+	}
+
+	return Result{
+		resultType: Assignment,
+		message:    fmt.Sprintf("Here are the assignments:\n%s", bulletlist(lines)),
+	}, nil
+}
+
+func taskCapacity(task option) (int, error) {
+	value, ok := task.Attr(capacityAttr)
+	if !ok {
+		return 1, nil
+	}
+
+	capacity, err := strconv.Atoi(value)
+	if err != nil || capacity < 1 {
+		return 0, Error{
+			cause: fmt.Errorf("invalid capacity %q for %q", value, task.Name),
+			helpText: fmt.Sprintf(
+				"Whoops, the capacity for %q needs to be a positive whole number, not %q!",
+				task.Name, value,
+			),
+		}
+	}
+	return capacity, nil
+}
+
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return fmt.Sprintf("%d %s", n, plural)
+}
@@ -0,0 +1,283 @@
+package randomizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "audit",
+		operand:    operandOptional,
+		permission: permRead,
+		flags:      []string{"last"},
+		handler:    App.showAudit,
+		section:    helpWorkspace,
+		help: []string{
+			"*See who recently changed groups and settings (admins only):* {{.Name}} /audit",
+			"*See who changed a group:* {{.Name}} /audit snacks --last 50",
+		},
+	})
+}
+
+// The audit trail records every change that an App makes to groups and
+// settings, so that teams sharing a randomizer can find out who changed a
+// group, from which channel, and when. It lives in the workspace store when
+// the App has one, so that /audit covers every channel in the workspace, or
+// in the channel's store otherwise. Dry runs and read-only operations aren't
+// recorded, except that every operation an operator runs on a user's behalf
+// is.
+//
+// The trail in the store only reaches back a few hundred changes. For
+// long-term retention, the App also sends each entry to the [AuditSink] given
+// to [WithAudit], like a log stream or a database table.
+
+// auditRecord holds the audit trail. Each entry is a space-separated UTC
+// timestamp, channel and user (each "-" if unknown), operation, target (or "-"),
+// and the arguments of the request. If an operator made the change, the user
+// is followed by "|" and the operator. Arguments never contain spaces, and the
+// timestamp prefix keeps entries chronological.
+const auditRecord = recordPrefix + "audit"
+
+// maxAudit is the number of changes kept in the audit trail.
+const maxAudit = 500
+
+// defaultAuditLength is the number of changes that /audit shows without
+// --last.
+const defaultAuditLength = 20
+
+// AuditEntry describes a single change to groups or settings.
+type AuditEntry struct {
+	Time    time.Time `json:"time"`
+	Team    string    `json:"team_id,omitempty"`
+	Channel string    `json:"channel_id"`
+	User    string    `json:"user_id,omitempty"`
+	// Operator identifies the operator who made the change on the user's
+	// behalf, if any. See [WithOperator].
+	Operator string `json:"operator,omitempty"`
+	// Operation is the operation that made the change, like "save" or
+	// "delete", and Target is its operand, usually the name of a group.
+	Operation string   `json:"operation"`
+	Target    string   `json:"target,omitempty"`
+	Args      []string `json:"args,omitempty"`
+	// Result is the type of the randomizer's result, like "SavedGroup".
+	Result string `json:"result"`
+}
+
+// AuditSink receives each entry in the audit trail as the App records it.
+// Errors are logged, and never fail the change.
+type AuditSink func(ctx context.Context, entry AuditEntry) error
+
+// Audit configures the audit trail for [WithAudit].
+type Audit struct {
+	// Team and Channel identify where requests come from.
+	Team    string
+	Channel string
+	// Sink, if non-nil, receives each entry in addition to the store.
+	Sink AuditSink
+	// Admins lists the users who may run /audit. If empty, nobody can.
+	Admins []string
+}
+
+// WithAudit records every change that the App makes in an audit trail. See
+// [Audit].
+func WithAudit(audit Audit) AppOption {
+	return func(a *App) { a.audit = &audit }
+}
+
+// WithOperator marks every request to the App as made by an operator on the
+// user's behalf, like an operator reproducing a user's problem through the
+// admin API. The audit trail records the operator along with the user, and
+// each result opens with a notice that an operator ran it.
+func WithOperator(operator string) AppOption {
+	return func(a *App) { a.operator = operator }
+}
+
+// operatorBanner returns the notice that opens results from an operator.
+func (a App) operatorBanner() string {
+	user := "a user"
+	if a.user != "" {
+		user = "<@" + a.user + ">"
+	}
+	return fmt.Sprintf(":detective: _Operator %s ran this as %s._\n\n", a.operator, user)
+}
+
+func (e AuditEntry) entry() string {
+	user := orDash(e.User)
+	if e.Operator != "" {
+		// Operators are named by their credentials, like "static token".
+		user += "|" + strings.Join(strings.Fields(e.Operator), "_")
+	}
+	fields := []string{e.Time.UTC().Format(historyTimeFormat), orDash(e.Channel), user, e.Operation, orDash(e.Target)}
+	return strings.Join(append(fields, e.Args...), " ")
+}
+
+func parseAuditEntry(entry string) (AuditEntry, bool) {
+	fields := strings.Fields(entry)
+	if len(fields) < 5 {
+		return AuditEntry{}, false
+	}
+	at, err := time.Parse(historyTimeFormat, fields[0])
+	if err != nil {
+		return AuditEntry{}, false
+	}
+	user, operator, _ := strings.Cut(fields[2], "|")
+	return AuditEntry{
+		Time:      at,
+		Channel:   fromDash(fields[1]),
+		User:      fromDash(user),
+		Operator:  operator,
+		Operation: fields[3],
+		Target:    fromDash(fields[4]),
+		Args:      fields[5:],
+	}, true
+}
+
+// auditStore returns the store that holds the audit trail.
+func (a App) auditStore() Store {
+	if a.workspace != nil {
+		return a.workspace
+	}
+	return a.store
+}
+
+// recordAudit adds a change to the audit trail and sends it to the sink. Like
+// selection history, the trail is never critical to the request itself, so it
+// logs and gives up on any error.
+func (a App) recordAudit(ctx context.Context, request request, result Result) {
+	if a.audit == nil {
+		return
+	}
+
+	e := AuditEntry{
+		Time:      a.now().UTC(),
+		Team:      a.audit.Team,
+		Channel:   a.audit.Channel,
+		User:      a.user,
+		Operator:  a.operator,
+		Operation: request.Command.name,
+		Target:    request.Operand,
+		Result:    result.Type().String(),
+	}
+	if len(request.Args) > 0 {
+		e.Args = slices.Clone(request.Args)
+	}
+	_, err := a.writeNonCritical(ctx, "audit trail", func(ctx context.Context) error {
+		return Update(ctx, a.auditStore(), auditRecord, func(entries []string) ([]string, error) {
+			slices.Sort(entries)
+			if len(entries) >= maxAudit {
+				entries = entries[len(entries)-maxAudit+1:]
+			}
+			return append(entries, e.entry()), nil
+		})
+	})
+	if err != nil {
+		a.logger.Warn("Failed to record change in audit trail", "operation", e.Operation, "err", err)
+	}
+
+	if a.audit.Sink != nil {
+		if err := a.audit.Sink(ctx, e); err != nil {
+			a.logger.Warn("Failed to send change to audit sink", "operation", e.Operation, "err", err)
+		}
+	}
+}
+
+// showAudit lists the most recent changes in the audit trail, optionally only
+// those to a single group.
+func (a App) showAudit(request request) (Result, error) {
+	var (
+		ctx    = request.Context
+		target = request.Operand
+	)
+
+	if a.audit == nil {
+		return Result{}, Error{
+			cause:    errors.New("no audit trail"),
+			helpText: "Whoops, the audit trail isn't available here!",
+		}
+	}
+	if !slices.Contains(a.audit.Admins, a.user) {
+		return Result{}, Error{
+			cause:    fmt.Errorf("user %q isn't an audit admin", a.user),
+			helpText: "Whoops, only admins can see the audit trail!",
+		}
+	}
+
+	n := defaultAuditLength
+	if value, ok := request.Flags.Value("last"); ok {
+		var err error
+		if n, err = strconv.Atoi(value); err != nil || n < 1 {
+			return Result{}, Error{
+				cause:    fmt.Errorf("invalid audit length %q", value),
+				helpText: fmt.Sprintf(`Whoops, I need a number of changes to show, like "%s /audit --last 50"!`, a.name),
+			}
+		}
+	}
+
+	entries, err := a.auditStore().Get(ctx, auditRecord)
+	if err != nil {
+		return Result{}, a.storeError(err, "getting the audit trail")
+	}
+	slices.Sort(entries)
+
+	var lines []string
+	for _, raw := range slices.Backward(entries) {
+		if len(lines) == n {
+			break
+		}
+		e, ok := parseAuditEntry(raw)
+		if !ok || (target != "" && e.Target != target) {
+			continue
+		}
+		lines = append(lines, e.line(a.name))
+	}
+
+	scope := "in this workspace"
+	if target != "" {
+		scope = fmt.Sprintf("to %q", target)
+	}
+	if len(lines) == 0 {
+		return Result{
+			resultType: ShowedAudit,
+			message:    fmt.Sprintf("I haven't recorded any changes %s yet.", scope),
+		}, nil
+	}
+	return Result{
+		resultType: ShowedAudit,
+		message: fmt.Sprintf(
+			"Here %s the last %d %s %s, newest first:\n%s",
+			pluralVerb(len(lines), "is", "are"), len(lines), pluralVerb(len(lines), "change", "changes"), scope, bulletlist(lines),
+		),
+	}, nil
+}
+
+func (e AuditEntry) line(name string) string {
+	who := "Someone"
+	if e.User != "" {
+		who = "<@" + e.User + ">"
+	}
+	switch {
+	case e.Operator != "" && e.User != "":
+		who = "Operator " + e.Operator + " (as " + who + ")"
+	case e.Operator != "":
+		who = "Operator " + e.Operator
+	}
+	command := strings.Join(slices.Concat([]string{name, "/" + e.Operation}, nonEmpty(e.Target), e.Args), " ")
+	line := fmt.Sprintf("%s: %s ran `%s`", slackDate(e.Time, false), who, command)
+	if e.Channel != "" {
+		line += " in <#" + e.Channel + ">"
+	}
+	return line
+}
+
+func nonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
@@ -0,0 +1,48 @@
+package randomizer
+
+import (
+	"context"
+	"sync"
+)
+
+// maxBatchConcurrency limits how many selections SelectGroups makes at once,
+// to keep a large batch from overwhelming the store.
+const maxBatchConcurrency = 8
+
+// GroupSelection is the outcome of one selection made by [App.SelectGroups].
+// Exactly one of Result and Err is meaningful.
+type GroupSelection struct {
+	Group  string
+	Result Result
+	Err    error
+}
+
+// SelectGroups makes a separate selection from each of the named groups, as
+// if each were requested on its own, and returns the outcomes in the same
+// order as the groups. A failure to select from one group doesn't affect the
+// others.
+//
+// Selections run concurrently, so the App's Store must support concurrent use.
+func (a App) SelectGroups(ctx context.Context, groups []string) []GroupSelection {
+	ctx, span := tracer.Start(ctx, "randomizer.SelectGroups")
+	defer span.End()
+
+	var (
+		wg         sync.WaitGroup
+		selections = make([]GroupSelection, len(groups))
+		sem        = make(chan struct{}, maxBatchConcurrency)
+	)
+	for i, group := range groups {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			// The "+" prefix ensures that every name is treated as a group, even
+			// one like "help" that would otherwise mean something else.
+			result, err := a.Main(ctx, []string{"+" + group})
+			selections[i] = GroupSelection{Group: group, Result: result, Err: err}
+		})
+	}
+	wg.Wait()
+	return selections
+}
@@ -0,0 +1,188 @@
+package randomizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "cap",
+		permission: permWrite,
+		handler:    App.setPickCap,
+		section:    helpRules,
+		help:       []string{"*Pick no one more than twice a week:* {{.Name}} /cap snacks 2 1w"},
+	})
+}
+
+// A pick cap keeps selections from a group fair over time, like "no one leads
+// standup more than twice a week." When a selection draws an option that has
+// already won the maximum number of times within the cap's period, it draws
+// again from the remaining options, and says so in the result.
+//
+// Caps count wins from the group's history, so they have no effect when
+// history is disabled.
+
+// capRecord returns the name of the record holding a group's pick cap, with a
+// single entry of the form "<max>|<period>".
+func capRecord(group string) string {
+	return recordPrefix + "cap/" + group
+}
+
+type pickCap struct {
+	Max    int
+	Period string // As typed by the user, e.g. "1w"
+	Window time.Duration
+}
+
+func parsePickCap(max, period string) (pickCap, error) {
+	n, err := strconv.Atoi(max)
+	if err != nil || n < 1 {
+		return pickCap{}, fmt.Errorf("invalid maximum %q", max)
+	}
+	window, err := parseAge(period)
+	if err != nil {
+		return pickCap{}, err
+	}
+	return pickCap{Max: n, Period: period, Window: window}, nil
+}
+
+func (c pickCap) String() string {
+	return fmt.Sprintf("%d %s per %s", c.Max, pluralVerb(c.Max, "time", "times"), c.Period)
+}
+
+// getPickCap returns a group's pick cap, or nil if it has none.
+func (a App) getPickCap(ctx context.Context, group string) (*pickCap, error) {
+	entries, err := a.store.Get(ctx, capRecord(group))
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	max, period, _ := strings.Cut(entries[0], "|")
+	c, err := parsePickCap(max, period)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// applyPickCap re-draws a shuffled selection from a group until its first n
+// winners are under the group's pick cap, if it has one. It returns the
+// remaining choices, and a note about any options that were passed over.
+func (a App) applyPickCap(ctx context.Context, group string, choices []string, n int) ([]string, string, error) {
+	c, err := a.getPickCap(ctx, group)
+	if err != nil || c == nil {
+		// Like expiry, the cap shouldn't block a selection if it can't be
+		// checked.
+		return choices, "", nil
+	}
+	raw, err := a.store.Get(ctx, historyRecord(group))
+	if err != nil {
+		return choices, "", nil
+	}
+
+	since := a.now().Add(-c.Window)
+	wins := make(map[string]int)
+	for _, entry := range parseHistory(raw) {
+		if entry.Time.After(since) {
+			wins[entry.Winner]++
+		}
+	}
+
+	var kept, skipped []string
+	for _, choice := range choices {
+		if len(kept) < n && wins[choice] >= c.Max {
+			skipped = append(skipped, choice)
+			continue
+		}
+		kept = append(kept, choice)
+	}
+	if len(skipped) == 0 {
+		return choices, "", nil
+	}
+	if len(kept) < n {
+		return nil, "", Error{
+			cause: fmt.Errorf("all options in group %q are at their cap", group),
+			helpText: fmt.Sprintf(
+				"Whoops, every option in the %q group has already been picked %s! (To lift the cap, use: %s /cap %s off)",
+				group, c, a.name, group,
+			),
+		}
+	}
+	return kept, fmt.Sprintf(
+		"\n:scales: I drew again, since %s %s already been picked %s.",
+		inlinelist(skipped), pluralVerb(len(skipped), "has", "have"), c,
+	), nil
+}
+
+func (a App) setPickCap(request request) (Result, error) {
+	var (
+		ctx   = request.Context
+		group = request.Operand
+		args  = request.Args
+	)
+
+	switch {
+	case len(args) == 0:
+		c, err := a.getPickCap(ctx, group)
+		if err != nil {
+			return Result{}, a.storeError(err, "getting that group's cap")
+		}
+		message := fmt.Sprintf("The %q group doesn't have a cap on how often options are picked.", group)
+		if c != nil {
+			message = fmt.Sprintf("Options in the %q group can be picked at most %s.", group, c)
+		}
+		return Result{resultType: UpdatedCap, message: message}, nil
+
+	case len(args) == 1 && strings.EqualFold(args[0], "off"):
+		if _, err := a.store.Delete(ctx, capRecord(group)); err != nil {
+			return Result{}, a.storeError(err, "updating that group's cap")
+		}
+		return Result{
+			resultType: UpdatedCap,
+			message:    fmt.Sprintf("Done! Options in the %q group can be picked any number of times.", group),
+		}, nil
+
+	case len(args) != 2:
+		return Result{}, Error{
+			cause: errors.New("wrong number of arguments to set cap"),
+			helpText: fmt.Sprintf(
+				`Whoops, I need the most times an option can be picked, followed by a period like "1w" or "30d"! (Type "%s help" to see an example.)`,
+				a.name,
+			),
+		}
+	}
+
+	c, err := parsePickCap(args[0], args[1])
+	if err != nil {
+		return Result{}, Error{
+			cause:    err,
+			helpText: `Whoops, I need a number of picks like "2", followed by a period like "7d", "1w", or "1mo"!`,
+		}
+	}
+
+	stored, err := a.getGroup(ctx, group)
+	if err != nil {
+		return Result{}, a.storeError(err, "getting that group")
+	}
+	if len(stored) == 0 {
+		return Result{}, Error{
+			cause:    errors.New("group does not exist"),
+			helpText: "Whoops, I can't find that group in this channel. (Use the /save flag to create it!)",
+		}
+	}
+
+	if err := a.store.Put(ctx, capRecord(group), []string{strconv.Itoa(c.Max) + "|" + c.Period}); err != nil {
+		return Result{}, a.storeError(err, "updating that group's cap")
+	}
+	return Result{
+		resultType: UpdatedCap,
+		message: fmt.Sprintf(
+			"Done! Options in the %q group can now be picked at most %s. When one hits the cap, I'll draw again.",
+			group, c,
+		),
+	}, nil
+}
@@ -0,0 +1,141 @@
+package randomizer
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// command describes an operation that users can request, like "/save".
+//
+// Each operation registers its command from the file that implements it, so
+// adding an operation doesn't require changes anywhere else. The registry
+// drives argument parsing, the flag spellings of operations, and the help
+// message.
+type command struct {
+	// name spells the operation as "/name", or as "--name" when it leads the
+	// arguments.
+	name string
+	// aliases are additional names that spell the same operation.
+	aliases []string
+	// operand sets whether the operation requires the argument that follows its
+	// name, which is usually the name of a group.
+	operand operandKind
+	// implicit marks the operation that handles requests without any other
+	// operation, which users don't name directly.
+	implicit bool
+	// exclusions lets arguments like "-alice" stand for "--exclude alice", for
+	// operations that draw from options.
+	exclusions bool
+	// flags lists the long flags that the operation uses, besides "--dry-run",
+	// which every operation accepts. Requests with other flags are rejected,
+	// rather than silently doing something other than what the user meant.
+	flags []string
+	// permission describes the strongest change that the operation can make,
+	// for frontends that restrict or audit changes.
+	permission permission
+	// handler performs the operation.
+	handler appHandler
+
+	// help lists examples of the operation for the help message, each of the
+	// form "*Description:* {{.Name}} example". Lines starting with "&gt; "
+	// continue the example before them.
+	help []string
+	// section groups the operation with related ones in the help message.
+	section helpSection
+}
+
+type appHandler func(App, request) (Result, error)
+
+type operandKind int
+
+const (
+	operandRequired operandKind = iota
+	operandOptional
+	operandNone
+)
+
+type permission int
+
+const (
+	// permRead operations only read the channel's data.
+	permRead permission = iota
+	// permWrite operations may change the channel's groups or settings.
+	permWrite
+	// permWorkspace operations may change settings for the entire workspace.
+	permWorkspace
+)
+
+func (p permission) String() string {
+	switch p {
+	case permRead:
+		return "read"
+	case permWrite:
+		return "write"
+	case permWorkspace:
+		return "workspace"
+	}
+	return fmt.Sprintf("permission(%d)", int(p))
+}
+
+// helpSection orders the groups of related operations in the help message.
+// Within a section, operations are ordered by name.
+type helpSection int
+
+const (
+	helpBasics helpSection = iota
+	helpGroups
+	helpOptions
+	helpRules
+	helpHistory
+	helpWorkspace
+)
+
+var (
+	// commands lists every registered command, in no particular order.
+	commands []*command
+	// commandNames maps each name and alias of a command to the command.
+	commandNames = make(map[string]*command)
+	// implicitCommand handles requests that don't name another command.
+	implicitCommand *command
+)
+
+// registerCommand adds a command to the registry. It panics on a duplicate
+// name, since that would make one of the commands unreachable.
+func registerCommand(c *command) {
+	commands = append(commands, c)
+	if c.implicit {
+		if implicitCommand != nil {
+			panic(fmt.Sprintf("randomizer: commands %q and %q are both implicit", implicitCommand.name, c.name))
+		}
+		implicitCommand = c
+		return
+	}
+	for _, name := range append([]string{c.name}, c.aliases...) {
+		if _, ok := commandNames[name]; ok {
+			panic(fmt.Sprintf("randomizer: command %q registered twice", name))
+		}
+		commandNames[name] = c
+	}
+}
+
+// lookupCommand returns the command named by an argument like "/save".
+func lookupCommand(arg string) (*command, bool) {
+	name, ok := strings.CutPrefix(arg, "/")
+	if !ok {
+		return nil, false
+	}
+	c, ok := commandNames[name]
+	return c, ok
+}
+
+// helpCommands returns the commands with help text, in the order of the help
+// message.
+func helpCommands() []*command {
+	sorted := slices.Clone(commands)
+	slices.SortFunc(sorted, func(x, y *command) int {
+		return cmp.Or(cmp.Compare(x.section, y.section), strings.Compare(x.name, y.name))
+	})
+	return slices.DeleteFunc(sorted, func(c *command) bool { return len(c.help) == 0 })
+}
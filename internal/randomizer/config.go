@@ -0,0 +1,225 @@
+package randomizer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "config",
+		operand:    operandOptional,
+		permission: permWorkspace,
+		flags:      []string{"for"},
+		handler:    App.configure,
+		section:    helpRules,
+		help: []string{
+			"*Pick from a group when no options are given:* {{.Name}} /config group lunch",
+			"*Pick 2 winners unless told otherwise:* {{.Name}} /config pick 2",
+			"*Phrase results your way:* {{.Name}} /config phrasing Today's winner is {result}!",
+			"*Or format them with a template:* {{.Name}} /config template :game_die: {{.Choice}} has been chosen from {{.Group}}!",
+			"*Show results only to the person who asked:* {{.Name}} /config visibility private",
+			"*Change the whole workspace, or only yourself:* {{.Name}} /config pick 2 --for workspace",
+			"&gt; {{.Name}} /config visibility private --for me",
+		},
+	})
+}
+
+// /config changes the channel's settings by default. The --for flag chooses
+// another level that users can change, and shows only that level's own
+// settings when given without a setting.
+
+// configScope describes a level of settings that /config can change.
+type configScope struct {
+	level   SettingsLevel
+	owner   string // possessive, as in "This channel's pick"
+	subject string // as in "This channel is back to the default configuration"
+}
+
+var configScopes = map[string]configScope{
+	"channel":   {LevelChannel, "This channel's", "This channel is"},
+	"workspace": {LevelWorkspace, "This workspace's", "This workspace is"},
+	"me":        {LevelUser, "Your", "You're"},
+	"user":      {LevelUser, "Your", "You're"},
+}
+
+// levelSources describe where each level's settings came from, for the
+// effective configuration.
+var levelSources = [settingsLevels]string{
+	LevelOrg:       "set by your administrators",
+	LevelWorkspace: "set for this workspace",
+	LevelChannel:   "set for this channel",
+	LevelUser:      "set for you",
+}
+
+// configTarget returns the store and record holding the settings of a scope.
+func (a App) configTarget(scope configScope) (Store, string, error) {
+	switch {
+	case scope.level == LevelChannel:
+		return a.store, configRecord, nil
+	case a.workspace == nil:
+		return nil, "", Error{
+			cause:    errors.New("no workspace store"),
+			helpText: "Whoops, I can only configure this channel here!",
+		}
+	case scope.level == LevelWorkspace:
+		return a.workspace, configRecord, nil
+	case a.user == "":
+		return nil, "", Error{
+			cause:    errors.New("no user"),
+			helpText: "Whoops, I don't know who you are, so I can only configure this channel!",
+		}
+	default:
+		return a.workspace, userConfigRecord(a.user), nil
+	}
+}
+
+func (a App) configure(request request) (Result, error) {
+	ctx := request.Context
+
+	scopeName, scoped := request.Flags.Value("for")
+	scope, ok := configScopes[strings.ToLower(scopeName)]
+	if !scoped {
+		scope = configScopes["channel"]
+	} else if !ok {
+		return Result{}, Error{
+			cause: fmt.Errorf("unknown config scope %q", scopeName),
+			helpText: `Whoops, I can configure settings for the channel, the workspace, or "me"! ` +
+				"(Your administrators choose the defaults for everywhere else.)",
+		}
+	}
+	store, record, err := a.configTarget(scope)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if request.Operand == "" {
+		if !scoped {
+			settings, err := a.settings(ctx)
+			if err != nil {
+				return Result{}, a.storeError(err, "getting this channel's configuration")
+			}
+			return a.showEffectiveConfig(settings), nil
+		}
+		settings, err := loadSettings(ctx, store, record)
+		if err != nil {
+			return Result{}, a.storeError(err, "getting the configuration")
+		}
+		return showScopedConfig(scope, settings), nil
+	}
+
+	if strings.EqualFold(request.Operand, "reset") && len(request.Args) == 0 {
+		if _, err := store.Delete(ctx, record); err != nil {
+			return Result{}, a.storeError(err, "updating the configuration")
+		}
+		return Result{
+			resultType: UpdatedConfig,
+			message:    fmt.Sprintf("Done! %s back to the default configuration.", scope.subject),
+		}, nil
+	}
+
+	spec, ok := findSettingSpec(request.Operand)
+	if !ok {
+		return Result{}, Error{
+			cause: fmt.Errorf("unknown config setting %q", request.Operand),
+			helpText: fmt.Sprintf(
+				`Whoops, I can only configure the group, pick, phrasing, template, or visibility! (Type "%s help" to see an example.)`,
+				a.name,
+			),
+		}
+	}
+	if len(request.Args) == 0 {
+		return Result{}, Error{
+			cause:    fmt.Errorf("no value for config setting %q", spec.key),
+			helpText: fmt.Sprintf(`Whoops, I need a value for the %s, or "off" to go back to the default!`, spec.key),
+		}
+	}
+
+	settings, err := loadSettings(ctx, store, record)
+	if err != nil {
+		return Result{}, a.storeError(err, "getting the configuration")
+	}
+
+	value := strings.Join(request.Args, " ")
+	removing := strings.EqualFold(value, "off")
+	if removing {
+		settings = settings.without(spec.key)
+	} else {
+		if !spec.set(&settings, value) {
+			return Result{}, Error{
+				cause:    fmt.Errorf("invalid value %q for config setting %q", value, spec.key),
+				helpText: spec.helpText,
+			}
+		}
+		if err := a.reviewContent(ctx, value); err != nil {
+			return Result{}, err
+		}
+	}
+
+	if entries := settings.entries(); len(entries) == 0 {
+		_, err = store.Delete(ctx, record)
+	} else {
+		err = store.Put(ctx, record, entries)
+	}
+	if err != nil {
+		return Result{}, a.storeError(err, "updating the configuration")
+	}
+
+	message := fmt.Sprintf("Done! %s %s is now %s.", scope.owner, spec.key, spec.get(settings))
+	if removing {
+		message = fmt.Sprintf("Done! %s %s is back to the default.", scope.owner, spec.key)
+	}
+	return Result{resultType: UpdatedConfig, message: message}, nil
+}
+
+// showEffectiveConfig lists the settings that apply in the channel, and where
+// each one came from.
+func (a App) showEffectiveConfig(settings LayeredSettings) Result {
+	var (
+		effective = settings.Effective()
+		lines     []string
+	)
+	for _, spec := range settingSpecs {
+		value := spec.get(effective)
+		if value == "" {
+			continue
+		}
+		level, _ := settings.Source(spec.key)
+		lines = append(lines, fmt.Sprintf("%s: %s (%s)", spec.key, value, levelSources[level]))
+	}
+	if len(lines) == 0 {
+		return Result{
+			resultType: ShowedConfig,
+			message: fmt.Sprintf(
+				`This channel uses the default configuration. (Type "%s help" to see how to change it.)`,
+				a.name,
+			),
+		}
+	}
+	return Result{
+		resultType: ShowedConfig,
+		message:    "Here's the configuration that applies to you in this channel:\n" + bulletlist(lines),
+	}
+}
+
+// showScopedConfig lists the settings that a single level sets itself.
+func showScopedConfig(scope configScope, settings Settings) Result {
+	var lines []string
+	for _, spec := range settingSpecs {
+		if value := spec.get(settings); value != "" {
+			lines = append(lines, spec.key+": "+value)
+		}
+	}
+	source := levelSources[scope.level]
+	if len(lines) == 0 {
+		return Result{
+			resultType: ShowedConfig,
+			message:    fmt.Sprintf("Nothing is %s.", source),
+		}
+	}
+	return Result{
+		resultType: ShowedConfig,
+		message:    fmt.Sprintf("Settings %s:\n%s", source, bulletlist(lines)),
+	}
+}
@@ -0,0 +1,59 @@
+package randomizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ContentCheck reviews text that a user asks to save, like a group's name and
+// options, for deployments with strict content policies. It returns a
+// [ContentRejection] for text that the policy doesn't allow, or any other
+// error if it can't review the text, in which case nothing is saved.
+type ContentCheck func(ctx context.Context, texts []string) error
+
+// ContentRejection is the error that a ContentCheck returns for text that
+// isn't allowed.
+type ContentRejection struct {
+	// Reason, if set, is shown to the user to explain the rejection. It should
+	// not repeat the offending text.
+	Reason string
+}
+
+func (r ContentRejection) Error() string {
+	if r.Reason == "" {
+		return "content rejected"
+	}
+	return "content rejected: " + r.Reason
+}
+
+// WithContentCheck reviews the names and options of groups, and the tags of
+// options, before they're saved.
+func WithContentCheck(check ContentCheck) AppOption {
+	return func(a *App) { a.checkContent = check }
+}
+
+// reviewContent runs the App's content check, if any, on texts that a user
+// asks to save.
+func (a App) reviewContent(ctx context.Context, texts ...string) error {
+	if a.checkContent == nil {
+		return nil
+	}
+
+	err := a.checkContent(ctx, texts)
+	if err == nil {
+		return nil
+	}
+	var rejection ContentRejection
+	if errors.As(err, &rejection) {
+		helpText := "Whoops, I can't save that, since it goes against this workspace's content policy."
+		if rejection.Reason != "" {
+			helpText = fmt.Sprintf("Whoops, I can't save that, since it goes against this workspace's content policy (%s).", rejection.Reason)
+		}
+		return Error{cause: err, helpText: helpText}
+	}
+	return Error{
+		cause:    fmt.Errorf("checking content: %w", err),
+		helpText: "Whoops, I couldn't check that against this workspace's content policy, so I didn't save it. Please try again later!",
+	}
+}
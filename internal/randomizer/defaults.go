@@ -0,0 +1,122 @@
+package randomizer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Defaults configures what an App does with requests that don't name an
+// operation it knows. The zero value keeps the built-in behavior: a bare
+// request shows help, and an unknown flag is randomized as an option.
+type Defaults struct {
+	// Bare applies to a request without any arguments.
+	Bare DefaultOperation
+	// UnknownFlag applies to a request whose first argument starts with "/" but
+	// doesn't name an operation.
+	UnknownFlag DefaultOperation
+	// Settings are the lowest level of selection settings, which workspaces,
+	// channels, and users may override with /config.
+	Settings Settings
+}
+
+// DefaultOperation is the operation that [Defaults] route a request to.
+type DefaultOperation struct {
+	kind  defaultKind
+	group string
+}
+
+type defaultKind int
+
+const (
+	defaultBuiltIn defaultKind = iota
+	defaultHelp
+	defaultRandomize
+	defaultGroup
+	defaultError
+)
+
+var (
+	// DefaultHelp shows the help message.
+	DefaultHelp = DefaultOperation{kind: defaultHelp}
+	// DefaultRandomize randomizes the request's arguments as options, as if no
+	// flag were given. For a bare request, it shows help, since there's
+	// nothing to randomize.
+	DefaultRandomize = DefaultOperation{kind: defaultRandomize}
+	// DefaultError rejects the request with a hint to ask for help.
+	DefaultError = DefaultOperation{kind: defaultError}
+)
+
+// DefaultGroup randomizes the options in a saved group, ignoring the rest of
+// the request's arguments.
+func DefaultGroup(name string) DefaultOperation {
+	return DefaultOperation{kind: defaultGroup, group: name}
+}
+
+// ParseDefaultOperation parses an operation written as "help", "randomize",
+// "error", or "group:<name>". An empty string keeps the built-in behavior.
+func ParseDefaultOperation(s string) (DefaultOperation, error) {
+	switch s = strings.TrimSpace(s); s {
+	case "":
+		return DefaultOperation{}, nil
+	case "help":
+		return DefaultHelp, nil
+	case "randomize":
+		return DefaultRandomize, nil
+	case "error":
+		return DefaultError, nil
+	}
+	if name, ok := strings.CutPrefix(s, "group:"); ok && name != "" && !isForbiddenGroupName(name) {
+		return DefaultGroup(name), nil
+	}
+	return DefaultOperation{}, fmt.Errorf(`invalid default operation %q (want "help", "randomize", "error", or "group:<name>")`, s)
+}
+
+func (o DefaultOperation) String() string {
+	switch o.kind {
+	case defaultHelp:
+		return "help"
+	case defaultRandomize:
+		return "randomize"
+	case defaultGroup:
+		return "group:" + o.group
+	case defaultError:
+		return "error"
+	default:
+		return ""
+	}
+}
+
+// WithDefaults routes requests that don't name a known operation according to
+// d, instead of the built-in behavior.
+func WithDefaults(d Defaults) AppOption {
+	return func(a *App) { a.defaults = d }
+}
+
+// route returns the command that handles a request under a default operation,
+// along with its arguments. The built-in operation is given as fallback.
+func (a App) route(o, fallback DefaultOperation, args []string) (*command, []string, error) {
+	if o.kind == defaultBuiltIn {
+		o = fallback
+	}
+	switch o.kind {
+	case defaultRandomize:
+		if len(args) > 0 {
+			return implicitCommand, args, nil
+		}
+	case defaultGroup:
+		return implicitCommand, []string{"+" + o.group}, nil
+	case defaultError:
+		if len(args) == 0 {
+			return nil, nil, Error{
+				cause:    errors.New("no operation given"),
+				helpText: fmt.Sprintf("Whoops, tell me what to randomize! (For help, use: %s help)", a.name),
+			}
+		}
+		return nil, nil, Error{
+			cause:    fmt.Errorf("unknown operation %q", args[0]),
+			helpText: fmt.Sprintf("Whoops, I don't know the %q operation! (For help, use: %s help)", args[0], a.name),
+		}
+	}
+	return commandNames["help"], nil, nil
+}
@@ -0,0 +1,48 @@
+package randomizer
+
+import (
+	"context"
+	"time"
+)
+
+// deferThreshold is the time remaining before a request's deadline below
+// which an App with deferred writes saves non-critical data, like selection
+// history, after it responds rather than before.
+const deferThreshold = 750 * time.Millisecond
+
+// deferredWriteTimeout bounds each write that runs after the App responds,
+// since the request's own deadline no longer applies to it.
+const deferredWriteTimeout = 10 * time.Second
+
+// WithDeferredWrites lets the App finish writes that the user doesn't need to
+// see, like selection history and onboarding state, after it responds to a
+// request that's close to its deadline. The App passes each deferred write to
+// start, which should run it without blocking (e.g. in a new goroutine) and
+// may track it to wait for it later.
+//
+// Without this option, the App makes every write before it responds, and may
+// fail a request that runs out of time while recording its history.
+func WithDeferredWrites(start func(func())) AppOption {
+	return func(a *App) { a.startDeferred = start }
+}
+
+// writeNonCritical runs write before returning, or defers it if the request is
+// close to its deadline, and reports whether it deferred the write. If the
+// write runs immediately, writeNonCritical returns its error. Errors from
+// deferred writes are logged with the description what.
+func (a App) writeNonCritical(ctx context.Context, what string, write func(context.Context) error) (deferred bool, err error) {
+	deadline, ok := ctx.Deadline()
+	if a.startDeferred == nil || !ok || time.Until(deadline) >= deferThreshold {
+		return false, write(ctx)
+	}
+
+	a.startDeferred(func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), deferredWriteTimeout)
+		defer cancel()
+		if err := write(ctx); err != nil {
+			a.logger.Warn("Failed deferred write", "what", what, "err", err)
+		}
+	})
+	a.logger.Info("Deferred write near request deadline", "what", what)
+	return true, nil
+}
@@ -0,0 +1,23 @@
+package randomizer
+
+import "fmt"
+
+// Selections from options that users list directly never touch the store, so
+// they keep working while saved groups are unavailable. Store failures guide
+// users toward them instead of suggesting that the randomizer is entirely down.
+
+// degradedBanner prefixes results that succeeded in spite of a store failure.
+const degradedBanner = ":warning: Saved groups are temporarily unavailable, so I can only pick from options you list directly.\n\n"
+
+// storeError reports a failed store operation, where action describes what the
+// randomizer was trying to do (e.g. "saving that group").
+func (a App) storeError(err error, action string) Error {
+	return Error{
+		cause: err,
+		helpText: fmt.Sprintf(
+			`Whoops, I had trouble %s. Saved groups seem to be temporarily unavailable, but you can still pick from options you list directly, like "%s pizza tacos ramen". Please try again later!`,
+			action, a.name,
+		),
+		storeUnavailable: true,
+	}
+}
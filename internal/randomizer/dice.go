@@ -0,0 +1,288 @@
+package randomizer
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "roll",
+		permission: permRead,
+		flags:      []string{"pick"},
+		handler:    App.roll,
+		section:    helpBasics,
+		help: []string{
+			"*Roll dice:* {{.Name}} /roll 2d6",
+			"*Roll dice with a modifier:* {{.Name}} /roll 1d20+5",
+		},
+	})
+	registerCommand(&command{
+		name:       "range",
+		permission: permRead,
+		flags:      []string{"pick"},
+		handler:    App.pickRange,
+		section:    helpBasics,
+		help: []string{
+			"*Pick a whole number:* {{.Name}} /range 1 100",
+			"*Pick several numbers at once:* {{.Name}} /range 1 10 -n 3",
+		},
+	})
+}
+
+// Dice are written in the usual tabletop notation, like "2d6+1d4-1": terms
+// joined by "+" or "-", where each term is either a number of dice with some
+// number of sides, or a constant modifier. "d20" is short for "1d20", and
+// "d%" for "1d100".
+
+// Limits on dice expressions, to keep responses a reasonable size.
+const (
+	maxDice     = 100
+	maxDieSides = 1000
+	maxModifier = 1_000_000
+)
+
+// diceTerm is a single term of a dice expression. Terms without dice are
+// constant modifiers.
+type diceTerm struct {
+	negative bool
+	count    int // Zero for a modifier
+	sides    int
+	modifier int
+}
+
+func (t diceTerm) String() string {
+	if t.count == 0 {
+		return strconv.Itoa(t.modifier)
+	}
+	return fmt.Sprintf("%dd%d", t.count, t.sides)
+}
+
+// parseDice parses a dice expression, which may be split across arguments,
+// as in "2d6 + 3".
+func parseDice(args []string) ([]diceTerm, error) {
+	expr := strings.ToLower(strings.Join(args, ""))
+	invalid := func(reason string) error {
+		return Error{
+			cause:    fmt.Errorf("invalid dice %q: %s", expr, reason),
+			helpText: fmt.Sprintf("Whoops, I can't roll %q: %s! Try something like 2d6 or 1d20+5.", expr, reason),
+		}
+	}
+
+	var (
+		terms []diceTerm
+		dice  int
+	)
+	for rest := expr; rest != ""; {
+		term := diceTerm{}
+		switch rest[0] {
+		case '-':
+			term.negative = true
+			rest = rest[1:]
+		case '+':
+			rest = rest[1:]
+		default:
+			if len(terms) > 0 {
+				return nil, invalid("terms need to be joined with + or -")
+			}
+		}
+
+		end := strings.IndexAny(rest, "+-")
+		if end < 0 {
+			end = len(rest)
+		}
+		text := rest[:end]
+		rest = rest[end:]
+
+		count, sides, isDice := strings.Cut(text, "d")
+		if !isDice {
+			n, err := strconv.Atoi(text)
+			if err != nil || n < 0 {
+				return nil, invalid(fmt.Sprintf("%q isn't a number or dice", text))
+			}
+			if n > maxModifier {
+				return nil, invalid(fmt.Sprintf("modifiers can be at most %d", maxModifier))
+			}
+			term.modifier = n
+			terms = append(terms, term)
+			continue
+		}
+
+		term.count = 1
+		if count != "" {
+			n, err := strconv.Atoi(count)
+			if err != nil || n < 1 {
+				return nil, invalid(fmt.Sprintf("%q isn't a number of dice", count))
+			}
+			term.count = n
+		}
+		if sides == "%" {
+			sides = "100"
+		}
+		n, err := strconv.Atoi(sides)
+		if err != nil || n < 1 || n > maxDieSides {
+			return nil, invalid(fmt.Sprintf("dice need from 1 to %d sides", maxDieSides))
+		}
+		term.sides = n
+		if dice += term.count; dice > maxDice {
+			return nil, invalid(fmt.Sprintf("I can roll at most %d dice at a time", maxDice))
+		}
+		terms = append(terms, term)
+	}
+
+	if dice == 0 {
+		return nil, invalid("there are no dice to roll")
+	}
+	return terms, nil
+}
+
+// formatDice writes terms back in canonical notation.
+func formatDice(terms []diceTerm) string {
+	var b strings.Builder
+	for i, term := range terms {
+		switch {
+		case term.negative:
+			b.WriteString("-")
+		case i > 0:
+			b.WriteString("+")
+		}
+		b.WriteString(term.String())
+	}
+	return b.String()
+}
+
+// diceRoll is the outcome of rolling a dice expression once.
+type diceRoll struct {
+	total int
+	// faces lists the face of each die that was rolled, by term.
+	faces [][]int
+}
+
+func rollDice(randomness Randomness, terms []diceTerm) diceRoll {
+	roll := diceRoll{faces: make([][]int, len(terms))}
+	randomness.Use(func(r *rand.Rand) {
+		for i, term := range terms {
+			sum := term.modifier
+			for range term.count {
+				face := r.IntN(term.sides) + 1
+				roll.faces[i] = append(roll.faces[i], face)
+				sum += face
+			}
+			if term.negative {
+				sum = -sum
+			}
+			roll.total += sum
+		}
+	})
+	return roll
+}
+
+// breakdown describes the dice behind a roll's total, like "2d6 (3, 5) + 2".
+// It's empty if the roll had a single die and nothing else.
+func (r diceRoll) breakdown(terms []diceTerm) string {
+	if len(terms) == 1 && terms[0].count == 1 && !terms[0].negative {
+		return ""
+	}
+	var b strings.Builder
+	for i, term := range terms {
+		switch {
+		case term.negative && i == 0:
+			b.WriteString("-")
+		case term.negative:
+			b.WriteString(" - ")
+		case i > 0:
+			b.WriteString(" + ")
+		}
+		if term.count == 0 {
+			b.WriteString(strconv.Itoa(term.modifier))
+			continue
+		}
+		faces := make([]string, len(r.faces[i]))
+		for j, face := range r.faces[i] {
+			faces[j] = strconv.Itoa(face)
+		}
+		fmt.Fprintf(&b, "%s (%s)", term, strings.Join(faces, ", "))
+	}
+	return b.String()
+}
+
+func (a App) roll(request request) (Result, error) {
+	terms, err := parseDice(append([]string{request.Operand}, request.Args...))
+	if err != nil {
+		return Result{}, err
+	}
+	count, err := a.sampleCount(request.Flags)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var (
+		totals     = make([]string, count)
+		breakdowns []string
+	)
+	for i := range totals {
+		roll := rollDice(a.randomness, terms)
+		totals[i] = strconv.Itoa(roll.total)
+		if breakdown := roll.breakdown(terms); breakdown != "" {
+			breakdowns = append(breakdowns, fmt.Sprintf("• *%d* = %s", roll.total, breakdown))
+		}
+	}
+
+	message := fmt.Sprintf(":game_die: I rolled %s and got: %s.", formatDice(terms), inlinelist(totals))
+	if len(breakdowns) > 0 {
+		message += "\n" + strings.Join(breakdowns, "\n")
+	}
+	return Result{
+		resultType: RolledDice,
+		message:    message,
+		choices:    totals,
+	}, nil
+}
+
+func (a App) pickRange(request request) (Result, error) {
+	if len(request.Args) != 1 {
+		return Result{}, Error{
+			cause:    fmt.Errorf("range needs 2 bounds, got %d", len(request.Args)+1),
+			helpText: fmt.Sprintf("Whoops, give me the smallest and largest numbers to pick from, like: %s /range 1 100", a.name),
+		}
+	}
+	bounds := make([]int64, 2)
+	for i, arg := range []string{request.Operand, request.Args[0]} {
+		n, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return Result{}, Error{
+				cause:    fmt.Errorf("invalid range bound %q: %w", arg, err),
+				helpText: fmt.Sprintf("Whoops, %q needs to be a whole number!", arg),
+			}
+		}
+		bounds[i] = n
+	}
+	lo, hi := min(bounds[0], bounds[1]), max(bounds[0], bounds[1])
+	count, err := a.sampleCount(request.Flags)
+	if err != nil {
+		return Result{}, err
+	}
+
+	// The span of the widest ranges overflows an int64, but not a uint64, and
+	// adding the offset back to lo wraps around to the right result.
+	span := uint64(hi - lo)
+	numbers := make([]string, count)
+	a.randomness.Use(func(r *rand.Rand) {
+		for i := range numbers {
+			offset := r.Uint64()
+			if span < math.MaxUint64 {
+				offset = r.Uint64N(span + 1)
+			}
+			numbers[i] = strconv.FormatInt(lo+int64(offset), 10)
+		}
+	})
+
+	return Result{
+		resultType: PickedNumbers,
+		message:    fmt.Sprintf(":1234: I picked from %d to %d and got: %s.", lo, hi, inlinelist(numbers)),
+		choices:    numbers,
+	}, nil
+}
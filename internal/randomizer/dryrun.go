@@ -0,0 +1,117 @@
+package randomizer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// dryRunStore wraps a Store to record writes instead of performing them, so
+// that handlers can run unmodified while previewing their effects.
+type dryRunStore struct {
+	Store
+	changes *[]storeChange
+}
+
+// storeChange represents a single write that a dry run would have made.
+type storeChange struct {
+	Group   string
+	Before  []string
+	After   []string
+	Deleted bool
+}
+
+func (s dryRunStore) Put(ctx context.Context, group string, options []string) error {
+	before, err := s.Store.Get(ctx, group)
+	if err != nil {
+		return err
+	}
+	*s.changes = append(*s.changes, storeChange{
+		Group:  group,
+		Before: before,
+		After:  slices.Clone(options),
+	})
+	return nil
+}
+
+func (s dryRunStore) Delete(ctx context.Context, group string) (existed bool, err error) {
+	before, err := s.Store.Get(ctx, group)
+	if err != nil {
+		return false, err
+	}
+	if len(before) == 0 {
+		return false, nil
+	}
+	*s.changes = append(*s.changes, storeChange{
+		Group:   group,
+		Before:  before,
+		Deleted: true,
+	})
+	return true, nil
+}
+
+// previewChanges describes the changes recorded by a dry run.
+func previewChanges(changes []storeChange) Result {
+	// Reserved records like history and expiry policies are bookkeeping that
+	// users don't manage as groups, so they're left out of the preview.
+	// Schedules are the exception, since users create them directly.
+	changes = slices.DeleteFunc(changes, func(c storeChange) bool {
+		return strings.HasPrefix(c.Group, recordPrefix) && !strings.HasPrefix(c.Group, schedulePrefix)
+	})
+	if len(changes) == 0 {
+		return Result{
+			resultType: PreviewedChanges,
+			message:    "Dry run complete! This wouldn't change any groups.",
+		}
+	}
+
+	lines := make([]string, len(changes))
+	for i, change := range changes {
+		lines[i] = change.describe()
+	}
+	return Result{
+		resultType: PreviewedChanges,
+		message: fmt.Sprintf(
+			"Dry run complete! Nothing was saved, but this would make the following changes:\n%s",
+			bulletlist(lines),
+		),
+	}
+}
+
+func (c storeChange) describe() string {
+	switch {
+	case strings.HasPrefix(c.Group, schedulePrefix):
+		return c.describeSchedule()
+	case c.Deleted:
+		return fmt.Sprintf("Delete the %q group (%s)", c.Group, strings.Join(displayNames(c.Before), ", "))
+	case len(c.Before) == 0:
+		return fmt.Sprintf("Create the %q group with %s", c.Group, strings.Join(displayNames(c.After), ", "))
+	}
+
+	var diffs []string
+	if added := difference(c.After, c.Before); len(added) > 0 {
+		diffs = append(diffs, "add "+strings.Join(displayNames(added), ", "))
+	}
+	if removed := difference(c.Before, c.After); len(removed) > 0 {
+		diffs = append(diffs, "remove "+strings.Join(displayNames(removed), ", "))
+	}
+	if len(diffs) == 0 && !slices.Equal(c.Before, c.After) {
+		return fmt.Sprintf("Reorder the %q group to %s", c.Group, strings.Join(displayNames(c.After), ", "))
+	}
+	if len(diffs) == 0 {
+		return fmt.Sprintf("Leave the %q group unchanged", c.Group)
+	}
+	return fmt.Sprintf("Update the %q group: %s", c.Group, strings.Join(diffs, "; "))
+}
+
+// difference returns the items of a that are not in b.
+func difference(a, b []string) []string {
+	var result []string
+	for _, item := range a {
+		if !slices.Contains(b, item) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,65 @@
+package randomizer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/calendar"
+)
+
+// eventTimeFormats are the accepted formats for the "--event" flag. Times are
+// interpreted in UTC, and dates without times produce all-day events.
+var eventTimeFormats = []string{
+	"2006-01-02T15:04",
+	"2006-01-02T15:04Z07:00",
+	time.DateOnly,
+}
+
+// withEvent attaches a calendar event for the winner of a selection, as
+// requested by the "--event" and "--event-duration" flags.
+func (a App) withEvent(ctx context.Context, result Result, flags flagSet) (Result, error) {
+	when, _ := flags.Value("event")
+
+	var (
+		start time.Time
+		err   error
+	)
+	for _, format := range eventTimeFormats {
+		if start, err = time.Parse(format, when); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return Result{}, Error{
+			cause:    fmt.Errorf("parsing event time %q: %w", when, err),
+			helpText: `Whoops, I need the event time as a date like "2006-01-02" or a UTC time like "2006-01-02T15:04"!`,
+		}
+	}
+
+	duration := 30 * time.Minute
+	if d, ok := flags.Value("event-duration"); ok {
+		duration, err = time.ParseDuration(d)
+		if err != nil || duration <= 0 {
+			return Result{}, Error{
+				cause:    fmt.Errorf("parsing event duration %q: %w", d, err),
+				helpText: `Whoops, I need the event duration as something like "30m" or "1h"!`,
+			}
+		}
+	}
+
+	winner := result.choices[0]
+	result.event = &calendar.Event{
+		Title:       a.plainMentions(ctx, fmt.Sprintf("%s (picked by the randomizer)", winner)),
+		Description: a.plainMentions(ctx, result.message),
+		Start:       start,
+		Duration:    duration,
+		AllDay:      len(when) == len(time.DateOnly),
+	}
+
+	result.message += fmt.Sprintf(
+		"\n:calendar: <%s|Add *%s* to your calendar> for %s",
+		result.event.GoogleCalendarURL(), winner, slackDate(start, result.event.AllDay),
+	)
+	return result, nil
+}
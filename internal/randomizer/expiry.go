@@ -0,0 +1,341 @@
+package randomizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "expire",
+		permission: permWrite,
+		handler:    App.setExpiry,
+		section:    helpOptions,
+		help: []string{
+			"*Flag options not picked in 6 months:* {{.Name}} /expire snacks 6mo",
+			"*Leave them out of selections instead:* {{.Name}} /expire snacks 6mo disable",
+		},
+	})
+	registerCommand(&command{
+		name:       "confirm-options",
+		permission: permWrite,
+		handler:    App.confirmGroupOptions,
+		section:    helpOptions,
+		help:       []string{"*Confirm that options are still active:* {{.Name}} /confirm-options snacks chips"},
+	})
+}
+
+// An expiry policy keeps long-lived groups from accumulating options that no
+// longer apply, like teammates who have moved on. Options that haven't been
+// picked or confirmed within the policy's age limit are "stale." Depending on
+// the policy, stale options are either flagged in selection results, or also
+// left out of selections until someone confirms them.
+//
+// Saving a group confirms all of its options, and /confirm-options confirms
+// some or all of them without changing the group.
+
+// expiryRecord returns the name of the record holding a group's expiry policy
+// and option confirmations. Entries take the forms "policy|<age>|<action>" and
+// "confirmed|<option>|<time>".
+func expiryRecord(group string) string {
+	return recordPrefix + "expiry/" + group
+}
+
+type expiryAction string
+
+const (
+	expiryFlag    expiryAction = "flag"
+	expiryDisable expiryAction = "disable"
+)
+
+type expiryPolicy struct {
+	Age       string // As typed by the user, e.g. "6mo"
+	MaxAge    time.Duration
+	Action    expiryAction
+	Confirmed map[string]time.Time
+}
+
+// parseAge accepts ages in days, weeks, or months, like "90d", "12w", or "6mo".
+// Months count as 30 days.
+func parseAge(age string) (time.Duration, error) {
+	const day = 24 * time.Hour
+	units := []struct {
+		suffix string
+		size   time.Duration
+	}{{"mo", 30 * day}, {"d", day}, {"w", 7 * day}}
+
+	for _, unit := range units {
+		if n, ok := strings.CutSuffix(age, unit.suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 1 {
+				break
+			}
+			return time.Duration(count) * unit.size, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid age %q", age)
+}
+
+// getExpiryPolicy returns a group's expiry policy, or nil if it has none.
+func (a App) getExpiryPolicy(ctx context.Context, group string) (*expiryPolicy, error) {
+	entries, err := a.store.Get(ctx, expiryRecord(group))
+	if err != nil {
+		return nil, err
+	}
+
+	var policy *expiryPolicy
+	confirmed := make(map[string]time.Time)
+	for _, entry := range entries {
+		kind, rest, _ := strings.Cut(entry, "|")
+		switch kind {
+		case "policy":
+			age, action, _ := strings.Cut(rest, "|")
+			maxAge, err := parseAge(age)
+			if err != nil {
+				return nil, err
+			}
+			policy = &expiryPolicy{Age: age, MaxAge: maxAge, Action: expiryAction(action)}
+		case "confirmed":
+			i := strings.LastIndexByte(rest, '|')
+			if i < 0 {
+				continue
+			}
+			if t, err := time.Parse(time.RFC3339, rest[i+1:]); err == nil {
+				confirmed[rest[:i]] = t
+			}
+		}
+	}
+	if policy != nil {
+		policy.Confirmed = confirmed
+	}
+	return policy, nil
+}
+
+// confirmOptions records that the named options are still active as of now.
+func (a App) confirmOptions(ctx context.Context, group string, names []string) error {
+	record := expiryRecord(group)
+	entries, err := a.store.Get(ctx, record)
+	if err != nil {
+		return err
+	}
+
+	now := a.now().UTC().Format(time.RFC3339)
+	entries = slices.DeleteFunc(entries, func(entry string) bool {
+		for _, name := range names {
+			if strings.HasPrefix(entry, "confirmed|"+name+"|") {
+				return true
+			}
+		}
+		return false
+	})
+	for _, name := range names {
+		entries = append(entries, "confirmed|"+name+"|"+now)
+	}
+	return a.store.Put(ctx, record, entries)
+}
+
+// staleOptions returns the options in a group that haven't been picked or
+// confirmed within the policy's age limit.
+func (a App) staleOptions(ctx context.Context, group string, policy *expiryPolicy, options []option) ([]option, error) {
+	history, err := a.store.Get(ctx, historyRecord(group))
+	if err != nil {
+		return nil, err
+	}
+
+	lastActive := make(map[string]time.Time, len(policy.Confirmed))
+	for name, t := range policy.Confirmed {
+		lastActive[name] = t
+	}
+	for _, entry := range parseHistory(history) {
+		if entry.Time.After(lastActive[entry.Winner]) {
+			lastActive[entry.Winner] = entry.Time
+		}
+	}
+
+	cutoff := a.now().Add(-policy.MaxAge)
+	var stale []option
+	for _, o := range options {
+		if lastActive[o.Name].Before(cutoff) {
+			stale = append(stale, o)
+		}
+	}
+	return stale, nil
+}
+
+// applyExpiry checks a selection from a group against the group's expiry
+// policy, if it has one. It returns the options that remain eligible, and a
+// note about any stale options to add to the result.
+func (a App) applyExpiry(ctx context.Context, group string, options []option) ([]option, string, error) {
+	policy, err := a.getExpiryPolicy(ctx, group)
+	if err != nil || policy == nil {
+		// Expiry is advisory, so a failure to check it shouldn't block the
+		// selection itself.
+		return options, "", nil
+	}
+	stale, err := a.staleOptions(ctx, group, policy, options)
+	if err != nil || len(stale) == 0 {
+		return options, "", nil
+	}
+
+	names := optionNames(stale)
+	confirm := fmt.Sprintf("%s /confirm-options %s %s", a.name, group, strings.Join(names, " "))
+	if policy.Action != expiryDisable {
+		return options, fmt.Sprintf(
+			"\n:hourglass: %s %s been picked or confirmed in %s. (To confirm, use: %s)",
+			inlinelist(names), pluralVerb(len(names), "hasn't", "haven't"), policy.Age, confirm,
+		), nil
+	}
+
+	remaining := slices.DeleteFunc(slices.Clone(options), func(o option) bool {
+		return slices.Contains(names, o.Name)
+	})
+	if len(remaining) == 0 {
+		return nil, "", Error{
+			cause: fmt.Errorf("all options in group %q are stale", group),
+			helpText: fmt.Sprintf(
+				"Whoops, every option in the %q group has expired! (To bring them back, use: %s /confirm-options %s)",
+				group, a.name, group,
+			),
+		}
+	}
+	return remaining, fmt.Sprintf(
+		"\n:hourglass: I left out %s, which %s been picked or confirmed in %s. (To bring them back, use: %s)",
+		inlinelist(names), pluralVerb(len(names), "hasn't", "haven't"), policy.Age, confirm,
+	), nil
+}
+
+func pluralVerb(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
+
+func (a App) setExpiry(request request) (Result, error) {
+	var (
+		ctx   = request.Context
+		group = request.Operand
+		args  = request.Args
+	)
+
+	if len(args) == 1 && strings.EqualFold(args[0], "off") {
+		record := expiryRecord(group)
+		if _, err := a.store.Delete(ctx, record); err != nil {
+			return Result{}, a.storeError(err, "updating that group's expiry policy")
+		}
+		return Result{
+			resultType: UpdatedExpiry,
+			message:    fmt.Sprintf("Done! Options in the %q group will no longer expire.", group),
+		}, nil
+	}
+
+	if len(args) < 1 || len(args) > 2 {
+		return Result{}, Error{
+			cause: errors.New("wrong number of arguments to set expiry"),
+			helpText: fmt.Sprintf(
+				`Whoops, I need an age like "90d", "12w", or "6mo", optionally followed by "flag" or "disable"! (Type "%s help" to see an example.)`,
+				a.name,
+			),
+		}
+	}
+
+	age := args[0]
+	if _, err := parseAge(age); err != nil {
+		return Result{}, Error{
+			cause:    err,
+			helpText: fmt.Sprintf(`Whoops, I need an age like "90d", "12w", or "6mo", not %q!`, age),
+		}
+	}
+	action := expiryFlag
+	if len(args) == 2 {
+		action = expiryAction(strings.ToLower(args[1]))
+		if action != expiryFlag && action != expiryDisable {
+			return Result{}, Error{
+				cause:    fmt.Errorf("invalid expiry action %q", args[1]),
+				helpText: `Whoops, stale options can either be flagged with "flag" or left out of selections with "disable"!`,
+			}
+		}
+	}
+
+	stored, err := a.getGroup(ctx, group)
+	if err != nil {
+		return Result{}, a.storeError(err, "getting that group")
+	}
+	if len(stored) == 0 {
+		return Result{}, Error{
+			cause:    errors.New("group does not exist"),
+			helpText: "Whoops, I can't find that group in this channel. (Use the /save flag to create it!)",
+		}
+	}
+
+	// Setting a policy confirms every current option, so that nothing expires
+	// immediately.
+	entries := []string{"policy|" + age + "|" + string(action)}
+	now := a.now().UTC().Format(time.RFC3339)
+	for _, o := range parseOptions(stored) {
+		entries = append(entries, "confirmed|"+o.Name+"|"+now)
+	}
+	if err := a.store.Put(ctx, expiryRecord(group), entries); err != nil {
+		return Result{}, a.storeError(err, "updating that group's expiry policy")
+	}
+
+	what := "I'll point them out in results"
+	if action == expiryDisable {
+		what = "I'll leave them out of selections"
+	}
+	return Result{
+		resultType: UpdatedExpiry,
+		message: fmt.Sprintf(
+			"Done! When options in the %q group haven't been picked or confirmed in %s, %s.",
+			group, age, what,
+		),
+	}, nil
+}
+
+func (a App) confirmGroupOptions(request request) (Result, error) {
+	var (
+		ctx   = request.Context
+		group = request.Operand
+	)
+
+	stored, err := a.getGroup(ctx, group)
+	if err != nil {
+		return Result{}, a.storeError(err, "getting that group")
+	}
+	if len(stored) == 0 {
+		return Result{}, Error{
+			cause:    errors.New("group does not exist"),
+			helpText: "Whoops, I can't find that group in this channel. (Use the /save flag to create it!)",
+		}
+	}
+
+	options := parseOptions(stored)
+	names := request.Args
+	if len(names) == 0 {
+		names = optionNames(options)
+	}
+	for _, name := range names {
+		if optionIndex(options, name) < 0 {
+			return Result{}, Error{
+				cause:    fmt.Errorf("option %q not in group %q", name, group),
+				helpText: fmt.Sprintf("Whoops, the %q group doesn't have an option named %q!", group, name),
+			}
+		}
+	}
+
+	if err := a.confirmOptions(ctx, group, names); err != nil {
+		return Result{}, a.storeError(err, "confirming those options")
+	}
+	return Result{
+		resultType: ConfirmedOptions,
+		message: fmt.Sprintf(
+			"Done! I confirmed the following options in the %q group:\n%s",
+			group, bulletlist(names),
+		),
+	}, nil
+}
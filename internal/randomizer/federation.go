@@ -0,0 +1,493 @@
+package randomizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "federate",
+		operand:    operandOptional,
+		permission: permWorkspace,
+		handler:    App.configureFederation,
+		section:    helpWorkspace,
+		help: []string{
+			"*Share a group with a connected workspace:* {{.Name}} /federate reviewers invite T0123ABCD",
+			"&gt; The other workspace accepts with {{.Name}} /federate accept ID, then each side adds its own members with {{.Name}} /federate reviewers add alice bob, and anyone in either workspace can pick with {{.Name}} +reviewers",
+		},
+	})
+}
+
+// A federated group is a candidate pool that spans two workspaces, like the
+// two companies in a Slack Connect channel, so that cross-company rotations
+// can draw fairly from both sides. One workspace invites the other, and the
+// group stays inactive until the other accepts. Each workspace manages only
+// its own members, and either can leave at any time, which ends the group for
+// both.
+//
+// Federated groups live in a partition of the store that every workspace
+// shares, given to [WithFederation], under a random ID. Each workspace finds
+// its federated groups by name through a record in its own store, so the two
+// sides can use different names. A channel's own group of the same name takes
+// precedence.
+
+// FederationPartition is the partition of the store that holds every
+// federated group.
+const FederationPartition = "federation"
+
+// federationPrefix starts the name of each federated group's record, which is
+// followed by the group's ID.
+const federationPrefix = "/federation/"
+
+// federationsRecord maps the names of a workspace's federated groups to their
+// IDs, with entries of the form "<name>|<id>".
+const federationsRecord = recordPrefix + "federations"
+
+// WithFederation enables /federate, saving federated groups in store (from
+// [FederationPartition]) on behalf of the workspace team. It requires
+// [WithWorkspaceStore].
+func WithFederation(store Store, team string) AppOption {
+	return func(a *App) {
+		a.federation = store
+		a.team = team
+	}
+}
+
+// federatedGroup is a group with members from two workspaces.
+type federatedGroup struct {
+	ID      string
+	Name    string   // The name the inviting workspace chose
+	Teams   []string // The workspaces that have consented
+	Invited string   // The workspace that has yet to accept, if any
+	Members map[string][]string
+}
+
+func (g federatedGroup) entries() []string {
+	entries := []string{"name|" + g.Name}
+	for _, team := range g.Teams {
+		entries = append(entries, "team|"+team)
+		for _, m := range g.Members[team] {
+			entries = append(entries, "member|"+team+"|"+m)
+		}
+	}
+	if g.Invited != "" {
+		entries = append(entries, "invited|"+g.Invited)
+	}
+	return entries
+}
+
+func parseFederatedGroup(id string, entries []string) federatedGroup {
+	g := federatedGroup{ID: id, Members: make(map[string][]string)}
+	for _, entry := range entries {
+		key, value, _ := strings.Cut(entry, "|")
+		switch key {
+		case "name":
+			g.Name = value
+		case "team":
+			g.Teams = append(g.Teams, value)
+		case "invited":
+			g.Invited = value
+		case "member":
+			team, member, _ := strings.Cut(value, "|")
+			g.Members[team] = append(g.Members[team], member)
+		}
+	}
+	slices.Sort(g.Teams) // Not every Store preserves the order of entries.
+	return g
+}
+
+// active reports whether both workspaces have consented to the group.
+func (g federatedGroup) active() bool {
+	return g.Invited == "" && len(g.Teams) == 2
+}
+
+// allMembers returns the members from both workspaces, each counted once.
+func (g federatedGroup) allMembers() []string {
+	var all []string
+	for _, team := range g.Teams {
+		for _, m := range g.Members[team] {
+			if !slices.Contains(all, m) {
+				all = append(all, m)
+			}
+		}
+	}
+	return all
+}
+
+// workspaceFederations returns the names and IDs of the workspace's federated
+// groups.
+func (a App) workspaceFederations(ctx context.Context) (map[string]string, error) {
+	entries, err := a.workspace.Get(ctx, federationsRecord)
+	if err != nil {
+		return nil, err
+	}
+	refs := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if name, id, ok := strings.Cut(entry, "|"); ok {
+			refs[name] = id
+		}
+	}
+	return refs, nil
+}
+
+// getFederatedGroup returns the federated group that the workspace knows by
+// name, if there is one.
+func (a App) getFederatedGroup(ctx context.Context, name string) (federatedGroup, bool, error) {
+	refs, err := a.workspaceFederations(ctx)
+	if err != nil {
+		return federatedGroup{}, false, err
+	}
+	id, ok := refs[name]
+	if !ok {
+		return federatedGroup{}, false, nil
+	}
+	entries, err := a.federation.Get(ctx, federationPrefix+id)
+	if err != nil {
+		return federatedGroup{}, false, err
+	}
+	if len(entries) == 0 {
+		// The other workspace left, so only the name remains.
+		return federatedGroup{ID: id, Name: name}, true, nil
+	}
+	return parseFederatedGroup(id, entries), true, nil
+}
+
+// federatedOptions returns the options of an active federated group that the
+// workspace knows by name, for selections that don't find a channel group.
+func (a App) federatedOptions(ctx context.Context, name string) ([]option, error) {
+	if a.federation == nil || a.workspace == nil {
+		return nil, nil
+	}
+	g, ok, err := a.getFederatedGroup(ctx, name)
+	if err != nil {
+		return nil, a.storeError(err, fmt.Sprintf("getting the %q federated group", name))
+	}
+	if !ok {
+		return nil, nil
+	}
+	if !g.active() {
+		return nil, Error{
+			cause:    fmt.Errorf("federated group %q is inactive", name),
+			helpText: fmt.Sprintf(`Whoops, the %q group isn't shared with another workspace right now. (Type "%s /federate %s" to see why.)`, name, a.name, name),
+		}
+	}
+	if members := g.allMembers(); len(members) > 0 {
+		return parseOptions(members), nil
+	}
+	return nil, Error{
+		cause:    fmt.Errorf("federated group %q is empty", name),
+		helpText: fmt.Sprintf(`Whoops, nobody has joined the %q group yet! (Type "%s /federate %s add ..." to add members.)`, name, a.name, name),
+	}
+}
+
+// teamIDPattern matches Slack workspace IDs.
+var teamIDPattern = regexp.MustCompile(`^T[A-Z0-9]{2,}$`)
+
+func (a App) configureFederation(request request) (Result, error) {
+	ctx := request.Context
+	if a.federation == nil || a.workspace == nil || a.team == "" {
+		return Result{}, Error{
+			cause:    errors.New("no federation store"),
+			helpText: "Whoops, sharing groups between workspaces isn't available here!",
+		}
+	}
+
+	if request.Operand == "" {
+		return a.listFederations(ctx)
+	}
+	if strings.EqualFold(request.Operand, "accept") {
+		return a.acceptFederation(ctx, request.Args)
+	}
+
+	name := request.Operand
+	if len(request.Args) == 0 {
+		return a.showFederation(ctx, name)
+	}
+	switch action, args := strings.ToLower(request.Args[0]), request.Args[1:]; action {
+	case "invite":
+		return a.inviteFederation(ctx, name, args)
+	case "add", "remove":
+		return a.updateFederatedMembers(ctx, name, action == "add", args)
+	case "leave":
+		return a.leaveFederation(ctx, name)
+	default:
+		return Result{}, Error{
+			cause:    fmt.Errorf("unknown federation action %q", action),
+			helpText: fmt.Sprintf(`Whoops, I can only "invite", "add", "remove", or "leave" for a shared group. (Type "%s help" to learn more!)`, a.name),
+		}
+	}
+}
+
+func (a App) listFederations(ctx context.Context) (Result, error) {
+	refs, err := a.workspaceFederations(ctx)
+	if err != nil {
+		return Result{}, a.storeError(err, "getting this workspace's shared groups")
+	}
+	if len(refs) == 0 {
+		return Result{
+			resultType: ShowedFederation,
+			message: fmt.Sprintf(
+				`This workspace doesn't share any groups with other workspaces. (Type "%s help" to see how to share one.)`,
+				a.name,
+			),
+		}, nil
+	}
+	names := slices.Sorted(func(yield func(string) bool) {
+		for name := range refs {
+			if !yield(name) {
+				return
+			}
+		}
+	})
+	return Result{
+		resultType: ShowedFederation,
+		message:    "This workspace shares the following groups with other workspaces:\n" + bulletlist(names),
+	}, nil
+}
+
+func (a App) showFederation(ctx context.Context, name string) (Result, error) {
+	g, ok, err := a.getFederatedGroup(ctx, name)
+	if err != nil {
+		return Result{}, a.storeError(err, "getting that shared group")
+	}
+	if !ok {
+		return Result{}, a.federationNotFound(name)
+	}
+
+	var status string
+	switch {
+	case len(g.Teams) == 0:
+		status = fmt.Sprintf(`The other workspace left the %q group, so nobody can pick from it. (Type "%s /federate %s leave" to clean it up.)`, name, a.name, name)
+	case g.Invited != "":
+		status = fmt.Sprintf("The %q group is waiting for workspace %s to accept it with \"%s /federate accept %s\".", name, g.Invited, a.name, g.ID)
+	default:
+		status = fmt.Sprintf("The %q group is shared between workspaces %s.", name, strings.Join(g.Teams, " and "))
+	}
+
+	var b strings.Builder
+	b.WriteString(status)
+	for _, team := range g.Teams {
+		members := g.Members[team]
+		if len(members) == 0 {
+			fmt.Fprintf(&b, "\nWorkspace %s hasn't added any members.", team)
+			continue
+		}
+		fmt.Fprintf(&b, "\nMembers from workspace %s:\n%s", team, bulletlist(displayNames(members)))
+	}
+	return Result{resultType: ShowedFederation, message: b.String()}, nil
+}
+
+func (a App) inviteFederation(ctx context.Context, name string, args []string) (Result, error) {
+	if len(args) != 1 || !teamIDPattern.MatchString(args[0]) || args[0] == a.team {
+		return Result{}, Error{
+			cause:    fmt.Errorf("invalid workspace to invite %q", args),
+			helpText: `Whoops, I need the ID of another workspace to share with, like "T0123ABCD"!`,
+		}
+	}
+	if isForbiddenGroupName(name) {
+		return Result{}, Error{
+			cause:    fmt.Errorf("sharing forbidden group name %q", name),
+			helpText: fmt.Sprintf(`Whoops, %q has a special meaning and can't be used as a group name.`, name),
+		}
+	}
+	if err := a.reviewContent(ctx, name); err != nil {
+		return Result{}, err
+	}
+
+	g := federatedGroup{ID: newResultID(), Name: name, Teams: []string{a.team}, Invited: args[0]}
+	if err := a.addFederationRef(ctx, name, g.ID); err != nil {
+		return Result{}, err
+	}
+	if err := a.federation.Put(ctx, federationPrefix+g.ID, g.entries()); err != nil {
+		return Result{}, a.storeError(err, "saving that shared group")
+	}
+	return Result{
+		resultType: UpdatedFederation,
+		message: fmt.Sprintf(
+			"Done! Ask someone in workspace %s to run \"%s /federate accept %s\" to share the %q group. Until then, nobody can pick from it.",
+			g.Invited, a.name, g.ID, name,
+		),
+	}, nil
+}
+
+// addFederationRef records the workspace's name for a federated group, unless
+// the name is taken.
+func (a App) addFederationRef(ctx context.Context, name, id string) error {
+	err := Update(ctx, a.workspace, federationsRecord, func(entries []string) ([]string, error) {
+		for _, entry := range entries {
+			if existing, _, _ := strings.Cut(entry, "|"); existing == name {
+				return nil, Error{
+					cause:    fmt.Errorf("federated group %q already exists", name),
+					helpText: fmt.Sprintf("Whoops, this workspace already shares a group named %q!", name),
+				}
+			}
+		}
+		return append(entries, name+"|"+id), nil
+	})
+	if err != nil {
+		return a.updateError(err, "saving that shared group")
+	}
+	return nil
+}
+
+func (a App) removeFederationRef(ctx context.Context, name, id string) error {
+	return Update(ctx, a.workspace, federationsRecord, func(entries []string) ([]string, error) {
+		return slices.DeleteFunc(entries, func(e string) bool { return e == name+"|"+id }), nil
+	})
+}
+
+func (a App) acceptFederation(ctx context.Context, args []string) (Result, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return Result{}, Error{
+			cause:    errors.New("accept needs an ID"),
+			helpText: fmt.Sprintf(`Whoops, I need the ID from the invitation, like "%s /federate accept k3x9qp"!`, a.name),
+		}
+	}
+	id := args[0]
+
+	entries, err := a.federation.Get(ctx, federationPrefix+id)
+	if err != nil {
+		return Result{}, a.storeError(err, "getting that shared group")
+	}
+	g := parseFederatedGroup(id, entries)
+	if len(entries) == 0 || g.Invited != a.team {
+		return Result{}, a.invitationNotFound(id)
+	}
+	name := g.Name
+	if len(args) == 2 {
+		name = args[1]
+	}
+	if isForbiddenGroupName(name) {
+		return Result{}, Error{
+			cause:    fmt.Errorf("sharing forbidden group name %q", name),
+			helpText: fmt.Sprintf(`Whoops, %q has a special meaning and can't be used as a group name.`, name),
+		}
+	}
+
+	// Claim the name first, so that a conflict doesn't leave the group
+	// accepted under no name.
+	if err := a.addFederationRef(ctx, name, id); err != nil {
+		return Result{}, err
+	}
+	err = Update(ctx, a.federation, federationPrefix+id, func(entries []string) ([]string, error) {
+		current := parseFederatedGroup(id, entries)
+		if len(entries) == 0 || current.Invited != a.team {
+			return nil, a.invitationNotFound(id)
+		}
+		current.Teams = append(current.Teams, a.team)
+		current.Invited = ""
+		return current.entries(), nil
+	})
+	if err != nil {
+		if err := a.removeFederationRef(ctx, name, id); err != nil {
+			a.logger.Warn("Failed to remove name of unaccepted federated group", "err", err)
+		}
+		return Result{}, a.updateError(err, "accepting that shared group")
+	}
+	return Result{
+		resultType: UpdatedFederation,
+		message: fmt.Sprintf(
+			"Done! The %q group is now shared with workspace %s. Add this workspace's members with \"%s /federate %s add ...\".",
+			name, strings.Join(g.Teams, " and "), a.name, name,
+		),
+	}, nil
+}
+
+func (a App) invitationNotFound(id string) error {
+	return Error{
+		cause:    fmt.Errorf("no invitation %q for %s", id, a.team),
+		helpText: fmt.Sprintf("Whoops, I couldn't find an invitation for this workspace with the ID %q!", id),
+	}
+}
+
+func (a App) updateFederatedMembers(ctx context.Context, name string, add bool, members []string) (Result, error) {
+	if len(members) == 0 {
+		return Result{}, Error{
+			cause:    errors.New("no members to update"),
+			helpText: "Whoops, I need at least one member to add or remove!",
+		}
+	}
+	if add {
+		if err := a.reviewContent(ctx, members...); err != nil {
+			return Result{}, err
+		}
+	}
+
+	members = slices.Clone(members)
+	for i, m := range members {
+		members[i] = parseTypedOption(m).String()
+	}
+
+	refs, err := a.workspaceFederations(ctx)
+	if err != nil {
+		return Result{}, a.storeError(err, "getting that shared group")
+	}
+	id, ok := refs[name]
+	if !ok {
+		return Result{}, a.federationNotFound(name)
+	}
+
+	var count int
+	err = Update(ctx, a.federation, federationPrefix+id, func(entries []string) ([]string, error) {
+		g := parseFederatedGroup(id, entries)
+		if !slices.Contains(g.Teams, a.team) {
+			return nil, Error{
+				cause:    fmt.Errorf("workspace %s left federated group %q", a.team, id),
+				helpText: fmt.Sprintf("Whoops, the %q group isn't shared anymore!", name),
+			}
+		}
+		own := g.Members[a.team]
+		for _, m := range members {
+			if add && !slices.Contains(own, m) {
+				own = append(own, m)
+			} else if !add {
+				own = slices.DeleteFunc(own, func(o string) bool { return o == m })
+			}
+		}
+		g.Members[a.team] = own
+		count = len(own)
+		return g.entries(), nil
+	})
+	if err != nil {
+		return Result{}, a.updateError(err, "updating that shared group")
+	}
+	return Result{
+		resultType: UpdatedFederation,
+		message:    fmt.Sprintf("Done! This workspace has %d %s in the %q group.", count, pluralVerb(count, "member", "members"), name),
+	}, nil
+}
+
+func (a App) leaveFederation(ctx context.Context, name string) (Result, error) {
+	refs, err := a.workspaceFederations(ctx)
+	if err != nil {
+		return Result{}, a.storeError(err, "getting that shared group")
+	}
+	id, ok := refs[name]
+	if !ok {
+		return Result{}, a.federationNotFound(name)
+	}
+	// Leaving withdraws this workspace's consent, which ends the group for
+	// both sides, along with both sides' members.
+	if _, err := a.federation.Delete(ctx, federationPrefix+id); err != nil {
+		return Result{}, a.storeError(err, "removing that shared group")
+	}
+	if err := a.removeFederationRef(ctx, name, id); err != nil {
+		return Result{}, a.storeError(err, "removing that shared group")
+	}
+	return Result{
+		resultType: UpdatedFederation,
+		message:    fmt.Sprintf("Done! This workspace left the %q group, so neither workspace can pick from it anymore.", name),
+	}, nil
+}
+
+func (a App) federationNotFound(name string) error {
+	return Error{
+		cause:    fmt.Errorf("federated group %q not found", name),
+		helpText: fmt.Sprintf(`Whoops, this workspace doesn't share a group named %q! (Type "%s /federate" to see the groups it shares.)`, name, a.name),
+	}
+}
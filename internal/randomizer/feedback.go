@@ -0,0 +1,187 @@
+package randomizer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "stats",
+		permission: permRead,
+		handler:    App.showStats,
+		section:    helpHistory,
+		help:       []string{"*See how often each option was picked:* {{.Name}} /stats snacks"},
+	})
+}
+
+// Feedback is a user's reaction to a selection result, such as a thumbs up or
+// thumbs down on the message that announced it.
+type Feedback struct {
+	// At is the time at which the result was announced. Feedback applies to the
+	// latest selection recorded shortly before this time.
+	At time.Time
+	// User identifies the user giving feedback. Each user has at most one
+	// opinion of each selection.
+	User string
+	// Positive indicates whether the user liked the result.
+	Positive bool
+	// Withdrawn indicates that the user took back earlier feedback.
+	Withdrawn bool
+}
+
+// feedbackWindow is how long before a result's announcement the selection
+// behind it may have been recorded.
+const feedbackWindow = 10 * time.Second
+
+// feedbackRecord returns the name of the record holding feedback on a group's
+// selections. Each entry holds the timestamp of a history entry, the user who
+// gave feedback on it, and "+" or "-", separated by "|".
+func feedbackRecord(group string) string {
+	return recordPrefix + "feedback/" + group
+}
+
+// RecordFeedback attributes feedback to the selection that it responds to,
+// and quietly ignores feedback that doesn't respond to any known selection.
+func (a App) RecordFeedback(ctx context.Context, f Feedback) error {
+	ctx, span := tracer.Start(ctx, "randomizer.RecordFeedback")
+	defer span.End()
+
+	group, selected, err := a.findSelection(ctx, f.At)
+	if err != nil || group == "" {
+		return err
+	}
+
+	record := feedbackRecord(group)
+	entries, err := a.store.Get(ctx, record)
+	if err != nil {
+		return err
+	}
+
+	prefix := selected.Format(historyTimeFormat) + "|" + f.User + "|"
+	entries = slices.DeleteFunc(entries, func(e string) bool { return strings.HasPrefix(e, prefix) })
+	if !f.Withdrawn {
+		sign := "-"
+		if f.Positive {
+			sign = "+"
+		}
+		entries = append(entries, prefix+sign)
+	}
+
+	if len(entries) == 0 {
+		_, err = a.store.Delete(ctx, record)
+		return err
+	}
+	return a.store.Put(ctx, record, entries)
+}
+
+// findSelection returns the group and time of the latest selection recorded
+// within feedbackWindow before at, if any.
+func (a App) findSelection(ctx context.Context, at time.Time) (group string, selected time.Time, err error) {
+	names, err := a.store.List(ctx)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	historyPrefix := historyRecord("")
+	for _, name := range names {
+		candidate, ok := strings.CutPrefix(name, historyPrefix)
+		if !ok {
+			continue
+		}
+		raw, err := a.store.Get(ctx, name)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		for _, entry := range parseHistory(raw) {
+			if entry.Time.After(at) || entry.Time.Before(at.Add(-feedbackWindow)) {
+				continue
+			}
+			if entry.Time.After(selected) {
+				group, selected = candidate, entry.Time
+			}
+		}
+	}
+	return group, selected, nil
+}
+
+type optionStats struct {
+	Name     string
+	Picks    int
+	Up, Down int
+}
+
+func (a App) showStats(request request) (Result, error) {
+	var (
+		ctx  = request.Context
+		name = request.Operand
+	)
+
+	group, err := a.expandGroup(ctx, name)
+	if err != nil {
+		return Result{}, err
+	}
+	rawHistory, err := a.store.Get(ctx, historyRecord(name))
+	if err != nil {
+		return Result{}, a.storeError(err, "getting that group's history")
+	}
+	rawFeedback, err := a.store.Get(ctx, feedbackRecord(name))
+	if err != nil {
+		return Result{}, a.storeError(err, "getting that group's feedback")
+	}
+
+	stats := make(map[string]*optionStats)
+	for _, o := range group {
+		stats[o.Name] = &optionStats{Name: o.Name}
+	}
+	winners := make(map[string]string)
+	for _, entry := range parseHistory(rawHistory) {
+		winners[entry.Time.Format(historyTimeFormat)] = entry.Winner
+		if s, ok := stats[entry.Winner]; ok {
+			s.Picks++
+		}
+	}
+	for _, entry := range rawFeedback {
+		ts, rest, _ := strings.Cut(entry, "|")
+		s, ok := stats[winners[ts]]
+		if !ok {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(rest, "|+"):
+			s.Up++
+		case strings.HasSuffix(rest, "|-"):
+			s.Down++
+		}
+	}
+
+	sorted := make([]*optionStats, 0, len(stats))
+	for _, s := range stats {
+		sorted = append(sorted, s)
+	}
+	slices.SortFunc(sorted, func(x, y *optionStats) int {
+		if x.Picks != y.Picks {
+			return y.Picks - x.Picks
+		}
+		return strings.Compare(x.Name, y.Name)
+	})
+
+	lines := make([]string, len(sorted))
+	for i, s := range sorted {
+		lines[i] = fmt.Sprintf("%s: picked %s", s.Name, pluralize(s.Picks, "time", "times"))
+		if s.Up+s.Down > 0 {
+			lines[i] += fmt.Sprintf(", %d :+1: / %d :-1:", s.Up, s.Down)
+		}
+	}
+
+	return Result{
+		resultType: ShowedStats,
+		message: fmt.Sprintf(
+			"Here's how often I've picked each option in the %q group:\n%s",
+			name, bulletlist(lines),
+		),
+	}, nil
+}
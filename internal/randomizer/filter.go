@@ -0,0 +1,182 @@
+package randomizer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// filter is a predicate over options, built from the expression following a
+// "where" keyword in a selection.
+//
+// The filter syntax supports the boolean operators "and", "or", and "not"
+// (in increasing order of precedence) along with parentheses for grouping.
+// Each term is one of the following:
+//
+//   - label: matches options with a bare tag or attribute key named "label"
+//   - tag=label: the same as above, spelled out for clarity
+//   - name=value: matches the option whose name is "value"
+//   - key=value: matches options whose "key" attribute equals "value"
+//   - key!=value: matches options without a "key" attribute equal to "value"
+type filter func(option) bool
+
+// parseFilter builds a filter from the words that follow "where".
+func parseFilter(args []string) (filter, error) {
+	p := filterParser{tokens: lexFilter(args)}
+	if len(p.tokens) == 0 {
+		return nil, errors.New("empty filter expression")
+	}
+
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %q in filter expression", p.peek())
+	}
+	return f, nil
+}
+
+// lexFilter splits filter arguments into tokens, treating parentheses as
+// separate tokens even when users attach them directly to words.
+func lexFilter(args []string) []string {
+	var tokens []string
+	for _, arg := range args {
+		start := 0
+		for i, r := range arg {
+			if r == '(' || r == ')' {
+				if start < i {
+					tokens = append(tokens, arg[start:i])
+				}
+				tokens = append(tokens, string(r))
+				start = i + 1
+			}
+		}
+		if start < len(arg) {
+			tokens = append(tokens, arg[start:])
+		}
+	}
+	return tokens
+}
+
+type filterParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *filterParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *filterParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) accept(keyword string) bool {
+	if !p.done() && strings.EqualFold(p.peek(), keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orFilter(left, right)
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filter, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("and") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andFilter(left, right)
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseNot() (filter, error) {
+	if p.accept("not") {
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return func(o option) bool { return !inner(o) }, nil
+	}
+	return p.parseTerm()
+}
+
+func (p *filterParser) parseTerm() (filter, error) {
+	if p.done() {
+		return nil, errors.New("filter expression ended unexpectedly")
+	}
+
+	if p.accept("(") {
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, errors.New(`missing ")" in filter expression`)
+		}
+		return inner, nil
+	}
+
+	token := p.peek()
+	switch strings.ToLower(token) {
+	case ")", "and", "or":
+		return nil, fmt.Errorf("unexpected %q in filter expression", token)
+	}
+	p.pos++
+	return termFilter(token), nil
+}
+
+func termFilter(term string) filter {
+	if key, value, ok := strings.Cut(term, "!="); ok {
+		match := termFilter(key + "=" + value)
+		return func(o option) bool { return !match(o) }
+	}
+
+	key, value, ok := strings.Cut(term, "=")
+	if !ok {
+		return func(o option) bool { return o.HasTag(term) }
+	}
+
+	switch strings.ToLower(key) {
+	case "tag":
+		return func(o option) bool { return o.HasTag(value) }
+	case "name":
+		return func(o option) bool { return o.Name == value }
+	default:
+		return func(o option) bool {
+			got, ok := o.Attr(key)
+			return ok && strings.EqualFold(got, value)
+		}
+	}
+}
+
+func andFilter(left, right filter) filter {
+	return func(o option) bool { return left(o) && right(o) }
+}
+
+func orFilter(left, right filter) filter {
+	return func(o option) bool { return left(o) || right(o) }
+}
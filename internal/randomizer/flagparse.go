@@ -0,0 +1,185 @@
+package randomizer
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// flagKind describes how a long flag accepts values.
+type flagKind int
+
+const (
+	// boolFlag may be given alone ("--dry-run") or with an explicit boolean
+	// value ("--dry-run=false").
+	boolFlag flagKind = iota
+	// valueFlag requires a value, given either as "--name=value" or as the
+	// following argument ("--name value").
+	valueFlag
+)
+
+// flagSpecs lists the long flags that the randomizer understands. Flags may
+// appear anywhere in the arguments, and may be repeated.
+var flagSpecs = map[string]flagKind{
+	"after":          valueFlag,
+	"approvals":      valueFlag,
+	"dedupe":         valueFlag,
+	"dry-run":        boolFlag,
+	"emails":         boolFlag,
+	"event":          valueFlag,
+	"event-duration": valueFlag,
+	"exclude":        valueFlag,
+	"for":            valueFlag,
+	"last":           valueFlag,
+	"pick":           valueFlag,
+	"variant":        valueFlag,
+	"verbose":        boolFlag,
+	"weights":        valueFlag,
+}
+
+// shortFlags maps single-letter flags, like "-n 3", to the long flags they
+// abbreviate. Short flags always take their value from the following argument.
+var shortFlags = map[string]string{
+	"n": "pick",
+}
+
+// flagSet holds the values of the long flags in a request. Each flag maps to
+// the values it was given, in order.
+type flagSet map[string][]string
+
+// Bool returns the last value of a boolean flag, or false if it was not set.
+func (f flagSet) Bool(name string) bool {
+	values := f[name]
+	if len(values) == 0 {
+		return false
+	}
+	b, _ := strconv.ParseBool(values[len(values)-1])
+	return b
+}
+
+// Value returns the last value of a flag, and whether it was set at all.
+func (f flagSet) Value(name string) (string, bool) {
+	values := f[name]
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
+
+// parseFlags separates long flags from the positional arguments of a request.
+//
+// An argument of "--" ends flag parsing, so that any remaining arguments are
+// positional even if they look like flags. The count of these literal
+// arguments, which end the positional arguments, lets takeExclusions skip
+// them. A leading flag that names a command (e.g. "--save"), for users who
+// expect command line conventions, is rewritten to the equivalent
+// slash-prefixed command.
+func parseFlags(args []string) (positional []string, literal int, flags flagSet, err error) {
+	flags = make(flagSet)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			literal = len(args) - i - 1
+			break
+		}
+
+		if short, ok := strings.CutPrefix(arg, "-"); ok && shortFlags[short] != "" && i+1 < len(args) {
+			i++
+			flags[shortFlags[short]] = append(flags[shortFlags[short]], args[i])
+			continue
+		}
+
+		spec, ok := strings.CutPrefix(arg, "--")
+		if !ok || spec == "" {
+			positional = append(positional, arg)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(spec, "=")
+		kind, known := flagSpecs[name]
+		switch {
+		case !known && len(positional) == 0 && !hasValue && commandNames[name] != nil:
+			positional = append(positional, "/"+name)
+
+		case !known:
+			return nil, 0, nil, Error{
+				cause: fmt.Errorf("unknown flag %q", arg),
+				helpText: fmt.Sprintf(
+					`Whoops, I don't know the "--%s" flag! (To use an option that starts with "--", put "--" before it.)`,
+					name,
+				),
+			}
+
+		case kind == boolFlag && !hasValue:
+			flags[name] = append(flags[name], "true")
+
+		case kind == boolFlag:
+			if _, err := strconv.ParseBool(value); err != nil {
+				return nil, 0, nil, Error{
+					cause:    fmt.Errorf("invalid boolean %q for flag %q", value, name),
+					helpText: fmt.Sprintf(`Whoops, "--%s" needs to be true or false!`, name),
+				}
+			}
+			flags[name] = append(flags[name], value)
+
+		case kind == valueFlag && !hasValue:
+			if i+1 >= len(args) {
+				return nil, 0, nil, Error{
+					cause:    fmt.Errorf("flag %q requires a value", name),
+					helpText: fmt.Sprintf(`Whoops, "--%s" requires a value!`, name),
+				}
+			}
+			i++
+			flags[name] = append(flags[name], args[i])
+
+		default:
+			flags[name] = append(flags[name], value)
+		}
+	}
+	return positional, literal, flags, nil
+}
+
+// checkFlags returns an error for a flag that the command doesn't use.
+func (c *command) checkFlags(flags flagSet) error {
+	for _, name := range slices.Sorted(maps.Keys(flags)) {
+		if name != "dry-run" && !slices.Contains(c.flags, name) {
+			return Error{
+				cause: fmt.Errorf("flag %q not used by %q", name, c.name),
+				helpText: fmt.Sprintf(
+					`Whoops, the "--%s" flag doesn't do anything there! (To use an option that starts with "--", put "--" before it.)`,
+					name,
+				),
+			}
+		}
+	}
+	return nil
+}
+
+// takeExclusions moves arguments like "-alice", which are shorthand for
+// "--exclude alice", out of args and into the flags, and returns the remaining
+// arguments. The last literal arguments followed "--", and are kept as is.
+func (f flagSet) takeExclusions(args []string, literal int) []string {
+	end := len(args) - literal
+	kept := make([]string, 0, len(args))
+	for i, arg := range args {
+		if excluded, ok := strings.CutPrefix(arg, "-"); ok && i < end && isExclusion(excluded) {
+			f["exclude"] = append(f["exclude"], excluded)
+			continue
+		}
+		kept = append(kept, arg)
+	}
+	return kept
+}
+
+// isExclusion reports whether the text after a single "-" names an option to
+// exclude. Short flags and negative numbers aren't exclusions.
+func isExclusion(name string) bool {
+	if name == "" || shortFlags[name] != "" {
+		return false
+	}
+	first := name[0]
+	return first != '-' && (first < '0' || first > '9')
+}
@@ -1,25 +1,76 @@
 package randomizer
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"slices"
 	"strings"
 )
 
+func init() {
+	registerCommand(&command{
+		name:       "list",
+		operand:    operandNone,
+		permission: permRead,
+		flags:      []string{"after"},
+		handler:    App.listGroups,
+		section:    helpGroups,
+		help:       []string{"*List your current channel's groups:* {{.Name}} /list"},
+	})
+	registerCommand(&command{
+		name:       "show",
+		permission: permRead,
+		flags:      []string{"verbose"},
+		handler:    App.showGroup,
+		section:    helpGroups,
+		help: []string{
+			"*Show the options in a group:* {{.Name}} /show snacks",
+			"*See who added each option to a group:* {{.Name}} /show snacks --verbose",
+		},
+	})
+	registerCommand(&command{
+		name:       "save",
+		permission: permWrite,
+		handler:    App.saveGroup,
+		section:    helpBasics,
+		help: []string{
+			"*Save a group:* {{.Name}} /save snacks chips pretzels trailmix",
+			"*Tag options in a group:* {{.Name}} /save team alice#backend bob#frontend#ooo",
+			"*Keep a # in an option:* {{.Name}} /save keys F\\#minor C#",
+			"*Preview changes without saving them:* {{.Name}} /save snacks chips popcorn --dry-run",
+			"*Use options that start with --:* {{.Name}} /save flags -- --fast --slow",
+		},
+	})
+	registerCommand(&command{
+		name:       "delete",
+		permission: permWrite,
+		handler:    App.deleteGroup,
+		section:    helpGroups,
+		help:       []string{"*Delete a group:* {{.Name}} /delete snacks"},
+	})
+}
+
+// listPageSize is the number of groups that /list shows at a time, so that
+// channels with many groups get messages of a reasonable size.
+const listPageSize = 50
+
 func (a App) listGroups(request request) (Result, error) {
 	var (
-		ctx = request.Context
+		ctx      = request.Context
+		after, _ = request.Flags.Value("after")
 	)
 
-	groups, err := a.store.List(ctx)
+	groups, more, err := a.listPage(ctx, after)
 	if err != nil {
-		return Result{}, Error{
-			cause:    err,
-			helpText: "Whoops, I had trouble getting this channel's groups. Please try again later!",
-		}
+		return Result{}, a.storeError(err, "getting this channel's groups")
 	}
 
+	if len(groups) == 0 && after != "" {
+		return Result{
+			resultType: ListedGroups,
+			message:    fmt.Sprintf("There are no more groups in this channel after %q.", after),
+		}, nil
+	}
 	if len(groups) == 0 {
 		return Result{
 			resultType: ListedGroups,
@@ -27,15 +78,53 @@ func (a App) listGroups(request request) (Result, error) {
 		}, nil
 	}
 
-	slices.Sort(groups)
+	// Icons are decoration, so the list is still useful without them.
+	icons, _ := a.getIcons(ctx)
+	lines := make([]string, len(groups))
+	for i, group := range groups {
+		lines[i] = group
+		if icon := icons[group]; icon != "" {
+			lines[i] = icon + " " + group
+		}
+	}
 
-	return Result{
+	intro := "The following groups are available in this channel:"
+	if after != "" {
+		intro = fmt.Sprintf("More groups in this channel, after %q:", after)
+	}
+	result := Result{
 		resultType: ListedGroups,
-		message: fmt.Sprintf(
-			"The following groups are available in this channel:\n%s",
-			bulletlist(groups),
-		),
-	}, nil
+		message:    fmt.Sprintf("%s\n%s", intro, bulletlist(lines)),
+	}
+	if more {
+		result.more = "/list --after " + groups[len(groups)-1]
+		result.message += fmt.Sprintf("\nThere are more groups. To see them, use: %s %s", a.name, result.more)
+	}
+	return result, nil
+}
+
+// listPage returns up to listPageSize groups that sort after the given name,
+// skipping records, along with whether more groups follow them.
+func (a App) listPage(ctx context.Context, after string) ([]string, bool, error) {
+	var groups []string
+	for {
+		names, more, err := ListAfter(ctx, a.store, after, listPageSize+1-len(groups))
+		if err != nil {
+			return nil, false, err
+		}
+		for _, name := range names {
+			if !isRecordName(name) {
+				groups = append(groups, name)
+			}
+		}
+		if len(groups) > listPageSize {
+			return groups[:listPageSize], true, nil
+		}
+		if !more || len(names) == 0 {
+			return groups, false, nil
+		}
+		after = names[len(names)-1]
+	}
 }
 
 func (a App) showGroup(request request) (Result, error) {
@@ -44,12 +133,9 @@ func (a App) showGroup(request request) (Result, error) {
 		name = request.Operand
 	)
 
-	group, err := a.store.Get(ctx, name)
+	group, err := a.getGroup(ctx, name)
 	if err != nil {
-		return Result{}, Error{
-			cause:    err,
-			helpText: "Whoops, I had trouble getting that group. Please try again later!",
-		}
+		return Result{}, a.storeError(err, "getting that group")
 	}
 
 	if len(group) == 0 {
@@ -59,13 +145,20 @@ func (a App) showGroup(request request) (Result, error) {
 		}
 	}
 
-	slices.Sort(group)
+	lines := displayNames(group)
+	if request.Flags.Bool("verbose") {
+		known, err := a.getProvenance(ctx, name)
+		if err != nil {
+			return Result{}, a.storeError(err, "getting that group's history")
+		}
+		lines = provenanceLines(group, known)
+	}
 
 	return Result{
 		resultType: ShowedGroup,
 		message: fmt.Sprintf(
-			"The %q group has the following options:\n%s",
-			name, bulletlist(group),
+			"%sThe %q group has the following options:\n%s",
+			a.groupIcon(ctx, name), name, bulletlist(lines),
 		),
 	}, nil
 }
@@ -94,28 +187,56 @@ func (a App) saveGroup(request request) (Result, error) {
 		}
 	}
 
+	typed, err := parseTypedOptions(options)
+	if err != nil {
+		return Result{}, err
+	}
+	options = storedOptions(typed)
+
+	if err := a.reviewContent(ctx, append([]string{name}, displayNames(options)...)...); err != nil {
+		return Result{}, err
+	}
+
 	if err := a.store.Put(ctx, name, options); err != nil {
-		return Result{}, Error{
-			cause:    err,
-			helpText: "Whoops, I had trouble saving that group. Please try again later!",
-		}
+		return Result{}, a.storeError(err, "saving that group")
 	}
 
-	slices.Sort(options)
+	if err := a.recordProvenance(ctx, name, "/save", optionNames(typed)); err != nil {
+		a.logger.Warn("Failed to record option provenance", "group", name, "err", err)
+	}
+
+	// Saving a group confirms all of its options, so that options added to a
+	// group with an expiry policy don't start out stale.
+	if policy, err := a.getExpiryPolicy(ctx, name); err == nil && policy != nil {
+		if err := a.confirmOptions(ctx, name, optionNames(typed)); err != nil {
+			a.logger.Warn("Failed to confirm saved options", "group", name, "err", err)
+		}
+	}
 
 	return Result{
 		resultType: SavedGroup,
 		message: fmt.Sprintf(
 			"Done! The %q group was saved in this channel with the following options:\n%s",
-			name, bulletlist(options),
+			name, bulletlist(displayNames(options)),
 		),
 	}, nil
 }
 
 func isForbiddenGroupName(name string) bool {
-	// Keep "/" reserved as a prefix for flags. Also block "help," as it has
-	// special handling.
-	return name == "help" || strings.HasPrefix(name, "/")
+	// Keep "/" reserved as a prefix for flags, and "+" as a prefix for explicit
+	// group references. Also block "help," as it has special handling.
+	return name == "help" || strings.HasPrefix(name, "/") || strings.HasPrefix(name, "+")
+}
+
+// displayNames returns the user-facing forms of the provided stored options,
+// in their stored order.
+func displayNames(raw []string) []string {
+	options := parseOptions(raw)
+	names := make([]string, len(options))
+	for i, o := range options {
+		names[i] = o.displayName()
+	}
+	return names
 }
 
 func (a App) deleteGroup(request request) (Result, error) {
@@ -124,14 +245,18 @@ func (a App) deleteGroup(request request) (Result, error) {
 		name = request.Operand
 	)
 
-	existed, err := a.store.Delete(ctx, name)
-	if err != nil {
+	if isRecordName(name) {
 		return Result{}, Error{
-			cause:    err,
-			helpText: "Whoops, I had trouble deleting that group. Please try again later!",
+			cause:    errors.New("group does not exist"),
+			helpText: "Whoops, I can't find that group in this channel!",
 		}
 	}
 
+	existed, err := a.store.Delete(ctx, name)
+	if err != nil {
+		return Result{}, a.storeError(err, "deleting that group")
+	}
+
 	if !existed {
 		return Result{}, Error{
 			cause:    errors.New("group does not exist"),
@@ -139,6 +264,17 @@ func (a App) deleteGroup(request request) (Result, error) {
 		}
 	}
 
+	// The history of a deleted group is no longer useful, and leaving it behind
+	// would attach it to any new group that reuses the name.
+	a.store.Delete(ctx, historyRecord(name))
+	a.store.Delete(ctx, feedbackRecord(name))
+	a.store.Delete(ctx, expiryRecord(name))
+	a.store.Delete(ctx, variantsRecord(name))
+	a.store.Delete(ctx, provenanceRecord(name))
+	a.store.Delete(ctx, capRecord(name))
+	a.store.Delete(ctx, rotationRecord(name))
+	a.putIcon(ctx, name, "")
+
 	return Result{
 		resultType: DeletedGroup,
 		message:    fmt.Sprintf("Done! The %q group was deleted.", name),
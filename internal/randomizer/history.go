@@ -0,0 +1,294 @@
+package randomizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "export",
+		permission: permRead,
+		flags:      []string{"emails"},
+		handler:    App.exportHistory,
+		section:    helpHistory,
+		help:       []string{"*Export a group's selection history:* {{.Name}} /export snacks"},
+	})
+	registerCommand(&command{
+		name:       "history",
+		operand:    operandOptional,
+		permission: permRead,
+		flags:      []string{"last"},
+		handler:    App.showHistory,
+		section:    helpHistory,
+		help: []string{
+			"*See recent picks in this channel:* {{.Name}} /history",
+			"*See a group's last 20 picks:* {{.Name}} /history snacks --last 20",
+		},
+	})
+}
+
+// maxHistory is the number of past selections kept for each group.
+const maxHistory = 100
+
+// defaultHistoryLength is the number of past selections that /history shows
+// without --last.
+const defaultHistoryLength = 10
+
+// historyRecord returns the name of the record holding a group's selection
+// history. Each entry is a UTC timestamp and the name of the winning option,
+// separated by "|", so that entries sort in chronological order. The winner
+// may be followed by a space and the ID of the full result.
+func historyRecord(group string) string {
+	return recordPrefix + "history/" + group
+}
+
+const historyTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+type historyEntry struct {
+	Time     time.Time
+	Winner   string
+	ResultID string
+}
+
+func parseHistory(raw []string) []historyEntry {
+	entries := make([]historyEntry, 0, len(raw))
+	for _, r := range raw {
+		ts, rest, ok := strings.Cut(r, "|")
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(historyTimeFormat, ts)
+		if err != nil {
+			continue
+		}
+		winner, id, _ := strings.Cut(rest, " ")
+		entries = append(entries, historyEntry{Time: t, Winner: winner, ResultID: id})
+	}
+	slices.SortFunc(entries, func(a, b historyEntry) int { return a.Time.Compare(b.Time) })
+	return entries
+}
+
+// recordSelection appends the winners of a selection to a group's history.
+// Like onboarding, history is never critical to the request itself, so it
+// logs and gives up on any store error.
+func (a App) recordSelection(ctx context.Context, group string, winners []string, resultID string) {
+	if a.noHistory {
+		return
+	}
+
+	record := historyRecord(group)
+	now := a.now().UTC().Format(historyTimeFormat)
+	entries := make([]string, len(winners))
+	for i, winner := range winners {
+		entries[i] = now + "|" + winner
+		if resultID != "" {
+			entries[i] += " " + resultID
+		}
+	}
+
+	_, err := a.writeNonCritical(ctx, "selection history", func(ctx context.Context) error {
+		return Update(ctx, a.store, record, func(history []string) ([]string, error) {
+			slices.Sort(history)
+			if keep := maxHistory - len(entries); len(history) > keep {
+				history = history[len(history)-max(keep, 0):]
+			}
+			return append(history, entries...), nil
+		})
+	})
+	if err != nil {
+		a.logger.Warn("Failed to record selection history", "group", group, "err", err)
+	}
+}
+
+func (a App) exportHistory(request request) (Result, error) {
+	var (
+		ctx  = request.Context
+		name = request.Operand
+	)
+
+	raw, err := a.store.Get(ctx, historyRecord(name))
+	if err != nil {
+		return Result{}, a.storeError(err, "getting that group's history")
+	}
+
+	history := parseHistory(raw)
+	if len(history) == 0 {
+		return Result{}, Error{
+			cause:    errors.New("no history for group"),
+			helpText: fmt.Sprintf("Whoops, I haven't made any selections from the %q group yet!", name),
+		}
+	}
+
+	withEmails := request.Flags.Bool("emails")
+	if withEmails && a.resolveEmail == nil {
+		return Result{}, Error{
+			cause:    errors.New("email export not enabled"),
+			helpText: "Whoops, exporting email addresses isn't turned on in this workspace. Ask whoever runs the randomizer about enabling it!",
+		}
+	}
+
+	var (
+		table  strings.Builder
+		emails = make(map[string]string)
+	)
+	if withEmails {
+		table.WriteString("| Date (UTC) | Selected | Email | Result ID |\n| --- | --- | --- | --- |\n")
+	} else {
+		table.WriteString("| Date (UTC) | Selected | Result ID |\n| --- | --- | --- |\n")
+	}
+	for _, entry := range history {
+		fmt.Fprintf(&table, "| %s | %s |", entry.Time.Format("2006-01-02 15:04"), escapeMarkdownCell(a.plainMentions(ctx, entry.Winner)))
+		if withEmails {
+			fmt.Fprintf(&table, " %s |", escapeMarkdownCell(a.mentionEmails(ctx, entry.Winner, emails)))
+		}
+		fmt.Fprintf(&table, " %s |\n", entry.ResultID)
+	}
+
+	message := fmt.Sprintf(
+		"Here are the last %d selections from the %q group, as a Markdown table you can paste into a doc:\n```\n%s```",
+		len(history), name, table.String(),
+	)
+	if withEmails {
+		var missing int
+		for _, email := range emails {
+			if email == "" {
+				missing++
+			}
+		}
+		if missing > 0 {
+			message += fmt.Sprintf("\n_I couldn't find email addresses for %d of the %d people listed. Their profiles may hide them._", missing, len(emails))
+		}
+		a.logger.Info("Exported email addresses", "group", name, "user", a.user, "users", len(emails), "missing", missing)
+	}
+
+	return Result{resultType: ExportedHistory, message: message}, nil
+}
+
+// showHistory lists the most recent winners from a group, or without a group,
+// the most recent selections in the channel.
+func (a App) showHistory(request request) (Result, error) {
+	var (
+		ctx   = request.Context
+		group = request.Operand
+	)
+
+	n := defaultHistoryLength
+	if value, ok := request.Flags.Value("last"); ok {
+		var err error
+		if n, err = strconv.Atoi(value); err != nil || n < 1 {
+			return Result{}, Error{
+				cause:    fmt.Errorf("invalid history length %q", value),
+				helpText: fmt.Sprintf(`Whoops, I need a number of picks to show, like "%s /history --last 20"!`, a.name),
+			}
+		}
+	}
+
+	if group == "" {
+		return a.showChannelHistory(ctx, n)
+	}
+
+	raw, err := a.store.Get(ctx, historyRecord(group))
+	if err != nil {
+		return Result{}, a.storeError(err, "getting that group's history")
+	}
+	history := parseHistory(raw)
+	if len(history) == 0 {
+		return Result{}, Error{
+			cause:    errors.New("no history for group"),
+			helpText: fmt.Sprintf("Whoops, I haven't made any selections from the %q group yet!", group),
+		}
+	}
+
+	var lines []string
+	for _, entry := range slices.Backward(history) {
+		if len(lines) == n {
+			break
+		}
+		lines = append(lines, historyLine(entry.Time, inlinelist([]string{entry.Winner}), entry.ResultID))
+	}
+	return Result{
+		resultType: ShowedHistory,
+		message: fmt.Sprintf(
+			"Here %s the last %d %s from the %q group, newest first:\n%s",
+			pluralVerb(len(lines), "is", "are"), len(lines), pluralVerb(len(lines), "pick", "picks"), group, bulletlist(lines),
+		),
+	}, nil
+}
+
+func (a App) showChannelHistory(ctx context.Context, n int) (Result, error) {
+	entries, err := a.store.Get(ctx, resultsRecord)
+	if err != nil {
+		return Result{}, a.storeError(err, "getting this channel's history")
+	}
+	slices.Sort(entries)
+
+	var lines []string
+	for _, entry := range slices.Backward(entries) {
+		if len(lines) == n {
+			break
+		}
+		record, ok := parseResultRecord(entry)
+		if !ok {
+			continue
+		}
+		source := ""
+		if record.Group != "" {
+			source = fmt.Sprintf(" from %q", record.Group)
+		}
+		lines = append(lines, historyLine(record.At, inlinelist(record.Choices)+source, record.ID))
+	}
+	if len(lines) == 0 {
+		return Result{}, Error{
+			cause:    errors.New("no history for channel"),
+			helpText: "Whoops, I haven't made any selections in this channel yet!",
+		}
+	}
+	return Result{
+		resultType: ShowedHistory,
+		message: fmt.Sprintf(
+			"Here %s the last %d %s in this channel, newest first:\n%s",
+			pluralVerb(len(lines), "is", "are"), len(lines), pluralVerb(len(lines), "selection", "selections"), bulletlist(lines),
+		),
+	}, nil
+}
+
+func historyLine(at time.Time, picked, resultID string) string {
+	line := slackDate(at, false) + ": " + picked
+	if resultID != "" {
+		line += " (" + resultID + ")"
+	}
+	return line
+}
+
+// mentionEmails returns the email addresses of the users mentioned in an
+// option, separated by commas. It records each address in emails, keyed by
+// user ID, with an empty address for a user whose address it couldn't find.
+func (a App) mentionEmails(ctx context.Context, option string, emails map[string]string) string {
+	var found []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(option, -1) {
+		id := match[1]
+		email, ok := emails[id]
+		if !ok {
+			var err error
+			if email, err = a.resolveEmail(ctx, id); err != nil {
+				a.logger.Warn("Failed to look up email address", "user", id, "err", err)
+				email = ""
+			}
+			emails[id] = email
+		}
+		if email != "" {
+			found = append(found, email)
+		}
+	}
+	return strings.Join(found, ", ")
+}
+
+func escapeMarkdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,142 @@
+package randomizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "icon",
+		permission: permWrite,
+		handler:    App.setIcon,
+		section:    helpGroups,
+		help:       []string{"*Give a group an icon:* {{.Name}} /icon snacks :popcorn:"},
+	})
+}
+
+// Group icons help people tell groups apart at a glance. Each group may have
+// an emoji, shown before its name in /list, at the top of /show, and before
+// the results of selections from it.
+
+// iconsRecord holds the icons of every group in a channel, with entries of the
+// form "<group>|<icon>", so that /list can read them all at once.
+const iconsRecord = recordPrefix + "icons"
+
+// isIcon accepts an emoji shortcode like ":pizza:", or a short run of Unicode
+// symbols like "🍕" for clients that send emoji as is.
+func isIcon(s string) bool {
+	if emojiPattern.MatchString(s) {
+		return true
+	}
+	if s == "" || utf8.RuneCountInString(s) > 8 {
+		return false
+	}
+	for _, r := range s {
+		if r < 0x2000 {
+			return false
+		}
+	}
+	return true
+}
+
+// getIcons returns the icon of each group in the channel that has one.
+func (a App) getIcons(ctx context.Context) (map[string]string, error) {
+	entries, err := a.store.Get(ctx, iconsRecord)
+	if err != nil {
+		return nil, err
+	}
+	icons := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if i := strings.LastIndexByte(entry, '|'); i > 0 {
+			icons[entry[:i]] = entry[i+1:]
+		}
+	}
+	return icons, nil
+}
+
+// groupIcon returns a group's icon followed by a space, or an empty string if
+// it has no icon. Icons are decoration, so a failure to read them is ignored.
+func (a App) groupIcon(ctx context.Context, group string) string {
+	icons, err := a.getIcons(ctx)
+	if err != nil || icons[group] == "" {
+		return ""
+	}
+	return icons[group] + " "
+}
+
+// putIcon sets or, if icon is empty, removes a group's icon.
+func (a App) putIcon(ctx context.Context, group, icon string) error {
+	entries, err := a.store.Get(ctx, iconsRecord)
+	if err != nil {
+		return err
+	}
+	entries = slices.DeleteFunc(entries, func(entry string) bool {
+		return strings.HasPrefix(entry, group+"|")
+	})
+	if icon != "" {
+		entries = append(entries, group+"|"+icon)
+		slices.Sort(entries)
+	}
+	if len(entries) == 0 {
+		_, err = a.store.Delete(ctx, iconsRecord)
+		return err
+	}
+	return a.store.Put(ctx, iconsRecord, entries)
+}
+
+func (a App) setIcon(request request) (Result, error) {
+	var (
+		ctx   = request.Context
+		group = request.Operand
+		args  = request.Args
+	)
+
+	if len(args) == 0 {
+		icons, err := a.getIcons(ctx)
+		if err != nil {
+			return Result{}, a.storeError(err, "getting that group's icon")
+		}
+		message := fmt.Sprintf("The %q group doesn't have an icon.", group)
+		if icon := icons[group]; icon != "" {
+			message = fmt.Sprintf("The %q group's icon is %s.", group, icon)
+		}
+		return Result{resultType: UpdatedIcon, message: message}, nil
+	}
+
+	if len(args) > 1 || !strings.EqualFold(args[0], "off") && !isIcon(args[0]) {
+		return Result{}, Error{
+			cause:    fmt.Errorf("invalid icon %q", strings.Join(args, " ")),
+			helpText: `Whoops, a group's icon needs to be a single emoji like ":pizza:", or "off" to remove it!`,
+		}
+	}
+
+	stored, err := a.getGroup(ctx, group)
+	if err != nil {
+		return Result{}, a.storeError(err, "getting that group")
+	}
+	if len(stored) == 0 {
+		return Result{}, Error{
+			cause:    errors.New("group does not exist"),
+			helpText: "Whoops, I can't find that group in this channel. (Use the /save flag to create it!)",
+		}
+	}
+
+	icon := args[0]
+	if strings.EqualFold(icon, "off") {
+		icon = ""
+	}
+	if err := a.putIcon(ctx, group, icon); err != nil {
+		return Result{}, a.storeError(err, "updating that group's icon")
+	}
+
+	message := fmt.Sprintf("Done! The %q group's icon is now %s.", group, icon)
+	if icon == "" {
+		message = fmt.Sprintf("Done! The %q group no longer has an icon.", group)
+	}
+	return Result{resultType: UpdatedIcon, message: message}, nil
+}
@@ -1,6 +1,9 @@
 package randomizer
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
 func inlinelist(items []string) string {
 	var b strings.Builder
@@ -26,3 +29,14 @@ func bulletlist(items []string) string {
 	}
 	return b.String()
 }
+
+func numberedlist(items []string) string {
+	var b strings.Builder
+	for i, item := range items {
+		if i > 0 {
+			b.WriteRune('\n')
+		}
+		fmt.Fprintf(&b, "%d. %s", i+1, item)
+	}
+	return b.String()
+}
@@ -0,0 +1,63 @@
+package randomizer
+
+import (
+	"context"
+	"regexp"
+)
+
+// Slack represents user mentions as "<@U123>", and may include the user's
+// name at the time of writing as in "<@U123|alice>". Options store only the
+// canonical form, since names change and Slack renders the current one.
+var mentionPattern = regexp.MustCompile(`<@([UW][A-Z0-9]+)(?:\|[^>]*)?>`)
+
+func canonicalMentions(args []string) []string {
+	for i, arg := range args {
+		args[i] = mentionPattern.ReplaceAllString(arg, "<@$1>")
+	}
+	return args
+}
+
+// MentionedUsers returns the IDs of the users mentioned in s, like a choice in
+// a Result, in the order of their mentions.
+func MentionedUsers(s string) []string {
+	var users []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(s, -1) {
+		users = append(users, match[1])
+	}
+	return users
+}
+
+// NameResolver returns the current display name of a Slack user.
+type NameResolver func(ctx context.Context, userID string) (string, error)
+
+// WithNameResolver enables the App to render user mentions as plain names in
+// output that Slack won't format, like calendar events and exported tables.
+func WithNameResolver(resolve NameResolver) AppOption {
+	return func(a *App) { a.resolveName = resolve }
+}
+
+// EmailResolver returns the email address of a Slack user, or an empty string
+// with a nil error if the user's profile doesn't share one.
+type EmailResolver func(ctx context.Context, userID string) (string, error)
+
+// WithEmailResolver lets "/export --emails" list the email addresses of the
+// users in a group's history, for workflows that contact winners outside of
+// Slack. Operators should enable it only where their users have agreed to it.
+func WithEmailResolver(resolve EmailResolver) AppOption {
+	return func(a *App) { a.resolveEmail = resolve }
+}
+
+// plainMentions replaces user mentions in s with plain "@name" text. Without a
+// resolver, or if a name can't be resolved, the mention falls back to the
+// user's ID.
+func (a App) plainMentions(ctx context.Context, s string) string {
+	return mentionPattern.ReplaceAllStringFunc(s, func(mention string) string {
+		id := mentionPattern.FindStringSubmatch(mention)[1]
+		if a.resolveName != nil {
+			if name, err := a.resolveName(ctx, id); err == nil && name != "" {
+				return "@" + name
+			}
+		}
+		return "@" + id
+	})
+}
@@ -0,0 +1,196 @@
+package randomizer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "merge",
+		permission: permWrite,
+		flags:      []string{"weights", "dedupe"},
+		handler:    App.mergeGroups,
+		section:    helpGroups,
+		help: []string{
+			"*Combine groups into a new group:* {{.Name}} /merge everyone seniors juniors",
+			"*Count one group's options twice:* {{.Name}} /merge everyone seniors juniors --weights 2,1",
+			"*Keep the highest weight for options in both:* {{.Name}} /merge everyone seniors juniors --dedupe max",
+		},
+	})
+}
+
+// Merging saves a group built from the options of other groups, so that a
+// composite pool like "everyone, with seniors counted twice" can be described
+// once instead of maintained by hand. Each source group's options keep their
+// own weights, multiplied by that group's weight from --weights.
+//
+// The merged group is an ordinary group holding a copy of the options, so
+// later changes to the source groups don't affect it until it's merged again.
+
+// mergeDedupe says what to do with an option that appears in more than one
+// source group, or more than once in the same group.
+type mergeDedupe string
+
+const (
+	// dedupeSum gives the option the sum of its weights, so that someone in
+	// two groups is twice as likely to be picked.
+	dedupeSum mergeDedupe = "sum"
+	// dedupeMax gives the option its highest weight.
+	dedupeMax mergeDedupe = "max"
+	// dedupeFirst keeps the option as it first appears, and ignores the rest.
+	dedupeFirst mergeDedupe = "first"
+)
+
+func (a App) mergeGroups(request request) (Result, error) {
+	var (
+		ctx     = request.Context
+		name    = request.Operand
+		sources = request.Args
+	)
+
+	if isForbiddenGroupName(name) {
+		return Result{}, Error{
+			cause: fmt.Errorf("merging into forbidden group name %q", name),
+			helpText: fmt.Sprintf(
+				`Whoops, %q has a special meaning and can't be used as a group name. (Type "%s help" to learn more!)`,
+				name, a.name,
+			),
+		}
+	}
+	if len(sources) == 0 {
+		return Result{}, Error{
+			cause: errors.New("no groups to merge"),
+			helpText: fmt.Sprintf(
+				`Whoops, I need the groups to merge, like "%s /merge %s seniors juniors"!`,
+				a.name, name,
+			),
+		}
+	}
+
+	weights, err := a.mergeWeights(request.Flags, len(sources))
+	if err != nil {
+		return Result{}, err
+	}
+	dedupe := dedupeSum
+	if value, ok := request.Flags.Value("dedupe"); ok {
+		dedupe = mergeDedupe(strings.ToLower(value))
+		if dedupe != dedupeSum && dedupe != dedupeMax && dedupe != dedupeFirst {
+			return Result{}, Error{
+				cause:    fmt.Errorf("invalid dedupe rule %q", value),
+				helpText: `Whoops, --dedupe needs to be "sum", "max", or "first"!`,
+			}
+		}
+	}
+
+	var (
+		merged      []option
+		totals      []int
+		sourceNames = make([]string, len(sources))
+	)
+	for i, source := range sources {
+		sourceNames[i] = groupReference(source)
+		options, err := a.expandGroup(ctx, sourceNames[i])
+		if err != nil {
+			return Result{}, err
+		}
+		for _, o := range options {
+			weight := o.Weight() * weights[i]
+			j := indexOptionName(merged, o.Name)
+			if j < 0 {
+				merged = append(merged, o.withoutTags([]string{weightAttr}))
+				totals = append(totals, weight)
+				continue
+			}
+			switch dedupe {
+			case dedupeSum:
+				totals[j] += weight
+			case dedupeMax:
+				totals[j] = max(totals[j], weight)
+			}
+		}
+	}
+
+	if len(merged) < 2 {
+		return Result{}, Error{
+			cause:    errors.New("too few options to save"),
+			helpText: "Whoops, I need at least two options to save a group!",
+		}
+	}
+
+	if err := a.reviewContent(ctx, name); err != nil {
+		return Result{}, err
+	}
+
+	stored := make([]string, len(merged))
+	for i, o := range merged {
+		if weight := min(totals[i], maxWeight); weight != 1 {
+			o = o.withTags([]string{weightAttr + "=" + strconv.Itoa(weight)})
+		}
+		stored[i] = o.String()
+	}
+
+	if err := a.store.Put(ctx, name, stored); err != nil {
+		return Result{}, a.storeError(err, "saving that group")
+	}
+
+	if err := a.recordProvenance(ctx, name, "/merge", optionNames(merged)); err != nil {
+		a.logger.Warn("Failed to record option provenance", "group", name, "err", err)
+	}
+
+	return Result{
+		resultType: MergedGroups,
+		message: fmt.Sprintf(
+			"Done! The %q group was saved in this channel from %s, with the following options:\n%s",
+			name, inlinelist(sourceNames), bulletlist(displayNames(stored)),
+		),
+	}, nil
+}
+
+// mergeWeights returns the weight of each of n source groups, which are all 1
+// unless the request has a comma-separated --weights flag.
+func (a App) mergeWeights(flags flagSet, n int) ([]int, error) {
+	weights := make([]int, n)
+	value, ok := flags.Value("weights")
+	if !ok {
+		for i := range weights {
+			weights[i] = 1
+		}
+		return weights, nil
+	}
+
+	fields := strings.Split(value, ",")
+	if len(fields) != n {
+		return nil, Error{
+			cause: fmt.Errorf("got %d weights for %d groups", len(fields), n),
+			helpText: fmt.Sprintf(
+				"Whoops, I need one weight for each group to merge, like --weights %s!",
+				strings.TrimSuffix(strings.Repeat("1,", n), ","),
+			),
+		}
+	}
+	for i, field := range fields {
+		weight, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || weight < 1 || weight > maxWeight {
+			return nil, Error{
+				cause:    fmt.Errorf("invalid merge weight %q", field),
+				helpText: fmt.Sprintf("Whoops, weights need to be whole numbers from 1 to %d, like --weights 2,1!", maxWeight),
+			}
+		}
+		weights[i] = weight
+	}
+	return weights, nil
+}
+
+// indexOptionName returns the index of the first option with the given name,
+// ignoring case, or -1 if there is none.
+func indexOptionName(options []option, name string) int {
+	for i, o := range options {
+		if strings.EqualFold(o.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,39 @@
+package randomizer
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// onboard records that the store has seen its first request, and returns a
+// welcome message if it had not been seen before. Since onboarding is never
+// critical to the request itself, callers should continue in spite of any store
+// error it returns.
+func (a App) onboard(ctx context.Context) (string, error) {
+	seen, err := a.store.Get(ctx, onboardingRecord)
+	if err != nil || len(seen) > 0 {
+		return "", err
+	}
+
+	now := []string{a.now().UTC().Format(time.RFC3339)}
+	_, err = a.writeNonCritical(ctx, "onboarding state", func(ctx context.Context) error {
+		return a.store.Put(ctx, onboardingRecord, now)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ReplaceAll(welcomeMessageTemplate, "{{.Name}}", a.name), nil
+}
+
+// welcomeMessageTemplate follows the same substitution rules as
+// helpMessageTemplate.
+const welcomeMessageTemplate = `:wave: Welcome! This looks like the first time I've been used here, so here are a few ways to get started:
+• *Try a quick pick:* {{.Name}} pizza tacos ramen
+• *Create a sample group:* {{.Name}} /save lunch pizza tacos ramen
+• *Pick from it later:* {{.Name}} lunch
+• *Set defaults, like a group to pick from when none is named:* {{.Name}} /config group lunch
+• *See everything I can do:* {{.Name}} help
+
+`
@@ -0,0 +1,150 @@
+package randomizer
+
+import "strings"
+
+// option represents a single randomizer option along with any metadata
+// attached to it.
+//
+// Users attach metadata to options with a compact "#" syntax, e.g.
+// "sushi#cheap#cuisine=japanese", where each "#"-separated tag is either a
+// bare label ("cheap") or a key=value attribute ("cuisine=japanese"). A "\#"
+// keeps a literal "#" in the name, as in "F\#minor".
+//
+// The stored form of an option separates its tags with " #" instead, as in
+// "sushi #cheap #cuisine=japanese". Typed options never contain spaces, so
+// options saved before metadata existed, like "F#minor", can't be mistaken
+// for tagged ones and are taken literally.
+type option struct {
+	Name string
+	Tags []string
+}
+
+// tagSeparator separates an option's name and tags in its stored form.
+const tagSeparator = " #"
+
+// parseOption splits the metadata out of a stored option.
+func parseOption(stored string) option {
+	name, rest, ok := strings.Cut(stored, tagSeparator)
+	if !ok || name == "" {
+		return option{Name: stored}
+	}
+	var tags []string
+	for tag := range strings.SplitSeq(rest, tagSeparator) {
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return option{Name: name, Tags: tags}
+}
+
+// parseTypedOption splits the metadata out of an option as typed by a user.
+//
+// Strings that don't look like they contain metadata are taken literally: a
+// leading "#" (as in "#general") or trailing "#" (as in "C#") is part of the
+// name. For Slack's special formatting sequences (like "<@U123>" mentions),
+// metadata may only follow the closing ">".
+func parseTypedOption(arg string) option {
+	start := 0
+	if strings.HasPrefix(arg, "<") {
+		if end := strings.IndexByte(arg, '>'); end >= 0 {
+			start = end + 1
+		}
+	}
+
+	i := indexUnescapedHash(arg[start:])
+	if i < 0 {
+		return option{Name: unescapeHash(arg)}
+	}
+	name, rest := arg[:start+i], arg[start+i+1:]
+	if name == "" || rest == "" {
+		return option{Name: unescapeHash(arg)}
+	}
+
+	var tags []string
+	for tag := range strings.SplitSeq(rest, "#") {
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return option{Name: unescapeHash(name), Tags: tags}
+}
+
+// indexUnescapedHash returns the index of the first "#" in s that isn't
+// escaped with a backslash, or -1 if there isn't one.
+func indexUnescapedHash(s string) int {
+	for i := range len(s) {
+		if s[i] == '#' && (i == 0 || s[i-1] != '\\') {
+			return i
+		}
+	}
+	return -1
+}
+
+func unescapeHash(s string) string {
+	return strings.ReplaceAll(s, `\#`, "#")
+}
+
+func parseOptions(raw []string) []option {
+	options := make([]option, len(raw))
+	for i, r := range raw {
+		options[i] = parseOption(r)
+	}
+	return options
+}
+
+// String returns the stored form of the option, including its metadata.
+func (o option) String() string {
+	if len(o.Tags) == 0 {
+		return o.Name
+	}
+	return o.Name + tagSeparator + strings.Join(o.Tags, tagSeparator)
+}
+
+func storedOptions(options []option) []string {
+	stored := make([]string, len(options))
+	for i, o := range options {
+		stored[i] = o.String()
+	}
+	return stored
+}
+
+// HasTag indicates whether the option carries a bare tag with the provided
+// label, or an attribute with the provided key.
+func (o option) HasTag(label string) bool {
+	for _, tag := range o.Tags {
+		key, _, _ := strings.Cut(tag, "=")
+		if strings.EqualFold(key, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// Attr returns the value of the attribute with the provided key, and whether
+// the option has that attribute at all.
+func (o option) Attr(key string) (value string, ok bool) {
+	for _, tag := range o.Tags {
+		k, v, hasValue := strings.Cut(tag, "=")
+		if hasValue && strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// displayName returns the user-facing form of the option, with tags listed
+// after the name when the option has any.
+func (o option) displayName() string {
+	if len(o.Tags) == 0 {
+		return o.Name
+	}
+	return o.Name + " (" + strings.Join(o.Tags, ", ") + ")"
+}
+
+func optionNames(options []option) []string {
+	names := make([]string, len(options))
+	for i, o := range options {
+		names[i] = o.Name
+	}
+	return names
+}
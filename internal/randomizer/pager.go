@@ -0,0 +1,34 @@
+package randomizer
+
+import (
+	"context"
+	"slices"
+)
+
+// Pager is implemented by stores that can list a partition's groups a page at
+// a time, so that partitions with many groups don't have to be read at once.
+type Pager interface {
+	// ListAfter returns up to limit group names that sort after the given name
+	// (or from the start, if it's empty) in byte order, along with whether
+	// more names follow them.
+	ListAfter(ctx context.Context, after string, limit int) (groups []string, more bool, err error)
+}
+
+// ListAfter lists a page of groups in the store, as described by
+// [Pager.ListAfter]. If the store isn't a Pager, ListAfter lists every group
+// and returns the requested page.
+func ListAfter(ctx context.Context, store Store, after string, limit int) ([]string, bool, error) {
+	if pager, ok := store.(Pager); ok {
+		return pager.ListAfter(ctx, after, limit)
+	}
+	groups, err := store.List(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	slices.Sort(groups)
+	groups = slices.DeleteFunc(groups, func(group string) bool { return group <= after })
+	if len(groups) > limit {
+		return groups[:limit], true, nil
+	}
+	return groups, false, nil
+}
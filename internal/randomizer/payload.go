@@ -0,0 +1,344 @@
+package randomizer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template/parse"
+	"time"
+)
+
+// Webhook templates use Go's text/template syntax, but like the help message,
+// they can't use text/template itself, which uses reflection in a way that
+// disables dead code elimination for the entire program. Instead, the App
+// parses them with text/template/parse, and evaluates the parts of the
+// language that payloads need against a WebhookResult:
+//
+//   - Fields of the result, like {{.Winner}}, which are {{$.Winner}} inside
+//     {{range}}
+//   - String constants, like "\n"
+//   - The functions json, join, and len, as calls or in pipelines
+//   - {{if}} and {{range}} (over .Choices), with {{else}}
+//
+// Anything else, like variables, {{with}}, or {{define}}, is rejected when the
+// template is saved. Result message templates use the same language, against
+// ResultFields instead.
+
+// templateFuncs names the functions that templates may call. The parser only
+// needs their names, with any non-nil value.
+var templateFuncs = map[string]any{"json": true, "join": true, "len": true}
+
+// templateLang describes the data that a kind of template evaluates.
+type templateLang struct {
+	name  string // Like "webhook", for errors
+	zero  any    // Checked against for unknown fields when parsing
+	field func(v any, name string) (any, error)
+	limit int // The most bytes that the template may render
+}
+
+var payloadLang = templateLang{
+	name:  "webhook",
+	zero:  WebhookResult{},
+	field: webhookResultField,
+	limit: maxWebhookPayloadBytes,
+}
+
+// restrictedTemplate is a parsed template in a templateLang.
+type restrictedTemplate struct {
+	lang templateLang
+	root *parse.ListNode
+}
+
+func parsePayloadTemplate(text string) (restrictedTemplate, error) {
+	return parseRestrictedTemplate(payloadLang, text)
+}
+
+func parseRestrictedTemplate(lang templateLang, text string) (restrictedTemplate, error) {
+	trees, err := parse.Parse(lang.name, text, "", "", templateFuncs)
+	if err != nil {
+		return restrictedTemplate{}, err
+	}
+	if len(trees) != 1 || trees[lang.name] == nil {
+		return restrictedTemplate{}, errors.New("templates can't define other templates")
+	}
+	t := restrictedTemplate{lang: lang, root: trees[lang.name].Root}
+	if err := t.check(t.root); err != nil {
+		return restrictedTemplate{}, err
+	}
+	return t, nil
+}
+
+// check rejects the parts of the template language that execute doesn't
+// support, even in branches that a sample result wouldn't reach.
+func (t restrictedTemplate) check(node parse.Node) error {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return nil
+		}
+		for _, n := range node.Nodes {
+			if err := t.check(n); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *parse.TextNode, *parse.CommentNode, *parse.DotNode, *parse.StringNode:
+		return nil
+	case *parse.ActionNode:
+		return t.check(node.Pipe)
+	case *parse.IfNode:
+		return errors.Join(t.check(node.Pipe), t.check(node.List), t.check(node.ElseList))
+	case *parse.RangeNode:
+		return errors.Join(t.check(node.Pipe), t.check(node.List), t.check(node.ElseList))
+	case *parse.PipeNode:
+		if len(node.Decl) > 0 {
+			return fmt.Errorf("%s: variables aren't supported in %s templates", node, t.lang.name)
+		}
+		for _, cmd := range node.Cmds {
+			for i, arg := range cmd.Args {
+				if _, ok := arg.(*parse.IdentifierNode); ok && i == 0 {
+					continue
+				}
+				if err := t.check(arg); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case *parse.FieldNode:
+		_, err := t.lang.lookup(t.lang.zero, node.Ident)
+		return err
+	case *parse.VariableNode:
+		if node.Ident[0] != "$" {
+			return fmt.Errorf("%s: variables aren't supported in %s templates", node, t.lang.name)
+		}
+		_, err := t.lang.lookup(t.lang.zero, node.Ident[1:])
+		return err
+	default:
+		return fmt.Errorf("%s: not supported in %s templates", node, t.lang.name)
+	}
+}
+
+// execute renders the template for its language's data.
+func (t restrictedTemplate) execute(data any) ([]byte, error) {
+	s := templateState{lang: t.lang, root: data}
+	if err := s.walk(data, t.root); err != nil {
+		return nil, err
+	}
+	return []byte(s.out.String()), nil
+}
+
+type templateState struct {
+	lang templateLang
+	root any
+	out  strings.Builder
+}
+
+func (s *templateState) walk(dot any, node parse.Node) error {
+	if s.out.Len() > s.lang.limit {
+		return fmt.Errorf("%s template output is over the limit of %d bytes", s.lang.name, s.lang.limit)
+	}
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return nil
+		}
+		for _, n := range node.Nodes {
+			if err := s.walk(dot, n); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *parse.TextNode:
+		s.out.Write(node.Text)
+		return nil
+	case *parse.CommentNode:
+		return nil
+	case *parse.ActionNode:
+		v, err := s.evalPipe(dot, node.Pipe)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(&s.out, v)
+		return nil
+	case *parse.IfNode:
+		v, err := s.evalPipe(dot, node.Pipe)
+		if err != nil {
+			return err
+		}
+		if truthy(v) {
+			return s.walk(dot, node.List)
+		}
+		return s.walk(dot, node.ElseList)
+	case *parse.RangeNode:
+		v, err := s.evalPipe(dot, node.Pipe)
+		if err != nil {
+			return err
+		}
+		list, ok := v.([]string)
+		if !ok {
+			return fmt.Errorf("%s: can only range over a list like .Choices", node)
+		}
+		if len(list) == 0 {
+			return s.walk(dot, node.ElseList)
+		}
+		for _, elem := range list {
+			if err := s.walk(elem, node.List); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("%s: not supported in %s templates", node, s.lang.name)
+	}
+}
+
+func (s *templateState) evalPipe(dot any, pipe *parse.PipeNode) (any, error) {
+	if len(pipe.Decl) > 0 {
+		return nil, fmt.Errorf("%s: variables aren't supported in %s templates", pipe, s.lang.name)
+	}
+	var (
+		v     any
+		piped bool
+	)
+	for _, cmd := range pipe.Cmds {
+		var err error
+		if v, err = s.evalCommand(dot, cmd, v, piped); err != nil {
+			return nil, err
+		}
+		piped = true
+	}
+	return v, nil
+}
+
+// evalCommand evaluates a command, passing the result of the previous command
+// in the pipeline (if piped) as the final argument to a function.
+func (s *templateState) evalCommand(dot any, cmd *parse.CommandNode, prev any, piped bool) (any, error) {
+	if fn, ok := cmd.Args[0].(*parse.IdentifierNode); ok {
+		var args []any
+		for _, arg := range cmd.Args[1:] {
+			v, err := s.evalArg(dot, arg)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, v)
+		}
+		if piped {
+			args = append(args, prev)
+		}
+		return callTemplateFunc(fn.Ident, args)
+	}
+	if len(cmd.Args) > 1 || piped {
+		return nil, fmt.Errorf("%s: only functions take arguments", cmd)
+	}
+	return s.evalArg(dot, cmd.Args[0])
+}
+
+func (s *templateState) evalArg(dot any, node parse.Node) (any, error) {
+	switch node := node.(type) {
+	case *parse.DotNode:
+		return dot, nil
+	case *parse.StringNode:
+		return node.Text, nil
+	case *parse.FieldNode:
+		return s.lang.lookup(dot, node.Ident)
+	case *parse.VariableNode:
+		if node.Ident[0] != "$" {
+			return nil, fmt.Errorf("%s: variables aren't supported in %s templates", node, s.lang.name)
+		}
+		return s.lang.lookup(s.root, node.Ident[1:])
+	case *parse.PipeNode:
+		return s.evalPipe(dot, node)
+	default:
+		return nil, fmt.Errorf("%s: not supported in %s templates", node, s.lang.name)
+	}
+}
+
+// lookup looks up a chain of fields, which can only be a single field of the
+// language's data.
+func (l templateLang) lookup(v any, fields []string) (any, error) {
+	if len(fields) == 0 {
+		return v, nil
+	}
+	if len(fields) > 1 {
+		return nil, fmt.Errorf("can't get .%s here", strings.Join(fields, "."))
+	}
+	return l.field(v, fields[0])
+}
+
+func webhookResultField(v any, name string) (any, error) {
+	result, ok := v.(WebhookResult)
+	if !ok {
+		return nil, fmt.Errorf("can't get .%s here", name)
+	}
+	switch name {
+	case "ID":
+		return result.ID, nil
+	case "Type":
+		return result.Type, nil
+	case "Group":
+		return result.Group, nil
+	case "User":
+		return result.User, nil
+	case "Choices":
+		return result.Choices, nil
+	case "Winner":
+		return result.Winner, nil
+	case "Message":
+		return result.Message, nil
+	case "At":
+		return result.At.Format(time.RFC3339), nil
+	default:
+		return nil, fmt.Errorf("a result has no field .%s", name)
+	}
+}
+
+func callTemplateFunc(name string, args []any) (any, error) {
+	switch name {
+	case "json":
+		if len(args) != 1 {
+			return nil, errors.New("json takes 1 argument")
+		}
+		b, err := json.Marshal(args[0])
+		return string(b), err
+	case "join":
+		if len(args) != 2 {
+			return nil, errors.New("join takes 2 arguments")
+		}
+		list, ok1 := args[0].([]string)
+		sep, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return nil, errors.New("join takes a list like .Choices and a separator")
+		}
+		return strings.Join(list, sep), nil
+	case "len":
+		if len(args) != 1 {
+			return nil, errors.New("len takes 1 argument")
+		}
+		switch v := args[0].(type) {
+		case []string:
+			return len(v), nil
+		case string:
+			return len(v), nil
+		}
+		return nil, errors.New("len takes a list or a string")
+	default:
+		return nil, fmt.Errorf("function %q not defined", name)
+	}
+}
+
+func truthy(v any) bool {
+	switch v := v.(type) {
+	case string:
+		return v != ""
+	case []string:
+		return len(v) > 0
+	case int:
+		return v != 0
+	case bool:
+		return v
+	default:
+		return v != nil
+	}
+}
@@ -0,0 +1,137 @@
+package randomizer
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// prefetchStore wraps a Store for the duration of a single request, to read
+// the records that most requests need all at once, rather than one after
+// another as each feature asks for them. Reads through the store are kept in
+// memory for the rest of the request, so a feature whose record doesn't exist
+// costs nothing after the prefetch. Writes through the store replace what it
+// keeps.
+type prefetchStore struct {
+	Store
+
+	mu    *sync.Mutex
+	items map[string][]string
+}
+
+func newPrefetchStore(store Store) prefetchStore {
+	return prefetchStore{Store: store, mu: new(sync.Mutex), items: make(map[string][]string)}
+}
+
+// prefetch starts reading each of the named items, and marks each read done
+// with wg. Items that can't be read are left for Get to try again, so that
+// the feature that needs them sees the error.
+func (s prefetchStore) prefetch(ctx context.Context, wg *sync.WaitGroup, names ...string) {
+	for _, name := range names {
+		wg.Go(func() {
+			if entries, err := s.Store.Get(ctx, name); err == nil {
+				s.keep(name, entries)
+			}
+		})
+	}
+}
+
+func (s prefetchStore) keep(name string, entries []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[name] = slices.Clone(entries)
+}
+
+func (s prefetchStore) forget(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, name)
+}
+
+func (s prefetchStore) Get(ctx context.Context, name string) ([]string, error) {
+	s.mu.Lock()
+	entries, ok := s.items[name]
+	s.mu.Unlock()
+	if ok {
+		return slices.Clone(entries), nil
+	}
+
+	entries, err := s.Store.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	s.keep(name, entries)
+	return entries, nil
+}
+
+func (s prefetchStore) ListAfter(ctx context.Context, after string, limit int) ([]string, bool, error) {
+	return ListAfter(ctx, s.Store, after, limit)
+}
+
+func (s prefetchStore) Put(ctx context.Context, name string, entries []string) error {
+	if err := s.Store.Put(ctx, name, entries); err != nil {
+		s.forget(name)
+		return err
+	}
+	s.keep(name, entries)
+	return nil
+}
+
+func (s prefetchStore) Update(ctx context.Context, name string, update func([]string) ([]string, error)) error {
+	var updated []string
+	err := Update(ctx, s.Store, name, func(entries []string) ([]string, error) {
+		var err error
+		updated, err = update(entries)
+		return updated, err
+	})
+	if err != nil {
+		s.forget(name)
+		return err
+	}
+	s.keep(name, updated)
+	return nil
+}
+
+func (s prefetchStore) Delete(ctx context.Context, name string) (bool, error) {
+	existed, err := s.Store.Delete(ctx, name)
+	if err != nil {
+		s.forget(name)
+		return false, err
+	}
+	s.keep(name, nil)
+	return existed, nil
+}
+
+// withPrefetch returns a copy of the App whose stores read ahead the settings
+// and records that nearly every request needs, and waits for the reads. The
+// returned function reads ahead the records of the groups that a selection
+// draws from, once the request names them.
+func (a App) withPrefetch(ctx context.Context) (App, func(groups []string)) {
+	channel := newPrefetchStore(a.store)
+	a.store = channel
+
+	var wg sync.WaitGroup
+	names := []string{configRecord, seedRecord, iconsRecord}
+	if a.onboarding {
+		names = append(names, onboardingRecord)
+	}
+	channel.prefetch(ctx, &wg, names...)
+	if a.workspace != nil {
+		workspace := newPrefetchStore(a.workspace)
+		a.workspace = workspace
+		names := []string{configRecord}
+		if a.user != "" {
+			names = append(names, userConfigRecord(a.user))
+		}
+		workspace.prefetch(ctx, &wg, names...)
+	}
+	wg.Wait()
+
+	return a, func(groups []string) {
+		var wg sync.WaitGroup
+		for _, group := range groups {
+			channel.prefetch(ctx, &wg, group, variantsRecord(group), expiryRecord(group), capRecord(group))
+		}
+		wg.Wait()
+	}
+}
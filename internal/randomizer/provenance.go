@@ -0,0 +1,116 @@
+package randomizer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Provenance records where each option in a group came from: who added it,
+// when, and with which command. Options keep their original provenance when a
+// group is saved again, so the record shows how a group's list evolved rather
+// than only its latest save. "/show <group> --verbose" displays it.
+
+// provenanceRecord returns the name of the record holding a group's option
+// provenance, with entries of the form "<time>|<user>|<via>|<option>". The
+// option comes last since it may contain "|".
+func provenanceRecord(group string) string {
+	return recordPrefix + "provenance/" + group
+}
+
+// WithUser identifies the user making requests to the App, so that changes to
+// groups can be attributed to them. Slack user IDs are shown as mentions.
+func WithUser(id string) AppOption {
+	return func(a *App) { a.user = id }
+}
+
+type provenance struct {
+	At     time.Time
+	User   string // Empty if the App didn't know the user
+	Via    string // The command that added the option, e.g. "/save"
+	Option string
+}
+
+func (p provenance) entry() string {
+	user := p.User
+	if user == "" {
+		user = "-"
+	}
+	return strings.Join([]string{p.At.UTC().Format(time.RFC3339), user, p.Via, p.Option}, "|")
+}
+
+func (p provenance) String() string {
+	var b strings.Builder
+	b.WriteString("added")
+	if p.User != "" {
+		fmt.Fprintf(&b, " by <@%s>", p.User)
+	}
+	fmt.Fprintf(&b, " with %s on %s", p.Via, slackDate(p.At, false))
+	return b.String()
+}
+
+// getProvenance returns the provenance of each option in a group, by name.
+func (a App) getProvenance(ctx context.Context, group string) (map[string]provenance, error) {
+	entries, err := a.store.Get(ctx, provenanceRecord(group))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]provenance, len(entries))
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, "|", 4)
+		if len(parts) != 4 {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, parts[0])
+		if err != nil {
+			continue
+		}
+		p := provenance{At: at, User: parts[1], Via: parts[2], Option: parts[3]}
+		if p.User == "-" {
+			p.User = ""
+		}
+		result[p.Option] = p
+	}
+	return result, nil
+}
+
+// recordProvenance attributes any options in names that the group doesn't
+// already have provenance for to the App's user and the via command, and
+// forgets the provenance of options no longer in the group.
+func (a App) recordProvenance(ctx context.Context, group, via string, names []string) error {
+	known, err := a.getProvenance(ctx, group)
+	if err != nil {
+		return err
+	}
+
+	now := a.now()
+	entries := make([]string, 0, len(names))
+	for _, name := range names {
+		p, ok := known[name]
+		if !ok {
+			p = provenance{At: now, User: a.user, Via: via, Option: name}
+		}
+		entries = append(entries, p.entry())
+	}
+	slices.Sort(entries)
+	entries = slices.Compact(entries)
+	return a.store.Put(ctx, provenanceRecord(group), entries)
+}
+
+// provenanceLines returns the user-facing forms of a group's options, each
+// followed by its provenance.
+func provenanceLines(raw []string, known map[string]provenance) []string {
+	options := parseOptions(raw)
+	lines := make([]string, len(options))
+	for i, o := range options {
+		if p, ok := known[o.Name]; ok {
+			lines[i] = o.displayName() + " — " + p.String()
+		} else {
+			lines[i] = o.displayName() + " — origin unknown"
+		}
+	}
+	return lines
+}
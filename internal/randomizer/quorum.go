@@ -0,0 +1,325 @@
+package randomizer
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "propose",
+		operand:    operandNone,
+		permission: permRead,
+		exclusions: true,
+		flags:      []string{"approvals", "pick", "exclude", "variant"},
+		handler:    App.propose,
+		section:    helpHistory,
+		help:       []string{"*Propose a pick that 3 people must approve:* {{.Name}} /propose lunch --approvals 3"},
+	})
+	registerCommand(&command{
+		name:       "approve",
+		permission: permRead,
+		handler:    App.approve,
+		section:    helpHistory,
+		help:       []string{"*Approve a proposed pick:* {{.Name}} /approve k3x9qp"},
+	})
+	registerCommand(&command{
+		name:       "reject",
+		permission: permRead,
+		handler:    App.reject,
+		section:    helpHistory,
+		help:       []string{"*Re-roll a proposed pick:* {{.Name}} /reject k3x9qp"},
+	})
+}
+
+// A proposal is a selection that only stands once enough people approve it,
+// for decisions where the randomizer suggests and people ratify. Until then,
+// anyone can reject it, which re-rolls it as a new proposal with a new ID, so
+// that approvals for the old winners can't carry over to the new ones.
+//
+// A proposal keeps every option in the order that the draw put them in, and
+// its winners are the first few. A re-roll moves the old winners to the back,
+// so that each re-roll proposes different winners until every option has had
+// a turn, while respecting the options' weights from the original draw.
+
+// proposalsRecord holds the open proposals in a store. Each entry is a
+// space-separated UTC timestamp, proposal ID, proposing user (or "-"), source
+// group (or "-"), pick count, required approvals, comma-separated approving
+// users (or "-"), and the options in draw order.
+const proposalsRecord = recordPrefix + "proposals"
+
+// maxProposals is the number of open proposals that are kept.
+const maxProposals = 50
+
+// defaultApprovals is the number of approvals that a proposal needs if the
+// request doesn't say.
+const defaultApprovals = 2
+
+// maxApprovals bounds the approvals that a proposal can require, to keep its
+// entry a reasonable size.
+const maxApprovals = 50
+
+type proposal struct {
+	ID        string
+	At        time.Time
+	User      string // Empty if the user is unknown
+	Group     string // Empty if the options were given directly
+	Pick      int
+	Need      int
+	Approvers []string
+	Options   []string
+}
+
+func (p proposal) winners() []string {
+	return p.Options[:max(p.Pick, 1)]
+}
+
+func (p proposal) entry() string {
+	approvers := "-"
+	if len(p.Approvers) > 0 {
+		approvers = strings.Join(p.Approvers, ",")
+	}
+	fields := []string{
+		p.At.UTC().Format(historyTimeFormat), p.ID, orDash(p.User), orDash(p.Group),
+		strconv.Itoa(p.Pick), strconv.Itoa(p.Need), approvers,
+	}
+	return strings.Join(append(fields, p.Options...), " ")
+}
+
+func parseProposal(entry string) (proposal, bool) {
+	fields := strings.Fields(entry)
+	if len(fields) < 8 {
+		return proposal{}, false
+	}
+	at, err := time.Parse(historyTimeFormat, fields[0])
+	if err != nil {
+		return proposal{}, false
+	}
+	pick, err := strconv.Atoi(fields[4])
+	if err != nil || pick > len(fields)-7 {
+		return proposal{}, false
+	}
+	need, err := strconv.Atoi(fields[5])
+	if err != nil {
+		return proposal{}, false
+	}
+	p := proposal{
+		ID:      fields[1],
+		At:      at,
+		User:    fromDash(fields[2]),
+		Group:   fromDash(fields[3]),
+		Pick:    pick,
+		Need:    need,
+		Options: fields[7:],
+	}
+	if fields[6] != "-" {
+		p.Approvers = strings.Split(fields[6], ",")
+	}
+	return p, true
+}
+
+func (a App) propose(request request) (Result, error) {
+	need, err := a.approvalCount(request.Flags)
+	if err != nil {
+		return Result{}, err
+	}
+
+	// A re-roll needs the options that didn't win, so the draw orders them all.
+	drawRequest := request
+	drawRequest.Flags = maps.Clone(request.Flags)
+	delete(drawRequest.Flags, "pick")
+	draw, err := a.draw(drawRequest)
+	if err != nil {
+		return Result{}, err
+	}
+	pick, err := a.pickCount(request.Flags, len(draw.choices))
+	if err != nil {
+		return Result{}, err
+	}
+
+	p := proposal{
+		ID:      newResultID(),
+		At:      a.now(),
+		User:    a.user,
+		Group:   draw.group,
+		Pick:    pick,
+		Need:    need,
+		Options: draw.choices,
+	}
+	err = a.updateProposals(request.Context, func(proposals []string) []string {
+		if len(proposals) >= maxProposals {
+			proposals = proposals[len(proposals)-maxProposals+1:]
+		}
+		return append(proposals, p.entry())
+	})
+	if err != nil {
+		return Result{}, a.storeError(err, "saving that proposal")
+	}
+	return a.proposalResult(request.Context, p, draw.notes), nil
+}
+
+func (a App) approvalCount(flags flagSet) (int, error) {
+	value, ok := flags.Value("approvals")
+	if !ok {
+		return defaultApprovals, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > maxApprovals {
+		return 0, Error{
+			cause:    fmt.Errorf("invalid approval count %q", value),
+			helpText: fmt.Sprintf(`Whoops, I need a number of approvals from 1 to %d, like "%s /propose lunch --approvals 3"!`, maxApprovals, a.name),
+		}
+	}
+	return n, nil
+}
+
+func (a App) proposalResult(ctx context.Context, p proposal, notes string) Result {
+	return Result{
+		resultType: ProposedPick,
+		message: fmt.Sprintf(
+			"%sI propose: %s. It needs %s to stand.%s\nTo approve it, use: %s /approve %s\nTo re-roll it, use: %s /reject %s",
+			a.groupIcon(ctx, p.Group), inlinelist(p.winners()),
+			pluralVerb(p.Need, "1 approval", fmt.Sprintf("%d approvals", p.Need)), notes,
+			a.name, p.ID, a.name, p.ID,
+		),
+		choices: p.winners(),
+		id:      p.ID,
+	}
+}
+
+func (a App) approve(request request) (Result, error) {
+	if a.user == "" {
+		return Result{}, Error{
+			cause:    fmt.Errorf("approval without a user"),
+			helpText: "Whoops, I can only count approvals from people I can tell apart.",
+		}
+	}
+	proposals, i, err := a.findProposal(request.Context, request.Operand)
+	if err != nil {
+		return Result{}, err
+	}
+
+	p, _ := parseProposal(proposals[i])
+	if slices.Contains(p.Approvers, a.user) {
+		return Result{
+			resultType: CountedApproval,
+			message:    fmt.Sprintf("You already approved proposal %s. It has %d of %d approvals.", p.ID, len(p.Approvers), p.Need),
+			id:         p.ID,
+		}, nil
+	}
+
+	open := proposals[i]
+	p.Approvers = append(p.Approvers, a.user)
+	ratified := len(p.Approvers) >= p.Need
+	err = a.updateProposals(request.Context, func(proposals []string) []string {
+		i := slices.Index(proposals, open)
+		switch {
+		case i < 0:
+			return proposals
+		case ratified:
+			return slices.Delete(proposals, i, i+1)
+		default:
+			proposals[i] = p.entry()
+			return proposals
+		}
+	})
+	if err != nil {
+		return Result{}, a.storeError(err, "counting that approval")
+	}
+
+	if !ratified {
+		return Result{
+			resultType: CountedApproval,
+			message:    fmt.Sprintf("I counted your approval of proposal %s. It has %d of %d approvals.", p.ID, len(p.Approvers), p.Need),
+			id:         p.ID,
+		}, nil
+	}
+
+	// Like any other selection, the winners count in the group's history, but
+	// only once they're ratified.
+	winners := p.winners()
+	a.saveResultRecord(request.Context, ResultRecord{ID: p.ID, At: a.now(), Group: p.Group, Choices: winners})
+	if p.Group != "" {
+		a.recordSelection(request.Context, p.Group, winners, p.ID)
+	}
+	approvers := make([]string, len(p.Approvers))
+	for i, user := range p.Approvers {
+		approvers[i] = fmt.Sprintf("<@%s>", user)
+	}
+	return Result{
+		resultType: RatifiedPick,
+		message: fmt.Sprintf(
+			":white_check_mark: Proposal %s got enough approvals, so it stands: %s.\n(Approved by %s.)",
+			p.ID, inlinelist(winners), strings.Join(approvers, ", "),
+		),
+		choices: winners,
+		group:   p.Group,
+		picked:  true,
+		id:      p.ID,
+	}, nil
+}
+
+func (a App) reject(request request) (Result, error) {
+	proposals, i, err := a.findProposal(request.Context, request.Operand)
+	if err != nil {
+		return Result{}, err
+	}
+
+	rejected := proposals[i]
+	p, _ := parseProposal(rejected)
+	n := max(p.Pick, 1)
+	p.Options = append(slices.Clone(p.Options[n:]), p.Options[:n]...)
+	p.ID = newResultID()
+	p.At = a.now()
+	p.Approvers = nil
+	err = a.updateProposals(request.Context, func(proposals []string) []string {
+		for i, entry := range proposals {
+			if entry == rejected {
+				proposals[i] = p.entry()
+			}
+		}
+		return proposals
+	})
+	if err != nil {
+		return Result{}, a.storeError(err, "re-rolling that proposal")
+	}
+	return a.proposalResult(request.Context, p, ""), nil
+}
+
+// findProposal returns the open proposals in a store, along with the index of
+// the one with the given ID.
+func (a App) findProposal(ctx context.Context, id string) ([]string, int, error) {
+	proposals, err := a.store.Get(ctx, proposalsRecord)
+	if err != nil {
+		return nil, 0, a.storeError(err, "looking up that proposal")
+	}
+	want := strings.ToLower(strings.TrimPrefix(id, "#"))
+	i := slices.IndexFunc(proposals, func(entry string) bool {
+		p, ok := parseProposal(entry)
+		return ok && p.ID == want
+	})
+	if i < 0 {
+		return nil, 0, Error{
+			cause: fmt.Errorf("proposal %q not found", id),
+			helpText: fmt.Sprintf(
+				"Whoops, I can't find an open proposal with the ID %q in this channel. It might have already been approved or re-rolled.",
+				id,
+			),
+		}
+	}
+	return proposals, i, nil
+}
+
+// updateProposals replaces the open proposals in the store with the result of
+// calling update on them in chronological order.
+func (a App) updateProposals(ctx context.Context, update func([]string) []string) error {
+	return Update(ctx, a.store, proposalsRecord, func(proposals []string) ([]string, error) {
+		slices.Sort(proposals)
+		return update(proposals), nil
+	})
+}
@@ -2,7 +2,12 @@
 // suitable for use by multiple frontends.
 package randomizer
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/featherbread/randomizer/internal/calendar"
+)
 
 // ResultType represents the type of successful result returned by the
 // randomizer.
@@ -22,12 +27,187 @@ const (
 	SavedGroup
 	// DeletedGroup indicates that a group was successfully deleted.
 	DeletedGroup
+	// TaggedOption indicates that the tags on an option in a group were
+	// successfully updated.
+	TaggedOption
+	// PreviewedChanges indicates that the randomizer described the changes that
+	// an operation would make, without making them.
+	PreviewedChanges
+	// Assignment indicates that the randomizer assigned people to tasks.
+	Assignment
+	// ExportedHistory indicates that the randomizer exported the selection
+	// history of a group.
+	ExportedHistory
+	// ShowedStats indicates that the randomizer displayed how often it selected
+	// each option in a group, along with any feedback on those selections.
+	ShowedStats
+	// ReorderedGroup indicates that the saved order of a group's options was
+	// successfully changed.
+	ReorderedGroup
+	// UpdatedExpiry indicates that a group's policy for expiring stale options
+	// was successfully changed.
+	UpdatedExpiry
+	// ConfirmedOptions indicates that options in a group were successfully
+	// confirmed as still active.
+	ConfirmedOptions
+	// UpdatedVariants indicates that a group's time-based variants were
+	// successfully changed.
+	UpdatedVariants
+	// ShowedVariants indicates that a group's time-based variants were
+	// successfully obtained.
+	ShowedVariants
+	// ShowedResult indicates that the details of a past selection were
+	// successfully obtained.
+	ShowedResult
+	// UpdatedTheme indicates that a workspace's theme was successfully changed.
+	UpdatedTheme
+	// ShowedTheme indicates that a workspace's theme was successfully obtained.
+	ShowedTheme
+	// UpdatedCap indicates that a group's cap on how often each option may be
+	// picked was successfully changed or obtained.
+	UpdatedCap
+	// UpdatedIcon indicates that a group's icon was successfully changed or
+	// obtained.
+	UpdatedIcon
+	// SealedPick indicates that the randomizer drew a selection and published a
+	// commitment to it, without revealing the winners.
+	SealedPick
+	// RevealedPick indicates that the winners of a sealed pick were revealed.
+	RevealedPick
+	// ShowedHistory indicates that the randomizer listed recent selections
+	// from a group or channel.
+	ShowedHistory
+	// ProposedPick indicates that the randomizer proposed a selection that
+	// needs approvals to stand, either new or re-rolled.
+	ProposedPick
+	// CountedApproval indicates that an approval of a proposed selection was
+	// counted, but the proposal needs more to stand.
+	CountedApproval
+	// RatifiedPick indicates that a proposed selection got enough approvals to
+	// stand.
+	RatifiedPick
+	// Teams indicates that the randomizer split options into teams.
+	Teams
+	// Sampled indicates that the randomizer sampled numbers from a
+	// distribution.
+	Sampled
+	// RolledDice indicates that the randomizer rolled dice.
+	RolledDice
+	// PickedNumbers indicates that the randomizer picked whole numbers from a
+	// range.
+	PickedNumbers
+	// ShowedVersion indicates that the randomizer described its build and
+	// deployment.
+	ShowedVersion
+	// UpdatedWebhook indicates that the randomizer set or removed a channel's
+	// result webhook.
+	UpdatedWebhook
+	// ShowedWebhook indicates that the randomizer described a channel's result
+	// webhook.
+	ShowedWebhook
+	// UpdatedSeed indicates that the randomizer set or removed a channel's
+	// seed.
+	UpdatedSeed
+	// ShowedSeed indicates that the randomizer described a channel's seed.
+	ShowedSeed
+	// UpdatedSchedules indicates that the randomizer added or removed one of a
+	// channel's schedules.
+	UpdatedSchedules
+	// ShowedSchedules indicates that the randomizer listed a channel's
+	// schedules.
+	ShowedSchedules
+	// UpdatedFederation indicates that the randomizer changed a group that is
+	// shared with another workspace.
+	UpdatedFederation
+	// ShowedFederation indicates that the randomizer described the groups that
+	// a workspace shares with other workspaces.
+	ShowedFederation
+	// ShowedAudit indicates that the randomizer listed recent changes from the
+	// audit trail.
+	ShowedAudit
+	// MergedGroups indicates that the randomizer saved a group combining the
+	// options of other groups.
+	MergedGroups
+	// UpdatedConfig indicates that the randomizer changed a channel's
+	// configuration.
+	UpdatedConfig
+	// ShowedConfig indicates that the randomizer described a channel's
+	// configuration.
+	ShowedConfig
 )
 
+var resultTypeNames = [...]string{
+	Selection:         "Selection",
+	ShowedHelp:        "ShowedHelp",
+	ListedGroups:      "ListedGroups",
+	ShowedGroup:       "ShowedGroup",
+	SavedGroup:        "SavedGroup",
+	DeletedGroup:      "DeletedGroup",
+	TaggedOption:      "TaggedOption",
+	PreviewedChanges:  "PreviewedChanges",
+	Assignment:        "Assignment",
+	ExportedHistory:   "ExportedHistory",
+	ShowedStats:       "ShowedStats",
+	ReorderedGroup:    "ReorderedGroup",
+	UpdatedExpiry:     "UpdatedExpiry",
+	ConfirmedOptions:  "ConfirmedOptions",
+	UpdatedVariants:   "UpdatedVariants",
+	ShowedVariants:    "ShowedVariants",
+	ShowedResult:      "ShowedResult",
+	UpdatedTheme:      "UpdatedTheme",
+	ShowedTheme:       "ShowedTheme",
+	UpdatedCap:        "UpdatedCap",
+	UpdatedIcon:       "UpdatedIcon",
+	SealedPick:        "SealedPick",
+	RevealedPick:      "RevealedPick",
+	ShowedHistory:     "ShowedHistory",
+	ProposedPick:      "ProposedPick",
+	CountedApproval:   "CountedApproval",
+	RatifiedPick:      "RatifiedPick",
+	Teams:             "Teams",
+	Sampled:           "Sampled",
+	RolledDice:        "RolledDice",
+	PickedNumbers:     "PickedNumbers",
+	ShowedVersion:     "ShowedVersion",
+	UpdatedWebhook:    "UpdatedWebhook",
+	ShowedWebhook:     "ShowedWebhook",
+	UpdatedSeed:       "UpdatedSeed",
+	ShowedSeed:        "ShowedSeed",
+	UpdatedSchedules:  "UpdatedSchedules",
+	ShowedSchedules:   "ShowedSchedules",
+	UpdatedFederation: "UpdatedFederation",
+	ShowedFederation:  "ShowedFederation",
+	ShowedAudit:       "ShowedAudit",
+	MergedGroups:      "MergedGroups",
+	UpdatedConfig:     "UpdatedConfig",
+	ShowedConfig:      "ShowedConfig",
+}
+
+func (t ResultType) String() string {
+	if t >= 0 && int(t) < len(resultTypeNames) {
+		return resultTypeNames[t]
+	}
+	return fmt.Sprintf("ResultType(%d)", int(t))
+}
+
 // Result represents a successful randomizer operation.
 type Result struct {
 	resultType ResultType
 	message    string
+	choices    []string
+	group      string
+	picked     bool
+	private    bool
+	changed    bool
+	dryRun     bool
+	event      *calendar.Event
+	id         string
+	more       string
+	fields     ResultFields
+	phrase     string // The part of message that Rephrased replaces
+	template   string
+	welcomed   bool
+	onboarded  bool
 }
 
 // Type returns the type of this result.
@@ -35,18 +215,110 @@ func (r Result) Type() ResultType {
 	return r.resultType
 }
 
+// Welcomed reports whether the result opens with the welcome message that
+// [WithOnboarding] shows on a store's first request.
+func (r Result) Welcomed() bool {
+	return r.welcomed
+}
+
+// Onboarded reports whether the request confirmed that its store has been
+// onboarded with [WithOnboarding]. Frontends may remember this, and leave
+// onboarding off for the store's later requests.
+func (r Result) Onboarded() bool {
+	return r.onboarded
+}
+
 // Message returns the user-friendly output associated with this result.
 func (r Result) Message() string {
 	return r.message
 }
 
+// Choices returns the randomized options for a [Selection] result, in their
+// chosen order. It returns nil for other types of results.
+func (r Result) Choices() []string {
+	return r.choices
+}
+
+// Picked reports whether a [Selection] chose a fixed number of winners with
+// "/pick", in which case Choices returns only the winners, rather than every
+// option in a random order.
+func (r Result) Picked() bool {
+	return r.picked
+}
+
+// Private reports whether the channel asked for a [Selection] to be shown
+// only to the user who requested it, rather than to the whole channel.
+func (r Result) Private() bool {
+	return r.private
+}
+
+// Changed reports whether the operation behind the result may have changed
+// saved groups or settings, as opposed to only reading them. Selections that
+// only record history are not considered changes.
+func (r Result) Changed() bool {
+	return r.changed
+}
+
+// DryRun reports whether the result came from a dry run, which leaves saved
+// data untouched.
+func (r Result) DryRun() bool {
+	return r.dryRun
+}
+
+// ID returns the short ID assigned to a [Selection], which [ShowedResult]
+// results also report. It returns an empty string for other types of results,
+// or if the selection couldn't be recorded.
+func (r Result) ID() string {
+	return r.id
+}
+
+// More returns the arguments that request the next page of a paged result,
+// like a [ListedGroups] result for a channel with many groups, or an empty
+// string if there is no next page. Frontends may offer a "Show more" control
+// that runs them.
+func (r Result) More() string {
+	return r.more
+}
+
+// Fields returns the parts of a [Selection] that result templates may use. It
+// returns zero ResultFields for other types of results.
+func (r Result) Fields() ResultFields {
+	return r.fields
+}
+
+// Template returns the result template that applies to a [Selection], or an
+// empty string if it should keep its usual phrasing. Frontends that support
+// templates render it with [RenderResultTemplate], and show it with
+// [Result.Rephrased].
+func (r Result) Template() string {
+	return r.template
+}
+
+// Rephrased returns the message with the usual phrasing of a [Selection]
+// replaced by text, like a rendered result template, keeping any notes and
+// banners around it. It returns the message unchanged for other types of
+// results.
+func (r Result) Rephrased(text string) string {
+	if r.phrase == "" {
+		return r.message
+	}
+	return strings.Replace(r.message, r.phrase, text, 1)
+}
+
+// Event returns the calendar event for a [Selection] made with the "--event"
+// flag, or nil if no event was requested.
+func (r Result) Event() *calendar.Event {
+	return r.event
+}
+
 // Error represents an error encountered by the randomizer. It includes
 // friendly help messages that can be displayed directly to users when errors
 // occur, along with an underlying developer-friendly error that may be useful
 // for debugging.
 type Error struct {
-	cause    error
-	helpText string
+	cause            error
+	helpText         string
+	storeUnavailable bool
 }
 
 func (e Error) Error() string {
@@ -59,6 +331,12 @@ func (e Error) Cause() error {
 	return e.cause
 }
 
+// StoreUnavailable indicates that the error came from a failure to read or
+// write saved groups, rather than from a problem with the user's input.
+func (e Error) StoreUnavailable() bool {
+	return e.storeUnavailable
+}
+
 // HelpText returns user-friendly help text associated with this error. While
 // the underlying error is more suitable for developer use, the help text may
 // be displayed directly to a user.
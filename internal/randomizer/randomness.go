@@ -0,0 +1,306 @@
+package randomizer
+
+import (
+	"context"
+	crand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "seed",
+		operand:    operandOptional,
+		permission: permWrite,
+		handler:    App.configureSeed,
+		section:    helpRules,
+		help:       []string{"*Make this channel's picks reproducible, like for a demo:* {{.Name}} /seed 42"},
+	})
+}
+
+// Randomness is a source of random numbers for an App. Every random choice
+// that an App makes, from ordering options to rolling dice, draws from its
+// Randomness, except for result IDs, which must stay unique even when picks
+// are reproducible.
+type Randomness interface {
+	// Use calls f with exclusive access to a generator, since Apps may serve
+	// concurrent requests.
+	Use(f func(r *rand.Rand))
+}
+
+// WithRandomness replaces the App's source of randomness, which defaults to
+// [MathRandomness]. A channel's /seed setting takes precedence over it.
+//
+// To control only the order of options, as in tests, see [WithRandomizer].
+func WithRandomness(r Randomness) AppOption {
+	return func(a *App) { a.randomness = r }
+}
+
+// lockedRand is a Randomness that guards a single generator with a mutex.
+type lockedRand struct {
+	mu sync.Mutex
+	*rand.Rand
+}
+
+func (l *lockedRand) Use(f func(r *rand.Rand)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f(l.Rand)
+}
+
+// rng is the default source of randomness for all Apps.
+//
+// We keep our own source rather than using the top-level math/rand/v2
+// functions so that it can be explicitly reseeded. If the process is
+// checkpointed and restored into multiple environments (as with AWS Lambda
+// SnapStart), every environment would otherwise continue from the same state
+// and produce the same sequence of "random" selections.
+var rng = &lockedRand{Rand: rand.New(newSource())}
+
+func newSource() rand.Source {
+	var seed [32]byte
+	crand.Read(seed[:])
+	return rand.NewChaCha8(seed)
+}
+
+// Reseed replaces the default source of randomness with a freshly seeded one.
+// Frontends should call it after restoring the process from a checkpoint.
+func Reseed() {
+	rng.Use(func(*rand.Rand) { rng.Rand = rand.New(newSource()) })
+}
+
+// MathRandomness returns the default source of randomness: a ChaCha8
+// generator from math/rand/v2, seeded from crypto/rand when the process
+// starts (and by [Reseed]). It is fast, and unpredictable without access to
+// the process's memory.
+func MathRandomness() Randomness {
+	return rng
+}
+
+// CryptoRandomness returns a source that reads every random number from
+// crypto/rand, for deployments that want each pick to be as unpredictable as
+// a cryptographic key, at some cost in speed. It needs no reseeding.
+func CryptoRandomness() Randomness {
+	return cryptoRand
+}
+
+var cryptoRand = &lockedRand{Rand: rand.New(cryptoSource{})}
+
+// cryptoSource is a rand.Source backed by crypto/rand.
+type cryptoSource struct{}
+
+func (cryptoSource) Uint64() uint64 {
+	var b [8]byte
+	crand.Read(b[:])
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+// SeededRandomness returns a deterministic source, which makes the same
+// choices for the same seed every time, e.g. for reproducible demos. Its picks
+// are predictable by anyone who knows the seed, so it's never suitable for
+// picks that need to be fair.
+func SeededRandomness(seed uint64) Randomness {
+	return &lockedRand{Rand: rand.New(rand.NewPCG(seed, 0))}
+}
+
+// RandomnessFromName returns the source of randomness with a name that
+// operators can configure: "math" (or "") for [MathRandomness], "crypto" for
+// [CryptoRandomness], or "seed:<n>" for [SeededRandomness] with the seed n.
+func RandomnessFromName(name string) (Randomness, error) {
+	switch {
+	case name == "" || name == "math":
+		return MathRandomness(), nil
+	case name == "crypto":
+		return CryptoRandomness(), nil
+	case strings.HasPrefix(name, "seed:"):
+		seed, err := strconv.ParseUint(strings.TrimPrefix(name, "seed:"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid seed in randomness %q", name)
+		}
+		return SeededRandomness(seed), nil
+	default:
+		return nil, fmt.Errorf(`unknown randomness %q (want "math", "crypto", or "seed:<n>")`, name)
+	}
+}
+
+// shuffle puts options in a random order drawn from the App's randomness,
+// unless [WithRandomizer] replaced it.
+func (a App) shuffle(options []string) {
+	if a.order != nil {
+		a.order(options)
+		return
+	}
+	a.randomness.Use(func(r *rand.Rand) {
+		r.Shuffle(len(options), func(i, j int) {
+			options[i], options[j] = options[j], options[i]
+		})
+	})
+}
+
+// A channel's seed makes its picks reproducible: after "/seed 42", the same
+// commands produce the same results every time, which helps with demos and
+// with explaining how a pick came about. Each request that draws random numbers
+// uses a generator seeded with the channel's seed and a count of the requests
+// before it, so that successive picks differ, and setting the seed again starts
+// the sequence over. Every seeded result says so, so that no one mistakes it
+// for a fair pick.
+
+// seedRecord holds a channel's seed, with entries of the form "seed|<n>" and
+// "draws|<n>".
+const seedRecord = recordPrefix + "seed"
+
+type channelSeed struct {
+	seed  uint64
+	draws uint64
+}
+
+func (s channelSeed) entries() []string {
+	return []string{"seed|" + strconv.FormatUint(s.seed, 10), "draws|" + strconv.FormatUint(s.draws, 10)}
+}
+
+// getSeed returns the channel's seed, if it has one.
+func (a App) getSeed(ctx context.Context) (channelSeed, bool, error) {
+	entries, err := a.store.Get(ctx, seedRecord)
+	if err != nil {
+		return channelSeed{}, false, err
+	}
+	s, ok := parseSeed(entries)
+	return s, ok, nil
+}
+
+func parseSeed(entries []string) (s channelSeed, ok bool) {
+	for _, entry := range entries {
+		key, value, _ := strings.Cut(entry, "|")
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "seed":
+			s.seed, ok = n, true
+		case "draws":
+			s.draws = n
+		}
+	}
+	return s, ok
+}
+
+// channelRandomness is the Randomness for a single request. The first time
+// the request draws a random number, it checks for the channel's seed, so that
+// requests that don't need randomness don't pay for the lookup.
+type channelRandomness struct {
+	ctx      context.Context
+	app      App
+	fallback Randomness
+
+	once   sync.Once
+	mu     sync.Mutex
+	seed   channelSeed
+	seeded bool
+	rand   *rand.Rand
+	used   bool
+}
+
+func (c *channelRandomness) Use(f func(r *rand.Rand)) {
+	c.once.Do(func() {
+		s, ok, err := c.app.getSeed(c.ctx)
+		if err != nil {
+			c.app.logger.Warn("Failed to get channel seed", "err", err)
+		}
+		if ok {
+			c.seed, c.seeded = s, true
+			c.rand = rand.New(rand.NewPCG(s.seed, s.draws))
+		}
+	})
+	if !c.seeded {
+		c.fallback.Use(f)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.used = true
+	f(c.rand)
+}
+
+// finish notes a seeded result, and advances the channel's sequence, if the
+// request drew from the channel's seed.
+func (c *channelRandomness) finish(result *Result) {
+	if !c.used {
+		return
+	}
+	result.message += fmt.Sprintf("\n:seedling: This channel's picks are seeded with %d, so they're reproducible.", c.seed.seed)
+	err := Update(c.ctx, c.app.store, seedRecord, func(entries []string) ([]string, error) {
+		current, ok := parseSeed(entries)
+		if !ok {
+			return entries, nil // Removed in the meantime
+		}
+		current.draws++
+		return current.entries(), nil
+	})
+	if err != nil {
+		c.app.logger.Warn("Failed to advance channel seed", "err", err)
+	}
+}
+
+// withChannelRandomness returns an App that draws from the channel's seed, if
+// it has one, along with the Randomness to finish once the request is done.
+func (a App) withChannelRandomness(ctx context.Context) (App, *channelRandomness) {
+	c := &channelRandomness{ctx: ctx, app: a, fallback: a.randomness}
+	a.randomness = c
+	return a, c
+}
+
+func (a App) configureSeed(request request) (Result, error) {
+	ctx := request.Context
+	if request.Operand == "" {
+		s, ok, err := a.getSeed(ctx)
+		if err != nil {
+			return Result{}, a.storeError(err, "getting this channel's seed")
+		}
+		if !ok {
+			return Result{
+				resultType: ShowedSeed,
+				message: fmt.Sprintf(
+					`This channel's picks aren't seeded. (Type "%s help" to see how to make them reproducible.)`,
+					a.name,
+				),
+			}, nil
+		}
+		return Result{
+			resultType: ShowedSeed,
+			message:    fmt.Sprintf("This channel's picks are seeded with %d, and %d have been made since it was set.", s.seed, s.draws),
+		}, nil
+	}
+
+	if strings.EqualFold(request.Operand, "off") {
+		if _, err := a.store.Delete(ctx, seedRecord); err != nil {
+			return Result{}, a.storeError(err, "removing this channel's seed")
+		}
+		return Result{
+			resultType: UpdatedSeed,
+			message:    "Done! This channel's picks are random again.",
+		}, nil
+	}
+
+	seed, err := strconv.ParseUint(request.Operand, 10, 64)
+	if err != nil {
+		return Result{}, Error{
+			cause:    fmt.Errorf("invalid seed %q: %w", request.Operand, err),
+			helpText: `Whoops, the seed needs to be a whole number like "42", or "off" to make picks random again!`,
+		}
+	}
+	if err := a.store.Put(ctx, seedRecord, channelSeed{seed: seed}.entries()); err != nil {
+		return Result{}, a.storeError(err, "saving this channel's seed")
+	}
+	return Result{
+		resultType: UpdatedSeed,
+		message: fmt.Sprintf(
+			`Done! This channel's picks are seeded with %d, so the same commands after "%s /seed %d" will get the same results every time.`,
+			seed, a.name, seed,
+		),
+	}, nil
+}
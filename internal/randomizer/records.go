@@ -0,0 +1,31 @@
+package randomizer
+
+import (
+	"context"
+	"strings"
+)
+
+// Records hold the randomizer's own state alongside groups in a Store. Their
+// names start with "/", which isForbiddenGroupName reserves for flags, so they
+// can never collide with user-created groups. Operations that expose group
+// names to users must hide records.
+//
+// Since some stores treat a group's options as an unordered set, records that
+// need ordering must encode it in their entries (e.g. with a timestamp prefix),
+// and every entry must be unique and non-empty.
+const recordPrefix = "/"
+
+const onboardingRecord = recordPrefix + "onboarding"
+
+func isRecordName(name string) bool {
+	return strings.HasPrefix(name, recordPrefix)
+}
+
+// getGroup returns the options in a user-created group, treating records as
+// if they don't exist.
+func (a App) getGroup(ctx context.Context, name string) ([]string, error) {
+	if isRecordName(name) {
+		return nil, nil
+	}
+	return a.store.Get(ctx, name)
+}
@@ -0,0 +1,121 @@
+package randomizer
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "reorder",
+		permission: permWrite,
+		handler:    App.reorderGroup,
+		section:    helpGroups,
+		help:       []string{"*Move an option in a group:* {{.Name}} /reorder snacks pretzels 1"},
+	})
+}
+
+// reorderGroup changes the saved order of a group's options, which /show and
+// other displays follow. It accepts either a single option and its new
+// position (counting from 1), or the names of every option in the new order.
+func (a App) reorderGroup(request request) (Result, error) {
+	var (
+		ctx   = request.Context
+		group = request.Operand
+		args  = request.Args
+	)
+
+	var updated []string
+	err := Update(ctx, a.store, group, func(stored []string) ([]string, error) {
+		if len(stored) == 0 || isRecordName(group) {
+			return nil, Error{
+				cause:    errors.New("group does not exist"),
+				helpText: "Whoops, I can't find that group in this channel. (Use the /save flag to create it!)",
+			}
+		}
+
+		options := parseOptions(stored)
+
+		// A second argument that names an option means that the user is listing
+		// the whole group, which happens to have two options.
+		var (
+			reordered []option
+			err       error
+		)
+		if len(args) == 2 && optionIndex(options, args[1]) < 0 {
+			reordered, err = moveOption(group, options, args[0], args[1])
+		} else {
+			reordered, err = a.orderOptions(group, options, args)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		updated = storedOptions(reordered)
+		return updated, nil
+	})
+	if err != nil {
+		return Result{}, a.updateError(err, "updating that group")
+	}
+
+	return Result{
+		resultType: ReorderedGroup,
+		message: fmt.Sprintf(
+			"Done! The %q group now has the following order:\n%s",
+			group, numberedlist(displayNames(updated)),
+		),
+	}, nil
+}
+
+func optionIndex(options []option, name string) int {
+	return slices.IndexFunc(options, func(o option) bool { return o.Name == name })
+}
+
+func moveOption(group string, options []option, name, position string) ([]option, error) {
+	i := optionIndex(options, name)
+	if i < 0 {
+		return nil, Error{
+			cause:    fmt.Errorf("option %q not in group %q", name, group),
+			helpText: fmt.Sprintf("Whoops, the %q group doesn't have an option named %q!", group, name),
+		}
+	}
+
+	n, err := strconv.Atoi(position)
+	if err != nil || n < 1 || n > len(options) {
+		return nil, Error{
+			cause:    fmt.Errorf("invalid position %q", position),
+			helpText: fmt.Sprintf("Whoops, the new position must be a number from 1 to %d!", len(options)),
+		}
+	}
+
+	moved := options[i]
+	rest := slices.Delete(slices.Clone(options), i, i+1)
+	return slices.Insert(rest, n-1, moved), nil
+}
+
+func (a App) orderOptions(group string, options []option, names []string) ([]option, error) {
+	if len(names) != len(options) {
+		return nil, Error{
+			cause: fmt.Errorf("got %d options to reorder, want %d", len(names), len(options)),
+			helpText: fmt.Sprintf(
+				`Whoops, I need an option and its new position, or all %d options of the %q group in their new order! (Type "%s help" to see an example.)`,
+				len(options), group, a.name,
+			),
+		}
+	}
+
+	reordered := make([]option, 0, len(options))
+	for _, name := range names {
+		i := optionIndex(options, name)
+		if i < 0 || optionIndex(reordered, name) >= 0 {
+			return nil, Error{
+				cause:    fmt.Errorf("option %q missing or repeated in new order", name),
+				helpText: fmt.Sprintf("Whoops, I need each option in the %q group exactly once, and %q doesn't fit!", group, name),
+			}
+		}
+		reordered = append(reordered, options[i])
+	}
+	return reordered, nil
+}
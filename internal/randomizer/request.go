@@ -3,91 +3,111 @@ package randomizer
 import (
 	"context"
 	"fmt"
+	"strings"
 )
 
-type operation int
-
-const (
-	makeSelection operation = iota
-	showHelp
-	listGroups
-	showGroup
-	saveGroup
-	deleteGroup
-)
-
-func (op operation) String() string {
-	switch op {
-	case makeSelection:
-		return "select"
-	case showHelp:
-		return "help"
-	case listGroups:
-		return "list"
-	case showGroup:
-		return "show"
-	case saveGroup:
-		return "save"
-	case deleteGroup:
-		return "delete"
-	}
-	return ""
-}
-
 // request represents a single user request to a randomizer instance, created
 // from raw user input.
 type request struct {
-	Context   context.Context
-	Operation operation
-	Operand   string
-	Args      []string
+	Context context.Context
+	Command *command
+	Operand string
+	Args    []string
+	Flags   flagSet
+
+	forceDryRun bool
+	// defaultPick is the settings' default number of winners for a selection
+	// without "--pick", or 0 to order every option.
+	defaultPick int
 }
 
 func (a App) newRequest(ctx context.Context, args []string) (req request, err error) {
 	req.Context = ctx
-	req.Operation, req.Operand, req.Args, err = parseArgs(args)
+	req.forceDryRun = a.dryRun
+	args, literal, flags, err := parseFlags(args)
+	if err != nil {
+		return
+	}
+	req.Flags = flags
+	if onlyExclusions(args, literal) {
+		// A default group from the settings is more specific than the
+		// deployment's default operation for bare requests, so it comes first.
+		if group := a.selectionSettings(ctx).Group; group != "" {
+			args = append([]string{"+" + group}, args...)
+		}
+	}
+	req.Command, req.Operand, req.Args, err = a.parseArgs(canonicalMentions(args))
+	if err == nil && req.Command.exclusions {
+		req.Args = req.Flags.takeExclusions(req.Args, literal)
+	}
+	if err == nil {
+		err = req.Command.checkFlags(req.Flags)
+	}
 	return
 }
 
-func parseArgs(args []string) (op operation, operand string, opargs []string, err error) {
+// onlyExclusions reports whether every argument is shorthand for an exclusion,
+// including when there are no arguments at all.
+func onlyExclusions(args []string, literal int) bool {
+	if literal > 0 {
+		return false
+	}
+	for _, arg := range args {
+		if excluded, ok := strings.CutPrefix(arg, "-"); !ok || !isExclusion(excluded) {
+			return false
+		}
+	}
+	return true
+}
+
+// DryRun indicates whether the request should only preview its changes to the
+// store, without making them.
+func (r request) DryRun() bool {
+	return r.forceDryRun || r.Flags.Bool("dry-run")
+}
+
+func (a App) parseArgs(args []string) (cmd *command, operand string, opargs []string, err error) {
 	// We accept the standard flag syntax for help, but expect that users won't
 	// know that syntax in advance. Logic elsewhere in the randomizer blocks
 	// using "help" as a group name to avoid conflicts with this special case.
-	if len(args) == 0 || args[0] == "/help" || len(args) == 1 && args[0] == "help" {
-		return showHelp, "", args, nil
+	if len(args) == 1 && args[0] == "help" {
+		return commandNames["help"], "", nil, nil
 	}
-
-	switch args[0] {
-	// Arguments without an explicitly known flag trigger randomization, even if
-	// the first argument starts with a slash, because it's easier to implement
-	// and unlikely to cause problems in practice. Logic elsewhere in the
-	// randomizer blocks using flag-like group names, so new flags can't make
-	// existing groups inaccessible.
-	default:
-		return makeSelection, "", args, nil
-
-	// Listing groups requires no arguments...
-	case "/list":
-		return listGroups, "", args, nil
-
-	// ...and everything else needs the name of a group to operate on, which we
-	// validate and extract out from the rest of the arguments for convenience. We
-	// make no assumptions about how each operation uses the rest of the available
-	// arguments.
-	case "/show":
-		op = showGroup
-	case "/save":
-		op = saveGroup
-	case "/delete":
-		op = deleteGroup
+	if len(args) == 0 {
+		cmd, opargs, err = a.route(a.defaults.Bare, DefaultHelp, nil)
+		return cmd, "", opargs, err
 	}
 
-	if len(args) < 2 {
-		return op, "", nil, Error{
-			cause:    fmt.Errorf("%q flag requires an argument", args[0]),
-			helpText: fmt.Sprintf("Whoops, %q requires an argument!", args[0]),
+	// By default, arguments without an explicitly known command trigger
+	// randomization, even if the first argument starts with a slash, because
+	// it's easier to implement and unlikely to cause problems in practice.
+	// Logic elsewhere in the randomizer blocks using flag-like group names, so
+	// new commands can't make existing groups inaccessible. Deployers may
+	// route unknown flags elsewhere, since users who mistype a command
+	// otherwise get a confusing selection.
+	cmd, ok := lookupCommand(args[0])
+	if !ok {
+		if strings.HasPrefix(args[0], "/") {
+			cmd, opargs, err = a.route(a.defaults.UnknownFlag, DefaultRandomize, args)
+			return cmd, "", opargs, err
 		}
+		return implicitCommand, "", args, nil
 	}
 
-	return op, args[1], args[2:], nil
+	// Some commands need no arguments, and others need the name of a group to
+	// operate on, which we validate and extract out from the rest of the
+	// arguments for convenience. We make no assumptions about how each command
+	// uses the rest of the available arguments.
+	switch {
+	case cmd.operand == operandNone:
+		return cmd, "", args[1:], nil
+	case len(args) >= 2:
+		return cmd, args[1], args[2:], nil
+	case cmd.operand == operandOptional:
+		return cmd, "", nil, nil
+	}
+	return cmd, "", nil, Error{
+		cause:    fmt.Errorf("%q flag requires an argument", args[0]),
+		helpText: fmt.Sprintf("Whoops, %q requires an argument!", args[0]),
+	}
 }
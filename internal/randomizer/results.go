@@ -0,0 +1,165 @@
+package randomizer
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "result",
+		permission: permRead,
+		handler:    App.showResult,
+		section:    helpHistory,
+		help:       []string{"*Look up a past result by its ID:* {{.Name}} /result k3x9qp"},
+	})
+}
+
+// resultsRecord holds the details of recent selections in a store, so that a
+// selection can be looked up by its ID for audits and disputes. Each entry is
+// a space-separated UTC timestamp, result ID, source group (or "-" for options
+// given directly), and the selected options in order. Options and group names
+// never contain spaces, and the timestamp prefix keeps entries chronological.
+const resultsRecord = recordPrefix + "results"
+
+// maxResults is the number of past selections whose details are kept.
+const maxResults = 200
+
+// resultIDAlphabet omits characters that are easy to confuse when read aloud
+// or copied by hand, like "0" and "o".
+const resultIDAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
+
+const resultIDLength = 6
+
+// ResultRecord holds the details of a past selection.
+type ResultRecord struct {
+	ID      string
+	At      time.Time
+	Group   string // Empty if the options were given directly
+	Choices []string
+}
+
+// newResultID returns a new result ID, always from the default randomness, so
+// that seeded channels don't reuse IDs.
+func newResultID() string {
+	id := make([]byte, resultIDLength)
+	rng.Use(func(r *rand.Rand) {
+		for i := range id {
+			id[i] = resultIDAlphabet[r.IntN(len(resultIDAlphabet))]
+		}
+	})
+	return string(id)
+}
+
+func (r ResultRecord) entry() string {
+	group := r.Group
+	if group == "" {
+		group = "-"
+	}
+	fields := append([]string{r.At.UTC().Format(historyTimeFormat), r.ID, group}, r.Choices...)
+	return strings.Join(fields, " ")
+}
+
+func parseResultRecord(entry string) (ResultRecord, bool) {
+	fields := strings.Fields(entry)
+	if len(fields) < 4 {
+		return ResultRecord{}, false
+	}
+	at, err := time.Parse(historyTimeFormat, fields[0])
+	if err != nil {
+		return ResultRecord{}, false
+	}
+	record := ResultRecord{ID: fields[1], At: at, Group: fields[2], Choices: fields[3:]}
+	if record.Group == "-" {
+		record.Group = ""
+	}
+	return record, true
+}
+
+// recordResult assigns an ID to a selection and saves its details. Like
+// history, the record is never critical to the request itself, so it returns
+// an empty ID if the details can't be saved or history is disabled. If the
+// write is deferred, it returns the ID before the details are saved.
+func (a App) recordResult(ctx context.Context, group string, choices []string) string {
+	if a.noHistory {
+		return ""
+	}
+
+	record := ResultRecord{ID: newResultID(), At: a.now(), Group: group, Choices: choices}
+	if !a.saveResultRecord(ctx, record) {
+		return ""
+	}
+	return record.ID
+}
+
+// saveResultRecord saves the details of a selection whose ID was assigned
+// elsewhere, and reports whether they were saved (or their write deferred).
+func (a App) saveResultRecord(ctx context.Context, record ResultRecord) bool {
+	if a.noHistory {
+		return false
+	}
+	_, err := a.writeNonCritical(ctx, "result record", func(ctx context.Context) error {
+		return Update(ctx, a.store, resultsRecord, func(entries []string) ([]string, error) {
+			slices.Sort(entries)
+			if len(entries) >= maxResults {
+				entries = entries[len(entries)-maxResults+1:]
+			}
+			return append(entries, record.entry()), nil
+		})
+	})
+	if err != nil {
+		a.logger.Warn("Failed to save result record", "err", err)
+		return false
+	}
+	return true
+}
+
+// LookupResult returns the details of a recent selection by its ID, and
+// whether the selection was found.
+func (a App) LookupResult(ctx context.Context, id string) (ResultRecord, bool, error) {
+	entries, err := a.store.Get(ctx, resultsRecord)
+	if err != nil {
+		return ResultRecord{}, false, err
+	}
+	id = strings.ToLower(strings.TrimPrefix(id, "#"))
+	for _, entry := range entries {
+		if record, ok := parseResultRecord(entry); ok && record.ID == id {
+			return record, true, nil
+		}
+	}
+	return ResultRecord{}, false, nil
+}
+
+func (a App) showResult(request request) (Result, error) {
+	record, found, err := a.LookupResult(request.Context, request.Operand)
+	if err != nil {
+		return Result{}, a.storeError(err, "looking up that result")
+	}
+	if !found {
+		return Result{}, Error{
+			cause: fmt.Errorf("result %q not found", request.Operand),
+			helpText: fmt.Sprintf(
+				"Whoops, I can't find a result with the ID %q in this channel. (I only keep the last %d.)",
+				request.Operand, maxResults,
+			),
+		}
+	}
+
+	source := "from options given directly"
+	if record.Group != "" {
+		source = fmt.Sprintf("from the %q group", record.Group)
+	}
+	return Result{
+		resultType: ShowedResult,
+		message: fmt.Sprintf(
+			"Result %s was made %s on %s, and got: %s.",
+			record.ID, source, slackDate(record.At, false), inlinelist(record.Choices),
+		),
+		choices: record.Choices,
+		id:      record.ID,
+	}, nil
+}
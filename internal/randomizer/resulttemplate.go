@@ -0,0 +1,91 @@
+package randomizer
+
+import "fmt"
+
+// Result templates let a channel rephrase its selections with more structure
+// than a phrasing setting, like "🎲 {{.Choice}} has been chosen from
+// {{.Group}}!". They use the same language as webhook templates (see
+// payload.go), against a selection's ResultFields. The App only carries the
+// effective template on each result, and leaves rendering it to frontends that
+// support it, through [RenderResultTemplate] and [Result.Rephrased].
+
+// maxResultTemplate bounds the length of a result template.
+const maxResultTemplate = 500
+
+// maxRenderedResultBytes bounds the size of a rendered result template.
+const maxRenderedResultBytes = 4000
+
+// ResultFields are the parts of a [Selection] that result templates may use.
+type ResultFields struct {
+	Choice  string // The first choice
+	Choices []string
+	Group   string // Empty if the options were given directly
+	User    string // Empty if the user is unknown
+	ID      string // Empty for dry runs, or if the result couldn't be recorded
+	Icon    string // The group's icon, with a trailing space, or empty
+	Picked  bool   // Whether Choices are only the winners, as with "/pick"
+}
+
+// sampleResultFields are rendered when a template is saved, so that mistakes
+// like misspelled fields surface right away.
+var sampleResultFields = ResultFields{
+	Choice:  "pizza",
+	Choices: []string{"pizza", "tacos"},
+	Group:   "lunch",
+	User:    "U00000000",
+	ID:      "k3x9qp",
+	Icon:    ":pizza: ",
+}
+
+var resultLang = templateLang{
+	name:  "result",
+	zero:  ResultFields{},
+	field: resultFieldsField,
+	limit: maxRenderedResultBytes,
+}
+
+func resultFieldsField(v any, name string) (any, error) {
+	fields, ok := v.(ResultFields)
+	if !ok {
+		return nil, fmt.Errorf("can't get .%s here", name)
+	}
+	switch name {
+	case "Choice":
+		return fields.Choice, nil
+	case "Choices":
+		return fields.Choices, nil
+	case "Group":
+		return fields.Group, nil
+	case "User":
+		return fields.User, nil
+	case "ID":
+		return fields.ID, nil
+	case "Icon":
+		return fields.Icon, nil
+	case "Picked":
+		return fields.Picked, nil
+	default:
+		return nil, fmt.Errorf("a result has no field .%s", name)
+	}
+}
+
+// RenderResultTemplate renders a result template, like one from
+// [Result.Template], for the fields of a selection.
+func RenderResultTemplate(text string, fields ResultFields) (string, error) {
+	tmpl, err := parseRestrictedTemplate(resultLang, text)
+	if err != nil {
+		return "", err
+	}
+	out, err := tmpl.execute(fields)
+	return string(out), err
+}
+
+// validResultTemplate reports whether a result template is short enough, and
+// renders for a sample selection.
+func validResultTemplate(text string) bool {
+	if text == "" || len(text) > maxResultTemplate {
+		return false
+	}
+	_, err := RenderResultTemplate(text, sampleResultFields)
+	return err == nil
+}
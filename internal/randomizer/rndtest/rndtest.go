@@ -6,10 +6,11 @@ import (
 	"errors"
 	"maps"
 	"slices"
+	"sync"
 )
 
-// Store implements randomizer.Store by mapping group names to sorted lists of
-// strings. A nil Store returns errors for every operation.
+// Store implements randomizer.Store by mapping group names to lists of strings,
+// in the order they were saved. A nil Store returns errors for every operation.
 type Store map[string][]string
 
 // Clone returns a deep copy of the original store.
@@ -45,9 +46,7 @@ func (s Store) Put(_ context.Context, name string, options []string) error {
 	if s == nil {
 		return errors.New("store put error")
 	}
-	copied := slices.Clone(options)
-	slices.Sort(copied)
-	s[name] = copied
+	s[name] = slices.Clone(options)
 	return nil
 }
 
@@ -60,3 +59,38 @@ func (s Store) Delete(_ context.Context, name string) (existed bool, err error)
 	delete(s, name)
 	return
 }
+
+// SyncStore wraps a Store with a mutex, for tests of operations that use the
+// store concurrently.
+type SyncStore struct {
+	mu    sync.Mutex
+	Store Store
+}
+
+// List implements randomizer.Store.
+func (s *SyncStore) List(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Store.List(ctx)
+}
+
+// Get implements randomizer.Store.
+func (s *SyncStore) Get(ctx context.Context, group string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Store.Get(ctx, group)
+}
+
+// Put implements randomizer.Store.
+func (s *SyncStore) Put(ctx context.Context, group string, options []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Store.Put(ctx, group, options)
+}
+
+// Delete implements randomizer.Store.
+func (s *SyncStore) Delete(ctx context.Context, group string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Store.Delete(ctx, group)
+}
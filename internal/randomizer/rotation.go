@@ -0,0 +1,171 @@
+package randomizer
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "rotate",
+		permission: permRead,
+		exclusions: true,
+		flags:      []string{"pick", "exclude", "variant"},
+		handler:    App.rotate,
+		section:    helpBasics,
+		help:       []string{"*Take turns, picking everyone once before anyone twice:* {{.Name}} /rotate standup"},
+	})
+}
+
+// A rotation picks from a group at random, but skips the options that have
+// already been picked in the current round, so that everyone takes a turn
+// before anyone takes a second one. When every option has been picked, a new
+// round starts, and the last winner of the old round goes last in the new one
+// so that no one is picked twice in a row. Options added to the group join the
+// current round, and removed options drop out of it.
+//
+// Rotations keep their own state, separate from the group's history, so that
+// ordinary selections from the same group don't count as turns.
+
+// rotationRecord returns the name of the record holding a group's rotation
+// state. Entries of the form "round|<option>" list the options picked in the
+// current round, and a "last|<option>" entry names the most recent winner.
+func rotationRecord(group string) string {
+	return recordPrefix + "rotation/" + group
+}
+
+type rotationState struct {
+	round map[string]bool
+	last  []string
+}
+
+func (a App) getRotation(ctx context.Context, group string) (rotationState, error) {
+	entries, err := a.store.Get(ctx, rotationRecord(group))
+	if err != nil {
+		return rotationState{}, err
+	}
+	state := rotationState{round: make(map[string]bool)}
+	for _, entry := range entries {
+		switch kind, option, _ := strings.Cut(entry, "|"); kind {
+		case "round":
+			state.round[option] = true
+		case "last":
+			state.last = append(state.last, option)
+		}
+	}
+	return state, nil
+}
+
+func (a App) putRotation(ctx context.Context, group string, state rotationState) error {
+	var entries []string
+	for _, option := range slices.Sorted(maps.Keys(state.round)) {
+		entries = append(entries, "round|"+option)
+	}
+	for _, option := range state.last {
+		entries = append(entries, "last|"+option)
+	}
+	return a.store.Put(ctx, rotationRecord(group), entries)
+}
+
+// next picks n winners from choices, which are in random order, and updates
+// the state to include them. It reports whether a new round started.
+func (s *rotationState) next(choices []string, n int) (winners []string, newRound bool) {
+	// The last winners can only come up again at the start of a new round, and
+	// they go to the back of it.
+	choices = slices.Clone(choices)
+	slices.SortStableFunc(choices, func(x, y string) int {
+		return compareBool(slices.Contains(s.last, x), slices.Contains(s.last, y))
+	})
+
+	for len(winners) < n {
+		i := slices.IndexFunc(choices, func(choice string) bool {
+			return !s.round[choice] && !slices.Contains(winners, choice)
+		})
+		if i < 0 {
+			s.round = make(map[string]bool)
+			for _, winner := range winners {
+				s.round[winner] = true
+			}
+			newRound = true
+			continue
+		}
+		winners = append(winners, choices[i])
+		s.round[choices[i]] = true
+	}
+	return winners, newRound
+}
+
+func compareBool(x, y bool) int {
+	switch {
+	case x == y:
+		return 0
+	case x:
+		return 1
+	default:
+		return -1
+	}
+}
+
+func (a App) rotate(request request) (Result, error) {
+	var (
+		ctx   = request.Context
+		group = request.Operand
+	)
+
+	// The rotation decides how many options win, so the draw orders them all.
+	drawRequest := request
+	drawRequest.Args = append([]string{"+" + group}, request.Args...)
+	drawRequest.Flags = maps.Clone(request.Flags)
+	delete(drawRequest.Flags, "pick")
+	draw, err := a.draw(drawRequest)
+	if err != nil {
+		return Result{}, err
+	}
+	n, err := a.pickCount(request.Flags, len(draw.choices))
+	if err != nil {
+		return Result{}, err
+	}
+
+	state, err := a.getRotation(ctx, group)
+	if err != nil {
+		return Result{}, a.storeError(err, "getting that group's rotation")
+	}
+	winners, newRound := state.next(draw.choices, max(n, 1))
+	state.last = winners
+
+	var id string
+	if !request.DryRun() {
+		if err := a.putRotation(ctx, group, state); err != nil {
+			return Result{}, a.storeError(err, "saving that group's rotation")
+		}
+		id = a.recordResult(ctx, draw.group, winners)
+		a.recordSelection(ctx, draw.group, winners, id)
+	}
+
+	var left int
+	for _, choice := range draw.choices {
+		if !state.round[choice] {
+			left++
+		}
+	}
+	note := fmt.Sprintf("\n:repeat: %d of %d left in this round.", left, len(draw.choices))
+	if newRound {
+		note = fmt.Sprintf("\n:repeat: Everyone had a turn, so I started a new round. %d of %d left.", left, len(draw.choices))
+	}
+
+	result := Result{
+		resultType: Selection,
+		message:    fmt.Sprintf("%sI randomized and got: %s.%s%s", a.groupIcon(ctx, draw.group), inlinelist(winners), note, draw.notes),
+		choices:    winners,
+		group:      draw.group,
+		picked:     true,
+		id:         id,
+	}
+	if id != "" {
+		result.message += fmt.Sprintf("\n(Result ID: %s)", id)
+	}
+	return result, nil
+}
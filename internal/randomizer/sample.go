@@ -0,0 +1,263 @@
+package randomizer
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "sample",
+		permission: permRead,
+		flags:      []string{"pick"},
+		handler:    App.sample,
+		section:    helpBasics,
+		help: []string{
+			"*Sample numbers from a distribution:* {{.Name}} /sample normal mean=10 stddev=2",
+			"*Sample several numbers at once:* {{.Name}} /sample poisson mean=3 -n 5",
+		},
+	})
+}
+
+// maxSamples bounds the numbers that a single request can sample, to keep
+// responses a reasonable size.
+const maxSamples = 100
+
+// maxPoissonMean bounds the mean of a Poisson distribution, since sampling
+// from it takes time proportional to the mean.
+const maxPoissonMean = 10000
+
+// defaultDecimals is the number of digits shown after the decimal point of
+// samples from continuous distributions.
+const defaultDecimals = 2
+
+type distParam struct {
+	name  string
+	value float64 // The default, if the request doesn't give one
+}
+
+// distribution describes a probability distribution that /sample supports.
+type distribution struct {
+	params []distParam
+	// aliases maps alternate spellings of parameters to their names.
+	aliases map[string]string
+	// check returns a description of the problem with invalid parameters, or
+	// an empty string if they're valid.
+	check func(p map[string]float64) string
+	// discrete distributions only produce whole numbers.
+	discrete bool
+	sample   func(r *rand.Rand, p map[string]float64) float64
+}
+
+var distributions = map[string]distribution{
+	"uniform": {
+		params: []distParam{{"min", 0}, {"max", 1}},
+		check: func(p map[string]float64) string {
+			if p["max"] < p["min"] {
+				return "max can't be less than min"
+			}
+			return ""
+		},
+		sample: func(r *rand.Rand, p map[string]float64) float64 {
+			return p["min"] + r.Float64()*(p["max"]-p["min"])
+		},
+	},
+	"normal": {
+		params:  []distParam{{"mean", 0}, {"stddev", 1}},
+		aliases: map[string]string{"sd": "stddev"},
+		check: func(p map[string]float64) string {
+			if p["stddev"] < 0 {
+				return "stddev can't be negative"
+			}
+			return ""
+		},
+		sample: func(r *rand.Rand, p map[string]float64) float64 {
+			return p["mean"] + r.NormFloat64()*p["stddev"]
+		},
+	},
+	"exponential": {
+		params: []distParam{{"mean", 1}},
+		check: func(p map[string]float64) string {
+			if p["mean"] <= 0 {
+				return "mean needs to be positive"
+			}
+			return ""
+		},
+		sample: func(r *rand.Rand, p map[string]float64) float64 {
+			return r.ExpFloat64() * p["mean"]
+		},
+	},
+	"poisson": {
+		params:   []distParam{{"mean", 1}},
+		aliases:  map[string]string{"lambda": "mean"},
+		discrete: true,
+		check: func(p map[string]float64) string {
+			if p["mean"] < 0 || p["mean"] > maxPoissonMean {
+				return fmt.Sprintf("mean needs to be from 0 to %d", maxPoissonMean)
+			}
+			return ""
+		},
+		sample: func(r *rand.Rand, p map[string]float64) float64 {
+			return float64(samplePoisson(r, p["mean"]))
+		},
+	},
+}
+
+// samplePoisson uses Knuth's algorithm, which multiplies uniform samples until
+// their product drops below e^-mean. To keep e^-mean from underflowing with
+// larger means, it adds up samples with smaller means, since a sum of Poisson
+// samples is itself Poisson distributed with the sum of their means.
+func samplePoisson(r *rand.Rand, mean float64) int {
+	const maxStep = 30
+	var k int
+	for mean > 0 {
+		step := min(mean, maxStep)
+		mean -= step
+		limit, product := math.Exp(-step), r.Float64()
+		for product > limit {
+			k++
+			product *= r.Float64()
+		}
+	}
+	return k
+}
+
+func (a App) sample(request request) (Result, error) {
+	name := strings.ToLower(request.Operand)
+	dist, ok := distributions[name]
+	if !ok {
+		return Result{}, Error{
+			cause: fmt.Errorf("unknown distribution %q", request.Operand),
+			helpText: fmt.Sprintf(
+				"Whoops, I don't know the %q distribution! I can sample from %s.",
+				request.Operand, strings.Join(slices.Sorted(maps.Keys(distributions)), ", "),
+			),
+		}
+	}
+
+	params, decimals, err := a.parseDistParams(name, dist, request.Args)
+	if err != nil {
+		return Result{}, err
+	}
+	count, err := a.sampleCount(request.Flags)
+	if err != nil {
+		return Result{}, err
+	}
+
+	samples := make([]float64, count)
+	a.randomness.Use(func(r *rand.Rand) {
+		for i := range samples {
+			samples[i] = dist.sample(r, params)
+		}
+	})
+
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', decimals, 64) }
+	formatted := make([]string, len(samples))
+	for i, v := range samples {
+		formatted[i] = format(v)
+	}
+
+	described := make([]string, len(dist.params))
+	for i, param := range dist.params {
+		described[i] = fmt.Sprintf("%s=%g", param.name, params[param.name])
+	}
+	message := fmt.Sprintf("I sampled from %s(%s) and got: %s.", name, strings.Join(described, ", "), inlinelist(formatted))
+	if count > 1 {
+		var sum float64
+		for _, v := range samples {
+			sum += v
+		}
+		message += fmt.Sprintf(
+			"\nMean: %s, min: %s, max: %s.",
+			strconv.FormatFloat(sum/float64(count), 'f', max(decimals, defaultDecimals), 64),
+			format(slices.Min(samples)), format(slices.Max(samples)),
+		)
+	}
+
+	return Result{
+		resultType: Sampled,
+		message:    message,
+		choices:    formatted,
+	}, nil
+}
+
+// parseDistParams parses "name=value" arguments into the parameters of a
+// distribution, along with the number of decimal places to show.
+func (a App) parseDistParams(name string, dist distribution, args []string) (map[string]float64, int, error) {
+	params := make(map[string]float64)
+	for _, param := range dist.params {
+		params[param.name] = param.value
+	}
+	decimals := defaultDecimals
+	if dist.discrete {
+		decimals = 0
+	}
+
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if canonical, isAlias := dist.aliases[key]; isAlias {
+			key = canonical
+		}
+		if key == "decimals" && !dist.discrete {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 || n > 6 {
+				return nil, 0, Error{
+					cause:    fmt.Errorf("invalid decimals %q", value),
+					helpText: "Whoops, decimals needs to be a whole number from 0 to 6!",
+				}
+			}
+			decimals = n
+			continue
+		}
+		if _, known := params[key]; !ok || !known {
+			var names []string
+			for _, param := range dist.params {
+				names = append(names, param.name+"=…")
+			}
+			return nil, 0, Error{
+				cause: fmt.Errorf("unknown parameter %q for %s", arg, name),
+				helpText: fmt.Sprintf(
+					"Whoops, I don't understand %q! The %s distribution takes %s.",
+					arg, name, strings.Join(names, " and "),
+				),
+			}
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, 0, Error{
+				cause:    fmt.Errorf("invalid value %q for %s", value, key),
+				helpText: fmt.Sprintf("Whoops, %s needs to be a number, not %q!", key, value),
+			}
+		}
+		params[key] = v
+	}
+
+	if problem := dist.check(params); problem != "" {
+		return nil, 0, Error{
+			cause:    errors.New(problem),
+			helpText: fmt.Sprintf("Whoops, I can't sample from that %s distribution: %s!", name, problem),
+		}
+	}
+	return params, decimals, nil
+}
+
+func (a App) sampleCount(flags flagSet) (int, error) {
+	value, ok := flags.Value("pick")
+	if !ok {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > maxSamples {
+		return 0, Error{
+			cause:    fmt.Errorf("invalid sample count %q", value),
+			helpText: fmt.Sprintf("Whoops, I can sample from 1 to %d numbers at a time!", maxSamples),
+		}
+	}
+	return n, nil
+}
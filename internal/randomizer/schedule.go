@@ -0,0 +1,448 @@
+package randomizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "schedule",
+		operand:    operandOptional,
+		permission: permWrite,
+		handler:    App.configureSchedule,
+		section:    helpHistory,
+		help: []string{
+			"*Pick on a schedule:* {{.Name}} /schedule daily 9am +standup",
+			"&gt; Use daily, weekdays, or days like mondays or mon,thu, with an optional time zone like America/New_York. See schedules with {{.Name}} /schedule, and stop one with {{.Name}} /schedule remove ID",
+		},
+	})
+}
+
+// A schedule runs a selection in a channel on a recurring cadence, like
+// "/schedule daily 9am +standup", and posts the result without anyone having to
+// ask. The App only saves and describes schedules; the frontend that enabled
+// them with [WithSchedules] finds the ones that are due with [DueSchedules],
+// runs them with a new App, and posts the results to their channels.
+//
+// Every channel's schedules live in a single partition of the store, so that a
+// sweep can find them with one List, with the target that the frontend uses to
+// find each channel.
+
+// SchedulePartition is the partition of the store that holds every channel's
+// schedules.
+const SchedulePartition = "schedules"
+
+// schedulePrefix starts the name of each schedule's record, which is followed
+// by the schedule's ID.
+const schedulePrefix = "/schedule/"
+
+// maxChannelSchedules limits the schedules in a single channel.
+const maxChannelSchedules = 10
+
+// WithSchedules enables /schedule, saving the channel's schedules in store
+// (from [SchedulePartition]) with a target that identifies the channel to the
+// frontend.
+func WithSchedules(store Store, target string) AppOption {
+	return func(a *App) {
+		a.schedules = store
+		a.scheduleTarget = target
+	}
+}
+
+// Schedule is a selection that runs on a recurring cadence.
+type Schedule struct {
+	ID      string
+	Target  string   // Identifies the channel to the frontend
+	User    string   // The user who created the schedule, if known
+	Command string   // The name of the randomizer, like "/randomize"
+	Args    []string // The arguments to run the randomizer with
+	Next    time.Time
+
+	cadence cadence
+}
+
+func (s Schedule) entries() []string {
+	entries := []string{
+		"target|" + s.Target,
+		"command|" + s.Command,
+		"args|" + strings.Join(s.Args, " "),
+		"cadence|" + s.cadence.days,
+		"at|" + fmt.Sprintf("%02d:%02d", s.cadence.hour, s.cadence.minute),
+		"next|" + s.Next.UTC().Format(time.RFC3339),
+	}
+	if s.cadence.zone != "" {
+		entries = append(entries, "zone|"+s.cadence.zone)
+	}
+	if s.User != "" {
+		entries = append(entries, "user|"+s.User)
+	}
+	return entries
+}
+
+func parseSchedule(id string, entries []string) (Schedule, error) {
+	s := Schedule{ID: id}
+	var at string
+	for _, entry := range entries {
+		key, value, _ := strings.Cut(entry, "|")
+		switch key {
+		case "target":
+			s.Target = value
+		case "user":
+			s.User = value
+		case "command":
+			s.Command = value
+		case "args":
+			s.Args = strings.Fields(value)
+		case "cadence":
+			s.cadence.days = value
+		case "at":
+			at = value
+		case "zone":
+			s.cadence.zone = value
+		case "next":
+			s.Next, _ = time.Parse(time.RFC3339, value)
+		}
+	}
+
+	var err error
+	if s.cadence, err = parseCadence(s.cadence.days, at, s.cadence.zone); err != nil {
+		return Schedule{}, fmt.Errorf("schedule %s: %w", id, err)
+	}
+	if s.Target == "" || len(s.Args) == 0 || s.Next.IsZero() {
+		return Schedule{}, fmt.Errorf("schedule %s is incomplete", id)
+	}
+	return s, nil
+}
+
+// Describe returns a description of when the schedule runs, like "every
+// weekday at 9:00 AM (UTC)".
+func (s Schedule) Describe() string {
+	return s.cadence.String()
+}
+
+// DueSchedules returns the schedules in store that are due to run at now, in
+// no particular order. It skips schedules that it can't read.
+func DueSchedules(ctx context.Context, store Store, now time.Time) ([]Schedule, error) {
+	schedules, err := listSchedules(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(schedules, func(s Schedule) bool { return s.Next.After(now) }), nil
+}
+
+func listSchedules(ctx context.Context, store Store) ([]Schedule, error) {
+	names, err := store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var schedules []Schedule
+	for _, name := range names {
+		id, ok := strings.CutPrefix(name, schedulePrefix)
+		if !ok {
+			continue
+		}
+		entries, err := store.Get(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			continue // Removed since we listed it.
+		}
+		if s, err := parseSchedule(id, entries); err == nil {
+			schedules = append(schedules, s)
+		}
+	}
+	return schedules, nil
+}
+
+// errScheduleClaimed cancels the update of a schedule that another sweep has
+// already claimed.
+var errScheduleClaimed = errors.New("schedule already claimed")
+
+// Claim advances a due schedule to its next run after now, and reports whether
+// the caller should run it. If another sweep has claimed the same run, or the
+// schedule was removed, Claim returns false, so each run happens at most once
+// even with concurrent sweeps.
+func (s Schedule) Claim(ctx context.Context, store Store, now time.Time) (bool, error) {
+	err := Update(ctx, store, schedulePrefix+s.ID, func(entries []string) ([]string, error) {
+		if len(entries) == 0 {
+			return nil, errScheduleClaimed // Removed in the meantime
+		}
+		current, err := parseSchedule(s.ID, entries)
+		if err != nil {
+			return nil, err
+		}
+		if !current.Next.Equal(s.Next) {
+			return nil, errScheduleClaimed
+		}
+		current.Next = current.cadence.next(now)
+		return current.entries(), nil
+	})
+	if errors.Is(err, errScheduleClaimed) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// cadence is when a schedule runs: at a time of day, on some days of the week.
+type cadence struct {
+	days   string // As normalized from the user's input, e.g. "weekdays" or "mon,wed"
+	hour   int
+	minute int
+	zone   string // An IANA time zone name, or empty for UTC
+
+	weekdays [7]bool
+	loc      *time.Location
+}
+
+// timeOfDayPattern matches times like "9am", "9:30pm", and "17:00".
+var timeOfDayPattern = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+
+// zonePattern matches names that look like time zones, like "UTC" or
+// "America/New_York", to tell them apart from the start of a selection.
+var zonePattern = regexp.MustCompile(`^(UTC|[A-Z][A-Za-z_]+(/[A-Za-z_+-]+)+)$`)
+
+// parseCadence accepts the days that [parseDays] does, along with full day
+// names like "monday" or "mondays", a time of day, and an optional time zone.
+func parseCadence(days, at, zone string) (cadence, error) {
+	c := cadence{days: normalizeDays(days), zone: zone, loc: time.UTC}
+
+	var err error
+	if c.weekdays, err = parseDays(c.days); err != nil {
+		return cadence{}, err
+	}
+
+	match := timeOfDayPattern.FindStringSubmatch(strings.ToLower(at))
+	if match == nil {
+		return cadence{}, fmt.Errorf("invalid time of day %q", at)
+	}
+	c.hour, _ = strconv.Atoi(match[1])
+	c.minute, _ = strconv.Atoi(match[2])
+	if match[3] != "" {
+		if c.hour < 1 || c.hour > 12 {
+			return cadence{}, fmt.Errorf("invalid time of day %q", at)
+		}
+		c.hour %= 12
+		if match[3] == "pm" {
+			c.hour += 12
+		}
+	}
+	if c.hour > 23 || c.minute > 59 {
+		return cadence{}, fmt.Errorf("invalid time of day %q", at)
+	}
+
+	if zone != "" {
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			return cadence{}, fmt.Errorf("unknown time zone %q", zone)
+		}
+		c.loc = loc
+	}
+	return c, nil
+}
+
+// normalizeDays shortens full day names like "mondays" to the abbreviations
+// that parseDays accepts.
+func normalizeDays(spec string) string {
+	parts := strings.Split(strings.ToLower(spec), ",")
+	for i, part := range parts {
+		name := strings.TrimSuffix(part, "s")
+		if len(name) > 3 {
+			if day, ok := weekdayNames[name[:3]]; ok && strings.EqualFold(day.String(), name) {
+				parts[i] = name[:3]
+			}
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// next returns the first time after t that the cadence runs.
+func (c cadence) next(t time.Time) time.Time {
+	local := t.In(c.loc)
+	for i := range 8 {
+		candidate := time.Date(local.Year(), local.Month(), local.Day()+i, c.hour, c.minute, 0, 0, c.loc)
+		if candidate.After(t) && c.weekdays[candidate.Weekday()] {
+			return candidate
+		}
+	}
+	panic("randomizer: cadence never runs") // parseDays never returns an empty set
+}
+
+func (c cadence) String() string {
+	var days string
+	switch day, single := weekdayNames[c.days]; {
+	case c.days == "daily":
+		days = "every day"
+	case c.days == "weekdays":
+		days = "every weekday"
+	case single:
+		days = "every " + day.String()
+	default:
+		days = "on " + c.days
+	}
+	at := time.Date(2006, 1, 2, c.hour, c.minute, 0, 0, time.UTC).Format("3:04 PM")
+	return fmt.Sprintf("%s at %s (%s)", days, at, c.loc)
+}
+
+// channelSchedules returns the schedules for the App's channel, in the order
+// of their next runs.
+func (a App) channelSchedules(ctx context.Context) ([]Schedule, error) {
+	schedules, err := listSchedules(ctx, a.schedules)
+	if err != nil {
+		return nil, err
+	}
+	schedules = slices.DeleteFunc(schedules, func(s Schedule) bool { return s.Target != a.scheduleTarget })
+	slices.SortFunc(schedules, func(x, y Schedule) int { return x.Next.Compare(y.Next) })
+	return schedules, nil
+}
+
+func (a App) configureSchedule(request request) (Result, error) {
+	ctx := request.Context
+	if a.schedules == nil {
+		return Result{}, Error{
+			cause:    errors.New("no schedule store"),
+			helpText: "Whoops, schedules aren't available here!",
+		}
+	}
+
+	switch {
+	case request.Operand == "":
+		return a.listChannelSchedules(ctx)
+	case strings.EqualFold(request.Operand, "remove"):
+		return a.removeSchedule(ctx, request.Args)
+	}
+
+	if len(request.Args) < 2 {
+		return Result{}, Error{
+			cause:    errors.New("schedule needs a time and a selection"),
+			helpText: fmt.Sprintf(`Whoops, a schedule needs a time and something to pick, like "%s /schedule daily 9am +standup"!`, a.name),
+		}
+	}
+	at, args := request.Args[0], request.Args[1:]
+	var zone string
+	if len(args) > 1 && zonePattern.MatchString(args[0]) {
+		zone, args = args[0], args[1:]
+	}
+	c, err := parseCadence(request.Operand, at, zone)
+	if err != nil {
+		return Result{}, Error{
+			cause:    err,
+			helpText: `Whoops, I can't schedule that. Try days like "daily", "weekdays", "mondays", or "mon,thu", then a time like "9am" or "17:30", and optionally a time zone like "America/New_York".`,
+		}
+	}
+
+	// Preview the selection, so that mistakes like a missing group surface now
+	// rather than at the first run.
+	preview := a
+	preview.dryRun, preview.onboarding = true, false
+	result, err := preview.main(ctx, args)
+	if err != nil {
+		return Result{}, err
+	}
+	if !isWebhookResult(result.resultType) {
+		return Result{}, Error{
+			cause:    fmt.Errorf("can't schedule %q", strings.Join(args, " ")),
+			helpText: "Whoops, I can only schedule picks, like a group or a list of options!",
+		}
+	}
+
+	existing, err := a.channelSchedules(ctx)
+	if err != nil {
+		return Result{}, a.storeError(err, "getting this channel's schedules")
+	}
+	if len(existing) >= maxChannelSchedules {
+		return Result{}, Error{
+			cause:    errors.New("too many schedules"),
+			helpText: fmt.Sprintf("Whoops, a channel can only have %d schedules! Remove one first.", maxChannelSchedules),
+		}
+	}
+
+	s := Schedule{
+		ID:      newResultID(),
+		Target:  a.scheduleTarget,
+		User:    a.user,
+		Command: a.name,
+		Args:    args,
+		Next:    c.next(a.now()),
+		cadence: c,
+	}
+	if err := a.schedules.Put(ctx, schedulePrefix+s.ID, s.entries()); err != nil {
+		return Result{}, a.storeError(err, "saving this schedule")
+	}
+	return Result{
+		resultType: UpdatedSchedules,
+		message: fmt.Sprintf(
+			"Done! I'll pick from `%s` %s, starting %s. (ID: `%s`)",
+			strings.Join(args, " "), s.Describe(), slackDate(s.Next, false), s.ID,
+		),
+	}, nil
+}
+
+// describeSchedule describes a dry run's change to a schedule.
+func (c storeChange) describeSchedule() string {
+	id := strings.TrimPrefix(c.Group, schedulePrefix)
+	if c.Deleted {
+		return fmt.Sprintf("Remove the schedule `%s`", id)
+	}
+	s, err := parseSchedule(id, c.After)
+	if err != nil {
+		return fmt.Sprintf("Save the schedule `%s`", id)
+	}
+	return fmt.Sprintf("Pick from `%s` %s, starting %s", strings.Join(s.Args, " "), s.Describe(), slackDate(s.Next, false))
+}
+
+func (a App) listChannelSchedules(ctx context.Context) (Result, error) {
+	schedules, err := a.channelSchedules(ctx)
+	if err != nil {
+		return Result{}, a.storeError(err, "getting this channel's schedules")
+	}
+	if len(schedules) == 0 {
+		return Result{
+			resultType: ShowedSchedules,
+			message: fmt.Sprintf(
+				`This channel doesn't have any schedules. (Type "%s help" to see how to add one.)`,
+				a.name,
+			),
+		}, nil
+	}
+	var b strings.Builder
+	b.WriteString("This channel's schedules are:")
+	for _, s := range schedules {
+		fmt.Fprintf(&b, "\n• `%s`: `%s` %s", s.ID, strings.Join(s.Args, " "), s.Describe())
+	}
+	return Result{resultType: ShowedSchedules, message: b.String()}, nil
+}
+
+func (a App) removeSchedule(ctx context.Context, args []string) (Result, error) {
+	if len(args) != 1 {
+		return Result{}, Error{
+			cause:    errors.New("remove needs a schedule ID"),
+			helpText: fmt.Sprintf(`Whoops, I need the ID of the schedule to remove, from "%s /schedule".`, a.name),
+		}
+	}
+	id := args[0]
+	entries, err := a.schedules.Get(ctx, schedulePrefix+id)
+	if err != nil {
+		return Result{}, a.storeError(err, "getting that schedule")
+	}
+	if s, err := parseSchedule(id, entries); len(entries) == 0 || (err == nil && s.Target != a.scheduleTarget) {
+		return Result{}, Error{
+			cause:    fmt.Errorf("schedule %q not found", id),
+			helpText: fmt.Sprintf(`Whoops, this channel doesn't have a schedule with the ID %q!`, id),
+		}
+	}
+	if _, err := a.schedules.Delete(ctx, schedulePrefix+id); err != nil {
+		return Result{}, a.storeError(err, "removing that schedule")
+	}
+	return Result{
+		resultType: UpdatedSchedules,
+		message:    fmt.Sprintf("Done! I removed the schedule %q.", id),
+	}, nil
+}
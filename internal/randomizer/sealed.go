@@ -0,0 +1,236 @@
+package randomizer
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "sealed-pick",
+		operand:    operandNone,
+		permission: permWrite,
+		exclusions: true,
+		flags:      []string{"pick", "exclude", "variant"},
+		handler:    App.sealPick,
+		section:    helpHistory,
+		help:       []string{"*Pick now, but reveal later:* {{.Name}} /sealed-pick raffle"},
+	})
+	registerCommand(&command{
+		name:       "reveal",
+		permission: permWrite,
+		handler:    App.revealPick,
+		section:    helpHistory,
+		help:       []string{"*Reveal a sealed pick:* {{.Name}} /reveal k3x9qp"},
+	})
+}
+
+// A sealed pick draws its winners immediately but keeps them secret until
+// someone reveals the pick by its ID, like for a raffle drawn before the prize
+// ceremony. When it's drawn, the randomizer publishes a commitment: the
+// SHA-256 hash of the pick's ID, group, winners, and a random nonce. The reveal
+// discloses the nonce, so anyone can hash the same values and check that the
+// winners weren't re-rolled in between. The nonce keeps the commitment from
+// being checked against every possible winner before the reveal.
+//
+// The winners are stored in the clear until the reveal, so a sealed pick hides
+// them from the channel but not from the store's operators.
+
+// sealedRecord holds recent sealed picks in a store. Each entry is a
+// space-separated UTC timestamp, pick ID, sealing user (or "-"), source group
+// (or "-"), nonce, reveal timestamp (or "-"), and the winners in order.
+const sealedRecord = recordPrefix + "sealed"
+
+// maxSealed is the number of sealed picks that are kept, revealed or not.
+const maxSealed = 50
+
+const sealedNonceBytes = 16
+
+type sealedPick struct {
+	ID         string
+	At         time.Time
+	User       string // Empty if the user is unknown
+	Group      string // Empty if the options were given directly
+	Nonce      string
+	RevealedAt time.Time // Zero until the pick is revealed
+	Winners    []string
+}
+
+// commitment returns the canonical text of the pick that its commitment
+// hashes.
+func (p sealedPick) commitment() string {
+	return strings.Join([]string{p.ID, orDash(p.Group), strings.Join(p.Winners, ","), p.Nonce}, "|")
+}
+
+// hash returns the hex-encoded SHA-256 hash of the pick's commitment.
+func (p sealedPick) hash() string {
+	sum := sha256.Sum256([]byte(p.commitment()))
+	return hex.EncodeToString(sum[:])
+}
+
+func (p sealedPick) entry() string {
+	revealed := "-"
+	if !p.RevealedAt.IsZero() {
+		revealed = p.RevealedAt.UTC().Format(historyTimeFormat)
+	}
+	fields := []string{p.At.UTC().Format(historyTimeFormat), p.ID, orDash(p.User), orDash(p.Group), p.Nonce, revealed}
+	return strings.Join(append(fields, p.Winners...), " ")
+}
+
+func parseSealedPick(entry string) (sealedPick, bool) {
+	fields := strings.Fields(entry)
+	if len(fields) < 7 {
+		return sealedPick{}, false
+	}
+	at, err := time.Parse(historyTimeFormat, fields[0])
+	if err != nil {
+		return sealedPick{}, false
+	}
+	p := sealedPick{
+		ID:      fields[1],
+		At:      at,
+		User:    fromDash(fields[2]),
+		Group:   fromDash(fields[3]),
+		Nonce:   fields[4],
+		Winners: fields[6:],
+	}
+	if fields[5] != "-" {
+		if p.RevealedAt, err = time.Parse(historyTimeFormat, fields[5]); err != nil {
+			return sealedPick{}, false
+		}
+	}
+	return p, true
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func fromDash(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+func newSealedNonce() string {
+	nonce := make([]byte, sealedNonceBytes)
+	rand.Read(nonce)
+	return hex.EncodeToString(nonce)
+}
+
+func (a App) sealPick(request request) (Result, error) {
+	draw, err := a.draw(request)
+	if err != nil {
+		return Result{}, err
+	}
+
+	pick := sealedPick{
+		ID:      newResultID(),
+		At:      a.now(),
+		User:    a.user,
+		Group:   draw.group,
+		Nonce:   newSealedNonce(),
+		Winners: draw.winners(),
+	}
+	err = a.updateSealedPicks(request.Context, func(picks []string) []string {
+		if len(picks) >= maxSealed {
+			picks = picks[len(picks)-maxSealed+1:]
+		}
+		return append(picks, pick.entry())
+	})
+	if err != nil {
+		return Result{}, a.storeError(err, "sealing that pick")
+	}
+
+	return Result{
+		resultType: SealedPick,
+		message: fmt.Sprintf(
+			":lock: I sealed a pick of %s. To reveal %s, use: %s /reveal %s\n(Commitment: `%s`)",
+			pluralVerb(len(pick.Winners), "1 winner", fmt.Sprintf("%d winners", len(pick.Winners))),
+			pluralVerb(len(pick.Winners), "it", "them"), a.name, pick.ID, pick.hash(),
+		),
+		id: pick.ID,
+	}, nil
+}
+
+func (a App) revealPick(request request) (Result, error) {
+	id := strings.ToLower(strings.TrimPrefix(request.Operand, "#"))
+	picks, err := a.store.Get(request.Context, sealedRecord)
+	if err != nil {
+		return Result{}, a.storeError(err, "looking up that sealed pick")
+	}
+	i := slices.IndexFunc(picks, func(entry string) bool {
+		p, ok := parseSealedPick(entry)
+		return ok && p.ID == id
+	})
+	if i < 0 {
+		return Result{}, Error{
+			cause: fmt.Errorf("sealed pick %q not found", request.Operand),
+			helpText: fmt.Sprintf(
+				"Whoops, I can't find a sealed pick with the ID %q in this channel. (I only keep the last %d.)",
+				request.Operand, maxSealed,
+			),
+		}
+	}
+
+	pick, _ := parseSealedPick(picks[i])
+	if pick.User != "" && a.user != "" && pick.User != a.user {
+		return Result{}, Error{
+			cause:    fmt.Errorf("sealed pick %q belongs to %s", pick.ID, pick.User),
+			helpText: fmt.Sprintf("Whoops, only <@%s> can reveal that pick.", pick.User),
+		}
+	}
+
+	if pick.RevealedAt.IsZero() {
+		sealed := picks[i]
+		pick.RevealedAt = a.now()
+		err := a.updateSealedPicks(request.Context, func(picks []string) []string {
+			for i, entry := range picks {
+				if entry == sealed {
+					picks[i] = pick.entry()
+				}
+			}
+			return picks
+		})
+		if err != nil {
+			return Result{}, a.storeError(err, "revealing that sealed pick")
+		}
+		// Like any other selection, the winners count in the group's history,
+		// but only once they're public.
+		a.saveResultRecord(request.Context, ResultRecord{ID: pick.ID, At: pick.At, Group: pick.Group, Choices: pick.Winners})
+		if pick.Group != "" {
+			a.recordSelection(request.Context, pick.Group, pick.Winners, pick.ID)
+		}
+	}
+
+	return Result{
+		resultType: RevealedPick,
+		message: fmt.Sprintf(
+			":unlock: Sealed pick %s, drawn on %s, got: %s.\nTo check it, hash `%s` with SHA-256 and compare it to the commitment `%s`.",
+			pick.ID, slackDate(pick.At, false), inlinelist(pick.Winners), pick.commitment(), pick.hash(),
+		),
+		choices: pick.Winners,
+		group:   pick.Group,
+		picked:  true,
+		id:      pick.ID,
+	}, nil
+}
+
+// updateSealedPicks replaces the sealed picks in the store with the result of
+// calling update on them in chronological order.
+func (a App) updateSealedPicks(ctx context.Context, update func([]string) []string) error {
+	return Update(ctx, a.store, sealedRecord, func(picks []string) ([]string, error) {
+		slices.Sort(picks)
+		return update(picks), nil
+	})
+}
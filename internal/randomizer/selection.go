@@ -2,44 +2,406 @@ package randomizer
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
 )
 
+func init() {
+	registerCommand(&command{
+		name:       "pick",
+		permission: permRead,
+		exclusions: true,
+		flags:      []string{"pick", "exclude", "variant", "event", "event-duration"},
+		handler:    App.pickOptions,
+		section:    helpBasics,
+		help:       []string{"*Pick a few winners:* {{.Name}} /pick 3 snacks"},
+	})
+	registerCommand(&command{
+		name:       "select",
+		implicit:   true,
+		permission: permRead,
+		exclusions: true,
+		flags:      []string{"pick", "exclude", "variant", "event", "event-duration"},
+		handler:    App.makeSelection,
+		section:    helpBasics,
+		help: []string{
+			"*Use a group:* {{.Name}} snacks",
+			"*Make some options likelier:* {{.Name}} pizza*3 sushi salad",
+			"*Select by tag:* {{.Name}} +team #backend",
+			"*Filter a group by tag:* {{.Name}} +team where backend and not ooo",
+			"*Leave a few people out:* {{.Name}} +team -alice -bob",
+			"*Combine groups and options:* {{.Name}} +on-call +backend charlie",
+			"*Add the winner to a calendar:* {{.Name}} +team --event=2026-01-02T15:00 --event-duration=1h",
+		},
+	})
+}
+
 func (a App) makeSelection(request request) (Result, error) {
-	options, err := a.expandArgs(request.Context, request.Args)
+	config := a.selectionSettings(request.Context)
+	request.defaultPick = config.Pick
+	draw, err := a.draw(request)
 	if err != nil {
 		return Result{}, err
 	}
 
-	a.shuffle(options)
+	var icon string
+	if draw.group != "" {
+		icon = a.groupIcon(request.Context, draw.group)
+	}
 
-	return Result{
+	var id string
+	if !request.DryRun() {
+		id = a.recordResult(request.Context, draw.group, draw.choices)
+		if draw.group != "" {
+			a.recordSelection(request.Context, draw.group, draw.winners(), id)
+		}
+	}
+
+	phrase := icon + config.phrase(draw.choices)
+	result := Result{
 		resultType: Selection,
-		message:    fmt.Sprintf("I randomized and got: %s.", inlinelist(options)),
-	}, nil
+		message:    phrase + draw.notes,
+		choices:    draw.choices,
+		group:      draw.group,
+		picked:     draw.pick > 0,
+		private:    config.Visibility == "private",
+		id:         id,
+		fields: ResultFields{
+			Choice:  draw.choices[0],
+			Choices: draw.choices,
+			Group:   draw.group,
+			User:    a.user,
+			ID:      id,
+			Icon:    icon,
+			Picked:  draw.pick > 0,
+		},
+		phrase:   phrase,
+		template: config.Template,
+	}
+	if _, ok := request.Flags.Value("event"); ok {
+		var err error
+		if result, err = a.withEvent(request.Context, result, request.Flags); err != nil {
+			return Result{}, err
+		}
+	}
+	if id != "" {
+		result.message += fmt.Sprintf("\n(Result ID: %s)", id)
+	}
+	return result, nil
 }
 
-func (a App) expandArgs(ctx context.Context, args []string) ([]string, error) {
+// selectionDraw is the outcome of drawing from the options that a request's
+// arguments describe, before the draw is recorded or announced.
+type selectionDraw struct {
+	group   string // Empty if the options were given directly
+	choices []string
+	pick    int    // The number of winners requested, or 0 to order every option
+	notes   string // Explanations of how the group's rules shaped the draw
+}
+
+// winners returns the choices that count as winners in the group's history.
+func (d selectionDraw) winners() []string {
+	if d.pick > 0 {
+		return d.choices
+	}
+	return d.choices[:1]
+}
+
+// draw randomizes the options that a request's arguments describe, applying
+// the variants, filters, expiry, weights, and caps of any group they name.
+func (a App) draw(request request) (selectionDraw, error) {
+	args, where := splitWhere(request.Args)
+
+	var variantNote string
+	if len(args) == 1 {
+		variant, note, err := a.resolveVariant(request.Context, groupReference(args[0]), request.Flags)
+		if err != nil {
+			return selectionDraw{}, err
+		}
+		args, variantNote = []string{"+" + variant}, note
+	}
+
+	options, err := a.expandArgs(request.Context, args)
+	if err != nil {
+		return selectionDraw{}, err
+	}
+
+	if where != nil {
+		options, err = applyFilter(options, where)
+		if err != nil {
+			return selectionDraw{}, err
+		}
+	}
+
+	var exclusionNote string
+	if excluded := request.Flags["exclude"]; len(excluded) > 0 {
+		options, exclusionNote, err = applyExclusions(options, canonicalMentions(excluded))
+		if err != nil {
+			return selectionDraw{}, err
+		}
+	}
+
+	var expiryNote string
+	if len(args) == 1 {
+		options, expiryNote, err = a.applyExpiry(request.Context, groupReference(args[0]), options)
+		if err != nil {
+			return selectionDraw{}, err
+		}
+	}
+
+	pick, err := a.pickCount(request.Flags, len(options))
+	if err != nil {
+		return selectionDraw{}, err
+	}
+	if _, ok := request.Flags.Value("pick"); !ok && request.defaultPick > 0 {
+		// Unlike an explicit --pick, a default can't know how many options a
+		// request has, so it picks them all rather than fail.
+		pick = min(request.defaultPick, len(options))
+	}
+
+	choices := a.weightedOrder(options)
+
+	var group, capNote string
 	if len(args) == 1 {
-		return a.expandGroup(ctx, args[0])
+		group = groupReference(args[0])
+		choices, capNote, err = a.applyPickCap(request.Context, group, choices, max(pick, 1))
+		if err != nil {
+			return selectionDraw{}, err
+		}
+	}
+
+	if pick > 0 {
+		choices = choices[:pick]
+	}
+	return selectionDraw{
+		group:   group,
+		choices: choices,
+		pick:    pick,
+		notes:   capNote + expiryNote + exclusionNote + variantNote,
+	}, nil
+}
+
+// pickOptions handles "/pick N ...", which selects N distinct winners from the
+// options that the rest of the arguments describe, as if they were given the
+// "--pick" flag.
+func (a App) pickOptions(request request) (Result, error) {
+	request.Flags = maps.Clone(request.Flags)
+	request.Flags["pick"] = append(request.Flags["pick"], request.Operand)
+	return a.makeSelection(request)
+}
+
+// pickCount returns the number of winners requested with the "--pick" flag,
+// or 0 if the selection should order every option.
+func (a App) pickCount(flags flagSet, available int) (int, error) {
+	value, ok := flags.Value("pick")
+	if !ok {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, Error{
+			cause:    fmt.Errorf("invalid pick count %q", value),
+			helpText: fmt.Sprintf(`Whoops, I need a number of winners to pick, like "%s /pick 3 snacks"!`, a.name),
+		}
+	}
+	if n > available {
+		return 0, Error{
+			cause: fmt.Errorf("pick count %d exceeds %d options", n, available),
+			helpText: fmt.Sprintf(
+				"Whoops, I can't pick %d winners from only %d %s!",
+				n, available, pluralVerb(available, "option", "options"),
+			),
+		}
+	}
+	return n, nil
+}
+
+// splitWhere separates a trailing filter expression from the arguments of a
+// selection. To avoid surprises when "where" or "#tag" are simply some of
+// several inline options, we only recognize filters that follow a single group
+// reference.
+//
+// Following an explicit "+group" reference, any "#tag" arguments narrow the
+// selection to options with all of those tags, and may be combined with a
+// "where" expression.
+func splitWhere(args []string) (candidates, where []string) {
+	if len(args) < 2 {
+		return args, nil
+	}
+
+	if strings.HasPrefix(args[0], "+") {
+		rest := args[1:]
+		for len(rest) > 0 && len(rest[0]) > 1 && strings.HasPrefix(rest[0], "#") {
+			if where != nil {
+				where = append(where, "and")
+			}
+			where = append(where, "tag="+rest[0][1:])
+			rest = rest[1:]
+		}
+		if where != nil && len(rest) == 0 {
+			return args[:1], where
+		}
+		if where != nil && (len(rest) < 2 || !strings.EqualFold(rest[0], "where")) {
+			return args, nil
+		}
+		if where != nil {
+			return args[:1], slices.Concat(where, []string{"and", "("}, rest[1:], []string{")"})
+		}
 	}
 
+	if len(args) > 2 && strings.EqualFold(args[1], "where") {
+		return args[:1], args[2:]
+	}
 	return args, nil
 }
 
-func (a App) expandGroup(ctx context.Context, group string) ([]string, error) {
-	expansion, err := a.store.Get(ctx, group)
+func applyFilter(options []option, where []string) ([]option, error) {
+	match, err := parseFilter(where)
 	if err != nil {
 		return nil, Error{
-			cause: err,
-			helpText: fmt.Sprintf(
-				"Whoops, I had trouble getting the %q group. Please try again later!",
-				group,
-			),
+			cause:    fmt.Errorf("parsing filter: %w", err),
+			helpText: fmt.Sprintf("Whoops, I couldn't understand that filter (%v).", err),
+		}
+	}
+
+	var matched []option
+	for _, o := range options {
+		if match(o) {
+			matched = append(matched, o)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, Error{
+			cause:    fmt.Errorf("no options match filter %q", strings.Join(where, " ")),
+			helpText: "Whoops, none of the options in that group match your filter!",
+		}
+	}
+	return matched, nil
+}
+
+// applyExclusions leaves out the options with any of the excluded names, and
+// notes any names that don't match an option, in case of a typo.
+func applyExclusions(options []option, excluded []string) ([]option, string, error) {
+	var (
+		kept    []option
+		matched = make([]bool, len(excluded))
+	)
+	for _, o := range options {
+		i := slices.IndexFunc(excluded, func(name string) bool { return strings.EqualFold(name, o.Name) })
+		if i < 0 {
+			kept = append(kept, o)
+			continue
+		}
+		for j, name := range excluded {
+			matched[j] = matched[j] || strings.EqualFold(name, o.Name)
+		}
+	}
+	if len(kept) == 0 {
+		return nil, "", Error{
+			cause:    errors.New("every option excluded"),
+			helpText: "Whoops, leaving out those options leaves nothing to pick from!",
+		}
+	}
+
+	var unmatched []string
+	for i, name := range excluded {
+		if !matched[i] {
+			unmatched = append(unmatched, name)
+		}
+	}
+	if len(unmatched) == 0 {
+		return kept, "", nil
+	}
+	return kept, fmt.Sprintf("\n:mag: I didn't find %s to leave out.", inlinelist(unmatched)), nil
+}
+
+// expandArgs returns the options that the arguments of a selection describe.
+// A single argument names a group, with or without a "+" prefix. Otherwise,
+// any "+group" references are replaced with the group's options and merged
+// with the rest of the arguments, which are options themselves. An option
+// that appears more than once in a merge is only kept once, so that someone in
+// two groups doesn't get twice the chance of being picked.
+func (a App) expandArgs(ctx context.Context, args []string) ([]option, error) {
+	if len(args) == 1 {
+		return a.expandGroup(ctx, groupReference(args[0]))
+	}
+	if !slices.ContainsFunc(args, isGroupReference) {
+		return parseTypedOptions(args)
+	}
+
+	var (
+		merged []option
+		seen   = make(map[string]bool)
+	)
+	for _, arg := range args {
+		var options []option
+		if isGroupReference(arg) {
+			expanded, err := a.expandGroup(ctx, groupReference(arg))
+			if err != nil {
+				return nil, err
+			}
+			options = expanded
+		} else {
+			inline, err := parseTypedOptions([]string{arg})
+			if err != nil {
+				return nil, err
+			}
+			options = inline
+		}
+		for _, o := range options {
+			if !seen[o.Name] {
+				seen[o.Name] = true
+				merged = append(merged, o)
+			}
 		}
 	}
+	return merged, nil
+}
+
+// selectedGroups returns the names of the groups that expandArgs would expand
+// for the arguments of a selection.
+func selectedGroups(args []string) []string {
+	if len(args) == 1 {
+		return []string{groupReference(args[0])}
+	}
+	var groups []string
+	for _, arg := range args {
+		if isGroupReference(arg) {
+			groups = append(groups, groupReference(arg))
+		}
+	}
+	return groups
+}
+
+// isGroupReference reports whether an argument is an explicit "+group"
+// reference.
+func isGroupReference(arg string) bool {
+	return len(arg) > 1 && strings.HasPrefix(arg, "+")
+}
+
+// groupReference returns the name of the group referenced by an argument,
+// which may be given bare or with an explicit "+" prefix.
+func groupReference(arg string) string {
+	if name, ok := strings.CutPrefix(arg, "+"); ok && name != "" {
+		return name
+	}
+	return arg
+}
+
+func (a App) expandGroup(ctx context.Context, group string) ([]option, error) {
+	expansion, err := a.getGroup(ctx, group)
+	if err != nil {
+		return nil, a.storeError(err, fmt.Sprintf("getting the %q group", group))
+	}
 
 	if len(expansion) == 0 {
+		if options, err := a.federatedOptions(ctx, group); options != nil || err != nil {
+			return options, err
+		}
 		return nil, Error{
 			cause: fmt.Errorf("group %q not found", group),
 			helpText: fmt.Sprintf(
@@ -49,5 +411,5 @@ func (a App) expandGroup(ctx context.Context, group string) ([]string, error) {
 		}
 	}
 
-	return expansion, nil
+	return parseOptions(expansion), nil
 }
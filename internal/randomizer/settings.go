@@ -0,0 +1,339 @@
+package randomizer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Settings set defaults for selections, which apply whenever a request leaves
+// them out: the group to pick from when a request names no options, the
+// number of winners to pick without --pick, how to phrase the result (or the
+// template that frontends may render instead), and whether the result is shown
+// to the whole channel.
+//
+// Settings may be configured at several levels, from operators' defaults for
+// the whole deployment down to a single user's preferences. Each setting comes
+// from the most specific level that sets it, so a channel can override its
+// workspace, and a user can override the channel.
+
+// SettingsLevel identifies a level at which Settings may be configured.
+type SettingsLevel int
+
+const (
+	// LevelOrg holds the operators' defaults for every workspace, given to
+	// [WithDefaults] in [Defaults.Settings].
+	LevelOrg SettingsLevel = iota
+	// LevelWorkspace holds defaults for every channel in a workspace, in the
+	// store given to [WithWorkspaceStore].
+	LevelWorkspace
+	// LevelChannel holds defaults for a single channel, in its own store.
+	LevelChannel
+	// LevelUser holds a user's preferences across a workspace, in the store
+	// given to [WithWorkspaceStore].
+	LevelUser
+
+	settingsLevels = iota
+)
+
+var settingsLevelNames = [settingsLevels]string{
+	LevelOrg:       "org",
+	LevelWorkspace: "workspace",
+	LevelChannel:   "channel",
+	LevelUser:      "user",
+}
+
+func (l SettingsLevel) String() string {
+	if l < 0 || int(l) >= len(settingsLevelNames) {
+		return fmt.Sprintf("SettingsLevel(%d)", int(l))
+	}
+	return settingsLevelNames[l]
+}
+
+// configRecord holds the settings of a workspace or channel in its store,
+// with entries of the form "<key>|<value>".
+const configRecord = recordPrefix + "config"
+
+// userConfigRecord holds a user's settings in their workspace's store, in the
+// same form as configRecord.
+func userConfigRecord(user string) string {
+	return configRecord + "/user/" + user
+}
+
+// maxPhrasing bounds the length of a custom result phrasing.
+const maxPhrasing = 200
+
+// resultPlaceholder marks where a custom phrasing puts the result.
+const resultPlaceholder = "{result}"
+
+// Settings holds the defaults for selections at a single level. Zero values
+// leave the setting to a less specific level, or to the built-in behavior.
+type Settings struct {
+	Group      string
+	Pick       int
+	Phrasing   string
+	Template   string // See resulttemplate.go
+	Visibility string // "public" or "private"
+}
+
+// settingSpec describes a single user-facing key of Settings.
+type settingSpec struct {
+	key      string
+	get      func(Settings) string
+	set      func(*Settings, string) bool
+	helpText string
+}
+
+// settingSpecs lists the keys of Settings, in display order.
+var settingSpecs = []settingSpec{
+	{
+		key: "group",
+		get: func(s Settings) string { return s.Group },
+		set: func(s *Settings, value string) bool {
+			if value == "" || isForbiddenGroupName(value) || strings.ContainsAny(value, " |") {
+				return false
+			}
+			s.Group = value
+			return true
+		},
+		helpText: "Whoops, I need the name of a single group!",
+	},
+	{
+		key: "pick",
+		get: func(s Settings) string {
+			if s.Pick == 0 {
+				return ""
+			}
+			return strconv.Itoa(s.Pick)
+		},
+		set: func(s *Settings, value string) bool {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return false
+			}
+			s.Pick = n
+			return true
+		},
+		helpText: "Whoops, I need a number of winners to pick, like 2!",
+	},
+	{
+		key: "phrasing",
+		get: func(s Settings) string { return s.Phrasing },
+		set: func(s *Settings, value string) bool {
+			if !strings.Contains(value, resultPlaceholder) || len(value) > maxPhrasing || strings.ContainsAny(value, "|\n") {
+				return false
+			}
+			s.Phrasing = value
+			return true
+		},
+		helpText: fmt.Sprintf(
+			"Whoops, the phrasing needs to include %s where the result goes, and be %d characters or less!",
+			resultPlaceholder, maxPhrasing,
+		),
+	},
+	{
+		key: "template",
+		get: func(s Settings) string { return s.Template },
+		set: func(s *Settings, value string) bool {
+			if strings.Contains(value, "\n") || !validResultTemplate(value) {
+				return false
+			}
+			s.Template = value
+			return true
+		},
+		helpText: fmt.Sprintf(
+			"Whoops, the template needs to be %d characters or less, and only use fields of a result like {{.Choice}} and {{.Group}}!",
+			maxResultTemplate,
+		),
+	},
+	{
+		key: "visibility",
+		get: func(s Settings) string { return s.Visibility },
+		set: func(s *Settings, value string) bool {
+			value = strings.ToLower(value)
+			if value != "public" && value != "private" {
+				return false
+			}
+			s.Visibility = value
+			return true
+		},
+		helpText: `Whoops, the visibility needs to be "public" or "private"!`,
+	},
+}
+
+func findSettingSpec(key string) (settingSpec, bool) {
+	i := slices.IndexFunc(settingSpecs, func(s settingSpec) bool {
+		return strings.EqualFold(s.key, key)
+	})
+	if i < 0 {
+		return settingSpec{}, false
+	}
+	return settingSpecs[i], true
+}
+
+// SettingKeys returns the keys that [Settings.Set] accepts, in display order.
+func SettingKeys() []string {
+	keys := make([]string, len(settingSpecs))
+	for i, spec := range settingSpecs {
+		keys[i] = spec.key
+	}
+	return keys
+}
+
+// Set parses and sets the setting with the given key, as users would with
+// /config.
+func (s *Settings) Set(key, value string) error {
+	spec, ok := findSettingSpec(key)
+	if !ok {
+		return fmt.Errorf("unknown setting %q (want one of %s)", key, strings.Join(SettingKeys(), ", "))
+	}
+	if !spec.set(s, value) {
+		return fmt.Errorf("invalid value %q for setting %q", value, spec.key)
+	}
+	return nil
+}
+
+// Values returns the settings that s sets, keyed as for [Settings.Set].
+func (s Settings) Values() map[string]string {
+	values := make(map[string]string)
+	for _, spec := range settingSpecs {
+		if value := spec.get(s); value != "" {
+			values[spec.key] = value
+		}
+	}
+	return values
+}
+
+func (s Settings) entries() []string {
+	var entries []string
+	for _, spec := range settingSpecs {
+		if value := spec.get(s); value != "" {
+			entries = append(entries, spec.key+"|"+value)
+		}
+	}
+	return entries
+}
+
+// without returns a copy of s with the setting named by key cleared.
+func (s Settings) without(key string) Settings {
+	var cleared Settings
+	for _, spec := range settingSpecs {
+		if value := spec.get(s); spec.key != key && value != "" {
+			spec.set(&cleared, value)
+		}
+	}
+	return cleared
+}
+
+// phrase returns the message announcing a selection's choices.
+func (s Settings) phrase(choices []string) string {
+	if s.Phrasing == "" {
+		return fmt.Sprintf("I randomized and got: %s.", inlinelist(choices))
+	}
+	return strings.ReplaceAll(s.Phrasing, resultPlaceholder, inlinelist(choices))
+}
+
+func loadSettings(ctx context.Context, store Store, record string) (Settings, error) {
+	entries, err := store.Get(ctx, record)
+	if err != nil {
+		return Settings{}, err
+	}
+
+	var settings Settings
+	for _, entry := range entries {
+		key, value, ok := strings.Cut(entry, "|")
+		if !ok {
+			continue
+		}
+		if spec, ok := findSettingSpec(key); ok {
+			spec.set(&settings, value)
+		}
+	}
+	return settings, nil
+}
+
+// LayeredSettings holds the Settings configured at each level for a single
+// user in a single channel.
+type LayeredSettings struct {
+	levels [settingsLevels]Settings
+}
+
+// LoadSettings loads the settings for a user in a channel, on top of the
+// operators' org defaults. A nil store or empty user skips the levels that
+// need it.
+func LoadSettings(ctx context.Context, org Settings, workspace, channel Store, user string) (LayeredSettings, error) {
+	var (
+		layers LayeredSettings
+		err    error
+	)
+	layers.levels[LevelOrg] = org
+	if workspace != nil {
+		if layers.levels[LevelWorkspace], err = loadSettings(ctx, workspace, configRecord); err != nil {
+			return LayeredSettings{}, err
+		}
+		if user != "" {
+			if layers.levels[LevelUser], err = loadSettings(ctx, workspace, userConfigRecord(user)); err != nil {
+				return LayeredSettings{}, err
+			}
+		}
+	}
+	if channel != nil {
+		if layers.levels[LevelChannel], err = loadSettings(ctx, channel, configRecord); err != nil {
+			return LayeredSettings{}, err
+		}
+	}
+	return layers, nil
+}
+
+// At returns the settings configured at a single level.
+func (l LayeredSettings) At(level SettingsLevel) Settings {
+	return l.levels[level]
+}
+
+// Source returns the most specific level that sets the setting named by key,
+// or false if no level sets it.
+func (l LayeredSettings) Source(key string) (SettingsLevel, bool) {
+	spec, ok := findSettingSpec(key)
+	if !ok {
+		return 0, false
+	}
+	for level := SettingsLevel(settingsLevels - 1); level >= 0; level-- {
+		if spec.get(l.levels[level]) != "" {
+			return level, true
+		}
+	}
+	return 0, false
+}
+
+// Effective returns the settings that apply, each from the most specific level
+// that sets it.
+func (l LayeredSettings) Effective() Settings {
+	var effective Settings
+	for _, level := range l.levels {
+		for _, spec := range settingSpecs {
+			if value := spec.get(level); value != "" {
+				spec.set(&effective, value)
+			}
+		}
+	}
+	return effective
+}
+
+// settings loads the settings for the App's user in its channel.
+func (a App) settings(ctx context.Context) (LayeredSettings, error) {
+	return LoadSettings(ctx, a.defaults.Settings, a.workspace, a.store, a.user)
+}
+
+// selectionSettings returns the effective settings for a selection. Like
+// icons, settings only shape how a selection is presented, so a selection goes
+// ahead with the built-in defaults if they can't be read.
+func (a App) selectionSettings(ctx context.Context) Settings {
+	settings, err := a.settings(ctx)
+	if err != nil {
+		a.logger.Warn("Failed to get settings", "err", err)
+		return a.defaults.Settings
+	}
+	return settings.Effective()
+}
@@ -0,0 +1,138 @@
+package randomizer
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "tag",
+		permission: permWrite,
+		handler:    App.tagOption,
+		section:    helpOptions,
+		help:       []string{"*Tag an option later:* {{.Name}} /tag team alice oncall=yes"},
+	})
+	registerCommand(&command{
+		name:       "untag",
+		permission: permWrite,
+		handler:    App.untagOption,
+		section:    helpOptions,
+		help:       []string{"*Remove a tag from an option:* {{.Name}} /untag team bob ooo"},
+	})
+}
+
+func (a App) tagOption(request request) (Result, error) {
+	// Removing tags never needs a review, so that offensive ones can go.
+	if len(request.Args) > 1 {
+		if err := a.reviewContent(request.Context, normalizeTags(request.Args[1:])...); err != nil {
+			return Result{}, err
+		}
+	}
+	return a.updateOptionTags(request, option.withTags)
+}
+
+func (a App) untagOption(request request) (Result, error) {
+	return a.updateOptionTags(request, option.withoutTags)
+}
+
+func (a App) updateOptionTags(request request, update func(option, []string) option) (Result, error) {
+	var (
+		ctx   = request.Context
+		group = request.Operand
+	)
+
+	if len(request.Args) < 2 {
+		return Result{}, Error{
+			cause: errors.New("too few arguments to update tags"),
+			helpText: fmt.Sprintf(
+				`Whoops, I need the name of an option and at least one tag! (Type "%s help" to see an example.)`,
+				a.name,
+			),
+		}
+	}
+	name, tags := request.Args[0], normalizeTags(request.Args[1:])
+
+	var updated option
+	err := Update(ctx, a.store, group, func(stored []string) ([]string, error) {
+		if len(stored) == 0 || isRecordName(group) {
+			return nil, Error{
+				cause:    errors.New("group does not exist"),
+				helpText: "Whoops, I can't find that group in this channel. (Use the /save flag to create it!)",
+			}
+		}
+		options := parseOptions(stored)
+		i := slices.IndexFunc(options, func(o option) bool { return o.Name == name })
+		if i < 0 {
+			return nil, Error{
+				cause:    fmt.Errorf("option %q not in group %q", name, group),
+				helpText: fmt.Sprintf("Whoops, the %q group doesn't have an option named %q!", group, name),
+			}
+		}
+		options[i] = update(options[i], tags)
+		updated = options[i]
+
+		return storedOptions(options), nil
+	})
+	if err != nil {
+		return Result{}, a.updateError(err, "getting that group")
+	}
+
+	message := fmt.Sprintf("Done! The %q option in the %q group has no tags.", name, group)
+	if len(updated.Tags) > 0 {
+		message = fmt.Sprintf(
+			"Done! The %q option in the %q group has the following tags:\n%s",
+			name, group, bulletlist(updated.Tags),
+		)
+	}
+	return Result{
+		resultType: TaggedOption,
+		message:    message,
+	}, nil
+}
+
+// normalizeTags strips the optional "#" prefix from tags as typed by users,
+// which mirrors the syntax for selecting by tag.
+func normalizeTags(args []string) []string {
+	tags := make([]string, 0, len(args))
+	for _, arg := range args {
+		if tag := strings.TrimLeft(arg, "#"); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// withTags returns a copy of the option with the provided tags added. Tags
+// with a key=value form replace any existing attribute with the same key.
+func (o option) withTags(tags []string) option {
+	result := option{Name: o.Name, Tags: slices.Clone(o.Tags)}
+	for _, tag := range tags {
+		key, _, isAttr := strings.Cut(tag, "=")
+		if isAttr {
+			result = result.withoutTags([]string{key})
+		} else if result.HasTag(tag) {
+			continue
+		}
+		result.Tags = append(result.Tags, tag)
+	}
+	return result
+}
+
+// withoutTags returns a copy of the option with the provided bare tags, and
+// any attributes with the provided keys, removed.
+func (o option) withoutTags(labels []string) option {
+	result := option{Name: o.Name}
+	for _, tag := range o.Tags {
+		key, _, _ := strings.Cut(tag, "=")
+		if !slices.ContainsFunc(labels, func(label string) bool {
+			label, _, _ = strings.Cut(label, "=")
+			return strings.EqualFold(key, label)
+		}) {
+			result.Tags = append(result.Tags, tag)
+		}
+	}
+	return result
+}
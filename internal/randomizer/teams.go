@@ -0,0 +1,85 @@
+package randomizer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "teams",
+		permission: permRead,
+		exclusions: true,
+		flags:      []string{"exclude"},
+		handler:    App.splitTeams,
+		section:    helpBasics,
+		help: []string{
+			"*Split people into teams:* {{.Name}} /teams 2 alice bob carol dave",
+			"*Split a saved group into teams:* {{.Name}} /teams 3 +standup",
+		},
+	})
+}
+
+func (a App) splitTeams(request request) (Result, error) {
+	n, err := strconv.Atoi(request.Operand)
+	if err != nil || n < 1 {
+		return Result{}, Error{
+			cause:    fmt.Errorf("invalid team count %q", request.Operand),
+			helpText: fmt.Sprintf(`Whoops, I need a number of teams, like "%s /teams 2 alice bob carol dave"!`, a.name),
+		}
+	}
+	if len(request.Args) == 0 {
+		return Result{}, Error{
+			cause: errors.New("no options to split into teams"),
+			helpText: fmt.Sprintf(
+				`Whoops, I need a group or some options to split into teams! (Type "%s help" to see an example.)`,
+				a.name,
+			),
+		}
+	}
+
+	options, err := a.expandArgs(request.Context, request.Args)
+	if err != nil {
+		return Result{}, err
+	}
+	var note string
+	if excluded := request.Flags["exclude"]; len(excluded) > 0 {
+		options, note, err = applyExclusions(options, canonicalMentions(excluded))
+		if err != nil {
+			return Result{}, err
+		}
+	}
+	if len(options) < n {
+		return Result{}, Error{
+			cause: fmt.Errorf("%d options for %d teams", len(options), n),
+			helpText: fmt.Sprintf(
+				"Whoops, I can't split %s into %d teams!",
+				pluralize(len(options), "option", "options"), n,
+			),
+		}
+	}
+
+	names := optionNames(options)
+	a.shuffle(names)
+
+	// When the options don't divide evenly, the first teams get one extra.
+	var (
+		lines = make([]string, n)
+		rest  = names
+	)
+	for i := range n {
+		size := len(names) / n
+		if i < len(names)%n {
+			size++
+		}
+		lines[i] = fmt.Sprintf("Team %d: %s", i+1, inlinelist(rest[:size]))
+		rest = rest[size:]
+	}
+
+	return Result{
+		resultType: Teams,
+		message:    fmt.Sprintf("Here are the teams:\n%s%s", bulletlist(lines), note),
+		choices:    names,
+	}, nil
+}
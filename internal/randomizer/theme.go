@@ -0,0 +1,225 @@
+package randomizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "theme",
+		operand:    operandOptional,
+		permission: permWorkspace,
+		handler:    App.configureTheme,
+		section:    helpWorkspace,
+		help: []string{
+			"*Theme this workspace's results:* {{.Name}} /theme emoji :tada:",
+			"&gt; {{.Name}} /theme color #36a64f",
+		},
+	})
+}
+
+// Themes let a community personalize how the randomizer presents itself
+// across an entire workspace, rather than in a single channel. The App reads
+// and writes them through the store given to [WithWorkspaceStore], and leaves
+// applying them to the frontend that renders each result.
+
+// themeRecord holds a workspace's theme settings, with entries of the form
+// "<key>|<value>".
+const themeRecord = recordPrefix + "theme"
+
+// Theme describes how a frontend should decorate the randomizer's responses
+// in a workspace. Empty fields keep the frontend's defaults.
+type Theme struct {
+	// Emoji is a shortcode like ":tada:" to show before each selection.
+	Emoji string
+	// Color is a hex color like "#36a64f" for the accent bar beside each
+	// selection.
+	Color string
+	// Username replaces the bot's display name on messages it posts itself.
+	Username string
+	// IconEmoji is a shortcode like ":robot_face:" that replaces the bot's
+	// avatar on messages it posts itself.
+	IconEmoji string
+}
+
+// themeSetting describes a single user-facing key of a Theme.
+type themeSetting struct {
+	key      string
+	field    func(*Theme) *string
+	valid    func(string) bool
+	helpText string
+}
+
+var (
+	emojiPattern = regexp.MustCompile(`^:[a-z0-9_+'-]+:$`)
+	colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+)
+
+// themeSettings lists the keys that /theme accepts, in display order.
+var themeSettings = []themeSetting{
+	{
+		key:      "emoji",
+		field:    func(t *Theme) *string { return &t.Emoji },
+		valid:    emojiPattern.MatchString,
+		helpText: `Whoops, the emoji needs to be a shortcode like ":tada:"!`,
+	},
+	{
+		key:      "color",
+		field:    func(t *Theme) *string { return &t.Color },
+		valid:    colorPattern.MatchString,
+		helpText: `Whoops, the color needs to be a hex code like "#36a64f"!`,
+	},
+	{
+		key:      "name",
+		field:    func(t *Theme) *string { return &t.Username },
+		valid:    func(s string) bool { return len(s) <= 80 && !strings.ContainsAny(s, "|\n") },
+		helpText: "Whoops, the name needs to be 80 characters or less!",
+	},
+	{
+		key:      "icon",
+		field:    func(t *Theme) *string { return &t.IconEmoji },
+		valid:    emojiPattern.MatchString,
+		helpText: `Whoops, the icon needs to be an emoji shortcode like ":robot_face:"!`,
+	},
+}
+
+func findThemeSetting(key string) (themeSetting, bool) {
+	i := slices.IndexFunc(themeSettings, func(s themeSetting) bool {
+		return strings.EqualFold(s.key, key)
+	})
+	if i < 0 {
+		return themeSetting{}, false
+	}
+	return themeSettings[i], true
+}
+
+// LoadTheme returns the theme saved in a workspace's store, or an empty Theme
+// if none was saved.
+func LoadTheme(ctx context.Context, store Store) (Theme, error) {
+	entries, err := store.Get(ctx, themeRecord)
+	if err != nil {
+		return Theme{}, err
+	}
+
+	var theme Theme
+	for _, entry := range entries {
+		key, value, ok := strings.Cut(entry, "|")
+		if !ok {
+			continue
+		}
+		if setting, ok := findThemeSetting(key); ok && setting.valid(value) {
+			*setting.field(&theme) = value
+		}
+	}
+	return theme, nil
+}
+
+func (t Theme) entries() []string {
+	var entries []string
+	for _, setting := range themeSettings {
+		if value := *setting.field(&t); value != "" {
+			entries = append(entries, setting.key+"|"+value)
+		}
+	}
+	return entries
+}
+
+func (a App) configureTheme(request request) (Result, error) {
+	ctx := request.Context
+	if a.workspace == nil {
+		return Result{}, Error{
+			cause:    errors.New("no workspace store"),
+			helpText: "Whoops, themes aren't available here!",
+		}
+	}
+
+	theme, err := LoadTheme(ctx, a.workspace)
+	if err != nil {
+		return Result{}, a.storeError(err, "getting this workspace's theme")
+	}
+
+	if request.Operand == "" {
+		return a.showTheme(theme), nil
+	}
+
+	if strings.EqualFold(request.Operand, "reset") && len(request.Args) == 0 {
+		if _, err := a.workspace.Delete(ctx, themeRecord); err != nil {
+			return Result{}, a.storeError(err, "updating this workspace's theme")
+		}
+		return Result{
+			resultType: UpdatedTheme,
+			message:    "Done! This workspace is back to the default theme.",
+		}, nil
+	}
+
+	setting, ok := findThemeSetting(request.Operand)
+	if !ok {
+		return Result{}, Error{
+			cause: fmt.Errorf("unknown theme setting %q", request.Operand),
+			helpText: fmt.Sprintf(
+				`Whoops, I can only theme the emoji, color, name, or icon! (Type "%s help" to see an example.)`,
+				a.name,
+			),
+		}
+	}
+	if len(request.Args) == 0 {
+		return Result{}, Error{
+			cause:    fmt.Errorf("no value for theme setting %q", setting.key),
+			helpText: fmt.Sprintf(`Whoops, I need a value for the %s, or "off" to go back to the default!`, setting.key),
+		}
+	}
+
+	value := strings.Join(request.Args, " ")
+	removing := strings.EqualFold(value, "off")
+	if removing {
+		value = ""
+	} else if !setting.valid(value) {
+		return Result{}, Error{
+			cause:    fmt.Errorf("invalid value %q for theme setting %q", value, setting.key),
+			helpText: setting.helpText,
+		}
+	}
+	*setting.field(&theme) = value
+
+	if entries := theme.entries(); len(entries) == 0 {
+		_, err = a.workspace.Delete(ctx, themeRecord)
+	} else {
+		err = a.workspace.Put(ctx, themeRecord, entries)
+	}
+	if err != nil {
+		return Result{}, a.storeError(err, "updating this workspace's theme")
+	}
+
+	message := fmt.Sprintf("Done! This workspace's %s is now %s.", setting.key, value)
+	if removing {
+		message = fmt.Sprintf("Done! This workspace's %s is back to the default.", setting.key)
+	}
+	return Result{resultType: UpdatedTheme, message: message}, nil
+}
+
+func (a App) showTheme(theme Theme) Result {
+	var lines []string
+	for _, setting := range themeSettings {
+		if value := *setting.field(&theme); value != "" {
+			lines = append(lines, setting.key+": "+value)
+		}
+	}
+	if len(lines) == 0 {
+		return Result{
+			resultType: ShowedTheme,
+			message: fmt.Sprintf(
+				`This workspace uses the default theme. (Type "%s help" to see how to change it.)`,
+				a.name,
+			),
+		}
+	}
+	return Result{
+		resultType: ShowedTheme,
+		message:    "This workspace's theme is:\n" + bulletlist(lines),
+	}
+}
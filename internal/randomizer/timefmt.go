@@ -0,0 +1,24 @@
+package randomizer
+
+import (
+	"fmt"
+	"time"
+)
+
+// slackDate formats t using Slack's date formatting sequence, which Slack
+// renders in each viewer's own locale and time zone. The fallback text, shown
+// by clients that don't support the sequence, uses UTC.
+//
+// When dateOnly is true, the time of day is omitted, and t is taken to be
+// midnight UTC on the relevant date.
+func slackDate(t time.Time, dateOnly bool) string {
+	if dateOnly {
+		// Slack would otherwise render midnight UTC as the previous day for
+		// viewers west of UTC, so anchor the date to noon instead.
+		noon := time.Date(t.Year(), t.Month(), t.Day(), 12, 0, 0, 0, time.UTC)
+		return fmt.Sprintf("<!date^%d^{date_short_pretty}|%s>",
+			noon.Unix(), t.Format("Mon, Jan 2"))
+	}
+	return fmt.Sprintf("<!date^%d^{date_short_pretty} at {time}|%s>",
+		t.Unix(), t.UTC().Format("Mon, Jan 2 at 15:04 UTC"))
+}
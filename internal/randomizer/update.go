@@ -0,0 +1,45 @@
+package randomizer
+
+import (
+	"context"
+	"errors"
+)
+
+// Updater is implemented by stores that can edit a group atomically, so that
+// concurrent edits to the same group don't overwrite each other.
+type Updater interface {
+	// Update calls update with the current options in the named group, and
+	// saves the options it returns in their place. If the group changes in
+	// between, Update tries again with the new options, so update may be called
+	// more than once. An error from update cancels the edit, and Update returns
+	// it unchanged.
+	Update(ctx context.Context, group string, update func(options []string) ([]string, error)) error
+}
+
+// Update edits a group in the store with update, as described by
+// [Updater.Update]. If the store isn't an Updater, Update gets and puts the
+// group without protecting it from concurrent edits.
+func Update(ctx context.Context, store Store, group string, update func(options []string) ([]string, error)) error {
+	if updater, ok := store.(Updater); ok {
+		return updater.Update(ctx, group, update)
+	}
+	options, err := store.Get(ctx, group)
+	if err != nil {
+		return err
+	}
+	updated, err := update(options)
+	if err != nil {
+		return err
+	}
+	return store.Put(ctx, group, updated)
+}
+
+// updateError returns the error to show for a failed call to Update: an
+// Error from the update function itself, or a store error for the action.
+func (a App) updateError(err error, action string) error {
+	var userErr Error
+	if errors.As(err, &userErr) {
+		return userErr
+	}
+	return a.storeError(err, action)
+}
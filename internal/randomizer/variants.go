@@ -0,0 +1,357 @@
+package randomizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "variant",
+		permission: permWrite,
+		handler:    App.setVariant,
+		section:    helpRules,
+		help: []string{
+			"*Use a different group on Fridays:* {{.Name}} /variant snacks fri friday-snacks",
+			"*Or only at certain times:* {{.Name}} /variant snacks mon-thu 14:00-17:00 afternoon-snacks",
+			"*Pick from the usual group anyway:* {{.Name}} snacks --variant=none",
+		},
+	})
+}
+
+// Variants let a group stand in for another group at certain times, like a
+// "friday-lunch" group replacing the usual "lunch" options on Fridays. Each
+// variant rule names the days, and optionally the time of day, when it
+// applies, and is evaluated in the App's local time zone.
+//
+// A selection from the group uses the first matching variant, preferring rules
+// with a time window, then rules covering fewer days. The "--variant" flag
+// overrides the choice with a specific variant, or with "none" for the group
+// itself.
+
+// variantsRecord returns the name of the record holding a group's variant
+// rules, with entries of the form "<days>|<window>|<variant>".
+func variantsRecord(group string) string {
+	return recordPrefix + "variants/" + group
+}
+
+type variantRule struct {
+	Days    string // As typed by the user, e.g. "mon-fri"
+	Window  string // Empty, or as typed by the user, e.g. "11:00-14:00"
+	Variant string
+
+	days       [7]bool
+	start, end int // Minutes since midnight
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseDays accepts comma-separated days and day ranges like "mon,wed" or
+// "fri-mon", along with "weekdays", "weekends", and "daily".
+func parseDays(spec string) (days [7]bool, err error) {
+	for part := range strings.SplitSeq(strings.ToLower(spec), ",") {
+		switch part {
+		case "daily":
+			part = "sun-sat"
+		case "weekdays":
+			part = "mon-fri"
+		case "weekends":
+			part = "sat-sun"
+		}
+
+		from, to, isRange := strings.Cut(part, "-")
+		if !isRange {
+			to = from
+		}
+		first, ok1 := weekdayNames[from]
+		last, ok2 := weekdayNames[to]
+		if !ok1 || !ok2 {
+			return days, fmt.Errorf("invalid days %q", spec)
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// parseWindow accepts a time range like "11:00-14:00". A window whose end is
+// before its start wraps past midnight.
+func parseWindow(spec string) (start, end int, err error) {
+	from, to, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid time window %q", spec)
+	}
+	for _, bound := range []struct {
+		text string
+		dst  *int
+	}{{from, &start}, {to, &end}} {
+		t, err := time.Parse("15:04", bound.text)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid time window %q", spec)
+		}
+		*bound.dst = t.Hour()*60 + t.Minute()
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("empty time window %q", spec)
+	}
+	return start, end, nil
+}
+
+func parseVariantRule(days, window, variant string) (variantRule, error) {
+	rule := variantRule{Days: days, Window: window, Variant: variant}
+
+	var err error
+	if rule.days, err = parseDays(days); err != nil {
+		return rule, err
+	}
+	if window != "" {
+		if rule.start, rule.end, err = parseWindow(window); err != nil {
+			return rule, err
+		}
+	}
+	return rule, nil
+}
+
+func (r variantRule) entry() string {
+	return r.Days + "|" + r.Window + "|" + r.Variant
+}
+
+func (r variantRule) String() string {
+	if r.Window == "" {
+		return fmt.Sprintf("%s on %s", r.Variant, r.Days)
+	}
+	return fmt.Sprintf("%s on %s from %s", r.Variant, r.Days, r.Window)
+}
+
+func (r variantRule) matches(t time.Time) bool {
+	if r.Window == "" {
+		return r.days[t.Weekday()]
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	if r.start < r.end {
+		return r.days[t.Weekday()] && minute >= r.start && minute < r.end
+	}
+	// A window that wraps past midnight belongs to the day on which it starts.
+	if minute >= r.start {
+		return r.days[t.Weekday()]
+	}
+	return minute < r.end && r.days[(t.Weekday()+6)%7]
+}
+
+// specificity orders rules so that narrower ones take precedence.
+func (r variantRule) specificity() int {
+	n := 7 - countTrue(r.days[:])
+	if r.Window != "" {
+		n += 10
+	}
+	return n
+}
+
+func countTrue(bs []bool) (n int) {
+	for _, b := range bs {
+		if b {
+			n++
+		}
+	}
+	return
+}
+
+// getVariantRules returns a group's variant rules in order of precedence.
+func (a App) getVariantRules(ctx context.Context, group string) ([]variantRule, error) {
+	entries, err := a.store.Get(ctx, variantsRecord(group))
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []variantRule
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, "|", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if rule, err := parseVariantRule(parts[0], parts[1], parts[2]); err == nil {
+			rules = append(rules, rule)
+		}
+	}
+	slices.SortStableFunc(rules, func(x, y variantRule) int {
+		if d := y.specificity() - x.specificity(); d != 0 {
+			return d
+		}
+		return strings.Compare(x.entry(), y.entry())
+	})
+	return rules, nil
+}
+
+// resolveVariant returns the group that a selection from group should use
+// right now, along with a note explaining any substitution.
+func (a App) resolveVariant(ctx context.Context, group string, flags flagSet) (string, string, error) {
+	override, overridden := flags.Value("variant")
+	if overridden && strings.EqualFold(override, "none") {
+		return group, "", nil
+	}
+
+	rules, err := a.getVariantRules(ctx, group)
+	if err != nil {
+		// Like expiry, variants shouldn't block a selection if they can't be
+		// checked, unless the user asked for one specifically.
+		if overridden {
+			return "", "", a.storeError(err, "getting that group's variants")
+		}
+		return group, "", nil
+	}
+
+	if overridden {
+		for _, rule := range rules {
+			if rule.Variant == override {
+				return override, fmt.Sprintf("\n(I used the %q variant of the %q group.)", override, group), nil
+			}
+		}
+		return "", "", Error{
+			cause: fmt.Errorf("group %q has no variant %q", group, override),
+			helpText: fmt.Sprintf(
+				`Whoops, the %q group doesn't have a variant named %q! (Use "%s /variant %s" to see its variants.)`,
+				group, override, a.name, group,
+			),
+		}
+	}
+
+	now := a.now()
+	for _, rule := range rules {
+		if rule.matches(now) {
+			return rule.Variant, fmt.Sprintf(
+				"\n(I used the %q variant of the %q group, since it's %s. To use the usual group, add --variant=none.)",
+				rule.Variant, group, now.Format("Monday 15:04"),
+			), nil
+		}
+	}
+	return group, "", nil
+}
+
+func (a App) setVariant(request request) (Result, error) {
+	var (
+		ctx   = request.Context
+		group = request.Operand
+		args  = request.Args
+	)
+
+	rules, err := a.getVariantRules(ctx, group)
+	if err != nil {
+		return Result{}, a.storeError(err, "getting that group's variants")
+	}
+
+	switch {
+	case len(args) == 0:
+		return a.listVariants(group, rules), nil
+	case len(args) == 1 && strings.EqualFold(args[0], "off"):
+		if _, err := a.store.Delete(ctx, variantsRecord(group)); err != nil {
+			return Result{}, a.storeError(err, "updating that group's variants")
+		}
+		return Result{
+			resultType: UpdatedVariants,
+			message:    fmt.Sprintf("Done! The %q group no longer has any variants.", group),
+		}, nil
+	case len(args) < 2 || len(args) > 3:
+		return Result{}, Error{
+			cause: errors.New("wrong number of arguments to set variant"),
+			helpText: fmt.Sprintf(
+				`Whoops, I need the days (and optionally the times) when a variant applies, followed by the group to use then! (Type "%s help" to see an example.)`,
+				a.name,
+			),
+		}
+	}
+
+	var window string
+	if len(args) == 3 {
+		window = args[1]
+	}
+	rule, err := parseVariantRule(args[0], window, groupReference(args[len(args)-1]))
+	if err != nil {
+		return Result{}, Error{
+			cause:    err,
+			helpText: `Whoops, I need days like "fri", "mon-thu", "weekdays", or "weekends", optionally followed by times like "11:00-14:00"!`,
+		}
+	}
+
+	rules = slices.DeleteFunc(rules, func(r variantRule) bool {
+		return r.Days == rule.Days && r.Window == rule.Window
+	})
+	removing := strings.EqualFold(rule.Variant, "off")
+	if !removing {
+		if rule.Variant == group {
+			return Result{}, Error{
+				cause:    errors.New("group is its own variant"),
+				helpText: "Whoops, a group can't be a variant of itself!",
+			}
+		}
+		variant, err := a.getGroup(ctx, rule.Variant)
+		if err != nil {
+			return Result{}, a.storeError(err, "getting that variant")
+		}
+		if len(variant) == 0 {
+			return Result{}, Error{
+				cause: fmt.Errorf("variant group %q does not exist", rule.Variant),
+				helpText: fmt.Sprintf(
+					"Whoops, I can't find the %q group in this channel. (Save it first, then make it a variant!)",
+					rule.Variant,
+				),
+			}
+		}
+		rules = append(rules, rule)
+	}
+
+	entries := make([]string, len(rules))
+	for i, r := range rules {
+		entries[i] = r.entry()
+	}
+	if len(entries) == 0 {
+		_, err = a.store.Delete(ctx, variantsRecord(group))
+	} else {
+		err = a.store.Put(ctx, variantsRecord(group), entries)
+	}
+	if err != nil {
+		return Result{}, a.storeError(err, "updating that group's variants")
+	}
+
+	when := rule.Days
+	if rule.Window != "" {
+		when += " from " + rule.Window
+	}
+	message := fmt.Sprintf("Done! On %s, selections from the %q group will use the %q group instead.", when, group, rule.Variant)
+	if removing {
+		message = fmt.Sprintf("Done! The %q group no longer has a variant on %s.", group, when)
+	}
+	return Result{resultType: UpdatedVariants, message: message}, nil
+}
+
+func (a App) listVariants(group string, rules []variantRule) Result {
+	if len(rules) == 0 {
+		return Result{
+			resultType: ShowedVariants,
+			message: fmt.Sprintf(
+				`The %q group doesn't have any variants. (Type "%s help" to learn how to add one!)`,
+				group, a.name,
+			),
+		}
+	}
+
+	lines := make([]string, len(rules))
+	for i, rule := range rules {
+		lines[i] = rule.String()
+	}
+	return Result{
+		resultType: ShowedVariants,
+		message:    fmt.Sprintf("The %q group has the following variants, in order of precedence:\n%s", group, bulletlist(lines)),
+	}
+}
@@ -0,0 +1,75 @@
+package randomizer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/buildinfo"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "version",
+		operand:    operandNone,
+		permission: permRead,
+		handler:    App.showVersion,
+		section:    helpWorkspace,
+		help:       []string{"*See which version of the randomizer is running:* {{.Name}} /version"},
+	})
+}
+
+// Deployment describes how the randomizer is deployed, for /version to report
+// alongside the build, so that support requests from different deployments
+// are easier to tell apart.
+type Deployment struct {
+	// Store names the store backend, like "dynamodb".
+	Store string
+	// Features, if non-nil, lists the feature flags that are enabled at the
+	// time of a request.
+	Features func() []string
+}
+
+// WithDeployment describes the deployment for /version.
+func WithDeployment(d Deployment) AppOption {
+	return func(a *App) { a.deployment = d }
+}
+
+func (a App) showVersion(request request) (Result, error) {
+	build := buildinfo.Get()
+
+	details := []string{build.Version}
+	if commit := build.ShortCommit(); commit != "" {
+		if build.Modified {
+			commit += ", modified"
+		}
+		details = append(details, "commit "+commit)
+	}
+	if !build.Date.IsZero() {
+		details = append(details, "built "+build.Date.UTC().Format(time.DateOnly))
+	}
+
+	lines := []string{"Store: " + orUnknown(a.deployment.Store)}
+	if a.deployment.Features != nil {
+		features := "none"
+		if enabled := a.deployment.Features(); len(enabled) > 0 {
+			features = strings.Join(enabled, ", ")
+		}
+		lines = append(lines, "Features: "+features)
+	}
+	if build.GoVersion != "" {
+		lines = append(lines, "Go: "+build.GoVersion)
+	}
+
+	return Result{
+		resultType: ShowedVersion,
+		message:    fmt.Sprintf(":information_source: Randomizer %s\n%s", strings.Join(details, ", "), bulletlist(lines)),
+	}, nil
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
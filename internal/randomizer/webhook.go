@@ -0,0 +1,269 @@
+package randomizer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerCommand(&command{
+		name:       "webhook",
+		operand:    operandOptional,
+		permission: permWrite,
+		handler:    App.configureWebhook,
+		section:    helpHistory,
+		help: []string{
+			"*Send every selection to a webhook:* {{.Name}} /webhook https://example.com/hook",
+			`&gt; {{.Name}} /webhook https://example.com/hook {"text": {{json .Winner}}}`,
+		},
+	})
+}
+
+// A result webhook lets a channel send each of its selections to another
+// system, like an issue tracker or a spreadsheet, without any code. After every
+// selection, the App POSTs a payload to the channel's webhook URL, rendered from
+// a template that the channel provides (see payload.go), or as a JSON object
+// with every field of a [WebhookResult] if it doesn't provide one.
+//
+// The App renders payloads itself, but leaves delivering them to the
+// [WebhookSender] given to [WithWebhookSender], so that deployments decide
+// whether and where the randomizer may make requests. Deliveries never fail the
+// selection that triggered them.
+
+// webhookRecord holds a channel's result webhook, with entries of the form
+// "url|<url>" and "template|<template>".
+const webhookRecord = recordPrefix + "webhook"
+
+// maxWebhookPayloadBytes bounds the size of a rendered payload.
+const maxWebhookPayloadBytes = 64 * 1024
+
+// WebhookSender POSTs a rendered payload to a channel's webhook URL.
+type WebhookSender func(ctx context.Context, url string, payload []byte) error
+
+// WithWebhookSender enables /webhook, and delivers the payloads of channels'
+// result webhooks with send.
+func WithWebhookSender(send WebhookSender) AppOption {
+	return func(a *App) { a.sendWebhook = send }
+}
+
+// WebhookResult is the data that a result webhook's template renders.
+type WebhookResult struct {
+	ID      string    `json:"id,omitempty"`    // Empty if history is disabled
+	Type    string    `json:"type"`            // Like "Selection" or "RolledDice"
+	Group   string    `json:"group,omitempty"` // Empty if the options were given directly
+	User    string    `json:"user,omitempty"`  // Empty if the user is unknown
+	Choices []string  `json:"choices"`
+	Winner  string    `json:"winner,omitempty"` // The first choice
+	Message string    `json:"message"`          // Without Slack's formatting for mentions
+	At      time.Time `json:"at"`
+}
+
+// sampleWebhookResult is rendered when a template is saved, so that mistakes
+// like misspelled fields surface right away.
+var sampleWebhookResult = WebhookResult{
+	ID:      "k3x9qp",
+	Type:    Selection.String(),
+	Group:   "lunch",
+	User:    "U00000000",
+	Choices: []string{"pizza", "tacos"},
+	Winner:  "pizza",
+	Message: "I randomized and got: *pizza*, *tacos*.",
+	At:      time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+}
+
+type resultWebhook struct {
+	URL      string
+	Template string // Empty for the default JSON payload
+}
+
+func (w resultWebhook) entries() []string {
+	entries := []string{"url|" + w.URL}
+	if w.Template != "" {
+		entries = append(entries, "template|"+w.Template)
+	}
+	return entries
+}
+
+func (a App) getWebhook(ctx context.Context) (resultWebhook, error) {
+	entries, err := a.store.Get(ctx, webhookRecord)
+	if err != nil {
+		return resultWebhook{}, err
+	}
+	var hook resultWebhook
+	for _, entry := range entries {
+		switch key, value, _ := strings.Cut(entry, "|"); key {
+		case "url":
+			hook.URL = value
+		case "template":
+			hook.Template = value
+		}
+	}
+	return hook, nil
+}
+
+// render returns the payload for a result.
+func (w resultWebhook) render(result WebhookResult) ([]byte, error) {
+	if w.Template == "" {
+		return json.Marshal(result)
+	}
+	tmpl, err := parsePayloadTemplate(w.Template)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl.execute(result)
+}
+
+// isWebhookResult reports whether a result type is a selection that result
+// webhooks receive.
+func isWebhookResult(t ResultType) bool {
+	switch t {
+	case Selection, Assignment, RevealedPick, RatifiedPick, Teams, Sampled, RolledDice, PickedNumbers:
+		return true
+	default:
+		return false
+	}
+}
+
+// deliverWebhook sends a selection to the channel's result webhook, if it has
+// one. Like other non-critical work, the delivery happens after the App
+// responds when the App supports deferred writes.
+func (a App) deliverWebhook(ctx context.Context, result Result) {
+	if a.sendWebhook == nil || !isWebhookResult(result.resultType) {
+		return
+	}
+	hook, err := a.getWebhook(ctx)
+	if err != nil {
+		a.logger.Warn("Failed to get result webhook", "err", err)
+		return
+	}
+	if hook.URL == "" {
+		return
+	}
+
+	data := WebhookResult{
+		ID:      result.id,
+		Type:    result.resultType.String(),
+		Group:   result.group,
+		User:    a.user,
+		Choices: result.choices,
+		Message: a.plainMentions(ctx, result.message),
+		At:      a.now().UTC(),
+	}
+	if len(data.Choices) > 0 {
+		data.Winner = data.Choices[0]
+	}
+	payload, err := hook.render(data)
+	if err != nil {
+		a.logger.Warn("Failed to render result webhook", "err", err)
+		return
+	}
+
+	send := func(ctx context.Context) {
+		if err := a.sendWebhook(ctx, hook.URL, payload); err != nil {
+			a.logger.Warn("Failed to send result webhook", "err", err)
+		}
+	}
+	if a.startDeferred == nil {
+		send(ctx)
+		return
+	}
+	a.startDeferred(func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), deferredWriteTimeout)
+		defer cancel()
+		send(ctx)
+	})
+}
+
+// slackUnescaper reverses the escaping that Slack applies to "&", "<", and ">"
+// in command text, which templates need as is.
+var slackUnescaper = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&")
+
+// parseWebhookURL accepts an HTTPS URL, either bare or in the "<url>" or
+// "<url|text>" forms that Slack may turn it into.
+func parseWebhookURL(s string) (string, bool) {
+	if strings.HasPrefix(s, "<") && strings.HasSuffix(s, ">") {
+		s, _, _ = strings.Cut(s[1:len(s)-1], "|")
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme != "https" || u.Host == "" || u.User != nil || strings.Contains(s, "|") {
+		return "", false
+	}
+	return s, true
+}
+
+func (a App) configureWebhook(request request) (Result, error) {
+	ctx := request.Context
+	if a.sendWebhook == nil {
+		return Result{}, Error{
+			cause:    errors.New("no webhook sender"),
+			helpText: "Whoops, webhooks aren't available here!",
+		}
+	}
+
+	hook, err := a.getWebhook(ctx)
+	if err != nil {
+		return Result{}, a.storeError(err, "getting this channel's webhook")
+	}
+
+	if request.Operand == "" {
+		return a.showWebhook(hook), nil
+	}
+
+	if strings.EqualFold(request.Operand, "off") && len(request.Args) == 0 {
+		if _, err := a.store.Delete(ctx, webhookRecord); err != nil {
+			return Result{}, a.storeError(err, "removing this channel's webhook")
+		}
+		return Result{
+			resultType: UpdatedWebhook,
+			message:    "Done! This channel's selections won't go to a webhook anymore.",
+		}, nil
+	}
+
+	target, ok := parseWebhookURL(request.Operand)
+	if !ok {
+		return Result{}, Error{
+			cause:    fmt.Errorf("invalid webhook URL %q", request.Operand),
+			helpText: `Whoops, the webhook needs to be an HTTPS URL like "https://example.com/hook"!`,
+		}
+	}
+	hook = resultWebhook{URL: target, Template: slackUnescaper.Replace(strings.Join(request.Args, " "))}
+	if _, err := hook.render(sampleWebhookResult); err != nil {
+		return Result{}, Error{
+			cause:    fmt.Errorf("invalid webhook template: %w", err),
+			helpText: fmt.Sprintf("Whoops, I can't use that template: %v", err),
+		}
+	}
+
+	if err := a.store.Put(ctx, webhookRecord, hook.entries()); err != nil {
+		return Result{}, a.storeError(err, "saving this channel's webhook")
+	}
+	return Result{
+		resultType: UpdatedWebhook,
+		message:    fmt.Sprintf("Done! I'll send every selection in this channel to %s.", target),
+	}, nil
+}
+
+func (a App) showWebhook(hook resultWebhook) Result {
+	if hook.URL == "" {
+		return Result{
+			resultType: ShowedWebhook,
+			message: fmt.Sprintf(
+				`This channel's selections don't go to a webhook. (Type "%s help" to see how to send them to one.)`,
+				a.name,
+			),
+		}
+	}
+	payload := "the default JSON payload."
+	if hook.Template != "" {
+		payload = fmt.Sprintf("this template:\n```%s```", hook.Template)
+	}
+	return Result{
+		resultType: ShowedWebhook,
+		message:    fmt.Sprintf("This channel sends every selection to %s, with %s", hook.URL, payload),
+	}
+}
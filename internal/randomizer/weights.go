@@ -0,0 +1,96 @@
+package randomizer
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// Weights make some options likelier to be picked than others. An option's
+// weight is stored as an ordinary attribute, like "pizza #weight=3", so groups
+// saved before weights existed keep working with every option at weight 1.
+// Users may also type the shorthand "pizza*3" wherever they list options.
+
+// weightAttr is the option attribute that sets the option's weight.
+const weightAttr = "weight"
+
+// maxWeight bounds weights, which keeps the cost of a weighted shuffle small.
+const maxWeight = 100
+
+// weightShorthand matches an option with a trailing "*<weight>".
+var weightShorthand = regexp.MustCompile(`^(.+)\*([0-9]+)$`)
+
+// parseTypedOptions parses options as typed by users, including any with the
+// weight shorthand, e.g. "pizza*3" to an option with the attribute weight=3.
+func parseTypedOptions(args []string) ([]option, error) {
+	options := make([]option, len(args))
+	for i, arg := range args {
+		match := weightShorthand.FindStringSubmatch(arg)
+		if match == nil {
+			options[i] = parseTypedOption(arg)
+			continue
+		}
+		weight, err := strconv.Atoi(match[2])
+		if err != nil || weight < 1 || weight > maxWeight {
+			return nil, Error{
+				cause:    fmt.Errorf("invalid weight in %q", arg),
+				helpText: fmt.Sprintf("Whoops, weights need to be whole numbers from 1 to %d, like pizza*3!", maxWeight),
+			}
+		}
+		o := parseTypedOption(match[1])
+		o.Tags = append(o.Tags, weightAttr+"="+match[2])
+		options[i] = o
+	}
+	return options, nil
+}
+
+// Weight returns the option's weight, or 1 if it has no valid weight.
+func (o option) Weight() int {
+	value, ok := o.Attr(weightAttr)
+	if !ok {
+		return 1
+	}
+	weight, err := strconv.Atoi(value)
+	if err != nil || weight < 1 {
+		return 1
+	}
+	return min(weight, maxWeight)
+}
+
+// weightedOrder returns the names of the options in a random order, where
+// each position is filled with probability proportional to the weights of the
+// options not yet placed.
+//
+// It shuffles a pool with one entry per unit of weight, and orders options by
+// their first appearance. Drawing from the pool this way is equivalent to
+// repeatedly choosing a weighted winner from the remaining options, and lets
+// the App's shuffle function stay the only source of randomness.
+func (a App) weightedOrder(options []option) []string {
+	names := optionNames(options)
+	if !slices.ContainsFunc(options, func(o option) bool { return o.Weight() != 1 }) {
+		a.shuffle(names)
+		return names
+	}
+
+	// The pool holds the index of each option, rather than its name, in case
+	// a group has more than one option with the same name.
+	var pool []string
+	for i, o := range options {
+		for range o.Weight() {
+			pool = append(pool, strconv.Itoa(i))
+		}
+	}
+	a.shuffle(pool)
+
+	seen := make([]bool, len(options))
+	order := make([]string, 0, len(options))
+	for _, entry := range pool {
+		i, _ := strconv.Atoi(entry)
+		if !seen[i] {
+			seen[i] = true
+			order = append(order, names[i])
+		}
+	}
+	return order
+}
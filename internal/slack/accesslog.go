@@ -0,0 +1,117 @@
+package slack
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+// AccessLogMode controls how much detail an [AccessLog] records about each
+// request.
+type AccessLogMode int
+
+const (
+	// AccessLogOff disables access logging.
+	AccessLogOff AccessLogMode = iota
+	// AccessLogRedacted logs each request with hashed user and channel IDs, and
+	// without the text of the command, so that logs can show usage patterns
+	// without revealing who picked what.
+	AccessLogRedacted
+	// AccessLogFull logs each request with its raw IDs and the full text of
+	// the command.
+	AccessLogFull
+)
+
+// AccessLog records a line for each slash command that the randomizer handles.
+type AccessLog struct {
+	Mode   AccessLogMode
+	Logger *slog.Logger
+	// HashKey keys the HMAC used to hash IDs in redacted logs. Hashes are
+	// only comparable across logs written with the same key.
+	HashKey []byte
+}
+
+// AccessLogFromEnv returns an AccessLog based on available environment
+// variables, or nil if access logging is disabled.
+//
+// ACCESS_LOG may be "off" (the default), "redacted", or "full".
+//
+// ACCESS_LOG_HASH_KEY may be set to a base64-encoded key for hashing IDs in
+// redacted logs. Without it, a random key is generated at startup, so the same
+// user's requests can be correlated only within a single process.
+func AccessLogFromEnv(logger *slog.Logger) (*AccessLog, error) {
+	var mode AccessLogMode
+	switch env := os.Getenv("ACCESS_LOG"); env {
+	case "", "off":
+		return nil, nil
+	case "redacted":
+		mode = AccessLogRedacted
+	case "full":
+		mode = AccessLogFull
+	default:
+		return nil, fmt.Errorf(`ACCESS_LOG must be "off", "redacted", or "full", not %q`, env)
+	}
+
+	key := make([]byte, 32)
+	if encoded, ok := os.LookupEnv("ACCESS_LOG_HASH_KEY"); ok {
+		var err error
+		if key, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+			return nil, fmt.Errorf("ACCESS_LOG_HASH_KEY is not valid base64: %w", err)
+		}
+	} else {
+		rand.Read(key)
+	}
+
+	return &AccessLog{Mode: mode, Logger: logger, HashKey: key}, nil
+}
+
+// record logs a single slash command and its outcome.
+func (l *AccessLog) record(ctx context.Context, params url.Values, start time.Time, result randomizer.Result, err error) {
+	if l == nil || l.Mode == AccessLogOff {
+		return
+	}
+
+	text := params.Get("text")
+	attrs := []slog.Attr{
+		slog.String("team_id", l.id(params.Get("team_id"))),
+		slog.String("channel_id", l.id(params.Get("channel_id"))),
+		slog.String("user_id", l.id(params.Get("user_id"))),
+		slog.String("command", params.Get("command")),
+		slog.Int("args", len(strings.Fields(text))),
+		slog.Duration("duration", time.Since(start)),
+	}
+	if l.Mode == AccessLogFull {
+		attrs = append(attrs, slog.String("text", text))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("outcome", "error"))
+		if l.Mode == AccessLogFull {
+			attrs = append(attrs, slog.String("err", err.Error()))
+		}
+	} else {
+		attrs = append(attrs, slog.String("outcome", result.Type().String()))
+	}
+
+	l.Logger.LogAttrs(ctx, slog.LevelInfo, "Slash command", attrs...)
+}
+
+// id returns a Slack ID as it should appear in the log.
+func (l *AccessLog) id(id string) string {
+	if l.Mode == AccessLogFull || id == "" {
+		return id
+	}
+	mac := hmac.New(sha256.New, l.HashKey)
+	mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
@@ -0,0 +1,59 @@
+package slack
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+)
+
+func TestAccessLogModes(t *testing.T) {
+	for _, tc := range []struct {
+		mode    AccessLogMode
+		want    []string
+		notWant []string
+	}{
+		{
+			mode:    AccessLogRedacted,
+			want:    []string{"outcome=Selection", "args=2"},
+			notWant: []string{"U12345678", "C12345678", "secret-option"},
+		},
+		{
+			mode: AccessLogFull,
+			want: []string{"outcome=Selection", "user_id=U12345678", "secret-option"},
+		},
+	} {
+		var buf bytes.Buffer
+		app := App{
+			TokenProvider: StaticToken("right"),
+			StoreFactory:  func(_ string) randomizer.Store { return make(rndtest.Store) },
+			AccessLog: &AccessLog{
+				Mode:    tc.mode,
+				Logger:  slog.New(slog.NewTextHandler(&buf, nil)),
+				HashKey: []byte("key"),
+			},
+		}
+
+		params := makeTestParams("secret-option other-option")
+		params.Set("user_id", "U12345678")
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(params.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		app.ServeHTTP(httptest.NewRecorder(), req)
+
+		for _, want := range tc.want {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("mode %v: log missing %q\n%s", tc.mode, want, buf.String())
+			}
+		}
+		for _, notWant := range tc.notWant {
+			if strings.Contains(buf.String(), notWant) {
+				t.Errorf("mode %v: log contains %q\n%s", tc.mode, notWant, buf.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package slack
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/featherbread/randomizer/internal/audit"
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+// Audit records every change to groups and settings in an audit trail, with
+// the workspace, channel, and user that made it, and lets admins review the
+// trail with /audit.
+type Audit struct {
+	// Sink, if non-nil, receives each change in addition to the store.
+	Sink randomizer.AuditSink
+	// Admins lists the IDs of the users who may run /audit.
+	Admins []string
+}
+
+// WithAudit records changes in an audit trail. See [Audit].
+func WithAudit(audit *Audit) AppOption {
+	return func(a *App) { a.Audit = audit }
+}
+
+// AuditFromEnv returns an Audit if AUDIT_LOG is "1", or nil otherwise. Its
+// admins are the comma-separated user IDs in AUDIT_ADMINS, and its sink is
+// described by audit.SinkFromEnv.
+func AuditFromEnv(ctx context.Context) (*Audit, error) {
+	if os.Getenv("AUDIT_LOG") != "1" {
+		return nil, nil
+	}
+	sink, err := audit.SinkFromEnv(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a := &Audit{Sink: sink}
+	for user := range strings.SplitSeq(os.Getenv("AUDIT_ADMINS"), ",") {
+		if user = strings.TrimSpace(user); user != "" {
+			a.Admins = append(a.Admins, user)
+		}
+	}
+	return a, nil
+}
+
+func (a *Audit) option(team, channel string) randomizer.AppOption {
+	return randomizer.WithAudit(randomizer.Audit{
+		Team:    team,
+		Channel: channel,
+		Sink:    a.Sink,
+		Admins:  a.Admins,
+	})
+}
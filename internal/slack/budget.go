@@ -0,0 +1,89 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultBudget leaves time within Slack's 3 second limit on slash command
+// responses for the response to reach Slack.
+const DefaultBudget = 2500 * time.Millisecond
+
+// Budget bounds the time that the App spends on each slash command, so that
+// users see a result instead of Slack's timeout error. When a command nears the
+// end of its budget, the randomizer finishes writes that don't affect the
+// response, like selection history, after responding. Commands that can't
+// produce any result in time fail with an error response.
+//
+// In environments that freeze between requests, like AWS Lambda, deferred
+// writes finish when the environment next runs, and are lost if it shuts down
+// first.
+type Budget struct {
+	limit   time.Duration
+	pending sync.WaitGroup
+}
+
+// NewBudget creates a Budget that gives each command the time limit, measured
+// from when the App starts to handle it.
+func NewBudget(limit time.Duration) *Budget {
+	return &Budget{limit: limit}
+}
+
+// BudgetFromEnv creates a Budget with the limit in SLACK_RESPONSE_BUDGET, a Go
+// duration, or [DefaultBudget] if it's unset. It returns nil if the limit is
+// "0" or "off", to run every command to completion before responding.
+func BudgetFromEnv() (*Budget, error) {
+	value, ok := os.LookupEnv("SLACK_RESPONSE_BUDGET")
+	if !ok || value == "" {
+		return NewBudget(DefaultBudget), nil
+	}
+	if value == "0" || value == "off" {
+		return nil, nil
+	}
+	limit, err := time.ParseDuration(value)
+	if err != nil || limit <= 0 {
+		return nil, fmt.Errorf("invalid SLACK_RESPONSE_BUDGET %q", value)
+	}
+	return NewBudget(limit), nil
+}
+
+// Limit returns the time limit that the Budget gives each command.
+func (b *Budget) Limit() time.Duration {
+	return b.limit
+}
+
+// Wait blocks until every deferred write has finished, or ctx is done. Servers
+// should call it while shutting down, so that history isn't lost.
+func (b *Budget) Wait(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	done := make(chan struct{})
+	go func() {
+		b.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// bound returns a context that expires at the end of the budget. A nil Budget
+// leaves ctx unbounded.
+func (b *Budget) bound(ctx context.Context) (context.Context, context.CancelFunc) {
+	if b == nil {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, b.limit)
+}
+
+// start runs a deferred write in a new goroutine, and tracks it for Wait.
+func (b *Budget) start(write func()) {
+	b.pending.Go(write)
+}
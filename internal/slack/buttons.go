@@ -0,0 +1,180 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Action IDs of the buttons that run a command. Each button's value is the
+// slash command and text to run, separated by a space.
+const (
+	pickAgainAction = "pick_again"
+	approveAction   = "approve_proposal"
+	rejectAction    = "reject_proposal"
+	showMoreAction  = "show_more"
+
+	onboardSampleAction  = "onboard_sample"
+	onboardDefaultAction = "onboard_default"
+	onboardHelpAction    = "onboard_help"
+)
+
+// Slack's limits on the text of a section block, and on the value of a button.
+const (
+	maxSectionChars     = 3000
+	maxButtonValueChars = 2000
+)
+
+type button struct {
+	actionID string
+	text     string
+	value    string
+	style    string // "primary", "danger", or empty for the default
+}
+
+// withPickAgain adds a button to a response that runs the same command again.
+func (r response) withPickAgain(command, text string) response {
+	return r.withButtons(button{actionID: pickAgainAction, text: "Pick again", value: command + " " + text})
+}
+
+// withProposalButtons adds buttons to a proposal's response that approve or
+// reject it.
+func (r response) withProposalButtons(command, id string) response {
+	return r.withButtons(
+		button{actionID: approveAction, text: "Approve", value: command + " /approve " + id, style: "primary"},
+		button{actionID: rejectAction, text: "Re-roll", value: command + " /reject " + id},
+	)
+}
+
+// withOnboardingButtons adds buttons to a welcome message that take the steps
+// it suggests: creating a sample group, making it the channel's default, and
+// seeing the help.
+func (r response) withOnboardingButtons(command string) response {
+	return r.withButtons(
+		button{actionID: onboardSampleAction, text: "Create a sample group", value: command + " /save lunch pizza tacos ramen", style: "primary"},
+		button{actionID: onboardDefaultAction, text: "Make it the default", value: command + " /config group lunch"},
+		button{actionID: onboardHelpAction, text: "See everything", value: command + " help"},
+	)
+}
+
+// withShowMore adds a button to a paged response that shows the next page.
+func (r response) withShowMore(command, more string) response {
+	return r.withButtons(button{actionID: showMoreAction, text: "Show more", value: command + " " + more})
+}
+
+// withButtons adds buttons that run commands to a response, or leaves the
+// response unchanged if Slack's limits wouldn't allow it.
+func (r response) withButtons(buttons ...button) response {
+	var elements []map[string]any
+	for _, b := range buttons {
+		if len(b.value) > maxButtonValueChars {
+			return r
+		}
+		element := map[string]any{
+			"type":      "button",
+			"action_id": b.actionID,
+			"text":      map[string]any{"type": "plain_text", "text": b.text},
+			"value":     b.value,
+		}
+		if b.style != "" {
+			element["style"] = b.style
+		}
+		elements = append(elements, element)
+	}
+
+	body, textType := r.Text, "mrkdwn"
+	if len(r.Attachments) > 0 {
+		body = r.Attachments[0].Text
+	}
+	if r.Mrkdwn != nil && !*r.Mrkdwn {
+		textType = "plain_text"
+	}
+	if body == "" || len(body) > maxSectionChars {
+		return r
+	}
+
+	blocks := []map[string]any{
+		{
+			"type": "section",
+			"text": map[string]any{"type": textType, "text": body},
+		},
+		{
+			"type":     "actions",
+			"elements": elements,
+		},
+	}
+
+	// A themed color needs an attachment, so the blocks go inside it.
+	if len(r.Attachments) > 0 {
+		r.Attachments = append([]attachment(nil), r.Attachments...)
+		r.Attachments[0].Blocks = blocks
+		return r
+	}
+	r.Blocks = blocks
+	return r
+}
+
+// withNote adds a line of context before the actions of a response with
+// blocks, or leaves a response without blocks unchanged.
+func (r response) withNote(note string) response {
+	blocks := &r.Blocks
+	if len(r.Attachments) > 0 {
+		r.Attachments = append([]attachment(nil), r.Attachments...)
+		blocks = &r.Attachments[0].Blocks
+	}
+	if len(*blocks) == 0 {
+		return r
+	}
+	context := map[string]any{
+		"type":     "context",
+		"elements": []map[string]any{{"type": "mrkdwn", "text": note}},
+	}
+	last := len(*blocks) - 1
+	*blocks = append((*blocks)[:last:last], context, (*blocks)[last])
+	return r
+}
+
+// runAction runs the command behind a button as the user who clicked it, and
+// posts the result through the interaction's response URL, leaving the
+// original message in place. If note is non-empty, the result notes who
+// clicked the button.
+func (a App) runAction(ctx context.Context, payload interactionPayload, value, note string) {
+	command, text, _ := strings.Cut(value, " ")
+	params := url.Values{
+		"team_id":    {payload.Team.ID},
+		"channel_id": {payload.Channel.ID},
+		"user_id":    {payload.User.ID},
+		"command":    {command},
+		"text":       {text},
+	}
+	resp := a.respond(ctx, params)
+	if note != "" {
+		resp = resp.withNote(fmt.Sprintf("%s <@%s>", note, payload.User.ID))
+	}
+	resp.ReplaceOriginal = new(false)
+
+	body, err := json.Marshal(resp)
+	if err != nil {
+		a.logErr(err, "Failed to encode button response")
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, payload.ResponseURL, bytes.NewReader(body))
+	if err != nil {
+		a.logErr(err, "Failed to post button response")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	httpResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		a.logErr(err, "Failed to post button response")
+		return
+	}
+	httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		a.logErr(fmt.Errorf("HTTP status %s", httpResp.Status), "Failed to post button response")
+	}
+}
@@ -0,0 +1,260 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/featherbread/randomizer/internal/admin"
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+)
+
+func TestPickAgain(t *testing.T) {
+	var posted []response
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resp response
+		if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+			t.Errorf("invalid response body: %v", err)
+		}
+		posted = append(posted, resp)
+	}))
+	defer srv.Close()
+
+	store := rndtest.Store{"snacks": {"chips", "pretzels"}}
+	app := NewApp(StaticToken("right"), func(_ string) randomizer.Store { return store }, WithPickAgain(true))
+
+	send := func(text string) response {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(makeTestParams(text).Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp := httptest.NewRecorder()
+		app.ServeHTTP(resp, req)
+		var got response
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	got := send("+snacks")
+	if len(got.Blocks) != 2 || got.Blocks[1]["type"] != "actions" {
+		t.Fatalf("selection missing a Pick again button: %+v", got)
+	}
+	button := got.Blocks[1]["elements"].([]any)[0].(map[string]any)
+	if button["action_id"] != pickAgainAction || button["value"] != "/randomize +snacks" {
+		t.Errorf("got button %v, want one that runs /randomize +snacks", button)
+	}
+	if got := send("/show snacks"); len(got.Blocks) != 0 {
+		t.Errorf("non-selection got blocks: %+v", got)
+	}
+
+	if resp := postButton(app, "right", pickAgainAction, "/randomize +snacks", "U2", srv.URL); resp.Code != http.StatusOK {
+		t.Fatalf("got status %v for pick again", resp.Code)
+	}
+	if len(posted) != 1 {
+		t.Fatalf("got %d posts to the response URL, want 1", len(posted))
+	}
+	again := posted[0]
+	if again.Type != typeInChannel || again.ReplaceOriginal == nil || *again.ReplaceOriginal {
+		t.Errorf("re-roll doesn't post a new message to the channel: %+v", again)
+	}
+	if len(again.Blocks) != 3 || !strings.Contains(again.Text, "I randomized and got") {
+		t.Fatalf("re-roll missing result or blocks: %+v", again)
+	}
+	if note := fmt.Sprint(again.Blocks[1]); !strings.Contains(note, "Picked again by <@U2>") {
+		t.Errorf("re-roll doesn't note who asked for it: %s", note)
+	}
+	if history := store["/history/snacks"]; len(history) != 2 {
+		t.Errorf("got history %v, want both selections", history)
+	}
+
+	if resp := postButton(app, "wrong", pickAgainAction, "/randomize +snacks", "U2", srv.URL); resp.Code != http.StatusForbidden {
+		t.Errorf("wrong status for invalid token: got %v, want %v", resp.Code, http.StatusForbidden)
+	}
+	if len(posted) != 1 {
+		t.Error("re-rolled an unverified interaction")
+	}
+}
+
+func TestProposalButtons(t *testing.T) {
+	var posted []response
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resp response
+		json.NewDecoder(r.Body).Decode(&resp)
+		posted = append(posted, resp)
+	}))
+	defer srv.Close()
+
+	store := rndtest.Store{"lunch": {"pizza", "sushi"}}
+	app := NewApp(StaticToken("right"), func(_ string) randomizer.Store { return store })
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(makeTestParams("/propose lunch").Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp := httptest.NewRecorder()
+	app.ServeHTTP(resp, req)
+	var proposed response
+	if err := json.NewDecoder(resp.Body).Decode(&proposed); err != nil {
+		t.Fatal(err)
+	}
+	if proposed.Type != typeInChannel || len(proposed.Blocks) != 2 {
+		t.Fatalf("proposal missing buttons: %+v", proposed)
+	}
+	buttons := proposed.Blocks[1]["elements"].([]any)
+	approve := buttons[0].(map[string]any)["value"].(string)
+	if !strings.HasPrefix(approve, "/randomize /approve ") {
+		t.Fatalf("got approve button value %q", approve)
+	}
+
+	for _, user := range []string{"U2", "U3"} {
+		postButton(app, "right", approveAction, approve, user, srv.URL)
+	}
+	if len(posted) != 2 {
+		t.Fatalf("got %d posts to the response URL, want 2", len(posted))
+	}
+	if posted[0].Type != typeEphemeral || !strings.Contains(posted[0].Text, "1 of 2 approvals") {
+		t.Errorf("first approval not acknowledged privately: %+v", posted[0])
+	}
+	if posted[1].Type != typeInChannel || !strings.Contains(posted[1].Text, "it stands") {
+		t.Errorf("second approval didn't ratify the proposal: %+v", posted[1])
+	}
+}
+
+func TestShowMoreButton(t *testing.T) {
+	var posted []response
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resp response
+		json.NewDecoder(r.Body).Decode(&resp)
+		posted = append(posted, resp)
+	}))
+	defer srv.Close()
+
+	store := make(rndtest.Store)
+	for i := range 60 {
+		store[fmt.Sprintf("group%02d", i)] = []string{"one"}
+	}
+	app := NewApp(StaticToken("right"), func(_ string) randomizer.Store { return store })
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(makeTestParams("/list").Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp := httptest.NewRecorder()
+	app.ServeHTTP(resp, req)
+	var listed response
+	if err := json.NewDecoder(resp.Body).Decode(&listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed.Blocks) != 2 {
+		t.Fatalf("list missing a Show more button: %+v", listed)
+	}
+	button := listed.Blocks[1]["elements"].([]any)[0].(map[string]any)
+	if button["action_id"] != showMoreAction || button["value"] != "/randomize /list --after group49" {
+		t.Fatalf("got button %v, want one that lists the next page", button)
+	}
+
+	postButton(app, "right", showMoreAction, button["value"].(string), "U2", srv.URL)
+	if len(posted) != 1 || !strings.Contains(posted[0].Text, "• group50") || len(posted[0].Blocks) != 0 {
+		t.Errorf("got posts %+v, want the last page without a button", posted)
+	}
+}
+
+func TestOnboardingButtons(t *testing.T) {
+	var posted []response
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resp response
+		if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+			t.Errorf("invalid response body: %v", err)
+		}
+		posted = append(posted, resp)
+	}))
+	defer srv.Close()
+
+	store := onboardingReadStore{Store: rndtest.Store{}, reads: new(int)}
+	app := NewApp(StaticToken("right"), func(_ string) randomizer.Store { return store }, WithOnboarding(NewOnboarding()))
+	send := func(text string) response {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(makeTestParams(text).Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp := httptest.NewRecorder()
+		app.ServeHTTP(resp, req)
+		var got response
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	result, err := app.RunAs(t.Context(), "static token", admin.RunAsRequest{
+		Channel: "C12345678", User: "U1", Command: "/randomize", Text: "one two",
+	})
+	if err != nil || result.Welcomed() || *store.reads != 0 {
+		t.Fatalf("RunAs onboarded the channel (err %v, %d reads): %q", err, *store.reads, result.Message())
+	}
+
+	got := send("one two")
+	if !strings.Contains(got.Text, "Welcome!") || len(got.Blocks) != 2 || got.Blocks[1]["type"] != "actions" {
+		t.Fatalf("first command missing a welcome with buttons: %+v", got)
+	}
+	var actions []string
+	for _, element := range got.Blocks[1]["elements"].([]any) {
+		button := element.(map[string]any)
+		actions = append(actions, fmt.Sprint(button["action_id"], " ", button["value"]))
+	}
+	want := []string{
+		onboardSampleAction + " /randomize /save lunch pizza tacos ramen",
+		onboardDefaultAction + " /randomize /config group lunch",
+		onboardHelpAction + " /randomize help",
+	}
+	if fmt.Sprint(actions) != fmt.Sprint(want) {
+		t.Errorf("got buttons %q, want %q", actions, want)
+	}
+
+	reads := *store.reads
+	if got := send("one two"); strings.Contains(got.Text, "Welcome!") || len(got.Blocks) != 0 {
+		t.Errorf("second command repeated the welcome: %+v", got)
+	}
+	if *store.reads != reads {
+		t.Errorf("second command read the onboarding state again")
+	}
+
+	if resp := postButton(app, "right", onboardSampleAction, want[0][len(onboardSampleAction)+1:], "U2", srv.URL); resp.Code != http.StatusOK {
+		t.Fatalf("got status %v for the sample group button", resp.Code)
+	}
+	if len(posted) != 1 || !strings.Contains(posted[0].Text, "saved") || len(store.Store["lunch"]) != 3 {
+		t.Errorf("sample group button didn't save the group: %+v", posted)
+	}
+}
+
+// onboardingReadStore counts the reads of the onboarding state.
+type onboardingReadStore struct {
+	rndtest.Store
+	reads *int
+}
+
+func (s onboardingReadStore) Get(ctx context.Context, name string) ([]string, error) {
+	if name == "/onboarding" {
+		*s.reads++
+	}
+	return s.Store.Get(ctx, name)
+}
+
+func postButton(app App, token, actionID, value, user, responseURL string) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(map[string]any{
+		"type":         "block_actions",
+		"token":        token,
+		"team":         map[string]any{"id": "T1"},
+		"user":         map[string]any{"id": user},
+		"channel":      map[string]any{"id": "C12345678"},
+		"response_url": responseURL,
+		"actions":      []map[string]any{{"action_id": actionID, "value": value}},
+	})
+	form := url.Values{"payload": {string(payload)}}
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	app.ServeHTTP(resp, req)
+	return resp
+}
@@ -0,0 +1,39 @@
+package slack
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+// DefaultsFromEnv returns the operations configured by DEFAULT_OPERATION, for
+// a bare slash command, and UNKNOWN_FLAG_OPERATION, for an unknown "/flag".
+// Each is "help", "randomize", "error", or "group:<name>", and an unset
+// variable keeps the built-in behavior.
+//
+// It also returns the org-wide selection settings, with each key of
+// [randomizer.SettingKeys] read from a variable like DEFAULT_SETTING_PICK.
+// Workspaces, channels, and users may override them with /config.
+func DefaultsFromEnv() (randomizer.Defaults, error) {
+	var (
+		d   randomizer.Defaults
+		err error
+	)
+	if d.Bare, err = randomizer.ParseDefaultOperation(os.Getenv("DEFAULT_OPERATION")); err != nil {
+		return randomizer.Defaults{}, fmt.Errorf("DEFAULT_OPERATION: %w", err)
+	}
+	if d.UnknownFlag, err = randomizer.ParseDefaultOperation(os.Getenv("UNKNOWN_FLAG_OPERATION")); err != nil {
+		return randomizer.Defaults{}, fmt.Errorf("UNKNOWN_FLAG_OPERATION: %w", err)
+	}
+	for _, key := range randomizer.SettingKeys() {
+		name := "DEFAULT_SETTING_" + strings.ToUpper(key)
+		if value := os.Getenv(name); value != "" {
+			if err := d.Settings.Set(key, value); err != nil {
+				return randomizer.Defaults{}, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,165 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/featherbread/randomizer/internal/mrkdwn"
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/workspace"
+)
+
+// Digests let each user opt in to direct messages about the selections they
+// appear in, with toggles on the App Home tab. The App saves each user's
+// choices with the rest of the workspace's settings, so they're exported and
+// deleted along with them.
+
+// Digest kinds, which are the values of the App Home toggles.
+const (
+	// digestPicked notifies a user when a selection picks them.
+	digestPicked = "picked"
+	// digestIncluded notifies a user of any selection that includes them as an
+	// option, whether or not they were picked.
+	digestIncluded = "included"
+)
+
+// digestAction is the action ID of the App Home toggles.
+const digestAction = "digest_toggle"
+
+type digestKind struct {
+	kind, label, description string
+}
+
+// digestKinds lists the toggles on the App Home tab, in display order.
+var digestKinds = []digestKind{
+	{digestPicked, "When I'm picked", "Get a message whenever a selection picks you."},
+	{digestIncluded, "When I'm an option", "Get a message about every selection you're an option in, even if you aren't picked."},
+}
+
+// digestRecord holds the digest kinds that a user subscribed to, in their
+// workspace's partition.
+func digestRecord(user string) string {
+	return "/digest/" + user
+}
+
+// digests returns the digest kinds that a user subscribed to.
+func (a App) digests(ctx context.Context, team, user string) ([]string, error) {
+	kinds, err := a.StoreFactory(workspace.Partition(team)).Get(ctx, digestRecord(user))
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(kinds, func(kind string) bool { return !isDigestKind(kind) }), nil
+}
+
+// saveDigests replaces the digest kinds that a user subscribed to.
+func (a App) saveDigests(ctx context.Context, team, user string, kinds []string) error {
+	kinds = slices.DeleteFunc(slices.Clone(kinds), func(kind string) bool { return !isDigestKind(kind) })
+	store := a.StoreFactory(workspace.Partition(team))
+	if len(kinds) == 0 {
+		_, err := store.Delete(ctx, digestRecord(user))
+		return err
+	}
+	return store.Put(ctx, digestRecord(user), kinds)
+}
+
+func isDigestKind(kind string) bool {
+	return slices.ContainsFunc(digestKinds, func(d digestKind) bool {
+		return d.kind == kind
+	})
+}
+
+// startDigests sends digests for a selection, unless it was private or a dry
+// run. Digests don't affect the response, so with a Budget they're sent after
+// it.
+func (a App) startDigests(ctx context.Context, params url.Values, result randomizer.Result) {
+	if a.Home == nil || !a.Home.Digests || result.Type() != randomizer.Selection || result.DryRun() || result.Private() {
+		return
+	}
+	if a.Budget == nil {
+		a.sendDigests(ctx, params, result)
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	a.Budget.start(func() { a.sendDigests(ctx, params, result) })
+}
+
+// sendDigests messages the users in a selection who subscribed to hear about
+// it, apart from the user who made it, who sees it already.
+func (a App) sendDigests(ctx context.Context, params url.Values, result randomizer.Result) {
+	team, requester := params.Get("team_id"), params.Get("user_id")
+	choices := result.Choices()
+	winners := choices
+	if !result.Picked() && len(choices) > 0 {
+		winners = choices[:1]
+	}
+
+	var (
+		notified = make(map[string]bool)
+		text     = digestText(params, choices)
+	)
+	if a.PlainText.Contains(team) {
+		text = text.plainText()
+	}
+	for _, choice := range choices {
+		for _, user := range randomizer.MentionedUsers(choice) {
+			if user == requester || notified[user] {
+				continue
+			}
+			notified[user] = true
+
+			kinds, err := a.digests(ctx, team, user)
+			if err != nil {
+				a.logErr(err, "Failed to load digest subscriptions")
+				continue
+			}
+			picked := slices.Contains(winners, choice)
+			if !slices.Contains(kinds, digestIncluded) && !(picked && slices.Contains(kinds, digestPicked)) {
+				continue
+			}
+
+			message := text.included
+			if picked {
+				message = text.picked
+			}
+			// Posting to a user's ID sends the message to their DM with the app.
+			err = a.Home.Client.Call(ctx, "chat.postMessage", map[string]any{
+				"channel": user,
+				"text":    message,
+			}, nil)
+			if err != nil {
+				a.logErr(err, "Failed to send digest")
+			}
+		}
+	}
+}
+
+type digestMessages struct {
+	picked, included string
+}
+
+// digestText returns the direct messages about a selection, for a user who
+// was picked and one who wasn't. They list the choices rather than repeat the
+// selection's message, which may be phrased for the channel or carry notes
+// meant for the user who made it.
+func digestText(params url.Values, choices []string) digestMessages {
+	names := make([]string, len(choices))
+	for i, choice := range choices {
+		names[i] = "*" + choice + "*"
+	}
+	var (
+		intro  = fmt.Sprintf("<@%s> made a selection in <#%s>", params.Get("user_id"), params.Get("channel_id"))
+		result = "The result: " + strings.Join(names, ", ")
+	)
+	return digestMessages{
+		picked:   intro + ", and you were picked! " + result,
+		included: intro + " that included you. " + result,
+	}
+}
+
+// plainText strips Slack formatting from both messages.
+func (m digestMessages) plainText() digestMessages {
+	return digestMessages{picked: mrkdwn.PlainText(m.picked), included: mrkdwn.PlainText(m.included)}
+}
@@ -0,0 +1,123 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+)
+
+func TestDigests(t *testing.T) {
+	var (
+		published []string
+		messages  = make(map[string][]string)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			UserID  string          `json:"user_id"`
+			View    json.RawMessage `json:"view"`
+			Channel string          `json:"channel"`
+			Text    string          `json:"text"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		switch r.URL.Path {
+		case "/views.publish":
+			published = append(published, string(payload.View))
+		case "/chat.postMessage":
+			messages[payload.Channel] = append(messages[payload.Channel], payload.Text)
+		default:
+			t.Errorf("unexpected call to %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer srv.Close()
+
+	partitions := make(map[string]rndtest.Store)
+	app := App{
+		TokenProvider: StaticToken("right"),
+		StoreFactory: func(partition string) randomizer.Store {
+			if partitions[partition] == nil {
+				partitions[partition] = make(rndtest.Store)
+			}
+			return partitions[partition]
+		},
+		Home: &Home{Client: WebClient{Token: "xoxb-test", BaseURL: srv.URL + "/"}, Digests: true},
+	}
+
+	postEvent(app, `{"token":"right","team_id":"T1","type":"event_callback","event":{"type":"app_home_opened","user":"U1","channel":"D1","tab":"home"}}`)
+	if len(published) != 1 || !strings.Contains(published[0], digestAction) || strings.Contains(published[0], "initial_options") {
+		t.Fatalf("got published views %v, want unselected digest toggles", published)
+	}
+
+	postDigestToggle(app, "U1", digestPicked)
+	postDigestToggle(app, "U2", digestIncluded, "bogus")
+	if got := partitions["workspace-T1"]["/digest/U2"]; !slices.Equal(got, []string{digestIncluded}) {
+		t.Fatalf("got saved digests %v for U2, want [%s]", got, digestIncluded)
+	}
+
+	postEvent(app, `{"token":"right","team_id":"T1","type":"event_callback","event":{"type":"app_home_opened","user":"U1","channel":"D1","tab":"home"}}`)
+	if len(published) != 2 || !strings.Contains(published[1], `"initial_options":[{"description"`) {
+		t.Fatalf("got published views %v, want the picked toggle selected", published)
+	}
+
+	send := func(user, text string) {
+		params := makeTestParams(text)
+		params.Set("team_id", "T1")
+		params.Set("user_id", user)
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(params.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		app.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	send("U9", "<@U1> <@U2> <@U3> --pick 3")
+	if got := messages["U1"]; len(got) != 1 || !strings.HasPrefix(got[0], "<@U9> made a selection in <#C12345678>, and you were picked! The result: *<@U") {
+		t.Errorf("got messages %q for U1, want a picked digest", got)
+	}
+	if got := messages["U2"]; len(got) != 1 {
+		t.Errorf("got messages %q for U2, want a single digest", got)
+	}
+	if got := messages["U3"]; len(got) != 0 {
+		t.Errorf("sent digests %q to U3, who didn't subscribe", got)
+	}
+
+	clear(messages)
+	send("U1", "<@U1> <@U2>")
+	if len(messages["U1"]) != 0 || len(messages["U2"]) != 1 {
+		t.Errorf("got messages %v, want a single digest for U2 only", messages)
+	}
+
+	clear(messages)
+	send("U9", "--dry-run <@U1> <@U2> --pick 2")
+	send("U9", "/config visibility private")
+	send("U9", "<@U1> <@U2> --pick 2")
+	if len(messages) != 0 {
+		t.Errorf("sent digests for dry runs or private selections: %v", messages)
+	}
+}
+
+func postDigestToggle(app App, user string, kinds ...string) *httptest.ResponseRecorder {
+	var selected []map[string]any
+	for _, kind := range kinds {
+		selected = append(selected, map[string]any{"value": kind})
+	}
+	payload, _ := json.Marshal(map[string]any{
+		"type":    "block_actions",
+		"token":   "right",
+		"team":    map[string]any{"id": "T1"},
+		"user":    map[string]any{"id": user},
+		"actions": []map[string]any{{"action_id": digestAction, "selected_options": selected}},
+	})
+	form := url.Values{"payload": {string(payload)}}
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	app.ServeHTTP(resp, req)
+	return resp
+}
@@ -0,0 +1,198 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/alert"
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+// Slack delivers Events API requests to the same URL as slash commands, as JSON
+// rather than form data. The randomizer subscribes to reaction_added and
+// reaction_removed so that users can give feedback on selection results with
+// :+1: and :-1: reactions, to app_uninstalled to schedule the deletion of a
+// workspace's data, to app_uninstalled and tokens_revoked to forget the
+// workspace's installation, and to app_home_opened to show the test console
+// and digest toggles.
+
+type eventRequest struct {
+	Token     string `json:"token"`
+	TeamID    string `json:"team_id"`
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Event     struct {
+		Type     string `json:"type"`
+		User     string `json:"user"`
+		Reaction string `json:"reaction"`
+		ItemUser string `json:"item_user"`
+		Channel  string `json:"channel"`
+		Tab      string `json:"tab"`
+		Item     struct {
+			Type    string `json:"type"`
+			Channel string `json:"channel"`
+			TS      string `json:"ts"`
+		} `json:"item"`
+	} `json:"event"`
+}
+
+func isEventRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+}
+
+func (a App) serveEvent(w http.ResponseWriter, r *http.Request, body []byte) {
+	var req eventRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		a.logErr(err, "Failed to read event")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	method, err := a.verify(r.Context(), r.Header, body, req.Token, req.TeamID)
+	if err != nil {
+		a.logErr(err, "Failed to verify request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if method == "" {
+		a.Alerts.Record(r.Context(), alert.Verification)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	r = r.WithContext(withTeam(r.Context(), req.TeamID))
+
+	switch req.Type {
+	case "url_verification":
+		w.Header().Add("Content-Type", "text/plain")
+		w.Write([]byte(req.Challenge))
+		return
+	case "event_callback":
+	default:
+		return
+	}
+
+	switch req.Event.Type {
+	case "app_uninstalled":
+		if a.forgetInstallation(w, r, req.TeamID) {
+			a.scheduleDeletion(w, r, req.TeamID)
+		}
+		return
+	case "tokens_revoked":
+		a.forgetInstallation(w, r, req.TeamID)
+		return
+	case "app_home_opened":
+		if req.Event.Tab == "home" {
+			a.publishHome(r.Context(), req.TeamID, req.Event.User, req.Event.Channel)
+		}
+		return
+	}
+
+	feedback, ok := reactionFeedback(req)
+	if !ok || (a.BotUserID != "" && req.Event.ItemUser != a.BotUserID) {
+		return
+	}
+
+	app := randomizer.NewApp("", a.StoreFactory(req.Event.Item.Channel))
+	if err := app.RecordFeedback(r.Context(), feedback); err != nil {
+		// Slack retries failed events, which is what we want for transient store
+		// errors.
+		a.logErr(err, "Failed to record feedback")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// forgetInstallation removes a workspace's installation once the App can no
+// longer act for it. It reports whether it succeeded.
+func (a App) forgetInstallation(w http.ResponseWriter, r *http.Request, team string) bool {
+	if a.Installations == nil || team == "" {
+		return true
+	}
+	if err := a.Installations.DeleteInstallation(r.Context(), team); err != nil {
+		a.logErr(err, "Failed to remove workspace installation")
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+	if a.Logger != nil {
+		a.Logger.Info("Removed workspace installation", "team", team)
+	}
+	return true
+}
+
+// scheduleDeletion schedules the deletion of a workspace's data after it
+// uninstalls the app, and notifies operators.
+func (a App) scheduleDeletion(w http.ResponseWriter, r *http.Request, team string) {
+	if a.Workspaces == nil || team == "" {
+		return
+	}
+
+	due, err := a.Workspaces.ScheduleDeletion(r.Context(), team)
+	if err != nil {
+		a.logErr(err, "Failed to schedule workspace deletion")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if a.Logger != nil {
+		a.Logger.Info("Scheduled workspace deletion", "team", team, "due", due)
+	}
+	a.Alerts.Notify(r.Context(), fmt.Sprintf(
+		"workspace %s uninstalled the app; its data is due for export and deletion on %s",
+		team, due.Format(time.DateOnly),
+	))
+}
+
+// reactionFeedback interprets a reaction event as feedback on a result.
+func reactionFeedback(req eventRequest) (randomizer.Feedback, bool) {
+	event := req.Event
+	if event.Type != "reaction_added" && event.Type != "reaction_removed" {
+		return randomizer.Feedback{}, false
+	}
+	if event.Item.Type != "message" {
+		return randomizer.Feedback{}, false
+	}
+
+	// Strip skin tone modifiers, like "+1::skin-tone-3".
+	reaction, _, _ := strings.Cut(event.Reaction, "::")
+	var positive bool
+	switch reaction {
+	case "+1", "thumbsup":
+		positive = true
+	case "-1", "thumbsdown":
+		positive = false
+	default:
+		return randomizer.Feedback{}, false
+	}
+
+	at, err := parseTS(event.Item.TS)
+	if err != nil {
+		return randomizer.Feedback{}, false
+	}
+
+	return randomizer.Feedback{
+		At:        at,
+		User:      event.User,
+		Positive:  positive,
+		Withdrawn: event.Type == "reaction_removed",
+	}, true
+}
+
+// parseTS converts a Slack message timestamp, like "1712345678.123456", to a
+// time.
+func parseTS(ts string) (time.Time, error) {
+	secs, micros, _ := strings.Cut(ts, ".")
+	s, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var us int64
+	if micros != "" {
+		if us, err = strconv.ParseInt(micros, 10, 64); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Unix(s, us*int64(time.Microsecond)), nil
+}
@@ -0,0 +1,91 @@
+package slack
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+	"github.com/featherbread/randomizer/internal/workspace"
+)
+
+func TestURLVerification(t *testing.T) {
+	app := App{
+		TokenProvider: StaticToken("right"),
+		StoreFactory:  func(_ string) randomizer.Store { return rndtest.Store(nil) },
+	}
+
+	resp := postEvent(app, `{"token":"right","type":"url_verification","challenge":"abc123"}`)
+	if got := resp.Body.String(); got != "abc123" {
+		t.Errorf("got challenge response %q, want %q", got, "abc123")
+	}
+
+	resp = postEvent(app, `{"token":"wrong","type":"url_verification","challenge":"abc123"}`)
+	if resp.Code != http.StatusForbidden {
+		t.Errorf("wrong status for invalid token: got %v, want %v", resp.Code, http.StatusForbidden)
+	}
+}
+
+func TestReactionFeedback(t *testing.T) {
+	store := rndtest.Store{
+		"test":          {"one", "two"},
+		"/history/test": {"2026-10-16T15:00:00.000000000Z|one"},
+	}
+	app := App{
+		TokenProvider: StaticToken("right"),
+		StoreFactory:  func(_ string) randomizer.Store { return store },
+		BotUserID:     "UBOT",
+	}
+
+	postEvent(app, eventJSON("reaction_added", "U1", "+1::skin-tone-2", "UBOT"))
+	postEvent(app, eventJSON("reaction_added", "U2", "-1", "UBOT"))
+	postEvent(app, eventJSON("reaction_added", "U3", "+1", "USOMEONE"))
+	postEvent(app, eventJSON("reaction_added", "U4", "tada", "UBOT"))
+
+	got := strings.Join(store["/feedback/test"], " ")
+	for _, want := range []string{"|U1|+", "|U2|-"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("feedback %q missing %q", got, want)
+		}
+	}
+	if len(store["/feedback/test"]) != 2 {
+		t.Errorf("got feedback %q, want only U1 and U2", got)
+	}
+}
+
+func TestAppUninstalled(t *testing.T) {
+	partitions := make(map[string]rndtest.Store)
+	factory := func(partition string) randomizer.Store {
+		if partitions[partition] == nil {
+			partitions[partition] = make(rndtest.Store)
+		}
+		return partitions[partition]
+	}
+	registry := &workspace.Registry{StoreFactory: factory}
+	app := App{
+		TokenProvider: StaticToken("right"),
+		StoreFactory:  factory,
+		Workspaces:    registry,
+	}
+
+	postEvent(app, `{"token":"right","team_id":"T1","type":"event_callback","event":{"type":"app_uninstalled"}}`)
+	if pending, _ := registry.Pending(t.Context()); len(pending) != 1 || pending[0].Team != "T1" {
+		t.Errorf("got pending deletions %v, want T1", pending)
+	}
+}
+
+func eventJSON(eventType, user, reaction, itemUser string) string {
+	return `{"token":"right","type":"event_callback","event":{"type":"` + eventType +
+		`","user":"` + user + `","reaction":"` + reaction + `","item_user":"` + itemUser +
+		`","item":{"type":"message","channel":"C12345678","ts":"1792162801.000200"}}}`
+}
+
+func postEvent(app App, body string) *httptest.ResponseRecorder {
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	app.ServeHTTP(resp, req)
+	return resp
+}
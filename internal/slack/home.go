@@ -0,0 +1,367 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/featherbread/randomizer/internal/alert"
+	"github.com/featherbread/randomizer/internal/mrkdwn"
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+// DefaultHomeCommand is the slash command that the App Home console shows in
+// its examples and previews.
+const DefaultHomeCommand = "/randomize"
+
+// Home publishes a test console to each user's App Home tab, where they can try
+// a command against a channel's groups and see what the randomizer would do
+// without changing anything.
+//
+// Home needs the App Home tab enabled, the app_home_opened event subscribed,
+// and interactivity enabled with the same Request URL as the slash command.
+type Home struct {
+	// Client publishes views to the App Home tab.
+	Client WebClient
+	// Command overrides DefaultHomeCommand, to match the name of the slash
+	// command as configured in Slack.
+	Command string
+	// Digests adds toggles above the console that subscribe a user to direct
+	// messages about the selections they appear in. Client must have a token
+	// with the chat:write scope to send them.
+	Digests bool
+}
+
+func (h *Home) command() string {
+	if h.Command == "" {
+		return DefaultHomeCommand
+	}
+	return h.Command
+}
+
+// Block and action IDs for the console's inputs, which come back to the App in
+// the state of the view.
+const (
+	consoleCommandBlock  = "console_command"
+	consoleCommandAction = "console_command_input"
+	consoleChannelBlock  = "console_channel"
+	consoleChannelAction = "console_channel_select"
+	consolePreviewAction = "console_preview"
+)
+
+// maxPreviewChars stays under Slack's limit on the text of a section block.
+const maxPreviewChars = 2900
+
+// interactionPayload is the subset of a Slack interaction payload that the
+// console and command buttons need. Slack sends it as JSON in the "payload"
+// field of a form.
+type interactionPayload struct {
+	Type  string `json:"type"`
+	Token string `json:"token"`
+	Team  struct {
+		ID string `json:"id"`
+	} `json:"team"`
+	User struct {
+		ID string `json:"id"`
+	} `json:"user"`
+	Channel struct {
+		ID string `json:"id"`
+	} `json:"channel"`
+	ResponseURL string `json:"response_url"`
+	View        struct {
+		Hash  string `json:"hash"`
+		State struct {
+			Values map[string]map[string]struct {
+				Value                string `json:"value"`
+				SelectedConversation string `json:"selected_conversation"`
+			} `json:"values"`
+		} `json:"state"`
+	} `json:"view"`
+	Actions []struct {
+		ActionID        string `json:"action_id"`
+		Value           string `json:"value"`
+		SelectedOptions []struct {
+			Value string `json:"value"`
+		} `json:"selected_options"`
+	} `json:"actions"`
+}
+
+func isInteractionRequest(params url.Values) bool {
+	return params.Has("payload")
+}
+
+// serveInteraction handles block actions from the console and command
+// buttons. Slack expects a quick, empty response, and shows the results only
+// once we publish the updated view or post to the response URL.
+func (a App) serveInteraction(w http.ResponseWriter, r *http.Request, body []byte) {
+	var payload interactionPayload
+	if err := json.Unmarshal([]byte(r.PostForm.Get("payload")), &payload); err != nil {
+		a.logErr(err, "Failed to read interaction payload")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	method, err := a.verify(r.Context(), r.Header, body, payload.Token, payload.Team.ID)
+	if err != nil {
+		a.logErr(err, "Failed to verify request")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if method == "" {
+		a.Alerts.Record(r.Context(), alert.Verification)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	r = r.WithContext(withTeam(r.Context(), payload.Team.ID))
+
+	if payload.Type != "block_actions" {
+		return
+	}
+	for _, action := range payload.Actions {
+		switch {
+		case action.ActionID == pickAgainAction && a.PickAgain:
+			a.runAction(r.Context(), payload, action.Value, "Picked again by")
+			return
+		case action.ActionID == approveAction:
+			a.runAction(r.Context(), payload, action.Value, "")
+			return
+		case action.ActionID == rejectAction:
+			a.runAction(r.Context(), payload, action.Value, "Re-rolled by")
+			return
+		case action.ActionID == showMoreAction:
+			a.runAction(r.Context(), payload, action.Value, "")
+			return
+		case action.ActionID == onboardSampleAction, action.ActionID == onboardDefaultAction, action.ActionID == onboardHelpAction:
+			a.runAction(r.Context(), payload, action.Value, "")
+			return
+		case action.ActionID == digestAction && a.Home != nil && a.Home.Digests:
+			var kinds []string
+			for _, option := range action.SelectedOptions {
+				kinds = append(kinds, option.Value)
+			}
+			if err := a.saveDigests(r.Context(), payload.Team.ID, payload.User.ID, kinds); err != nil {
+				a.logErr(err, "Failed to save digest subscriptions")
+			}
+			return
+		}
+	}
+	if a.Home == nil || !payload.triggersPreview() {
+		return
+	}
+
+	values := payload.View.State.Values
+	var (
+		text    = values[consoleCommandBlock][consoleCommandAction].Value
+		channel = values[consoleChannelBlock][consoleChannelAction].SelectedConversation
+	)
+	preview := a.preview(r.Context(), payload.Team.ID, payload.User.ID, channel, text)
+	view := a.Home.view(channel, text, preview, a.homeDigests(r.Context(), payload.Team.ID, payload.User.ID))
+	err = a.Home.Client.Call(r.Context(), "views.publish", map[string]any{
+		"user_id": payload.User.ID,
+		"hash":    payload.View.Hash,
+		"view":    view,
+	}, nil)
+	if err != nil {
+		// A hash conflict means that the user changed the view again before we
+		// finished, and that newer action will publish its own preview.
+		if !strings.Contains(err.Error(), "hash_conflict") {
+			a.logErr(err, "Failed to publish console preview")
+		}
+	}
+}
+
+func (p interactionPayload) triggersPreview() bool {
+	for _, action := range p.Actions {
+		if action.ActionID == consoleCommandAction || action.ActionID == consolePreviewAction {
+			return true
+		}
+	}
+	return false
+}
+
+// publishHome shows the console to a user who opened the App Home tab, with
+// the user's DM with the app selected as the channel to preview in.
+func (a App) publishHome(ctx context.Context, team, user, channel string) {
+	if a.Home == nil || user == "" {
+		return
+	}
+	err := a.Home.Client.Call(ctx, "views.publish", map[string]any{
+		"user_id": user,
+		"view":    a.Home.view(channel, "", "", a.homeDigests(ctx, team, user)),
+	}, nil)
+	if err != nil {
+		a.logErr(err, "Failed to publish App Home")
+	}
+}
+
+// homeDigests returns the digest kinds to show as selected on a user's App
+// Home tab, or nil if digests are disabled or can't be loaded.
+func (a App) homeDigests(ctx context.Context, team, user string) []string {
+	if !a.Home.Digests {
+		return nil
+	}
+	kinds, err := a.digests(ctx, team, user)
+	if err != nil {
+		a.logErr(err, "Failed to load digest subscriptions")
+	}
+	return kinds
+}
+
+// preview runs a command as a dry run in a channel, and returns the message
+// that the randomizer would respond with.
+func (a App) preview(ctx context.Context, team, user, channel, text string) string {
+	if channel == "" {
+		return "Choose a channel to try that in, so I know which groups to use."
+	}
+
+	opts := append(a.randomizerOptions(team, user), randomizer.WithDryRun())
+	app := randomizer.NewApp(a.Home.command(), a.StoreFactory(channel), opts...)
+	result, err := app.Main(ctx, strings.Fields(text))
+
+	var message string
+	if err != nil {
+		a.trackError(ctx, err)
+		message = errorResponse(err).Text
+	} else {
+		message = result.Message()
+	}
+	if a.PlainText.Contains(team) {
+		message = mrkdwn.PlainText(message)
+	}
+	return truncate(message, maxPreviewChars)
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
+
+// view returns the Block Kit view of the console, with the previous command
+// and its preview filled in if there was one, and the user's digest
+// subscriptions selected.
+func (h *Home) view(channel, text, preview string, digests []string) map[string]any {
+	commandInput := map[string]any{
+		"type":      "plain_text_input",
+		"action_id": consoleCommandAction,
+		"placeholder": map[string]any{
+			"type": "plain_text",
+			"text": "/save snacks chips pretzels popcorn",
+		},
+	}
+	if text != "" {
+		commandInput["initial_value"] = text
+	}
+
+	channelSelect := map[string]any{
+		"type":      "conversations_select",
+		"action_id": consoleChannelAction,
+		"filter":    map[string]any{"exclude_bot_users": true},
+	}
+	if channel != "" {
+		channelSelect["initial_conversation"] = channel
+	}
+
+	var blocks []map[string]any
+	if h.Digests {
+		blocks = append(blocks, digestBlocks(digests)...)
+	}
+	blocks = append(blocks, []map[string]any{
+		{
+			"type": "header",
+			"text": map[string]any{"type": "plain_text", "text": "Test console"},
+		},
+		{
+			"type": "section",
+			"text": map[string]any{
+				"type": "mrkdwn",
+				"text": "Type anything you'd put after *" + h.command() + "* to see what I would do. " +
+					"This is only a preview, so I won't save any changes or post anything in the channel.",
+			},
+		},
+		{
+			"type":            "input",
+			"block_id":        consoleCommandBlock,
+			"dispatch_action": true,
+			"label":           map[string]any{"type": "plain_text", "text": "Command"},
+			"element":         commandInput,
+		},
+		{
+			"type":     "input",
+			"block_id": consoleChannelBlock,
+			"label":    map[string]any{"type": "plain_text", "text": "Use the groups in"},
+			"element":  channelSelect,
+		},
+		{
+			"type": "actions",
+			"elements": []map[string]any{{
+				"type":      "button",
+				"action_id": consolePreviewAction,
+				"style":     "primary",
+				"text":      map[string]any{"type": "plain_text", "text": "Preview"},
+			}},
+		},
+	}...)
+	if preview != "" {
+		blocks = append(blocks,
+			map[string]any{"type": "divider"},
+			map[string]any{
+				"type": "section",
+				"text": map[string]any{"type": "mrkdwn", "text": preview},
+			},
+		)
+	}
+	return map[string]any{"type": "home", "blocks": blocks}
+}
+
+// digestBlocks returns the Block Kit blocks of the digest toggles, with the
+// kinds that the user subscribed to selected.
+func digestBlocks(selected []string) []map[string]any {
+	var options, initial []map[string]any
+	for _, d := range digestKinds {
+		option := map[string]any{
+			"text":        map[string]any{"type": "plain_text", "text": d.label},
+			"description": map[string]any{"type": "plain_text", "text": d.description},
+			"value":       d.kind,
+		}
+		options = append(options, option)
+		if slices.Contains(selected, d.kind) {
+			initial = append(initial, option)
+		}
+	}
+
+	checkboxes := map[string]any{
+		"type":      "checkboxes",
+		"action_id": digestAction,
+		"options":   options,
+	}
+	// Slack rejects an empty list of initial options.
+	if len(initial) > 0 {
+		checkboxes["initial_options"] = initial
+	}
+	return []map[string]any{
+		{
+			"type": "header",
+			"text": map[string]any{"type": "plain_text", "text": "Notifications"},
+		},
+		{
+			"type": "section",
+			"text": map[string]any{
+				"type": "mrkdwn",
+				"text": "I can send you a message when a selection includes you. Your choices apply to every channel in this workspace.",
+			},
+		},
+		{
+			"type":     "actions",
+			"elements": []map[string]any{checkboxes},
+		},
+		{"type": "divider"},
+	}
+}
@@ -0,0 +1,96 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+)
+
+func TestHomeConsole(t *testing.T) {
+	var published []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/views.publish" {
+			t.Errorf("unexpected call to %s", r.URL.Path)
+		}
+		var payload struct {
+			UserID string          `json:"user_id"`
+			View   json.RawMessage `json:"view"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		if payload.UserID != "U1" {
+			t.Errorf("published view for %q, want U1", payload.UserID)
+		}
+		published = append(published, string(payload.View))
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer srv.Close()
+
+	store := rndtest.Store{"snacks": {"chips", "pretzels"}}
+	app := App{
+		TokenProvider: StaticToken("right"),
+		StoreFactory:  func(_ string) randomizer.Store { return store },
+		Home:          &Home{Client: WebClient{Token: "xoxb-test", BaseURL: srv.URL + "/"}},
+	}
+
+	postEvent(app, `{"token":"right","team_id":"T1","type":"event_callback","event":{"type":"app_home_opened","user":"U1","channel":"D1","tab":"home"}}`)
+	if len(published) != 1 || !strings.Contains(published[0], `"initial_conversation":"D1"`) {
+		t.Fatalf("got published views %v, want the console in D1", published)
+	}
+
+	for _, text := range []string{"/save snacks chips popcorn", "snacks"} {
+		postInteraction(app, "right", text, "C1")
+	}
+	if len(published) != 3 {
+		t.Fatalf("got %d published views, want 3", len(published))
+	}
+	if !strings.Contains(published[1], `add popcorn; remove pretzels`) {
+		t.Errorf("save preview missing changes: %s", published[1])
+	}
+	if !strings.Contains(published[2], "I randomized and got") {
+		t.Errorf("selection preview missing result: %s", published[2])
+	}
+	if got := store["snacks"]; !slices.Equal(got, []string{"chips", "pretzels"}) {
+		t.Errorf("console changed the group to %v", got)
+	}
+	if _, ok := store["/history/snacks"]; ok {
+		t.Error("console recorded history")
+	}
+
+	if resp := postInteraction(app, "wrong", "snacks", "C1"); resp.Code != http.StatusForbidden {
+		t.Errorf("wrong status for invalid token: got %v, want %v", resp.Code, http.StatusForbidden)
+	}
+	if len(published) != 3 {
+		t.Error("published a view for an unverified interaction")
+	}
+}
+
+func postInteraction(app App, token, text, channel string) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(map[string]any{
+		"type":    "block_actions",
+		"token":   token,
+		"team":    map[string]any{"id": "T1"},
+		"user":    map[string]any{"id": "U1"},
+		"actions": []map[string]any{{"action_id": consolePreviewAction}},
+		"view": map[string]any{
+			"hash": "1.2",
+			"state": map[string]any{"values": map[string]any{
+				consoleCommandBlock: map[string]any{consoleCommandAction: map[string]any{"value": text}},
+				consoleChannelBlock: map[string]any{consoleChannelAction: map[string]any{"selected_conversation": channel}},
+			}},
+		},
+	})
+	form := url.Values{"payload": {string(payload)}}
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	app.ServeHTTP(resp, req)
+	return resp
+}
@@ -0,0 +1,442 @@
+package slack
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+
+	"github.com/featherbread/randomizer/internal/awsconfig"
+	"github.com/featherbread/randomizer/internal/clock"
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+// A single deployment of the randomizer can serve many Slack workspaces by
+// letting each one install it through Slack's OAuth flow, which an Installer
+// serves. Each installation grants a separate bot token, which a TokenStore
+// keeps. The App's Web API calls pick the token of the workspace that they act
+// for, and an App with Installations rejects requests from workspaces that
+// haven't installed it.
+
+// DefaultInstallScopes are the bot token scopes that an Installer requests by
+// default: just enough for slash commands.
+var DefaultInstallScopes = []string{"commands"}
+
+// InstallationsPartition is the store partition that holds installations for
+// a [StoreTokens].
+const InstallationsPartition = "slack-installations"
+
+// installStateCookie holds the random state of an install flow in the browser
+// that started it, so that a callback can't complete someone else's flow.
+const installStateCookie = "randomizer_slack_install"
+
+// installStateTTL bounds how long an install flow may take.
+const installStateTTL = 10 * time.Minute
+
+// Installation describes the installation of the randomizer in a Slack
+// workspace.
+type Installation struct {
+	TeamID      string    `json:"team_id"`
+	TeamName    string    `json:"team_name,omitempty"`
+	BotUserID   string    `json:"bot_user_id,omitempty"`
+	BotToken    string    `json:"bot_token"`
+	Scopes      []string  `json:"scopes,omitempty"`
+	InstalledAt time.Time `json:"installed_at"`
+}
+
+// ErrNotInstalled indicates that a workspace has no installation.
+var ErrNotInstalled = errors.New("randomizer is not installed in this workspace")
+
+// TokenStore keeps the installation of each Slack workspace.
+type TokenStore interface {
+	// Installation returns a workspace's installation, or ErrNotInstalled.
+	Installation(ctx context.Context, team string) (Installation, error)
+	// SaveInstallation saves a workspace's installation, replacing any
+	// previous one.
+	SaveInstallation(ctx context.Context, inst Installation) error
+	// DeleteInstallation removes a workspace's installation, if it has one.
+	DeleteInstallation(ctx context.Context, team string) error
+}
+
+// StoreTokens keeps installations in a randomizer store, like the DynamoDB
+// table that holds groups. Bot tokens are secrets, so the store should be
+// encrypted at rest.
+type StoreTokens struct {
+	Store randomizer.Store
+}
+
+// Installation implements TokenStore.
+func (s StoreTokens) Installation(ctx context.Context, team string) (Installation, error) {
+	entries, err := s.Store.Get(ctx, team)
+	if err != nil {
+		return Installation{}, err
+	}
+	if len(entries) == 0 {
+		return Installation{}, ErrNotInstalled
+	}
+	var inst Installation
+	if err := json.Unmarshal([]byte(entries[0]), &inst); err != nil {
+		return Installation{}, fmt.Errorf("decoding installation of %s: %w", team, err)
+	}
+	return inst, nil
+}
+
+// SaveInstallation implements TokenStore.
+func (s StoreTokens) SaveInstallation(ctx context.Context, inst Installation) error {
+	data, err := json.Marshal(inst)
+	if err != nil {
+		return err
+	}
+	return s.Store.Put(ctx, inst.TeamID, []string{string(data)})
+}
+
+// DeleteInstallation implements TokenStore.
+func (s StoreTokens) DeleteInstallation(ctx context.Context, team string) error {
+	_, err := s.Store.Delete(ctx, team)
+	return err
+}
+
+// SSMTokens keeps each installation as a SecureString parameter in the AWS SSM
+// Parameter Store, named with Prefix followed by the workspace's ID.
+type SSMTokens struct {
+	Prefix string
+	// Client, if non-nil, replaces a client configured from the environment.
+	Client *ssm.Client
+}
+
+func (s SSMTokens) client(ctx context.Context) (*ssm.Client, error) {
+	if s.Client != nil {
+		return s.Client, nil
+	}
+	cfg, err := awsconfig.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ssm.NewFromConfig(cfg), nil
+}
+
+// Installation implements TokenStore.
+func (s SSMTokens) Installation(ctx context.Context, team string) (Installation, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return Installation{}, err
+	}
+	output, err := client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(s.Prefix + team),
+		WithDecryption: aws.Bool(true),
+	})
+	var notFound *ssmtypes.ParameterNotFound
+	if errors.As(err, &notFound) {
+		return Installation{}, ErrNotInstalled
+	}
+	if err != nil {
+		return Installation{}, fmt.Errorf("loading installation of %s: %w", team, err)
+	}
+	var inst Installation
+	if err := json.Unmarshal([]byte(*output.Parameter.Value), &inst); err != nil {
+		return Installation{}, fmt.Errorf("decoding installation of %s: %w", team, err)
+	}
+	return inst, nil
+}
+
+// SaveInstallation implements TokenStore.
+func (s SSMTokens) SaveInstallation(ctx context.Context, inst Installation) error {
+	client, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(inst)
+	if err != nil {
+		return err
+	}
+	_, err = client.PutParameter(ctx, &ssm.PutParameterInput{
+		Name:      aws.String(s.Prefix + inst.TeamID),
+		Value:     aws.String(string(data)),
+		Type:      ssmtypes.ParameterTypeSecureString,
+		Overwrite: aws.Bool(true),
+	})
+	if err != nil {
+		return fmt.Errorf("saving installation of %s: %w", inst.TeamID, err)
+	}
+	return nil
+}
+
+// DeleteInstallation implements TokenStore.
+func (s SSMTokens) DeleteInstallation(ctx context.Context, team string) error {
+	client, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = client.DeleteParameter(ctx, &ssm.DeleteParameterInput{Name: aws.String(s.Prefix + team)})
+	var notFound *ssmtypes.ParameterNotFound
+	if err != nil && !errors.As(err, &notFound) {
+		return fmt.Errorf("deleting installation of %s: %w", team, err)
+	}
+	return nil
+}
+
+// TokenStoreFromEnv returns the TokenStore configured by the environment: an
+// SSMTokens if SLACK_INSTALLATIONS_SSM_PREFIX is set, or else a StoreTokens
+// backed by the InstallationsPartition of stores.
+func TokenStoreFromEnv(stores func(partition string) randomizer.Store) TokenStore {
+	if prefix, ok := os.LookupEnv("SLACK_INSTALLATIONS_SSM_PREFIX"); ok {
+		return SSMTokens{Prefix: prefix}
+	}
+	return StoreTokens{Store: stores(InstallationsPartition)}
+}
+
+type teamContextKey struct{}
+
+// withTeam records the workspace that a request came from, so that Web API
+// calls made on its behalf can use that workspace's bot token.
+func withTeam(ctx context.Context, team string) context.Context {
+	if team == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, teamContextKey{}, team)
+}
+
+func teamFromContext(ctx context.Context) string {
+	team, _ := ctx.Value(teamContextKey{}).(string)
+	return team
+}
+
+// InstalledBotTokens returns a BotTokenProvider that provides the bot token of
+// the workspace that each call acts for. Calls that don't act for a particular
+// workspace, or act for one that hasn't installed the randomizer, use fallback
+// if it's non-nil.
+func InstalledBotTokens(tokens TokenStore, fallback BotTokenProvider) BotTokenProvider {
+	return func(ctx context.Context) (string, error) {
+		if team := teamFromContext(ctx); team != "" {
+			inst, err := tokens.Installation(ctx, team)
+			if err == nil {
+				return inst.BotToken, nil
+			}
+			if !errors.Is(err, ErrNotInstalled) {
+				return "", err
+			}
+		}
+		if fallback == nil {
+			return "", ErrNotInstalled
+		}
+		return fallback(ctx)
+	}
+}
+
+// isInstalled reports whether a workspace may use the App.
+func (a App) isInstalled(ctx context.Context, team string) (bool, error) {
+	if a.Installations == nil || team == "" {
+		return true, nil
+	}
+	_, err := a.Installations.Installation(ctx, team)
+	if errors.Is(err, ErrNotInstalled) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Installer serves Slack's OAuth 2.0 flow for installing the randomizer in a
+// workspace, at "GET /slack/install" and "GET /slack/oauth/callback".
+type Installer struct {
+	// ClientID and ClientSecret identify the Slack app. They can be obtained
+	// from the app's credentials.
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the public URL of the callback endpoint, which must also
+	// be listed in the app's OAuth settings.
+	RedirectURL string
+	// Scopes lists the bot token scopes to request. If empty, it defaults to
+	// DefaultInstallScopes.
+	Scopes []string
+	// Tokens keeps the installation of each workspace.
+	Tokens TokenStore
+	// BaseURL overrides DefaultWebAPIURL, e.g. for testing.
+	BaseURL string
+	// HTTPClient overrides http.DefaultClient.
+	HTTPClient *http.Client
+	// Clock, if non-nil, replaces the system clock for recording installation
+	// times.
+	Clock clock.Clock
+	// Logger, if non-nil, logs installations and failed attempts.
+	Logger *slog.Logger
+}
+
+// InstallerFromEnv returns an Installer configured by SLACK_CLIENT_ID,
+// SLACK_CLIENT_SECRET, SLACK_REDIRECT_URL, and the comma-separated
+// SLACK_INSTALL_SCOPES, or nil if SLACK_CLIENT_ID is unset.
+func InstallerFromEnv(tokens TokenStore) (*Installer, error) {
+	clientID := os.Getenv("SLACK_CLIENT_ID")
+	if clientID == "" {
+		return nil, nil
+	}
+	i := &Installer{
+		ClientID:     clientID,
+		ClientSecret: os.Getenv("SLACK_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("SLACK_REDIRECT_URL"),
+		Tokens:       tokens,
+	}
+	if i.ClientSecret == "" || i.RedirectURL == "" {
+		return nil, errors.New("SLACK_CLIENT_ID requires SLACK_CLIENT_SECRET and SLACK_REDIRECT_URL")
+	}
+	for scope := range strings.SplitSeq(os.Getenv("SLACK_INSTALL_SCOPES"), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			i.Scopes = append(i.Scopes, scope)
+		}
+	}
+	return i, nil
+}
+
+// Handler returns an HTTP handler for the install flow.
+func (i Installer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /slack/install", i.start)
+	mux.HandleFunc("GET /slack/oauth/callback", i.callback)
+	return mux
+}
+
+func (i Installer) start(w http.ResponseWriter, r *http.Request) {
+	state := rand.Text()
+	http.SetCookie(w, &http.Cookie{
+		Name:     installStateCookie,
+		Value:    state,
+		Path:     "/slack/",
+		MaxAge:   int(installStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	scopes := i.Scopes
+	if len(scopes) == 0 {
+		scopes = DefaultInstallScopes
+	}
+	query := url.Values{
+		"client_id":    {i.ClientID},
+		"scope":        {strings.Join(scopes, ",")},
+		"redirect_uri": {i.RedirectURL},
+		"state":        {state},
+	}
+	http.Redirect(w, r, "https://slack.com/oauth/v2/authorize?"+query.Encode(), http.StatusFound)
+}
+
+func (i Installer) callback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	cookie, err := r.Cookie(installStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(query.Get("state"))) != 1 {
+		i.fail(w, http.StatusBadRequest, "state mismatch", errors.New("install flow expired or started in another browser"))
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: installStateCookie, Path: "/slack/", MaxAge: -1})
+
+	if reason := query.Get("error"); reason != "" {
+		i.fail(w, http.StatusBadRequest, "install declined", errors.New(reason))
+		return
+	}
+
+	inst, err := i.exchange(r.Context(), query.Get("code"))
+	if err != nil {
+		i.fail(w, http.StatusBadGateway, "code exchange failed", err)
+		return
+	}
+	if err := i.Tokens.SaveInstallation(r.Context(), inst); err != nil {
+		i.fail(w, http.StatusInternalServerError, "saving installation failed", err)
+		return
+	}
+
+	if i.Logger != nil {
+		i.Logger.Info("Installed in Slack workspace", "team", inst.TeamID, "scopes", inst.Scopes)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<title>Randomizer installed</title>\n<p>The randomizer is installed in %s. You can close this window.</p>\n",
+		html.EscapeString(cmp.Or(inst.TeamName, inst.TeamID)))
+}
+
+// exchange trades an authorization code for the workspace's installation.
+func (i Installer) exchange(ctx context.Context, code string) (Installation, error) {
+	if code == "" {
+		return Installation{}, errors.New("missing authorization code")
+	}
+	form := url.Values{
+		"client_id":     {i.ClientID},
+		"client_secret": {i.ClientSecret},
+		"code":          {code},
+		"redirect_uri":  {i.RedirectURL},
+	}
+	baseURL := i.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultWebAPIURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"oauth.v2.access", strings.NewReader(form.Encode()))
+	if err != nil {
+		return Installation{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := i.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Installation{}, fmt.Errorf("calling oauth.v2.access: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Error       string `json:"error"`
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		Scope       string `json:"scope"`
+		BotUserID   string `json:"bot_user_id"`
+		Team        struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"team"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Installation{}, fmt.Errorf("decoding oauth.v2.access response: %w", err)
+	}
+	if !result.OK {
+		return Installation{}, fmt.Errorf("oauth.v2.access failed: %s", result.Error)
+	}
+	if result.TokenType != "bot" || result.AccessToken == "" || result.Team.ID == "" {
+		return Installation{}, errors.New("oauth.v2.access did not return a bot token for a workspace")
+	}
+
+	inst := Installation{
+		TeamID:      result.Team.ID,
+		TeamName:    result.Team.Name,
+		BotUserID:   result.BotUserID,
+		BotToken:    result.AccessToken,
+		InstalledAt: clock.Or(i.Clock).Now().UTC(),
+	}
+	for scope := range strings.SplitSeq(result.Scope, ",") {
+		if scope != "" && !slices.Contains(inst.Scopes, scope) {
+			inst.Scopes = append(inst.Scopes, scope)
+		}
+	}
+	return inst, nil
+}
+
+func (i Installer) fail(w http.ResponseWriter, status int, msg string, err error) {
+	if i.Logger != nil {
+		i.Logger.Warn("Slack install failed: "+msg, "err", err)
+	}
+	http.Error(w, "Sorry, the randomizer couldn't be installed. Please try again from the start.", status)
+}
@@ -0,0 +1,127 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+)
+
+func TestInstaller(t *testing.T) {
+	slackAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/oauth.v2.access" || r.FormValue("code") != "good-code" || r.FormValue("client_secret") != "secret" {
+			json.NewEncoder(w).Encode(map[string]any{"ok": false, "error": "invalid_code"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"ok":           true,
+			"access_token": "xoxb-t1",
+			"token_type":   "bot",
+			"scope":        "commands,chat:write",
+			"bot_user_id":  "UBOT",
+			"team":         map[string]string{"id": "T1", "name": "Team <One>"},
+		})
+	}))
+	defer slackAPI.Close()
+
+	tokens := StoreTokens{Store: make(rndtest.Store)}
+	installer := Installer{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "https://randomizer.example.com/slack/oauth/callback",
+		Tokens:       tokens,
+		BaseURL:      slackAPI.URL + "/",
+	}
+	handler := installer.Handler()
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/slack/install", nil))
+	location, err := url.Parse(resp.Header().Get("Location"))
+	if err != nil || resp.Code != http.StatusFound || location.Host != "slack.com" {
+		t.Fatalf("got install response %d to %q, want a redirect to Slack", resp.Code, resp.Header().Get("Location"))
+	}
+	state := location.Query().Get("state")
+	if location.Query().Get("scope") != "commands" || state == "" {
+		t.Errorf("got authorize query %v, want default scopes and a state", location.Query())
+	}
+	cookies := resp.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != state || !cookies[0].HttpOnly {
+		t.Fatalf("got cookies %v, want an HttpOnly state cookie", cookies)
+	}
+
+	callback := func(query string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/slack/oauth/callback?"+query, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		handler.ServeHTTP(resp, req)
+		return resp
+	}
+
+	if resp := callback("code=good-code&state="+state, nil); resp.Code != http.StatusBadRequest {
+		t.Errorf("got status %d without a state cookie, want %d", resp.Code, http.StatusBadRequest)
+	}
+	if resp := callback("code=bad-code&state="+state, cookies[0]); resp.Code != http.StatusBadGateway {
+		t.Errorf("got status %d for a bad code, want %d", resp.Code, http.StatusBadGateway)
+	}
+
+	resp = callback("code=good-code&state="+state, cookies[0])
+	if resp.Code != http.StatusOK || !strings.Contains(resp.Body.String(), "Team &lt;One&gt;") {
+		t.Fatalf("got callback response %d %s, want success", resp.Code, resp.Body)
+	}
+	inst, err := tokens.Installation(t.Context(), "T1")
+	if err != nil || inst.BotToken != "xoxb-t1" || inst.BotUserID != "UBOT" || len(inst.Scopes) != 2 {
+		t.Errorf("got installation %+v (err %v)", inst, err)
+	}
+}
+
+func TestInstalledBotTokens(t *testing.T) {
+	tokens := StoreTokens{Store: make(rndtest.Store)}
+	tokens.SaveInstallation(t.Context(), Installation{TeamID: "T1", BotToken: "xoxb-t1"})
+
+	provider := InstalledBotTokens(tokens, nil)
+	if token, err := provider(withTeam(t.Context(), "T1")); err != nil || token != "xoxb-t1" {
+		t.Errorf("got token %q (err %v) for T1, want xoxb-t1", token, err)
+	}
+	if _, err := provider(withTeam(t.Context(), "T2")); err != ErrNotInstalled {
+		t.Errorf("got err %v for T2 without a fallback, want ErrNotInstalled", err)
+	}
+
+	provider = InstalledBotTokens(tokens, func(context.Context) (string, error) { return "xoxb-shared", nil })
+	if token, _ := provider(withTeam(t.Context(), "T2")); token != "xoxb-shared" {
+		t.Errorf("got token %q for T2, want the fallback", token)
+	}
+}
+
+func TestInstallationRequired(t *testing.T) {
+	tokens := StoreTokens{Store: make(rndtest.Store)}
+	tokens.SaveInstallation(t.Context(), Installation{TeamID: "T1", BotToken: "xoxb-t1"})
+	app := App{
+		TokenProvider: StaticToken("right"),
+		StoreFactory:  func(_ string) randomizer.Store { return make(rndtest.Store) },
+		Installations: tokens,
+	}
+
+	for team, want := range map[string]int{"T1": http.StatusOK, "T2": http.StatusForbidden} {
+		resp := httptest.NewRecorder()
+		body := url.Values{"token": {"right"}, "team_id": {team}, "channel_id": {"C1"}, "text": {"one two"}}
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		app.ServeHTTP(resp, req)
+		if resp.Code != want {
+			t.Errorf("got status %d for %s, want %d", resp.Code, team, want)
+		}
+	}
+
+	postEvent(app, `{"token":"right","team_id":"T1","type":"event_callback","event":{"type":"tokens_revoked"}}`)
+	if _, err := tokens.Installation(t.Context(), "T1"); err != ErrNotInstalled {
+		t.Errorf("got err %v after tokens were revoked, want ErrNotInstalled", err)
+	}
+}
@@ -0,0 +1,57 @@
+package slack
+
+import (
+	"sync"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+// maxOnboardedChannels bounds the channels that an Onboarding remembers.
+// Forgetting them only costs each channel another read of its onboarding
+// state, so an Onboarding that reaches the limit simply starts over.
+const maxOnboardedChannels = 10000
+
+// Onboarding welcomes each channel on the first slash command that someone
+// runs there, with buttons that create a sample group, make it the channel's
+// default, and show the help. Commands that operators run on a user's behalf
+// and scheduled picks are never welcomed.
+//
+// An Onboarding remembers the channels that it has seen onboarded, so that
+// later commands in those channels don't read their onboarding state from the
+// store. Like a RetryCache, it only covers the process it lives in.
+type Onboarding struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// NewOnboarding creates an Onboarding that hasn't seen any channels.
+func NewOnboarding() *Onboarding {
+	return &Onboarding{seen: make(map[string]bool)}
+}
+
+// options returns the randomizer options that onboard a channel, or nil if
+// the Onboarding is nil or has seen the channel onboarded.
+func (o *Onboarding) options(team, channel string) []randomizer.AppOption {
+	if o == nil {
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.seen[team+"/"+channel] {
+		return nil
+	}
+	return []randomizer.AppOption{randomizer.WithOnboarding()}
+}
+
+// remember notes a channel that a result confirmed is onboarded.
+func (o *Onboarding) remember(team, channel string, result randomizer.Result) {
+	if o == nil || !result.Onboarded() {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.seen) >= maxOnboardedChannels {
+		clear(o.seen)
+	}
+	o.seen[team+"/"+channel] = true
+}
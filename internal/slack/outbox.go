@@ -0,0 +1,271 @@
+package slack
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/clock"
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+// Default settings for an Outbox.
+const (
+	DefaultOutboxMaxAttempts   = 10
+	DefaultOutboxSweepInterval = time.Minute
+)
+
+// outboxPartition holds the messages waiting in an Outbox. Each message is a
+// record named with outboxPrefix and a random ID, so that sweeps can find them
+// with a single List.
+const (
+	outboxPartition = "outbox"
+	outboxPrefix    = "/message/"
+)
+
+// outboxGrace is how long a new message waits before a sweep may retry it,
+// which leaves time for the original delivery (like a suspenseful reveal) to
+// finish on its own.
+const outboxGrace = time.Minute
+
+// maxOutboxBackoff bounds the delay between retries of a message.
+const maxOutboxBackoff = 15 * time.Minute
+
+// Outbox guarantees at-least-once delivery of the messages that the App posts
+// after responding to a slash command, like the final announcement of a
+// suspenseful selection.
+//
+// The App records each message in the Outbox before it starts to deliver it,
+// and removes the record once the delivery succeeds. A periodic [Outbox.Sweep]
+// posts any message whose delivery failed or never finished (for example,
+// because the process exited mid-reveal), backing off between attempts. A
+// message that fails to post MaxAttempts times is dropped and logged.
+//
+// Since a sweep can't tell a slow delivery from a failed one, a message may
+// occasionally be posted twice.
+type Outbox struct {
+	// StoreFactory provides the Store for the Outbox's partition, like the one
+	// that serves groups.
+	StoreFactory func(partition string) randomizer.Store
+	// Client posts messages during sweeps, and must have a token with the
+	// chat:write scope.
+	Client WebClient
+	// MaxAttempts overrides DefaultOutboxMaxAttempts.
+	MaxAttempts int
+	// Clock, if non-nil, replaces the system clock for scheduling retries.
+	Clock clock.Clock
+	// Logger, if non-nil, logs failed deliveries.
+	Logger *slog.Logger
+}
+
+// outboxMessage is a message waiting in an Outbox.
+type outboxMessage struct {
+	ID        string
+	Team      string // The workspace whose bot token posts the message, if known
+	Channel   string
+	Text      string
+	Username  string
+	IconEmoji string
+	Attempts  int
+	Next      time.Time
+}
+
+// entries encodes a message as the entries of its record, of the form
+// "<key>|<value>". Entries don't depend on their order, since not every Store
+// preserves it.
+func (m outboxMessage) entries() []string {
+	entries := []string{
+		"channel|" + m.Channel,
+		"text|" + m.Text,
+		"attempts|" + strconv.Itoa(m.Attempts),
+		"next|" + m.Next.UTC().Format(time.RFC3339),
+	}
+	if m.Team != "" {
+		entries = append(entries, "team|"+m.Team)
+	}
+	if m.Username != "" {
+		entries = append(entries, "username|"+m.Username)
+	}
+	if m.IconEmoji != "" {
+		entries = append(entries, "icon_emoji|"+m.IconEmoji)
+	}
+	return entries
+}
+
+func parseOutboxMessage(id string, entries []string) (outboxMessage, error) {
+	m := outboxMessage{ID: id}
+	for _, entry := range entries {
+		key, value, _ := strings.Cut(entry, "|")
+		switch key {
+		case "team":
+			m.Team = value
+		case "channel":
+			m.Channel = value
+		case "text":
+			m.Text = value
+		case "username":
+			m.Username = value
+		case "icon_emoji":
+			m.IconEmoji = value
+		case "attempts":
+			m.Attempts, _ = strconv.Atoi(value)
+		case "next":
+			m.Next, _ = time.Parse(time.RFC3339, value)
+		}
+	}
+	if m.Channel == "" || m.Text == "" {
+		return outboxMessage{}, fmt.Errorf("outbox message %s is incomplete", id)
+	}
+	return m, nil
+}
+
+// payload returns the chat.postMessage payload for the message.
+func (m outboxMessage) payload() map[string]string {
+	payload := map[string]string{"channel": m.Channel, "text": m.Text}
+	if m.Username != "" {
+		payload["username"] = m.Username
+	}
+	if m.IconEmoji != "" {
+		payload["icon_emoji"] = m.IconEmoji
+	}
+	return payload
+}
+
+func (o *Outbox) store() randomizer.Store {
+	return o.StoreFactory(outboxPartition)
+}
+
+// add records a message before its first delivery attempt, and returns its ID.
+// A nil *Outbox records nothing.
+func (o *Outbox) add(ctx context.Context, m outboxMessage) (string, error) {
+	if o == nil {
+		return "", nil
+	}
+	m.ID = strings.ToLower(rand.Text())
+	m.Next = clock.Or(o.Clock).Now().Add(outboxGrace)
+	return m.ID, o.store().Put(ctx, outboxPrefix+m.ID, m.entries())
+}
+
+// delivered removes a message from the Outbox after a successful delivery.
+func (o *Outbox) delivered(ctx context.Context, id string) error {
+	if o == nil || id == "" {
+		return nil
+	}
+	_, err := o.store().Delete(ctx, outboxPrefix+id)
+	return err
+}
+
+// Sweep posts every message in the Outbox that is due for another attempt,
+// and returns the number that it delivered. It stops early only if it can't
+// read the Outbox's store; failures to post individual messages are retried by
+// later sweeps.
+func (o *Outbox) Sweep(ctx context.Context) (delivered int, err error) {
+	ctx, span := tracer.Start(ctx, "slack.Outbox.Sweep")
+	defer span.End()
+
+	store := o.store()
+	names, err := store.List(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return 0, err
+	}
+
+	now := clock.Or(o.Clock).Now()
+	for _, name := range names {
+		id, ok := strings.CutPrefix(name, outboxPrefix)
+		if !ok {
+			continue
+		}
+		entries, err := store.Get(ctx, name)
+		if err != nil {
+			span.RecordError(err)
+			return delivered, err
+		}
+		if len(entries) == 0 {
+			continue // Delivered since we listed it.
+		}
+
+		m, err := parseOutboxMessage(id, entries)
+		if err != nil {
+			o.logErr(err, "Dropping unreadable outbox message", id)
+			store.Delete(ctx, name)
+			continue
+		}
+		if now.Before(m.Next) {
+			continue
+		}
+
+		if err := o.Client.Call(withTeam(ctx, m.Team), "chat.postMessage", m.payload(), nil); err != nil {
+			o.retryLater(ctx, m, now, err)
+			continue
+		}
+		if _, err := store.Delete(ctx, name); err != nil {
+			o.logErr(err, "Failed to remove delivered outbox message", id)
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+// retryLater records a failed attempt to post a message, and schedules the
+// next one with exponential backoff, or drops the message if it has run out of
+// attempts.
+func (o *Outbox) retryLater(ctx context.Context, m outboxMessage, now time.Time, cause error) {
+	maxAttempts := o.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = DefaultOutboxMaxAttempts
+	}
+
+	m.Attempts++
+	if m.Attempts >= maxAttempts {
+		o.logErr(cause, "Giving up on outbox message", m.ID, "channel", m.Channel, "attempts", m.Attempts)
+		o.store().Delete(ctx, outboxPrefix+m.ID)
+		return
+	}
+
+	backoff := min(outboxGrace<<(m.Attempts-1), maxOutboxBackoff)
+	var rateLimited RateLimitedError
+	if errors.As(cause, &rateLimited) {
+		backoff = max(backoff, rateLimited.RetryAfter)
+	}
+	m.Next = now.Add(backoff)
+	o.logErr(cause, "Failed to deliver outbox message; will retry", m.ID, "attempts", m.Attempts, "next", m.Next)
+	if err := o.store().Put(ctx, outboxPrefix+m.ID, m.entries()); err != nil {
+		o.logErr(err, "Failed to reschedule outbox message", m.ID)
+	}
+}
+
+// Run sweeps the Outbox at the provided interval, or at
+// DefaultOutboxSweepInterval if it's zero, until ctx is canceled.
+func (o *Outbox) Run(ctx context.Context, interval time.Duration) {
+	if interval == 0 {
+		interval = DefaultOutboxSweepInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := o.Sweep(ctx); err != nil {
+				o.logErr(err, "Failed to sweep outbox", "")
+			}
+		}
+	}
+}
+
+func (o *Outbox) logErr(err error, msg, id string, args ...any) {
+	if o.Logger == nil {
+		return
+	}
+	if id != "" {
+		args = append([]any{"id", id}, args...)
+	}
+	o.Logger.Error(msg, append(args, "err", err)...)
+}
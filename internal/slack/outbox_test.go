@@ -0,0 +1,108 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/clock/clocktest"
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+)
+
+func TestOutboxSweep(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		fail   = true
+		posted []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			w.Write([]byte(`{"ok": false, "error": "internal_error"}`))
+			return
+		}
+		posted = append(posted, payload["text"])
+		w.Write([]byte(`{"ok": true, "channel": "C12345678", "ts": "1.2"}`))
+	}))
+	defer srv.Close()
+
+	store := make(rndtest.Store)
+	clk := clocktest.New(time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC))
+	outbox := &Outbox{
+		StoreFactory: func(_ string) randomizer.Store { return store },
+		Client:       WebClient{Token: "xoxb-test", BaseURL: srv.URL + "/"},
+		MaxAttempts:  3,
+		Clock:        clk,
+	}
+	suspense := &Suspense{Client: outbox.Client, Interval: time.Millisecond, Outbox: outbox}
+
+	app := randomizer.NewApp("/randomize", rndtest.Store{})
+	result, err := app.Main(context.Background(), []string{"one", "two", "three"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	suspense.reveal(context.Background(), "C12345678", result, randomizer.Theme{}, slog.New(slog.DiscardHandler))
+	if len(store) != 1 {
+		t.Fatalf("failed reveal left %d outbox messages, want 1", len(store))
+	}
+
+	sweep := func() int {
+		t.Helper()
+		n, err := outbox.Sweep(context.Background())
+		if err != nil {
+			t.Fatalf("Sweep() failed: %v", err)
+		}
+		return n
+	}
+
+	if n := sweep(); n != 0 {
+		t.Errorf("swept %d messages before the grace period ended", n)
+	}
+	clk.Advance(outboxGrace)
+	if n := sweep(); n != 0 || len(store) != 1 {
+		t.Errorf("swept %d messages while Slack was failing", n)
+	}
+
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+	clk.Advance(outboxGrace)
+	if n := sweep(); n != 1 {
+		t.Errorf("swept %d messages, want 1", n)
+	}
+	if len(posted) != 1 || !strings.Contains(posted[0], "I randomized and got") {
+		t.Errorf("got posted messages %q, want the selection", posted)
+	}
+	if len(store) != 0 {
+		t.Errorf("delivered message left in outbox: %v", store)
+	}
+
+	// A successful reveal removes its own message.
+	suspense.reveal(context.Background(), "C12345678", result, randomizer.Theme{}, slog.New(slog.DiscardHandler))
+	if len(store) != 0 {
+		t.Errorf("finished reveal left a message in the outbox: %v", store)
+	}
+
+	// Messages that never post are dropped after MaxAttempts.
+	mu.Lock()
+	fail = true
+	mu.Unlock()
+	outbox.add(context.Background(), outboxMessage{Channel: "C12345678", Text: "hello"})
+	for range 3 {
+		clk.Advance(maxOutboxBackoff)
+		sweep()
+	}
+	if len(store) != 0 {
+		t.Errorf("outbox kept a message after %d attempts: %v", outbox.MaxAttempts, store)
+	}
+}
@@ -0,0 +1,44 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+)
+
+func TestPlainTextTeams(t *testing.T) {
+	app := App{
+		TokenProvider: StaticToken("right"),
+		StoreFactory:  func(_ string) randomizer.Store { return make(rndtest.Store) },
+		PlainText:     TeamSet{"T111": true},
+	}
+
+	for _, tc := range []struct {
+		team      string
+		wantPlain bool
+	}{
+		{"T111", true},
+		{"T222", false},
+	} {
+		params := makeTestParams("one")
+		params.Set("team_id", tc.team)
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(params.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		app.ServeHTTP(resp, req)
+
+		var got response
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		isPlain := got.Mrkdwn != nil && !*got.Mrkdwn && !strings.Contains(got.Text, "*")
+		if isPlain != tc.wantPlain {
+			t.Errorf("team %s: got %+v, want plain text = %v", tc.team, got, tc.wantPlain)
+		}
+	}
+}
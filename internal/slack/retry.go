@@ -0,0 +1,108 @@
+package slack
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/clock"
+)
+
+// DefaultRetryCacheTTL is long enough to cover Slack's retries of a slash
+// command, which follow closely behind the original attempt.
+const DefaultRetryCacheTTL = 2 * time.Minute
+
+// RetryCache remembers recent responses so that duplicate deliveries of the
+// same slash command invocation receive the original response, rather than
+// re-running the randomizer and producing a second (different) selection.
+//
+// Invocations are identified by their workspace, channel, user, and trigger
+// ID. Requests without a trigger ID are never treated as duplicates.
+//
+// A RetryCache only covers the process it lives in. When requests are spread
+// across multiple instances (e.g. concurrent AWS Lambda environments), some
+// retries may still run twice.
+type RetryCache struct {
+	ttl   time.Duration
+	clock clock.Clock
+
+	mu      sync.Mutex
+	entries map[string]*retryEntry
+}
+
+type retryEntry struct {
+	done    chan struct{}
+	resp    response
+	expires time.Time
+}
+
+// NewRetryCache creates a RetryCache that remembers each response for ttl.
+func NewRetryCache(ttl time.Duration) *RetryCache {
+	return &RetryCache{
+		ttl:     ttl,
+		clock:   clock.System,
+		entries: make(map[string]*retryEntry),
+	}
+}
+
+// do returns the response previously produced for key if one exists, waiting
+// for any in-flight attempt to finish. Otherwise, it produces a new response
+// with fn and remembers it.
+func (c *RetryCache) do(ctx context.Context, key string, fn func() response) (resp response, duplicate bool, err error) {
+	now := c.clock.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok && now.After(entry.expires) {
+		ok = false
+	}
+	if !ok {
+		c.sweepLocked(now)
+		entry = &retryEntry{done: make(chan struct{}), expires: now.Add(c.ttl)}
+		c.entries[key] = entry
+	}
+	c.mu.Unlock()
+
+	if ok {
+		select {
+		case <-entry.done:
+			return entry.resp, true, nil
+		case <-ctx.Done():
+			return response{}, true, ctx.Err()
+		}
+	}
+
+	defer close(entry.done)
+	entry.resp = fn()
+	return entry.resp, false, nil
+}
+
+func (c *RetryCache) sweepLocked(now time.Time) {
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func retryKey(params url.Values) string {
+	triggerID := params.Get("trigger_id")
+	if triggerID == "" {
+		return ""
+	}
+	return strings.Join([]string{
+		params.Get("team_id"),
+		params.Get("channel_id"),
+		params.Get("user_id"),
+		triggerID,
+	}, "\x00")
+}
+
+// Flush forgets every remembered response.
+func (c *RetryCache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
@@ -0,0 +1,169 @@
+package slack
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/clock"
+	"github.com/featherbread/randomizer/internal/mrkdwn"
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+// DefaultScheduleSweepInterval is how often RunSchedules looks for schedules
+// that are due. Schedules run at minute granularity, so a pick may be posted up
+// to a minute after its scheduled time.
+const DefaultScheduleSweepInterval = time.Minute
+
+// Scheduler posts the picks that channels schedule with /schedule, like
+// "/schedule daily 9am +standup".
+//
+// The App saves schedules in the randomizer.SchedulePartition of its store,
+// and [App.SweepSchedules] runs the ones that are due and posts their results
+// as new messages. Long-running servers can sweep with [App.RunSchedules];
+// deployments like AWS Lambda can call SweepSchedules from a periodic trigger
+// instead.
+type Scheduler struct {
+	// Client posts the picks, and must have a token with the chat:write scope,
+	// for a bot that's a member of each channel with a schedule.
+	Client WebClient
+	// Outbox, if non-nil, records each pick before posting it, so that a sweep
+	// of the Outbox can post it if the first attempt fails.
+	Outbox *Outbox
+}
+
+// WithScheduler enables /schedule, and posts scheduled picks with s. See
+// [Scheduler].
+func WithScheduler(s *Scheduler) AppOption {
+	return func(a *App) { a.Scheduler = s }
+}
+
+// scheduleTarget identifies a channel to the randomizer's schedules, along with
+// the workspace whose bot token posts to it.
+func scheduleTarget(team, channel string) string {
+	if team == "" {
+		return channel
+	}
+	return team + "/" + channel
+}
+
+func parseScheduleTarget(target string) (team, channel string) {
+	team, channel, ok := strings.Cut(target, "/")
+	if !ok {
+		return "", target
+	}
+	return team, channel
+}
+
+func (a App) scheduleStore() randomizer.Store {
+	return a.StoreFactory(randomizer.SchedulePartition)
+}
+
+// SweepSchedules runs every schedule that is due, posts the results to their
+// channels, and returns the number of picks that it posted. It stops early only
+// if it can't read the schedules; failures to run or post individual picks are
+// logged.
+//
+// Each run is claimed before it starts, so concurrent sweeps never post the
+// same pick twice, and a run that fails isn't retried except by the Outbox.
+func (a App) SweepSchedules(ctx context.Context) (posted int, err error) {
+	ctx, span := tracer.Start(ctx, "slack.App.SweepSchedules")
+	defer span.End()
+
+	if a.Scheduler == nil {
+		return 0, nil
+	}
+
+	store := a.scheduleStore()
+	now := clock.Or(a.Clock).Now()
+	due, err := randomizer.DueSchedules(ctx, store, now)
+	if err != nil {
+		span.RecordError(err)
+		return 0, err
+	}
+
+	for _, s := range due {
+		claimed, err := s.Claim(ctx, store, now)
+		if err != nil {
+			a.logErr(err, "Failed to claim schedule")
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if err := a.runSchedule(ctx, s); err != nil {
+			a.logErr(err, "Failed to post scheduled pick")
+			continue
+		}
+		posted++
+	}
+	return posted, nil
+}
+
+// runSchedule runs a scheduled pick as the user who scheduled it, and posts
+// the result to the schedule's channel. If the pick fails, as when its group
+// was deleted, the error is posted instead, so the channel knows to fix or
+// remove the schedule.
+func (a App) runSchedule(ctx context.Context, s randomizer.Schedule) error {
+	team, channel := parseScheduleTarget(s.Target)
+	ctx = withTeam(ctx, team)
+
+	// The sweep itself is already in the background, so the randomizer's writes
+	// don't need to wait for later.
+	opts := append(a.randomizerOptions(team, s.User), randomizer.WithDeferredWrites(nil))
+	app := randomizer.NewApp(s.Command, a.StoreFactory(channel), opts...)
+	result, err := app.Main(ctx, s.Args)
+
+	var (
+		text  string
+		theme randomizer.Theme
+	)
+	if err != nil {
+		a.trackError(ctx, err)
+		text = ":alarm_clock: I couldn't run this channel's scheduled pick `" + strings.Join(s.Args, " ") + "`. " + errorResponse(err).Text
+	} else {
+		if result.Type() == randomizer.Selection {
+			theme = a.theme(ctx, team)
+		}
+		text = result.Message()
+		if theme.Emoji != "" {
+			text = theme.Emoji + " " + text
+		}
+	}
+	if a.PlainText.Contains(team) {
+		text = mrkdwn.PlainText(text)
+	}
+
+	m := outboxMessage{Team: team, Channel: channel, Text: text, Username: theme.Username, IconEmoji: theme.IconEmoji}
+	outboxID, err := a.Scheduler.Outbox.add(ctx, m)
+	if err != nil {
+		a.logErr(err, "Failed to record scheduled pick in outbox")
+	}
+	if err := a.Scheduler.Client.Call(ctx, "chat.postMessage", m.payload(), nil); err != nil {
+		return err
+	}
+	if err := a.Scheduler.Outbox.delivered(ctx, outboxID); err != nil {
+		a.logErr(err, "Failed to remove posted pick from outbox")
+	}
+	return nil
+}
+
+// RunSchedules sweeps the App's schedules at the provided interval, or at
+// DefaultScheduleSweepInterval if it's zero, until ctx is canceled.
+func (a App) RunSchedules(ctx context.Context, interval time.Duration) {
+	if interval == 0 {
+		interval = DefaultScheduleSweepInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := a.SweepSchedules(ctx); err != nil {
+				a.logErr(err, "Failed to sweep schedules")
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/clock/clocktest"
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+	"github.com/featherbread/randomizer/internal/slacktest"
+)
+
+func TestSweepSchedules(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		posted []map[string]string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		defer mu.Unlock()
+		posted = append(posted, payload)
+		w.Write([]byte(`{"ok": true, "channel": "C12345678", "ts": "1.2"}`))
+	}))
+	defer srv.Close()
+
+	stores := map[string]rndtest.Store{"C12345678": {"lunch": {"pizza", "tacos"}}}
+	clk := clocktest.New(time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC))
+	app := NewApp(StaticToken("token"),
+		func(partition string) randomizer.Store {
+			if stores[partition] == nil {
+				stores[partition] = rndtest.Store{}
+			}
+			return stores[partition]
+		},
+		WithScheduler(&Scheduler{Client: WebClient{Token: "xoxb-test", BaseURL: srv.URL + "/"}}),
+		WithClock(clk),
+	)
+
+	for _, text := range []string{"/save standup alice bob carol", "/schedule daily 9am standup", "/schedule daily 10am +lunch"} {
+		if _, err := app.runRandomizer(context.Background(), slacktest.SlashCommand(text)); err != nil {
+			t.Fatalf("%q failed: %v", text, err)
+		}
+	}
+	if len(stores[randomizer.SchedulePartition]) != 2 {
+		t.Fatalf("got schedules %v, want 2", stores[randomizer.SchedulePartition])
+	}
+
+	sweep := func() int {
+		t.Helper()
+		n, err := app.SweepSchedules(context.Background())
+		if err != nil {
+			t.Fatalf("SweepSchedules() failed: %v", err)
+		}
+		return n
+	}
+
+	if n := sweep(); n != 0 {
+		t.Errorf("posted %d picks before any were due", n)
+	}
+	clk.Advance(time.Hour)
+	if n := sweep(); n != 1 {
+		t.Errorf("posted %d picks, want 1", n)
+	}
+	if n := sweep(); n != 0 {
+		t.Errorf("posted %d picks again in the same minute", n)
+	}
+
+	delete(stores["C12345678"], "lunch")
+	clk.Advance(time.Hour)
+	if n := sweep(); n != 1 {
+		t.Errorf("posted %d picks, want 1", n)
+	}
+
+	if len(posted) != 2 {
+		t.Fatalf("got posted messages %v, want 2", posted)
+	}
+	if posted[0]["channel"] != "C12345678" || !strings.Contains(posted[0]["text"], "I randomized and got") {
+		t.Errorf("got scheduled pick %v", posted[0])
+	}
+	if !strings.Contains(posted[1]["text"], "couldn't run this channel's scheduled pick `+lunch`") {
+		t.Errorf("got failed pick %v", posted[1])
+	}
+}
@@ -0,0 +1,72 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// scopeRequirement describes the bot token scopes that an optional feature
+// needs in order to work.
+type scopeRequirement struct {
+	Feature string
+	Scopes  []string
+}
+
+// requiredScopes returns the scopes needed by the features that a is
+// configured to use.
+func (a App) requiredScopes() []scopeRequirement {
+	var required []scopeRequirement
+	if a.Suspense != nil {
+		required = append(required, scopeRequirement{"gradual reveals (SLACK_SUSPENSE)", []string{"chat:write"}})
+	}
+	if a.Uploader != nil {
+		required = append(required, scopeRequirement{"file uploads (SLACK_UPLOAD_LARGE_RESULTS)", []string{"files:write"}})
+	}
+	if a.UserNames != nil {
+		required = append(required, scopeRequirement{"user names in plain-text output", []string{"users:read"}})
+	}
+	if a.UserNames != nil && len(a.EmailExport) > 0 {
+		required = append(required, scopeRequirement{"email exports (EMAIL_EXPORT_TEAMS)", []string{"users:read", "users:read.email"}})
+	}
+	if a.BotUserID != "" {
+		required = append(required, scopeRequirement{"reaction feedback", []string{"reactions:read"}})
+	}
+	return required
+}
+
+// CheckScopes confirms that the bot token used by client has the scopes that
+// a's configured features need, and logs a warning for each feature that will
+// fail at request time without them. It returns an error only if it can't
+// determine the token's scopes at all.
+//
+// CheckScopes is meant to run once at startup, so that a missing scope shows
+// up in the logs right away rather than the first time someone uses the
+// feature.
+func (a App) CheckScopes(ctx context.Context, client WebClient) error {
+	required := a.requiredScopes()
+	if len(required) == 0 {
+		return nil
+	}
+
+	granted, err := client.Scopes(ctx)
+	if err != nil {
+		return fmt.Errorf("checking Slack bot token scopes: %w", err)
+	}
+
+	for _, req := range required {
+		var missing []string
+		for _, scope := range req.Scopes {
+			if !slices.Contains(granted, scope) {
+				missing = append(missing, scope)
+			}
+		}
+		if len(missing) > 0 && a.Logger != nil {
+			a.Logger.Warn(
+				"Slack bot token is missing scopes; feature will fail until they're added",
+				"feature", req.Feature, "missing", missing, "granted", granted,
+			)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckScopes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer xoxb-rotated" {
+			t.Errorf("auth.test called with Authorization %q", got)
+		}
+		w.Header().Set("X-OAuth-Scopes", "commands, chat:write")
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer srv.Close()
+
+	var logs bytes.Buffer
+	client := WebClient{
+		Tokens:  func(context.Context) (string, error) { return "xoxb-rotated", nil },
+		BaseURL: srv.URL + "/",
+	}
+	app := App{
+		Suspense:  &Suspense{Client: client},
+		UserNames: &UserNames{Client: client},
+		Logger:    slog.New(slog.NewTextHandler(&logs, nil)),
+	}
+	if err := app.CheckScopes(context.Background(), client); err != nil {
+		t.Fatalf("CheckScopes() = %v", err)
+	}
+
+	out := logs.String()
+	if !strings.Contains(out, "users:read") {
+		t.Errorf("missing users:read scope was not logged:\n%s", out)
+	}
+	if strings.Contains(out, "gradual reveals") {
+		t.Errorf("granted chat:write scope was logged as missing:\n%s", out)
+	}
+}
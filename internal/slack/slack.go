@@ -2,41 +2,311 @@
 package slack
 
 import (
+	"bytes"
 	"context"
 	"crypto/subtle"
 	"encoding/json"
+	"errors"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"go.opentelemetry.io/otel"
 
+	"github.com/featherbread/randomizer/internal/activity"
+	"github.com/featherbread/randomizer/internal/admin"
+	"github.com/featherbread/randomizer/internal/alert"
+	"github.com/featherbread/randomizer/internal/clock"
+	"github.com/featherbread/randomizer/internal/mrkdwn"
 	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/workspace"
 )
 
 var tracer = otel.Tracer("github.com/featherbread/randomizer/internal/slack")
 
 // App serves the randomizer through the Slack slash command API.
 //
-// App supports legacy static verification tokens to confirm the legitimacy of
-// requests from Slack; it does not support the newer signed secrets strategy.
+// App confirms the legitimacy of requests from Slack with legacy static
+// verification tokens, signing secrets, or both during a migration from one to
+// the other.
 //
 // App supports only HTTP POST requests; it does not support the GET requests
 // allowed by the deprecated legacy slash command integration.
 type App struct {
 	// TokenProvider provides the expected value of the slash command verification
 	// token generated by Slack. This can be obtained from the slash command
-	// configuration.
+	// configuration. If nil, the App accepts only signed requests.
 	TokenProvider TokenProvider
+	// SigningSecret, if non-nil, provides the signing secret that Slack uses to
+	// sign requests. This can be obtained from the app's credentials.
+	SigningSecret TokenProvider
+	// RequireSignature lists the workspaces whose requests must be signed,
+	// since they've finished migrating from the legacy verification token.
+	RequireSignature TeamSet
+	// Verification, if non-nil, tracks how each workspace's requests are
+	// verified.
+	Verification *VerificationTracker
 	// StoreFactory provides a Store for the Slack channel in which the request
 	// was made.
 	StoreFactory func(partition string) randomizer.Store
+	// Suspense, if non-nil, reveals large enough selections gradually through
+	// a series of message updates instead of responding immediately.
+	Suspense *Suspense
+	// Scheduler, if non-nil, enables /schedule, and posts the picks that
+	// channels schedule.
+	Scheduler *Scheduler
+	// RetryCache, if non-nil, remembers recent responses so that Slack's retries
+	// of a slash command don't produce duplicate selections.
+	RetryCache *RetryCache
+	// Onboarding, if non-nil, welcomes each channel on the first slash command
+	// that someone runs there.
+	Onboarding *Onboarding
+	// UserNames, if non-nil, resolves user mentions to plain names where Slack
+	// won't render them, like calendar events and exported history.
+	UserNames *UserNames
+	// EmailExport lists the workspaces whose users may export the email
+	// addresses of the people in a group's history. It requires UserNames.
+	EmailExport TeamSet
+	// Audit, if non-nil, records every change to groups and settings in an
+	// audit trail.
+	Audit *Audit
+	// AccessLog, if non-nil, records each slash command that the App handles.
+	AccessLog *AccessLog
+	// PlainText, if non-nil, lists the workspaces that receive responses in
+	// plain text instead of Slack's mrkdwn formatting, for accessibility.
+	PlainText TeamSet
+	// Uploader, if non-nil, posts large results to the channel as files.
+	Uploader *Uploader
+	// PickAgain adds a "Pick again" button to selections, which runs the same
+	// command again. It needs interactivity enabled with the same Request URL
+	// as the slash command.
+	PickAgain bool
+	// Federation enables /federate, which shares groups between workspaces
+	// that have both installed the App, like the two sides of a Slack Connect
+	// channel.
+	Federation bool
+	// Defaults configures what the randomizer does with requests that don't
+	// name an operation it knows.
+	Defaults randomizer.Defaults
+	// ContentCheck, if non-nil, reviews group names, options, and tags before
+	// they're saved.
+	ContentCheck randomizer.ContentCheck
+	// Deployment describes the deployment for the /version operation.
+	Deployment randomizer.Deployment
+	// WebhookSender, if non-nil, enables /webhook, and delivers each channel's
+	// selections to the webhook it registers.
+	WebhookSender randomizer.WebhookSender
+	// Randomness, if non-nil, replaces the randomizer's default source of
+	// randomness.
+	Randomness randomizer.Randomness
+	// BotUserID, if set, limits reaction feedback to messages posted by this
+	// user, which should be the app's bot user.
+	BotUserID string
+	// Budget, if non-nil, bounds the time spent on each slash command, and
+	// lets the randomizer defer non-critical writes to respond in time.
+	Budget *Budget
+	// Workspaces, if non-nil, indexes the channels of each workspace, and
+	// schedules the deletion of a workspace's data when it uninstalls the app.
+	Workspaces *workspace.Registry
+	// Alerts, if non-nil, notifies operators when verification failures, store
+	// errors, or timeouts spike.
+	Alerts *alert.Tracker
+	// Home, if non-nil, publishes a test console to each user's App Home tab.
+	Home *Home
+	// Activity, if non-nil, receives an event for each selection and change
+	// that the App makes, for live dashboards.
+	Activity *activity.Feed
+	// Clock, if non-nil, replaces the system clock for checking request
+	// timestamps and for the randomizer's history and schedules.
+	Clock clock.Clock
+	// Installations, if non-nil, holds the installation of each workspace
+	// that installed the App through an [Installer]. The App rejects requests
+	// from other workspaces, and forgets a workspace's installation when it
+	// uninstalls the App or revokes its tokens.
+	Installations TokenStore
 	// Logger, if non-nil, logs errors encountered during request handling.
 	Logger *slog.Logger
 }
 
-// ServeHTTP serves POST requests from Slack.
+// AppOption configures optional behavior for an App.
+type AppOption func(*App)
+
+// NewApp returns an App that verifies requests with tokens and serves them
+// from the stores that stores provides for each channel.
+//
+// Frontends may also build an App as a struct literal, but NewApp lets them
+// compose optional features without depending on the App's fields.
+func NewApp(tokens TokenProvider, stores func(partition string) randomizer.Store, opts ...AppOption) App {
+	app := App{TokenProvider: tokens, StoreFactory: stores}
+	for _, opt := range opts {
+		opt(&app)
+	}
+	return app
+}
+
+// WithSuspense reveals large enough selections gradually. See [Suspense].
+func WithSuspense(s *Suspense) AppOption {
+	return func(a *App) { a.Suspense = s }
+}
+
+// WithRetryCache replays recent responses to Slack's retries of a slash
+// command. See [RetryCache].
+func WithRetryCache(c *RetryCache) AppOption {
+	return func(a *App) { a.RetryCache = c }
+}
+
+// WithOnboarding welcomes each channel on its first slash command. See
+// [Onboarding].
+func WithOnboarding(o *Onboarding) AppOption {
+	return func(a *App) { a.Onboarding = o }
+}
+
+// WithUserNames resolves user mentions to plain names where Slack won't render
+// them. See [UserNames].
+func WithUserNames(u *UserNames) AppOption {
+	return func(a *App) { a.UserNames = u }
+}
+
+// WithEmailExport lets users in the listed workspaces export the email
+// addresses of the people in a group's history. See [App.EmailExport].
+func WithEmailExport(teams TeamSet) AppOption {
+	return func(a *App) { a.EmailExport = teams }
+}
+
+// WithAccessLog records each slash command that the App handles. See
+// [AccessLog].
+func WithAccessLog(l *AccessLog) AppOption {
+	return func(a *App) { a.AccessLog = l }
+}
+
+// WithPlainText sends responses in plain text to the listed workspaces, for
+// accessibility.
+func WithPlainText(teams TeamSet) AppOption {
+	return func(a *App) { a.PlainText = teams }
+}
+
+// WithUploader posts large results as files. See [Uploader].
+func WithUploader(u *Uploader) AppOption {
+	return func(a *App) { a.Uploader = u }
+}
+
+// WithFederation enables groups shared between workspaces. See
+// [App.Federation].
+func WithFederation(enabled bool) AppOption {
+	return func(a *App) { a.Federation = enabled }
+}
+
+// WithPickAgain adds a "Pick again" button to selections. See [App.PickAgain].
+func WithPickAgain(enabled bool) AppOption {
+	return func(a *App) { a.PickAgain = enabled }
+}
+
+// WithDefaults routes requests that don't name a known operation. See
+// [randomizer.Defaults].
+func WithDefaults(d randomizer.Defaults) AppOption {
+	return func(a *App) { a.Defaults = d }
+}
+
+// WithContentCheck reviews group names, options, and tags before they're
+// saved. See [randomizer.ContentCheck].
+func WithContentCheck(check randomizer.ContentCheck) AppOption {
+	return func(a *App) { a.ContentCheck = check }
+}
+
+// WithDeployment describes the deployment for the /version operation. See
+// [randomizer.Deployment].
+func WithDeployment(d randomizer.Deployment) AppOption {
+	return func(a *App) { a.Deployment = d }
+}
+
+// WithWebhookSender enables /webhook, which sends a channel's selections to a
+// webhook. See [randomizer.WebhookSender].
+func WithWebhookSender(send randomizer.WebhookSender) AppOption {
+	return func(a *App) { a.WebhookSender = send }
+}
+
+// WithRandomness replaces the randomizer's default source of randomness. See
+// [randomizer.Randomness].
+func WithRandomness(r randomizer.Randomness) AppOption {
+	return func(a *App) { a.Randomness = r }
+}
+
+// WithBotUserID limits reaction feedback to messages posted by the app's bot
+// user.
+func WithBotUserID(id string) AppOption {
+	return func(a *App) { a.BotUserID = id }
+}
+
+// WithBudget bounds the time spent on each slash command. See [Budget].
+func WithBudget(b *Budget) AppOption {
+	return func(a *App) { a.Budget = b }
+}
+
+// WithWorkspaces indexes the channels of each workspace, so that its data can
+// be deleted after it uninstalls the app. See [workspace.Registry].
+func WithWorkspaces(r *workspace.Registry) AppOption {
+	return func(a *App) { a.Workspaces = r }
+}
+
+// WithInstallations rejects requests from workspaces that haven't installed
+// the app, and removes a workspace's installation when it uninstalls
+// the app. See [Installer].
+func WithInstallations(tokens TokenStore) AppOption {
+	return func(a *App) { a.Installations = tokens }
+}
+
+// WithAlerts notifies operators when error rates spike. See [alert.Tracker].
+func WithAlerts(t *alert.Tracker) AppOption {
+	return func(a *App) { a.Alerts = t }
+}
+
+// WithHome publishes a test console to each user's App Home tab. See [Home].
+func WithHome(h *Home) AppOption {
+	return func(a *App) { a.Home = h }
+}
+
+// WithActivity publishes an event for each selection and change to an activity
+// feed. See [activity.Feed].
+func WithActivity(f *activity.Feed) AppOption {
+	return func(a *App) { a.Activity = f }
+}
+
+// WithClock replaces the system clock, for example to test time-dependent
+// behavior without waiting.
+func WithClock(c clock.Clock) AppOption {
+	return func(a *App) { a.Clock = c }
+}
+
+// WithLogger logs errors encountered during request handling, including those
+// that the randomizer recovers from.
+func WithLogger(logger *slog.Logger) AppOption {
+	return func(a *App) { a.Logger = logger }
+}
+
+// WithSigningSecret verifies signed requests from Slack, in addition to any
+// requests with a valid legacy token.
+func WithSigningSecret(secret TokenProvider) AppOption {
+	return func(a *App) { a.SigningSecret = secret }
+}
+
+// WithRequireSignature rejects legacy tokens from the listed workspaces.
+func WithRequireSignature(teams TeamSet) AppOption {
+	return func(a *App) { a.RequireSignature = teams }
+}
+
+// WithVerificationTracker tracks how each workspace's requests are verified.
+func WithVerificationTracker(v *VerificationTracker) AppOption {
+	return func(a *App) { a.Verification = v }
+}
+
+// maxRequestBytes bounds the size of a request body from Slack.
+const maxRequestBytes = 1 << 20
+
+// ServeHTTP serves POST requests from Slack, including slash commands, Events
+// API requests, and interactions with the App Home console.
 func (a App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.Header().Add("Allow", http.MethodPost)
@@ -44,19 +314,39 @@ func (a App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Signatures cover the raw body, so we keep a copy of it for verification.
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	if err != nil {
+		a.logErr(err, "Failed to read request body")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if isEventRequest(r) {
+		a.serveEvent(w, r, body)
+		return
+	}
+
 	if err := r.ParseForm(); err != nil {
 		a.logErr(err, "Failed to read POST form")
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	tokenIsValid, err := a.isTokenValid(r.Context(), r.PostForm)
+	if isInteractionRequest(r.PostForm) {
+		a.serveInteraction(w, r, body)
+		return
+	}
+
+	method, err := a.verify(r.Context(), r.Header, body, r.PostForm.Get("token"), r.PostForm.Get("team_id"))
 	if err != nil {
-		a.logErr(err, "Failed to validate token")
+		a.logErr(err, "Failed to verify request")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-	if !tokenIsValid {
+	if method == "" {
+		a.Alerts.Record(r.Context(), alert.Verification)
 		w.WriteHeader(http.StatusForbidden)
 		return
 	}
@@ -64,19 +354,141 @@ func (a App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.PostForm.Get("ssl_check") == "1" {
 		return
 	}
+	r = r.WithContext(withTeam(r.Context(), r.PostForm.Get("team_id")))
+
+	key := retryKey(r.PostForm)
+	if a.RetryCache == nil || key == "" {
+		a.writeResponse(w, a.respond(r.Context(), r.PostForm))
+		return
+	}
+
+	resp, duplicate, err := a.RetryCache.do(r.Context(), key, func() response {
+		return a.respond(r.Context(), r.PostForm)
+	})
+	if err != nil {
+		a.logErr(err, "Failed to wait for original response to retried request")
+		if errors.Is(err, context.DeadlineExceeded) {
+			a.Alerts.Record(r.Context(), alert.Timeout)
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if duplicate && a.Logger != nil {
+		a.Logger.Info("Replaying response to retried request", "retry", r.Header.Get("X-Slack-Retry-Num"))
+	}
+	a.writeResponse(w, resp)
+}
+
+func (a App) respond(ctx context.Context, params url.Values) response {
+	start := time.Now()
+	if err := a.Workspaces.Track(ctx, params.Get("team_id"), params.Get("channel_id")); err != nil {
+		a.logErr(err, "Failed to index workspace channel")
+	}
+
+	budgetCtx, cancel := a.Budget.bound(ctx)
+	team, channel := params.Get("team_id"), params.Get("channel_id")
+	result, err := a.runRandomizer(budgetCtx, params, a.Onboarding.options(team, channel)...)
+	cancel()
+	a.Onboarding.remember(team, channel, result)
+	a.AccessLog.record(ctx, params, start, result, err)
+	if err == nil {
+		a.publishActivity(params, result)
+		a.startDigests(ctx, params, result)
+	}
 
-	result, err := a.runRandomizer(r.Context(), r.PostForm)
+	plain := a.PlainText.Contains(params.Get("team_id"))
 	if err != nil {
 		a.logErr(err, "Failed to run randomizer")
-		a.writeError(w, err)
+		a.trackError(ctx, err)
+		return errorResponse(err).render(plain)
+	}
+
+	var theme randomizer.Theme
+	if !plain && result.Type() == randomizer.Selection {
+		theme = a.theme(ctx, params.Get("team_id"))
+	}
+
+	if !plain && a.Suspense.applies(result) {
+		logger := a.Logger
+		if logger == nil {
+			logger = slog.New(slog.DiscardHandler)
+		}
+		go a.Suspense.reveal(context.WithoutCancel(ctx), params.Get("channel_id"), result, theme, logger)
+		return response{
+			Type: typeEphemeral,
+			Text: "Get ready… :drum_with_drumsticks:",
+		}
+	}
+
+	if a.Uploader.applies(result) {
+		err := a.Uploader.upload(ctx, params.Get("channel_id"), result, plain)
+		if err == nil {
+			return response{
+				Type: typeEphemeral,
+				Text: "That result was too long for a message, so I posted it as a file.",
+			}
+		}
+		a.logErr(err, "Failed to upload large result")
+	}
+
+	resp := resultResponse(result).themed(theme).render(plain)
+	switch {
+	case result.Welcomed():
+		resp = resp.withOnboardingButtons(params.Get("command"))
+	case result.DryRun():
+	case result.Type() == randomizer.Selection && a.PickAgain:
+		resp = resp.withPickAgain(params.Get("command"), params.Get("text"))
+	case result.Type() == randomizer.ProposedPick:
+		resp = resp.withProposalButtons(params.Get("command"), result.ID())
+	case result.More() != "":
+		resp = resp.withShowMore(params.Get("command"), result.More())
+	}
+	return resp
+}
+
+// publishActivity sends selections and changes to the App's activity feed.
+// Dry runs and read-only results aren't published.
+func (a App) publishActivity(params url.Values, result randomizer.Result) {
+	if a.Activity == nil || result.DryRun() {
+		return
+	}
+
+	var kind string
+	switch {
+	case result.Type() == randomizer.Selection, result.Type() == randomizer.Assignment, result.Type() == randomizer.RevealedPick, result.Type() == randomizer.RatifiedPick, result.Type() == randomizer.Teams:
+		kind = activity.KindSelection
+	case result.Changed():
+		kind = activity.KindMutation
+	default:
 		return
 	}
 
-	a.writeResult(w, result)
+	a.Activity.Publish(activity.Event{
+		Kind:     kind,
+		Team:     params.Get("team_id"),
+		Channel:  params.Get("channel_id"),
+		User:     params.Get("user_id"),
+		Command:  params.Get("command"),
+		Text:     params.Get("text"),
+		Result:   result.Type().String(),
+		Message:  result.Message(),
+		Choices:  result.Choices(),
+		ResultID: result.ID(),
+	})
 }
 
-func (a App) isTokenValid(ctx context.Context, params url.Values) (ok bool, _ error) {
-	gotToken := params.Get("token")
+// trackError counts errors from the randomizer that may indicate an outage.
+func (a App) trackError(ctx context.Context, err error) {
+	rerr, ok := err.(randomizer.Error)
+	switch {
+	case ok && errors.Is(rerr.Cause(), context.DeadlineExceeded):
+		a.Alerts.Record(ctx, alert.Timeout)
+	case ok && rerr.StoreUnavailable():
+		a.Alerts.Record(ctx, alert.Store)
+	}
+}
+
+func (a App) isTokenValid(ctx context.Context, gotToken string) (ok bool, _ error) {
 	wantToken, err := a.TokenProvider(ctx)
 	if err != nil {
 		return false, err
@@ -88,20 +500,105 @@ func (a App) isTokenValid(ctx context.Context, params url.Values) (ok bool, _ er
 	return
 }
 
-func (a App) runRandomizer(ctx context.Context, params url.Values) (randomizer.Result, error) {
+// RunAs runs a slash command as if a user had run it in a channel, for
+// operators reproducing the user's problem through the admin API. It skips
+// request verification, so it must only be reachable by operators. The
+// randomizer records the operator in its audit trail, and opens the result
+// with a notice that the operator ran it. Nothing is posted to the channel,
+// apart from any webhook delivery that the command triggers.
+func (a App) RunAs(ctx context.Context, operator string, req admin.RunAsRequest) (randomizer.Result, error) {
+	params := url.Values{
+		"team_id":    {req.Team},
+		"channel_id": {req.Channel},
+		"user_id":    {req.User},
+		"command":    {req.Command},
+		"text":       {req.Text},
+	}
+	start := time.Now()
+	ctx = withTeam(ctx, req.Team)
+	result, err := a.runRandomizer(ctx, params, randomizer.WithOperator(operator))
+	a.AccessLog.record(ctx, params, start, result, err)
+	return result, err
+}
+
+func (a App) runRandomizer(ctx context.Context, params url.Values, extra ...randomizer.AppOption) (randomizer.Result, error) {
 	var (
 		name      = params.Get("command")
 		channelID = params.Get("channel_id")
 		args      = strings.Fields(params.Get("text"))
 	)
 
-	app := randomizer.NewApp(name, a.StoreFactory(channelID))
+	opts := a.randomizerOptions(params.Get("team_id"), params.Get("user_id"))
+	if a.Scheduler != nil {
+		opts = append(opts, randomizer.WithSchedules(a.scheduleStore(), scheduleTarget(params.Get("team_id"), channelID)))
+	}
+	if a.Audit != nil {
+		opts = append(opts, a.Audit.option(params.Get("team_id"), channelID))
+	}
+	app := randomizer.NewApp(name, a.StoreFactory(channelID), append(opts, extra...)...)
 	return app.Main(ctx, args)
 }
 
+// randomizerOptions configures the randomizer for a request from a user in a
+// workspace.
+func (a App) randomizerOptions(team, user string) []randomizer.AppOption {
+	opts := []randomizer.AppOption{
+		randomizer.WithUser(user),
+		randomizer.WithDefaults(a.Defaults),
+		randomizer.WithDeployment(a.Deployment),
+	}
+	if a.Logger != nil {
+		opts = append(opts, randomizer.WithLogger(a.Logger))
+	}
+	if a.UserNames != nil {
+		opts = append(opts, randomizer.WithNameResolver(a.UserNames.Resolve))
+		if a.EmailExport.Contains(team) {
+			opts = append(opts, randomizer.WithEmailResolver(a.UserNames.Email))
+		}
+	}
+	if a.ContentCheck != nil {
+		opts = append(opts, randomizer.WithContentCheck(a.ContentCheck))
+	}
+	if a.WebhookSender != nil {
+		opts = append(opts, randomizer.WithWebhookSender(a.WebhookSender))
+	}
+	if a.Randomness != nil {
+		opts = append(opts, randomizer.WithRandomness(a.Randomness))
+	}
+	if a.Budget != nil {
+		opts = append(opts, randomizer.WithDeferredWrites(a.Budget.start))
+	}
+	if a.Clock != nil {
+		opts = append(opts, randomizer.WithClock(a.Clock))
+	}
+	if team != "" {
+		opts = append(opts, randomizer.WithWorkspaceStore(a.StoreFactory(workspace.Partition(team))))
+		if a.Federation {
+			opts = append(opts, randomizer.WithFederation(a.StoreFactory(randomizer.FederationPartition), team))
+		}
+	}
+	return opts
+}
+
 type response struct {
-	Type responseType `json:"response_type"`
-	Text string       `json:"text"`
+	Type        responseType     `json:"response_type"`
+	Text        string           `json:"text"`
+	Mrkdwn      *bool            `json:"mrkdwn,omitempty"`
+	Attachments []attachment     `json:"attachments,omitempty"`
+	Blocks      []map[string]any `json:"blocks,omitempty"`
+	// ReplaceOriginal applies to responses posted to an interaction's response
+	// URL, which replace the message that was interacted with by default.
+	ReplaceOriginal *bool `json:"replace_original,omitempty"`
+}
+
+// render returns the response as it should be sent, in plain text if
+// requested.
+func (r response) render(plain bool) response {
+	if plain {
+		r.Text = mrkdwn.PlainText(r.Text)
+		r.Mrkdwn = new(false)
+	}
+	return r
 }
 
 type responseType string
@@ -111,29 +608,52 @@ const (
 	typeInChannel responseType = "in_channel"
 )
 
-func (a App) writeResult(w http.ResponseWriter, result randomizer.Result) {
+func resultResponse(result randomizer.Result) response {
 	rtype := typeEphemeral
 	switch result.Type() {
-	case randomizer.Selection, randomizer.SavedGroup, randomizer.DeletedGroup:
+	case randomizer.Selection, randomizer.SavedGroup, randomizer.DeletedGroup, randomizer.TaggedOption, randomizer.ReorderedGroup, randomizer.Assignment, randomizer.UpdatedTheme, randomizer.SealedPick, randomizer.RevealedPick, randomizer.ProposedPick, randomizer.RatifiedPick, randomizer.Teams, randomizer.Sampled, randomizer.RolledDice, randomizer.PickedNumbers:
 		rtype = typeInChannel
 	}
+	if result.Private() {
+		rtype = typeEphemeral
+	}
 
-	a.writeResponse(w, response{
-		Text: result.Message(),
+	return response{
+		Text: resultMessage(result),
 		Type: rtype,
-	})
+	}
 }
 
-func (a App) writeError(w http.ResponseWriter, err error) {
-	a.writeResponse(w, response{
+// resultMessage returns the message for a result, rendering the result
+// template that applies to a selection in place of its usual phrasing. Since
+// templates are checked when they're saved, a template that fails to render
+// anyway falls back to the usual phrasing.
+func resultMessage(result randomizer.Result) string {
+	if result.Template() == "" {
+		return result.Message()
+	}
+	text, err := randomizer.RenderResultTemplate(result.Template(), result.Fields())
+	if err != nil {
+		return result.Message()
+	}
+	return result.Rephrased(text)
+}
+
+func errorResponse(err error) response {
+	return response{
 		Text: err.(randomizer.Error).HelpText(),
 		Type: typeEphemeral,
-	})
+	}
 }
 
 func (a App) writeResponse(w http.ResponseWriter, response response) {
 	w.Header().Add("Content-Type", "application/json")
-	err := json.NewEncoder(w).Encode(response)
+
+	// Slack's formatting sequences (like "<@U123>" mentions) are common in
+	// responses, and don't need the escaping meant for embedding JSON in HTML.
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(response)
 	if err != nil {
 		a.logErr(err, "Failed to write response")
 	}
@@ -1,12 +1,20 @@
 package slack
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/featherbread/randomizer/internal/activity"
+	"github.com/featherbread/randomizer/internal/admin"
+	"github.com/featherbread/randomizer/internal/alert"
+	"github.com/featherbread/randomizer/internal/clock/clocktest"
 	"github.com/featherbread/randomizer/internal/randomizer"
 	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
 )
@@ -30,11 +38,63 @@ func TestValidRequests(t *testing.T) {
 	if resp.Result().StatusCode != http.StatusOK {
 		t.Errorf("invalid status: got %v, want %v", resp.Result().StatusCode, http.StatusOK)
 	}
-	if len(store) < 1 {
+	if _, ok := store["test"]; !ok {
 		t.Error("/save command failed to save a new group in the store")
 	}
 }
 
+func TestRetriedRequests(t *testing.T) {
+	store := make(rndtest.Store)
+	app := NewApp(
+		StaticToken("right"),
+		func(_ string) randomizer.Store { return store },
+		WithRetryCache(NewRetryCache(DefaultRetryCacheTTL)),
+	)
+
+	params := makeTestParams("/save test one two")
+	params.Set("trigger_id", "12345.67890")
+
+	var bodies []string
+	for range 2 {
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(params.Encode()))
+		req.Header = http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}
+		app.ServeHTTP(resp, req)
+		bodies = append(bodies, resp.Body.String())
+
+		// If the retry re-ran the randomizer, it would save the group again.
+		delete(store, "test")
+	}
+
+	if bodies[0] != bodies[1] {
+		t.Errorf("retry got a different response\noriginal: %s\nretry:    %s", bodies[0], bodies[1])
+	}
+	if _, ok := store["test"]; ok {
+		t.Error("retried /save command re-ran the randomizer")
+	}
+}
+
+func TestRetryCacheExpiry(t *testing.T) {
+	cache := NewRetryCache(time.Minute)
+	clock := clocktest.New(time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC))
+	cache.clock = clock
+
+	var calls int
+	respond := func() response {
+		calls++
+		return response{Text: "done"}
+	}
+	cache.do(t.Context(), "key", respond)
+	clock.Advance(30 * time.Second)
+	if _, duplicate, _ := cache.do(t.Context(), "key", respond); !duplicate || calls != 1 {
+		t.Errorf("retry within the TTL got duplicate = %v after %d calls", duplicate, calls)
+	}
+	clock.Advance(time.Minute)
+	if _, duplicate, _ := cache.do(t.Context(), "key", respond); duplicate || calls != 2 {
+		t.Errorf("retry after the TTL got duplicate = %v after %d calls", duplicate, calls)
+	}
+}
+
 func TestInvalidMethod(t *testing.T) {
 	app := App{
 		TokenProvider: StaticToken("right"),
@@ -77,6 +137,187 @@ func TestInvalidToken(t *testing.T) {
 	}
 }
 
+func TestStoreErrorAlerts(t *testing.T) {
+	var notified []alert.Alert
+	app := App{
+		TokenProvider: StaticToken("right"),
+		StoreFactory:  func(_ string) randomizer.Store { return rndtest.Store(nil) },
+		Alerts: &alert.Tracker{
+			Thresholds: map[alert.Kind]int{alert.Store: 2},
+			Notifiers:  []alert.Notifier{notifierFunc(func(a alert.Alert) { notified = append(notified, a) })},
+		},
+	}
+
+	for range 3 {
+		params := makeTestParams("/show test")
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(params.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		app.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if len(notified) != 1 || notified[0].Kind != alert.Store {
+		t.Errorf("got alerts %+v, want a single store alert", notified)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	gate := make(chan struct{})
+	store := gatedStore{SyncStore: &rndtest.SyncStore{Store: rndtest.Store{"test": {"one", "two"}}}, gate: gate}
+	budget := NewBudget(100 * time.Millisecond)
+	app := NewApp(
+		StaticToken("right"),
+		func(_ string) randomizer.Store { return store },
+		WithBudget(budget),
+	)
+
+	// If the history write weren't deferred, the gate would block the response
+	// until the test times out.
+	params := makeTestParams("test")
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(params.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp := httptest.NewRecorder()
+	app.ServeHTTP(resp, req)
+	if !strings.Contains(resp.Body.String(), "I randomized and got") {
+		t.Fatalf("got response %q, want a selection", resp.Body.String())
+	}
+
+	close(gate)
+	if err := budget.Wait(t.Context()); err != nil {
+		t.Fatalf("waiting for deferred writes: %v", err)
+	}
+	if history, _ := store.Get(t.Context(), "/history/test"); len(history) != 1 {
+		t.Errorf("got history %v after deferred writes, want 1 entry", history)
+	}
+}
+
+func TestTheme(t *testing.T) {
+	partitions := make(map[string]rndtest.Store)
+	app := NewApp(
+		StaticToken("right"),
+		func(partition string) randomizer.Store {
+			if partitions[partition] == nil {
+				partitions[partition] = make(rndtest.Store)
+			}
+			return partitions[partition]
+		},
+	)
+	send := func(team, text string) response {
+		params := makeTestParams(text)
+		params.Set("team_id", team)
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(params.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp := httptest.NewRecorder()
+		app.ServeHTTP(resp, req)
+		var got response
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	send("T111", "/theme emoji :tada:")
+	send("T111", "/theme color #36a64f")
+	if theme := partitions["workspace-T111"]["/theme"]; len(theme) != 2 {
+		t.Fatalf("theme not saved for the workspace: %v", partitions)
+	}
+
+	got := send("T111", "one two")
+	if len(got.Attachments) != 1 || got.Attachments[0].Color != "#36a64f" ||
+		!strings.HasPrefix(got.Attachments[0].Text, ":tada: I randomized and got") {
+		t.Errorf("selection not themed: %+v", got)
+	}
+
+	got = send("T222", "one two")
+	if len(got.Attachments) != 0 || !strings.HasPrefix(got.Text, "I randomized and got") {
+		t.Errorf("another workspace's selection was themed: %+v", got)
+	}
+}
+
+func TestResultTemplate(t *testing.T) {
+	store := make(rndtest.Store)
+	app := NewApp(StaticToken("right"), func(_ string) randomizer.Store { return store })
+	send := func(text string) response {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(makeTestParams(text).Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp := httptest.NewRecorder()
+		app.ServeHTTP(resp, req)
+		var got response
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	send("/save lunch pizza pizza")
+	send("/config template :game_die: {{.Choice}} has been chosen from {{.Group}}!")
+	got := send("lunch")
+	if !strings.HasPrefix(got.Text, ":game_die: pizza has been chosen from lunch!\n(Result ID: ") {
+		t.Errorf("got text %q, want the rendered template", got.Text)
+	}
+}
+
+func TestActivity(t *testing.T) {
+	store := make(rndtest.Store)
+	feed := new(activity.Feed)
+	app := NewApp(StaticToken("right"), func(_ string) randomizer.Store { return store }, WithActivity(feed))
+
+	for _, text := range []string{
+		"/save test one two",
+		"/list",
+		"/save --dry-run test one two three",
+		"test",
+		"--dry-run test",
+		"/delete test",
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(makeTestParams(text).Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		app.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// A canceled request streams the feed's history and returns.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/events", nil)
+	req.Header.Set("Authorization", "Bearer admin")
+	feed.Handler(admin.StaticToken("admin"), nil).ServeHTTP(resp, req)
+
+	var got []string
+	for line := range strings.Lines(resp.Body.String()) {
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			var e activity.Event
+			if err := json.Unmarshal([]byte(data), &e); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, e.Kind+" "+e.Result)
+		}
+	}
+	want := []string{"mutation SavedGroup", "selection Selection", "mutation DeletedGroup"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got events %q, want %q", got, want)
+	}
+}
+
+// gatedStore blocks writes to records until its gate is closed.
+type gatedStore struct {
+	*rndtest.SyncStore
+	gate chan struct{}
+}
+
+func (s gatedStore) Put(ctx context.Context, name string, options []string) error {
+	if strings.HasPrefix(name, "/") {
+		<-s.gate
+	}
+	return s.SyncStore.Put(ctx, name, options)
+}
+
+type notifierFunc func(alert.Alert)
+
+func (f notifierFunc) Notify(_ context.Context, a alert.Alert) error {
+	f(a)
+	return nil
+}
+
 func makeTestParams(text string) url.Values {
 	params := make(url.Values)
 	params.Add("token", "right")
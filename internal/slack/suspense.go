@@ -0,0 +1,156 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+// DefaultSuspenseInterval paces message updates to stay under Slack's limit of
+// roughly one message per second per channel.
+const DefaultSuspenseInterval = 1500 * time.Millisecond
+
+// Suspense reveals selections gradually, by posting a message that eliminates
+// candidates over several updates before announcing the winner.
+//
+// Since the reveal continues after the slash command response is sent,
+// Suspense is only suitable for long-running servers. It is not suitable for
+// AWS Lambda, which freezes the environment after each response.
+type Suspense struct {
+	// Client posts and updates the suspenseful message, and must have a token
+	// with the chat:write scope.
+	Client WebClient
+	// Interval sets the delay between message updates. If zero, it defaults to
+	// DefaultSuspenseInterval.
+	Interval time.Duration
+	// MinChoices sets the smallest selection that will be revealed with
+	// suspense. Smaller selections are answered immediately.
+	MinChoices int
+	// Outbox, if non-nil, records the winner's announcement before each reveal,
+	// so that a sweep can post it if the reveal fails or never finishes.
+	Outbox *Outbox
+}
+
+func (s *Suspense) applies(result randomizer.Result) bool {
+	// Eliminating candidates down to a single winner doesn't suit selections
+	// that pick several, and a reveal in the channel doesn't suit private
+	// selections.
+	return s != nil && result.Type() == randomizer.Selection && !result.Picked() && !result.Private() &&
+		len(result.Choices()) >= max(s.MinChoices, 3)
+}
+
+// reveal posts the stages of a suspenseful selection to the channel, as the
+// bot's themed name and icon if the workspace has them. Customizing these
+// requires the chat:write.customize scope.
+func (s *Suspense) reveal(ctx context.Context, channelID string, result randomizer.Result, theme randomizer.Theme, logger *slog.Logger) {
+	ctx, span := tracer.Start(ctx, "slack.Suspense.reveal")
+	defer span.End()
+
+	interval := s.Interval
+	if interval == 0 {
+		interval = DefaultSuspenseInterval
+	}
+
+	choices := result.Choices()
+	stages := suspenseStages(len(choices))
+
+	final := resultMessage(result)
+	if theme.Emoji != "" {
+		final = theme.Emoji + " " + final
+	}
+	outboxID, err := s.Outbox.add(ctx, outboxMessage{
+		Team:      teamFromContext(ctx),
+		Channel:   channelID,
+		Text:      final,
+		Username:  theme.Username,
+		IconEmoji: theme.IconEmoji,
+	})
+	if err != nil {
+		logger.Warn("Failed to record suspenseful reveal in outbox", "err", err)
+	}
+
+	var posted struct {
+		Channel string `json:"channel"`
+		TS      string `json:"ts"`
+	}
+	message := map[string]string{
+		"channel": channelID,
+		"text":    suspenseText(choices[:stages[0]]),
+	}
+	if theme.Username != "" {
+		message["username"] = theme.Username
+	}
+	if theme.IconEmoji != "" {
+		message["icon_emoji"] = theme.IconEmoji
+	}
+	err = s.call(ctx, "chat.postMessage", message, &posted)
+	if err != nil {
+		span.RecordError(err)
+		logger.Error("Failed to start suspenseful reveal", "err", err)
+		return
+	}
+
+	for _, n := range stages[1:] {
+		time.Sleep(interval)
+		err := s.call(ctx, "chat.update", map[string]string{
+			"channel": posted.Channel,
+			"ts":      posted.TS,
+			"text":    suspenseText(choices[:n]),
+		}, nil)
+		if err != nil {
+			logger.Warn("Failed to update suspenseful reveal", "err", err)
+		}
+	}
+
+	time.Sleep(interval)
+	err = s.call(ctx, "chat.update", map[string]string{
+		"channel": posted.Channel,
+		"ts":      posted.TS,
+		"text":    final,
+	}, nil)
+	if err != nil {
+		span.RecordError(err)
+		logger.Error("Failed to finish suspenseful reveal", "err", err)
+		return
+	}
+	if err := s.Outbox.delivered(ctx, outboxID); err != nil {
+		logger.Warn("Failed to remove finished reveal from outbox", "err", err)
+	}
+}
+
+// call invokes the Web API, waiting out a single rate limit response if
+// necessary.
+func (s *Suspense) call(ctx context.Context, method string, payload, out any) error {
+	err := s.Client.Call(ctx, method, payload, out)
+	var rateLimited RateLimitedError
+	if errors.As(err, &rateLimited) {
+		time.Sleep(rateLimited.RetryAfter)
+		err = s.Client.Call(ctx, method, payload, out)
+	}
+	return err
+}
+
+// suspenseStages returns the decreasing numbers of candidates to show before
+// revealing the winner, with up to 4 stages in total.
+func suspenseStages(n int) []int {
+	stages := []int{n}
+	for remaining := n; len(stages) < 4 && remaining > 2; {
+		remaining = (remaining + 1) / 2
+		stages = append(stages, remaining)
+	}
+	return stages
+}
+
+func suspenseText(remaining []string) string {
+	names := make([]string, len(remaining))
+	for i, choice := range remaining {
+		names[i] = "*" + choice + "*"
+	}
+	return fmt.Sprintf(":drum_with_drumsticks: Eliminating candidates… %d left: %s",
+		len(remaining), strings.Join(names, ", "))
+}
@@ -0,0 +1,64 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+)
+
+func TestSuspenseReveal(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		methods []string
+		texts   []string
+		icons   []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		methods = append(methods, strings.TrimPrefix(r.URL.Path, "/"))
+		texts = append(texts, payload["text"])
+		icons = append(icons, payload["icon_emoji"])
+		mu.Unlock()
+		w.Write([]byte(`{"ok": true, "channel": "C12345678", "ts": "1.2"}`))
+	}))
+	defer srv.Close()
+
+	app := randomizer.NewApp("/randomize", rndtest.Store{})
+	result, err := app.Main(context.Background(), []string{"one", "two", "three", "four", "five"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	suspense := &Suspense{
+		Client:   WebClient{Token: "xoxb-test", BaseURL: srv.URL + "/"},
+		Interval: time.Millisecond,
+	}
+	theme := randomizer.Theme{Emoji: ":tada:", IconEmoji: ":robot_face:"}
+	suspense.reveal(context.Background(), "C12345678", result, theme, slog.New(slog.DiscardHandler))
+
+	wantMethods := []string{"chat.postMessage", "chat.update", "chat.update", "chat.update"}
+	if !slices.Equal(methods, wantMethods) {
+		t.Errorf("got calls %v, want %v", methods, wantMethods)
+	}
+	if !strings.Contains(texts[0], "5 left") {
+		t.Errorf("first message does not show all candidates: %q", texts[0])
+	}
+	if icons[0] != ":robot_face:" {
+		t.Errorf("first message has icon %q, want the theme's icon", icons[0])
+	}
+	if last := texts[len(texts)-1]; last != ":tada: "+result.Message() {
+		t.Errorf("final message %q does not reveal themed result %q", last, result.Message())
+	}
+}
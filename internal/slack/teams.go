@@ -0,0 +1,34 @@
+package slack
+
+import (
+	"os"
+	"strings"
+)
+
+// TeamSet lists Slack workspaces by team ID, for settings that apply to some
+// workspaces in an install but not others. The special ID "*" includes every
+// workspace.
+type TeamSet map[string]bool
+
+// TeamSetFromEnv returns the workspaces listed in a comma-separated
+// environment variable, or nil if it is unset.
+func TeamSetFromEnv(name string) TeamSet {
+	env := os.Getenv(name)
+	if env == "" {
+		return nil
+	}
+
+	teams := make(TeamSet)
+	for team := range strings.SplitSeq(env, ",") {
+		if team = strings.TrimSpace(team); team != "" {
+			teams[team] = true
+		}
+	}
+	return teams
+}
+
+// Contains indicates whether the set includes the workspace with the provided
+// team ID.
+func (s TeamSet) Contains(teamID string) bool {
+	return s["*"] || (teamID != "" && s[teamID])
+}
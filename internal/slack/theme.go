@@ -0,0 +1,51 @@
+package slack
+
+import (
+	"context"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/workspace"
+)
+
+// attachment is a legacy message attachment, which remains the only way for a
+// slash command response to show a colored accent bar.
+type attachment struct {
+	Color    string           `json:"color,omitempty"`
+	Text     string           `json:"text"`
+	Fallback string           `json:"fallback,omitempty"`
+	MrkdwnIn []string         `json:"mrkdwn_in,omitempty"`
+	Blocks   []map[string]any `json:"blocks,omitempty"`
+}
+
+// theme returns the theme that a workspace configured with /theme, or the
+// default theme if it can't be loaded. A broken theme shouldn't break the
+// selection it decorates.
+func (a App) theme(ctx context.Context, team string) randomizer.Theme {
+	if team == "" {
+		return randomizer.Theme{}
+	}
+	theme, err := randomizer.LoadTheme(ctx, a.StoreFactory(workspace.Partition(team)))
+	if err != nil {
+		a.logErr(err, "Failed to load workspace theme")
+		return randomizer.Theme{}
+	}
+	return theme
+}
+
+// themed decorates a selection response with a workspace's result emoji and
+// accent color.
+func (r response) themed(theme randomizer.Theme) response {
+	if theme.Emoji != "" {
+		r.Text = theme.Emoji + " " + r.Text
+	}
+	if theme.Color != "" {
+		r.Attachments = []attachment{{
+			Color:    theme.Color,
+			Text:     r.Text,
+			Fallback: r.Text,
+			MrkdwnIn: []string{"text"},
+		}}
+		r.Text = ""
+	}
+	return r
+}
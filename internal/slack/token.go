@@ -9,9 +9,9 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
-	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/featherbread/randomizer/internal/awsconfig"
+	"github.com/featherbread/randomizer/internal/cache"
 )
 
 const DefaultAWSParameterTTL = 2 * time.Minute
@@ -28,7 +28,8 @@ type TokenProvider func(ctx context.Context) (string, error)
 // If SLACK_TOKEN_SSM_NAME is set, it returns an AWS SSM token provider,
 // with the TTL optionally set by SLACK_TOKEN_SSM_TTL.
 //
-// Otherwise, it returns an error.
+// Otherwise, it returns nil, for deployments that verify requests only with a
+// signing secret (see [SigningSecretFromEnv]).
 func TokenProviderFromEnv() (TokenProvider, error) {
 	if token, ok := os.LookupEnv("SLACK_TOKEN"); ok {
 		return StaticToken(token), nil
@@ -42,7 +43,24 @@ func TokenProviderFromEnv() (TokenProvider, error) {
 		return AWSParameter(ssmName, ttl), nil
 	}
 
-	return nil, errors.New("missing SLACK_TOKEN or SLACK_TOKEN_SSM_NAME in environment")
+	return nil, nil
+}
+
+// VerifiersFromEnv returns the legacy token provider and signing secret
+// provider configured by the environment, and returns an error if neither is
+// configured.
+func VerifiersFromEnv() (token, signingSecret TokenProvider, err error) {
+	if token, err = TokenProviderFromEnv(); err != nil {
+		return nil, nil, err
+	}
+	if signingSecret, err = SigningSecretFromEnv(); err != nil {
+		return nil, nil, err
+	}
+	if token == nil && signingSecret == nil {
+		return nil, nil, errors.New(
+			"missing SLACK_TOKEN, SLACK_TOKEN_SSM_NAME, SLACK_SIGNING_SECRET, or SLACK_SIGNING_SECRET_SSM_NAME in environment")
+	}
+	return token, signingSecret, nil
 }
 
 func ssmTTLFromEnv() (time.Duration, error) {
@@ -68,39 +86,13 @@ func StaticToken(token string) TokenProvider {
 
 // AWSParameter retrieves the expected value of the verification token from the
 // AWS SSM Parameter Store, decrypting it if necessary, and caches the retrieved
-// token value for the provided TTL.
+// token value for roughly the provided TTL.
+//
+// Once the TTL expires, the cached token continues to serve requests for up to
+// another TTL while a fresh copy loads in the background, so that an SSM
+// outage or slow response doesn't hold up every request at expiry time.
 func AWSParameter(name string, ttl time.Duration) TokenProvider {
-	var (
-		lock   = make(chan struct{}, 1)
-		token  string
-		expiry time.Time
-	)
-
-	return func(ctx context.Context) (string, error) {
-		var cached bool
-
-		ctx, span := tracer.Start(ctx, "slack.AWSParameter")
-		defer span.End()
-
-		select {
-		case lock <- struct{}{}:
-			defer func() {
-				<-lock
-				span.SetAttributes(
-					attribute.Bool("randomizer.slack.ssm.cached", cached),
-					attribute.Int64("randomizer.slack.ssm.expiry", expiry.Unix()))
-			}()
-
-		case <-ctx.Done():
-			span.RecordError(ctx.Err())
-			return "", ctx.Err()
-		}
-
-		if time.Now().Before(expiry) {
-			cached = true
-			return token, nil
-		}
-
+	token := cache.New(func(ctx context.Context) (string, error) {
 		cfg, err := awsconfig.New(ctx)
 		if err != nil {
 			return "", err
@@ -113,9 +105,56 @@ func AWSParameter(name string, ttl time.Duration) TokenProvider {
 		if err != nil {
 			return "", fmt.Errorf("loading Slack token parameter: %w", err)
 		}
+		return *output.Parameter.Value, nil
+	}, cache.Options{TTL: ttl, Jitter: 0.1, Stale: ttl})
 
-		token = *output.Parameter.Value
-		expiry = time.Now().Add(ttl)
-		return token, nil
+	return func(ctx context.Context) (string, error) {
+		ctx, span := tracer.Start(ctx, "slack.AWSParameter")
+		defer span.End()
+
+		value, err := token.Get(ctx)
+		if err != nil {
+			span.RecordError(err)
+		}
+		return value, err
+	}
+}
+
+// BotTokenProvider provides the bot token that the randomizer uses to call the
+// Slack Web API, for features like gradual reveals and user name lookups.
+type BotTokenProvider func(ctx context.Context) (string, error)
+
+// secretsManagerPrefix is the SSM parameter path that references AWS Secrets
+// Manager secrets, letting the SSM client read them without a separate SDK.
+const secretsManagerPrefix = "/aws/reference/secretsmanager/"
+
+// BotTokenProviderFromEnv returns a BotTokenProvider based on available
+// environment variables.
+//
+// If SLACK_BOT_TOKEN is set, it returns a static token provider.
+//
+// If SLACK_BOT_TOKEN_SSM_NAME is set, it returns an AWS SSM token provider. If
+// SLACK_BOT_TOKEN_SECRET_ID is set, it returns a provider for the AWS Secrets
+// Manager secret with that ID. Either way, the TTL is optionally set by
+// SLACK_TOKEN_SSM_TTL.
+//
+// Otherwise, it returns nil, as features that need a bot token are optional.
+func BotTokenProviderFromEnv() (BotTokenProvider, error) {
+	if token, ok := os.LookupEnv("SLACK_BOT_TOKEN"); ok {
+		return BotTokenProvider(StaticToken(token)), nil
+	}
+
+	name, ok := os.LookupEnv("SLACK_BOT_TOKEN_SSM_NAME")
+	if secretID, isSecret := os.LookupEnv("SLACK_BOT_TOKEN_SECRET_ID"); !ok && isSecret {
+		name, ok = secretsManagerPrefix+secretID, true
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	ttl, err := ssmTTLFromEnv()
+	if err != nil {
+		return nil, err
 	}
+	return BotTokenProvider(AWSParameter(name, ttl)), nil
 }
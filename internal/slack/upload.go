@@ -0,0 +1,129 @@
+package slack
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/mrkdwn"
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+// Defaults for an Uploader.
+const (
+	// DefaultUploadMinChars is a little under the length at which Slack starts
+	// collapsing messages behind a "Show more" link, and well under the length
+	// at which it truncates them.
+	DefaultUploadMinChars = 3500
+	// DefaultUploadTimeout leaves room within Slack's 3 second limit on slash
+	// command responses to fall back to an ordinary message.
+	DefaultUploadTimeout = 2 * time.Second
+)
+
+// maxSummaryChars bounds the line of a large result that its summary keeps.
+const maxSummaryChars = 300
+
+// Uploader posts the full text of large selections, assignments, and teams to the
+// channel as a file, with a short summary as its comment, rather than as a
+// message that Slack would collapse or truncate. If an upload fails, the
+// result is sent as an ordinary message instead.
+//
+// Uploads finish before the slash command response, so they work on AWS
+// Lambda as well as long-running servers.
+type Uploader struct {
+	// Client uploads and shares files, and must have a token with the
+	// files:write scope. The bot must be a member of the channel to share
+	// files in it.
+	Client WebClient
+	// MinChars sets the length of the shortest result that is uploaded. If
+	// zero, it defaults to DefaultUploadMinChars.
+	MinChars int
+	// Timeout bounds each upload. If zero, it defaults to DefaultUploadTimeout.
+	Timeout time.Duration
+	// HTTPClient overrides http.DefaultClient for sending file contents.
+	HTTPClient *http.Client
+}
+
+func (u *Uploader) applies(result randomizer.Result) bool {
+	if u == nil || result.DryRun() || result.Private() {
+		return false
+	}
+	switch result.Type() {
+	case randomizer.Selection, randomizer.Assignment, randomizer.Teams:
+		return len(result.Message()) >= cmp.Or(u.MinChars, DefaultUploadMinChars)
+	}
+	return false
+}
+
+// upload shares the full text of a result in a channel as a file, with a
+// summary of the result as its comment.
+func (u *Uploader) upload(ctx context.Context, channelID string, result randomizer.Result, plain bool) error {
+	ctx, span := tracer.Start(ctx, "slack.Uploader.upload")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, cmp.Or(u.Timeout, DefaultUploadTimeout))
+	defer cancel()
+
+	// Formatting characters would show up literally in a text file.
+	content := []byte(mrkdwn.PlainText(result.Message()))
+	filename := "result.txt"
+	if id := result.ID(); id != "" {
+		filename = "result-" + id + ".txt"
+	}
+
+	var target struct {
+		UploadURL string `json:"upload_url"`
+		FileID    string `json:"file_id"`
+	}
+	err := u.Client.Call(ctx, "files.getUploadURLExternal", url.Values{
+		"filename": {filename},
+		"length":   {strconv.Itoa(len(content))},
+	}, &target)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.UploadURL, bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	client := u.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("uploading file contents: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("uploading file contents: HTTP status %s", resp.Status)
+	}
+
+	summary := uploadSummary(result)
+	if plain {
+		summary = mrkdwn.PlainText(summary)
+	}
+	return u.Client.Call(ctx, "files.completeUploadExternal", map[string]any{
+		"files":           []map[string]string{{"id": target.FileID, "title": "Full result"}},
+		"channel_id":      channelID,
+		"initial_comment": summary,
+	}, nil)
+}
+
+// uploadSummary returns the first line of a result's message, shortened if
+// necessary, along with its result ID.
+func uploadSummary(result randomizer.Result) string {
+	first, _, _ := strings.Cut(result.Message(), "\n")
+	summary := truncate(first, maxSummaryChars) + "\n:page_facing_up: The full result is in the attached file."
+	if id := result.ID(); id != "" {
+		summary += fmt.Sprintf("\n(Result ID: %s)", id)
+	}
+	return summary
+}
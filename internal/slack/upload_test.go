@@ -0,0 +1,87 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+)
+
+func TestUploader(t *testing.T) {
+	var (
+		failing  bool
+		uploaded string
+		shared   struct {
+			ChannelID      string `json:"channel_id"`
+			InitialComment string `json:"initial_comment"`
+		}
+	)
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/files.getUploadURLExternal":
+			if failing {
+				w.Write([]byte(`{"ok": false, "error": "not_allowed_token_type"}`))
+				return
+			}
+			fmt.Fprintf(w, `{"ok": true, "upload_url": %q, "file_id": "F1"}`, srv.URL+"/upload")
+		case "/upload":
+			body, _ := io.ReadAll(r.Body)
+			uploaded = string(body)
+		case "/files.completeUploadExternal":
+			json.NewDecoder(r.Body).Decode(&shared)
+			w.Write([]byte(`{"ok": true}`))
+		default:
+			t.Errorf("unexpected call to %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	var options []string
+	for i := range 120 {
+		options = append(options, fmt.Sprintf("option%03d", i))
+	}
+	text := "/pick 100 " + strings.Join(options, " ")
+	app := NewApp(
+		StaticToken("right"),
+		func(_ string) randomizer.Store { return make(rndtest.Store) },
+		WithUploader(&Uploader{Client: WebClient{Token: "xoxb-test", BaseURL: srv.URL + "/"}, MinChars: 1000}),
+	)
+	send := func(text string) response {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(makeTestParams(text).Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp := httptest.NewRecorder()
+		app.ServeHTTP(resp, req)
+		var got response
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	got := send(text)
+	if got.Type != typeEphemeral || !strings.Contains(got.Text, "posted it as a file") {
+		t.Errorf("got response %+v, want a note about the file", got)
+	}
+	if strings.Count(uploaded, "option") != 100 || strings.Contains(uploaded, "*") {
+		t.Errorf("uploaded file doesn't hold the full result in plain text: %q", uploaded)
+	}
+	if shared.ChannelID != "C12345678" || !strings.Contains(shared.InitialComment, "full result is in the attached file") {
+		t.Errorf("file shared with %+v", shared)
+	}
+
+	if got := send("one two"); got.Type != typeInChannel || !strings.Contains(got.Text, "I randomized and got") {
+		t.Errorf("small result not sent as a message: %+v", got)
+	}
+
+	failing = true
+	if got := send(text); got.Type != typeInChannel || strings.Count(got.Text, "option") != 100 {
+		t.Errorf("failed upload didn't fall back to a message: %+v", got)
+	}
+}
@@ -0,0 +1,99 @@
+package slack
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/cache"
+)
+
+// DefaultUserNameTTL is the default time for which UserNames caches each
+// user's display name.
+const DefaultUserNameTTL = time.Hour
+
+// UserNames looks up the display names of Slack users through the users.info
+// Web API method, which requires the users:read scope. It caches names so that
+// rendering a group full of mentions doesn't take a call for every member.
+//
+// UserNames can also look up email addresses, which requires the
+// users:read.email scope as well.
+type UserNames struct {
+	Client WebClient
+	// TTL overrides DefaultUserNameTTL.
+	TTL time.Duration
+
+	mu    sync.Mutex
+	names map[string]*cache.Value[userProfile]
+}
+
+type userProfile struct {
+	Name  string
+	Email string
+}
+
+// Resolve returns the display name of the user with the provided ID, falling
+// back to the user's full name or username if they haven't set one.
+func (u *UserNames) Resolve(ctx context.Context, id string) (string, error) {
+	profile, err := u.value(id).Get(ctx)
+	return profile.Name, err
+}
+
+// Email returns the email address of the user with the provided ID. It returns
+// an empty string with a nil error if the address is hidden from the app,
+// because the user is a bot, or the app lacks the users:read.email scope.
+func (u *UserNames) Email(ctx context.Context, id string) (string, error) {
+	profile, err := u.value(id).Get(ctx)
+	return profile.Email, err
+}
+
+func (u *UserNames) value(id string) *cache.Value[userProfile] {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if v, ok := u.names[id]; ok {
+		return v
+	}
+	if u.names == nil {
+		u.names = make(map[string]*cache.Value[userProfile])
+	}
+
+	ttl := u.TTL
+	if ttl == 0 {
+		ttl = DefaultUserNameTTL
+	}
+	v := cache.New(func(ctx context.Context) (userProfile, error) {
+		return u.lookup(ctx, id)
+	}, cache.Options{TTL: ttl, Jitter: 0.1, Stale: ttl})
+	u.names[id] = v
+	return v
+}
+
+func (u *UserNames) lookup(ctx context.Context, id string) (userProfile, error) {
+	var out struct {
+		User struct {
+			Name    string `json:"name"`
+			Profile struct {
+				DisplayName string `json:"display_name"`
+				RealName    string `json:"real_name"`
+				Email       string `json:"email"`
+			} `json:"profile"`
+		} `json:"user"`
+	}
+	if err := u.Client.Call(ctx, "users.info", url.Values{"user": {id}}, &out); err != nil {
+		return userProfile{}, err
+	}
+
+	user := out.User
+	profile := userProfile{Email: user.Profile.Email}
+	switch {
+	case user.Profile.DisplayName != "":
+		profile.Name = user.Profile.DisplayName
+	case user.Profile.RealName != "":
+		profile.Name = user.Profile.RealName
+	default:
+		profile.Name = user.Name
+	}
+	return profile, nil
+}
@@ -0,0 +1,60 @@
+package slack
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestUserNames(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		switch r.FormValue("user") {
+		case "U111":
+			w.Write([]byte(`{"ok": true, "user": {"name": "alice", "profile": {"display_name": "Alice", "real_name": "Alice Anderson", "email": "alice@example.com"}}}`))
+		case "U222":
+			w.Write([]byte(`{"ok": true, "user": {"name": "bob", "profile": {"display_name": "", "real_name": "Bob Brown"}}}`))
+		default:
+			w.Write([]byte(`{"ok": false, "error": "user_not_found"}`))
+		}
+	}))
+	defer srv.Close()
+
+	names := &UserNames{Client: WebClient{Token: "xoxb-test", BaseURL: srv.URL + "/"}}
+	ctx := context.Background()
+	for _, tc := range []struct{ id, want string }{
+		{"U111", "Alice"},
+		{"U222", "Bob Brown"},
+		{"U111", "Alice"},
+	} {
+		got, err := names.Resolve(ctx, tc.id)
+		if err != nil || got != tc.want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q, nil", tc.id, got, err, tc.want)
+		}
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("made %d calls to users.info, want 2", n)
+	}
+
+	// Email addresses come from the same cached profiles. Bob's is hidden,
+	// like it would be without the users:read.email scope.
+	for _, tc := range []struct{ id, want string }{
+		{"U111", "alice@example.com"},
+		{"U222", ""},
+	} {
+		got, err := names.Email(ctx, tc.id)
+		if err != nil || got != tc.want {
+			t.Errorf("Email(%q) = %q, %v; want %q, nil", tc.id, got, err, tc.want)
+		}
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("made %d calls to users.info after email lookups, want 2", n)
+	}
+
+	if _, err := names.Resolve(ctx, "U999"); err == nil {
+		t.Error("expected an error for an unknown user")
+	}
+}
@@ -0,0 +1,182 @@
+package slack
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/featherbread/randomizer/internal/clock"
+)
+
+// Slack verifies its requests in one of two ways: with a legacy verification
+// token included in each request, or with an HMAC signature of each request
+// keyed by a signing secret. An App configured with both accepts either, so
+// that large installs can migrate to signing secrets gradually. Once every
+// request from a workspace is signed, adding that workspace to the App's
+// RequireSignature set stops it from accepting the legacy token.
+
+// VerificationMethod identifies how the App verified a request from Slack.
+type VerificationMethod string
+
+const (
+	VerifiedBySignature VerificationMethod = "signature"
+	VerifiedByToken     VerificationMethod = "token"
+)
+
+// maxSignatureAge bounds how old a signed request may be, to limit replays.
+const maxSignatureAge = 5 * time.Minute
+
+// SigningSecretFromEnv returns a provider for the Slack signing secret based
+// on available environment variables.
+//
+// If SLACK_SIGNING_SECRET is set, it returns a static provider. If
+// SLACK_SIGNING_SECRET_SSM_NAME is set, it returns an AWS SSM provider, with
+// the TTL optionally set by SLACK_TOKEN_SSM_TTL. Otherwise, it returns nil.
+func SigningSecretFromEnv() (TokenProvider, error) {
+	if secret, ok := os.LookupEnv("SLACK_SIGNING_SECRET"); ok {
+		return StaticToken(secret), nil
+	}
+
+	if ssmName, ok := os.LookupEnv("SLACK_SIGNING_SECRET_SSM_NAME"); ok {
+		ttl, err := ssmTTLFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return AWSParameter(ssmName, ttl), nil
+	}
+
+	return nil, nil
+}
+
+// verify checks that a request came from Slack, using the request's signature
+// if it has one and the App can check it, or else the legacy token. It returns
+// an empty method if the request is not verified, or comes from a workspace
+// that hasn't installed the App.
+func (a App) verify(ctx context.Context, header http.Header, body []byte, token, teamID string) (VerificationMethod, error) {
+	method, err := a.verifyMethod(ctx, header, body, token, teamID)
+	if err != nil || method == "" {
+		return "", err
+	}
+	if ok, err := a.isInstalled(ctx, teamID); err != nil || !ok {
+		return "", err
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("slack.verification", string(method)))
+	a.Verification.record(teamID, method, clock.Or(a.Clock).Now())
+	return method, nil
+}
+
+func (a App) verifyMethod(ctx context.Context, header http.Header, body []byte, token, teamID string) (VerificationMethod, error) {
+	if signature := header.Get("X-Slack-Signature"); signature != "" && a.SigningSecret != nil {
+		secret, err := a.SigningSecret(ctx)
+		if err != nil {
+			return "", err
+		}
+		// A request with a bad signature is rejected outright, rather than
+		// getting a second chance with its token.
+		if !validSignature(secret, header.Get("X-Slack-Request-Timestamp"), signature, body, clock.Or(a.Clock).Now()) {
+			return "", nil
+		}
+		return VerifiedBySignature, nil
+	}
+
+	if a.TokenProvider == nil || a.RequireSignature.Contains(teamID) {
+		return "", nil
+	}
+	ok, err := a.isTokenValid(ctx, token)
+	if err != nil || !ok {
+		return "", err
+	}
+	return VerifiedByToken, nil
+}
+
+func validSignature(secret, timestamp, signature string, body []byte, now time.Time) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > maxSignatureAge || age < -maxSignatureAge {
+		return false
+	}
+
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, "v0="))
+	if err != nil || !strings.HasPrefix(signature, "v0=") {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// VerificationTracker counts how each workspace's requests were verified, to
+// show when a workspace has finished migrating to signing secrets.
+//
+// A nil *VerificationTracker records nothing.
+type VerificationTracker struct {
+	mu    sync.Mutex
+	teams map[string]*TeamVerification
+}
+
+// TeamVerification summarizes how one workspace's requests were verified.
+type TeamVerification struct {
+	TeamID    string    `json:"team_id"`
+	Signature int       `json:"signature"`
+	Token     int       `json:"token"`
+	LastToken time.Time `json:"last_token,omitzero"`
+}
+
+func (v *VerificationTracker) record(teamID string, method VerificationMethod, at time.Time) {
+	if v == nil || method == "" {
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.teams == nil {
+		v.teams = make(map[string]*TeamVerification)
+	}
+	team, ok := v.teams[teamID]
+	if !ok {
+		team = &TeamVerification{TeamID: teamID}
+		v.teams[teamID] = team
+	}
+	switch method {
+	case VerifiedBySignature:
+		team.Signature++
+	case VerifiedByToken:
+		team.Token++
+		team.LastToken = at.UTC()
+	}
+}
+
+// Report returns the verification counts for every workspace that has made a
+// request since the process started, ordered by team ID.
+func (v *VerificationTracker) Report() []TeamVerification {
+	if v == nil {
+		return nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	report := make([]TeamVerification, 0, len(v.teams))
+	for _, team := range v.teams {
+		report = append(report, *team)
+	}
+	slices.SortFunc(report, func(x, y TeamVerification) int {
+		return strings.Compare(x.TeamID, y.TeamID)
+	})
+	return report
+}
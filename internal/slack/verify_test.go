@@ -0,0 +1,113 @@
+package slack
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+	"github.com/featherbread/randomizer/internal/slacktest"
+)
+
+func TestVerificationMigration(t *testing.T) {
+	tracker := new(VerificationTracker)
+	app := App{
+		TokenProvider:    StaticToken("right"),
+		SigningSecret:    StaticToken("secret"),
+		RequireSignature: TeamSet{"TSIGNED": true},
+		Verification:     tracker,
+		StoreFactory:     func(_ string) randomizer.Store { return make(rndtest.Store) },
+	}
+
+	now := time.Now()
+	testCases := []struct {
+		description string
+		team, token string
+		sign        func(body string) (timestamp, signature string)
+		want        int
+	}{
+		{
+			description: "legacy token",
+			team:        "TLEGACY",
+			token:       "right",
+			want:        http.StatusOK,
+		},
+		{
+			description: "valid signature",
+			team:        "TLEGACY",
+			sign:        signer("secret", now),
+			want:        http.StatusOK,
+		},
+		{
+			description: "invalid signature with valid token",
+			team:        "TLEGACY",
+			token:       "right",
+			sign:        signer("wrong", now),
+			want:        http.StatusForbidden,
+		},
+		{
+			description: "expired signature",
+			team:        "TLEGACY",
+			sign:        signer("secret", now.Add(-time.Hour)),
+			want:        http.StatusForbidden,
+		},
+		{
+			description: "legacy token from migrated workspace",
+			team:        "TSIGNED",
+			token:       "right",
+			want:        http.StatusForbidden,
+		},
+		{
+			description: "valid signature from migrated workspace",
+			team:        "TSIGNED",
+			sign:        signer("secret", now),
+			want:        http.StatusOK,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			params := makeTestParams("one two")
+			params.Set("token", tc.token)
+			params.Set("team_id", tc.team)
+			body := params.Encode()
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tc.sign != nil {
+				timestamp, signature := tc.sign(body)
+				req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+				req.Header.Set("X-Slack-Signature", signature)
+			}
+			resp := httptest.NewRecorder()
+			app.ServeHTTP(resp, req)
+
+			if resp.Code != tc.want {
+				t.Errorf("got status %v, want %v", resp.Code, tc.want)
+			}
+		})
+	}
+
+	report := tracker.Report()
+	want := []TeamVerification{
+		{TeamID: "TLEGACY", Signature: 1, Token: 1},
+		{TeamID: "TSIGNED", Signature: 1},
+	}
+	if len(report) != len(want) {
+		t.Fatalf("got report %+v, want %+v", report, want)
+	}
+	for i := range want {
+		report[i].LastToken = time.Time{}
+		if report[i] != want[i] {
+			t.Errorf("got report entry %+v, want %+v", report[i], want[i])
+		}
+	}
+}
+
+func signer(secret string, at time.Time) func(string) (string, string) {
+	return func(body string) (string, string) {
+		return slacktest.Sign(secret, []byte(body), at)
+	}
+}
@@ -0,0 +1,146 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultWebAPIURL is the base URL for Slack Web API methods.
+const DefaultWebAPIURL = "https://slack.com/api/"
+
+// WebClient makes calls to the Slack Web API (for example, to post messages)
+// using a bot token.
+type WebClient struct {
+	// Token is the bot token used to authorize calls.
+	Token string
+	// Tokens, if non-nil, provides the bot token for each call in place of Token,
+	// e.g. to pick up a rotated token without a restart.
+	Tokens BotTokenProvider
+	// BaseURL overrides DefaultWebAPIURL, e.g. for testing.
+	BaseURL string
+	// HTTPClient overrides http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// RateLimitedError is returned when Slack rejects a call due to rate limits.
+type RateLimitedError struct {
+	RetryAfter time.Duration
+}
+
+func (e RateLimitedError) Error() string {
+	return fmt.Sprintf("rate limited by Slack; retry after %v", e.RetryAfter)
+}
+
+// Call invokes a Web API method with a JSON payload, and decodes the response
+// into out if it is non-nil. It returns an error if Slack indicates that the
+// call was not successful.
+//
+// Some read methods (like users.info) don't accept JSON. For these, payload may
+// be a url.Values, which Call sends as a form instead.
+func (c WebClient) Call(ctx context.Context, method string, payload, out any) error {
+	_, err := c.call(ctx, method, payload, out)
+	return err
+}
+
+// Scopes returns the OAuth scopes granted to the bot token, as reported by the
+// auth.test Web API method.
+func (c WebClient) Scopes(ctx context.Context) ([]string, error) {
+	header, err := c.call(ctx, "auth.test", url.Values{}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var scopes []string
+	for scope := range strings.SplitSeq(header.Get("X-OAuth-Scopes"), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+func (c WebClient) call(ctx context.Context, method string, payload, out any) (http.Header, error) {
+	ctx, span := tracer.Start(ctx, "slack.WebClient.Call")
+	defer span.End()
+
+	token := c.Token
+	if c.Tokens != nil {
+		var err error
+		if token, err = c.Tokens(ctx); err != nil {
+			return nil, fmt.Errorf("loading Slack bot token: %w", err)
+		}
+	}
+
+	contentType := "application/json; charset=utf-8"
+	var body []byte
+	if form, ok := payload.(url.Values); ok {
+		contentType = "application/x-www-form-urlencoded"
+		body = []byte(form.Encode())
+	} else {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return nil, fmt.Errorf("encoding %s payload: %w", method, err)
+		}
+	}
+
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultWebAPIURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+method, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, fmt.Errorf("calling %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return nil, RateLimitedError{RetryAfter: time.Duration(max(retryAfter, 1)) * time.Second}
+	}
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("decoding %s response (status %d): %w", method, resp.StatusCode, err)
+	}
+
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return nil, fmt.Errorf("decoding %s response: %w", method, err)
+	}
+	if !status.OK {
+		if status.Error == "" {
+			return nil, fmt.Errorf("%s failed with status %d", method, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("%s failed: %w", method, errors.New(status.Error))
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(raw, out); err != nil {
+			return nil, err
+		}
+	}
+	return resp.Header, nil
+}
@@ -0,0 +1,136 @@
+package slacktest_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/compress"
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+	"github.com/featherbread/randomizer/internal/slack"
+	"github.com/featherbread/randomizer/internal/slacktest"
+)
+
+// These tests drive the full HTTP handler stack that the server and Lambda
+// frontends use, against a fake Slack API where the flow needs one.
+
+func newHandler(store *rndtest.SyncStore, opts ...slack.AppOption) http.Handler {
+	app := slack.NewApp(
+		slack.StaticToken("token"),
+		func(string) randomizer.Store { return store },
+		append([]slack.AppOption{slack.WithSigningSecret(slack.StaticToken("secret"))}, opts...)...,
+	)
+	return compress.Handler(app, 0)
+}
+
+type response struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+func serve(t *testing.T, h http.Handler, req *http.Request) response {
+	t.Helper()
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("got status %v, want %v", resp.Code, http.StatusOK)
+	}
+	var out response
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	return out
+}
+
+func TestSaveAndSelect(t *testing.T) {
+	store := &rndtest.SyncStore{Store: rndtest.Store{}}
+	h := newHandler(store)
+
+	saved := serve(t, h, slacktest.CommandRequest(slacktest.SlashCommand("/save lunch pizza tacos"), "token"))
+	if saved.ResponseType != "in_channel" || !strings.Contains(saved.Text, "lunch") {
+		t.Errorf("unexpected response to /save: %+v", saved)
+	}
+
+	selected := serve(t, h, slacktest.SignedCommandRequest(slacktest.SlashCommand("lunch"), "secret", time.Now()))
+	if !strings.Contains(selected.Text, "*pizza*") || !strings.Contains(selected.Text, "*tacos*") {
+		t.Errorf("unexpected response to selection: %+v", selected)
+	}
+}
+
+func TestForgedSignature(t *testing.T) {
+	h := newHandler(&rndtest.SyncStore{Store: rndtest.Store{}})
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, slacktest.SignedCommandRequest(slacktest.SlashCommand("one two"), "forged", time.Now()))
+	if resp.Code != http.StatusForbidden {
+		t.Errorf("got status %v, want %v", resp.Code, http.StatusForbidden)
+	}
+}
+
+func TestSuspensefulReveal(t *testing.T) {
+	api := slacktest.NewServer(t)
+	api.Stub("chat.postMessage", map[string]any{"ok": true, "channel": "C12345678", "ts": "1.2"})
+
+	h := newHandler(&rndtest.SyncStore{Store: rndtest.Store{}}, slack.WithSuspense(&slack.Suspense{
+		Client:   slack.WebClient{Token: "xoxb-test", BaseURL: api.WebAPIURL()},
+		Interval: time.Millisecond,
+	}))
+	got := serve(t, h, slacktest.CommandRequest(slacktest.SlashCommand("one two three four"), "token"))
+	if got.ResponseType != "ephemeral" {
+		t.Errorf("got %+v, want an ephemeral placeholder", got)
+	}
+
+	// The reveal continues in the background, finishing with the result.
+	revealed := func() bool {
+		updates := api.Calls("chat.update")
+		return len(updates) > 0 && strings.Contains(updates[len(updates)-1].Params["text"], "I randomized and got")
+	}
+	for deadline := time.Now().Add(5 * time.Second); !revealed() && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !revealed() {
+		t.Fatalf("reveal did not finish with the result; got calls %+v", api.Calls())
+	}
+	updates := api.Calls("chat.update")
+	if token := updates[0].Token; token != "xoxb-test" {
+		t.Errorf("Web API called with token %q, want %q", token, "xoxb-test")
+	}
+}
+
+func TestReactionFeedback(t *testing.T) {
+	store := &rndtest.SyncStore{Store: rndtest.Store{"lunch": {"pizza", "tacos"}}}
+	h := newHandler(store)
+	serve(t, h, slacktest.CommandRequest(slacktest.SlashCommand("lunch"), "token"))
+
+	// The message announcing the result is posted just after the selection.
+	ts := time.Now().Add(time.Second)
+	req := slacktest.EventRequest(map[string]any{
+		"token":   "token",
+		"team_id": "T12345678",
+		"type":    "event_callback",
+		"event": map[string]any{
+			"type":     "reaction_added",
+			"user":     "U87654321",
+			"reaction": "+1",
+			"item": map[string]any{
+				"type":    "message",
+				"channel": "C12345678",
+				"ts":      fmt.Sprintf("%d.%06d", ts.Unix(), ts.Nanosecond()/1000),
+			},
+		},
+	})
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("event got status %v, want %v", resp.Code, http.StatusOK)
+	}
+
+	stats := serve(t, h, slacktest.CommandRequest(slacktest.SlashCommand("/stats lunch"), "token"))
+	if !strings.Contains(stats.Text, "1 :+1: / 0 :-1:") {
+		t.Errorf("stats don't reflect feedback: %q", stats.Text)
+	}
+}
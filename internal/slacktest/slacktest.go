@@ -0,0 +1,241 @@
+// Package slacktest provides a fake Slack API server and request helpers for
+// testing the randomizer's Slack integration without a live workspace.
+package slacktest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Server is a fake Slack API server. It answers Web API calls under /api/
+// with stubbed responses, and captures messages posted to response URLs.
+type Server struct {
+	srv *httptest.Server
+
+	mu        sync.Mutex
+	stubs     map[string]any
+	calls     []Call
+	responses map[string][]map[string]any
+	nextURL   int
+}
+
+// Call is a single Web API call received by a Server.
+type Call struct {
+	Method string
+	// Token is the bearer token that authorized the call.
+	Token string
+	// Params holds the call's arguments, from either a form or a JSON object.
+	// JSON values other than strings are kept in their encoded form.
+	Params map[string]string
+}
+
+// NewServer starts a Server that is closed when the test finishes.
+func NewServer(t testing.TB) *Server {
+	s := &Server{
+		stubs:     make(map[string]any),
+		responses: make(map[string][]map[string]any),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/{method}", s.serveWebAPI)
+	mux.HandleFunc("POST /response/{id}", s.serveResponseURL)
+	s.srv = httptest.NewServer(mux)
+	t.Cleanup(s.srv.Close)
+	return s
+}
+
+// WebAPIURL returns the base URL for Web API methods, suitable for
+// slack.WebClient.BaseURL.
+func (s *Server) WebAPIURL() string {
+	return s.srv.URL + "/api/"
+}
+
+// Stub sets the response to calls of a Web API method. Stubbed responses
+// should include "ok": true if the call is meant to succeed. Methods without a
+// stub respond with {"ok": true}.
+func (s *Server) Stub(method string, response any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stubs[method] = response
+}
+
+// Calls returns the Web API calls received so far, in order. If any methods
+// are provided, only calls to those methods are returned.
+func (s *Server) Calls(methods ...string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var calls []Call
+	for _, call := range s.calls {
+		if len(methods) == 0 || slices.Contains(methods, call.Method) {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// NewResponseURL returns a fresh URL that captures the messages posted to it,
+// for use as a request's response_url.
+func (s *Server) NewResponseURL() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextURL++
+	return s.srv.URL + "/response/" + strconv.Itoa(s.nextURL)
+}
+
+// Responses returns the messages posted to a response URL, in order.
+func (s *Server) Responses(responseURL string) []map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := responseURL[strings.LastIndexByte(responseURL, '/')+1:]
+	return s.responses[id]
+}
+
+func (s *Server) serveWebAPI(w http.ResponseWriter, r *http.Request) {
+	params, err := readParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	method := r.PathValue("method")
+	s.mu.Lock()
+	s.calls = append(s.calls, Call{
+		Method: method,
+		Token:  strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "),
+		Params: params,
+	})
+	response, ok := s.stubs[method]
+	s.mu.Unlock()
+
+	if !ok {
+		response = map[string]any{"ok": true}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) serveResponseURL(w http.ResponseWriter, r *http.Request) {
+	var message map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := r.PathValue("id")
+	s.responses[id] = append(s.responses[id], message)
+}
+
+func readParams(r *http.Request) (map[string]string, error) {
+	params := make(map[string]string)
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		for key := range r.PostForm {
+			params[key] = r.PostForm.Get(key)
+		}
+		return params, nil
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		return nil, err
+	}
+	for key, value := range raw {
+		var str string
+		if json.Unmarshal(value, &str) == nil {
+			params[key] = str
+		} else {
+			params[key] = string(value)
+		}
+	}
+	return params, nil
+}
+
+// SlashCommand returns the form parameters of a slash command invocation, with
+// reasonable defaults for fields other than the command text. Tests may modify
+// the parameters before building a request from them.
+func SlashCommand(text string) url.Values {
+	return url.Values{
+		"command":      {"/randomize"},
+		"text":         {text},
+		"team_id":      {"T12345678"},
+		"channel_id":   {"C12345678"},
+		"user_id":      {"U12345678"},
+		"trigger_id":   {strconv.FormatInt(time.Now().UnixNano(), 10)},
+		"response_url": {"https://hooks.slack.com/commands/T12345678/1/abc"},
+	}
+}
+
+// CommandRequest returns a slash command request with a legacy verification
+// token.
+func CommandRequest(params url.Values, token string) *http.Request {
+	params = cloneValues(params)
+	params.Set("token", token)
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(params.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+// SignedCommandRequest returns a slash command request signed with a signing
+// secret, as Slack would sign it at the provided time.
+func SignedCommandRequest(params url.Values, secret string, at time.Time) *http.Request {
+	body := params.Encode()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	SignRequest(req, []byte(body), secret, at)
+	return req
+}
+
+// EventRequest returns an Events API request carrying the provided event
+// payload, which should include a verification token if the test relies on
+// one.
+func EventRequest(payload any) *http.Request {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		panic(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+// SignRequest adds Slack's signature headers to a request with the provided
+// body.
+func SignRequest(req *http.Request, body []byte, secret string, at time.Time) {
+	timestamp, signature := Sign(secret, body, at)
+	req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	req.Header.Set("X-Slack-Signature", signature)
+}
+
+// Sign returns the timestamp and signature headers that Slack would send with
+// a request body at the provided time.
+func Sign(secret string, body []byte, at time.Time) (timestamp, signature string) {
+	timestamp = strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	io.WriteString(mac, "v0:"+timestamp+":")
+	mac.Write(body)
+	return timestamp, "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func cloneValues(v url.Values) url.Values {
+	out := make(url.Values, len(v))
+	for key, values := range v {
+		out[key] = slices.Clone(values)
+	}
+	return out
+}
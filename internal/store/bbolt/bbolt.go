@@ -7,6 +7,7 @@ import (
 	"encoding/gob"
 	"errors"
 	"fmt"
+	"io"
 
 	bolt "go.etcd.io/bbolt"
 )
@@ -114,3 +115,30 @@ func (b Store) Delete(_ context.Context, name string) (existed bool, err error)
 	})
 	return
 }
+
+// Partitions lists the buckets in the database backing this store, each of
+// which holds the groups for one partition.
+func (b Store) Partitions(_ context.Context) (partitions []string, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			partitions = append(partitions, string(name))
+			return nil
+		})
+	})
+	return
+}
+
+// Snapshot writes a consistent copy of the entire database backing this store,
+// with every partition, to w. It runs in a read-only transaction, so the
+// server keeps handling requests while it runs. The copy is itself a bbolt
+// database, which a new server can open in place of the original.
+func (b Store) Snapshot(ctx context.Context, w io.Writer) (n int64, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err = tx.WriteTo(w)
+		return err
+	})
+	return
+}
@@ -2,14 +2,21 @@ package bbolt
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
+	"time"
 
 	bolt "go.etcd.io/bbolt"
+	bolterrors "go.etcd.io/bbolt/errors"
 
 	"github.com/featherbread/randomizer/internal/randomizer"
 	"github.com/featherbread/randomizer/internal/store/registry"
 )
 
+// openTimeout bounds the wait for another process to close the database.
+const openTimeout = 2 * time.Second
+
 func init() {
 	registry.Provide("bbolt", FactoryFromEnv, "DB_PATH")
 }
@@ -19,7 +26,13 @@ func init() {
 func FactoryFromEnv(_ context.Context) (func(string) randomizer.Store, error) {
 	path := pathFromEnv()
 
-	db, err := bolt.Open(path, os.ModePerm&0644, nil)
+	// Only one process can open the database at a time, so fail rather than
+	// wait forever if a server already has it open. A running server can still
+	// take a snapshot; see [Store.Snapshot].
+	db, err := bolt.Open(path, os.ModePerm&0644, &bolt.Options{Timeout: openTimeout})
+	if errors.Is(err, bolterrors.ErrTimeout) {
+		return nil, fmt.Errorf("%s is in use by another process, like a running server", path)
+	}
 	if err != nil {
 		return nil, err
 	}
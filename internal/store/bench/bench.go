@@ -0,0 +1,263 @@
+// Package bench measures how a store backend performs under a workload like
+// the randomizer's, so that operators can compare backends before choosing
+// one for their scale.
+package bench
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+// Default settings for a Workload.
+const (
+	DefaultGroups      = 20
+	DefaultOptions     = 10
+	DefaultRounds      = 5
+	DefaultConcurrency = 4
+)
+
+// PartitionPrefix starts the name of every partition that a benchmark writes
+// to. Each run uses a fresh partition, and deletes its groups when it's done.
+const PartitionPrefix = "randomizer-bench-"
+
+// Operations that a Workload measures, in the order of a Report.
+const (
+	OpPut    = "put"
+	OpGet    = "get"
+	OpList   = "list"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+var operations = []string{OpPut, OpGet, OpList, OpUpdate, OpDelete}
+
+// Workload describes the operations of a benchmark. Each round saves every
+// group, reads each one a few times as selections do, lists the groups, and
+// edits each one as tags and history do. The last round deletes every group.
+type Workload struct {
+	// Groups sets the number of groups, and Options the number of options in
+	// each. If zero, they default to DefaultGroups and DefaultOptions.
+	Groups  int
+	Options int
+	// Rounds sets the number of times to run through the groups. If zero, it
+	// defaults to DefaultRounds.
+	Rounds int
+	// Concurrency sets the number of groups that are worked on at once. If
+	// zero, it defaults to DefaultConcurrency.
+	Concurrency int
+}
+
+func (w Workload) withDefaults() Workload {
+	w.Groups = cmp.Or(w.Groups, DefaultGroups)
+	w.Options = cmp.Or(w.Options, DefaultOptions)
+	w.Rounds = cmp.Or(w.Rounds, DefaultRounds)
+	w.Concurrency = cmp.Or(w.Concurrency, DefaultConcurrency)
+	return w
+}
+
+// readsPerRound is the number of times each group is read per round, since
+// selections far outnumber edits.
+const readsPerRound = 4
+
+// Stats summarizes the calls of a single operation.
+type Stats struct {
+	Op     string
+	Calls  int
+	Errors int
+	// Bytes counts the option text written or read by the calls, which drives
+	// the cost of most hosted backends.
+	Bytes int64
+	Mean  time.Duration
+	P50   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// Report holds the results of a benchmark.
+type Report struct {
+	Partition string
+	Elapsed   time.Duration
+	Ops       []Stats
+	// FirstError is the first error returned by the store, if any.
+	FirstError error
+}
+
+// Op returns the stats of a single operation.
+func (r Report) Op(name string) Stats {
+	i := slices.IndexFunc(r.Ops, func(s Stats) bool { return s.Op == name })
+	if i < 0 {
+		return Stats{Op: name}
+	}
+	return r.Ops[i]
+}
+
+// Calls returns the total number of calls made to the store.
+func (r Report) Calls() int {
+	var n int
+	for _, s := range r.Ops {
+		n += s.Calls
+	}
+	return n
+}
+
+type recorder struct {
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+	errors    map[string]int
+	bytes     map[string]int64
+	first     error
+}
+
+func (r *recorder) record(op string, start time.Time, bytes int, err error) {
+	elapsed := time.Since(start)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.latencies[op] = append(r.latencies[op], elapsed)
+	r.bytes[op] += int64(bytes)
+	if err != nil {
+		r.errors[op]++
+		if r.first == nil {
+			r.first = fmt.Errorf("%s: %w", op, err)
+		}
+	}
+}
+
+// Run runs a workload against a fresh partition of a store factory's backend,
+// and reports the latency of each operation. Errors from the store are
+// counted rather than stopping the run; Run returns an error only if ctx is
+// canceled.
+func Run(ctx context.Context, factory func(partition string) randomizer.Store, w Workload) (Report, error) {
+	w = w.withDefaults()
+	partition := PartitionPrefix + strings.ToLower(rand.Text()[:8])
+	store := factory(partition)
+	rec := &recorder{
+		latencies: make(map[string][]time.Duration),
+		errors:    make(map[string]int),
+		bytes:     make(map[string]int64),
+	}
+
+	groups := make([]string, w.Groups)
+	for i := range groups {
+		groups[i] = fmt.Sprintf("bench-%03d", i)
+	}
+
+	start := time.Now()
+	for round := range w.Rounds {
+		if err := forEach(ctx, groups, w.Concurrency, func(group string) {
+			options := benchOptions(w.Options, round)
+			t := time.Now()
+			err := store.Put(ctx, group, options)
+			rec.record(OpPut, t, size(options), err)
+
+			for range readsPerRound {
+				t := time.Now()
+				got, err := store.Get(ctx, group)
+				rec.record(OpGet, t, size(got), err)
+			}
+
+			var written int
+			t = time.Now()
+			err = randomizer.Update(ctx, store, group, func(options []string) ([]string, error) {
+				options = append(options, fmt.Sprintf("extra-%d", round))
+				written = size(options)
+				return options, nil
+			})
+			rec.record(OpUpdate, t, written, err)
+		}); err != nil {
+			return Report{}, err
+		}
+
+		t := time.Now()
+		names, err := store.List(ctx)
+		rec.record(OpList, t, size(names), err)
+	}
+
+	if err := forEach(ctx, groups, w.Concurrency, func(group string) {
+		t := time.Now()
+		_, err := store.Delete(ctx, group)
+		rec.record(OpDelete, t, 0, err)
+	}); err != nil {
+		return Report{}, err
+	}
+
+	report := Report{Partition: partition, Elapsed: time.Since(start), FirstError: rec.first}
+	for _, op := range operations {
+		report.Ops = append(report.Ops, summarize(op, rec.latencies[op], rec.errors[op], rec.bytes[op]))
+	}
+	return report, nil
+}
+
+// forEach calls fn for each group with bounded concurrency, and returns early
+// only if ctx is canceled.
+func forEach(ctx context.Context, groups []string, concurrency int, fn func(string)) error {
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, concurrency)
+	)
+	for _, group := range groups {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		case sem <- struct{}{}:
+		}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			fn(group)
+		})
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// benchOptions returns options that vary between rounds, so that stores can't
+// skip writes that change nothing.
+func benchOptions(n, round int) []string {
+	options := make([]string, n)
+	for i := range options {
+		options[i] = fmt.Sprintf("option-%03d-round-%d", i, round)
+	}
+	return options
+}
+
+func size(entries []string) int {
+	var n int
+	for _, entry := range entries {
+		n += len(entry)
+	}
+	return n
+}
+
+func summarize(op string, latencies []time.Duration, errors int, bytes int64) Stats {
+	s := Stats{Op: op, Calls: len(latencies), Errors: errors, Bytes: bytes}
+	if len(latencies) == 0 {
+		return s
+	}
+	slices.Sort(latencies)
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+	}
+	s.Mean = total / time.Duration(len(latencies))
+	s.P50 = percentile(latencies, 0.50)
+	s.P95 = percentile(latencies, 0.95)
+	s.P99 = percentile(latencies, 0.99)
+	s.Max = latencies[len(latencies)-1]
+	return s
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
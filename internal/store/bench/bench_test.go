@@ -0,0 +1,73 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/store/memory"
+)
+
+func TestRun(t *testing.T) {
+	db := memory.NewDatabase(nil)
+	var partitions []string
+	factory := func(partition string) randomizer.Store {
+		partitions = append(partitions, partition)
+		return db.Factory()(partition)
+	}
+
+	report, err := Run(t.Context(), factory, Workload{Groups: 3, Options: 2, Rounds: 2, Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(partitions) != 1 || !strings.HasPrefix(partitions[0], PartitionPrefix) || report.Partition != partitions[0] {
+		t.Fatalf("benchmarked partitions %v, want one fresh partition", partitions)
+	}
+
+	want := map[string]int{OpPut: 6, OpGet: 6 * readsPerRound, OpList: 2, OpUpdate: 6, OpDelete: 3}
+	for op, calls := range want {
+		if s := report.Op(op); s.Calls != calls || s.Errors != 0 || s.Max < s.P50 {
+			t.Errorf("got %+v, want %d calls without errors", s, calls)
+		}
+	}
+	if report.Op(OpGet).Bytes == 0 || report.FirstError != nil {
+		t.Errorf("got report %+v, want bytes read and no errors", report)
+	}
+	if names, _ := db.Factory()(report.Partition).List(t.Context()); len(names) != 0 {
+		t.Errorf("benchmark left groups %v behind", names)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	factory := func(string) randomizer.Store { return failingStore{} }
+	report, err := Run(t.Context(), factory, Workload{Groups: 1, Rounds: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := report.Op(OpPut); s.Errors != 1 || !errors.Is(report.FirstError, errUnavailable) {
+		t.Errorf("got put stats %+v and first error %v", s, report.FirstError)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := range 100 {
+		latencies = append(latencies, time.Duration(i+1)*time.Millisecond)
+	}
+	s := summarize(OpGet, latencies, 0, 0)
+	if s.P50 != 50*time.Millisecond || s.P95 != 95*time.Millisecond || s.P99 != 99*time.Millisecond || s.Max != 100*time.Millisecond {
+		t.Errorf("got %+v", s)
+	}
+}
+
+var errUnavailable = errors.New("store unavailable")
+
+type failingStore struct{}
+
+func (failingStore) List(context.Context) ([]string, error)        { return nil, errUnavailable }
+func (failingStore) Get(context.Context, string) ([]string, error) { return nil, errUnavailable }
+func (failingStore) Put(context.Context, string, []string) error   { return errUnavailable }
+func (failingStore) Delete(context.Context, string) (bool, error)  { return false, errUnavailable }
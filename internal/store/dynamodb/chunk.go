@@ -0,0 +1,219 @@
+package dynamodb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Groups whose options exceed DynamoDB's 400 KB item size limit are stored in
+// chunks. The group's own row becomes a manifest holding the number of chunks
+// and a random generation ID in place of its options, and each chunk is a
+// separate row in the same partition, marked with the name of the group it
+// belongs to so that List can skip it.
+//
+// Chunks for a new generation are written before the manifest that points to
+// them, and the previous generation's chunks are removed only after the
+// manifest is replaced. A reader always sees a complete group, save for the
+// brief window in which it may find that an old generation's chunks have just
+// been removed, which Get handles by reading the manifest again.
+const (
+	chunkCountKey      = "Chunks"
+	chunkGenerationKey = "Generation"
+	chunkOfKey         = "ChunkOf"
+)
+
+// maxChunkBytes bounds the total size of the options in each chunk, leaving
+// plenty of room under the item size limit for keys and attribute names.
+const maxChunkBytes = 300 * 1024
+
+const (
+	maxBatchGet   = 100
+	maxBatchWrite = 25
+	maxAttempts   = 5
+)
+
+// splitChunks divides options into chunks of at most maxBytes each, not
+// counting the few bytes of per-element overhead that DynamoDB adds for lists.
+func splitChunks(options []string, maxBytes int) [][]string {
+	var (
+		chunks [][]string
+		size   int
+	)
+	for _, option := range options {
+		if len(chunks) == 0 || size+len(option) > maxBytes {
+			chunks = append(chunks, nil)
+			size = 0
+		}
+		last := len(chunks) - 1
+		chunks[last] = append(chunks[last], option)
+		size += len(option)
+	}
+	return chunks
+}
+
+func chunkKey(name, generation string, i int) string {
+	return name + "/chunk/" + generation + "/" + strconv.Itoa(i)
+}
+
+func newGeneration() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func (s Store) key(group string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		partitionKey: &types.AttributeValueMemberS{Value: s.partition},
+		groupKey:     &types.AttributeValueMemberS{Value: group},
+	}
+}
+
+// manifest describes the chunks of a group read from its row, if any.
+type manifest struct {
+	Count      int
+	Generation string
+}
+
+func parseManifest(item map[string]types.AttributeValue) (manifest, bool, error) {
+	countAttr, ok := item[chunkCountKey].(*types.AttributeValueMemberN)
+	if !ok {
+		return manifest{}, false, nil
+	}
+	count, err := strconv.Atoi(countAttr.Value)
+	if err != nil {
+		return manifest{}, false, fmt.Errorf("invalid chunk count %q: %w", countAttr.Value, err)
+	}
+	genAttr, ok := item[chunkGenerationKey].(*types.AttributeValueMemberS)
+	if !ok {
+		return manifest{}, false, fmt.Errorf("invalid type %T in chunk generation", item[chunkGenerationKey])
+	}
+	return manifest{Count: count, Generation: genAttr.Value}, true, nil
+}
+
+// getChunks reads every chunk of a group, and indicates whether all of them
+// were present.
+func (s Store) getChunks(ctx context.Context, name string, m manifest) ([]string, bool, error) {
+	chunks := make(map[string][]string, m.Count)
+	for start := 0; start < m.Count; start += maxBatchGet {
+		var keys []map[string]types.AttributeValue
+		for i := start; i < min(start+maxBatchGet, m.Count); i++ {
+			keys = append(keys, s.key(chunkKey(name, m.Generation, i)))
+		}
+
+		request := map[string]types.KeysAndAttributes{
+			s.table: {Keys: keys, ConsistentRead: aws.Bool(true)},
+		}
+		for attempt := 0; len(request) > 0; attempt++ {
+			if attempt >= maxAttempts {
+				return nil, false, fmt.Errorf("reading chunks of %q: too many unprocessed keys", name)
+			}
+			if attempt > 0 {
+				time.Sleep(backoff(attempt))
+			}
+
+			result, err := s.db.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: request})
+			if err != nil {
+				return nil, false, fmt.Errorf("reading chunks of %q: %w", name, err)
+			}
+			for _, item := range result.Responses[s.table] {
+				key, ok := item[groupKey].(*types.AttributeValueMemberS)
+				if !ok {
+					continue
+				}
+				items, err := decodeItems(item[itemsKey])
+				if err != nil {
+					return nil, false, fmt.Errorf("reading chunks of %q: %w", name, err)
+				}
+				chunks[key.Value] = items
+			}
+			request = result.UnprocessedKeys
+		}
+	}
+
+	var options []string
+	for i := range m.Count {
+		chunk, ok := chunks[chunkKey(name, m.Generation, i)]
+		if !ok {
+			return nil, false, nil
+		}
+		options = append(options, chunk...)
+	}
+	return options, true, nil
+}
+
+// putChunks writes the chunks of a new generation of a group, returning the
+// manifest that points to them.
+func (s Store) putChunks(ctx context.Context, name string, chunks [][]string) (manifest, error) {
+	m := manifest{Count: len(chunks), Generation: newGeneration()}
+
+	var writes []types.WriteRequest
+	for i, chunk := range chunks {
+		item := s.key(chunkKey(name, m.Generation, i))
+		item[chunkOfKey] = &types.AttributeValueMemberS{Value: name}
+		item[itemsKey] = encodeItems(chunk)
+		writes = append(writes, types.WriteRequest{PutRequest: &types.PutRequest{Item: item}})
+	}
+	if err := s.batchWrite(ctx, writes); err != nil {
+		return manifest{}, fmt.Errorf("writing chunks of %q: %w", name, err)
+	}
+	return m, nil
+}
+
+// deleteChunks removes the chunks of an old generation of a group.
+func (s Store) deleteChunks(ctx context.Context, name string, m manifest) error {
+	var writes []types.WriteRequest
+	for i := range m.Count {
+		writes = append(writes, types.WriteRequest{
+			DeleteRequest: &types.DeleteRequest{Key: s.key(chunkKey(name, m.Generation, i))},
+		})
+	}
+	if err := s.batchWrite(ctx, writes); err != nil {
+		return fmt.Errorf("removing old chunks of %q: %w", name, err)
+	}
+	return nil
+}
+
+// deleteOldChunks removes the chunks referenced by a group's previous row, as
+// returned by a write to that row.
+func (s Store) deleteOldChunks(ctx context.Context, name string, old map[string]types.AttributeValue) error {
+	m, chunked, err := parseManifest(old)
+	if err != nil || !chunked {
+		return err
+	}
+	return s.deleteChunks(ctx, name, m)
+}
+
+func (s Store) batchWrite(ctx context.Context, writes []types.WriteRequest) error {
+	for start := 0; start < len(writes); start += maxBatchWrite {
+		request := map[string][]types.WriteRequest{
+			s.table: writes[start:min(start+maxBatchWrite, len(writes))],
+		}
+		for attempt := 0; len(request) > 0; attempt++ {
+			if attempt >= maxAttempts {
+				return fmt.Errorf("too many unprocessed writes")
+			}
+			if attempt > 0 {
+				time.Sleep(backoff(attempt))
+			}
+
+			result, err := s.db.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: request})
+			if err != nil {
+				return err
+			}
+			request = result.UnprocessedItems
+		}
+	}
+	return nil
+}
+
+func backoff(attempt int) time.Duration {
+	return time.Duration(1<<attempt) * 25 * time.Millisecond
+}
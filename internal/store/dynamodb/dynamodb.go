@@ -5,7 +5,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
@@ -15,24 +20,43 @@ const (
 	partitionKey = "Partition"
 	groupKey     = "Group"
 	itemsKey     = "Items"
+	revisionKey  = "Revision"
 )
 
 // Store is a store backed by a pre-existing Amazon DynamoDB table.
 //
 // The DynamoDB table used by a Store must have a composite primary key, with a
 // partition key named "Partition" and a sort key named "Group", both
-// string-valued. Items in each row are stored in a string set attribute named
-// "Items".
+// string-valued. Items in each row are stored in a list attribute named
+// "Items", which preserves their order. (Rows written by older versions of the
+// randomizer hold an unordered string set instead, which Get still accepts.)
+// Groups too large to fit in a single row are split across several, as
+// described in chunk.go.
+//
+// Every write gives a row a new random "Revision" attribute, which Update uses
+// to detect concurrent writes to the same group.
 type Store struct {
-	db        *dynamodb.Client
-	table     string
-	partition string
+	db              *dynamodb.Client
+	table           string
+	partition       string
+	consistentReads bool
+}
+
+// Option configures a Store.
+type Option func(*Store)
+
+// WithConsistentReads makes Get and List use strongly consistent reads, so that
+// they always see the latest writes, at twice the read capacity cost. By
+// default, they may briefly return stale data, like a group as it was before
+// being saved.
+func WithConsistentReads(enabled bool) Option {
+	return func(s *Store) { s.consistentReads = enabled }
 }
 
 // New creates a new store, backed by the provided DynamoDB client, that writes
 // groups into the provided table using the provided partition key. See the
 // Store documentation for details.
-func New(db *dynamodb.Client, table, partition string) (Store, error) {
+func New(db *dynamodb.Client, table, partition string, opts ...Option) (Store, error) {
 	if db == nil {
 		return Store{}, errors.New("DynamoDB instance is required")
 	}
@@ -45,15 +69,70 @@ func New(db *dynamodb.Client, table, partition string) (Store, error) {
 		return Store{}, errors.New("partition is required")
 	}
 
-	return Store{
+	s := Store{
 		db:        db,
 		table:     table,
 		partition: partition,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s, nil
 }
 
 // List obtains the list of stored groups for this Store's partition.
 func (s Store) List(ctx context.Context) ([]string, error) {
+	// DynamoDB returns at most 1 MB of items per query, so large partitions
+	// take several.
+	list := []string{}
+	var startKey map[string]types.AttributeValue
+	for {
+		names, next, err := s.queryNames(ctx, startKey, 0)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, names...)
+		if next == nil {
+			return list, nil
+		}
+		startKey = next
+	}
+}
+
+// ListAfter implements randomizer.Pager, reading only as many pages of the
+// partition as it takes to fill the requested page.
+func (s Store) ListAfter(ctx context.Context, after string, limit int) ([]string, bool, error) {
+	var startKey map[string]types.AttributeValue
+	if after != "" {
+		startKey = map[string]types.AttributeValue{
+			partitionKey: &types.AttributeValueMemberS{Value: s.partition},
+			groupKey:     &types.AttributeValueMemberS{Value: after},
+		}
+	}
+
+	// We read one name past the limit to know whether there are more.
+	var list []string
+	for {
+		names, next, err := s.queryNames(ctx, startKey, int32(limit+1-len(list)))
+		if err != nil {
+			return nil, false, err
+		}
+		list = append(list, names...)
+		if len(list) > limit {
+			return list[:limit], true, nil
+		}
+		if next == nil {
+			return list, false, nil
+		}
+		startKey = next
+	}
+}
+
+// queryNames reads a single page of group names from the partition, starting
+// after startKey if it's non-nil, and returns the key that the next page starts
+// after, or nil after the last page. A positive limit bounds the items that
+// DynamoDB reads, which includes the chunks of large groups.
+func (s Store) queryNames(ctx context.Context, startKey map[string]types.AttributeValue, limit int32) ([]string, map[string]types.AttributeValue, error) {
 	expr, err := expression.NewBuilder().
 		WithKeyCondition(
 			expression.KeyEqual(
@@ -62,96 +141,241 @@ func (s Store) List(ctx context.Context) ([]string, error) {
 		).
 		WithProjection(expression.NamesList(
 			expression.Name(groupKey),
+			expression.Name(chunkOfKey),
 		)).
 		Build()
 	if err != nil {
-		return nil, fmt.Errorf("building expression: %w", err)
+		return nil, nil, fmt.Errorf("building expression: %w", err)
 	}
 
-	result, err := s.db.Query(ctx, &dynamodb.QueryInput{
+	input := &dynamodb.QueryInput{
 		TableName:                 &s.table,
 		KeyConditionExpression:    expr.KeyCondition(),
 		ProjectionExpression:      expr.Projection(),
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
-	})
+		ConsistentRead:            aws.Bool(s.consistentReads),
+		ExclusiveStartKey:         startKey,
+	}
+	if limit > 0 {
+		input.Limit = aws.Int32(limit)
+	}
+	result, err := s.db.Query(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("listing groups for %q from table %q: %w", s.partition, s.table, err)
+		return nil, nil, fmt.Errorf("listing groups for %q from table %q: %w", s.partition, s.table, err)
 	}
 
-	list := make([]string, len(result.Items))
-	for i, item := range result.Items {
+	names := make([]string, 0, len(result.Items))
+	for _, item := range result.Items {
+		if _, isChunk := item[chunkOfKey]; isChunk {
+			continue
+		}
 		v, ok := item[groupKey].(*types.AttributeValueMemberS)
 		if !ok {
-			return nil, fmt.Errorf("invalid type %T in group names", item[groupKey])
+			return nil, nil, fmt.Errorf("invalid type %T in group names", item[groupKey])
 		}
-		list[i] = v.Value
+		names = append(names, v.Value)
 	}
-	return list, nil
+
+	if len(result.LastEvaluatedKey) == 0 {
+		return names, nil, nil // The last page
+	}
+	return names, result.LastEvaluatedKey, nil
 }
 
 // Get obtains the options in a single named group from this Store's partition.
 func (s Store) Get(ctx context.Context, name string) ([]string, error) {
+	// A chunked group may be replaced between reading its manifest and reading
+	// its chunks, in which case we start over with the new manifest.
+	for range 2 {
+		options, _, ok, err := s.get(ctx, name, s.consistentReads)
+		if err != nil || ok {
+			return options, err
+		}
+	}
+	return nil, fmt.Errorf("getting %q for %q from table %q: chunks changed while reading", name, s.partition, s.table)
+}
+
+// get reads a group along with its row's revision, which is empty for rows
+// that don't have one. It indicates whether the group's chunks were all
+// present, if it has any.
+func (s Store) get(ctx context.Context, name string, consistent bool) (options []string, revision string, ok bool, err error) {
 	expr, err := expression.NewBuilder().
 		WithProjection(expression.NamesList(
 			expression.Name(itemsKey),
+			expression.Name(chunkCountKey),
+			expression.Name(chunkGenerationKey),
+			expression.Name(revisionKey),
 		)).
 		Build()
 	if err != nil {
-		return nil, fmt.Errorf("building expression: %w", err)
+		return nil, "", false, fmt.Errorf("building expression: %w", err)
 	}
 
 	result, err := s.db.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: &s.table,
-		Key: map[string]types.AttributeValue{
-			partitionKey: &types.AttributeValueMemberS{Value: s.partition},
-			groupKey:     &types.AttributeValueMemberS{Value: name},
-		},
+		TableName:                &s.table,
+		Key:                      s.key(name),
 		ProjectionExpression:     expr.Projection(),
 		ExpressionAttributeNames: expr.Names(),
+		ConsistentRead:           aws.Bool(consistent),
 	})
 	if err != nil {
-		return nil, fmt.Errorf("getting %q for %q from table %q: %w", name, s.partition, s.table, err)
+		return nil, "", false, fmt.Errorf("getting %q for %q from table %q: %w", name, s.partition, s.table, err)
 	}
 
 	if len(result.Item) == 0 {
-		return nil, nil
+		return nil, "", true, nil
+	}
+	if v, ok := result.Item[revisionKey].(*types.AttributeValueMemberS); ok {
+		revision = v.Value
 	}
 
-	v, ok := result.Item[itemsKey].(*types.AttributeValueMemberSS)
-	if !ok {
-		return nil, fmt.Errorf("invalid type %T in group items", v)
+	m, chunked, err := parseManifest(result.Item)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("getting %q for %q from table %q: %w", name, s.partition, s.table, err)
+	}
+	if chunked {
+		options, ok, err = s.getChunks(ctx, name, m)
+		return options, revision, ok, err
+	}
+
+	options, err = decodeItems(result.Item[itemsKey])
+	if err != nil {
+		return nil, "", false, err
 	}
+	return options, revision, true, nil
+}
 
-	return v.Value, nil
+func encodeItems(options []string) types.AttributeValue {
+	list := make([]types.AttributeValue, len(options))
+	for i, option := range options {
+		list[i] = &types.AttributeValueMemberS{Value: option}
+	}
+	return &types.AttributeValueMemberL{Value: list}
+}
+
+func decodeItems(attr types.AttributeValue) ([]string, error) {
+	switch attr := attr.(type) {
+	case *types.AttributeValueMemberSS:
+		return attr.Value, nil
+	case *types.AttributeValueMemberL:
+		options := make([]string, len(attr.Value))
+		for i, elem := range attr.Value {
+			s, ok := elem.(*types.AttributeValueMemberS)
+			if !ok {
+				return nil, fmt.Errorf("invalid type %T in group items", elem)
+			}
+			options[i] = s.Value
+		}
+		return options, nil
+	default:
+		return nil, fmt.Errorf("invalid type %T in group items", attr)
+	}
 }
 
 // Put saves the provided options into a named group for this Store's
 // partition.
 func (s Store) Put(ctx context.Context, name string, options []string) error {
-	_, err := s.db.PutItem(ctx, &dynamodb.PutItemInput{
-		TableName: &s.table,
-		Item: map[string]types.AttributeValue{
-			partitionKey: &types.AttributeValueMemberS{Value: s.partition},
-			groupKey:     &types.AttributeValueMemberS{Value: name},
-			itemsKey:     &types.AttributeValueMemberSS{Value: options},
-		},
-	})
+	return s.put(ctx, name, options, nil)
+}
+
+// errRevisionChanged indicates that a conditional put found a different
+// revision of the group than the one it was based on.
+var errRevisionChanged = errors.New("group changed since it was read")
+
+// put saves options into a named group. If revision is non-nil, it only saves
+// them if the group's row still has that revision, and returns
+// errRevisionChanged otherwise.
+func (s Store) put(ctx context.Context, name string, options []string, revision *string) error {
+	item := s.key(name)
+	item[revisionKey] = &types.AttributeValueMemberS{Value: newGeneration()}
+
+	var (
+		m       manifest
+		chunked bool
+	)
+	if chunks := splitChunks(options, maxChunkBytes); len(chunks) > 1 {
+		var err error
+		m, err = s.putChunks(ctx, name, chunks)
+		if err != nil {
+			return fmt.Errorf("saving %q for %q to table %q: %w", name, s.partition, s.table, err)
+		}
+		chunked = true
+		item[chunkCountKey] = &types.AttributeValueMemberN{Value: strconv.Itoa(m.Count)}
+		item[chunkGenerationKey] = &types.AttributeValueMemberS{Value: m.Generation}
+	} else {
+		item[itemsKey] = encodeItems(options)
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName:    &s.table,
+		Item:         item,
+		ReturnValues: types.ReturnValueAllOld,
+	}
+	if revision != nil {
+		// Rows without a revision were written by older versions of the
+		// randomizer, or don't exist yet.
+		cond := expression.AttributeNotExists(expression.Name(revisionKey))
+		if *revision != "" {
+			cond = expression.Name(revisionKey).Equal(expression.Value(*revision))
+		}
+		expr, err := expression.NewBuilder().WithCondition(cond).Build()
+		if err != nil {
+			return fmt.Errorf("building expression: %w", err)
+		}
+		input.ConditionExpression = expr.Condition()
+		input.ExpressionAttributeNames = expr.Names()
+		input.ExpressionAttributeValues = expr.Values()
+	}
+
+	result, err := s.db.PutItem(ctx, input)
+	var conflict *types.ConditionalCheckFailedException
+	if errors.As(err, &conflict) {
+		if chunked {
+			// The new chunks were never referenced, so nothing can be reading them.
+			s.deleteChunks(ctx, name, m)
+		}
+		return errRevisionChanged
+	}
 	if err != nil {
 		return fmt.Errorf("saving %q for %q to table %q: %w", name, s.partition, s.table, err)
 	}
 
-	return nil
+	return s.deleteOldChunks(ctx, name, result.Attributes)
+}
+
+// Update implements randomizer.Updater. It reads the group with a strongly
+// consistent read, and saves the update only if no other write to the group
+// happened in between, trying again a few times if one did.
+func (s Store) Update(ctx context.Context, name string, update func([]string) ([]string, error)) error {
+	for attempt := range maxAttempts {
+		if attempt > 0 {
+			time.Sleep(backoff(attempt))
+		}
+
+		options, revision, ok, err := s.get(ctx, name, true)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		updated, err := update(options)
+		if err != nil {
+			return err
+		}
+		if err := s.put(ctx, name, updated, &revision); !errors.Is(err, errRevisionChanged) {
+			return err
+		}
+	}
+	return fmt.Errorf("updating %q for %q in table %q: too many concurrent writes", name, s.partition, s.table)
 }
 
 // Delete removes the named group from this Store's partition.
 func (s Store) Delete(ctx context.Context, name string) (bool, error) {
 	result, err := s.db.DeleteItem(ctx, &dynamodb.DeleteItemInput{
-		TableName: &s.table,
-		Key: map[string]types.AttributeValue{
-			partitionKey: &types.AttributeValueMemberS{Value: s.partition},
-			groupKey:     &types.AttributeValueMemberS{Value: name},
-		},
+		TableName:    &s.table,
+		Key:          s.key(name),
 		ReturnValues: types.ReturnValueAllOld,
 	})
 	if err != nil {
@@ -159,5 +383,41 @@ func (s Store) Delete(ctx context.Context, name string) (bool, error) {
 	}
 
 	existed := len(result.Attributes) > 0
-	return existed, nil
+	return existed, s.deleteOldChunks(ctx, name, result.Attributes)
+}
+
+// Partitions lists every partition with at least one group in this Store's
+// table. It scans the full table, and is intended for administrative use
+// rather than request handling.
+func (s Store) Partitions(ctx context.Context) ([]string, error) {
+	expr, err := expression.NewBuilder().
+		WithProjection(expression.NamesList(
+			expression.Name(partitionKey),
+		)).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("building expression: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	paginator := dynamodb.NewScanPaginator(s.db, &dynamodb.ScanInput{
+		TableName:                &s.table,
+		ProjectionExpression:     expr.Projection(),
+		ExpressionAttributeNames: expr.Names(),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("scanning partitions from table %q: %w", s.table, err)
+		}
+		for _, item := range page.Items {
+			v, ok := item[partitionKey].(*types.AttributeValueMemberS)
+			if !ok {
+				return nil, fmt.Errorf("invalid type %T in partition names", item[partitionKey])
+			}
+			seen[v.Value] = true
+		}
+	}
+
+	return slices.Collect(maps.Keys(seen)), nil
 }
@@ -2,10 +2,9 @@ package dynamodb
 
 import (
 	"context"
+	"fmt"
 	"os"
-
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"strconv"
 
 	"github.com/featherbread/randomizer/internal/awsconfig"
 	"github.com/featherbread/randomizer/internal/randomizer"
@@ -14,7 +13,7 @@ import (
 
 func init() {
 	registry.Provide("dynamodb", FactoryFromEnv,
-		"DYNAMODB", "DYNAMODB_TABLE", "DYNAMODB_ENDPOINT")
+		"DYNAMODB", "DYNAMODB_TABLE", "DYNAMODB_ENDPOINT_URL", "DYNAMODB_ENDPOINT", "DYNAMODB_CONSISTENT_READS")
 }
 
 // FactoryFromEnv returns a store.Factory whose stores are backed by Amazon
@@ -22,21 +21,29 @@ func init() {
 //
 // AWS configuration is read as described at
 // https://docs.aws.amazon.com/cli/latest/userguide/cli-chap-getting-started.html.
+// See [EndpointFromEnv] and [NewClient] for local development support.
+//
+// Setting DYNAMODB_CONSISTENT_READS to a true value enables
+// [WithConsistentReads].
 func FactoryFromEnv(ctx context.Context) (func(string) randomizer.Store, error) {
 	cfg, err := awsconfig.New(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	table := tableFromEnv()
-	db := dynamodb.NewFromConfig(cfg, func(opts *dynamodb.Options) {
-		if endpoint := os.Getenv("DYNAMODB_ENDPOINT"); endpoint != "" {
-			opts.BaseEndpoint = aws.String(endpoint)
+	var consistent bool
+	if v := os.Getenv("DYNAMODB_CONSISTENT_READS"); v != "" {
+		consistent, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DYNAMODB_CONSISTENT_READS: %w", err)
 		}
-	})
+	}
+
+	table := tableFromEnv()
+	db := NewClient(cfg, EndpointFromEnv())
 
 	return func(partition string) randomizer.Store {
-		store, err := New(db, table, partition)
+		store, err := New(db, table, partition, WithConsistentReads(consistent))
 		if err != nil {
 			panic(err)
 		}
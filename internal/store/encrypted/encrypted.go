@@ -0,0 +1,381 @@
+// Package encrypted wraps randomizer stores to encrypt the options they save,
+// using a separate data key for each partition.
+//
+// Data keys are derived with HKDF from a master secret held by the deployment
+// and a random salt saved in each partition. Since the master secret never
+// reaches the store, a leak of the store's contents alone reveals no options.
+// Deleting a partition's salt (see [Store.Shred]) makes its existing data
+// permanently unreadable, even to holders of the master secret.
+//
+// Keys are per partition rather than per workspace, since a store only knows
+// the partition it was created for, and Slack partitions channels without
+// naming their workspace. A workspace's data spans the partitions of its
+// channels and its own workspace-wide partition, each with its own key, so
+// crypto-shredding a workspace means shredding each of them, as the admin API's
+// workspace deletion does.
+//
+// Group names are not encrypted, as stores use them as keys.
+package encrypted
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/store/wrap"
+)
+
+// saltRecord is the name under which each partition's key salt is saved. It
+// follows the randomizer's convention for internal records, which keeps it
+// hidden from users.
+const saltRecord = "/encryption-salt"
+
+// ciphertextPrefix marks encrypted options, so that options saved before
+// encryption was enabled remain readable and are encrypted on their next save.
+const ciphertextPrefix = "enc:v1:"
+
+// MinSecretLength is the minimum length of a master secret in bytes.
+const MinSecretLength = 32
+
+// Keyring derives data keys for partitions from a master secret, and caches
+// each partition's salt for reading.
+type Keyring struct {
+	secret []byte
+
+	mu    sync.Mutex
+	salts map[string][]byte
+}
+
+// NewKeyring creates a Keyring with the provided master secret.
+func NewKeyring(secret []byte) (*Keyring, error) {
+	if len(secret) < MinSecretLength {
+		return nil, fmt.Errorf("master secret must be at least %d bytes", MinSecretLength)
+	}
+	return &Keyring{secret: secret, salts: make(map[string][]byte)}, nil
+}
+
+// WrapFromEnv wraps factory to encrypt stored options if STORE_ENCRYPTION_KEY
+// is set to a base64-encoded master secret. Otherwise, it returns factory
+// unchanged.
+func WrapFromEnv(factory func(string) randomizer.Store) (func(string) randomizer.Store, error) {
+	encoded, ok := os.LookupEnv("STORE_ENCRYPTION_KEY")
+	if !ok {
+		return factory, nil
+	}
+
+	secret, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("STORE_ENCRYPTION_KEY is not valid base64: %w", err)
+	}
+	keyring, err := NewKeyring(secret)
+	if err != nil {
+		return nil, fmt.Errorf("STORE_ENCRYPTION_KEY: %w", err)
+	}
+	return Wrap(factory, keyring), nil
+}
+
+// Wrap returns a factory whose stores encrypt options with keys from keyring.
+func Wrap(factory func(string) randomizer.Store, keyring *Keyring) func(string) randomizer.Store {
+	return func(partition string) randomizer.Store {
+		return Store{
+			base:      factory(partition),
+			keyring:   keyring,
+			partition: partition,
+		}
+	}
+}
+
+// Store encrypts the options saved in an underlying store.
+type Store struct {
+	base      randomizer.Store
+	keyring   *Keyring
+	partition string
+}
+
+// List implements randomizer.Store.
+func (s Store) List(ctx context.Context) ([]string, error) {
+	return s.base.List(ctx)
+}
+
+// ListAfter implements randomizer.Pager, paging through the base store if it
+// can.
+func (s Store) ListAfter(ctx context.Context, after string, limit int) ([]string, bool, error) {
+	return randomizer.ListAfter(ctx, s.base, after, limit)
+}
+
+// Get implements randomizer.Store.
+func (s Store) Get(ctx context.Context, group string) ([]string, error) {
+	stored, err := s.base.Get(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	return s.open(ctx, group, stored)
+}
+
+// Put implements randomizer.Store.
+func (s Store) Put(ctx context.Context, group string, options []string) error {
+	sealed, err := s.seal(ctx, group, options)
+	if err != nil {
+		return err
+	}
+	return s.base.Put(ctx, group, sealed)
+}
+
+// Update implements randomizer.Updater, atomically if the underlying store
+// does.
+func (s Store) Update(ctx context.Context, group string, update func([]string) ([]string, error)) error {
+	return randomizer.Update(ctx, s.base, group, func(stored []string) ([]string, error) {
+		options, err := s.open(ctx, group, stored)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := update(options)
+		if err != nil {
+			return nil, err
+		}
+		return s.seal(ctx, group, updated)
+	})
+}
+
+// open decrypts the options of a group read from the underlying store.
+func (s Store) open(ctx context.Context, group string, stored []string) ([]string, error) {
+	if group == saltRecord || !anyEncrypted(stored) {
+		return stored, nil
+	}
+
+	aead, err := s.aead(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	options, err := decryptAll(aead, group, stored)
+	if err == nil {
+		return options, nil
+	}
+
+	// The cached salt may be out of date, if another instance shredded the
+	// partition and saved new data under a new salt.
+	salt, saltErr := s.loadSalt(ctx)
+	if saltErr != nil || salt == nil {
+		return nil, err
+	}
+	s.keyring.mu.Lock()
+	s.keyring.salts[s.partition] = salt
+	s.keyring.mu.Unlock()
+	if aead, err = s.keyring.aead(s.partition, salt); err != nil {
+		return nil, err
+	}
+	return decryptAll(aead, group, stored)
+}
+
+func decryptAll(aead cipher.AEAD, group string, stored []string) ([]string, error) {
+	options := make([]string, len(stored))
+	for i, value := range stored {
+		var err error
+		options[i], err = decrypt(aead, group, value)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting %q: %w", group, err)
+		}
+	}
+	return options, nil
+}
+
+// seal encrypts the options of a group to write to the underlying store.
+func (s Store) seal(ctx context.Context, group string, options []string) ([]string, error) {
+	if group == saltRecord {
+		return nil, errors.New("can't overwrite encryption salt")
+	}
+
+	aead, err := s.aead(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := make([]string, len(options))
+	for i, option := range options {
+		sealed[i], err = encrypt(aead, group, option)
+		if err != nil {
+			return nil, fmt.Errorf("encrypting %q: %w", group, err)
+		}
+	}
+	return sealed, nil
+}
+
+// Delete implements randomizer.Store.
+func (s Store) Delete(ctx context.Context, group string) (bool, error) {
+	return s.base.Delete(ctx, group)
+}
+
+// Reserved reports whether the store keeps its own data under the given name,
+// which can't be overwritten.
+func (s Store) Reserved(name string) bool {
+	return name == saltRecord
+}
+
+// Shred deletes this partition's key salt, making any encrypted data that
+// remains in the partition permanently unreadable. Other instances check the
+// salt before their next write to the partition, and start a new one.
+func (s Store) Shred(ctx context.Context) error {
+	s.keyring.mu.Lock()
+	delete(s.keyring.salts, s.partition)
+	s.keyring.mu.Unlock()
+
+	_, err := s.base.Delete(ctx, saltRecord)
+	return err
+}
+
+// Partitions implements admin.PartitionLister if the underlying store does.
+func (s Store) Partitions(ctx context.Context) ([]string, error) {
+	return wrap.Partitions(ctx, s.base)
+}
+
+// Snapshot implements admin.Snapshotter if the underlying store does.
+func (s Store) Snapshot(ctx context.Context, w io.Writer) (int64, error) {
+	return wrap.Snapshot(ctx, s.base, w)
+}
+
+// aead returns the cipher for this store's partition. When sealing, it reads
+// the partition's salt from the store rather than the keyring's cache, and
+// creates a salt for partitions that don't have one.
+func (s Store) aead(ctx context.Context, seal bool) (cipher.AEAD, error) {
+	salt, err := s.salt(ctx, seal)
+	if err != nil {
+		return nil, err
+	}
+	return s.keyring.aead(s.partition, salt)
+}
+
+func (k *Keyring) aead(partition string, salt []byte) (cipher.AEAD, error) {
+	info := "randomizer partition data key v1|" + partition
+	key, err := hkdf.Key(sha256.New, k.secret, salt, info, 32)
+	if err != nil {
+		return nil, fmt.Errorf("deriving data key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// salt returns the partition's key salt.
+//
+// Reads may use the keyring's cached salt, but writes always check the stored
+// one. Otherwise, after another instance shreds the partition, this one could
+// go on encrypting new data with the destroyed salt, leaving it unreadable
+// from the start.
+func (s Store) salt(ctx context.Context, seal bool) ([]byte, error) {
+	if !seal {
+		s.keyring.mu.Lock()
+		salt, ok := s.keyring.salts[s.partition]
+		s.keyring.mu.Unlock()
+		if ok {
+			return salt, nil
+		}
+	}
+
+	salt, err := s.loadSalt(ctx)
+	if err == nil && salt == nil && seal {
+		salt, err = s.createSalt(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if salt == nil {
+		return nil, errors.New("data key for this partition has been destroyed")
+	}
+
+	s.keyring.mu.Lock()
+	s.keyring.salts[s.partition] = salt
+	s.keyring.mu.Unlock()
+	return salt, nil
+}
+
+// loadSalt reads the partition's key salt, or returns nil if it has none.
+func (s Store) loadSalt(ctx context.Context) ([]byte, error) {
+	stored, err := s.base.Get(ctx, saltRecord)
+	if err != nil {
+		return nil, fmt.Errorf("loading key salt: %w", err)
+	}
+	if len(stored) == 0 {
+		return nil, nil
+	}
+	salt, err := base64.StdEncoding.DecodeString(stored[0])
+	if err != nil {
+		return nil, fmt.Errorf("decoding key salt: %w", err)
+	}
+	return salt, nil
+}
+
+// createSalt saves a new key salt for the partition, unless another writer
+// saves one first, and returns the salt that the store ends up with.
+func (s Store) createSalt(ctx context.Context) ([]byte, error) {
+	fresh := make([]byte, 16)
+	rand.Read(fresh)
+	err := randomizer.Update(ctx, s.base, saltRecord, func(stored []string) ([]string, error) {
+		if len(stored) > 0 {
+			return stored, nil
+		}
+		return []string{base64.StdEncoding.EncodeToString(fresh)}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("saving key salt: %w", err)
+	}
+	// Stores that can't update atomically may still let a racing writer
+	// replace our salt, so only trust what the store holds now.
+	salt, err := s.loadSalt(ctx)
+	if err == nil && salt == nil {
+		err = errors.New("key salt disappeared after saving")
+	}
+	return salt, err
+}
+
+func anyEncrypted(values []string) bool {
+	for _, v := range values {
+		if strings.HasPrefix(v, ciphertextPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// encrypt seals an option, binding it to its group name so that ciphertexts
+// can't be moved between groups unnoticed.
+func encrypt(aead cipher.AEAD, group, option string) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	rand.Read(nonce)
+	sealed := aead.Seal(nonce, nonce, []byte(option), []byte(group))
+	return ciphertextPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+func decrypt(aead cipher.AEAD, group, value string) (string, error) {
+	encoded, ok := strings.CutPrefix(value, ciphertextPrefix)
+	if !ok {
+		return value, nil
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(group))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,142 @@
+package encrypted
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+)
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	base := rndtest.Store{"legacy": {"plain"}}
+	keyring, err := NewKeyring([]byte(strings.Repeat("k", MinSecretLength)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := Wrap(func(string) randomizer.Store { return base }, keyring)("C12345678")
+
+	if err := store.Put(ctx, "lunch", []string{"ramen", "sushi"}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	for _, value := range base["lunch"] {
+		if !strings.HasPrefix(value, ciphertextPrefix) {
+			t.Errorf("stored option %q is not encrypted", value)
+		}
+	}
+
+	got, err := store.Get(ctx, "lunch")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	slices.Sort(got)
+	if want := []string{"ramen", "sushi"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	legacy, err := store.Get(ctx, "legacy")
+	if err != nil || !slices.Equal(legacy, []string{"plain"}) {
+		t.Errorf("got legacy options %v (err %v), want [plain]", legacy, err)
+	}
+}
+
+func TestShred(t *testing.T) {
+	ctx := context.Background()
+	base := rndtest.Store{}
+	keyring, err := NewKeyring([]byte(strings.Repeat("k", MinSecretLength)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := Wrap(func(string) randomizer.Store { return base }, keyring)("C12345678").(Store)
+
+	if err := store.Put(ctx, "lunch", []string{"ramen", "sushi"}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if err := store.Shred(ctx); err != nil {
+		t.Fatalf("shred: %v", err)
+	}
+	if _, err := store.Get(ctx, "lunch"); err == nil {
+		t.Error("read shredded data without error")
+	}
+}
+
+func TestSaltRace(t *testing.T) {
+	ctx := context.Background()
+	base := rndtest.Store{}
+
+	// Another instance saves the partition's first group while this one is
+	// between reading the missing salt and creating its own.
+	other := newInstance(t, base)
+	racing := newInstance(t, base)
+	racing.base = beforeUpdateStore{Store: base, before: func() {
+		if err := other.Put(ctx, "dinner", []string{"tacos"}); err != nil {
+			t.Fatalf("other put: %v", err)
+		}
+	}}
+	if err := racing.Put(ctx, "lunch", []string{"ramen"}); err != nil {
+		t.Fatalf("racing put: %v", err)
+	}
+
+	for _, instance := range []Store{other, racing, newInstance(t, base)} {
+		for group, want := range map[string]string{"lunch": "ramen", "dinner": "tacos"} {
+			if got, err := instance.Get(ctx, group); err != nil || !slices.Equal(got, []string{want}) {
+				t.Errorf("got %s options %v (err %v), want [%s]", group, got, err, want)
+			}
+		}
+	}
+}
+
+func TestShredElsewhere(t *testing.T) {
+	ctx := context.Background()
+	base := rndtest.Store{}
+
+	stale, shredder := newInstance(t, base), newInstance(t, base)
+	if err := stale.Put(ctx, "lunch", []string{"ramen"}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if err := shredder.Shred(ctx); err != nil {
+		t.Fatalf("shred: %v", err)
+	}
+
+	// The instance that cached the destroyed salt must not keep using it.
+	if err := stale.Put(ctx, "dinner", []string{"tacos"}); err != nil {
+		t.Fatalf("put after shred: %v", err)
+	}
+	if got, err := newInstance(t, base).Get(ctx, "dinner"); err != nil || !slices.Equal(got, []string{"tacos"}) {
+		t.Errorf("got options %v (err %v) written after a shred, want [tacos]", got, err)
+	}
+	if err := shredder.Put(ctx, "snacks", []string{"chips"}); err != nil {
+		t.Fatalf("put after shred: %v", err)
+	}
+	if got, err := stale.Get(ctx, "snacks"); err != nil || !slices.Equal(got, []string{"chips"}) {
+		t.Errorf("got options %v (err %v) from another instance, want [chips]", got, err)
+	}
+}
+
+// beforeUpdateStore calls before ahead of each update, to interleave another
+// writer.
+type beforeUpdateStore struct {
+	randomizer.Store
+	before func()
+}
+
+func (s beforeUpdateStore) Update(ctx context.Context, group string, update func([]string) ([]string, error)) error {
+	if s.before != nil {
+		s.before()
+	}
+	return randomizer.Update(ctx, s.Store, group, update)
+}
+
+// newInstance wraps base in an encrypted store with its own keyring, like
+// another server process that shares the same database would.
+func newInstance(t *testing.T, base randomizer.Store) Store {
+	t.Helper()
+	keyring, err := NewKeyring([]byte(strings.Repeat("k", MinSecretLength)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Wrap(func(string) randomizer.Store { return base }, keyring)("C12345678").(Store)
+}
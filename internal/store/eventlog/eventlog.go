@@ -0,0 +1,302 @@
+// Package eventlog wraps randomizer stores to keep a log of the changes to
+// each group, alongside the group itself, so that a group can be rebuilt as
+// it was at any point in its recent past.
+//
+// Every save and deletion of a group appends an event holding the group's
+// full new state to the group's log, which is a separate item in the same
+// partition. Logs keep the most recent [MaxEvents] events that fit in
+// [MaxLogBytes], so they can restore a mangled group as long as it hasn't
+// changed too many times since. A group too large to log in full gets an
+// event that notes the change without its options, which the log can't
+// restore.
+//
+// Records that the randomizer keeps for itself, like selection history, are
+// not logged.
+package eventlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/clock"
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/store/wrap"
+)
+
+// MaxEvents is the number of events kept in each group's log.
+const MaxEvents = 100
+
+// MaxLogBytes bounds the size of each group's log, which is rewritten in full
+// with every change to the group.
+const MaxLogBytes = 64 << 10
+
+// logPrefix begins the names of the items holding each group's log. Like
+// every record name, it starts with "/", which users can't save groups under.
+const logPrefix = "/events/"
+
+const timeFormat = time.RFC3339Nano
+
+// Event describes a change to a group.
+type Event struct {
+	At      time.Time
+	Deleted bool
+	// Unlogged indicates that the group was saved with too many options to
+	// log, so the event doesn't hold them.
+	Unlogged bool
+	// Options holds the group's options after the change, unless it was
+	// deleted or unlogged.
+	Options []string
+}
+
+func (e Event) entry() (string, error) {
+	at := e.At.UTC().Format(timeFormat)
+	if e.Deleted {
+		return at + " delete", nil
+	}
+	options, err := json.Marshal(e.Options)
+	if err != nil {
+		return "", err
+	}
+	entry := at + " put " + string(options)
+	if len(entry) > MaxLogBytes {
+		return at + " unlogged", nil
+	}
+	return entry, nil
+}
+
+func parseEvent(entry string) (Event, error) {
+	at, rest, _ := strings.Cut(entry, " ")
+	t, err := time.Parse(timeFormat, at)
+	if err != nil {
+		return Event{}, fmt.Errorf("invalid event time: %w", err)
+	}
+	switch kind, options, _ := strings.Cut(rest, " "); kind {
+	case "delete":
+		return Event{At: t, Deleted: true}, nil
+	case "unlogged":
+		return Event{At: t, Unlogged: true}, nil
+	case "put":
+		e := Event{At: t}
+		if err := json.Unmarshal([]byte(options), &e.Options); err != nil {
+			return Event{}, fmt.Errorf("invalid event options: %w", err)
+		}
+		return e, nil
+	default:
+		return Event{}, fmt.Errorf("invalid event kind %q", kind)
+	}
+}
+
+// WrapFromEnv wraps factory to log the changes to each group if EVENT_LOG is
+// set to 1. Otherwise, it returns factory unchanged.
+func WrapFromEnv(factory func(string) randomizer.Store, logger *slog.Logger) func(string) randomizer.Store {
+	if os.Getenv("EVENT_LOG") != "1" {
+		return factory
+	}
+	return Wrap(factory, logger)
+}
+
+// Wrap returns a factory whose stores log the changes to each group. Changes
+// that can't be logged are reported to logger, or to the default logger if
+// logger is nil.
+func Wrap(factory func(string) randomizer.Store, logger *slog.Logger) func(string) randomizer.Store {
+	return func(partition string) randomizer.Store {
+		return Store{base: factory(partition), logger: logger}
+	}
+}
+
+// Store logs the changes to the groups in an underlying store.
+//
+// A change that's saved but can't be logged still succeeds, since failing it
+// would tell the user that a saved change wasn't. The log misses that change,
+// so a later restoration may not reflect it.
+type Store struct {
+	base   randomizer.Store
+	logger *slog.Logger
+	clock  clock.Clock // Replaces the system clock in tests, if non-nil
+}
+
+// List implements randomizer.Store.
+func (s Store) List(ctx context.Context) ([]string, error) {
+	return s.base.List(ctx)
+}
+
+// ListAfter implements randomizer.Pager, paging through the base store if it
+// can.
+func (s Store) ListAfter(ctx context.Context, after string, limit int) ([]string, bool, error) {
+	return randomizer.ListAfter(ctx, s.base, after, limit)
+}
+
+// Get implements randomizer.Store.
+func (s Store) Get(ctx context.Context, name string) ([]string, error) {
+	return s.base.Get(ctx, name)
+}
+
+// Put implements randomizer.Store.
+func (s Store) Put(ctx context.Context, name string, options []string) error {
+	if err := s.base.Put(ctx, name, options); err != nil {
+		return err
+	}
+	s.log(ctx, name, Event{Options: options})
+	return nil
+}
+
+// Update implements randomizer.Updater, atomically if the underlying store
+// does.
+func (s Store) Update(ctx context.Context, name string, update func([]string) ([]string, error)) error {
+	var updated []string
+	err := randomizer.Update(ctx, s.base, name, func(options []string) ([]string, error) {
+		var err error
+		updated, err = update(options)
+		return updated, err
+	})
+	if err != nil {
+		return err
+	}
+	s.log(ctx, name, Event{Options: updated})
+	return nil
+}
+
+// Delete implements randomizer.Store.
+func (s Store) Delete(ctx context.Context, name string) (bool, error) {
+	existed, err := s.base.Delete(ctx, name)
+	if err != nil || !existed {
+		return existed, err
+	}
+	s.log(ctx, name, Event{Deleted: true})
+	return existed, nil
+}
+
+// Shred deletes the logs of every group in the partition, which a group's
+// deletion leaves behind, along with the underlying store's data key if it
+// has one, like an encrypted store does.
+func (s Store) Shred(ctx context.Context) error {
+	names, err := s.base.List(ctx)
+	if err != nil {
+		return fmt.Errorf("listing logs: %w", err)
+	}
+	for _, name := range names {
+		if strings.HasPrefix(name, logPrefix) {
+			if _, err := s.base.Delete(ctx, name); err != nil {
+				return fmt.Errorf("deleting %q: %w", name, err)
+			}
+		}
+	}
+	return wrap.Shred(ctx, s.base)
+}
+
+// Partitions implements admin.PartitionLister if the underlying store does.
+func (s Store) Partitions(ctx context.Context) ([]string, error) {
+	return wrap.Partitions(ctx, s.base)
+}
+
+// Snapshot implements admin.Snapshotter if the underlying store does.
+func (s Store) Snapshot(ctx context.Context, w io.Writer) (int64, error) {
+	return wrap.Snapshot(ctx, s.base, w)
+}
+
+// Events returns the logged changes to a group, from oldest to newest.
+func (s Store) Events(ctx context.Context, group string) ([]Event, error) {
+	entries, err := s.base.Get(ctx, logPrefix+group)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]Event, 0, len(entries))
+	for _, entry := range entries {
+		e, err := parseEvent(entry)
+		if err != nil {
+			return nil, fmt.Errorf("reading log of %q: %w", group, err)
+		}
+		events = append(events, e)
+	}
+	slices.SortStableFunc(events, func(x, y Event) int { return x.At.Compare(y.At) })
+	return events, nil
+}
+
+// StateAt replays a group's log to find the options it held at the given
+// time. It returns nil options if the group was deleted at that time, and an
+// error if the log doesn't reach back that far.
+func (s Store) StateAt(ctx context.Context, group string, at time.Time) ([]string, error) {
+	events, err := s.Events(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("no changes to %q are logged", group)
+	}
+	if at.Before(events[0].At) {
+		// The group may have been saved before logging started, or the event
+		// that saved it may have fallen off the log.
+		return nil, fmt.Errorf("the log of %q only reaches back to %s", group, events[0].At.Format(time.RFC3339))
+	}
+
+	var last Event
+	for _, e := range events {
+		if e.At.After(at) {
+			break
+		}
+		last = e
+	}
+	if last.Unlogged {
+		return nil, fmt.Errorf("%q had too many options to log at that time", group)
+	}
+	return last.Options, nil
+}
+
+// Restore saves a group as it was at the given time, or deletes it if it
+// didn't exist then, and returns its restored options. The restoration is
+// logged like any other change, so it can be undone.
+func (s Store) Restore(ctx context.Context, group string, at time.Time) ([]string, error) {
+	options, err := s.StateAt(ctx, group, at)
+	if err != nil {
+		return nil, err
+	}
+	if len(options) == 0 {
+		_, err = s.Delete(ctx, group)
+	} else {
+		err = s.Put(ctx, group, options)
+	}
+	return options, err
+}
+
+// log appends an event for a change to a group, unless the change was to one
+// of the randomizer's own records. It reports any failure to the logger.
+func (s Store) log(ctx context.Context, name string, e Event) {
+	if strings.HasPrefix(name, "/") {
+		return
+	}
+	e.At = clock.Or(s.clock).Now()
+	err := s.append(ctx, name, e)
+	if err != nil {
+		logger := s.logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.Error("Failed to log change to group", "group", name, "err", err)
+	}
+}
+
+func (s Store) append(ctx context.Context, name string, e Event) error {
+	entry, err := e.entry()
+	if err != nil {
+		return err
+	}
+	return randomizer.Update(ctx, s.base, logPrefix+name, func(entries []string) ([]string, error) {
+		entries = append(entries, entry)
+		// Keep the newest events that fit, which always includes this one.
+		start, size := max(len(entries)-MaxEvents, 0), 0
+		for i := len(entries) - 1; i >= start; i-- {
+			if size += len(entries[i]); size > MaxLogBytes {
+				start = i + 1
+				break
+			}
+		}
+		return entries[start:], nil
+	})
+}
@@ -0,0 +1,159 @@
+package eventlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/clock/clocktest"
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+)
+
+func TestRestore(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		base  = rndtest.Store{}
+		start = time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+		clock = clocktest.New(start)
+		store = Store{base: base, clock: clock}
+	)
+
+	must(t, store.Put(ctx, "lunch", []string{"ramen", "sushi"}))
+	clock.Advance(time.Hour)
+	must(t, randomizer.Update(ctx, store, "lunch", func(options []string) ([]string, error) {
+		return append(options, "tacos"), nil
+	}))
+	clock.Advance(time.Hour)
+	must(t, store.Put(ctx, "lunch", []string{"oops"}))
+	clock.Advance(time.Hour)
+	if _, err := store.Delete(ctx, "lunch"); err != nil {
+		t.Fatal(err)
+	}
+	must(t, store.Put(ctx, "/history/lunch", []string{"not logged"}))
+
+	events, err := store.Events(ctx, "lunch")
+	if err != nil || len(events) != 4 || !events[3].Deleted {
+		t.Fatalf("got events %+v (err %v), want 4 ending in a deletion", events, err)
+	}
+	if events, _ := store.Events(ctx, "/history/lunch"); len(events) > 0 {
+		t.Errorf("logged changes to a record: %+v", events)
+	}
+
+	restored, err := store.Restore(ctx, "lunch", start.Add(90*time.Minute))
+	if want := []string{"ramen", "sushi", "tacos"}; err != nil || !slices.Equal(restored, want) {
+		t.Fatalf("restored %v (err %v), want %v", restored, err, want)
+	}
+	if got, _ := store.Get(ctx, "lunch"); !slices.Equal(got, restored) {
+		t.Errorf("got %v after restoring, want %v", got, restored)
+	}
+	if events, _ := store.Events(ctx, "lunch"); len(events) != 5 {
+		t.Errorf("got %d events after restoring, want 5", len(events))
+	}
+
+	if _, err := store.StateAt(ctx, "lunch", start.Add(-time.Minute)); err == nil {
+		t.Error("got a state from before the log started")
+	}
+}
+
+func TestMaxEvents(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		base  = rndtest.Store{}
+		clock = clocktest.New(time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC))
+		store = Store{base: base, clock: clock}
+	)
+	for range MaxEvents + 5 {
+		clock.Advance(time.Second)
+		must(t, store.Put(ctx, "lunch", []string{"ramen"}))
+	}
+	if n := len(base[logPrefix+"lunch"]); n != MaxEvents {
+		t.Errorf("log has %d events, want %d", n, MaxEvents)
+	}
+}
+
+func TestMaxLogBytes(t *testing.T) {
+	var (
+		ctx   = context.Background()
+		base  = rndtest.Store{}
+		start = time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+		clock = clocktest.New(start)
+		store = Store{base: base, clock: clock}
+	)
+	big := []string{strings.Repeat("x", MaxLogBytes/4)}
+	for range 5 {
+		clock.Advance(time.Second)
+		must(t, store.Put(ctx, "lunch", big))
+	}
+	if n := len(base[logPrefix+"lunch"]); n != 3 {
+		t.Errorf("log has %d events, want the 3 that fit", n)
+	}
+
+	clock.Advance(time.Second)
+	must(t, store.Put(ctx, "lunch", []string{strings.Repeat("x", MaxLogBytes)}))
+	events, err := store.Events(ctx, "lunch")
+	if err != nil || len(events) != 4 || !events[3].Unlogged {
+		t.Fatalf("got events %+v (err %v), want 4 ending in an unlogged one", events, err)
+	}
+	if _, err := store.StateAt(ctx, "lunch", clock.Now()); err == nil {
+		t.Error("got the state of an unlogged change")
+	}
+	if got, err := store.StateAt(ctx, "lunch", clock.Now().Add(-time.Second)); err != nil || !slices.Equal(got, big) {
+		t.Errorf("got state %.20q (err %v) before the unlogged change", got, err)
+	}
+}
+
+func TestShred(t *testing.T) {
+	ctx := context.Background()
+	base := rndtest.Store{}
+	store := Wrap(func(string) randomizer.Store { return base }, nil)("C12345678").(Store)
+
+	must(t, store.Put(ctx, "lunch", []string{"ramen"}))
+	if _, err := store.Delete(ctx, "lunch"); err != nil {
+		t.Fatal(err)
+	}
+	must(t, store.Shred(ctx))
+	if len(base) > 0 {
+		t.Errorf("store still holds %v after shredding", base)
+	}
+}
+
+func TestLogFailure(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		base   = rndtest.Store{}
+		logged bytes.Buffer
+		store  = Store{base: failingLogStore{base}, logger: slog.New(slog.NewTextHandler(&logged, nil))}
+	)
+	if err := store.Put(ctx, "lunch", []string{"ramen"}); err != nil {
+		t.Errorf("Put() of a saved group failed: %v", err)
+	}
+	if got := base["lunch"]; !slices.Equal(got, []string{"ramen"}) {
+		t.Errorf("saved %v, want [ramen]", got)
+	}
+	if !strings.Contains(logged.String(), "Failed to log change") {
+		t.Errorf("didn't report the failure, got log: %s", logged.String())
+	}
+}
+
+// failingLogStore fails to save the logs of groups.
+type failingLogStore struct{ rndtest.Store }
+
+func (s failingLogStore) Put(ctx context.Context, name string, options []string) error {
+	if strings.HasPrefix(name, logPrefix) {
+		return errors.New("log unavailable")
+	}
+	return s.Store.Put(ctx, name, options)
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -4,6 +4,7 @@ package firestore
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"cloud.google.com/go/firestore"
 	"github.com/googleapis/gax-go/v2/apierror"
@@ -32,13 +33,29 @@ func (f Store) List(ctx context.Context) ([]string, error) {
 
 	result := make([]string, len(allRefs))
 	for i, ref := range allRefs {
-		result[i] = ref.ID
+		result[i] = groupName(ref.ID)
 	}
 	return result, nil
 }
 
+// Firestore document IDs can't contain "/", which the randomizer uses in the
+// names of its internal records. Since user-created groups can't contain "/"
+// either, we can escape it without affecting any existing documents.
+var (
+	docIDEscaper   = strings.NewReplacer("/", "%2F")
+	docIDUnescaper = strings.NewReplacer("%2F", "/")
+)
+
+func docID(group string) string {
+	return docIDEscaper.Replace(group)
+}
+
+func groupName(docID string) string {
+	return docIDUnescaper.Replace(docID)
+}
+
 func (f Store) Get(ctx context.Context, group string) ([]string, error) {
-	ref := f.client.Collection(f.partition).Doc(group)
+	ref := f.client.Collection(f.partition).Doc(docID(group))
 	doc, err := ref.Get(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("getting document: %w", err)
@@ -54,13 +71,13 @@ func (f Store) Get(ctx context.Context, group string) ([]string, error) {
 }
 
 func (f Store) Put(ctx context.Context, group string, options []string) error {
-	ref := f.client.Collection(f.partition).Doc(group)
+	ref := f.client.Collection(f.partition).Doc(docID(group))
 	_, err := ref.Set(ctx, optionsDoc{options})
 	return err
 }
 
 func (f Store) Delete(ctx context.Context, group string) (bool, error) {
-	ref := f.client.Collection(f.partition).Doc(group)
+	ref := f.client.Collection(f.partition).Doc(docID(group))
 	_, err := ref.Delete(ctx, firestore.Exists)
 
 	apiErr, ok := apierror.FromError(err)
@@ -72,3 +89,18 @@ func (f Store) Delete(ctx context.Context, group string) (bool, error) {
 	}
 	return true, nil
 }
+
+// Partitions lists the top-level collections in the database backing this
+// store, each of which holds the groups for one partition.
+func (f Store) Partitions(ctx context.Context) ([]string, error) {
+	refs, err := f.client.Collections(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("listing collections: %w", err)
+	}
+
+	result := make([]string, len(refs))
+	for i, ref := range refs {
+		result[i] = ref.ID
+	}
+	return result, nil
+}
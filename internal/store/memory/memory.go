@@ -0,0 +1,93 @@
+// Package memory supports randomizer storage in process memory, for demos and
+// tests where persistence is not required.
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+// Database holds the groups for every partition in memory. It is safe for
+// concurrent use.
+type Database struct {
+	mu         sync.Mutex
+	partitions map[string]map[string][]string
+	seed       map[string][]string
+}
+
+// NewDatabase creates an empty Database. If seed is non-nil, every partition
+// starts with a copy of the groups in seed the first time it is used.
+func NewDatabase(seed map[string][]string) *Database {
+	return &Database{
+		partitions: make(map[string]map[string][]string),
+		seed:       seed,
+	}
+}
+
+// Factory returns a store.Factory for stores backed by this Database.
+func (db *Database) Factory() func(string) randomizer.Store {
+	return func(partition string) randomizer.Store {
+		return Store{db: db, partition: partition}
+	}
+}
+
+func (db *Database) groupsLocked(partition string) map[string][]string {
+	groups, ok := db.partitions[partition]
+	if !ok {
+		groups = make(map[string][]string, len(db.seed))
+		for name, options := range db.seed {
+			groups[name] = slices.Clone(options)
+		}
+		db.partitions[partition] = groups
+	}
+	return groups
+}
+
+// Store is a store for a single partition of a Database.
+type Store struct {
+	db        *Database
+	partition string
+}
+
+// List implements randomizer.Store.
+func (s Store) List(_ context.Context) ([]string, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	return slices.Sorted(maps.Keys(s.db.groupsLocked(s.partition))), nil
+}
+
+// Get implements randomizer.Store.
+func (s Store) Get(_ context.Context, group string) ([]string, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	return slices.Clone(s.db.groupsLocked(s.partition)[group]), nil
+}
+
+// Put implements randomizer.Store.
+func (s Store) Put(_ context.Context, group string, options []string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	s.db.groupsLocked(s.partition)[group] = slices.Clone(options)
+	return nil
+}
+
+// Delete implements randomizer.Store.
+func (s Store) Delete(_ context.Context, group string) (bool, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	groups := s.db.groupsLocked(s.partition)
+	_, existed := groups[group]
+	delete(groups, group)
+	return existed, nil
+}
+
+// Partitions lists every partition that has been used in the Database.
+func (s Store) Partitions(_ context.Context) ([]string, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	return slices.Sorted(maps.Keys(s.db.partitions)), nil
+}
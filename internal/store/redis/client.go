@@ -0,0 +1,251 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxIdleConns bounds the connections that a Client keeps open between
+// commands.
+const maxIdleConns = 8
+
+// Client sends commands to a single Redis server over the RESP2 protocol. It
+// supports only what the store needs: authentication, database selection, TLS,
+// and simple request-response commands, with a small pool of connections.
+//
+// A Client is safe for concurrent use.
+type Client struct {
+	addr     string
+	tls      *tls.Config
+	username string
+	password string
+	db       int
+
+	dialer net.Dialer
+	idle   chan *conn
+}
+
+// Error is an error reply from the Redis server.
+type Error string
+
+func (e Error) Error() string { return "redis: " + string(e) }
+
+// ParseURL returns a Client for the server described by a URL of the form
+// "redis://[[user]:password@]host[:port][/db]". The "rediss" scheme connects
+// with TLS, as required by ElastiCache with in-transit encryption.
+func ParseURL(rawURL string) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+
+	c := &Client{idle: make(chan *conn, maxIdleConns)}
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		c.tls = &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+	default:
+		return nil, fmt.Errorf(`Redis URL scheme must be "redis" or "rediss", not %q`, u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, errors.New("Redis URL is missing a host")
+	}
+	c.addr = u.Host
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.username = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if c.db, err = strconv.Atoi(db); err != nil || c.db < 0 {
+			return nil, fmt.Errorf("invalid Redis database number %q", db)
+		}
+	}
+	return c, nil
+}
+
+// Do sends a command to the server and returns its reply, which is a string,
+// an int64, a []byte (or nil, for a missing value), or a []any of these.
+func (c *Client) Do(ctx context.Context, args ...string) (any, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := cn.do(ctx, args...)
+	if err != nil && !errors.As(err, new(Error)) {
+		// The connection may be left mid-reply, so it can't be reused.
+		cn.Close()
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		// The context's cancellation may yet expire the connection's deadline.
+		cn.Close()
+		return reply, err
+	}
+	c.put(cn)
+	return reply, err
+}
+
+// Close closes the Client's idle connections.
+func (c *Client) Close() error {
+	for {
+		select {
+		case cn := <-c.idle:
+			cn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+	}
+
+	nc, err := c.dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+	if c.tls != nil {
+		tc := tls.Client(nc, c.tls)
+		if err := tc.HandshakeContext(ctx); err != nil {
+			nc.Close()
+			return nil, err
+		}
+		nc = tc
+	}
+
+	cn := &conn{Conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+	if c.password != "" {
+		auth := []string{"AUTH", c.password}
+		if c.username != "" {
+			auth = []string{"AUTH", c.username, c.password}
+		}
+		if _, err := cn.do(ctx, auth...); err != nil {
+			cn.Close()
+			return nil, fmt.Errorf("authenticating to Redis: %w", err)
+		}
+	}
+	if c.db != 0 {
+		if _, err := cn.do(ctx, "SELECT", strconv.Itoa(c.db)); err != nil {
+			cn.Close()
+			return nil, fmt.Errorf("selecting Redis database: %w", err)
+		}
+	}
+	return cn, nil
+}
+
+func (c *Client) put(cn *conn) {
+	select {
+	case c.idle <- cn:
+	default:
+		cn.Close()
+	}
+}
+
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+func (cn *conn) do(ctx context.Context, args ...string) (any, error) {
+	deadline, _ := ctx.Deadline() // The zero time clears any earlier deadline.
+	cn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { cn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	fmt.Fprintf(cn.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(cn.w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := cn.w.Flush(); err != nil {
+		return nil, contextError(ctx, err)
+	}
+	reply, err := readReply(cn.r)
+	if err != nil && !errors.As(err, new(Error)) {
+		return nil, contextError(ctx, err)
+	}
+	return reply, err
+}
+
+// contextError prefers the context's error to the I/O error caused by its
+// cancellation.
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	kind, rest := line[0], line[1:]
+	switch kind {
+	case '+':
+		return rest, nil
+	case '-':
+		return nil, Error(rest)
+	case ':':
+		return strconv.ParseInt(rest, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(rest)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return []byte(nil), nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(rest)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return []any(nil), nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			item, err := readReply(r)
+			var rerr Error
+			switch {
+			case errors.As(err, &rerr):
+				item = rerr // An error inside an array belongs to that item.
+			case err != nil:
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
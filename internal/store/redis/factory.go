@@ -0,0 +1,41 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/store/registry"
+)
+
+func init() {
+	registry.Provide("redis", FactoryFromEnv, "REDIS_URL")
+}
+
+// FactoryFromEnv returns a store.Factory whose stores are backed by the Redis
+// server at REDIS_URL, with keys namespaced by REDIS_KEY_PREFIX if it's set,
+// or by DefaultKeyPrefix otherwise.
+func FactoryFromEnv(_ context.Context) (func(string) randomizer.Store, error) {
+	rawURL, ok := os.LookupEnv("REDIS_URL")
+	if !ok {
+		return nil, errors.New("missing REDIS_URL in environment")
+	}
+	client, err := ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, ok := os.LookupEnv("REDIS_KEY_PREFIX")
+	if !ok {
+		prefix = DefaultKeyPrefix
+	}
+
+	return func(partition string) randomizer.Store {
+		store, err := New(client, prefix, partition)
+		if err != nil {
+			panic(err)
+		}
+		return store
+	}, nil
+}
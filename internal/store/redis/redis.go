@@ -0,0 +1,151 @@
+// Package redis supports randomizer storage in Redis, including managed
+// services like Amazon ElastiCache.
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DefaultKeyPrefix namespaces the keys that stores use in a Redis database.
+const DefaultKeyPrefix = "randomizer:"
+
+// Store is a store backed by a Redis database.
+//
+// Each partition is a single Redis hash, named with the partition and a
+// prefix, whose fields are the names of groups and whose values are the
+// options of each group encoded as a JSON array.
+type Store struct {
+	client *Client
+	prefix string
+	key    string
+}
+
+// New creates a new store for a partition, backed by the provided client and
+// namespaced by prefix.
+func New(client *Client, prefix, partition string) (Store, error) {
+	if client == nil {
+		return Store{}, errors.New("Redis client is required")
+	}
+	if partition == "" {
+		return Store{}, errors.New("partition is required")
+	}
+	return Store{client: client, prefix: prefix, key: prefix + partition}, nil
+}
+
+// List obtains the set of stored groups.
+func (s Store) List(ctx context.Context) ([]string, error) {
+	reply, err := s.client.Do(ctx, "HKEYS", s.key)
+	if err != nil {
+		return nil, fmt.Errorf("listing groups: %w", err)
+	}
+	return stringsReply(reply)
+}
+
+// Get obtains the options in a single named group.
+func (s Store) Get(ctx context.Context, name string) ([]string, error) {
+	reply, err := s.client.Do(ctx, "HGET", s.key, name)
+	if err != nil {
+		return nil, fmt.Errorf("getting group %q: %w", name, err)
+	}
+	data, _ := reply.([]byte)
+	if data == nil {
+		return nil, nil
+	}
+
+	var options []string
+	if err := json.Unmarshal(data, &options); err != nil {
+		return nil, fmt.Errorf("decoding group %q: %w", name, err)
+	}
+	return options, nil
+}
+
+// Put saves the provided options into a named group.
+func (s Store) Put(ctx context.Context, name string, options []string) error {
+	if options == nil {
+		options = []string{}
+	}
+	data, err := json.Marshal(options)
+	if err != nil {
+		return fmt.Errorf("encoding group %q: %w", name, err)
+	}
+	if _, err := s.client.Do(ctx, "HSET", s.key, name, string(data)); err != nil {
+		return fmt.Errorf("writing group %q: %w", name, err)
+	}
+	return nil
+}
+
+// Delete removes the named group from the store.
+func (s Store) Delete(ctx context.Context, name string) (existed bool, err error) {
+	reply, err := s.client.Do(ctx, "HDEL", s.key, name)
+	if err != nil {
+		return false, fmt.Errorf("deleting group %q: %w", name, err)
+	}
+	n, _ := reply.(int64)
+	return n > 0, nil
+}
+
+// Partitions lists every partition with at least one group in the database
+// backing this store, scanning the keys under the store's prefix. Redis removes
+// a hash along with its last field, so empty partitions don't appear.
+func (s Store) Partitions(ctx context.Context) ([]string, error) {
+	var (
+		partitions []string
+		cursor     = "0"
+	)
+	for {
+		reply, err := s.client.Do(ctx, "SCAN", cursor, "MATCH", globEscape(s.prefix)+"*", "COUNT", "100")
+		if err != nil {
+			return nil, fmt.Errorf("scanning partitions: %w", err)
+		}
+		items, ok := reply.([]any)
+		if !ok || len(items) != 2 {
+			return nil, fmt.Errorf("unexpected SCAN reply %v", reply)
+		}
+		next, _ := items[0].([]byte)
+		keys, err := stringsReply(items[1])
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			partitions = append(partitions, strings.TrimPrefix(key, s.prefix))
+		}
+		if cursor = string(next); cursor == "0" || cursor == "" {
+			// SCAN may return a key more than once.
+			slices.Sort(partitions)
+			return slices.Compact(partitions), nil
+		}
+	}
+}
+
+func stringsReply(reply any) ([]string, error) {
+	items, ok := reply.([]any)
+	if !ok && reply != nil {
+		return nil, fmt.Errorf("unexpected reply %v", reply)
+	}
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		value, ok := item.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("unexpected reply item %v", item)
+		}
+		values = append(values, string(value))
+	}
+	return values, nil
+}
+
+// globEscape escapes the characters of s that are special in a Redis glob.
+func globEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`\*?[]^`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
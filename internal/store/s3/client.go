@@ -0,0 +1,247 @@
+package s3
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// Client makes the few S3 API calls that stores need, signed with AWS
+// Signature Version 4. It talks to Amazon S3 by default, or to a compatible
+// service like Google Cloud Storage's XML API, MinIO, or LocalStack at a
+// custom endpoint.
+//
+// The randomizer doesn't use the AWS SDK's S3 client, which would add a large
+// dependency for the three API calls that stores make.
+type Client struct {
+	http        aws.HTTPClient
+	credentials aws.CredentialsProvider
+	signer      *v4.Signer
+	region      string
+	bucket      string
+	endpoint    *url.URL // Nil for Amazon S3's virtual-hosted endpoints
+	gcs         bool
+}
+
+// NewClient creates a client for a bucket, using the credentials, region, and
+// HTTP client of cfg. An empty endpoint uses Amazon S3. Any other endpoint is
+// addressed with path-style URLs, like https://example.com/bucket/key, and an
+// endpoint of https://storage.googleapis.com uses the preconditions of Google
+// Cloud Storage.
+func NewClient(cfg aws.Config, bucket, endpoint string) (*Client, error) {
+	if bucket == "" {
+		return nil, errors.New("S3 bucket is required")
+	}
+	if cfg.Credentials == nil {
+		return nil, errors.New("AWS credentials are required")
+	}
+	client := &Client{
+		http:        cmp.Or[aws.HTTPClient](cfg.HTTPClient, http.DefaultClient),
+		credentials: cfg.Credentials,
+		signer: v4.NewSigner(func(o *v4.SignerOptions) {
+			// S3 expects object keys to be escaped only once.
+			o.DisableURIPathEscaping = true
+		}),
+		region: cfg.Region,
+		bucket: bucket,
+	}
+	if endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+		}
+		client.endpoint = u
+		client.gcs = u.Host == "storage.googleapis.com"
+	}
+	if client.region == "" && client.endpoint == nil {
+		return nil, errors.New("AWS region is required for Amazon S3")
+	}
+	return client, nil
+}
+
+// errConflict indicates that an object changed since it was read.
+var errConflict = errors.New("object changed since it was read")
+
+// version identifies the revision of an object that a conditional write
+// replaces. The zero version stands for an object that doesn't exist yet.
+type version struct {
+	etag       string
+	generation string // Only for Google Cloud Storage
+}
+
+// getObject returns the contents and version of an object, or a nil slice if
+// it doesn't exist.
+func (c *Client) getObject(ctx context.Context, key string) ([]byte, version, error) {
+	resp, err := c.do(ctx, http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		return nil, version{}, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, version{}, fmt.Errorf("reading %s: %w", key, err)
+		}
+		return data, version{
+			etag:       resp.Header.Get("ETag"),
+			generation: resp.Header.Get("x-goog-generation"),
+		}, nil
+	case http.StatusNotFound:
+		return nil, version{}, nil
+	default:
+		return nil, version{}, statusError(resp, http.MethodGet, key)
+	}
+}
+
+// putObject writes an object, but only if it's still at version v. It returns
+// errConflict if another write happened first.
+func (c *Client) putObject(ctx context.Context, key string, data []byte, v version) error {
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	switch {
+	case c.gcs:
+		header.Set("x-goog-if-generation-match", cmp.Or(v.generation, "0"))
+	case v.etag == "":
+		header.Set("If-None-Match", "*")
+	default:
+		header.Set("If-Match", v.etag)
+	}
+
+	resp, err := c.do(ctx, http.MethodPut, key, nil, header, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		// S3 reports a conflicting write that's still in progress with 409.
+		return errConflict
+	default:
+		return statusError(resp, http.MethodPut, key)
+	}
+}
+
+// listObjects returns the keys of every object under prefix.
+func (c *Client) listObjects(ctx context.Context, prefix string) ([]string, error) {
+	var (
+		keys  []string
+		token string
+	)
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := c.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			err := statusError(resp, http.MethodGet, "?list-type=2")
+			resp.Body.Close()
+			return nil, err
+		}
+
+		var page struct {
+			Contents []struct {
+				Key string
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decoding object list: %w", err)
+		}
+		for _, obj := range page.Contents {
+			keys = append(keys, obj.Key)
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// do sends a signed request for an object, or for the bucket itself if key is
+// empty.
+func (c *Client) do(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.objectURL(key, query), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	creds, err := c.credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting AWS credentials: %w", err)
+	}
+	if err := c.signer.SignHTTP(ctx, creds, req, payloadHash, "s3", cmp.Or(c.region, "auto"), time.Now()); err != nil {
+		return nil, fmt.Errorf("signing S3 request: %w", err)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3 %s %s: %w", method, key, err)
+	}
+	return resp, nil
+}
+
+func (c *Client) objectURL(key string, query url.Values) string {
+	var u url.URL
+	if c.endpoint == nil {
+		u = url.URL{Scheme: "https", Host: fmt.Sprintf("%s.s3.%s.amazonaws.com", c.bucket, c.region), Path: "/"}
+	} else {
+		u = *c.endpoint
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + c.bucket + "/"
+	}
+	u.RawPath = escapePath(u.Path + key)
+	u.Path += key
+	u.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+	return u.String()
+}
+
+// escapePath escapes every byte of a path that SigV4 requires, which is more
+// than net/url escapes, so that the request matches its signature.
+func escapePath(path string) string {
+	var b strings.Builder
+	for i := range len(path) {
+		c := path[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// statusError describes an unexpected response, including the start of its
+// body, which S3 fills with an XML error document.
+func statusError(resp *http.Response, method, key string) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("S3 %s %s: %s: %s", method, key, resp.Status, bytes.TrimSpace(body))
+}
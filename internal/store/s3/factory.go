@@ -0,0 +1,50 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/featherbread/randomizer/internal/awsconfig"
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/store/registry"
+)
+
+func init() {
+	registry.Provide("s3", FactoryFromEnv, "S3_BUCKET", "S3_KEY_PREFIX", "S3_ENDPOINT")
+}
+
+// FactoryFromEnv returns a store.Factory whose stores are backed by objects in
+// the S3 bucket named by S3_BUCKET, with keys namespaced by S3_KEY_PREFIX if
+// it's set, or by DefaultKeyPrefix otherwise. Setting S3_ENDPOINT uses a
+// compatible service instead of Amazon S3 (see [NewClient]).
+//
+// AWS configuration is read as described at
+// https://docs.aws.amazon.com/cli/latest/userguide/cli-chap-getting-started.html.
+func FactoryFromEnv(ctx context.Context) (func(string) randomizer.Store, error) {
+	bucket, ok := os.LookupEnv("S3_BUCKET")
+	if !ok {
+		return nil, errors.New("missing S3_BUCKET in environment")
+	}
+	cfg, err := awsconfig.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	client, err := NewClient(cfg, bucket, os.Getenv("S3_ENDPOINT"))
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, ok := os.LookupEnv("S3_KEY_PREFIX")
+	if !ok {
+		prefix = DefaultKeyPrefix
+	}
+
+	return func(partition string) randomizer.Store {
+		store, err := New(client, prefix, partition)
+		if err != nil {
+			panic(err)
+		}
+		return store
+	}, nil
+}
@@ -0,0 +1,162 @@
+// Package s3 supports randomizer storage in Amazon S3, or a compatible object
+// store like Google Cloud Storage.
+package s3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// DefaultKeyPrefix namespaces the objects that stores use in a bucket.
+const DefaultKeyPrefix = "randomizer/"
+
+// objectSuffix ends the key of every partition's object.
+const objectSuffix = ".json"
+
+// maxAttempts bounds how many times a store rereads and rewrites a partition
+// whose object changed while it was being updated.
+const maxAttempts = 5
+
+// Store is a store backed by an object in an S3 bucket.
+//
+// Each partition is a single JSON object, named with the partition and a
+// prefix, that holds every group in the partition. Every operation reads the
+// whole object, and every change rewrites it with a conditional write, so that
+// concurrent changes to the same partition retry rather than overwrite each
+// other. This suits deployments with little traffic, which pay for a handful
+// of requests rather than a database.
+type Store struct {
+	client *Client
+	prefix string
+	key    string
+}
+
+// New creates a new store for a partition, backed by the provided client and
+// namespaced by prefix.
+func New(client *Client, prefix, partition string) (Store, error) {
+	if client == nil {
+		return Store{}, errors.New("S3 client is required")
+	}
+	if partition == "" {
+		return Store{}, errors.New("partition is required")
+	}
+	return Store{client: client, prefix: prefix, key: prefix + partition + objectSuffix}, nil
+}
+
+// partitionObject is the content of a partition's object.
+type partitionObject struct {
+	Groups map[string][]string `json:"groups"`
+}
+
+func (s Store) load(ctx context.Context) (partitionObject, version, error) {
+	data, v, err := s.client.getObject(ctx, s.key)
+	if err != nil {
+		return partitionObject{}, version{}, err
+	}
+	obj := partitionObject{Groups: make(map[string][]string)}
+	if data == nil {
+		return obj, v, nil
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return partitionObject{}, version{}, fmt.Errorf("decoding %s: %w", s.key, err)
+	}
+	if obj.Groups == nil {
+		obj.Groups = make(map[string][]string)
+	}
+	return obj, v, nil
+}
+
+// update applies change to the partition's groups, and saves them if change
+// reports that it changed them. If another write to the partition happens in
+// between, update starts over with the new groups.
+func (s Store) update(ctx context.Context, change func(groups map[string][]string) bool) error {
+	for range maxAttempts {
+		obj, v, err := s.load(ctx)
+		if err != nil {
+			return err
+		}
+		if !change(obj.Groups) {
+			return nil
+		}
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("encoding %s: %w", s.key, err)
+		}
+		err = s.client.putObject(ctx, s.key, data, v)
+		if !errors.Is(err, errConflict) {
+			return err
+		}
+	}
+	return fmt.Errorf("writing %s: %w after %d attempts", s.key, errConflict, maxAttempts)
+}
+
+// List obtains the set of stored groups.
+func (s Store) List(ctx context.Context) ([]string, error) {
+	obj, _, err := s.load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing groups: %w", err)
+	}
+	return slices.Sorted(maps.Keys(obj.Groups)), nil
+}
+
+// Get obtains the options in a single named group.
+func (s Store) Get(ctx context.Context, name string) ([]string, error) {
+	obj, _, err := s.load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting group %q: %w", name, err)
+	}
+	return obj.Groups[name], nil
+}
+
+// Put saves the provided options into a named group.
+func (s Store) Put(ctx context.Context, name string, options []string) error {
+	if options == nil {
+		options = []string{}
+	}
+	err := s.update(ctx, func(groups map[string][]string) bool {
+		groups[name] = options
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("writing group %q: %w", name, err)
+	}
+	return nil
+}
+
+// Delete removes the named group from the store.
+func (s Store) Delete(ctx context.Context, name string) (existed bool, err error) {
+	err = s.update(ctx, func(groups map[string][]string) bool {
+		_, existed = groups[name]
+		delete(groups, name)
+		return existed
+	})
+	if err != nil {
+		return false, fmt.Errorf("deleting group %q: %w", name, err)
+	}
+	return existed, nil
+}
+
+// Partitions lists every partition with an object in the bucket backing this
+// store, under the store's prefix. A partition whose last group was deleted
+// keeps an empty object, so it still appears.
+func (s Store) Partitions(ctx context.Context) ([]string, error) {
+	keys, err := s.client.listObjects(ctx, s.prefix)
+	if err != nil {
+		return nil, fmt.Errorf("listing partitions: %w", err)
+	}
+	var partitions []string
+	for _, key := range keys {
+		partition, ok := strings.CutSuffix(strings.TrimPrefix(key, s.prefix), objectSuffix)
+		// Partitions don't contain slashes, so deeper keys belong to
+		// something else that shares the prefix.
+		if ok && partition != "" && !strings.Contains(partition, "/") {
+			partitions = append(partitions, partition)
+		}
+	}
+	return partitions, nil
+}
@@ -0,0 +1,223 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestStore(t *testing.T) {
+	for _, gcs := range []bool{false, true} {
+		t.Run(fmt.Sprintf("gcs=%v", gcs), func(t *testing.T) {
+			srv := newFakeServer(t, "bucket", gcs)
+			client := srv.client(t)
+			store, err := New(client, DefaultKeyPrefix, "C1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := t.Context()
+
+			if groups, err := store.List(ctx); err != nil || len(groups) != 0 {
+				t.Fatalf("List() on empty store = %v, %v", groups, err)
+			}
+			if options, err := store.Get(ctx, "lunch"); err != nil || options != nil {
+				t.Fatalf("Get() of missing group = %v, %v", options, err)
+			}
+
+			want := []string{"pizza", "tacos \"al pastor\""}
+			if err := store.Put(ctx, "lunch", want); err != nil {
+				t.Fatalf("Put() failed: %v", err)
+			}
+			if got, err := store.Get(ctx, "lunch"); err != nil || !slices.Equal(got, want) {
+				t.Errorf("Get() = %q, %v; want %q", got, err, want)
+			}
+
+			// Another writer sneaks in between the next read and write, which
+			// the store should retry rather than overwrite.
+			srv.beforePut = func() {
+				srv.beforePut = nil
+				srv.objects["randomizer/C1.json"] = object{
+					data:       []byte(`{"groups":{"lunch":["pizza"],"dinner":["sushi"]}}`),
+					generation: srv.nextGeneration(),
+				}
+			}
+			if err := store.Put(ctx, "/history", []string{"x"}); err != nil {
+				t.Fatalf("Put() with a conflict failed: %v", err)
+			}
+			if groups, err := store.List(ctx); err != nil || !slices.Equal(groups, []string{"/history", "dinner", "lunch"}) {
+				t.Errorf("List() = %v, %v; want both writers' groups", groups, err)
+			}
+
+			other, _ := New(client, DefaultKeyPrefix, "workspace-T1")
+			if groups, _ := other.List(ctx); len(groups) != 0 {
+				t.Errorf("another partition sees groups %v", groups)
+			}
+			other.Put(ctx, "dinner", []string{"sushi"})
+			srv.objects["randomizer/C1/backup.json"] = object{data: []byte("{}")}
+			if partitions, err := store.Partitions(ctx); err != nil || !slices.Equal(partitions, []string{"C1", "workspace-T1"}) {
+				t.Errorf("Partitions() = %v, %v; want [C1 workspace-T1]", partitions, err)
+			}
+
+			if existed, err := store.Delete(ctx, "lunch"); err != nil || !existed {
+				t.Errorf("Delete() of saved group = %v, %v", existed, err)
+			}
+			if existed, err := store.Delete(ctx, "lunch"); err != nil || existed {
+				t.Errorf("Delete() of missing group = %v, %v", existed, err)
+			}
+		})
+	}
+}
+
+func TestStoreGivesUpOnConflicts(t *testing.T) {
+	srv := newFakeServer(t, "bucket", false)
+	store, _ := New(srv.client(t), DefaultKeyPrefix, "C1")
+	srv.beforePut = func() {
+		srv.objects["randomizer/C1.json"] = object{data: []byte(`{"groups":{}}`), generation: srv.nextGeneration()}
+	}
+	if err := store.Put(t.Context(), "lunch", []string{"pizza"}); err == nil {
+		t.Error("Put() succeeded despite a conflict on every attempt")
+	}
+}
+
+type object struct {
+	data       []byte
+	generation int
+}
+
+func (o object) etag() string {
+	return strconv.Quote(strconv.Itoa(o.generation))
+}
+
+// fakeServer implements the parts of the S3 API that stores use, with the
+// preconditions of either S3 or Google Cloud Storage.
+type fakeServer struct {
+	*httptest.Server
+	bucket string
+	gcs    bool
+
+	mu         sync.Mutex
+	objects    map[string]object
+	generation int
+	beforePut  func() // Called with mu held
+}
+
+func newFakeServer(t *testing.T, bucket string, gcs bool) *fakeServer {
+	srv := &fakeServer{bucket: bucket, gcs: gcs, objects: make(map[string]object)}
+	srv.Server = httptest.NewServer(http.HandlerFunc(srv.serve))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (s *fakeServer) client(t *testing.T) *Client {
+	cfg := aws.Config{
+		Region: "us-west-2",
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, nil
+		}),
+		HTTPClient: s.Server.Client(),
+	}
+	client, err := NewClient(cfg, s.bucket, s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.gcs = s.gcs
+	return client
+}
+
+func (s *fakeServer) nextGeneration() int {
+	s.generation++
+	return s.generation
+}
+
+func (s *fakeServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+		http.Error(w, "unsigned request", http.StatusForbidden)
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+s.bucket+"/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && key == "" && r.URL.Query().Get("list-type") == "2":
+		s.list(w, r)
+	case r.Method == http.MethodGet:
+		obj, ok := s.objects[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", obj.etag())
+		if s.gcs {
+			w.Header().Set("x-goog-generation", strconv.Itoa(obj.generation))
+		}
+		w.Write(obj.data)
+	case r.Method == http.MethodPut:
+		if s.beforePut != nil {
+			s.beforePut()
+		}
+		obj, exists := s.objects[key]
+		var match bool
+		if s.gcs {
+			want, _ := strconv.Atoi(r.Header.Get("x-goog-if-generation-match"))
+			match = (!exists && want == 0) || (exists && want == obj.generation)
+		} else {
+			match = (r.Header.Get("If-None-Match") == "*" && !exists) || (exists && r.Header.Get("If-Match") == obj.etag())
+		}
+		if !match {
+			http.Error(w, "PreconditionFailed", http.StatusPreconditionFailed)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		s.objects[key] = object{data: data, generation: s.nextGeneration()}
+	default:
+		http.Error(w, "unsupported", http.StatusMethodNotAllowed)
+	}
+}
+
+// list returns a single key per page, to exercise continuation.
+func (s *fakeServer) list(w http.ResponseWriter, r *http.Request) {
+	var keys []string
+	for key := range s.objects {
+		if strings.HasPrefix(key, r.URL.Query().Get("prefix")) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	if token := r.URL.Query().Get("continuation-token"); token != "" {
+		i, _ := strconv.Atoi(token)
+		keys = keys[i:]
+	}
+
+	type contents struct{ Key string }
+	page := struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Contents              []contents
+		IsTruncated           bool
+		NextContinuationToken string `xml:",omitempty"`
+	}{}
+	if len(keys) > 0 {
+		page.Contents = []contents{{keys[0]}}
+	}
+	if len(keys) > 1 {
+		page.IsTruncated = true
+		done, _ := strconv.Atoi(r.URL.Query().Get("continuation-token"))
+		page.NextContinuationToken = strconv.Itoa(done + 1)
+	}
+	xml.NewEncoder(w).Encode(page)
+}
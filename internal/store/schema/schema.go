@@ -0,0 +1,309 @@
+// Package schema wraps randomizer stores to version the format of the items
+// they save, so that the format can change without migrating every item at
+// once.
+//
+// Each item saved through a Store begins with a marker entry naming the
+// schema version it was written with. Items without a marker, saved before
+// versioning existed, are version 1. Reading an item upgrades it to the
+// current version in memory, and the upgrade is saved the next time the item
+// is written. [Store.Migrate] saves the upgrade for every item in a partition
+// at once.
+//
+// Versioning is opt-in with [WrapFromEnv], since builds from before versioning
+// existed show the marker entry as an option. [Store.Downgrade] removes the
+// markers again before rolling back to such a build.
+//
+// To change the format of stored items, increment CurrentVersion and add a
+// migration from the previous version, along with a downgrade back to it.
+package schema
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/store/wrap"
+)
+
+// CurrentVersion is the schema version of items that this build writes.
+const CurrentVersion = 2
+
+// markerPrefix begins the marker entry of a versioned item. Only an item's
+// first entry can be a marker, and every item saved through a Store has one,
+// so entries provided by users are never mistaken for markers.
+const markerPrefix = "\x00schema="
+
+// Migration upgrades the entries of an item, given its name, from one schema
+// version to the next.
+type Migration func(name string, entries []string) ([]string, error)
+
+// migrations maps each schema version before CurrentVersion to the migration
+// that upgrades items from it.
+var migrations = map[int]Migration{
+	// Version 2 introduced the marker entry without changing anything else.
+	1: func(_ string, entries []string) ([]string, error) { return entries, nil },
+}
+
+// downgrades maps each schema version after 1 to the migration that returns
+// items to the previous version.
+var downgrades = map[int]Migration{
+	2: func(_ string, entries []string) ([]string, error) { return entries, nil },
+}
+
+// WrapFromEnv wraps factory to version the items it saves if SCHEMA_VERSIONS
+// is set to 1. Otherwise, its stores still read versioned items, but save them
+// in the format of version 1 without a marker, so that turning versioning off
+// doesn't expose the markers saved while it was on.
+func WrapFromEnv(factory func(string) randomizer.Store) func(string) randomizer.Store {
+	if os.Getenv("SCHEMA_VERSIONS") != "1" {
+		return func(partition string) randomizer.Store {
+			return Store{base: factory(partition), unmarked: true}
+		}
+	}
+	return Wrap(factory)
+}
+
+// Wrap returns a factory whose stores version the items they save.
+func Wrap(factory func(string) randomizer.Store) func(string) randomizer.Store {
+	return func(partition string) randomizer.Store {
+		return Store{base: factory(partition)}
+	}
+}
+
+// Store versions the items saved in an underlying store.
+type Store struct {
+	base     randomizer.Store
+	unmarked bool
+}
+
+// List implements randomizer.Store.
+func (s Store) List(ctx context.Context) ([]string, error) {
+	return s.base.List(ctx)
+}
+
+// ListAfter implements randomizer.Pager, paging through the base store if it
+// can.
+func (s Store) ListAfter(ctx context.Context, after string, limit int) ([]string, bool, error) {
+	return randomizer.ListAfter(ctx, s.base, after, limit)
+}
+
+// Get implements randomizer.Store. It returns an item's entries upgraded to
+// the current version, without the marker entry.
+func (s Store) Get(ctx context.Context, name string) ([]string, error) {
+	stored, err := s.base.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	entries, _, err := upgrade(name, stored)
+	return entries, err
+}
+
+// Put implements randomizer.Store.
+func (s Store) Put(ctx context.Context, name string, entries []string) error {
+	stored, err := s.store(name, entries)
+	if err != nil {
+		return err
+	}
+	return s.base.Put(ctx, name, stored)
+}
+
+// Update implements randomizer.Updater, atomically if the underlying store
+// does.
+func (s Store) Update(ctx context.Context, name string, update func([]string) ([]string, error)) error {
+	return randomizer.Update(ctx, s.base, name, func(stored []string) ([]string, error) {
+		entries, _, err := upgrade(name, stored)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := update(entries)
+		if err != nil {
+			return nil, err
+		}
+		return s.store(name, updated)
+	})
+}
+
+// store returns the entries to save for an item in the underlying store.
+func (s Store) store(name string, entries []string) ([]string, error) {
+	if s.unmarked {
+		return downgrade(name, entries)
+	}
+	return mark(entries), nil
+}
+
+// Delete implements randomizer.Store.
+func (s Store) Delete(ctx context.Context, name string) (bool, error) {
+	return s.base.Delete(ctx, name)
+}
+
+// Outdated returns the names of the items in the partition that were saved
+// with an older schema version.
+func (s Store) Outdated(ctx context.Context) ([]string, error) {
+	return s.migrate(ctx, false)
+}
+
+// Migrate saves every item in the partition that was saved with an older
+// schema version in the current version, and returns the names of the items
+// that it saved.
+//
+// An item written between Migrate reading and saving it may lose that write,
+// so Migrate is best run while the partition is quiet.
+func (s Store) Migrate(ctx context.Context) ([]string, error) {
+	return s.migrate(ctx, true)
+}
+
+func (s Store) migrate(ctx context.Context, save bool) ([]string, error) {
+	return s.rewrite(ctx, func(name string, stored []string) ([]string, bool, error) {
+		entries, version, err := upgrade(name, stored)
+		if err != nil || version == CurrentVersion || len(entries) == 0 {
+			return nil, false, err
+		}
+		if !save {
+			return nil, true, nil
+		}
+		return mark(entries), true, nil
+	})
+}
+
+// Marked returns the names of the items in the partition that were saved with
+// a marker.
+func (s Store) Marked(ctx context.Context) ([]string, error) {
+	return s.unmark(ctx, false)
+}
+
+// Downgrade saves every item in the partition that was saved with a marker in
+// the format of version 1, without one, so that a build from before schema
+// versions existed can read it. It returns the names of the items that it
+// saved. Like Migrate, it's best run while the partition is quiet, and with
+// versioning turned off so that later writes don't add the markers back.
+func (s Store) Downgrade(ctx context.Context) ([]string, error) {
+	return s.unmark(ctx, true)
+}
+
+func (s Store) unmark(ctx context.Context, save bool) ([]string, error) {
+	return s.rewrite(ctx, func(name string, stored []string) ([]string, bool, error) {
+		entries, _, err := upgrade(name, stored)
+		if err != nil || len(stored) == 0 || !strings.HasPrefix(stored[0], markerPrefix) {
+			return nil, false, err
+		}
+		if !save {
+			return nil, true, nil
+		}
+		entries, err = downgrade(name, entries)
+		return entries, true, err
+	})
+}
+
+// rewrite calls fn with the stored entries of every item in the partition,
+// and saves the entries that fn returns for the items it reports as changed,
+// unless those entries are nil. It returns the names of the changed items.
+func (s Store) rewrite(ctx context.Context, fn func(name string, stored []string) ([]string, bool, error)) ([]string, error) {
+	names, err := s.base.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing items: %w", err)
+	}
+
+	reserved, _ := s.base.(interface{ Reserved(string) bool })
+	var changed []string
+	for _, name := range names {
+		if reserved != nil && reserved.Reserved(name) {
+			continue
+		}
+		stored, err := s.base.Get(ctx, name)
+		if err != nil {
+			return changed, fmt.Errorf("getting %q: %w", name, err)
+		}
+		entries, ok, err := fn(name, stored)
+		if err != nil {
+			return changed, err
+		}
+		if !ok {
+			continue
+		}
+		if entries != nil {
+			if err := s.base.Put(ctx, name, entries); err != nil {
+				return changed, fmt.Errorf("saving %q: %w", name, err)
+			}
+		}
+		changed = append(changed, name)
+	}
+	return changed, nil
+}
+
+// Shred shreds the underlying store's data key if it has one, like an
+// encrypted store does, and otherwise does nothing.
+func (s Store) Shred(ctx context.Context) error {
+	return wrap.Shred(ctx, s.base)
+}
+
+// Partitions implements admin.PartitionLister if the underlying store does.
+func (s Store) Partitions(ctx context.Context) ([]string, error) {
+	return wrap.Partitions(ctx, s.base)
+}
+
+// Snapshot implements admin.Snapshotter if the underlying store does.
+func (s Store) Snapshot(ctx context.Context, w io.Writer) (int64, error) {
+	return wrap.Snapshot(ctx, s.base, w)
+}
+
+// mark adds the current version's marker to an item's entries. Empty items
+// stay empty, since some stores treat them as deleted.
+func mark(entries []string) []string {
+	if len(entries) == 0 {
+		return entries
+	}
+	return append([]string{markerPrefix + strconv.Itoa(CurrentVersion)}, entries...)
+}
+
+// downgrade migrates an item's entries from the current version to version 1.
+func downgrade(name string, entries []string) ([]string, error) {
+	for v := CurrentVersion; v > 1; v-- {
+		migrate, ok := downgrades[v]
+		if !ok {
+			return nil, fmt.Errorf("no downgrade from schema version %d", v)
+		}
+		var err error
+		if entries, err = migrate(name, entries); err != nil {
+			return nil, fmt.Errorf("downgrading %q from schema version %d: %w", name, v, err)
+		}
+	}
+	return entries, nil
+}
+
+// upgrade removes the marker from a stored item, and migrates its entries from
+// the version that the marker names to the current version. It also returns
+// the version that the item was stored with.
+func upgrade(name string, stored []string) (entries []string, version int, err error) {
+	entries, version = stored, 1
+	if len(stored) > 0 {
+		if v, ok := strings.CutPrefix(stored[0], markerPrefix); ok {
+			if version, err = strconv.Atoi(v); err != nil || version < 1 {
+				return nil, 0, fmt.Errorf("item %q has invalid schema version %q", name, v)
+			}
+			entries = stored[1:]
+		}
+	}
+
+	if version > CurrentVersion {
+		// Reading a newer format as if it were the current one could corrupt it
+		// on the next write, e.g. after rolling back a deployment.
+		return nil, version, fmt.Errorf(
+			"item %q has schema version %d, but this build only supports up to version %d",
+			name, version, CurrentVersion,
+		)
+	}
+	for v := version; v < CurrentVersion; v++ {
+		migrate, ok := migrations[v]
+		if !ok {
+			return nil, version, fmt.Errorf("no migration from schema version %d", v)
+		}
+		if entries, err = migrate(name, entries); err != nil {
+			return nil, version, fmt.Errorf("migrating %q from schema version %d: %w", name, v, err)
+		}
+	}
+	return entries, version, nil
+}
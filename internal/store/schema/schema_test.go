@@ -0,0 +1,197 @@
+package schema
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+)
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	base := rndtest.Store{"legacy": {"plain"}}
+	store := Wrap(func(string) randomizer.Store { return base })("C12345678")
+
+	if err := store.Put(ctx, "lunch", []string{"ramen", "sushi"}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if want := []string{"\x00schema=2", "ramen", "sushi"}; !slices.Equal(base["lunch"], want) {
+		t.Errorf("stored %q, want %q", base["lunch"], want)
+	}
+
+	got, err := store.Get(ctx, "lunch")
+	if err != nil || !slices.Equal(got, []string{"ramen", "sushi"}) {
+		t.Errorf("got %v (err %v), want [ramen sushi]", got, err)
+	}
+
+	legacy, err := store.Get(ctx, "legacy")
+	if err != nil || !slices.Equal(legacy, []string{"plain"}) {
+		t.Errorf("got legacy options %v (err %v), want [plain]", legacy, err)
+	}
+	if !slices.Equal(base["legacy"], []string{"plain"}) {
+		t.Errorf("reading a legacy item rewrote it to %q", base["legacy"])
+	}
+}
+
+func TestNewerVersion(t *testing.T) {
+	ctx := context.Background()
+	base := rndtest.Store{
+		"future":  {"\x00schema=99", "ramen"},
+		"invalid": {"\x00schema=x", "ramen"},
+	}
+	store := Wrap(func(string) randomizer.Store { return base })("C12345678")
+
+	if _, err := store.Get(ctx, "future"); err == nil || !strings.Contains(err.Error(), "version 99") {
+		t.Errorf("got error %v reading a newer version", err)
+	}
+	if _, err := store.Get(ctx, "invalid"); err == nil {
+		t.Error("read an item with an invalid version")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	base := rndtest.Store{
+		"legacy":  {"plain"},
+		"current": {"\x00schema=2", "ramen"},
+		"salt":    {"secret"},
+	}
+	store := Wrap(func(string) randomizer.Store { return reservingStore{base} })("C12345678").(Store)
+
+	outdated, err := store.Outdated(ctx)
+	if err != nil || !slices.Equal(outdated, []string{"legacy"}) {
+		t.Fatalf("got outdated %v (err %v), want [legacy]", outdated, err)
+	}
+	if !slices.Equal(base["legacy"], []string{"plain"}) {
+		t.Fatalf("listing outdated items rewrote one to %q", base["legacy"])
+	}
+
+	migrated, err := store.Migrate(ctx)
+	if err != nil || !slices.Equal(migrated, []string{"legacy"}) {
+		t.Fatalf("got migrated %v (err %v), want [legacy]", migrated, err)
+	}
+	if want := []string{"\x00schema=2", "plain"}; !slices.Equal(base["legacy"], want) {
+		t.Errorf("stored %q after migrating, want %q", base["legacy"], want)
+	}
+	if !slices.Equal(base["salt"], []string{"secret"}) {
+		t.Errorf("migrated reserved item to %q", base["salt"])
+	}
+
+	if migrated, err := store.Migrate(ctx); err != nil || len(migrated) > 0 {
+		t.Errorf("migrated %v (err %v) a second time", migrated, err)
+	}
+}
+
+func TestDowngrade(t *testing.T) {
+	ctx := context.Background()
+	base := rndtest.Store{
+		"legacy":  {"plain"},
+		"current": {"\x00schema=2", "ramen", "sushi"},
+		"salt":    {"\x00schema=2", "secret"},
+	}
+	store := Wrap(func(string) randomizer.Store { return reservingStore{base} })("C12345678").(Store)
+
+	marked, err := store.Marked(ctx)
+	if err != nil || !slices.Equal(marked, []string{"current"}) {
+		t.Fatalf("got marked %v (err %v), want [current]", marked, err)
+	}
+	downgraded, err := store.Downgrade(ctx)
+	if err != nil || !slices.Equal(downgraded, []string{"current"}) {
+		t.Fatalf("got downgraded %v (err %v), want [current]", downgraded, err)
+	}
+
+	// A build from before schema versions existed reads the base store
+	// directly, and should see exactly the options that were saved.
+	want := rndtest.Store{
+		"legacy":  {"plain"},
+		"current": {"ramen", "sushi"},
+		"salt":    {"\x00schema=2", "secret"},
+	}
+	for name, entries := range want {
+		if !slices.Equal(base[name], entries) {
+			t.Errorf("stored %q as %q after downgrading, want %q", name, base[name], entries)
+		}
+	}
+	if downgraded, err := store.Downgrade(ctx); err != nil || len(downgraded) > 0 {
+		t.Errorf("downgraded %v (err %v) a second time", downgraded, err)
+	}
+}
+
+func TestWrapFromEnv(t *testing.T) {
+	ctx := context.Background()
+	base := rndtest.Store{}
+	factory := func(string) randomizer.Store { return base }
+
+	t.Setenv("SCHEMA_VERSIONS", "")
+	if err := WrapFromEnv(factory)("C12345678").Put(ctx, "lunch", []string{"ramen"}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if !slices.Equal(base["lunch"], []string{"ramen"}) {
+		t.Errorf("stored %q without SCHEMA_VERSIONS, want no marker", base["lunch"])
+	}
+
+	t.Setenv("SCHEMA_VERSIONS", "1")
+	if err := WrapFromEnv(factory)("C12345678").Put(ctx, "lunch", []string{"ramen"}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if want := []string{"\x00schema=2", "ramen"}; !slices.Equal(base["lunch"], want) {
+		t.Errorf("stored %q with SCHEMA_VERSIONS=1, want %q", base["lunch"], want)
+	}
+
+	// Turning versioning off again still reads the markers saved while it was
+	// on, and removes them as items are written.
+	t.Setenv("SCHEMA_VERSIONS", "")
+	store := WrapFromEnv(factory)("C12345678")
+	if got, err := store.Get(ctx, "lunch"); err != nil || !slices.Equal(got, []string{"ramen"}) {
+		t.Errorf("got %q (err %v) after turning versions off, want [ramen]", got, err)
+	}
+	if err := store.Put(ctx, "lunch", []string{"sushi"}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if !slices.Equal(base["lunch"], []string{"sushi"}) {
+		t.Errorf("stored %q after turning versions off, want no marker", base["lunch"])
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	base := &updatingStore{Store: rndtest.Store{"lunch": {"ramen"}}}
+	store := Wrap(func(string) randomizer.Store { return base })("C12345678")
+
+	err := randomizer.Update(ctx, store, "lunch", func(options []string) ([]string, error) {
+		if !slices.Equal(options, []string{"ramen"}) {
+			t.Errorf("update got %q, want [ramen]", options)
+		}
+		return append(options, "sushi"), nil
+	})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if base.updates != 1 {
+		t.Errorf("underlying store got %d updates, want 1", base.updates)
+	}
+	if want := []string{"\x00schema=2", "ramen", "sushi"}; !slices.Equal(base.Store["lunch"], want) {
+		t.Errorf("stored %q, want %q", base.Store["lunch"], want)
+	}
+}
+
+type reservingStore struct{ rndtest.Store }
+
+func (reservingStore) Reserved(name string) bool { return name == "salt" }
+
+type updatingStore struct {
+	rndtest.Store
+	updates int
+}
+
+func (s *updatingStore) Update(ctx context.Context, name string, update func([]string) ([]string, error)) error {
+	s.updates++
+	updated, err := update(s.Store[name])
+	if err != nil {
+		return err
+	}
+	return s.Store.Put(ctx, name, updated)
+}
@@ -40,8 +40,18 @@ type Factory = func(partition string) randomizer.Store
 // or missing store configurations, or use a default bbolt configuration if no
 // build tags have been used to restrict the backends available in this binary.
 func FactoryFromEnv(ctx context.Context) (Factory, error) {
+	chosen, err := BackendFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return registry.Registry[chosen].FactoryFromEnv(ctx)
+}
+
+// BackendFromEnv returns the name of the store backend that [FactoryFromEnv]
+// selects, like "dynamodb", without connecting to it.
+func BackendFromEnv() (string, error) {
 	if len(registry.Registry) == 0 {
-		return nil, errors.New("no store backends available in this build")
+		return "", errors.New("no store backends available in this build")
 	}
 
 	candidates := make(map[string]struct{})
@@ -63,16 +73,15 @@ func FactoryFromEnv(ctx context.Context) (Factory, error) {
 
 	if chosen == "" && len(candidates) == 0 {
 		available := slices.Sorted(maps.Keys(registry.Registry))
-		return nil, fmt.Errorf(
+		return "", fmt.Errorf(
 			"can't find environment settings to select between store backends: %v", available)
 	}
 	if chosen == "" {
 		options := slices.Sorted(maps.Keys(candidates))
-		return nil, fmt.Errorf(
+		return "", fmt.Errorf(
 			"environment settings match multiple store backends: %v", options)
 	}
-
-	return registry.Registry[chosen].FactoryFromEnv(ctx)
+	return chosen, nil
 }
 
 func envHasAny(names ...string) bool {
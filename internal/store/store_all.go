@@ -1,4 +1,4 @@
-//go:build !randomizer.bbolt && !randomizer.dynamodb && !randomizer.firestore
+//go:build !randomizer.bbolt && !randomizer.dynamodb && !randomizer.firestore && !randomizer.redis && !randomizer.s3
 
 package store
 
@@ -6,6 +6,8 @@ import (
 	_ "github.com/featherbread/randomizer/internal/store/bbolt"
 	_ "github.com/featherbread/randomizer/internal/store/dynamodb"
 	_ "github.com/featherbread/randomizer/internal/store/firestore"
+	_ "github.com/featherbread/randomizer/internal/store/redis"
+	_ "github.com/featherbread/randomizer/internal/store/s3"
 )
 
 func init() {
@@ -0,0 +1,5 @@
+//go:build randomizer.redis
+
+package store
+
+import _ "github.com/featherbread/randomizer/internal/store/redis"
@@ -0,0 +1,5 @@
+//go:build randomizer.s3
+
+package store
+
+import _ "github.com/featherbread/randomizer/internal/store/s3"
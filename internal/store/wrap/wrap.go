@@ -0,0 +1,45 @@
+// Package wrap provides the optional store capabilities that a store wrapper,
+// like an encrypted or schema-versioned store, passes through to the store
+// that it wraps.
+package wrap
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+// Shred shreds base's data key if it has one, like an encrypted store does,
+// and otherwise does nothing.
+func Shred(ctx context.Context, base randomizer.Store) error {
+	if shredder, ok := base.(interface{ Shred(context.Context) error }); ok {
+		return shredder.Shred(ctx)
+	}
+	return nil
+}
+
+// Partitions lists every partition in base's backing database, or fails if
+// base can't list partitions.
+func Partitions(ctx context.Context, base randomizer.Store) ([]string, error) {
+	lister, ok := base.(interface {
+		Partitions(context.Context) ([]string, error)
+	})
+	if !ok {
+		return nil, errors.New("underlying store can't list partitions")
+	}
+	return lister.Partitions(ctx)
+}
+
+// Snapshot copies base's entire backing database to w, or fails if base
+// can't take snapshots.
+func Snapshot(ctx context.Context, base randomizer.Store, w io.Writer) (int64, error) {
+	snapshotter, ok := base.(interface {
+		Snapshot(context.Context, io.Writer) (int64, error)
+	})
+	if !ok {
+		return 0, errors.New("underlying store can't take snapshots")
+	}
+	return snapshotter.Snapshot(ctx, w)
+}
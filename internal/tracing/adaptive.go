@@ -0,0 +1,164 @@
+// Package tracing provides OpenTelemetry span processing suited to serving
+// slash commands under Slack's response deadline, along with OTLP export of
+// spans and logs that ties each log record to its trace.
+package tracing
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/featherbread/randomizer/internal/clock"
+)
+
+// Default thresholds for an AdaptiveProcessor, measured from the start of each
+// request. DefaultDropAfter matches the randomizer's default response budget
+// for Slack.
+const (
+	DefaultSyncUntil = time.Second
+	DefaultDropAfter = 2500 * time.Millisecond
+)
+
+// AdaptiveProcessor exports spans in a way that keeps tracing from pushing a
+// request past Slack's response deadline.
+//
+// Early in a request, it exports each span synchronously as it ends, like
+// [sdktrace.NewSimpleSpanProcessor], so that spans aren't lost if the
+// environment freezes after responding (as AWS Lambda does). Once a request
+// has run for SyncUntil, it queues spans for batched export instead, which
+// happens in the background or at the next flush. Once a request has run for
+// DropAfter, it drops the request's spans entirely, since a request that slow
+// needs every remaining moment to respond.
+//
+// A request starts with its local root span: a span with no parent, or whose
+// parent came from another process.
+type AdaptiveProcessor struct {
+	// SyncUntil overrides DefaultSyncUntil.
+	SyncUntil time.Duration
+	// DropAfter overrides DefaultDropAfter.
+	DropAfter time.Duration
+	// Clock, if non-nil, replaces the system clock for measuring the age of
+	// requests.
+	Clock clock.Clock
+
+	exporter sdktrace.SpanExporter
+	batch    sdktrace.SpanProcessor
+
+	exportMu sync.Mutex // Exporters don't support concurrent calls.
+
+	mu    sync.Mutex
+	roots map[trace.TraceID]time.Time
+
+	dropped    atomic.Int64
+	deferFlush atomic.Bool
+}
+
+// NewAdaptiveProcessor returns an AdaptiveProcessor that exports spans through
+// exporter, with the provided options for batched exports.
+func NewAdaptiveProcessor(exporter sdktrace.SpanExporter, opts ...sdktrace.BatchSpanProcessorOption) *AdaptiveProcessor {
+	p := &AdaptiveProcessor{
+		exporter: exporter,
+		roots:    make(map[trace.TraceID]time.Time),
+	}
+	p.batch = sdktrace.NewBatchSpanProcessor(lockedExporter{exporter, &p.exportMu}, opts...)
+	return p
+}
+
+// Dropped returns the number of spans that the processor has dropped because
+// their requests ran too long.
+func (p *AdaptiveProcessor) Dropped() int64 {
+	return p.dropped.Load()
+}
+
+// OnStart notes the start of each request.
+func (p *AdaptiveProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	if isLocalRoot(s) {
+		p.mu.Lock()
+		p.roots[s.SpanContext().TraceID()] = s.StartTime()
+		p.mu.Unlock()
+	}
+}
+
+// OnEnd exports, queues, or drops a span depending on the age of its request.
+func (p *AdaptiveProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	traceID := s.SpanContext().TraceID()
+	p.mu.Lock()
+	start, ok := p.roots[traceID]
+	root := isLocalRoot(s)
+	if root {
+		delete(p.roots, traceID)
+	}
+	p.mu.Unlock()
+	if !ok {
+		start = s.StartTime()
+	}
+
+	syncUntil := p.SyncUntil
+	if syncUntil == 0 {
+		syncUntil = DefaultSyncUntil
+	}
+	dropAfter := p.DropAfter
+	if dropAfter == 0 {
+		dropAfter = DefaultDropAfter
+	}
+
+	age := clock.Or(p.Clock).Now().Sub(start)
+	if root {
+		p.deferFlush.Store(age >= syncUntil)
+	}
+	if !s.SpanContext().IsSampled() {
+		return
+	}
+
+	switch {
+	case age >= dropAfter:
+		p.dropped.Add(1)
+	case age >= syncUntil:
+		p.batch.OnEnd(s)
+	default:
+		p.exportMu.Lock()
+		defer p.exportMu.Unlock()
+		// Like the SDK's simple processor, we have nowhere to report an export
+		// error besides the global error handler, which the exporter may
+		// already use.
+		_ = p.exporter.ExportSpans(context.Background(), []sdktrace.ReadOnlySpan{s})
+	}
+}
+
+// ForceFlush exports every queued span, unless the latest request to finish
+// ran long enough to queue its spans. In that case, it leaves the queue for
+// the next background export, since the flush may delay the response to that
+// request (as on AWS Lambda, where spans are flushed after each invocation).
+func (p *AdaptiveProcessor) ForceFlush(ctx context.Context) error {
+	if p.deferFlush.Load() {
+		return nil
+	}
+	return p.batch.ForceFlush(ctx)
+}
+
+// Shutdown exports every queued span, and shuts down the exporter.
+func (p *AdaptiveProcessor) Shutdown(ctx context.Context) error {
+	return p.batch.Shutdown(ctx)
+}
+
+func isLocalRoot(s sdktrace.ReadOnlySpan) bool {
+	parent := s.Parent()
+	return !parent.IsValid() || parent.IsRemote()
+}
+
+// lockedExporter serializes the batch processor's exports with the
+// processor's synchronous ones.
+type lockedExporter struct {
+	sdktrace.SpanExporter
+	mu *sync.Mutex
+}
+
+func (e lockedExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.SpanExporter.ExportSpans(ctx, spans)
+}
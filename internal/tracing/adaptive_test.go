@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/featherbread/randomizer/internal/clock/clocktest"
+)
+
+type recordingExporter struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (e *recordingExporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, s := range spans {
+		e.names = append(e.names, s.Name())
+	}
+	return nil
+}
+
+func (e *recordingExporter) Shutdown(context.Context) error { return nil }
+
+func (e *recordingExporter) exported() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return slices.Clone(e.names)
+}
+
+func TestAdaptiveProcessor(t *testing.T) {
+	exporter := new(recordingExporter)
+	clk := clocktest.New(time.Now())
+	p := NewAdaptiveProcessor(exporter, sdktrace.WithBatchTimeout(time.Hour))
+	p.Clock = clk
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(p)).Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, fast := tracer.Start(ctx, "fast")
+	fast.End()
+	if got := exporter.exported(); !slices.Equal(got, []string{"fast"}) {
+		t.Fatalf("after fast span, exported %v; want it exported right away", got)
+	}
+
+	clk.Advance(1500 * time.Millisecond)
+	_, slow := tracer.Start(ctx, "slow")
+	slow.End()
+	if got := exporter.exported(); len(got) != 1 {
+		t.Fatalf("after slow span, exported %v; want it queued", got)
+	}
+
+	clk.Advance(1500 * time.Millisecond)
+	_, late := tracer.Start(ctx, "late")
+	late.End()
+	root.End()
+	if p.Dropped() != 2 {
+		t.Errorf("dropped %d spans, want the late span and the root", p.Dropped())
+	}
+
+	if err := p.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("ForceFlush() = %v", err)
+	}
+	if got := exporter.exported(); len(got) != 1 {
+		t.Errorf("flush after a slow request exported %v; want the queue left alone", got)
+	}
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() = %v", err)
+	}
+	if got := exporter.exported(); !slices.Equal(got, []string{"fast", "slow"}) {
+		t.Errorf("after shutdown, exported %v; want fast and slow", got)
+	}
+}
@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"context"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/featherbread/randomizer/internal/cache"
+)
+
+// LazySpanExporter returns an exporter that creates the exporter it wraps on
+// its first export, which keeps the work of creating it off of a process's
+// startup path. Concurrent first exports share a single attempt, and a failed
+// attempt is retried on the next export.
+func LazySpanExporter(create cache.Loader[sdktrace.SpanExporter]) sdktrace.SpanExporter {
+	return lazySpanExporter{cache.Lazy(create)}
+}
+
+type lazySpanExporter struct {
+	exporter *cache.Value[sdktrace.SpanExporter]
+}
+
+func (e lazySpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	exporter, err := e.exporter.Get(ctx)
+	if err != nil {
+		return err
+	}
+	return exporter.ExportSpans(ctx, spans)
+}
+
+func (e lazySpanExporter) Shutdown(ctx context.Context) error {
+	if exporter, ok := e.exporter.Peek(); ok {
+		return exporter.Shutdown(ctx)
+	}
+	return nil
+}
+
+// lazyLogExporter is the log counterpart to [LazySpanExporter].
+type lazyLogExporter struct {
+	exporter *cache.Value[sdklog.Exporter]
+}
+
+func (e lazyLogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	exporter, err := e.exporter.Get(ctx)
+	if err != nil {
+		return err
+	}
+	return exporter.Export(ctx, records)
+}
+
+func (e lazyLogExporter) Shutdown(ctx context.Context) error {
+	if exporter, ok := e.exporter.Peek(); ok {
+		return exporter.Shutdown(ctx)
+	}
+	return nil
+}
+
+func (e lazyLogExporter) ForceFlush(ctx context.Context) error {
+	if exporter, ok := e.exporter.Peek(); ok {
+		return exporter.ForceFlush(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"go.opentelemetry.io/contrib/bridges/otelslog"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/featherbread/randomizer/internal/cache"
+)
+
+// instrumentationName identifies the randomizer's logs and spans to OTLP
+// backends.
+const instrumentationName = "github.com/featherbread/randomizer"
+
+// CorrelatedHandler adds the trace and span IDs of the current span, if any,
+// to each record as "trace_id" and "span_id" attributes, so that a log line
+// can lead to its trace and a trace can lead to its logs. The IDs are added
+// within any groups opened with WithGroup.
+type CorrelatedHandler struct {
+	slog.Handler
+}
+
+// Handle implements slog.Handler.
+func (h CorrelatedHandler) Handle(ctx context.Context, r slog.Record) error {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r = r.Clone()
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler.
+func (h CorrelatedHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return CorrelatedHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler.
+func (h CorrelatedHandler) WithGroup(name string) slog.Handler {
+	return CorrelatedHandler{h.Handler.WithGroup(name)}
+}
+
+// Logs exports log records through OTLP, alongside the randomizer's usual log
+// output. A nil *Logs exports nothing.
+type Logs struct {
+	provider *sdklog.LoggerProvider
+}
+
+// LogsFromEnv returns Logs that export through OTLP over HTTP if
+// OTEL_LOGS_EXPORTER is "otlp", or nil otherwise. The standard
+// OTEL_EXPORTER_OTLP_* variables configure the endpoint, headers, and so on,
+// and res describes the process exporting the logs.
+func LogsFromEnv(ctx context.Context, res *resource.Resource) (*Logs, error) {
+	switch exporter := os.Getenv("OTEL_LOGS_EXPORTER"); exporter {
+	case "", "none":
+		return nil, nil
+	case "otlp":
+	default:
+		return nil, fmt.Errorf("unsupported OTEL_LOGS_EXPORTER %q (want \"otlp\" or \"none\")", exporter)
+	}
+
+	// The exporter is created on first use, so that processes that start on
+	// a latency-sensitive path, like AWS Lambda functions, don't wait for it.
+	exporter := lazyLogExporter{cache.Lazy(func(ctx context.Context) (sdklog.Exporter, error) {
+		exporter, err := otlploghttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("creating OTLP log exporter: %w", err)
+		}
+		return exporter, nil
+	})}
+	provider := sdklog.NewLoggerProvider(
+		sdklog.WithResource(res),
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)))
+	return &Logs{provider: provider}, nil
+}
+
+// Handler returns a handler that writes each record to base with trace
+// correlation, and exports it through OTLP if l is non-nil. Exported records
+// carry the trace context natively rather than as attributes.
+func (l *Logs) Handler(base slog.Handler) slog.Handler {
+	correlated := CorrelatedHandler{base}
+	if l == nil {
+		return correlated
+	}
+	bridge := otelslog.NewHandler(instrumentationName, otelslog.WithLoggerProvider(l.provider))
+	return slog.NewMultiHandler(correlated, bridge)
+}
+
+// ForceFlush exports any queued records, for environments like AWS Lambda that
+// may freeze the process between requests.
+func (l *Logs) ForceFlush(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	return l.provider.ForceFlush(ctx)
+}
+
+// Shutdown exports any queued records and stops exporting new ones.
+func (l *Logs) Shutdown(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	return l.provider.Shutdown(ctx)
+}
+
+// Flushers flushes each of its elements in turn, for instrumentation that
+// accepts only a single flusher.
+type Flushers []interface {
+	ForceFlush(context.Context) error
+}
+
+// ForceFlush flushes every element, even if some fail.
+func (f Flushers) ForceFlush(ctx context.Context) error {
+	var errs []error
+	for _, flusher := range f {
+		errs = append(errs, flusher.ForceFlush(ctx))
+	}
+	return errors.Join(errs...)
+}
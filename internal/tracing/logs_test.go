@@ -0,0 +1,86 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestCorrelatedHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New((*Logs)(nil).Handler(slog.NewJSONHandler(&buf, nil))).With("app", "randomizer")
+
+	tp := sdktrace.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "request")
+	logger.InfoContext(ctx, "Traced")
+	span.End()
+	logger.InfoContext(context.Background(), "Untraced")
+
+	dec := json.NewDecoder(&buf)
+	var traced, untraced map[string]any
+	if err := dec.Decode(&traced); err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.Decode(&untraced); err != nil {
+		t.Fatal(err)
+	}
+
+	sc := span.SpanContext()
+	if traced["trace_id"] != sc.TraceID().String() || traced["span_id"] != sc.SpanID().String() || traced["app"] != "randomizer" {
+		t.Errorf("got traced record %v, want IDs from %v", traced, sc)
+	}
+	if _, ok := untraced["trace_id"]; ok {
+		t.Errorf("got trace ID in untraced record %v", untraced)
+	}
+}
+
+type flushFunc func(context.Context) error
+
+func (f flushFunc) ForceFlush(ctx context.Context) error { return f(ctx) }
+
+func TestFlushers(t *testing.T) {
+	var calls int
+	failing := flushFunc(func(context.Context) error { calls++; return errors.New("failed") })
+	working := flushFunc(func(context.Context) error { calls++; return nil })
+
+	err := Flushers{failing, working, (*Logs)(nil)}.ForceFlush(context.Background())
+	if err == nil || calls != 2 {
+		t.Errorf("got error %v after %d calls, want an error after 2", err, calls)
+	}
+}
+
+func TestLogsFromEnv(t *testing.T) {
+	t.Setenv("OTEL_LOGS_EXPORTER", "")
+	if logs, err := LogsFromEnv(context.Background(), nil); logs != nil || err != nil {
+		t.Errorf("LogsFromEnv() = %v, %v without an exporter", logs, err)
+	}
+	t.Setenv("OTEL_LOGS_EXPORTER", "console")
+	if _, err := LogsFromEnv(context.Background(), nil); err == nil {
+		t.Error("no error for an unsupported exporter")
+	}
+}
+
+func TestLazySpanExporter(t *testing.T) {
+	var created int
+	exporter := LazySpanExporter(func(context.Context) (sdktrace.SpanExporter, error) {
+		created++
+		return new(recordingExporter), nil
+	})
+	if err := exporter.Shutdown(context.Background()); err != nil || created != 0 {
+		t.Errorf("Shutdown() = %v after creating %d exporters, want none", err, created)
+	}
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	for range 2 {
+		_, span := tp.Tracer("test").Start(context.Background(), "request")
+		span.End()
+	}
+	if created != 1 {
+		t.Errorf("created %d exporters, want 1", created)
+	}
+}
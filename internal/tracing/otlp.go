@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// TracesFromEnv returns a tracer provider that exports spans through OTLP over
+// HTTP if OTEL_TRACES_EXPORTER is "otlp", or nil otherwise. Like
+// [LogsFromEnv], it's configured by the standard OTEL_EXPORTER_OTLP_*
+// variables, so that one endpoint can receive both.
+//
+// The provider batches spans in the background, which suits long-running
+// servers. For AWS Lambda, use an [AdaptiveProcessor] instead.
+func TracesFromEnv(ctx context.Context, res *resource.Resource) (*sdktrace.TracerProvider, error) {
+	switch exporter := os.Getenv("OTEL_TRACES_EXPORTER"); exporter {
+	case "", "none":
+		return nil, nil
+	case "otlp":
+	default:
+		return nil, fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q (want \"otlp\" or \"none\")", exporter)
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating OTLP span exporter: %w", err)
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithResource(res),
+		sdktrace.WithBatcher(exporter)), nil
+}
+
+// Resource describes a randomizer process to OTLP backends by the service
+// name, which OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES may override.
+func Resource(ctx context.Context, service string) *resource.Resource {
+	// Detection errors are partial, and res still holds whatever could be
+	// detected, so they're safe to ignore.
+	res, _ := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(service)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv())
+	return res
+}
@@ -0,0 +1,261 @@
+// Package warm keeps serverless deployments of the randomizer warm, for teams
+// that can't use provisioned concurrency, and measures how often requests
+// still wait for a cold start.
+//
+// A [Pinger] requests the deployment's [Path] on an interval, several times at
+// once, and [Handler] holds each ping open briefly so that concurrent pings
+// land in separate instances. [ColdStarts] logs each instance's first
+// invocation as a CloudWatch embedded metric, labeled with what triggered it,
+// so the share of cold starts that users wait for is visible before and after
+// keep-warm is turned on.
+package warm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/clock"
+)
+
+// Path is the path that pings request.
+const Path = "/warmz"
+
+// Default settings for a Pinger.
+const (
+	DefaultInterval = 5 * time.Minute
+	DefaultHold     = 100 * time.Millisecond
+	DefaultTimeout  = 5 * time.Second
+)
+
+// maxHold bounds how long Handler holds a ping, since anyone can request it.
+const maxHold = time.Second
+
+// Handler responds to pings with no content, after holding each one for the
+// duration in its "hold" query parameter (up to a second), so that concurrent
+// pings can't all be served by the same instance. It does no other work.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hold, _ := time.ParseDuration(r.URL.Query().Get("hold"))
+		if hold = min(hold, maxHold); hold > 0 {
+			select {
+			case <-time.After(hold):
+			case <-r.Context().Done():
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// Status describes a Pinger's recent pings.
+type Status struct {
+	Rounds   int64     `json:"rounds"`
+	Pings    int64     `json:"pings"`
+	Failures int64     `json:"failures"`
+	LastPing time.Time `json:"last_ping,omitzero"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Pinger keeps a deployment warm by requesting its [Path] with several
+// concurrent pings on every interval.
+type Pinger struct {
+	// URL is the deployment's base URL, like a Lambda function URL.
+	URL string
+	// Concurrency sets the number of concurrent pings in each round, which is
+	// roughly the number of instances kept warm. If zero, it defaults to 1.
+	Concurrency int
+	// Interval sets the time between rounds. If zero, it defaults to
+	// DefaultInterval.
+	Interval time.Duration
+	// Hold sets how long the deployment holds each ping. If zero, it defaults
+	// to DefaultHold.
+	Hold time.Duration
+	// Client, if non-nil, replaces http.DefaultClient.
+	Client *http.Client
+	// Clock, if non-nil, replaces the system clock.
+	Clock clock.Clock
+	// Logger, if non-nil, logs failed pings.
+	Logger *slog.Logger
+
+	mu     sync.Mutex
+	status Status
+}
+
+// PingerFromEnv returns a Pinger for the URL in KEEP_WARM_URL, with the
+// settings in KEEP_WARM_INTERVAL (a Go duration) and KEEP_WARM_CONCURRENCY, or
+// nil if KEEP_WARM_URL is unset.
+func PingerFromEnv() (*Pinger, error) {
+	base := os.Getenv("KEEP_WARM_URL")
+	if base == "" {
+		return nil, nil
+	}
+	if u, err := url.Parse(base); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid KEEP_WARM_URL %q", base)
+	}
+
+	p := &Pinger{URL: base}
+	if value := os.Getenv("KEEP_WARM_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid KEEP_WARM_INTERVAL %q", value)
+		}
+		p.Interval = interval
+	}
+	if value := os.Getenv("KEEP_WARM_CONCURRENCY"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 100 {
+			return nil, fmt.Errorf("invalid KEEP_WARM_CONCURRENCY %q (want 1 to 100)", value)
+		}
+		p.Concurrency = n
+	}
+	return p, nil
+}
+
+// Ping sends one round of concurrent pings, and returns the errors from any
+// that failed.
+func (p *Pinger) Ping(ctx context.Context) error {
+	n := max(p.Concurrency, 1)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Go(func() { errs[i] = p.ping(ctx) })
+	}
+	wg.Wait()
+	err := errors.Join(errs...)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status.Rounds++
+	p.status.Pings += int64(n)
+	p.status.LastPing = clock.Or(p.Clock).Now()
+	p.status.Error = ""
+	for _, err := range errs {
+		if err != nil {
+			p.status.Failures++
+			p.status.Error = err.Error()
+		}
+	}
+	return err
+}
+
+func (p *Pinger) ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+
+	hold := p.Hold
+	if hold == 0 {
+		hold = DefaultHold
+	}
+	target := strings.TrimSuffix(p.URL, "/") + Path + "?hold=" + hold.String()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("keep-warm ping responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// Run sends a round of pings on every interval until ctx is canceled.
+func (p *Pinger) Run(ctx context.Context) {
+	interval := p.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.Ping(ctx); err != nil && p.Logger != nil {
+				p.Logger.Warn("Failed to send keep-warm pings", "err", err)
+			}
+		}
+	}
+}
+
+// Status returns the Pinger's recent history. A nil *Pinger has none.
+func (p *Pinger) Status() Status {
+	if p == nil {
+		return Status{}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status
+}
+
+// Triggers of an instance's first invocation, for [ColdStarts.Observe].
+const (
+	TriggerRequest  = "request"  // A user waited for the cold start.
+	TriggerWarmUp   = "warmup"   // A keep-warm ping absorbed it.
+	TriggerSchedule = "schedule" // A background task absorbed it.
+)
+
+// ColdStarts logs an instance's first invocation as a cold start, in the
+// CloudWatch embedded metric format, so that CloudWatch graphs a ColdStarts
+// metric by trigger without any API calls. Comparing the "request" trigger
+// with Lambda's own Invocations metric shows how often users wait for one.
+//
+// The zero value is ready to use, and a nil *ColdStarts logs nothing.
+type ColdStarts struct {
+	// Logger logs the metric. It must write JSON to standard output or
+	// standard error, like slog.NewJSONHandler.
+	Logger *slog.Logger
+	// Namespace overrides the CloudWatch namespace "Randomizer".
+	Namespace string
+
+	once sync.Once
+}
+
+// processStart approximates when the instance started initializing.
+var processStart = time.Now()
+
+// Observe records an invocation with the given trigger, and logs the metric if
+// it's the instance's first.
+func (c *ColdStarts) Observe(trigger string) {
+	if c == nil || c.Logger == nil {
+		return
+	}
+	c.once.Do(func() {
+		namespace := c.Namespace
+		if namespace == "" {
+			namespace = "Randomizer"
+		}
+		c.Logger.Info("Cold start",
+			slog.Any("_aws", map[string]any{
+				"Timestamp": time.Now().UnixMilli(),
+				"CloudWatchMetrics": []map[string]any{{
+					"Namespace":  namespace,
+					"Dimensions": [][]string{{"Trigger"}},
+					"Metrics": []map[string]string{
+						{"Name": "ColdStarts", "Unit": "Count"},
+						{"Name": "TimeSinceInit", "Unit": "Milliseconds"},
+					},
+				}},
+			}),
+			slog.String("Trigger", trigger),
+			slog.Int("ColdStarts", 1),
+			slog.Int64("TimeSinceInit", time.Since(processStart).Milliseconds()),
+		)
+	})
+}
@@ -0,0 +1,113 @@
+package warm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPinger(t *testing.T) {
+	var (
+		inFlight, peak atomic.Int32
+		handler        = Handler()
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != Path {
+			http.NotFound(w, r)
+			return
+		}
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	p := &Pinger{URL: srv.URL + "/", Concurrency: 3}
+	if err := p.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping() failed: %v", err)
+	}
+	if got := peak.Load(); got != 3 {
+		t.Errorf("got %d concurrent pings, want 3", got)
+	}
+	if s := p.Status(); s.Rounds != 1 || s.Pings != 3 || s.Failures != 0 || s.LastPing.IsZero() {
+		t.Errorf("got status %+v after one round", s)
+	}
+
+	p.URL = srv.URL + "/missing"
+	if err := p.Ping(context.Background()); err == nil {
+		t.Error("Ping() succeeded for a missing path")
+	}
+	if s := p.Status(); s.Failures != 3 || s.Error == "" {
+		t.Errorf("got status %+v after failed round", s)
+	}
+}
+
+func TestPingerFromEnv(t *testing.T) {
+	t.Setenv("KEEP_WARM_URL", "")
+	if p, err := PingerFromEnv(); p != nil || err != nil {
+		t.Errorf("got %v, %v without KEEP_WARM_URL", p, err)
+	}
+
+	t.Setenv("KEEP_WARM_URL", "https://example.com")
+	t.Setenv("KEEP_WARM_INTERVAL", "2m")
+	t.Setenv("KEEP_WARM_CONCURRENCY", "4")
+	p, err := PingerFromEnv()
+	if err != nil || p.URL != "https://example.com" || p.Interval.Minutes() != 2 || p.Concurrency != 4 {
+		t.Errorf("got %+v, %v", p, err)
+	}
+
+	for name, value := range map[string]string{
+		"KEEP_WARM_URL":         "example.com",
+		"KEEP_WARM_INTERVAL":    "often",
+		"KEEP_WARM_CONCURRENCY": "0",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := PingerFromEnv(); err == nil {
+				t.Errorf("accepted %s=%q", name, value)
+			}
+		})
+	}
+}
+
+func TestColdStarts(t *testing.T) {
+	var buf bytes.Buffer
+	c := &ColdStarts{Logger: slog.New(slog.NewJSONHandler(&buf, nil))}
+	c.Observe(TriggerWarmUp)
+	c.Observe(TriggerRequest)
+
+	var entry struct {
+		AWS struct {
+			CloudWatchMetrics []struct {
+				Namespace string
+			}
+		} `json:"_aws"`
+		Trigger    string
+		ColdStarts int
+	}
+	dec := json.NewDecoder(&buf)
+	if err := dec.Decode(&entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Trigger != TriggerWarmUp || entry.ColdStarts != 1 ||
+		len(entry.AWS.CloudWatchMetrics) != 1 || entry.AWS.CloudWatchMetrics[0].Namespace != "Randomizer" {
+		t.Errorf("got log entry %+v", entry)
+	}
+	if dec.More() {
+		t.Error("logged more than one cold start")
+	}
+
+	var nilStarts *ColdStarts
+	nilStarts.Observe(TriggerRequest)
+}
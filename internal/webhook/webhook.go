@@ -0,0 +1,120 @@
+// Package webhook delivers the payloads of result webhooks, which channels
+// register with the randomizer's /webhook operation.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+// DefaultTimeout bounds each delivery, including redirects.
+const DefaultTimeout = 5 * time.Second
+
+// ErrPrivateAddress is returned for webhook URLs that resolve to addresses
+// that aren't on the public internet.
+var ErrPrivateAddress = errors.New("webhook address is not public")
+
+// Sender POSTs rendered payloads to webhook URLs. Payloads that are valid JSON
+// are sent as application/json, and others as plain text.
+//
+// Since any user who can save groups in a channel can choose its webhook URL,
+// the default client refuses to connect to loopback, private, and link-local
+// addresses, so that webhooks can't reach services on the deployment's own
+// network.
+type Sender struct {
+	// Timeout bounds each delivery. If zero, it defaults to DefaultTimeout.
+	Timeout time.Duration
+	// HTTPClient, if non-nil, replaces a client that only connects to public
+	// addresses.
+	HTTPClient *http.Client
+}
+
+// SenderFromEnv returns a sender for result webhooks if RESULT_WEBHOOKS is
+// "1", or nil to leave /webhook disabled.
+func SenderFromEnv() randomizer.WebhookSender {
+	if os.Getenv("RESULT_WEBHOOKS") != "1" {
+		return nil
+	}
+	return Sender{}.Send
+}
+
+// Send implements randomizer.WebhookSender.
+func (s Sender) Send(ctx context.Context, url string, payload []byte) error {
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	if json.Valid(payload) {
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	}
+
+	client := s.HTTPClient
+	if client == nil {
+		client = publicClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// publicClient connects only to public addresses. It checks each address
+// after it's resolved, so that neither DNS names nor redirects can point it
+// somewhere else.
+var publicClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: DefaultTimeout,
+			Control: func(_, address string, _ syscall.RawConn) error {
+				if !isPublic(address) {
+					return fmt.Errorf("%w: %s", ErrPrivateAddress, address)
+				}
+				return nil
+			},
+		}).DialContext,
+		ForceAttemptHTTP2:   true,
+		TLSHandshakeTimeout: DefaultTimeout,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+	},
+}
+
+// sharedAddressSpace is the range for carrier-grade NAT, which some cloud
+// networks use internally.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+func isPublic(address string) bool {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return false
+	}
+	addr := addrPort.Addr().Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
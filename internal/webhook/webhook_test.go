@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSend(t *testing.T) {
+	var gotType, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotType, gotBody = r.Header.Get("Content-Type"), string(body)
+		if gotBody == "fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+	sender := Sender{HTTPClient: srv.Client()}
+
+	if err := sender.Send(t.Context(), srv.URL, []byte(`{"winner": "pizza"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if gotType != "application/json" || gotBody != `{"winner": "pizza"}` {
+		t.Errorf("got %s %q", gotType, gotBody)
+	}
+
+	if err := sender.Send(t.Context(), srv.URL, []byte("pizza won")); err != nil {
+		t.Fatal(err)
+	}
+	if gotType != "text/plain; charset=utf-8" {
+		t.Errorf("got content type %s for plain text", gotType)
+	}
+
+	if err := sender.Send(t.Context(), srv.URL, []byte("fail")); err == nil {
+		t.Error("no error for a failed delivery")
+	}
+}
+
+func TestPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("delivered a webhook to a loopback address")
+	}))
+	defer srv.Close()
+
+	err := Sender{}.Send(t.Context(), srv.URL, []byte("{}"))
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("got error %v, want %v", err, ErrPrivateAddress)
+	}
+
+	for address, want := range map[string]bool{
+		"93.184.215.14:443":      true,
+		"[2606:2800:21f::1]:443": true,
+		"127.0.0.1:443":          false,
+		"10.1.2.3:443":           false,
+		"192.168.1.1:443":        false,
+		"169.254.169.254:80":     false,
+		"100.100.100.200:80":     false,
+		"[::1]:443":              false,
+		"[fd00::1]:443":          false,
+		"[::ffff:127.0.0.1]:443": false,
+		"0.0.0.0:443":            false,
+		"not an address":         false,
+	} {
+		if got := isPublic(address); got != want {
+			t.Errorf("isPublic(%q) = %v, want %v", address, got, want)
+		}
+	}
+}
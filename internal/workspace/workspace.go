@@ -0,0 +1,224 @@
+// Package workspace tracks the channels in which each Slack workspace uses the
+// randomizer, so that a workspace's data can be found, exported, and deleted
+// after it uninstalls the app.
+//
+// Groups are saved per channel, and nothing in a channel's partition says
+// which workspace it belongs to. A Registry keeps that index in partitions of
+// its own: one per workspace listing its channels, and a shared one listing
+// the workspaces scheduled for deletion.
+package workspace
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/clock"
+	"github.com/featherbread/randomizer/internal/randomizer"
+)
+
+// DefaultGracePeriod is how long a workspace's data outlives an uninstall of
+// the app, in case the workspace reinstalls it.
+const DefaultGracePeriod = 30 * 24 * time.Hour
+
+// Records in the Registry's partitions start with "/", like the randomizer's
+// own records, so that they never look like groups.
+const (
+	channelsRecord = "/channels"
+	pendingRecord  = "/pending-deletions"
+)
+
+// registryPartition holds the list of workspaces scheduled for deletion.
+const registryPartition = "workspaces"
+
+// recheckInterval bounds how long a Registry trusts its memory that a channel
+// is indexed, so that a workspace that reinstalls the app during its grace
+// period cancels its deletion soon after its next request in each process.
+const recheckInterval = time.Hour
+
+// Registry indexes the channels of each workspace, and schedules the deletion
+// of a workspace's data after it uninstalls the app.
+//
+// A nil *Registry ignores all requests to track channels.
+type Registry struct {
+	// StoreFactory provides a Store for a given partition, like the one that
+	// serves groups.
+	StoreFactory func(partition string) randomizer.Store
+	// GracePeriod sets the time between an uninstall and the deletion of the
+	// workspace's data. If zero, it defaults to DefaultGracePeriod.
+	GracePeriod time.Duration
+	// Clock, if non-nil, replaces the system clock for scheduling deletions
+	// and spacing out checks of the store.
+	Clock clock.Clock
+
+	mu      sync.Mutex
+	checked map[string]time.Time
+}
+
+// RegistryFromEnv returns a Registry for stores, with the grace period in
+// UNINSTALL_GRACE_PERIOD as a Go duration if it's set.
+func RegistryFromEnv(stores func(partition string) randomizer.Store) (*Registry, error) {
+	registry := &Registry{StoreFactory: stores}
+	if env, ok := os.LookupEnv("UNINSTALL_GRACE_PERIOD"); ok {
+		grace, err := time.ParseDuration(env)
+		if err != nil || grace < 0 {
+			return nil, fmt.Errorf("UNINSTALL_GRACE_PERIOD is not a valid duration: %q", env)
+		}
+		registry.GracePeriod = grace
+	}
+	return registry, nil
+}
+
+// Deletion describes a workspace whose data is scheduled for deletion.
+type Deletion struct {
+	Team string    `json:"team"`
+	Due  time.Time `json:"due"`
+}
+
+// Partition returns the name of the partition holding a workspace's channel
+// index, along with settings that apply to all of its channels.
+func Partition(team string) string {
+	return "workspace-" + team
+}
+
+// Track records that a workspace has used the randomizer in a channel, and
+// cancels any scheduled deletion of the workspace's data, since it must have
+// reinstalled the app. Each process checks the store for a given channel at
+// most once per hour.
+func (r *Registry) Track(ctx context.Context, team, channel string) error {
+	if r == nil || team == "" || channel == "" {
+		return nil
+	}
+
+	key := team + "/" + channel
+	now := clock.Or(r.Clock).Now()
+	r.mu.Lock()
+	if now.Sub(r.checked[key]) < recheckInterval {
+		r.mu.Unlock()
+		return nil
+	}
+	r.mu.Unlock()
+
+	store := r.StoreFactory(Partition(team))
+	channels, err := store.Get(ctx, channelsRecord)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(channels, channel) {
+		if err := store.Put(ctx, channelsRecord, append(channels, channel)); err != nil {
+			return err
+		}
+	}
+	if _, err := r.cancelDeletion(ctx, team); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.checked == nil {
+		r.checked = make(map[string]time.Time)
+	}
+	r.checked[key] = now
+	return nil
+}
+
+// Channels returns the channels in which a workspace has used the randomizer.
+func (r *Registry) Channels(ctx context.Context, team string) ([]string, error) {
+	channels, err := r.StoreFactory(Partition(team)).Get(ctx, channelsRecord)
+	slices.Sort(channels)
+	return channels, err
+}
+
+// ScheduleDeletion schedules the deletion of a workspace's data at the end of
+// the grace period, and returns the time when it will be due. Scheduling the
+// deletion of a workspace again keeps the later due time.
+func (r *Registry) ScheduleDeletion(ctx context.Context, team string) (time.Time, error) {
+	grace := r.GracePeriod
+	if grace == 0 {
+		grace = DefaultGracePeriod
+	}
+	due := clock.Or(r.Clock).Now().Add(grace).UTC().Truncate(time.Second)
+
+	store := r.StoreFactory(registryPartition)
+	entries, err := store.Get(ctx, pendingRecord)
+	if err != nil {
+		return time.Time{}, err
+	}
+	entries = slices.DeleteFunc(entries, func(entry string) bool {
+		return strings.HasPrefix(entry, team+"|")
+	})
+	entries = append(entries, team+"|"+due.Format(time.RFC3339))
+	if err := store.Put(ctx, pendingRecord, entries); err != nil {
+		return time.Time{}, err
+	}
+
+	// Forget which of the workspace's channels this process has checked, so
+	// that a reinstall cancels the deletion right away.
+	r.mu.Lock()
+	for key := range r.checked {
+		if strings.HasPrefix(key, team+"/") {
+			delete(r.checked, key)
+		}
+	}
+	r.mu.Unlock()
+	return due, nil
+}
+
+// Pending returns every scheduled deletion, in order of when they're due.
+func (r *Registry) Pending(ctx context.Context) ([]Deletion, error) {
+	entries, err := r.StoreFactory(registryPartition).Get(ctx, pendingRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	deletions := make([]Deletion, 0, len(entries))
+	for _, entry := range entries {
+		team, due, ok := strings.Cut(entry, "|")
+		if !ok {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, due); err == nil {
+			deletions = append(deletions, Deletion{Team: team, Due: t})
+		}
+	}
+	slices.SortFunc(deletions, func(x, y Deletion) int { return x.Due.Compare(y.Due) })
+	return deletions, nil
+}
+
+// Forget removes a workspace from the Registry, along with any scheduled
+// deletion of its data. It doesn't delete the data in the workspace's
+// channels, or its workspace-wide settings, which callers should do first.
+func (r *Registry) Forget(ctx context.Context, team string) error {
+	if _, err := r.cancelDeletion(ctx, team); err != nil {
+		return err
+	}
+	_, err := r.StoreFactory(Partition(team)).Delete(ctx, channelsRecord)
+	return err
+}
+
+// cancelDeletion removes any scheduled deletion of a workspace's data, and
+// reports whether there was one.
+func (r *Registry) cancelDeletion(ctx context.Context, team string) (bool, error) {
+	store := r.StoreFactory(registryPartition)
+	entries, err := store.Get(ctx, pendingRecord)
+	if err != nil {
+		return false, err
+	}
+
+	remaining := slices.DeleteFunc(slices.Clone(entries), func(entry string) bool {
+		return strings.HasPrefix(entry, team+"|")
+	})
+	if len(remaining) == len(entries) {
+		return false, nil
+	}
+	if len(remaining) == 0 {
+		_, err = store.Delete(ctx, pendingRecord)
+	} else {
+		err = store.Put(ctx, pendingRecord, remaining)
+	}
+	return true, err
+}
@@ -0,0 +1,75 @@
+package workspace
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/featherbread/randomizer/internal/clock/clocktest"
+	"github.com/featherbread/randomizer/internal/randomizer"
+	"github.com/featherbread/randomizer/internal/randomizer/rndtest"
+)
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	partitions := make(map[string]rndtest.Store)
+	registry := &Registry{
+		StoreFactory: func(partition string) randomizer.Store {
+			if partitions[partition] == nil {
+				partitions[partition] = make(rndtest.Store)
+			}
+			return partitions[partition]
+		},
+		GracePeriod: 24 * time.Hour,
+		Clock:       clocktest.New(now),
+	}
+
+	for _, channel := range []string{"C2", "C1", "C2"} {
+		if err := registry.Track(ctx, "T1", channel); err != nil {
+			t.Fatalf("tracking %s: %v", channel, err)
+		}
+	}
+	if channels, _ := registry.Channels(ctx, "T1"); !slices.Equal(channels, []string{"C1", "C2"}) {
+		t.Errorf("got channels %v, want [C1 C2]", channels)
+	}
+
+	due, err := registry.ScheduleDeletion(ctx, "T1")
+	if err != nil {
+		t.Fatalf("scheduling deletion: %v", err)
+	}
+	if want := now.Add(24 * time.Hour); !due.Equal(want) {
+		t.Errorf("deletion due at %v, want %v", due, want)
+	}
+	if pending, _ := registry.Pending(ctx); len(pending) != 1 || pending[0].Team != "T1" {
+		t.Errorf("got pending deletions %v, want T1", pending)
+	}
+
+	// A request after the uninstall means that the workspace reinstalled the
+	// app, even from a channel that this process already checked.
+	if err := registry.Track(ctx, "T1", "C1"); err != nil {
+		t.Fatalf("tracking after uninstall: %v", err)
+	}
+	if pending, _ := registry.Pending(ctx); len(pending) != 0 {
+		t.Errorf("deletion still pending after reinstall: %v", pending)
+	}
+
+	registry.ScheduleDeletion(ctx, "T1")
+	if err := registry.Forget(ctx, "T1"); err != nil {
+		t.Fatalf("forgetting workspace: %v", err)
+	}
+	if pending, _ := registry.Pending(ctx); len(pending) != 0 {
+		t.Errorf("deletion still pending after forgetting workspace: %v", pending)
+	}
+	if channels, _ := registry.Channels(ctx, "T1"); len(channels) != 0 {
+		t.Errorf("channels still indexed after forgetting workspace: %v", channels)
+	}
+}
+
+func TestNilRegistry(t *testing.T) {
+	var registry *Registry
+	if err := registry.Track(context.Background(), "T1", "C1"); err != nil {
+		t.Errorf("nil registry returned error %v", err)
+	}
+}